@@ -0,0 +1,350 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os/exec"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const rbacMarker = "+kubebuilder:rbac:"
+
+var (
+	forVerbs    = []string{"get", "list", "watch", "update", "patch"}
+	statusVerbs = []string{"get", "update", "patch"}
+	ownsVerbs   = []string{"get", "list", "watch", "create", "update", "patch", "delete"}
+	watchVerbs  = []string{"get", "list", "watch"}
+)
+
+// goPackage is a package listed by go list.
+type goPackage struct {
+	importPath string
+	dir        string
+	files      []string
+}
+
+// generator collects the permissions needed by the controllers of packages.
+type generator struct {
+	// verbs are the verbs needed on each resource, keyed by group and resource.
+	verbs map[schema.GroupResource]sets.String
+	// groups caches the API group of the packages, by import path.
+	groups map[string]string
+	// listPackages lists the packages matching the patterns, see goList.
+	listPackages func(patterns ...string) ([]goPackage, error)
+}
+
+func newGenerator() *generator {
+	return &generator{
+		verbs:        map[schema.GroupResource]sets.String{},
+		groups:       map[string]string{},
+		listPackages: goList,
+	}
+}
+
+// goList lists the packages matching the patterns with go list.
+func goList(patterns ...string) ([]goPackage, error) {
+	args := append([]string{"list", "-f", "{{.ImportPath}}\t{{.Dir}}\t{{join .GoFiles \" \"}}"}, patterns...)
+	cmd := exec.Command("go", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list %s: %w: %s", strings.Join(patterns, " "), err, stderr.String())
+	}
+	var pkgs []goPackage
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			continue
+		}
+		pkgs = append(pkgs, goPackage{importPath: fields[0], dir: fields[1], files: strings.Fields(fields[2])})
+	}
+	return pkgs, nil
+}
+
+// grant adds the verbs on the resource.
+func (g *generator) grant(gr schema.GroupResource, verbs ...string) {
+	if g.verbs[gr] == nil {
+		g.verbs[gr] = sets.NewString()
+	}
+	g.verbs[gr].Insert(verbs...)
+}
+
+// load collects the permissions needed by the packages matching the patterns.
+func (g *generator) load(patterns ...string) error {
+	pkgs, err := g.listPackages(patterns...)
+	if err != nil {
+		return err
+	}
+	for _, pkg := range pkgs {
+		fset := token.NewFileSet()
+		for _, name := range pkg.files {
+			file, err := parser.ParseFile(fset, path.Join(pkg.dir, name), nil, parser.ParseComments)
+			if err != nil {
+				return err
+			}
+			if err := g.loadFile(pkg, file); err != nil {
+				return fmt.Errorf("%s: %w", fset.Position(file.Pos()).Filename, err)
+			}
+		}
+	}
+	return nil
+}
+
+// loadFile collects the permissions needed by the controllers and the markers of the file.
+func (g *generator) loadFile(pkg goPackage, file *ast.File) error {
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			text := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+			if !strings.HasPrefix(text, rbacMarker) {
+				continue
+			}
+			if err := g.loadRBACMarker(strings.TrimPrefix(text, rbacMarker)); err != nil {
+				return err
+			}
+		}
+	}
+
+	imports := map[string]string{}
+	for _, spec := range file.Imports {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		name := path.Base(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = importPath
+	}
+
+	var err error
+	ast.Inspect(file, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok || err != nil {
+			return err == nil
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || len(call.Args) == 0 || !isBuilderChain(sel.X) {
+			return true
+		}
+		switch sel.Sel.Name {
+		case "For":
+			err = g.grantType(pkg, imports, call.Args[0], forVerbs, statusVerbs)
+		case "Owns":
+			err = g.grantType(pkg, imports, call.Args[0], ownsVerbs, nil)
+		case "Watches", "WatchesAcrossClusters":
+			if kind := kindSourceType(call.Args[0]); kind != nil {
+				err = g.grantType(pkg, imports, kind, watchVerbs, nil)
+			}
+		}
+		return err == nil
+	})
+	return err
+}
+
+// loadRBACMarker grants the permissions of a +kubebuilder:rbac marker, e.g.
+// groups=apps,resources=deployments;deployments/status,verbs=get;list.
+func (g *generator) loadRBACMarker(marker string) error {
+	values := map[string][]string{}
+	for _, arg := range strings.Split(marker, ",") {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid argument %q of marker %s%s", arg, rbacMarker, marker)
+		}
+		for _, value := range strings.Split(kv[1], ";") {
+			values[kv[0]] = append(values[kv[0]], strings.Trim(value, `"`))
+		}
+	}
+	if len(values["resources"]) == 0 || len(values["verbs"]) == 0 {
+		return fmt.Errorf("marker %s%s has no resources or verbs", rbacMarker, marker)
+	}
+	groups := values["groups"]
+	if len(groups) == 0 {
+		groups = []string{""}
+	}
+	for _, group := range groups {
+		for _, resource := range values["resources"] {
+			g.grant(schema.GroupResource{Group: group, Resource: resource}, values["verbs"]...)
+		}
+	}
+	return nil
+}
+
+// grantType grants the verbs on the resource of the type of expr, e.g. &corev1.Pod{}, and the
+// status verbs on its status if any.
+func (g *generator) grantType(pkg goPackage, imports map[string]string, expr ast.Expr, verbs, statusVerbs []string) error {
+	if unary, ok := expr.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+		expr = unary.X
+	}
+	lit, ok := expr.(*ast.CompositeLit)
+	if !ok {
+		// not a literal, e.g. a variable: the type is unknown.
+		return nil
+	}
+
+	importPath, kind := pkg.importPath, ""
+	switch typ := lit.Type.(type) {
+	case *ast.Ident:
+		kind = typ.Name
+	case *ast.SelectorExpr:
+		pkgName, ok := typ.X.(*ast.Ident)
+		if !ok || imports[pkgName.Name] == "" {
+			return nil
+		}
+		importPath, kind = imports[pkgName.Name], typ.Sel.Name
+	default:
+		return nil
+	}
+
+	group, err := g.groupOf(importPath)
+	if err != nil {
+		return err
+	}
+	resource, _ := meta.UnsafeGuessKindToResource(schema.GroupVersionKind{Group: group, Version: path.Base(importPath), Kind: kind})
+	g.grant(resource.GroupResource(), verbs...)
+	if len(statusVerbs) > 0 {
+		g.grant(schema.GroupResource{Group: group, Resource: resource.Resource + "/status"}, statusVerbs...)
+	}
+	return nil
+}
+
+// groupOf returns the API group of the package, from its +groupName marker or its GroupName
+// constant.
+func (g *generator) groupOf(importPath string) (string, error) {
+	if group, ok := g.groups[importPath]; ok {
+		return group, nil
+	}
+	pkgs, err := g.listPackages(importPath)
+	if err != nil {
+		return "", err
+	}
+	for _, pkg := range pkgs {
+		fset := token.NewFileSet()
+		for _, name := range pkg.files {
+			file, err := parser.ParseFile(fset, path.Join(pkg.dir, name), nil, parser.ParseComments)
+			if err != nil {
+				return "", err
+			}
+			if group, ok := groupOfFile(file); ok {
+				g.groups[importPath] = group
+				return group, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("cannot find the API group of package %s, add a +groupName marker to it", importPath)
+}
+
+// groupOfFile returns the API group of the +groupName marker or of the GroupName constant of the file.
+func groupOfFile(file *ast.File) (string, bool) {
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			text := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+			if strings.HasPrefix(text, "+groupName=") {
+				return strings.TrimPrefix(text, "+groupName="), true
+			}
+		}
+	}
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			value := spec.(*ast.ValueSpec)
+			for i, name := range value.Names {
+				if name.Name != "GroupName" || i >= len(value.Values) {
+					continue
+				}
+				if lit, ok := value.Values[i].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+					group, err := strconv.Unquote(lit.Value)
+					return group, err == nil
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// isBuilderChain returns whether expr is a chain of calls on a builder, e.g.
+// ctrl.NewControllerManagedBy(mgr).For(&corev1.Pod{}).
+func isBuilderChain(expr ast.Expr) bool {
+	for {
+		call, ok := expr.(*ast.CallExpr)
+		if !ok {
+			return false
+		}
+		var name string
+		switch fun := call.Fun.(type) {
+		case *ast.SelectorExpr:
+			name, expr = fun.Sel.Name, fun.X
+		case *ast.Ident:
+			name, expr = fun.Name, nil
+		default:
+			return false
+		}
+		if name == "ControllerManagedBy" || name == "NewControllerManagedBy" {
+			return true
+		}
+	}
+}
+
+// kindSourceType returns the Type of a source.Kind literal, e.g. &source.Kind{Type: &corev1.Pod{}}.
+func kindSourceType(expr ast.Expr) ast.Expr {
+	if unary, ok := expr.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+		expr = unary.X
+	}
+	lit, ok := expr.(*ast.CompositeLit)
+	if !ok {
+		return nil
+	}
+	if sel, ok := lit.Type.(*ast.SelectorExpr); !ok || sel.Sel.Name != "Kind" {
+		return nil
+	}
+	for _, elt := range lit.Elts {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			if key, ok := kv.Key.(*ast.Ident); ok && key.Name == "Type" {
+				return kv.Value
+			}
+		}
+	}
+	return nil
+}
+
+// groupResources returns the resources the verbs are granted on, sorted.
+func (g *generator) groupResources() []schema.GroupResource {
+	grs := make([]schema.GroupResource, 0, len(g.verbs))
+	for gr := range g.verbs {
+		grs = append(grs, gr)
+	}
+	sort.Slice(grs, func(i, j int) bool {
+		if grs[i].Group != grs[j].Group {
+			return grs[i].Group < grs[j].Group
+		}
+		return grs[i].Resource < grs[j].Resource
+	})
+	return grs
+}
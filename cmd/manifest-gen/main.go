@@ -0,0 +1,71 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// manifest-gen generates the RBAC and kcp manifests of the controllers of Go packages, so
+// that the deploy manifests stay in sync with the code.  It is meant to be run with go generate:
+//
+//	//go:generate go run sigs.k8s.io/controller-runtime/cmd/manifest-gen -output-dir config ./...
+//
+// It inspects the controllers built with the builder package, e.g.
+// ctrl.NewControllerManagedBy(mgr).For(&appsv1.Deployment{}).Owns(&corev1.Pod{}), and grants:
+//
+//   - get, list, watch, update and patch on the kinds of For, and get, update and patch on
+//     their status;
+//   - get, list, watch, create, update, patch and delete on the kinds of Owns;
+//   - get, list and watch on the kinds of the source.Kinds of Watches and WatchesAcrossClusters.
+//
+// The builder calls must be chained to the call to ControllerManagedBy or NewControllerManagedBy.
+// The rules of the +kubebuilder:rbac markers of the packages are added, e.g.
+//
+//	// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+//
+// The API group of a kind is the one of the +groupName marker of its package, as generated by
+// kubebuilder, or else of its GroupName constant, as in the packages of k8s.io/api.
+//
+// The ClusterRole is written to role.yaml.  With -apiexport-name, a kcp APIBinding of the
+// APIExport is written to apibinding.yaml, claiming the permissions on the resources not
+// served by the APIExport itself, see -apiexport-groups.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func main() {
+	opts := options{}
+	flag.StringVar(&opts.outputDir, "output-dir", "config", "The directory to write the manifests to.")
+	flag.StringVar(&opts.roleName, "role-name", "manager-role", "The name of the ClusterRole.")
+	flag.StringVar(&opts.apiExportName, "apiexport-name", "", "The name of the APIExport of the APIBinding.  No APIBinding is written if empty.")
+	flag.StringVar(&opts.apiExportPath, "apiexport-path", "", "The path of the workspace of the APIExport, e.g. root:org.")
+	apiExportGroups := flag.String("apiexport-groups", "", "The comma-separated API groups served by the APIExport, for which no permission is claimed.")
+	flag.Parse()
+
+	if *apiExportGroups != "" {
+		opts.apiExportGroups = strings.Split(*apiExportGroups, ",")
+	}
+	patterns := flag.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	if err := run(opts, patterns); err != nil {
+		fmt.Fprintf(os.Stderr, "manifest-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
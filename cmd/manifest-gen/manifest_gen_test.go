@@ -0,0 +1,84 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"sigs.k8s.io/yaml"
+)
+
+var _ = Describe("manifest-gen", func() {
+	It("should grant the permissions needed by the controllers and the markers", func() {
+		g := newGenerator()
+		Expect(g.load("./testdata/controllers")).To(Succeed())
+
+		role := g.clusterRole("manager-role")
+		Expect(role.Name).To(Equal("manager-role"))
+		Expect(role.Rules).To(Equal([]rbacv1.PolicyRule{
+			{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get", "list", "watch"}},
+			{APIGroups: []string{""}, Resources: []string{"events"}, Verbs: []string{"create", "patch"}},
+			{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"create", "delete", "get", "list", "patch", "update", "watch"}},
+			{APIGroups: []string{"widgets.example.com"}, Resources: []string{"widgets"}, Verbs: []string{"get", "list", "patch", "update", "watch"}},
+			{APIGroups: []string{"widgets.example.com"}, Resources: []string{"widgets/status"}, Verbs: []string{"get", "patch", "update"}},
+		}))
+	})
+
+	It("should claim the permissions on the resources not served by the APIExport", func() {
+		g := newGenerator()
+		Expect(g.load("./testdata/controllers")).To(Succeed())
+
+		binding := g.apiBinding(options{apiExportName: "widgets", apiExportPath: "root:org", apiExportGroups: []string{"widgets.example.com"}})
+		Expect(binding.Spec.Reference.Workspace).To(Equal(workspaceExportReference{Path: "root:org", ExportName: "widgets"}))
+		Expect(binding.Spec.PermissionClaims).To(Equal([]permissionClaim{
+			{Group: "", Resource: "configmaps", State: "Accepted"},
+			{Group: "", Resource: "events", State: "Accepted"},
+			{Group: "apps", Resource: "deployments", State: "Accepted"},
+		}))
+	})
+
+	It("should write the manifests", func() {
+		dir, err := os.MkdirTemp("", "manifest-gen")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		Expect(run(options{outputDir: dir, roleName: "manager-role", apiExportName: "widgets"}, []string{"./testdata/controllers"})).To(Succeed())
+
+		data, err := os.ReadFile(filepath.Join(dir, "role.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(HavePrefix(header))
+		role := &rbacv1.ClusterRole{}
+		Expect(yaml.Unmarshal(data, role)).To(Succeed())
+		Expect(role.Kind).To(Equal("ClusterRole"))
+		Expect(role.Rules).To(HaveLen(5))
+
+		data, err = os.ReadFile(filepath.Join(dir, "apibinding.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		binding := &apiBinding{}
+		Expect(yaml.Unmarshal(data, binding)).To(Succeed())
+		Expect(binding.Kind).To(Equal("APIBinding"))
+		Expect(binding.Spec.PermissionClaims).To(HaveLen(4))
+	})
+
+	It("should fail on invalid markers", func() {
+		Expect(newGenerator().loadRBACMarker("groups=apps")).NotTo(Succeed())
+	})
+})
@@ -0,0 +1,133 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+)
+
+const header = "# Code generated by manifest-gen. DO NOT EDIT.\n"
+
+// options are the options of the generated manifests.
+type options struct {
+	outputDir       string
+	roleName        string
+	apiExportName   string
+	apiExportPath   string
+	apiExportGroups []string
+}
+
+// apiBinding is a kcp APIBinding, see github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1.
+type apiBinding struct {
+	metav1.TypeMeta `json:",inline"`
+	Metadata        metav1.ObjectMeta `json:"metadata"`
+	Spec            apiBindingSpec    `json:"spec"`
+}
+
+type apiBindingSpec struct {
+	Reference        exportReference   `json:"reference"`
+	PermissionClaims []permissionClaim `json:"permissionClaims,omitempty"`
+}
+
+type exportReference struct {
+	Workspace workspaceExportReference `json:"workspace"`
+}
+
+type workspaceExportReference struct {
+	Path       string `json:"path,omitempty"`
+	ExportName string `json:"exportName"`
+}
+
+type permissionClaim struct {
+	Group    string `json:"group"`
+	Resource string `json:"resource"`
+	State    string `json:"state"`
+}
+
+// run writes the manifests of the packages matching the patterns.
+func run(opts options, patterns []string) error {
+	g := newGenerator()
+	if err := g.load(patterns...); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(opts.outputDir, 0o755); err != nil {
+		return err
+	}
+	if err := writeYAML(filepath.Join(opts.outputDir, "role.yaml"), g.clusterRole(opts.roleName)); err != nil {
+		return err
+	}
+	if opts.apiExportName == "" {
+		return nil
+	}
+	return writeYAML(filepath.Join(opts.outputDir, "apibinding.yaml"), g.apiBinding(opts))
+}
+
+// clusterRole returns the ClusterRole granting the collected permissions, with a rule per resource.
+func (g *generator) clusterRole(name string) *rbacv1.ClusterRole {
+	role := &rbacv1.ClusterRole{
+		TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRole"},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+	}
+	for _, gr := range g.groupResources() {
+		role.Rules = append(role.Rules, rbacv1.PolicyRule{
+			APIGroups: []string{gr.Group},
+			Resources: []string{gr.Resource},
+			Verbs:     g.verbs[gr].List(),
+		})
+	}
+	return role
+}
+
+// apiBinding returns the APIBinding of the APIExport, accepting the claims of the permissions
+// on the resources the APIExport doesn't serve.
+func (g *generator) apiBinding(opts options) *apiBinding {
+	binding := &apiBinding{
+		TypeMeta: metav1.TypeMeta{APIVersion: "apis.kcp.dev/v1alpha1", Kind: "APIBinding"},
+		Metadata: metav1.ObjectMeta{Name: opts.apiExportName},
+		Spec: apiBindingSpec{
+			Reference: exportReference{Workspace: workspaceExportReference{Path: opts.apiExportPath, ExportName: opts.apiExportName}},
+		},
+	}
+	exported := sets.NewString(opts.apiExportGroups...)
+	claimed := sets.NewString()
+	for _, gr := range g.groupResources() {
+		// permissions are claimed on whole resources, including their subresources.
+		resource := strings.SplitN(gr.Resource, "/", 2)[0]
+		if exported.Has(gr.Group) || claimed.Has(gr.Group+"/"+resource) {
+			continue
+		}
+		claimed.Insert(gr.Group + "/" + resource)
+		binding.Spec.PermissionClaims = append(binding.Spec.PermissionClaims, permissionClaim{Group: gr.Group, Resource: resource, State: "Accepted"})
+	}
+	return binding
+}
+
+// writeYAML writes the object as YAML to the file.
+func writeYAML(file string, obj interface{}) error {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, append([]byte(header), data...), 0o644)
+}
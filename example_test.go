@@ -121,7 +121,7 @@ type ReplicaSetReconciler struct {
 func (a *ReplicaSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	// Read the ReplicaSet
 	rs := &appsv1.ReplicaSet{}
-	err := a.Get(ctx, req.NamespacedName, rs)
+	err := a.Get(ctx, req.ObjectKey, rs)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
@@ -42,7 +42,7 @@ func (r *reconcileReplicaSet) Reconcile(ctx context.Context, request reconcile.R
 
 	// Fetch the ReplicaSet from the cache
 	rs := &appsv1.ReplicaSet{}
-	err := r.client.Get(ctx, request.NamespacedName, rs)
+	err := r.client.Get(ctx, request.ObjectKey, rs)
 	if errors.IsNotFound(err) {
 		log.Error(nil, "Could not find ReplicaSet")
 		return reconcile.Result{}, nil
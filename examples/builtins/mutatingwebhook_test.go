@@ -0,0 +1,54 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var _ = Describe("podAnnotator", func() {
+	It("should annotate the Pods without patching their logical cluster", func() {
+		decoder, err := admission.NewDecoder(scheme.Scheme)
+		Expect(err).NotTo(HaveOccurred())
+		annotator := &podAnnotator{}
+		Expect(annotator.InjectDecoder(decoder)).To(Succeed())
+
+		resp := annotator.Handle(context.Background(), admission.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				Operation: admissionv1.Create,
+				Object:    runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "foo", "namespace": "default"}}`)},
+			},
+			ClusterName: logicalcluster.New("root:org:a"),
+		})
+		Expect(resp.Allowed).To(BeTrue())
+		var paths []string
+		for _, patch := range resp.Patches {
+			paths = append(paths, patch.Path)
+		}
+		Expect(paths).To(ContainElement("/metadata/annotations"))
+		Expect(paths).NotTo(ContainElement("/metadata/clusterName"))
+	})
+})
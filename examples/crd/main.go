@@ -43,18 +43,18 @@ type reconciler struct {
 }
 
 func (r *reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	log := log.FromContext(ctx).WithValues("chaospod", req.NamespacedName)
+	log := log.FromContext(ctx).WithValues("chaospod", req.ObjectKey)
 	log.V(1).Info("reconciling chaos pod")
 
 	var chaospod api.ChaosPod
-	if err := r.Get(ctx, req.NamespacedName, &chaospod); err != nil {
+	if err := r.Get(ctx, req.ObjectKey, &chaospod); err != nil {
 		log.Error(err, "unable to get chaosctl")
 		return ctrl.Result{}, err
 	}
 
 	var pod corev1.Pod
 	podFound := true
-	if err := r.Get(ctx, req.NamespacedName, &pod); err != nil {
+	if err := r.Get(ctx, req.ObjectKey, &pod); err != nil {
 		if !apierrors.IsNotFound(err) {
 			log.Error(err, "unable to get pod")
 			return ctrl.Result{}, err
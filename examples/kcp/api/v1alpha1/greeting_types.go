@@ -0,0 +1,63 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GreetingSpec defines the desired state of Greeting.
+type GreetingSpec struct {
+	// Message is published in the greetings namespace of the workspace of the Greeting.
+	Message string `json:"message"`
+}
+
+// GreetingStatus defines the observed state of Greeting.
+type GreetingStatus struct {
+	// ConfigMap is the namespace/name of the ConfigMap the message is published in.
+	// +optional
+	ConfigMap string `json:"configMap,omitempty"`
+
+	// ObservedGeneration is the generation of the Greeting which was last published.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// Greeting is a message published by the example controller in the workspace it is created in.
+type Greeting struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GreetingSpec   `json:"spec,omitempty"`
+	Status GreetingStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// GreetingList contains a list of Greeting.
+type GreetingList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Greeting `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Greeting{}, &GreetingList{})
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/examples/kcp/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// greetingFinalizer makes the controller remove the published ConfigMap of a Greeting
+	// before the Greeting is deleted.  The ConfigMap is in another namespace, so it can't be
+	// garbage collected through an owner reference.
+	greetingFinalizer = "examples.kcp.dev/greeting"
+
+	// greetingNamespaceLabel and greetingNameLabel map a published ConfigMap back to its Greeting.
+	greetingNamespaceLabel = "examples.kcp.dev/greeting-namespace"
+	greetingNameLabel      = "examples.kcp.dev/greeting-name"
+
+	// messageKey is the key of the message in the published ConfigMaps.
+	messageKey = "message"
+)
+
+// +kubebuilder:rbac:groups=examples.kcp.dev,resources=greetings,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups=examples.kcp.dev,resources=greetings/status,verbs=update
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;delete
+
+// greeter publishes the message of each Greeting in a ConfigMap of the greetings namespace of
+// the logical cluster of the Greeting.  It reconciles the Greetings of all the logical clusters
+// with the client of a cluster-aware manager: the context passed to Reconcile targets the
+// cluster of the request, so that the client reads and writes the objects of that cluster.
+type greeter struct {
+	client    client.Client
+	namespace string
+	finalizer *controllerutil.ClusterFinalizer
+}
+
+func newGreeter(c client.Client, namespace string) *greeter {
+	return &greeter{
+		client:    c,
+		namespace: namespace,
+		finalizer: controllerutil.NewClusterFinalizer(c, greetingFinalizer),
+	}
+}
+
+// Reconcile implements reconcile.Reconciler.
+func (g *greeter) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	greeting := &v1alpha1.Greeting{}
+	if err := g.client.Get(ctx, req.ObjectKey, greeting); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	cm := &corev1.ConfigMap{}
+	cm.Namespace = g.namespace
+	cm.Name = greeting.Namespace + "." + greeting.Name
+
+	if !greeting.DeletionTimestamp.IsZero() {
+		if err := g.client.Delete(ctx, cm); client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, err
+		}
+		_, err := g.finalizer.RemoveFinalizer(ctx, greeting)
+		return ctrl.Result{}, err
+	}
+	if _, err := g.finalizer.AddFinalizer(ctx, greeting); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// The namespace is created by the discovery controller once the workspace is ready, the
+	// request is retried until then.
+	op, err := controllerutil.CreateOrUpdate(ctx, g.client, cm, func() error {
+		cm.Labels = map[string]string{
+			greetingNamespaceLabel: greeting.Namespace,
+			greetingNameLabel:      greeting.Name,
+		}
+		cm.Data = map[string]string{messageKey: greeting.Spec.Message}
+		return nil
+	})
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to publish the greeting: %w", err)
+	}
+	if op != controllerutil.OperationResultNone {
+		log.Info("Published greeting", "configMap", client.ObjectKeyFromObject(cm).String(), "operation", op)
+	}
+
+	status := v1alpha1.GreetingStatus{
+		ConfigMap:          g.namespace + "/" + cm.Name,
+		ObservedGeneration: greeting.Generation,
+	}
+	if greeting.Status == status {
+		return ctrl.Result{}, nil
+	}
+	greeting.Status = status
+	if err := g.client.Status().Update(ctx, greeting); err != nil {
+		if apierrors.IsConflict(err) {
+			return ctrl.Result{Requeue: true}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// greetingFor maps a published ConfigMap to its Greeting, so that changes to the ConfigMap
+// are reverted.  The requests are for the logical cluster of the ConfigMap, which is the one
+// of its Greeting.
+func (g *greeter) greetingFor(obj client.Object) []reconcile.Request {
+	if obj.GetNamespace() != g.namespace {
+		return nil
+	}
+	labels := obj.GetLabels()
+	if labels[greetingNameLabel] == "" {
+		return nil
+	}
+	return []reconcile.Request{{ObjectKey: client.ObjectKey{NamespacedName: types.NamespacedName{
+		Namespace: labels[greetingNamespaceLabel],
+		Name:      labels[greetingNameLabel],
+	}}}}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/kcp"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// clusterWorkspaceGVK is the kind of the workspaces of kcp.  The example doesn't depend on the
+// kcp API types, it reads the workspaces as unstructured objects.
+var clusterWorkspaceGVK = schema.GroupVersionKind{Group: "tenancy.kcp.dev", Version: "v1alpha1", Kind: "ClusterWorkspace"}
+
+// newClusterWorkspace returns an empty unstructured ClusterWorkspace.
+func newClusterWorkspace() *unstructured.Unstructured {
+	ws := &unstructured.Unstructured{}
+	ws.SetGroupVersionKind(clusterWorkspaceGVK)
+	return ws
+}
+
+// +kubebuilder:rbac:groups=tenancy.kcp.dev,resources=clusterworkspaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;create
+
+// discovery discovers the logical clusters from the ClusterWorkspaces of their parents, and
+// prepares them for the greeting controller once they are ready: it creates the namespace the
+// Greetings are published in.  The ClusterWorkspace lives in the parent logical cluster, the
+// namespace is created in the logical cluster of the workspace, by passing the client a
+// context targeting it.
+type discovery struct {
+	client    client.Client
+	namespace string
+}
+
+// Reconcile implements reconcile.Reconciler.  The context targets the logical cluster of the
+// ClusterWorkspace.
+func (d *discovery) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ws := newClusterWorkspace()
+	if err := d.client.Get(ctx, req.ObjectKey, ws); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if phase, _, _ := unstructured.NestedString(ws.Object, "status", "phase"); phase != "Ready" {
+		return ctrl.Result{}, nil
+	}
+
+	cluster := req.Cluster.Join(ws.GetName())
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: d.namespace}}
+	if err := d.client.Create(kcp.WithCluster(ctx, cluster), ns); err != nil && !apierrors.IsAlreadyExists(err) {
+		return ctrl.Result{}, err
+	}
+	log.FromContext(ctx).V(1).Info("Discovered workspace", "workspace", cluster.String())
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	kcpenvtest "sigs.k8s.io/controller-runtime/pkg/envtest/kcp"
+	"sigs.k8s.io/controller-runtime/pkg/envtest/printer"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestKCP(t *testing.T) {
+	RegisterFailHandler(Fail)
+	suiteName := "kcp Example Suite"
+	RunSpecsWithDefaultAndCustomReporters(t, suiteName, []Reporter{printer.NewlineReporter{}, printer.NewProwReporter(suiteName)})
+}
+
+// workspaces are the logical clusters the controllers are tested against.
+var workspaces = []logicalcluster.Name{
+	logicalcluster.New("root:example:a"),
+	logicalcluster.New("root:example:b"),
+}
+
+var testenv *kcpenvtest.Environment
+
+var _ = BeforeSuite(func() {
+	logf.SetLogger(zap.New(zap.WriteTo(GinkgoWriter), zap.UseDevMode(true)))
+
+	testenv = &kcpenvtest.Environment{Workspaces: workspaces}
+	_, err := testenv.Start()
+	Expect(err).NotTo(HaveOccurred())
+
+	// The Greeting API is served in each workspace by its own CRD.
+	for _, ws := range workspaces {
+		_, err := envtest.InstallCRDs(testenv.ConfigFor(ws), envtest.CRDInstallOptions{
+			Paths: []string{filepath.Join("config", "crd")},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	}
+}, 120)
+
+var _ = AfterSuite(func() {
+	Expect(testenv.Stop()).To(Succeed())
+})
@@ -0,0 +1,152 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/controller-runtime/examples/kcp/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/kcp"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+var _ = Describe("kcp example", func() {
+	var (
+		ctx     context.Context
+		cancel  context.CancelFunc
+		done    chan struct{}
+		clients map[logicalcluster.Name]client.Client
+	)
+
+	// publishedFor returns the message published for the Greeting in its workspace.
+	publishedFor := func(ws logicalcluster.Name, greeting *v1alpha1.Greeting) func() (string, error) {
+		return func() (string, error) {
+			cm := &corev1.ConfigMap{}
+			key := types.NamespacedName{Namespace: "greetings", Name: greeting.Namespace + "." + greeting.Name}
+			err := clients[ws].Get(ctx, client.ObjectKey{NamespacedName: key}, cm)
+			return cm.Data[messageKey], err
+		}
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+
+		mgr, err := kcp.NewClusterAwareManager(testenv.Config, manager.Options{MetricsBindAddress: "0"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(setupWithManager(mgr, "greetings")).To(Succeed())
+
+		clients = map[logicalcluster.Name]client.Client{}
+		for _, ws := range workspaces {
+			clients[ws], err = client.New(testenv.ConfigFor(ws), client.Options{Scheme: mgr.GetScheme()})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		done = make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(mgr.Start(ctx)).To(Succeed())
+		}()
+	})
+
+	AfterEach(func() {
+		for ws, c := range clients {
+			Expect(c.DeleteAllOf(context.Background(), &v1alpha1.Greeting{}, client.InNamespace("default"))).To(Succeed(), "workspace %s", ws)
+		}
+		// Let the controllers remove their finalizers before stopping them.
+		for _, c := range clients {
+			Eventually(func() ([]v1alpha1.Greeting, error) {
+				list := &v1alpha1.GreetingList{}
+				err := c.List(context.Background(), list)
+				return list.Items, err
+			}, 30).Should(BeEmpty())
+		}
+		cancel()
+		Eventually(done, 30).Should(BeClosed())
+	})
+
+	It("should create the greetings namespace in the discovered workspaces", func() {
+		for _, ws := range workspaces {
+			Eventually(func() error {
+				return clients[ws].Get(ctx, client.ObjectKey{NamespacedName: types.NamespacedName{Name: "greetings"}}, &corev1.Namespace{})
+			}, 30).Should(Succeed(), "workspace %s", ws)
+		}
+	})
+
+	It("should publish the Greetings with the same name in their own workspace", func() {
+		greetings := map[logicalcluster.Name]*v1alpha1.Greeting{}
+		for _, ws := range workspaces {
+			greetings[ws] = &v1alpha1.Greeting{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "hello"},
+				Spec:       v1alpha1.GreetingSpec{Message: "hello from " + ws.String()},
+			}
+			Expect(clients[ws].Create(ctx, greetings[ws])).To(Succeed())
+		}
+
+		for ws, greeting := range greetings {
+			Eventually(publishedFor(ws, greeting), 30).Should(Equal("hello from "+ws.String()), "workspace %s", ws)
+
+			By("reporting the published ConfigMap in the status of the Greeting")
+			Eventually(func() (v1alpha1.GreetingStatus, error) {
+				err := clients[ws].Get(ctx, client.ObjectKeyFromObject(greeting), greeting)
+				return greeting.Status, err
+			}, 30).Should(Equal(v1alpha1.GreetingStatus{ConfigMap: "greetings/default.hello", ObservedGeneration: greeting.Generation}))
+			Expect(greeting.Finalizers).To(ContainElement(greetingFinalizer))
+		}
+
+		By("republishing a Greeting once its message changes")
+		a := workspaces[0]
+		greetings[a].Spec.Message = "hello again"
+		Expect(clients[a].Update(ctx, greetings[a])).To(Succeed())
+		Eventually(publishedFor(a, greetings[a]), 30).Should(Equal("hello again"))
+		Consistently(publishedFor(workspaces[1], greetings[workspaces[1]]), 2).Should(Equal("hello from " + workspaces[1].String()))
+	})
+
+	It("should unpublish a Greeting once it is deleted, before removing its finalizer", func() {
+		a, b := workspaces[0], workspaces[1]
+		for _, ws := range workspaces {
+			greeting := &v1alpha1.Greeting{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "goodbye"},
+				Spec:       v1alpha1.GreetingSpec{Message: "goodbye"},
+			}
+			Expect(clients[ws].Create(ctx, greeting)).To(Succeed())
+			Eventually(publishedFor(ws, greeting), 30).Should(Equal("goodbye"))
+		}
+
+		greeting := &v1alpha1.Greeting{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "goodbye"}}
+		Expect(clients[a].Delete(ctx, greeting)).To(Succeed())
+		Eventually(func() bool {
+			_, err := publishedFor(a, greeting)()
+			return apierrors.IsNotFound(err)
+		}, 30).Should(BeTrue())
+		Eventually(func() bool {
+			return apierrors.IsNotFound(clients[a].Get(ctx, client.ObjectKeyFromObject(greeting), greeting))
+		}, 30).Should(BeTrue())
+
+		By("leaving the Greeting of the other workspace alone")
+		Consistently(publishedFor(b, greeting), 2).Should(Equal("goodbye"))
+	})
+})
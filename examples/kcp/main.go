@@ -1,5 +1,5 @@
 /*
-Copyright 2022 The KCP Authors.
+Copyright 2022 The Kubernetes Authors.
 
 Licensed under the Apache License, Version 2.0 (the "License");
 you may not use this file except in compliance with the License.
@@ -14,113 +14,83 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
+// The kcp example runs a controller against all the logical clusters of a kcp server at once, with
+// a cluster-aware manager: it publishes the message of each Greeting in a ConfigMap of the
+// workspace of the Greeting.
 package main
 
 import (
-	"context"
-	"fmt"
+	"flag"
 	"os"
 
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 
-	kcpcache "github.com/kcp-dev/apimachinery/pkg/cache"
-	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
-	"github.com/kcp-dev/logicalcluster"
-
-	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
-	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
-
-	"sigs.k8s.io/controller-runtime/pkg/cache"
-	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/cluster"
-	"sigs.k8s.io/controller-runtime/pkg/controller"
-
+	"sigs.k8s.io/controller-runtime/examples/kcp/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/kcp"
-	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
-var (
-	setupLog = ctrl.Log.WithName("setup")
-)
-
-type reconciler struct {
-	client.Client
-	scheme *runtime.Scheme
-}
-
-func (r *reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	log := log.FromContext(ctx).WithValues("cluster", req.ObjectKey.Cluster.String())
-
-	ctx = kcpclient.WithCluster(ctx, req.ObjectKey.Cluster)
-
-	var configmap corev1.ConfigMap
-	if err := r.Get(ctx, req.ObjectKey, &configmap); err != nil {
-		log.Error(err, "unable to get configmap")
-		return ctrl.Result{}, err
-	}
-	log.Info("Retrieved configmap")
-
-	return ctrl.Result{}, nil
-}
+var setupLog = ctrl.Log.WithName("setup")
 
 func main() {
-
-	ctrl.SetLogger(zap.New())
-
-	cfg := ctrl.GetConfigOrDie()
-	fmt.Println(cfg.Host)
-	httpClient, err := rest.HTTPClientFor(cfg)
+	var namespace string
+	flag.StringVar(&namespace, "greetings-namespace", "greetings",
+		"The namespace of each workspace the Greetings are published in.")
+	opts := zap.Options{}
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+
+	// The config targets the kcp server, the manager watches all the logical clusters the
+	// config has access to.
+	mgr, err := kcp.NewClusterAwareManager(ctrl.GetConfigOrDie(), ctrl.Options{})
 	if err != nil {
-		setupLog.Error(err, "unable to build http client")
+		setupLog.Error(err, "unable to create manager")
 		os.Exit(1)
 	}
-	clusterRoundTripper := kcpclient.NewClusterRoundTripper(httpClient.Transport)
-	httpClient.Transport = clusterRoundTripper
-	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
-		LeaderElection: false,
-		NewCache: func(config *rest.Config, opts cache.Options) (cache.Cache, error) {
-			c := rest.CopyConfig(config)
-			c.Host += "/clusters/*"
-			opts.KeyFunction = kcpcache.ClusterAwareKeyFunc
-			return cache.New(c, opts)
-		},
-		NewClient: func(cache cache.Cache, config *rest.Config, opts client.Options, uncachedObjects ...client.Object) (client.Client, error) {
-			opts.HTTPClient = httpClient
-			return cluster.DefaultNewClient(cache, config, opts, uncachedObjects...)
-		},
-	})
-	if err != nil {
-		setupLog.Error(err, "unable to start manager")
+	if err := setupWithManager(mgr, namespace); err != nil {
+		setupLog.Error(err, "unable to set up controllers")
 		os.Exit(1)
 	}
 
-	err = corev1.AddToScheme(mgr.GetScheme())
-	if err != nil {
-		setupLog.Error(err, "unable to add scheme")
+	setupLog.Info("starting manager")
+	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
+}
 
-	c, err := controller.New("kcp-controller", mgr, controller.Options{
-		Reconciler: &reconciler{
-			Client: mgr.GetClient(),
-			scheme: mgr.GetScheme(),
-		}})
-	if err != nil {
-		setupLog.Error(err, "unable to set up individual controller")
-		os.Exit(1)
-	}
-	if err := c.Watch(&source.Kind{Type: &corev1.ConfigMap{}}, &kcp.EnqueueRequestForObject{}); err != nil {
-		setupLog.Error(err, "unable to watch configmaps")
-		os.Exit(1)
+// setupWithManager wires the controllers of the example into a cluster-aware manager:
+//
+//  1. the discovery controller watches the ClusterWorkspaces of all the logical clusters, and
+//     creates the greetings namespace in each workspace once it is ready;
+//  2. the greeting controller watches the Greetings of all the logical clusters, publishes
+//     them in the greetings namespace of their workspace, and reports the ConfigMap in their
+//     status.  It watches the published ConfigMaps to revert their changes.
+//
+// Both controllers enqueue requests carrying the logical cluster of their object, and their
+// reconcilers are passed a context targeting it.
+func setupWithManager(mgr manager.Manager, namespace string) error {
+	if err := v1alpha1.AddToScheme(mgr.GetScheme()); err != nil {
+		return err
 	}
 
-	setupLog.Info("starting manager")
-	if err := mgr.Start(kcpclient.WithCluster(ctrl.SetupSignalHandler(), logicalcluster.Wildcard)); err != nil {
-		setupLog.Error(err, "problem running manager")
-		os.Exit(1)
+	if err := builder.ControllerManagedBy(mgr).
+		Named("workspace-discovery").
+		For(newClusterWorkspace()).
+		Complete(&discovery{client: mgr.GetClient(), namespace: namespace}); err != nil {
+		return err
 	}
+
+	g := newGreeter(mgr.GetClient(), namespace)
+	return builder.ControllerManagedBy(mgr).
+		Named("greeting").
+		For(&v1alpha1.Greeting{}).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(g.greetingFor)).
+		Complete(g)
 }
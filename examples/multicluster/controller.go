@@ -0,0 +1,155 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kcp-dev/logicalcluster"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// clusterAnnotation is set by the annotator on the ConfigMaps of each member cluster.
+const clusterAnnotation = "multicluster.example.com/cluster"
+
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;update;patch
+
+// annotator annotates the ConfigMaps of a member cluster with the name of the cluster.  There
+// is one annotator per member cluster, reading from the cache of the cluster and writing with
+// its client.
+type annotator struct {
+	cluster logicalcluster.Name
+	client  client.Client
+}
+
+// Reconcile implements reconcile.Reconciler.
+func (a *annotator) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	cm := &corev1.ConfigMap{}
+	if err := a.client.Get(ctx, req.ObjectKey, cm); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if cm.Annotations[clusterAnnotation] == a.cluster.String() {
+		return ctrl.Result{}, nil
+	}
+
+	if cm.Annotations == nil {
+		cm.Annotations = map[string]string{}
+	}
+	cm.Annotations[clusterAnnotation] = a.cluster.String()
+	if err := a.client.Update(ctx, cm); err != nil {
+		if apierrors.IsConflict(err) {
+			return ctrl.Result{Requeue: true}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	log.Info("Annotated ConfigMap")
+	return ctrl.Result{}, nil
+}
+
+// clusterControllers runs an annotator controller for each cluster of a ClusterSet, as long
+// as the cluster is part of the set.  It is a Runnable, so that the controllers are only
+// started once the manager is, and a ClusterSetHandler.
+type clusterControllers struct {
+	mgr manager.Manager
+
+	mu      sync.Mutex
+	ctx     context.Context
+	pending map[logicalcluster.Name]controller.Controller
+	running map[logicalcluster.Name]context.CancelFunc
+}
+
+var _ cluster.ClusterSetHandler = &clusterControllers{}
+
+func newClusterControllers(mgr manager.Manager) *clusterControllers {
+	return &clusterControllers{
+		mgr:     mgr,
+		pending: map[logicalcluster.Name]controller.Controller{},
+		running: map[logicalcluster.Name]context.CancelFunc{},
+	}
+}
+
+// ClusterAdded implements cluster.ClusterSetHandler.
+func (c *clusterControllers) ClusterAdded(name logicalcluster.Name, cl cluster.Cluster) {
+	// Requests are dispatched to the controller of their cluster, they don't need to carry it.
+	ctl, err := controller.NewUnmanaged("configmap-annotator-"+name.String(), c.mgr, controller.Options{
+		Reconciler: &annotator{cluster: name, client: cl.GetClient()},
+	})
+	if err == nil {
+		err = ctl.Watch(source.NewKindWithCache(&corev1.ConfigMap{}, cl.GetCache()), &handler.EnqueueRequestForObject{})
+	}
+	if err != nil {
+		setupLog.Error(err, "unable to set up the controller of a cluster", "cluster", name.String())
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ctx == nil {
+		c.pending[name] = ctl
+		return
+	}
+	c.start(name, ctl)
+}
+
+// ClusterRemoved implements cluster.ClusterSetHandler.
+func (c *clusterControllers) ClusterRemoved(name logicalcluster.Name) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, name)
+	if cancel, ok := c.running[name]; ok {
+		cancel()
+		delete(c.running, name)
+	}
+}
+
+// Start implements manager.Runnable.
+func (c *clusterControllers) Start(ctx context.Context) error {
+	c.mu.Lock()
+	c.ctx = ctx
+	for name, ctl := range c.pending {
+		c.start(name, ctl)
+	}
+	c.pending = nil
+	c.mu.Unlock()
+
+	<-ctx.Done()
+	return nil
+}
+
+// start starts the controller of a cluster.  c.mu must be held.
+func (c *clusterControllers) start(name logicalcluster.Name, ctl controller.Controller) {
+	ctx, cancel := context.WithCancel(c.ctx)
+	c.running[name] = cancel
+	go func() {
+		if err := ctl.Start(ctx); err != nil {
+			setupLog.Error(err, "controller of a cluster stopped with an error", "cluster", name.String())
+		}
+	}()
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kcp-dev/logicalcluster"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// kubeconfigLabel marks the Secrets holding the kubeconfig of a member cluster.
+	kubeconfigLabel = "multicluster.example.com/kubeconfig"
+	// kubeconfigKey is the key of the kubeconfig in the Secrets.
+	kubeconfigKey = "kubeconfig"
+)
+
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+
+// discovery keeps the ClusterSet in sync with the kubeconfig Secrets of a namespace of the
+// host cluster: each Secret labelled with kubeconfigLabel is a member cluster, named after
+// the Secret.  The config of a cluster is read when it is added: changing the kubeconfig of a
+// cluster requires removing its Secret and creating it again.
+type discovery struct {
+	// reader reads the Secrets from the host cluster, bypassing the cache so that the
+	// kubeconfigs aren't kept in memory.
+	reader    client.Reader
+	namespace string
+	clusters  *cluster.ClusterSet
+}
+
+// Reconcile implements reconcile.Reconciler.  All the Secrets are listed whatever the
+// request, so that removed Secrets remove their cluster.
+func (d *discovery) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl.Result, error) {
+	secrets := &corev1.SecretList{}
+	if err := d.reader.List(ctx, secrets, client.InNamespace(d.namespace), client.HasLabels{kubeconfigLabel}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	names := make([]logicalcluster.Name, 0, len(secrets.Items))
+	for _, secret := range secrets.Items {
+		names = append(names, logicalcluster.New(secret.Name))
+	}
+	log.FromContext(ctx).Info("Syncing clusters", "clusters", names)
+	return ctrl.Result{}, d.clusters.Sync(names)
+}
+
+// clusterConfig is the ClusterConfigFunc of the ClusterSet, it reads the config of a member
+// cluster from its kubeconfig Secret.
+func (d *discovery) clusterConfig(_ *rest.Config, clusterName string) (*rest.Config, error) {
+	secret := &corev1.Secret{}
+	key := client.ObjectKey{}
+	key.Namespace, key.Name = d.namespace, clusterName
+	if err := d.reader.Get(context.Background(), key, secret); err != nil {
+		return nil, err
+	}
+	kubeconfig, ok := secret.Data[kubeconfigKey]
+	if !ok {
+		return nil, fmt.Errorf("secret %s/%s has no %q key", d.namespace, clusterName, kubeconfigKey)
+	}
+	return clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+}
+
+// isKubeconfig filters the events of the discovery controller.
+func (d *discovery) isKubeconfig(obj client.Object) bool {
+	_, ok := obj.GetLabels()[kubeconfigLabel]
+	return ok && obj.GetNamespace() == d.namespace
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"os"
+
+	kcpcache "github.com/kcp-dev/apimachinery/pkg/cache"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+var setupLog = ctrl.Log.WithName("setup")
+
+func main() {
+	var namespace string
+	flag.StringVar(&namespace, "kubeconfig-namespace", "default",
+		"The namespace of the host cluster holding the kubeconfig Secrets of the member clusters.")
+	opts := zap.Options{}
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{})
+	if err != nil {
+		setupLog.Error(err, "unable to create manager")
+		os.Exit(1)
+	}
+	if err := setupWithManager(mgr, namespace); err != nil {
+		setupLog.Error(err, "unable to set up controllers")
+		os.Exit(1)
+	}
+
+	setupLog.Info("starting manager")
+	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+		setupLog.Error(err, "problem running manager")
+		os.Exit(1)
+	}
+}
+
+// setupWithManager wires the multi-cluster controllers into the manager of the host cluster:
+//
+//  1. a ClusterSet, started by the manager, holds a Cluster with its own cache and client
+//     for each member cluster;
+//  2. the discovery controller watches the kubeconfig Secrets of the host cluster and syncs
+//     the ClusterSet with them, reading the config of each member from its Secret;
+//  3. an annotator controller is run for each member cluster while it is part of the set.
+func setupWithManager(mgr manager.Manager, namespace string) error {
+	d := &discovery{reader: mgr.GetAPIReader(), namespace: namespace}
+	clusters, err := cluster.NewClusterSet(mgr.GetConfig(), cluster.ClusterSetOptions{ClusterConfig: d.clusterConfig}, func(o *cluster.Options) {
+		o.Scheme = mgr.GetScheme()
+		o.Logger = mgr.GetLogger()
+		o.NewCache = newMemberCache
+	})
+	if err != nil {
+		return err
+	}
+	d.clusters = clusters
+	if err := mgr.Add(clusters); err != nil {
+		return err
+	}
+
+	controllers := newClusterControllers(mgr)
+	clusters.AddHandler(controllers)
+	if err := mgr.Add(controllers); err != nil {
+		return err
+	}
+
+	return builder.ControllerManagedBy(mgr).
+		Named("cluster-discovery").
+		For(&corev1.Secret{}, builder.WithPredicates(predicate.NewPredicateFuncs(d.isKubeconfig))).
+		Complete(d)
+}
+
+// newMemberCache builds the cache of a member cluster.  The cache readers key objects by
+// logical cluster, so the store of the informers must be keyed the same way, even though the
+// objects of the member clusters don't belong to any logical cluster.
+func newMemberCache(config *rest.Config, opts cache.Options) (cache.Cache, error) {
+	opts.KeyFunction = kcpcache.ClusterAwareKeyFunc
+	return cache.New(config, opts)
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// kubeconfigFor returns a kubeconfig with the server and credentials of the config.
+func kubeconfigFor(config *rest.Config) []byte {
+	kubeconfig := clientcmdapi.NewConfig()
+	kubeconfig.Clusters["member"] = &clientcmdapi.Cluster{
+		Server:                   config.Host,
+		CertificateAuthorityData: config.CAData,
+	}
+	kubeconfig.AuthInfos["member"] = &clientcmdapi.AuthInfo{
+		ClientCertificateData: config.CertData,
+		ClientKeyData:         config.KeyData,
+		Token:                 config.BearerToken,
+	}
+	kubeconfig.Contexts["member"] = &clientcmdapi.Context{Cluster: "member", AuthInfo: "member"}
+	kubeconfig.CurrentContext = "member"
+	data, err := clientcmd.Write(*kubeconfig)
+	Expect(err).NotTo(HaveOccurred())
+	return data
+}
+
+var _ = Describe("multi-cluster example", func() {
+	var (
+		ctx     context.Context
+		cancel  context.CancelFunc
+		c       client.Client
+		done    chan struct{}
+		secrets []client.Object
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+
+		var err error
+		c, err = client.New(cfg, client.Options{})
+		Expect(err).NotTo(HaveOccurred())
+
+		mgr, err := manager.New(cfg, manager.Options{MetricsBindAddress: "0"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(setupWithManager(mgr, "default")).To(Succeed())
+
+		done = make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(mgr.Start(ctx)).To(Succeed())
+		}()
+	})
+
+	AfterEach(func() {
+		for _, secret := range secrets {
+			Expect(client.IgnoreNotFound(c.Delete(context.Background(), secret))).To(Succeed())
+		}
+		secrets = nil
+		cancel()
+		Eventually(done, 30).Should(BeClosed())
+	})
+
+	It("should annotate the ConfigMaps of the clusters discovered from the kubeconfig Secrets", func() {
+		// The member cluster is the envtest cluster itself, reached through its kubeconfig.
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      "member-a",
+				Labels:    map[string]string{kubeconfigLabel: "true"},
+			},
+			Data: map[string][]byte{kubeconfigKey: kubeconfigFor(cfg)},
+		}
+		Expect(c.Create(ctx, secret)).To(Succeed())
+		secrets = append(secrets, secret)
+
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "annotate-me"},
+			Data:       map[string]string{"foo": "bar"},
+		}
+		Expect(c.Create(ctx, cm)).To(Succeed())
+		defer func() {
+			Expect(c.Delete(context.Background(), cm)).To(Succeed())
+		}()
+
+		Eventually(func() (string, error) {
+			err := c.Get(ctx, client.ObjectKeyFromObject(cm), cm)
+			return cm.Annotations[clusterAnnotation], err
+		}, 30).Should(Equal("member-a"))
+	})
+
+	It("should ignore the Secrets which are not labelled as kubeconfigs", func() {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "not-a-member"},
+			Data:       map[string][]byte{kubeconfigKey: kubeconfigFor(cfg)},
+		}
+		Expect(c.Create(ctx, secret)).To(Succeed())
+		secrets = append(secrets, secret)
+
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "leave-me-alone"}}
+		Expect(c.Create(ctx, cm)).To(Succeed())
+		defer func() {
+			Expect(c.Delete(context.Background(), cm)).To(Succeed())
+		}()
+
+		Consistently(func() (map[string]string, error) {
+			err := c.Get(ctx, client.ObjectKeyFromObject(cm), cm)
+			return cm.Annotations, err
+		}, 2).ShouldNot(HaveKey(clusterAnnotation))
+	})
+})
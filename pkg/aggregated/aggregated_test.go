@@ -0,0 +1,300 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregated
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/internal/testing/certs"
+)
+
+// reviewClient answers the reviews it creates with the given functions.
+type reviewClient struct {
+	client.Client
+	tokenReview  func(ctx context.Context, review *authenticationv1.TokenReview)
+	accessReview func(ctx context.Context, review *authorizationv1.SubjectAccessReview)
+}
+
+func (c *reviewClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	switch review := obj.(type) {
+	case *authenticationv1.TokenReview:
+		c.tokenReview(ctx, review)
+		return nil
+	case *authorizationv1.SubjectAccessReview:
+		c.accessReview(ctx, review)
+		return nil
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+var _ = Describe("Aggregated API server", func() {
+	DescribeTable("request attributes",
+		func(method, target string, expected Attributes) {
+			Expect(RequestAttributes(httptest.NewRequest(method, target, nil))).To(Equal(expected))
+		},
+		Entry("a non-resource request", http.MethodGet, "/healthz",
+			Attributes{Verb: "get", Path: "/healthz"}),
+		Entry("the discovery of a group version", http.MethodGet, "/apis/metrics.example.com/v1",
+			Attributes{Verb: "get", Path: "/apis/metrics.example.com/v1", APIGroup: "metrics.example.com", APIVersion: "v1"}),
+		Entry("a list of namespaced resources", http.MethodGet, "/apis/metrics.example.com/v1/namespaces/default/pods",
+			Attributes{Verb: "list", Path: "/apis/metrics.example.com/v1/namespaces/default/pods", APIGroup: "metrics.example.com", APIVersion: "v1",
+				Namespace: "default", Resource: "pods", ResourceRequest: true}),
+		Entry("a watch of resources", http.MethodGet, "/apis/metrics.example.com/v1/nodes?watch=true",
+			Attributes{Verb: "watch", Path: "/apis/metrics.example.com/v1/nodes", APIGroup: "metrics.example.com", APIVersion: "v1",
+				Resource: "nodes", ResourceRequest: true}),
+		Entry("the subresource of a core resource", http.MethodPut, "/api/v1/namespaces/default/pods/foo/status",
+			Attributes{Verb: "update", Path: "/api/v1/namespaces/default/pods/foo/status", APIVersion: "v1",
+				Namespace: "default", Resource: "pods", Name: "foo", Subresource: "status", ResourceRequest: true}),
+		Entry("a namespace", http.MethodDelete, "/api/v1/namespaces/default",
+			Attributes{Verb: "delete", Path: "/api/v1/namespaces/default", APIVersion: "v1",
+				Resource: "namespaces", Name: "default", ResourceRequest: true}),
+		Entry("a collection", http.MethodDelete, "/apis/metrics.example.com/v1/nodes",
+			Attributes{Verb: "deletecollection", Path: "/apis/metrics.example.com/v1/nodes", APIGroup: "metrics.example.com", APIVersion: "v1",
+				Resource: "nodes", ResourceRequest: true}),
+		Entry("a request to a logical cluster", http.MethodGet, "/clusters/root:org/apis/metrics.example.com/v1/nodes/foo",
+			Attributes{Cluster: logicalcluster.New("root:org"), Verb: "get", Path: "/apis/metrics.example.com/v1/nodes/foo",
+				APIGroup: "metrics.example.com", APIVersion: "v1", Resource: "nodes", Name: "foo", ResourceRequest: true}),
+	)
+
+	Describe("request header authentication", func() {
+		var (
+			ca     *certs.TinyCA
+			config *RequestHeaderConfig
+		)
+
+		BeforeEach(func() {
+			var err error
+			ca, err = certs.NewTinyCA()
+			Expect(err).NotTo(HaveOccurred())
+			caData := string(ca.CA.CertBytes())
+
+			c := fake.NewClientBuilder().WithObjects(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "extension-apiserver-authentication"},
+				Data: map[string]string{
+					"requestheader-client-ca-file":       caData,
+					"requestheader-allowed-names":        `["front-proxy-client"]`,
+					"requestheader-username-headers":     `["X-Remote-User"]`,
+					"requestheader-group-headers":        `["X-Remote-Group"]`,
+					"requestheader-extra-headers-prefix": `["X-Remote-Extra-"]`,
+				},
+			}).Build()
+			config, err = LoadRequestHeaderConfig(context.Background(), c)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config).NotTo(BeNil())
+			Expect(config.AllowedNames).To(Equal([]string{"front-proxy-client"}))
+		})
+
+		request := func(name string) *http.Request {
+			cert, err := ca.NewClientCert(certs.ClientInfo{Name: name})
+			Expect(err).NotTo(HaveOccurred())
+			r := httptest.NewRequest(http.MethodGet, "/apis/metrics.example.com/v1/nodes", nil)
+			r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert.Cert}}
+			r.Header.Set("X-Remote-User", "alice")
+			r.Header.Add("X-Remote-Group", "admins")
+			r.Header.Add("X-Remote-Group", "system:authenticated")
+			r.Header.Set("X-Remote-Extra-Scopes", "read")
+			return r
+		}
+
+		It("should authenticate the user proxied by an allowed client", func() {
+			user, ok, err := config.Authenticate(request("front-proxy-client"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(user.Username).To(Equal("alice"))
+			Expect(user.Groups).To(Equal([]string{"admins", "system:authenticated"}))
+			Expect(user.Extra).To(HaveKeyWithValue("scopes", authenticationv1.ExtraValue{"read"}))
+		})
+
+		It("should reject the requests proxied by another client", func() {
+			_, ok, err := config.Authenticate(request("someone-else"))
+			Expect(err).To(HaveOccurred())
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should reject client certificates of another CA", func() {
+			other, err := certs.NewTinyCA()
+			Expect(err).NotTo(HaveOccurred())
+			cert, err := other.NewClientCert(certs.ClientInfo{Name: "front-proxy-client"})
+			Expect(err).NotTo(HaveOccurred())
+			r := request("front-proxy-client")
+			r.TLS.PeerCertificates = []*x509.Certificate{cert.Cert}
+
+			_, ok, err := config.Authenticate(r)
+			Expect(err).To(HaveOccurred())
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should not authenticate requests without a client certificate", func() {
+			r := request("front-proxy-client")
+			r.TLS = nil
+			_, ok, err := config.Authenticate(r)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("serving", func() {
+		var (
+			srv      *Server
+			reviewed []*authorizationv1.SubjectAccessReview
+			served   *http.Request
+		)
+
+		BeforeEach(func() {
+			reviewed, served = nil, nil
+			c := &reviewClient{
+				Client: fake.NewClientBuilder().Build(),
+				tokenReview: func(_ context.Context, review *authenticationv1.TokenReview) {
+					if review.Spec.Token == "alice-token" {
+						review.Status.Authenticated = true
+						review.Status.User = authenticationv1.UserInfo{Username: "alice"}
+					}
+				},
+				accessReview: func(ctx context.Context, review *authorizationv1.SubjectAccessReview) {
+					cluster, _ := kcpclient.ClusterFromContext(ctx)
+					review.Status.Allowed = cluster == logicalcluster.New("root:org") && review.Spec.ResourceAttributes.Verb == "get"
+					reviewed = append(reviewed, review)
+				},
+			}
+			srv = &Server{
+				Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					served = r
+					w.WriteHeader(http.StatusOK)
+				}),
+				Authenticator: &TokenReviewAuthenticator{Client: c},
+				Authorizer:    &SubjectAccessReviewAuthorizer{Client: c},
+			}
+			srv.setDefaults()
+		})
+
+		serve := func(method, target, token string) *httptest.ResponseRecorder {
+			r := httptest.NewRequest(method, target, nil)
+			if token != "" {
+				r.Header.Set("Authorization", "Bearer "+token)
+			}
+			w := httptest.NewRecorder()
+			srv.handler().ServeHTTP(w, r)
+			return w
+		}
+
+		It("should serve the authorized requests in their logical cluster", func() {
+			w := serve(http.MethodGet, "/clusters/root:org/apis/metrics.example.com/v1/nodes/foo", "alice-token")
+			Expect(w.Code).To(Equal(http.StatusOK))
+
+			Expect(served).NotTo(BeNil())
+			Expect(served.URL.Path).To(Equal("/apis/metrics.example.com/v1/nodes/foo"))
+			cluster, ok := kcpclient.ClusterFromContext(served.Context())
+			Expect(ok).To(BeTrue())
+			Expect(cluster).To(Equal(logicalcluster.New("root:org")))
+			user, ok := UserFrom(served.Context())
+			Expect(ok).To(BeTrue())
+			Expect(user.Username).To(Equal("alice"))
+			attrs, ok := AttributesFrom(served.Context())
+			Expect(ok).To(BeTrue())
+			Expect(attrs.Resource).To(Equal("nodes"))
+
+			Expect(reviewed).To(HaveLen(1))
+			Expect(reviewed[0].Spec.User).To(Equal("alice"))
+			Expect(reviewed[0].Spec.ResourceAttributes.Name).To(Equal("foo"))
+		})
+
+		It("should reject the unauthenticated requests", func() {
+			w := serve(http.MethodGet, "/clusters/root:org/apis/metrics.example.com/v1/nodes/foo", "bob-token")
+			Expect(w.Code).To(Equal(http.StatusUnauthorized))
+			Expect(served).To(BeNil())
+
+			status := &metav1.Status{}
+			Expect(json.NewDecoder(w.Body).Decode(status)).To(Succeed())
+			Expect(status.Reason).To(Equal(metav1.StatusReasonUnauthorized))
+		})
+
+		It("should forbid the unauthorized requests", func() {
+			w := serve(http.MethodDelete, "/clusters/root:org/apis/metrics.example.com/v1/nodes/foo", "alice-token")
+			Expect(w.Code).To(Equal(http.StatusForbidden))
+			Expect(served).To(BeNil())
+
+			w = serve(http.MethodGet, "/clusters/root:other/apis/metrics.example.com/v1/nodes/foo", "alice-token")
+			Expect(w.Code).To(Equal(http.StatusForbidden))
+			Expect(served).To(BeNil())
+		})
+
+		It("should always allow the health checks", func() {
+			w := serve(http.MethodGet, "/healthz", "")
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(served).NotTo(BeNil())
+		})
+	})
+
+	Describe("APIService registration", func() {
+		It("should create and update the APIService of a group version", func() {
+			c := fake.NewClientBuilder().Build()
+			apiService := APIService{
+				Group: "metrics.example.com", Version: "v1",
+				ServiceNamespace: "system", ServiceName: "metrics-server",
+				CABundle: []byte("ca"),
+			}
+			Expect(apiService.Register(context.Background(), c)).To(Succeed())
+
+			obj := &unstructured.Unstructured{}
+			obj.SetGroupVersionKind(apiServiceGVK)
+			Expect(c.Get(context.Background(), client.ObjectKey{NamespacedName: types.NamespacedName{Name: "v1.metrics.example.com"}}, obj)).To(Succeed())
+			Expect(obj.Object["spec"]).To(Equal(map[string]interface{}{
+				"group":                "metrics.example.com",
+				"version":              "v1",
+				"groupPriorityMinimum": int64(1000),
+				"versionPriority":      int64(15),
+				"caBundle":             "Y2E=",
+				"service": map[string]interface{}{
+					"namespace": "system",
+					"name":      "metrics-server",
+					"port":      int64(443),
+				},
+			}))
+
+			apiService.ServicePort = 8443
+			Expect(apiService.Register(context.Background(), c)).To(Succeed())
+			Expect(c.Get(context.Background(), client.ObjectKey{NamespacedName: types.NamespacedName{Name: "v1.metrics.example.com"}}, obj)).To(Succeed())
+			port, _, _ := unstructured.NestedInt64(obj.Object, "spec", "service", "port")
+			Expect(port).To(Equal(int64(8443)))
+		})
+
+		It("should require a CA bundle", func() {
+			apiService := APIService{Group: "metrics.example.com", Version: "v1", ServiceNamespace: "system", ServiceName: "metrics-server"}
+			Expect(apiService.Register(context.Background(), fake.NewClientBuilder().Build())).NotTo(Succeed())
+		})
+	})
+})
@@ -0,0 +1,114 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregated
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// apiServiceGVK is the kind of the APIServices registering aggregated API servers.
+var apiServiceGVK = schema.GroupVersionKind{Group: "apiregistration.k8s.io", Version: "v1", Kind: "APIService"}
+
+// APIService registers a group version served by a Server with the API server aggregator.
+type APIService struct {
+	// Group is the API group served.
+	Group string
+
+	// Version is the API version served.
+	Version string
+
+	// GroupPriorityMinimum is the priority of the group in discovery. Defaults to 1000.
+	GroupPriorityMinimum int32
+
+	// VersionPriority is the priority of the version within its group. Defaults to 15.
+	VersionPriority int32
+
+	// ServiceNamespace and ServiceName reference the service in front of the Server.
+	ServiceNamespace string
+	ServiceName      string
+
+	// ServicePort is the port of the service. Defaults to 443.
+	ServicePort int32
+
+	// CABundle verifies the serving certificate of the Server.  Defaults to the ca.crt
+	// file in the certificate directory of the Server.
+	CABundle []byte
+}
+
+// Name returns the name of the APIService object, <version>.<group>.
+func (s APIService) Name() string {
+	return s.Version + "." + s.Group
+}
+
+func (s *APIService) setDefaults() {
+	if s.GroupPriorityMinimum == 0 {
+		s.GroupPriorityMinimum = 1000
+	}
+	if s.VersionPriority == 0 {
+		s.VersionPriority = 15
+	}
+	if s.ServicePort == 0 {
+		s.ServicePort = 443
+	}
+}
+
+// Register creates or updates the APIService object registering the group version.
+func (s APIService) Register(ctx context.Context, c client.Client) error {
+	s.setDefaults()
+	if s.Group == "" || s.Version == "" {
+		return fmt.Errorf("APIService requires a group and a version")
+	}
+	if s.ServiceName == "" || s.ServiceNamespace == "" {
+		return fmt.Errorf("APIService %s requires a service", s.Name())
+	}
+	if len(s.CABundle) == 0 {
+		return fmt.Errorf("APIService %s requires a CA bundle", s.Name())
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(apiServiceGVK)
+	obj.SetName(s.Name())
+	_, err := controllerutil.CreateOrUpdate(ctx, c, obj, func() error {
+		spec := map[string]interface{}{
+			"group":                s.Group,
+			"version":              s.Version,
+			"groupPriorityMinimum": int64(s.GroupPriorityMinimum),
+			"versionPriority":      int64(s.VersionPriority),
+			"service": map[string]interface{}{
+				"namespace": s.ServiceNamespace,
+				"name":      s.ServiceName,
+				"port":      int64(s.ServicePort),
+			},
+		}
+		if len(s.CABundle) > 0 {
+			spec["caBundle"] = base64.StdEncoding.EncodeToString(s.CABundle)
+		}
+		return unstructured.SetNestedField(obj.Object, spec, "spec")
+	})
+	if err != nil {
+		return fmt.Errorf("failed to register APIService %s: %w", s.Name(), err)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregated
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/kcp-dev/logicalcluster"
+	authenticationv1 "k8s.io/api/authentication/v1"
+
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// Attributes describe a request to a Server, to authorize it.
+type Attributes struct {
+	// Cluster is the logical cluster of the request, if any.
+	Cluster logicalcluster.Name
+
+	// Verb is the kubernetes verb of a resource request, e.g. "list", or the lower-cased
+	// HTTP method of a non-resource request.
+	Verb string
+
+	APIGroup    string
+	APIVersion  string
+	Namespace   string
+	Resource    string
+	Subresource string
+	Name        string
+
+	// Path is the path of the request, without the logical cluster prefix.
+	Path string
+
+	// ResourceRequest is true for requests to API resources.
+	ResourceRequest bool
+}
+
+// RequestAttributes returns the attributes of the given request.
+func RequestAttributes(r *http.Request) Attributes {
+	attrs := Attributes{Path: r.URL.Path, Verb: strings.ToLower(r.Method)}
+	if cluster, rest, ok := admission.ClusterFromPath(attrs.Path); ok {
+		attrs.Cluster, attrs.Path = cluster, rest
+	}
+
+	parts := splitPath(attrs.Path)
+	switch {
+	case len(parts) >= 3 && parts[0] == "apis":
+		attrs.APIGroup, attrs.APIVersion, parts = parts[1], parts[2], parts[3:]
+	case len(parts) >= 2 && parts[0] == "api":
+		attrs.APIVersion, parts = parts[1], parts[2:]
+	default:
+		return attrs
+	}
+	if len(parts) == 0 {
+		// discovery of the group version.
+		return attrs
+	}
+	attrs.ResourceRequest = true
+
+	if len(parts) >= 2 && parts[0] == "namespaces" {
+		attrs.Namespace = parts[1]
+		if len(parts) > 2 {
+			parts = parts[2:]
+		} else {
+			// the namespace itself.
+			parts = []string{"namespaces", parts[1]}
+			attrs.Namespace = ""
+		}
+	}
+	attrs.Resource = parts[0]
+	if len(parts) >= 2 {
+		attrs.Name = parts[1]
+	}
+	if len(parts) >= 3 {
+		attrs.Subresource = strings.Join(parts[2:], "/")
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		switch {
+		case r.URL.Query().Get("watch") == "true" || r.URL.Query().Get("watch") == "1":
+			attrs.Verb = "watch"
+		case attrs.Name == "":
+			attrs.Verb = "list"
+		default:
+			attrs.Verb = "get"
+		}
+	case http.MethodPost:
+		attrs.Verb = "create"
+	case http.MethodPut:
+		attrs.Verb = "update"
+	case http.MethodPatch:
+		attrs.Verb = "patch"
+	case http.MethodDelete:
+		if attrs.Name == "" {
+			attrs.Verb = "deletecollection"
+		} else {
+			attrs.Verb = "delete"
+		}
+	}
+	return attrs
+}
+
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+type userKey struct{}
+
+type attributesKey struct{}
+
+// UserFrom returns the authenticated user of the request with the given context.
+func UserFrom(ctx context.Context) (authenticationv1.UserInfo, bool) {
+	user, ok := ctx.Value(userKey{}).(authenticationv1.UserInfo)
+	return user, ok
+}
+
+// AttributesFrom returns the attributes of the request with the given context.
+func AttributesFrom(ctx context.Context) (Attributes, bool) {
+	attrs, ok := ctx.Value(attributesKey{}).(Attributes)
+	return attrs, ok
+}
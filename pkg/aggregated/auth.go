@@ -0,0 +1,267 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregated
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Authenticator authenticates the requests to a Server.
+type Authenticator interface {
+	// Authenticate returns the user of the request, and whether it was authenticated.
+	Authenticate(r *http.Request) (*authenticationv1.UserInfo, bool, error)
+}
+
+// AuthenticatorFunc implements Authenticator using a function.
+type AuthenticatorFunc func(r *http.Request) (*authenticationv1.UserInfo, bool, error)
+
+// Authenticate implements Authenticator.
+func (f AuthenticatorFunc) Authenticate(r *http.Request) (*authenticationv1.UserInfo, bool, error) {
+	return f(r)
+}
+
+// UnionAuthenticator authenticates the requests with the first of its Authenticators that
+// authenticates them.
+type UnionAuthenticator []Authenticator
+
+// Authenticate implements Authenticator.
+func (u UnionAuthenticator) Authenticate(r *http.Request) (*authenticationv1.UserInfo, bool, error) {
+	var errs []error
+	for _, a := range u {
+		user, ok, err := a.Authenticate(r)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if ok {
+			return user, true, nil
+		}
+	}
+	return nil, false, kerrors.NewAggregate(errs)
+}
+
+// authenticationConfigMap is the ConfigMap in which the API server publishes the configuration
+// of the authentication of the requests it proxies to aggregated API servers.
+var authenticationConfigMap = types.NamespacedName{Namespace: "kube-system", Name: "extension-apiserver-authentication"}
+
+// RequestHeaderConfig authenticates the requests proxied by the API server aggregator, which
+// presents a client certificate and passes the user it authenticated in request headers.
+type RequestHeaderConfig struct {
+	// ClientCAs verify the client certificates of the proxied requests.
+	ClientCAs *x509.CertPool
+
+	// AllowedNames are the common names of the client certificates allowed to proxy requests.
+	// Any name is allowed if empty.
+	AllowedNames []string
+
+	// UsernameHeaders are the headers holding the name of the user, the first one set wins.
+	UsernameHeaders []string
+
+	// GroupHeaders are the headers holding the groups of the user.
+	GroupHeaders []string
+
+	// ExtraHeaderPrefixes prefix the headers holding the extra information of the user.
+	ExtraHeaderPrefixes []string
+}
+
+// LoadRequestHeaderConfig reads the RequestHeaderConfig published by the API server in the
+// kube-system/extension-apiserver-authentication ConfigMap.  It returns nil if the API server
+// doesn't proxy requests with request headers.
+func LoadRequestHeaderConfig(ctx context.Context, reader client.Reader) (*RequestHeaderConfig, error) {
+	cm := &corev1.ConfigMap{}
+	if err := reader.Get(ctx, client.ObjectKey{NamespacedName: authenticationConfigMap}, cm); err != nil {
+		return nil, fmt.Errorf("failed to read the %s ConfigMap: %w", authenticationConfigMap, err)
+	}
+	caData := cm.Data["requestheader-client-ca-file"]
+	if caData == "" {
+		return nil, nil
+	}
+	c := &RequestHeaderConfig{ClientCAs: x509.NewCertPool()}
+	if !c.ClientCAs.AppendCertsFromPEM([]byte(caData)) {
+		return nil, fmt.Errorf("failed to parse the request header client CA of the %s ConfigMap", authenticationConfigMap)
+	}
+	for key, into := range map[string]*[]string{
+		"requestheader-allowed-names":        &c.AllowedNames,
+		"requestheader-username-headers":     &c.UsernameHeaders,
+		"requestheader-group-headers":        &c.GroupHeaders,
+		"requestheader-extra-headers-prefix": &c.ExtraHeaderPrefixes,
+	} {
+		if value := cm.Data[key]; value != "" {
+			if err := json.Unmarshal([]byte(value), into); err != nil {
+				return nil, fmt.Errorf("failed to parse %s of the %s ConfigMap: %w", key, authenticationConfigMap, err)
+			}
+		}
+	}
+	return c, nil
+}
+
+// Authenticate implements Authenticator.
+func (c *RequestHeaderConfig) Authenticate(r *http.Request) (*authenticationv1.UserInfo, bool, error) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil, false, nil
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range r.TLS.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	cert := r.TLS.PeerCertificates[0]
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         c.ClientCAs,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return nil, false, fmt.Errorf("failed to verify the request header client certificate: %w", err)
+	}
+	if len(c.AllowedNames) > 0 && !contains(c.AllowedNames, cert.Subject.CommonName) {
+		return nil, false, fmt.Errorf("client certificate %q is not allowed to proxy requests", cert.Subject.CommonName)
+	}
+
+	user := &authenticationv1.UserInfo{}
+	for _, header := range c.UsernameHeaders {
+		if user.Username = r.Header.Get(header); user.Username != "" {
+			break
+		}
+	}
+	if user.Username == "" {
+		return nil, false, nil
+	}
+	for _, header := range c.GroupHeaders {
+		user.Groups = append(user.Groups, r.Header.Values(header)...)
+	}
+	for header, values := range r.Header {
+		for _, prefix := range c.ExtraHeaderPrefixes {
+			if !strings.HasPrefix(strings.ToLower(header), strings.ToLower(prefix)) {
+				continue
+			}
+			key, err := url.PathUnescape(strings.ToLower(header[len(prefix):]))
+			if err != nil {
+				key = strings.ToLower(header[len(prefix):])
+			}
+			if user.Extra == nil {
+				user.Extra = map[string]authenticationv1.ExtraValue{}
+			}
+			user.Extra[key] = append(user.Extra[key], values...)
+		}
+	}
+	return user, true, nil
+}
+
+// TokenReviewAuthenticator authenticates the requests bearing a token with TokenReviews, in
+// the logical cluster of the request if any.
+type TokenReviewAuthenticator struct {
+	Client client.Client
+}
+
+// Authenticate implements Authenticator.
+func (a *TokenReviewAuthenticator) Authenticate(r *http.Request) (*authenticationv1.UserInfo, bool, error) {
+	token := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	if token == "" || token == r.Header.Get("Authorization") {
+		return nil, false, nil
+	}
+	review := &authenticationv1.TokenReview{Spec: authenticationv1.TokenReviewSpec{Token: token}}
+	if err := a.Client.Create(r.Context(), review); err != nil {
+		return nil, false, fmt.Errorf("failed to review the token: %w", err)
+	}
+	if !review.Status.Authenticated {
+		return nil, false, nil
+	}
+	return &review.Status.User, true, nil
+}
+
+// Authorizer authorizes the requests to a Server.
+type Authorizer interface {
+	// Authorize returns whether the user is allowed to make the request with the given
+	// attributes, and the reason of the decision.
+	Authorize(ctx context.Context, user authenticationv1.UserInfo, attrs Attributes) (bool, string, error)
+}
+
+// AuthorizerFunc implements Authorizer using a function.
+type AuthorizerFunc func(ctx context.Context, user authenticationv1.UserInfo, attrs Attributes) (bool, string, error)
+
+// Authorize implements Authorizer.
+func (f AuthorizerFunc) Authorize(ctx context.Context, user authenticationv1.UserInfo, attrs Attributes) (bool, string, error) {
+	return f(ctx, user, attrs)
+}
+
+// SubjectAccessReviewAuthorizer authorizes the requests with SubjectAccessReviews, in the
+// logical cluster of the request if any.
+type SubjectAccessReviewAuthorizer struct {
+	Client client.Client
+}
+
+// Authorize implements Authorizer.
+func (a *SubjectAccessReviewAuthorizer) Authorize(ctx context.Context, user authenticationv1.UserInfo, attrs Attributes) (bool, string, error) {
+	review := &authorizationv1.SubjectAccessReview{Spec: authorizationv1.SubjectAccessReviewSpec{
+		User:   user.Username,
+		UID:    user.UID,
+		Groups: user.Groups,
+	}}
+	if len(user.Extra) > 0 {
+		review.Spec.Extra = make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+		for key, value := range user.Extra {
+			review.Spec.Extra[key] = authorizationv1.ExtraValue(value)
+		}
+	}
+	if attrs.ResourceRequest {
+		review.Spec.ResourceAttributes = &authorizationv1.ResourceAttributes{
+			Namespace:   attrs.Namespace,
+			Verb:        attrs.Verb,
+			Group:       attrs.APIGroup,
+			Version:     attrs.APIVersion,
+			Resource:    attrs.Resource,
+			Subresource: attrs.Subresource,
+			Name:        attrs.Name,
+		}
+	} else {
+		review.Spec.NonResourceAttributes = &authorizationv1.NonResourceAttributes{Path: attrs.Path, Verb: attrs.Verb}
+	}
+	if !attrs.Cluster.Empty() {
+		ctx = kcpclient.WithCluster(ctx, attrs.Cluster)
+	}
+	if err := a.Client.Create(ctx, review); err != nil {
+		return false, "", fmt.Errorf("failed to review the access: %w", err)
+	}
+	if review.Status.EvaluationError != "" && !review.Status.Allowed {
+		return false, review.Status.Reason, errors.New(review.Status.EvaluationError)
+	}
+	return review.Status.Allowed, review.Status.Reason, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
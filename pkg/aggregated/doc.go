@@ -0,0 +1,40 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package aggregated helps running an aggregated API server alongside controllers, to
+expose virtual resources, e.g. derived from the state of many logical clusters, through
+the API server aggregator.
+
+A Server serves the given handler over TLS, with serving certificates reloaded from
+disk, once it registered its APIServices.  It authenticates the requests proxied by the
+aggregator with the request header configuration the API server publishes, or with
+TokenReviews, and authorizes them with SubjectAccessReviews:
+
+	srv := &aggregated.Server{
+		CertDir: "/tmp/serving-certs",
+		Handler: virtualResourcesHandler,
+		APIServices: []aggregated.APIService{{
+			Group: "metrics.example.com", Version: "v1alpha1",
+			ServiceNamespace: "system", ServiceName: "metrics-server",
+		}},
+	}
+	err := mgr.Add(srv)
+
+The handler reads the authenticated user and the attributes of the request from the
+context of the request, see UserFrom and AttributesFrom.
+*/
+package aggregated
@@ -0,0 +1,315 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregated
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/internal/httpserver"
+	logf "sigs.k8s.io/controller-runtime/pkg/internal/log"
+)
+
+var log = logf.RuntimeLog.WithName("aggregated")
+
+// DefaultPort is the default port that the aggregated API server serves.
+var DefaultPort = 8443
+
+// Server is an aggregated API server, run by a manager alongside controllers.
+//
+// It registers its APIServices, and serves its Handler over TLS to the authenticated and
+// authorized requests.  The serving certificate is reloaded when it changes on disk.
+type Server struct {
+	// Host is the address that the server will listen on.
+	// Defaults to "" - all addresses.
+	Host string
+
+	// Port is the port number that the server will serve.
+	// It will be defaulted to 8443 if unspecified.
+	Port int
+
+	// CertDir is the directory that contains the server key and certificate, and the
+	// CA certificate registered with the APIServices. The server key and certificate
+	// must be named tls.key and tls.crt, respectively, and the CA ca.crt, unless set
+	// otherwise. Defaults to <temp-dir>/k8s-aggregated-server/serving-certs.
+	CertDir string
+
+	// CertName is the server certificate name. Defaults to tls.crt.
+	CertName string
+
+	// KeyName is the server key name. Defaults to tls.key.
+	KeyName string
+
+	// CAName is the name of the CA certificate registered with the APIServices, unless
+	// they set their CABundle. Defaults to ca.crt.
+	CAName string
+
+	// Handler serves the virtual resources. The logical cluster of a request sent to
+	// /clusters/<name>/... is stripped off the path, and passed in the context of the
+	// request along with the authenticated user and the attributes of the request.
+	Handler http.Handler
+
+	// APIServices are registered with the API server aggregator when the server starts.
+	APIServices []APIService
+
+	// Authenticator authenticates the requests. Defaults to the request header
+	// configuration published by the API server, then to TokenReviews.
+	Authenticator Authenticator
+
+	// Authorizer authorizes the requests. Defaults to SubjectAccessReviews.
+	Authorizer Authorizer
+
+	// AlwaysAllowPaths are served without authentication nor authorization.
+	// Defaults to /healthz, /livez and /readyz.
+	AlwaysAllowPaths []string
+
+	// client registers the APIServices and creates the reviews.
+	client client.Client
+
+	// apiReader reads the request header configuration.
+	apiReader client.Reader
+
+	// defaultingOnce ensures that the default fields are only ever set once.
+	defaultingOnce sync.Once
+}
+
+// InjectClient injects the client registering the APIServices and creating the reviews.
+func (s *Server) InjectClient(c client.Client) error {
+	s.client = c
+	return nil
+}
+
+// InjectAPIReader injects the reader of the request header configuration.
+func (s *Server) InjectAPIReader(r client.Reader) error {
+	s.apiReader = r
+	return nil
+}
+
+// NeedLeaderElection implements the LeaderElectionRunnable interface, which indicates
+// the aggregated API server doesn't need leader election.
+func (*Server) NeedLeaderElection() bool {
+	return false
+}
+
+// setDefaults does defaulting for the Server.
+func (s *Server) setDefaults() {
+	if s.Port <= 0 {
+		s.Port = DefaultPort
+	}
+	if len(s.CertDir) == 0 {
+		s.CertDir = filepath.Join(os.TempDir(), "k8s-aggregated-server", "serving-certs")
+	}
+	if len(s.CertName) == 0 {
+		s.CertName = "tls.crt"
+	}
+	if len(s.KeyName) == 0 {
+		s.KeyName = "tls.key"
+	}
+	if len(s.CAName) == 0 {
+		s.CAName = "ca.crt"
+	}
+	if s.AlwaysAllowPaths == nil {
+		s.AlwaysAllowPaths = []string{"/healthz", "/livez", "/readyz"}
+	}
+}
+
+// Start registers the APIServices and runs the server until the context is done.
+func (s *Server) Start(ctx context.Context) error {
+	s.defaultingOnce.Do(s.setDefaults)
+
+	if s.Handler == nil {
+		return errors.New("aggregated API server requires a handler")
+	}
+	if err := s.registerAPIServices(ctx); err != nil {
+		return err
+	}
+
+	var clientCAs *RequestHeaderConfig
+	if s.Authenticator == nil {
+		authn, requestHeader, err := s.defaultAuthenticator(ctx)
+		if err != nil {
+			return err
+		}
+		s.Authenticator, clientCAs = authn, requestHeader
+	}
+	if s.Authorizer == nil {
+		if s.client == nil {
+			return errors.New("aggregated API server requires a client to authorize the requests")
+		}
+		s.Authorizer = &SubjectAccessReviewAuthorizer{Client: s.client}
+	}
+
+	certWatcher, err := certwatcher.New(filepath.Join(s.CertDir, s.CertName), filepath.Join(s.CertDir, s.KeyName))
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := certWatcher.Start(ctx); err != nil {
+			log.Error(err, "certificate watcher error")
+		}
+	}()
+
+	cfg := &tls.Config{
+		NextProtos:     []string{"h2"},
+		GetCertificate: certWatcher.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
+	}
+	if clientCAs != nil {
+		// the client certificates are verified by the request header authenticator,
+		// which also checks their common name.
+		cfg.ClientAuth = tls.RequestClientCert
+	}
+
+	listener, err := tls.Listen("tcp", net.JoinHostPort(s.Host, strconv.Itoa(s.Port)), cfg)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Serving aggregated API server", "host", s.Host, "port", s.Port)
+
+	srv := httpserver.New(s.handler())
+
+	idleConnsClosed := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		log.Info("shutting down aggregated API server")
+
+		if err := srv.Shutdown(context.Background()); err != nil {
+			log.Error(err, "error shutting down the HTTP server")
+		}
+		close(idleConnsClosed)
+	}()
+
+	if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+
+	<-idleConnsClosed
+	return nil
+}
+
+// registerAPIServices creates or updates the APIServices of the server.
+func (s *Server) registerAPIServices(ctx context.Context) error {
+	if len(s.APIServices) == 0 {
+		return nil
+	}
+	if s.client == nil {
+		return errors.New("aggregated API server requires a client to register its APIServices")
+	}
+	var caBundle []byte
+	for _, apiService := range s.APIServices {
+		if len(apiService.CABundle) == 0 {
+			if caBundle == nil {
+				var err error
+				if caBundle, err = ioutil.ReadFile(filepath.Join(s.CertDir, s.CAName)); err != nil {
+					return fmt.Errorf("failed to read the CA of APIService %s: %w", apiService.Name(), err)
+				}
+			}
+			apiService.CABundle = caBundle
+		}
+		if err := apiService.Register(ctx, s.client); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// defaultAuthenticator authenticates the requests proxied by the aggregator with the
+// request header configuration of the API server if any, then bearer tokens.
+func (s *Server) defaultAuthenticator(ctx context.Context) (Authenticator, *RequestHeaderConfig, error) {
+	if s.client == nil || s.apiReader == nil {
+		return nil, nil, errors.New("aggregated API server requires a client to authenticate the requests")
+	}
+	requestHeader, err := LoadRequestHeaderConfig(ctx, s.apiReader)
+	if err != nil {
+		return nil, nil, err
+	}
+	tokenReview := &TokenReviewAuthenticator{Client: s.client}
+	if requestHeader == nil {
+		return tokenReview, nil, nil
+	}
+	return UnionAuthenticator{requestHeader, tokenReview}, requestHeader, nil
+}
+
+// handler authenticates and authorizes the requests before serving them with the
+// handler of the server.
+func (s *Server) handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attrs := RequestAttributes(r)
+		if !attrs.Cluster.Empty() {
+			r = r.Clone(kcpclient.WithCluster(r.Context(), attrs.Cluster))
+			r.URL.Path = attrs.Path
+			r.URL.RawPath = ""
+		}
+		if contains(s.AlwaysAllowPaths, attrs.Path) {
+			s.Handler.ServeHTTP(w, r)
+			return
+		}
+
+		user, ok, err := s.Authenticator.Authenticate(r)
+		if err != nil {
+			log.V(1).Info("failed to authenticate request", "path", attrs.Path, "error", err.Error())
+		}
+		if !ok {
+			writeStatus(w, apierrors.NewUnauthorized("Unauthorized"))
+			return
+		}
+
+		allowed, reason, err := s.Authorizer.Authorize(r.Context(), *user, attrs)
+		if err != nil {
+			log.Error(err, "failed to authorize request", "path", attrs.Path, "user", user.Username)
+			writeStatus(w, apierrors.NewInternalError(err))
+			return
+		}
+		if !allowed {
+			gr := schema.GroupResource{Group: attrs.APIGroup, Resource: attrs.Resource}
+			writeStatus(w, apierrors.NewForbidden(gr, attrs.Name, fmt.Errorf("user %q cannot %s: %s", user.Username, attrs.Verb, reason)))
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), userKey{}, *user)
+		ctx = context.WithValue(ctx, attributesKey{}, attrs)
+		s.Handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// writeStatus writes the status of the given API error.
+func writeStatus(w http.ResponseWriter, err *apierrors.StatusError) {
+	status := err.Status()
+	status.Kind, status.APIVersion = "Status", "v1"
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(int(status.Code))
+	if err := json.NewEncoder(w).Encode(&status); err != nil {
+		log.Error(err, "unable to encode the response")
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The triggers of a reconcile, i.e. how its request was enqueued.
+const (
+	// TriggerEvent is for the requests enqueued by the event handlers of the watches of the
+	// controller, including the ones replayed by a resync.
+	TriggerEvent = "event"
+
+	// TriggerDelete is for the requests enqueued for Delete events, when the controller
+	// prioritizes them or enqueues tombstones.
+	TriggerDelete = "delete"
+
+	// TriggerRequeue is for the requests requeued with a backoff, after an error or a
+	// reconcile.Result with Requeue.
+	TriggerRequeue = "requeue"
+
+	// TriggerRequeueAfter is for the requests requeued after a delay, e.g. for a
+	// reconcile.Result with RequeueAfter.
+	TriggerRequeueAfter = "requeue_after"
+)
+
+// The results of a reconcile, as in the metrics of the controllers.
+const (
+	ResultSuccess      = "success"
+	ResultError        = "error"
+	ResultRequeue      = "requeue"
+	ResultRequeueAfter = "requeue_after"
+)
+
+// The classes of the errors which aren't returned by the API server.
+const (
+	ErrorClassPanic    = "Panic"
+	ErrorClassTimeout  = "Timeout"
+	ErrorClassCanceled = "Canceled"
+	ErrorClassUnknown  = "Unknown"
+)
+
+// Record describes a reconcile.
+type Record struct {
+	// Time is when the reconcile started.
+	Time time.Time `json:"time"`
+
+	// Controller is the name of the controller.
+	Controller string `json:"controller"`
+
+	// Key is the key of the reconciled request, see reconcile.Request.Key.
+	Key string `json:"key"`
+
+	// Cluster is the logical cluster of the reconciled request, if any.
+	Cluster string `json:"cluster,omitempty"`
+
+	// Trigger is how the request was enqueued, e.g. TriggerEvent, or empty if it is unknown.
+	Trigger string `json:"trigger,omitempty"`
+
+	// Retries is the number of times the request was requeued with a backoff before.
+	Retries int `json:"retries,omitempty"`
+
+	// Duration is how long the reconcile took, encoded in nanoseconds.
+	Duration time.Duration `json:"duration"`
+
+	// Result is the result of the reconcile, e.g. ResultSuccess.
+	Result string `json:"result"`
+
+	// RequeueAfter is the delay after which the request is reconciled again, for ResultRequeueAfter,
+	// encoded in nanoseconds.
+	RequeueAfter time.Duration `json:"requeueAfter,omitempty"`
+
+	// ErrorClass is the class of the error of the reconcile, see ErrorClass.
+	ErrorClass string `json:"errorClass,omitempty"`
+
+	// Error is the message of the error of the reconcile.
+	Error string `json:"error,omitempty"`
+}
+
+// Sink receives the Records of the reconciles of controllers.  It must be safe for
+// concurrent use, as the workers of controllers reconcile concurrently.
+type Sink interface {
+	Write(Record) error
+}
+
+// ErrorClass returns the class of an error returned by a Reconciler: the reason of the
+// errors returned by the API server, e.g. "Conflict" or "Forbidden", ErrorClassTimeout and
+// ErrorClassCanceled for the errors of contexts, or ErrorClassUnknown.  It returns an
+// empty string for a nil error.
+func ErrorClass(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	}
+	if reason := apierrors.ReasonForError(err); reason != metav1.StatusReasonUnknown {
+		return string(reason)
+	}
+	return ErrorClassUnknown
+}
+
+// jsonSink writes Records as JSON lines.
+type jsonSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONSink returns a Sink writing the Records to w, one JSON object per line.
+func NewJSONSink(w io.Writer) Sink {
+	return &jsonSink{enc: json.NewEncoder(w)}
+}
+
+// Write implements Sink.
+func (s *jsonSink) Write(rec Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(rec)
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var _ = Describe("ErrorClass", func() {
+	It("should classify the errors of the API server by their reason", func() {
+		err := apierrors.NewConflict(schema.GroupResource{Resource: "pods"}, "foo", errors.New("changed"))
+		Expect(ErrorClass(fmt.Errorf("failed to update: %w", err))).To(Equal("Conflict"))
+	})
+
+	It("should classify the errors of contexts", func() {
+		Expect(ErrorClass(fmt.Errorf("failed to get: %w", context.DeadlineExceeded))).To(Equal(ErrorClassTimeout))
+		Expect(ErrorClass(context.Canceled)).To(Equal(ErrorClassCanceled))
+	})
+
+	It("should classify the other errors as unknown, and no error as empty", func() {
+		Expect(ErrorClass(errors.New("failed"))).To(Equal(ErrorClassUnknown))
+		Expect(ErrorClass(nil)).To(BeEmpty())
+	})
+})
+
+var _ = Describe("NewJSONSink", func() {
+	It("should write the records as JSON lines", func() {
+		var buf bytes.Buffer
+		sink := NewJSONSink(&buf)
+		rec := Record{
+			Time:       time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC),
+			Controller: "foo",
+			Key:        "root:org|ns/name",
+			Cluster:    "root:org",
+			Trigger:    TriggerEvent,
+			Duration:   time.Second,
+			Result:     ResultError,
+			ErrorClass: ErrorClassUnknown,
+			Error:      "failed",
+		}
+		Expect(sink.Write(rec)).To(Succeed())
+		Expect(sink.Write(Record{Controller: "bar", Result: ResultSuccess})).To(Succeed())
+
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		Expect(lines).To(HaveLen(2))
+		var decoded Record
+		Expect(json.Unmarshal([]byte(lines[0]), &decoded)).To(Succeed())
+		Expect(decoded).To(Equal(rec))
+		Expect(lines[1]).NotTo(ContainSubstring("error"))
+	})
+})
+
+var _ = Describe("FileSink", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "audit")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	readRecords := func(path string) []string {
+		data, err := ioutil.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		var controllers []string
+		for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+			var rec Record
+			Expect(json.Unmarshal([]byte(line), &rec)).To(Succeed())
+			controllers = append(controllers, rec.Controller)
+		}
+		return controllers
+	}
+
+	It("should rotate the file once it grows above MaxSize, keeping MaxBackups backups", func() {
+		path := filepath.Join(dir, "audit.jsonl")
+		line, err := json.Marshal(Record{Controller: "0"})
+		Expect(err).NotTo(HaveOccurred())
+		sink, err := NewFileSink(path, FileOptions{MaxSize: int64(2 * (len(line) + 1)), MaxBackups: 2})
+		Expect(err).NotTo(HaveOccurred())
+		defer sink.Close()
+
+		for i := 0; i < 7; i++ {
+			Expect(sink.Write(Record{Controller: fmt.Sprint(i)})).To(Succeed())
+		}
+		Expect(readRecords(path)).To(Equal([]string{"6"}))
+		Expect(readRecords(path + ".1")).To(Equal([]string{"4", "5"}))
+		Expect(readRecords(path + ".2")).To(Equal([]string{"2", "3"}))
+		Expect(path + ".3").NotTo(BeAnExistingFile())
+	})
+
+	It("should append to an existing file, and fail to write once closed", func() {
+		path := filepath.Join(dir, "audit.jsonl")
+		sink, err := NewFileSink(path, FileOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sink.Write(Record{Controller: "foo"})).To(Succeed())
+		Expect(sink.Close()).To(Succeed())
+		Expect(sink.Write(Record{Controller: "bar"})).NotTo(Succeed())
+
+		sink, err = NewFileSink(path, FileOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		defer sink.Close()
+		Expect(sink.Write(Record{Controller: "bar"})).To(Succeed())
+		Expect(readRecords(path)).To(Equal([]string{"foo", "bar"}))
+	})
+})
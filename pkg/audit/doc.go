@@ -0,0 +1,32 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package audit records every reconcile of controllers, for compliance audits of controllers
+operating across the logical clusters of many tenants.
+
+A Sink set in the options of a controller receives a Record for each reconcile, holding the
+key of the request and its logical cluster, what triggered it, how long it took, its result
+and the class of its error.  NewJSONSink writes them as JSON lines to any io.Writer, and
+NewFileSink to a file on disk which it rotates:
+
+	sink, err := audit.NewFileSink("/var/log/controller/audit.jsonl", audit.FileOptions{MaxBackups: 10})
+	...
+	err = builder.ControllerManagedBy(mgr).For(&v1.Quota{}).
+		WithOptions(controller.Options{AuditSink: sink}).
+		Complete(r)
+*/
+package audit
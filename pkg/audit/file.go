@@ -0,0 +1,141 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+const (
+	defaultMaxSize    = 100 << 20
+	defaultMaxBackups = 5
+)
+
+// FileOptions are the options of a FileSink.
+type FileOptions struct {
+	// MaxSize is the size in bytes above which the file is rotated.  Defaults to 100MiB.
+	MaxSize int64
+
+	// MaxBackups is the number of rotated files to keep, named after the file with the
+	// suffixes ".1", the most recent, to ".<MaxBackups>".  Defaults to 5.
+	MaxBackups int
+}
+
+// FileSink is a Sink writing the Records to a file as JSON lines, which it rotates once
+// it grows above a size.
+type FileSink struct {
+	path string
+	opts FileOptions
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+var _ Sink = &FileSink{}
+
+// NewFileSink returns a FileSink appending to the file at path, which it creates if needed.
+func NewFileSink(path string, opts FileOptions) (*FileSink, error) {
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = defaultMaxSize
+	}
+	if opts.MaxBackups <= 0 {
+		opts.MaxBackups = defaultMaxBackups
+	}
+	s := &FileSink{path: path, opts: opts}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Write implements Sink.
+func (s *FileSink) Write(rec Record) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(rec); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return errors.New("audit file is closed")
+	}
+	if s.size > 0 && s.size+int64(buf.Len()) > s.opts.MaxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := s.file.Write(buf.Bytes())
+	s.size += int64(n)
+	return err
+}
+
+// Close closes the file.  The records written afterwards are dropped with an error.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// open opens the file for appending.
+func (s *FileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to stat audit file: %w", err)
+	}
+	s.file, s.size = file, info.Size()
+	return nil
+}
+
+// rotate shifts the backups of the file, dropping the oldest one, renames the file to
+// the most recent backup and opens a new one.
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("failed to close audit file: %w", err)
+	}
+	s.file = nil
+	for i := s.opts.MaxBackups - 1; i > 0; i-- {
+		if err := os.Rename(s.backup(i), s.backup(i+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate audit file: %w", err)
+		}
+	}
+	if err := os.Rename(s.path, s.backup(1)); err != nil {
+		return fmt.Errorf("failed to rotate audit file: %w", err)
+	}
+	return s.open()
+}
+
+// backup returns the path of the i-th most recent backup.
+func (s *FileSink) backup(i int) string {
+	return fmt.Sprintf("%s.%d", s.path, i)
+}
@@ -21,6 +21,7 @@ import (
 	"strings"
 
 	"github.com/go-logr/logr"
+	"github.com/kcp-dev/logicalcluster"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
@@ -71,7 +72,9 @@ type ForInput struct {
 	object           client.Object
 	predicates       []predicate.Predicate
 	objectProjection objectProjection
+	clusters         []logicalcluster.Name
 	err              error
+	filters          []predicate.MetadataFilter
 }
 
 // For defines the type of Object being *reconciled*, and configures the ControllerManagedBy to respond to create / delete /
@@ -97,6 +100,8 @@ type OwnsInput struct {
 	object           client.Object
 	predicates       []predicate.Predicate
 	objectProjection objectProjection
+	clusters         []logicalcluster.Name
+	filters          []predicate.MetadataFilter
 }
 
 // Owns defines types of Objects being *generated* by the ControllerManagedBy, and configures the ControllerManagedBy to respond to
@@ -118,6 +123,8 @@ type WatchesInput struct {
 	eventhandler     handler.EventHandler
 	predicates       []predicate.Predicate
 	objectProjection objectProjection
+	clusters         []logicalcluster.Name
+	filters          []predicate.MetadataFilter
 }
 
 // Watches exposes the lower-level ControllerManagedBy Watches functions through the builder.  Consider using
@@ -133,6 +140,14 @@ func (blder *Builder) Watches(src source.Source, eventhandler handler.EventHandl
 	return blder
 }
 
+// WatchesAcrossClusters watches the objects of src and reconciles the Requests mapped from their events by fn,
+// which may target objects in other logical clusters than the object of the event.  fn must set the cluster
+// of each Request, see handler.EnqueueRequestsAcrossClusters.  The Reconciler is passed a context targeting
+// the cluster of the Request, so that the client of the manager reads and writes the mapped objects.
+func (blder *Builder) WatchesAcrossClusters(src source.Source, fn handler.MapFunc, opts ...WatchesOption) *Builder {
+	return blder.Watches(src, handler.EnqueueRequestsAcrossClusters(fn), opts...)
+}
+
 // WithEventFilter sets the event filters, to filter which create/update/delete/generic events eventually
 // trigger reconciliations.  For example, filtering on whether the resource version has changed.
 // Given predicate is added for all watched objects.
@@ -154,6 +169,13 @@ func (blder *Builder) WithLogger(log logr.Logger) *Builder {
 	return blder
 }
 
+// WithConfig sets the configuration of the controller, whose value is passed to the Reconciler
+// in the context of each request, see reconcile.ConfigFrom.
+func (blder *Builder) WithConfig(config *reconcile.Config) *Builder {
+	blder.ctrlOptions.Config = config
+	return blder
+}
+
 // Named sets the name of the controller to the given name.  The name shows up
 // in metrics, among other things, and thus should be a prometheus compatible name
 // (underscores and alphanumeric characters only).
@@ -222,9 +244,10 @@ func (blder *Builder) doWatch() error {
 	if err != nil {
 		return err
 	}
-	src := &source.Kind{Type: typeForSrc}
+	src := &source.Kind{Type: typeForSrc, Filters: blder.forInput.filters}
 	hdler := &handler.EnqueueRequestForObject{}
-	allPredicates := append(blder.globalPredicates, blder.forInput.predicates...)
+	allPredicates := append(clusterPredicates(blder.forInput.clusters), blder.globalPredicates...)
+	allPredicates = append(allPredicates, blder.forInput.predicates...)
 	if err := blder.ctrl.Watch(src, hdler, allPredicates...); err != nil {
 		return err
 	}
@@ -235,12 +258,12 @@ func (blder *Builder) doWatch() error {
 		if err != nil {
 			return err
 		}
-		src := &source.Kind{Type: typeForSrc}
+		src := &source.Kind{Type: typeForSrc, Filters: own.filters}
 		hdler := &handler.EnqueueRequestForOwner{
 			OwnerType:    blder.forInput.object,
 			IsController: true,
 		}
-		allPredicates := append([]predicate.Predicate(nil), blder.globalPredicates...)
+		allPredicates := append(clusterPredicates(own.clusters), blder.globalPredicates...)
 		allPredicates = append(allPredicates, own.predicates...)
 		if err := blder.ctrl.Watch(src, hdler, allPredicates...); err != nil {
 			return err
@@ -249,7 +272,7 @@ func (blder *Builder) doWatch() error {
 
 	// Do the watch requests
 	for _, w := range blder.watchesInput {
-		allPredicates := append([]predicate.Predicate(nil), blder.globalPredicates...)
+		allPredicates := append(clusterPredicates(w.clusters), blder.globalPredicates...)
 		allPredicates = append(allPredicates, w.predicates...)
 
 		// If the source of this watch is of type *source.Kind, project it.
@@ -259,6 +282,9 @@ func (blder *Builder) doWatch() error {
 				return err
 			}
 			srckind.Type = typeForSrc
+			srckind.Filters = append(srckind.Filters, w.filters...)
+		} else if len(w.filters) > 0 {
+			return fmt.Errorf("metadata filters can only be used with a *source.Kind, not %T", w.src)
 		}
 
 		if err := blder.ctrl.Watch(w.src, w.eventhandler, allPredicates...); err != nil {
@@ -268,6 +294,15 @@ func (blder *Builder) doWatch() error {
 	return nil
 }
 
+// clusterPredicates returns the predicates filtering the events of a watch by their logical
+// cluster, which come first so that the other predicates only see the selected clusters.
+func clusterPredicates(clusters []logicalcluster.Name) []predicate.Predicate {
+	if len(clusters) == 0 {
+		return nil
+	}
+	return []predicate.Predicate{predicate.InClusters(clusters...)}
+}
+
 func (blder *Builder) getControllerName(gvk schema.GroupVersionKind) string {
 	if blder.name != "" {
 		return blder.name
@@ -290,6 +325,10 @@ func (blder *Builder) doController(r reconcile.Reconciler) error {
 		return err
 	}
 
+	if ctrlOptions.GroupKind.Empty() {
+		ctrlOptions.GroupKind = gvk.GroupKind()
+	}
+
 	// Setup concurrency.
 	if ctrlOptions.MaxConcurrentReconciles == 0 {
 		groupKind := gvk.GroupKind().String()
@@ -23,6 +23,7 @@ import (
 	"sync/atomic"
 
 	"github.com/go-logr/logr"
+	"github.com/kcp-dev/logicalcluster"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
@@ -397,6 +398,96 @@ var _ = Describe("application", func() {
 		})
 	})
 
+	Describe("Set clusters", func() {
+		var watches []watchRequest
+
+		BeforeEach(func() {
+			watches = nil
+			newController = func(name string, mgr manager.Manager, options controller.Options) (controller.Controller, error) {
+				return &watchRecorder{watches: &watches}, nil
+			}
+		})
+
+		podIn := func(cluster string) *corev1.Pod {
+			return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default", ClusterName: cluster}}
+		}
+		admits := func(w watchRequest, obj client.Object) bool {
+			for _, p := range w.predicates {
+				if !p.Create(event.CreateEvent{Object: obj}) {
+					return false
+				}
+			}
+			return true
+		}
+
+		It("should only admit the events of the given clusters", func() {
+			m, err := manager.New(cfg, manager.Options{})
+			Expect(err).NotTo(HaveOccurred())
+
+			var seen []string
+			global := predicate.NewPredicateFuncs(func(o client.Object) bool {
+				seen = append(seen, o.GetClusterName())
+				return true
+			})
+			_, err = ControllerManagedBy(m).
+				For(&appsv1.ReplicaSet{}, WithClusters("root:a", "root:b")).
+				Owns(&corev1.Pod{}, WithClusters("root:a")).
+				Watches(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestForObject{}).
+				WithEventFilter(global).
+				Build(noop)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(watches).To(HaveLen(3))
+
+			Expect(admits(watches[0], podIn("root:b"))).To(BeTrue())
+			Expect(admits(watches[0], podIn("root:c"))).To(BeFalse())
+			Expect(admits(watches[1], podIn("root:a"))).To(BeTrue())
+			Expect(admits(watches[1], podIn("root:b"))).To(BeFalse())
+			Expect(admits(watches[2], podIn("root:c"))).To(BeTrue())
+
+			By("filtering by cluster before the other predicates")
+			Expect(seen).To(Equal([]string{"root:b", "root:a", "root:c"}))
+		})
+
+		It("should admit the events of all the clusters with ForAllClusters", func() {
+			m, err := manager.New(cfg, manager.Options{})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = ControllerManagedBy(m).
+				For(&appsv1.ReplicaSet{}, WithClusters("root:a"), ForAllClusters()).
+				Build(noop)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(watches).To(HaveLen(1))
+			Expect(admits(watches[0], podIn("root:c"))).To(BeTrue())
+		})
+
+		It("should map the events of a cluster to the Requests of another one with WatchesAcrossClusters", func() {
+			m, err := manager.New(cfg, manager.Options{})
+			Expect(err).NotTo(HaveOccurred())
+
+			provider := client.ObjectKey{
+				NamespacedName: types.NamespacedName{Name: "export"},
+				Cluster:        logicalcluster.New("root:provider"),
+			}
+			_, err = ControllerManagedBy(m).
+				For(&appsv1.ReplicaSet{}, WithClusters("root:provider")).
+				WatchesAcrossClusters(&source.Kind{Type: &corev1.ConfigMap{}}, func(client.Object) []reconcile.Request {
+					return []reconcile.Request{{ObjectKey: provider}}
+				}, WithClusters("root:a")).
+				Build(noop)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(watches).To(HaveLen(2))
+			Expect(admits(watches[1], podIn("root:a"))).To(BeTrue())
+			Expect(admits(watches[1], podIn("root:b"))).To(BeFalse())
+
+			q := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+			defer q.ShutDown()
+			watches[1].handler.Create(event.CreateEvent{Object: podIn("root:a")}, q)
+			Expect(q.Len()).To(Equal(1))
+			item, _ := q.Get()
+			Expect(item).To(Equal(reconcile.Request{ObjectKey: provider}))
+		})
+	})
+
 	Describe("watching with projections", func() {
 		var mgr manager.Manager
 		BeforeEach(func() {
@@ -581,8 +672,8 @@ func doReconcileTest(ctx context.Context, nameSuffix string, mgr manager.Manager
 	Expect(err).NotTo(HaveOccurred())
 
 	By("Waiting for the Deployment Reconcile")
-	Eventually(ch).Should(Receive(Equal(reconcile.Request{
-		NamespacedName: types.NamespacedName{Namespace: "default", Name: deployName}})))
+	Eventually(ch).Should(Receive(Equal(reconcile.Request{ObjectKey: client.ObjectKey{
+		NamespacedName: types.NamespacedName{Namespace: "default", Name: deployName}}})))
 
 	By("Creating a ReplicaSet")
 	// Expect a Reconcile when an Owned object is managedObjects.
@@ -611,8 +702,8 @@ func doReconcileTest(ctx context.Context, nameSuffix string, mgr manager.Manager
 	Expect(err).NotTo(HaveOccurred())
 
 	By("Waiting for the ReplicaSet Reconcile")
-	Eventually(ch).Should(Receive(Equal(reconcile.Request{
-		NamespacedName: types.NamespacedName{Namespace: "default", Name: deployName}})))
+	Eventually(ch).Should(Receive(Equal(reconcile.Request{ObjectKey: client.ObjectKey{
+		NamespacedName: types.NamespacedName{Namespace: "default", Name: deployName}}})))
 }
 
 var _ runtime.Object = &fakeType{}
@@ -624,3 +715,20 @@ type fakeType struct {
 
 func (*fakeType) GetObjectKind() schema.ObjectKind { return nil }
 func (*fakeType) DeepCopyObject() runtime.Object   { return nil }
+
+type watchRequest struct {
+	src        source.Source
+	handler    handler.EventHandler
+	predicates []predicate.Predicate
+}
+
+// watchRecorder is a controller recording its watches.
+type watchRecorder struct {
+	controller.Controller
+	watches *[]watchRequest
+}
+
+func (c *watchRecorder) Watch(src source.Source, eventhandler handler.EventHandler, predicates ...predicate.Predicate) error {
+	*c.watches = append(*c.watches, watchRequest{src: src, handler: eventhandler, predicates: predicates})
+	return nil
+}
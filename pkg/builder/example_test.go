@@ -54,7 +54,7 @@ func ExampleBuilder_metadata_only() {
 		Complete(reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
 			// Read the ReplicaSet
 			rs := &appsv1.ReplicaSet{}
-			err := cl.Get(ctx, req.NamespacedName, rs)
+			err := cl.Get(ctx, req.ObjectKey, rs)
 			if err != nil {
 				return reconcile.Result{}, client.IgnoreNotFound(err)
 			}
@@ -134,7 +134,7 @@ type ReplicaSetReconciler struct {
 func (a *ReplicaSetReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
 	// Read the ReplicaSet
 	rs := &appsv1.ReplicaSet{}
-	err := a.Get(ctx, req.NamespacedName, rs)
+	err := a.Get(ctx, req.ObjectKey, rs)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
@@ -17,6 +17,8 @@ limitations under the License.
 package builder
 
 import (
+	"github.com/kcp-dev/logicalcluster"
+
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 )
 
@@ -75,6 +77,80 @@ var _ ForOption = &Predicates{}
 var _ OwnsOption = &Predicates{}
 var _ WatchesOption = &Predicates{}
 
+// WithClusters only watches the objects of the given logical clusters, e.g. root:org:ws.
+// The cache of the manager must hold the objects of these clusters, see
+// kcp.NewClusterAwareManager.
+func WithClusters(names ...string) Clusters {
+	clusters := make([]logicalcluster.Name, 0, len(names))
+	for _, name := range names {
+		clusters = append(clusters, logicalcluster.New(name))
+	}
+	return Clusters{clusters: clusters}
+}
+
+// ForAllClusters watches the objects of all the logical clusters held by the cache of the
+// manager, which is the default.  It overrides a previous WithClusters.
+func ForAllClusters() Clusters {
+	return Clusters{}
+}
+
+// Clusters selects the logical clusters whose objects are watched.  Events carry the logical
+// cluster of their object, and the handlers of the builder enqueue requests for this cluster.
+type Clusters struct {
+	clusters []logicalcluster.Name
+}
+
+// ApplyToFor applies this configuration to the given ForInput options.
+func (w Clusters) ApplyToFor(opts *ForInput) {
+	opts.clusters = w.clusters
+}
+
+// ApplyToOwns applies this configuration to the given OwnsInput options.
+func (w Clusters) ApplyToOwns(opts *OwnsInput) {
+	opts.clusters = w.clusters
+}
+
+// ApplyToWatches applies this configuration to the given WatchesInput options.
+func (w Clusters) ApplyToWatches(opts *WatchesInput) {
+	opts.clusters = w.clusters
+}
+
+var _ ForOption = &Clusters{}
+var _ OwnsOption = &Clusters{}
+var _ WatchesOption = &Clusters{}
+
+// WithMetadataFilters sets the given metadata filters, which drop the events of the objects they
+// don't admit in the event handler of the informer, before the predicates are run.  They are
+// cheaper than predicates for the kinds with a lot of changes, see predicate.MetadataFilter.
+// With Watches, the source must be a *source.Kind.
+func WithMetadataFilters(filters ...predicate.MetadataFilter) MetadataFilters {
+	return MetadataFilters{filters: filters}
+}
+
+// MetadataFilters filters events by the metadata of their object before building them.
+type MetadataFilters struct {
+	filters []predicate.MetadataFilter
+}
+
+// ApplyToFor applies this configuration to the given ForInput options.
+func (w MetadataFilters) ApplyToFor(opts *ForInput) {
+	opts.filters = w.filters
+}
+
+// ApplyToOwns applies this configuration to the given OwnsInput options.
+func (w MetadataFilters) ApplyToOwns(opts *OwnsInput) {
+	opts.filters = w.filters
+}
+
+// ApplyToWatches applies this configuration to the given WatchesInput options.
+func (w MetadataFilters) ApplyToWatches(opts *WatchesInput) {
+	opts.filters = w.filters
+}
+
+var _ ForOption = &MetadataFilters{}
+var _ OwnsOption = &MetadataFilters{}
+var _ WatchesOption = &MetadataFilters{}
+
 // }}}
 
 // {{{ For & Owns Dual-Type options
@@ -18,15 +18,18 @@ package builder
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"strings"
 
+	"github.com/kcp-dev/logicalcluster"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
 )
@@ -39,6 +42,10 @@ type WebhookBuilder struct {
 	gvk           schema.GroupVersionKind
 	mgr           manager.Manager
 	config        *rest.Config
+	clusters      []logicalcluster.Name
+	apiExport     string
+	serverName    string
+	server        *webhook.Server
 }
 
 // WebhookManagedBy allows inform its manager.Manager.
@@ -68,11 +75,41 @@ func (blder *WebhookBuilder) WithValidator(validator admission.CustomValidator)
 	return blder
 }
 
+// ForClusters restricts the defaulting and validating webhooks to the admission requests of
+// the given logical clusters: they are registered at /clusters/<name>/<path> for each of
+// them, rather than at <path>, which the webhook server serves for every logical cluster.
+// Point the webhook configuration of each logical cluster at its own path, see
+// webhook.ClusterWebhookConfigurations.
+func (blder *WebhookBuilder) ForClusters(clusters ...logicalcluster.Name) *WebhookBuilder {
+	blder.clusters = append(blder.clusters, clusters...)
+	return blder
+}
+
+// WithAPIExport encodes the name of the APIExport exporting the type in the generated paths
+// of the defaulting and validating webhooks, i.e. /exports/<name>/mutate-<group>-<version>-<kind>,
+// so that a binary serving the types of several APIExports doesn't mix their webhooks.
+func (blder *WebhookBuilder) WithAPIExport(name string) *WebhookBuilder {
+	blder.apiExport = name
+	return blder
+}
+
+// WithWebhookServer registers the webhooks with the webhook server of the manager with the given
+// name, see manager.Options.WebhookServers, rather than with its default webhook server.
+func (blder *WebhookBuilder) WithWebhookServer(name string) *WebhookBuilder {
+	blder.serverName = name
+	return blder
+}
+
 // Complete builds the webhook.
 func (blder *WebhookBuilder) Complete() error {
 	// Set the Config
 	blder.loadRestConfig()
 
+	// Set the webhook server
+	if err := blder.loadServer(); err != nil {
+		return err
+	}
+
 	// Set the Webhook if needed
 	return blder.registerWebhooks()
 }
@@ -83,6 +120,18 @@ func (blder *WebhookBuilder) loadRestConfig() {
 	}
 }
 
+func (blder *WebhookBuilder) loadServer() error {
+	if blder.serverName == "" {
+		blder.server = blder.mgr.GetWebhookServer()
+		return nil
+	}
+	blder.server = blder.mgr.GetNamedWebhookServer(blder.serverName)
+	if blder.server == nil {
+		return fmt.Errorf("the manager has no webhook server named %q", blder.serverName)
+	}
+	return nil
+}
+
 func (blder *WebhookBuilder) registerWebhooks() error {
 	typ, err := blder.getType()
 	if err != nil {
@@ -109,15 +158,15 @@ func (blder *WebhookBuilder) registerWebhooks() error {
 func (blder *WebhookBuilder) registerDefaultingWebhook() {
 	mwh := blder.getDefaultingWebhook()
 	if mwh != nil {
-		path := generateMutatePath(blder.gvk)
-
-		// Checking if the path is already registered.
-		// If so, just skip it.
-		if !blder.isAlreadyHandled(path) {
-			log.Info("Registering a mutating webhook",
-				"GVK", blder.gvk,
-				"path", path)
-			blder.mgr.GetWebhookServer().Register(path, mwh)
+		for _, path := range blder.paths(generateMutatePath(blder.gvk)) {
+			// Checking if the path is already registered.
+			// If so, just skip it.
+			if !blder.isAlreadyHandled(path) {
+				log.Info("Registering a mutating webhook",
+					"GVK", blder.gvk,
+					"path", path)
+				blder.server.Register(path, mwh)
+			}
 		}
 	}
 }
@@ -138,19 +187,34 @@ func (blder *WebhookBuilder) getDefaultingWebhook() *admission.Webhook {
 func (blder *WebhookBuilder) registerValidatingWebhook() {
 	vwh := blder.getValidatingWebhook()
 	if vwh != nil {
-		path := generateValidatePath(blder.gvk)
-
-		// Checking if the path is already registered.
-		// If so, just skip it.
-		if !blder.isAlreadyHandled(path) {
-			log.Info("Registering a validating webhook",
-				"GVK", blder.gvk,
-				"path", path)
-			blder.mgr.GetWebhookServer().Register(path, vwh)
+		for _, path := range blder.paths(generateValidatePath(blder.gvk)) {
+			// Checking if the path is already registered.
+			// If so, just skip it.
+			if !blder.isAlreadyHandled(path) {
+				log.Info("Registering a validating webhook",
+					"GVK", blder.gvk,
+					"path", path)
+				blder.server.Register(path, vwh)
+			}
 		}
 	}
 }
 
+// paths returns the paths to register a webhook at, from its generated path.
+func (blder *WebhookBuilder) paths(path string) []string {
+	if blder.apiExport != "" {
+		path = "/exports/" + blder.apiExport + path
+	}
+	if len(blder.clusters) == 0 {
+		return []string{path}
+	}
+	paths := make([]string, 0, len(blder.clusters))
+	for _, cluster := range blder.clusters {
+		paths = append(paths, cluster.Path()+path)
+	}
+	return paths
+}
+
 func (blder *WebhookBuilder) getValidatingWebhook() *admission.Webhook {
 	if validator := blder.withValidator; validator != nil {
 		return admission.WithCustomValidator(blder.apiType, validator)
@@ -172,7 +236,7 @@ func (blder *WebhookBuilder) registerConversionWebhook() error {
 	}
 	if ok {
 		if !blder.isAlreadyHandled("/convert") {
-			blder.mgr.GetWebhookServer().Register("/convert", &conversion.Webhook{})
+			blder.server.Register("/convert", &conversion.Webhook{})
 		}
 		log.Info("Conversion webhook enabled", "GVK", blder.gvk)
 	}
@@ -188,10 +252,10 @@ func (blder *WebhookBuilder) getType() (runtime.Object, error) {
 }
 
 func (blder *WebhookBuilder) isAlreadyHandled(path string) bool {
-	if blder.mgr.GetWebhookServer().WebhookMux == nil {
+	if blder.server.WebhookMux == nil {
 		return false
 	}
-	h, p := blder.mgr.GetWebhookServer().WebhookMux.Handler(&http.Request{URL: &url.URL{Path: path}})
+	h, p := blder.server.WebhookMux.Handler(&http.Request{URL: &url.URL{Path: path}})
 	if p == path && h != nil {
 		return true
 	}
@@ -22,9 +22,11 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"strings"
 
+	"github.com/kcp-dev/logicalcluster"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -34,6 +36,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
@@ -534,6 +537,106 @@ func runTests(admissionReviewVersion string) {
 		ExpectWithOffset(1, w.Body).To(ContainSubstring(`"allowed":true`))
 		ExpectWithOffset(1, w.Body).To(ContainSubstring(`"code":200`))
 	})
+
+	It("should register the webhooks with the named webhook server of the manager", func() {
+		By("creating a controller manager with a named webhook server")
+		m, err := manager.New(cfg, manager.Options{WebhookServers: map[string]*webhook.Server{
+			"validation": {Port: 9444},
+		}})
+		ExpectWithOffset(1, err).NotTo(HaveOccurred())
+
+		By("registering the type in the Scheme")
+		builder := scheme.Builder{GroupVersion: testValidatorGVK.GroupVersion()}
+		builder.Register(&TestValidator{}, &TestValidatorList{})
+		err = builder.AddToScheme(m.GetScheme())
+		ExpectWithOffset(1, err).NotTo(HaveOccurred())
+
+		err = WebhookManagedBy(m).
+			For(&TestValidator{}).
+			WithWebhookServer("validation").
+			Complete()
+		ExpectWithOffset(1, err).NotTo(HaveOccurred())
+
+		path := generateValidatePath(testValidatorGVK)
+		svr := m.GetNamedWebhookServer("validation")
+		ExpectWithOffset(1, svr.WebhookMux).NotTo(BeNil())
+		_, pattern := svr.WebhookMux.Handler(&http.Request{URL: &url.URL{Path: path}})
+		ExpectWithOffset(1, pattern).To(Equal(path))
+		ExpectWithOffset(1, m.GetWebhookServer().WebhookMux).To(BeNil())
+
+		By("failing to register the webhooks with an unknown webhook server")
+		err = WebhookManagedBy(m).
+			For(&TestValidator{}).
+			WithWebhookServer("other").
+			Complete()
+		ExpectWithOffset(1, err).To(MatchError(ContainSubstring(`no webhook server named "other"`)))
+	})
+
+	It("should register the webhooks at the paths of the logical clusters, encoding the APIExport", func() {
+		By("creating a controller manager")
+		m, err := manager.New(cfg, manager.Options{})
+		ExpectWithOffset(1, err).NotTo(HaveOccurred())
+
+		By("registering the type in the Scheme")
+		builder := scheme.Builder{GroupVersion: testValidatorGVK.GroupVersion()}
+		builder.Register(&TestValidator{}, &TestValidatorList{})
+		err = builder.AddToScheme(m.GetScheme())
+		ExpectWithOffset(1, err).NotTo(HaveOccurred())
+
+		err = WebhookManagedBy(m).
+			For(&TestValidator{}).
+			ForClusters(logicalcluster.New("root:a"), logicalcluster.New("root:b")).
+			WithAPIExport("validators").
+			Complete()
+		ExpectWithOffset(1, err).NotTo(HaveOccurred())
+		svr := m.GetWebhookServer()
+		ExpectWithOffset(1, svr).NotTo(BeNil())
+
+		body := `{
+  "kind":"AdmissionReview",
+  "apiVersion":"admission.k8s.io/` + admissionReviewVersion + `",
+  "request":{
+    "uid":"07e52e8d-4513-11e9-a716-42010a800270",
+    "kind":{
+      "group":"",
+      "version":"v1",
+      "kind":"TestValidator"
+    },
+    "resource":{
+      "group":"",
+      "version":"v1",
+      "resource":"testvalidator"
+    },
+    "namespace":"default",
+    "operation":"CREATE",
+    "object":{
+      "replica":1
+    }
+  }
+}`
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err = svr.Start(ctx)
+		if err != nil && !os.IsNotExist(err) {
+			ExpectWithOffset(1, err).NotTo(HaveOccurred())
+		}
+
+		for path, code := range map[string]int{
+			"/clusters/root:a/exports/validators" + generateValidatePath(testValidatorGVK): http.StatusOK,
+			"/clusters/root:b/exports/validators" + generateValidatePath(testValidatorGVK): http.StatusOK,
+			"/clusters/root:c/exports/validators" + generateValidatePath(testValidatorGVK): http.StatusNotFound,
+			"/exports/validators" + generateValidatePath(testValidatorGVK):                 http.StatusNotFound,
+			generateValidatePath(testValidatorGVK):                                         http.StatusNotFound,
+		} {
+			By("sending a request to " + path)
+			req := httptest.NewRequest("POST", "http://svc-name.svc-ns.svc"+path, strings.NewReader(body))
+			req.Header.Add("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			svr.WebhookMux.ServeHTTP(w, req)
+			ExpectWithOffset(1, w.Code).To(Equal(code))
+		}
+	})
 }
 
 // TestDefaulter.
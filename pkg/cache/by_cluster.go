@@ -0,0 +1,151 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"hash/fnv"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+	"k8s.io/client-go/rest"
+)
+
+// DefaultResyncJitter is the default ClusterOptions.ResyncJitter.
+const DefaultResyncJitter = 0.25
+
+// ClusterOptions scope the cache of a logical cluster, see ByCluster.
+type ClusterOptions struct {
+	// Namespaces restricts the cache to the given namespaces, with a multi-namespace
+	// cache if there are several of them.  All the namespaces are cached if it is empty.
+	Namespaces []string
+
+	// SelectorsByObject restricts the objects of the given types which are cached,
+	// see Options.SelectorsByObject.
+	SelectorsByObject SelectorsByObject
+
+	// DefaultSelector restricts the objects of the types without a selector in
+	// SelectorsByObject, see Options.DefaultSelector.
+	DefaultSelector ObjectSelector
+
+	// ListWatchProxy directs the lists and watches of the cache of the cluster to a caching
+	// proxy, see Options.ListWatchProxy.
+	ListWatchProxy *Proxy
+
+	// Resync overrides the resync period of the informers of the cache of the cluster, see
+	// Options.Resync.
+	Resync *time.Duration
+
+	// ResyncJitter spreads the resyncs of the caches of the clusters: the resync period of
+	// each cluster is lengthened by up to ResyncJitter times the period, by an amount derived
+	// from the name of the cluster, so that the caches of hundreds of clusters started
+	// together don't resync together.  It adds to the jitter of the informers of a cache.
+	// Defaults to DefaultResyncJitter, set it to a negative value to disable it.
+	ResyncJitter float64
+}
+
+// resyncFor returns the resync period of the cache of the given logical cluster, from the
+// one of the options of the cache.
+func (o ClusterOptions) resyncFor(name logicalcluster.Name, resync *time.Duration) time.Duration {
+	period := defaultResyncTime
+	switch {
+	case o.Resync != nil:
+		period = *o.Resync
+	case resync != nil:
+		period = *resync
+	}
+	jitter := o.ResyncJitter
+	if jitter == 0 {
+		jitter = DefaultResyncJitter
+	}
+	if jitter < 0 {
+		return period
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name.String()))
+	spread := float64(h.Sum32()) / (1 << 32)
+	return period + time.Duration(jitter*spread*float64(period))
+}
+
+// ByCluster associates logical clusters with the options scoping their caches, the way
+// SelectorsByObject associates types with selectors.  The logical clusters without an entry
+// use the one of logicalcluster.Wildcard, if any.  E.g. to only cache the Deployments with
+// some label in the tenant workspaces, but all of them in the management workspace:
+//
+//	cache.ByCluster{
+//		logicalcluster.Wildcard: {SelectorsByObject: cache.SelectorsByObject{
+//			&appsv1.Deployment{}: {Label: labels.SelectorFromSet(labels.Set{"tenant": "true"})},
+//		}},
+//		logicalcluster.New("root:management"): {},
+//	}
+//
+// It applies to the caches of the clusters of a cluster.ClusterSet, which are each built for
+// a single logical cluster, see Builder.
+type ByCluster map[logicalcluster.Name]ClusterOptions
+
+// For returns the options of the given logical cluster, falling back to the ones of
+// logicalcluster.Wildcard, and whether there are any.
+func (b ByCluster) For(name logicalcluster.Name) (ClusterOptions, bool) {
+	if opts, ok := b[name]; ok {
+		return opts, true
+	}
+	opts, ok := b[logicalcluster.Wildcard]
+	return opts, ok
+}
+
+// Builder returns a NewCacheFunc building the cache of the given logical cluster with
+// newCache, or New if nil, scoped by the options of the cluster.  The namespaces, selectors,
+// proxy and resync period of the cluster, if set, take precedence over the ones of the
+// Options, and the resync period is jittered across the clusters, see ClusterOptions.ResyncJitter.
+func (b ByCluster) Builder(name logicalcluster.Name, newCache NewCacheFunc) NewCacheFunc {
+	if newCache == nil {
+		newCache = New
+	}
+	clusterOpts, ok := b.For(name)
+	if !ok {
+		return newCache
+	}
+	return func(config *rest.Config, opts Options) (Cache, error) {
+		resync := clusterOpts.resyncFor(name, opts.Resync)
+		opts.Resync = &resync
+		if clusterOpts.SelectorsByObject != nil {
+			opts.SelectorsByObject = clusterOpts.SelectorsByObject
+		}
+		if clusterOpts.DefaultSelector.Label != nil || clusterOpts.DefaultSelector.Field != nil {
+			opts.DefaultSelector = clusterOpts.DefaultSelector
+		}
+		if clusterOpts.ListWatchProxy != nil {
+			opts.ListWatchProxy = clusterOpts.ListWatchProxy
+		}
+		switch len(clusterOpts.Namespaces) {
+		case 0:
+			return newCache(config, opts)
+		case 1:
+			opts.Namespace = clusterOpts.Namespaces[0]
+			return newCache(config, opts)
+		default:
+			return multiNamespacedCacheBuilder(clusterOpts.Namespaces, newCache)(config, opts)
+		}
+	}
+}
+
+var _ CacheFactory = ByCluster(nil)
+
+// NewClusterCache implements CacheFactory: it builds the cache of the given logical cluster
+// with New, scoped by the options of the cluster.
+func (b ByCluster) NewClusterCache(name logicalcluster.Name, config *rest.Config, opts Options) (Cache, error) {
+	return b.Builder(name, nil)(config, opts)
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+var _ = Describe("ByCluster", func() {
+	tenant := logicalcluster.New("root:tenant")
+	management := logicalcluster.New("root:management")
+	selector := labels.SelectorFromSet(labels.Set{"tenant": "true"})
+	byCluster := ByCluster{
+		logicalcluster.Wildcard: {SelectorsByObject: SelectorsByObject{&appsv1.Deployment{}: {Label: selector}}},
+		management:              {Namespaces: []string{"a", "b"}},
+	}
+
+	var built []Options
+	newCache := func(_ *rest.Config, opts Options) (Cache, error) {
+		built = append(built, opts)
+		return &informerCache{}, nil
+	}
+	config := &rest.Config{}
+	opts := Options{Scheme: scheme.Scheme, Mapper: meta.NewDefaultRESTMapper(nil), Namespace: "default"}
+
+	BeforeEach(func() {
+		built = nil
+	})
+
+	It("should scope the caches of the clusters without an entry with the wildcard one", func() {
+		clusterOpts, ok := byCluster.For(tenant)
+		Expect(ok).To(BeTrue())
+		Expect(clusterOpts.SelectorsByObject).To(HaveLen(1))
+		_, ok = ByCluster{management: {}}.For(tenant)
+		Expect(ok).To(BeFalse())
+
+		_, err := byCluster.Builder(tenant, newCache)(config, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(built).To(HaveLen(1))
+		Expect(built[0].Namespace).To(Equal("default"))
+		Expect(built[0].SelectorsByObject).To(HaveKeyWithValue(&appsv1.Deployment{}, ObjectSelector{Label: selector}))
+	})
+
+	It("should build a multi-namespace cache for the clusters with several namespaces", func() {
+		c, err := byCluster.Builder(management, newCache)(config, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c).To(BeAssignableToTypeOf(&multiNamespaceCache{}))
+		Expect(c.(*multiNamespaceCache).namespaceToCache).To(HaveLen(2))
+
+		var namespaces []string
+		for _, o := range built {
+			namespaces = append(namespaces, o.Namespace)
+			Expect(o.SelectorsByObject).To(BeEmpty())
+		}
+		Expect(namespaces).To(Equal([]string{"default", "a", "b"}))
+	})
+
+	It("should pass the clusters without options to the cache builder as is", func() {
+		_, err := ByCluster{management: {Namespaces: []string{"a"}}}.Builder(tenant, newCache)(config, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(built).To(Equal([]Options{opts}))
+	})
+
+	It("should direct the lists and watches of a cluster to its proxy", func() {
+		proxy := &Proxy{Host: "https://cache-server:6443"}
+		_, err := ByCluster{tenant: {ListWatchProxy: proxy}}.Builder(tenant, newCache)(config, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(built).To(HaveLen(1))
+		Expect(built[0].ListWatchProxy).To(BeIdenticalTo(proxy))
+	})
+
+	It("should override the resync period of a cluster and jitter it across the clusters", func() {
+		hour := time.Hour
+		resync := func(b ByCluster, name logicalcluster.Name) time.Duration {
+			built = nil
+			_, err := b.Builder(name, newCache)(config, Options{Resync: &hour})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(built).To(HaveLen(1))
+			return *built[0].Resync
+		}
+
+		periods := map[time.Duration]bool{}
+		for _, name := range []string{"root:a", "root:b", "root:c", "root:d"} {
+			period := resync(ByCluster{logicalcluster.Wildcard: {}}, logicalcluster.New(name))
+			Expect(period).To(BeNumerically(">=", hour))
+			Expect(period).To(BeNumerically("<", hour+time.Duration(DefaultResyncJitter*float64(hour))))
+			Expect(resync(ByCluster{logicalcluster.Wildcard: {}}, logicalcluster.New(name))).To(Equal(period))
+			periods[period] = true
+		}
+		Expect(len(periods)).To(BeNumerically(">", 1))
+
+		minutes := 10 * time.Minute
+		Expect(resync(ByCluster{tenant: {Resync: &minutes, ResyncJitter: -1}}, tenant)).To(Equal(minutes))
+		Expect(resync(ByCluster{tenant: {ResyncJitter: -1}}, tenant)).To(Equal(hour))
+	})
+})
@@ -18,10 +18,15 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
+	kcpcache "github.com/kcp-dev/apimachinery/pkg/cache"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -31,6 +36,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/cache/internal"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/internal/clusterhost"
 	logf "sigs.k8s.io/controller-runtime/pkg/internal/log"
 )
 
@@ -60,7 +66,7 @@ type Informers interface {
 	GetInformerForKind(ctx context.Context, gvk schema.GroupVersionKind) (Informer, error)
 
 	// Start runs all the informers known to this cache until the context is closed.
-	// It blocks.
+	// It blocks, and only returns once the informers are stopped.
 	Start(ctx context.Context) error
 
 	// WaitForCacheSync waits for all the caches to sync.  Returns false if it could not sync a cache.
@@ -70,6 +76,30 @@ type Informers interface {
 	client.FieldIndexer
 }
 
+// QuorumSyncer is implemented by the caches which can wait to observe the writes made
+// before a call, e.g. to start reconciling from the state the previous leader left.
+type QuorumSyncer interface {
+	// WaitForQuorumSync waits until the cache is synced and holds at least the objects read
+	// from the API server with a quorum list of each kind it caches.  It returns an error if
+	// the lists fail or the context is done first.
+	WaitForQuorumSync(ctx context.Context) error
+}
+
+// WaitForQuorumSync waits for the quorum sync of the cache if it is a QuorumSyncer, and
+// for it to sync otherwise.
+func WaitForQuorumSync(ctx context.Context, c Informers) error {
+	if s, ok := c.(QuorumSyncer); ok {
+		return s.WaitForQuorumSync(ctx)
+	}
+	if !c.WaitForCacheSync(ctx) {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return errors.New("unable to sync the cache")
+	}
+	return nil
+}
+
 // Informer - informer allows you interact with the underlying informer.
 type Informer interface {
 	// AddEventHandler adds an event handler to the shared informer using the shared informer's resync
@@ -95,6 +125,22 @@ type ObjectSelector internal.Selector
 // a more specific setting here, if any).
 type SelectorsByObject map[client.Object]ObjectSelector
 
+// SingleObject returns an ObjectSelector restricting the cache of a type to the objects with the
+// given name, and namespace if not empty, for controllers which only care about one object of the
+// type, e.g. a configuration object, rather than caching the whole type.  When watching across logical
+// clusters, the cache holds the object with that name of each cluster.
+//
+//	cache.Options{SelectorsByObject: cache.SelectorsByObject{
+//		&corev1.ConfigMap{}: cache.SingleObject("kube-system", "config"),
+//	}}
+func SingleObject(namespace, name string) ObjectSelector {
+	set := fields.Set{"metadata.name": name}
+	if namespace != "" {
+		set["metadata.namespace"] = namespace
+	}
+	return ObjectSelector{Field: set.AsSelector()}
+}
+
 // Options are the optional arguments for creating a new InformersMap object.
 type Options struct {
 	// Scheme is the scheme to use for mapping objects to GroupVersionKinds
@@ -109,6 +155,10 @@ type Options struct {
 	// So that all informers will not send list requests simultaneously.
 	Resync *time.Duration
 
+	// KeyFunction is the function keying the objects in the stores of the informers.  It
+	// defaults to the cluster-aware kcpcache.ClusterAwareKeyFunc, keying the objects by
+	// logical cluster, namespace and name, so that the objects with the same namespace and
+	// name in different logical clusters don't collide in a wildcard cache.
 	KeyFunction cache.KeyFunc
 
 	// Namespace restricts the cache's ListWatch to the desired namespace
@@ -131,6 +181,105 @@ type Options struct {
 	// Be very careful with this, when enabled you must DeepCopy any object before mutating it,
 	// otherwise you will mutate the object in the cache.
 	UnsafeDisableDeepCopyByObject DisableDeepCopyByObject
+
+	// TransformByObject is a map from GVKs to the functions transforming their objects before
+	// they are cached, e.g. to strip their managedFields or large annotations and reduce the
+	// memory used by the caches of many logical clusters.  The functions receive the objects
+	// of every logical cluster the cache watches, so they can transform them per cluster,
+	// see logicalcluster.From.  They may modify the objects in place, and must return objects
+	// of the same type.
+	TransformByObject TransformByObject
+
+	// DefaultTransform is the transform function used for the kinds which don't have one in
+	// TransformByObject.
+	DefaultTransform toolscache.TransformFunc
+
+	// MetadataOnlyByObject forces the informers of the kinds of the given objects, or of all
+	// kinds with ObjectAll, to only cache the metadata of the objects, whatever the type of
+	// the objects they are requested for, e.g. by the sources of controllers.  The objects of
+	// these kinds must be read from the cache as metav1.PartialObjectMetadata.  It applies to
+	// every logical cluster, as the caches of the clusters of a ClusterSet are built with the
+	// same options.
+	MetadataOnlyByObject MetadataOnlyByObject
+
+	// StrictDecoding, if set, makes the cache reject the objects of the selected kinds and
+	// logical clusters that have unknown fields.  The lists and watches returning them fail,
+	// and are retried, so their informers don't sync until the drift is fixed.
+	StrictDecoding *apiutil.StrictDecoding
+
+	// ListChunkSize is the number of objects requested per page by the lists of the
+	// informers, to bound the memory used to sync the kinds with many objects, e.g. across
+	// many logical clusters.  Defaults to the chunk size of the reflectors, which don't
+	// paginate the lists served from the watch cache of the API server.  Setting it makes
+	// the lists paginate, at the cost of them not being served from the watch cache.
+	// Streaming the lists with watches instead isn't supported by the client of the
+	// informers yet.
+	ListChunkSize int64
+
+	// RelistBackoff, if set, delays the lists of each informer after its first one, e.g.
+	// after its watch expired, so that relisting the kinds of many logical clusters at
+	// once doesn't spike the memory use.  It is in addition to the backoff of the
+	// reflectors after errors.
+	RelistBackoff *RelistBackoff
+
+	// ListWatchProxy, if set, directs the lists and watches of the informers to a caching
+	// proxy instead of the API server of the config, e.g. to share the watches of the kinds
+	// many controller replicas watch and reduce the load on the kcp shards.  Only the cache
+	// uses it: the writes of the client of a manager, and its reads bypassing the cache, still
+	// go to the API server.
+	ListWatchProxy *Proxy
+
+	// ResourceVersionStore, if set, persists the resourceVersion last observed by each
+	// informer, from its lists and the bookmarks of its watches, per logical cluster of the
+	// config and kind, see NewFileResourceVersionStore.  After a restart, the first list of
+	// an informer requests the objects at a resourceVersion not older than the saved one, so
+	// that the cache doesn't go back to a state older than the one it had observed, e.g.
+	// when served by a lagging API server.  It doesn't resume the watches, nor make the
+	// restarts cheaper: the objects aren't persisted, so the informers still list all of
+	// them once after a restart, paginated as usual.
+	ResourceVersionStore ResourceVersionStore
+}
+
+// Proxy is a caching proxy serving the lists and watches of the informers of a cache.
+type Proxy struct {
+	// Host is the URL of the proxy, e.g. https://cache-server:6443.  The path of the host of
+	// the config of the cache, e.g. the /clusters/<name> prefix of a logical cluster, is
+	// appended to its path.
+	Host string
+
+	// TLSClientConfig, if set, replaces the TLS configuration of the config of the cache for
+	// the proxy, e.g. to trust its CA.  The credentials of the config are kept otherwise.
+	TLSClientConfig *rest.TLSClientConfig
+}
+
+// configFor returns a copy of the config directing the requests to the proxy.
+func (p *Proxy) configFor(config *rest.Config) (*rest.Config, error) {
+	proxyURL, err := url.Parse(p.Host)
+	if err != nil || proxyURL.Scheme == "" || proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid host %q for the list and watch proxy, it must be a URL", p.Host)
+	}
+	if hostURL, err := url.Parse(config.Host); err == nil && hostURL.Scheme != "" {
+		proxyURL.Path = strings.TrimSuffix(proxyURL.Path, "/") + hostURL.Path
+	}
+	config = rest.CopyConfig(config)
+	config.Host = proxyURL.String()
+	if p.TLSClientConfig != nil {
+		config.TLSClientConfig = *p.TLSClientConfig
+	}
+	return config, nil
+}
+
+// RelistBackoff configures the delays between the lists of an informer.
+type RelistBackoff struct {
+	// Initial is the delay of the first relist.
+	Initial time.Duration
+
+	// Max caps the delays.  The delays are reset to Initial once no list happened for
+	// twice Max.  Defaults to Initial.
+	Max time.Duration
+
+	// Factor multiplies the delay after each relist.  Defaults to 2.
+	Factor float64
 }
 
 var defaultResyncTime = 10 * time.Hour
@@ -149,7 +298,29 @@ func New(config *rest.Config, opts Options) (Cache, error) {
 	if err != nil {
 		return nil, err
 	}
-	im := internal.NewInformersMap(config, opts.Scheme, opts.Mapper, *opts.Resync, opts.Namespace, selectorsByGVK, disableDeepCopyByGVK, opts.KeyFunction)
+	metadataOnlyByGVK, err := convertToMetadataOnlyByGVK(opts.MetadataOnlyByObject, opts.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	transformByGVK, err := convertToTransformByGVK(opts.TransformByObject, opts.DefaultTransform, opts.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	listOptions := internal.ListOptions{ChunkSize: opts.ListChunkSize, Cluster: clusterhost.Cluster(config.Host)}
+	if opts.RelistBackoff != nil {
+		listOptions.RelistInitial = opts.RelistBackoff.Initial
+		listOptions.RelistMax = opts.RelistBackoff.Max
+		listOptions.RelistFactor = opts.RelistBackoff.Factor
+	}
+	if opts.ResourceVersionStore != nil {
+		listOptions.ResourceVersions = clusterResourceVersions{store: opts.ResourceVersionStore, cluster: listOptions.Cluster}
+	}
+	if opts.ListWatchProxy != nil {
+		if config, err = opts.ListWatchProxy.configFor(config); err != nil {
+			return nil, err
+		}
+	}
+	im := internal.NewInformersMap(config, opts.Scheme, opts.Mapper, *opts.Resync, opts.Namespace, selectorsByGVK, disableDeepCopyByGVK, opts.KeyFunction, opts.StrictDecoding, listOptions, metadataOnlyByGVK, transformByGVK)
 	return &informerCache{InformersMap: im}, nil
 }
 
@@ -174,6 +345,42 @@ func BuilderWithOptions(options Options) NewCacheFunc {
 		if options.Namespace == "" {
 			options.Namespace = opts.Namespace
 		}
+		if options.StrictDecoding == nil {
+			options.StrictDecoding = opts.StrictDecoding
+		}
+		if options.ListChunkSize == 0 {
+			options.ListChunkSize = opts.ListChunkSize
+		}
+		if options.RelistBackoff == nil {
+			options.RelistBackoff = opts.RelistBackoff
+		}
+		if options.ListWatchProxy == nil {
+			options.ListWatchProxy = opts.ListWatchProxy
+		}
+		if options.ResourceVersionStore == nil {
+			options.ResourceVersionStore = opts.ResourceVersionStore
+		}
+		if options.MetadataOnlyByObject == nil {
+			options.MetadataOnlyByObject = opts.MetadataOnlyByObject
+		}
+		if options.UnsafeDisableDeepCopyByObject == nil {
+			options.UnsafeDisableDeepCopyByObject = opts.UnsafeDisableDeepCopyByObject
+		}
+		if options.TransformByObject == nil {
+			options.TransformByObject = opts.TransformByObject
+		}
+		if options.DefaultTransform == nil {
+			options.DefaultTransform = opts.DefaultTransform
+		}
+		if options.KeyFunction == nil {
+			options.KeyFunction = opts.KeyFunction
+		}
+		if options.SelectorsByObject == nil {
+			options.SelectorsByObject = opts.SelectorsByObject
+		}
+		if options.DefaultSelector.Label == nil && options.DefaultSelector.Field == nil {
+			options.DefaultSelector = opts.DefaultSelector
+		}
 		if opts.Resync == nil {
 			opts.Resync = options.Resync
 		}
@@ -204,7 +411,7 @@ func defaultOpts(config *rest.Config, opts Options) (Options, error) {
 	}
 
 	if opts.KeyFunction == nil {
-		opts.KeyFunction = cache.MetaNamespaceKeyFunc
+		opts.KeyFunction = kcpcache.ClusterAwareKeyFunc
 	}
 	return opts, nil
 }
@@ -248,3 +455,42 @@ func convertToDisableDeepCopyByGVK(disableDeepCopyByObject DisableDeepCopyByObje
 	}
 	return disableDeepCopyByGVK, nil
 }
+
+// TransformByObject associate a client.Object's GVK to the function transforming its objects
+// before they are cached.
+type TransformByObject map[client.Object]toolscache.TransformFunc
+
+func convertToTransformByGVK(transformByObject TransformByObject, defaultTransform toolscache.TransformFunc, scheme *runtime.Scheme) (internal.TransformFuncByGVK, error) {
+	transformByGVK := internal.TransformFuncByGVK{}
+	for obj, transform := range transformByObject {
+		gvk, err := apiutil.GVKForObject(obj, scheme)
+		if err != nil {
+			return nil, err
+		}
+		transformByGVK[gvk] = transform
+	}
+	if defaultTransform != nil {
+		transformByGVK[internal.GroupVersionKindAll] = defaultTransform
+	}
+	return transformByGVK, nil
+}
+
+// MetadataOnlyByObject associate a client.Object's GVK to force caching only the metadata of its objects.
+type MetadataOnlyByObject map[client.Object]bool
+
+func convertToMetadataOnlyByGVK(metadataOnlyByObject MetadataOnlyByObject, scheme *runtime.Scheme) (internal.MetadataOnlyByGVK, error) {
+	metadataOnlyByGVK := internal.MetadataOnlyByGVK{}
+	for obj, metadataOnly := range metadataOnlyByObject {
+		switch obj.(type) {
+		case ObjectAll, *ObjectAll:
+			metadataOnlyByGVK[internal.GroupVersionKindAll] = metadataOnly
+		default:
+			gvk, err := apiutil.GVKForObject(obj, scheme)
+			if err != nil {
+				return nil, err
+			}
+			metadataOnlyByGVK[gvk] = metadataOnly
+		}
+	}
+	return metadataOnlyByGVK, nil
+}
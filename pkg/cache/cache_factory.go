@@ -0,0 +1,55 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"github.com/kcp-dev/logicalcluster"
+	"k8s.io/client-go/rest"
+)
+
+// CacheFactory builds the cache of each logical cluster of a multi-cluster topology, e.g. of the
+// clusters of a cluster.ClusterSet, so that the caches can be built differently than with New,
+// e.g. as views of a cache shared by the clusters, or against physical clusters rather than
+// the logical clusters of a kcp server.
+type CacheFactory interface {
+	// NewClusterCache builds the cache of the given logical cluster, with the config and
+	// options of the cluster.
+	NewClusterCache(name logicalcluster.Name, config *rest.Config, opts Options) (Cache, error)
+}
+
+// CacheFactoryFunc is a CacheFactory function.
+type CacheFactoryFunc func(name logicalcluster.Name, config *rest.Config, opts Options) (Cache, error)
+
+var _ CacheFactory = CacheFactoryFunc(nil)
+
+// NewClusterCache implements CacheFactory.
+func (f CacheFactoryFunc) NewClusterCache(name logicalcluster.Name, config *rest.Config, opts Options) (Cache, error) {
+	return f(name, config, opts)
+}
+
+// DefaultCacheFactory builds the cache of each logical cluster with New.
+var DefaultCacheFactory CacheFactory = CacheFactoryFunc(func(_ logicalcluster.Name, config *rest.Config, opts Options) (Cache, error) {
+	return New(config, opts)
+})
+
+// BuilderFor returns a NewCacheFunc building the cache of the given logical cluster with the
+// factory.
+func BuilderFor(factory CacheFactory, name logicalcluster.Name) NewCacheFunc {
+	return func(config *rest.Config, opts Options) (Cache, error) {
+		return factory.NewClusterCache(name, config, opts)
+	}
+}
@@ -18,21 +18,28 @@ package cache_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"sort"
 	"strconv"
+	"sync"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	kscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	kcache "k8s.io/client-go/tools/cache"
@@ -191,6 +198,96 @@ var _ = Describe("Cache with selectors", func() {
 	})
 })
 
+var _ = Describe("Cache with list options", func() {
+	var (
+		server   *httptest.Server
+		mu       sync.Mutex
+		limits   []string
+		listedAt []time.Time
+	)
+
+	BeforeEach(func() {
+		limits, listedAt = nil, nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			query := req.URL.Query()
+			if query.Get("watch") == "true" {
+				// End the watch right away, so that the reflector relists.
+				return
+			}
+			mu.Lock()
+			limits = append(limits, query.Get("limit"))
+			if query.Get("continue") == "" {
+				listedAt = append(listedAt, time.Now())
+			}
+			mu.Unlock()
+
+			// Serve 3 pods, 2 per page at most.
+			list := &corev1.PodList{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "PodList"}}
+			list.ResourceVersion = "1"
+			first := 0
+			if query.Get("continue") != "" {
+				first = 2
+			}
+			for i := first; i < 3 && i < first+2; i++ {
+				list.Items = append(list.Items, corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod-" + strconv.Itoa(i)}})
+			}
+			if first == 0 && query.Get("limit") == "2" {
+				list.Continue = "page-2"
+			}
+			Expect(json.NewEncoder(w).Encode(list)).To(Succeed())
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	startCache := func(opts cache.Options) (cache.Cache, context.CancelFunc) {
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(corev1.SchemeGroupVersion.WithKind("Pod"), meta.RESTScopeNamespace)
+		opts.Mapper = mapper
+		informerCache, err := cache.New(&rest.Config{Host: server.URL}, opts)
+		Expect(err).NotTo(HaveOccurred())
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			defer GinkgoRecover()
+			Expect(informerCache.Start(ctx)).To(Succeed())
+		}()
+		_, err = informerCache.GetInformer(ctx, &corev1.Pod{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(informerCache.WaitForCacheSync(ctx)).To(BeTrue())
+		return informerCache, cancel
+	}
+
+	It("should list in chunks of the given size", func() {
+		informerCache, cancel := startCache(cache.Options{ListChunkSize: 2})
+		defer cancel()
+
+		pods := &corev1.PodList{}
+		Expect(informerCache.List(context.Background(), pods)).To(Succeed())
+		Expect(pods.Items).To(HaveLen(3))
+
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(limits[:2]).To(Equal([]string{"2", "2"}))
+	})
+
+	It("should delay the relists", func() {
+		_, cancel := startCache(cache.Options{RelistBackoff: &cache.RelistBackoff{Initial: 2 * time.Second, Max: 4 * time.Second}})
+		defer cancel()
+
+		Eventually(func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(listedAt)
+		}, 10*time.Second).Should(BeNumerically(">=", 2))
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(listedAt[1].Sub(listedAt[0])).To(BeNumerically(">=", 2*time.Second))
+	})
+})
+
 func CacheTest(createCacheFunc func(config *rest.Config, opts cache.Options) (cache.Cache, error), opts cache.Options) {
 	Describe("Cache test", func() {
 		var (
@@ -288,7 +385,7 @@ func CacheTest(createCacheFunc func(config *rest.Config, opts cache.Options) (ca
 				It("should be able to get objects that haven't been watched previously", func() {
 					By("getting the Kubernetes service")
 					svc := &corev1.Service{}
-					svcKey := client.ObjectKey{Namespace: "default", Name: "kubernetes"}
+					svcKey := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "default", Name: "kubernetes"}}
 					Expect(informerCache.Get(context.Background(), svcKey, svc)).To(Succeed())
 
 					By("verifying that the returned service looks reasonable")
@@ -363,7 +460,7 @@ func CacheTest(createCacheFunc func(config *rest.Config, opts cache.Options) (ca
 					It("should deep copy the object unless told otherwise", func() {
 						By("retrieving a specific pod from the cache")
 						out := &corev1.Pod{}
-						podKey := client.ObjectKey{Name: "test-pod-2", Namespace: testNamespaceTwo}
+						podKey := client.ObjectKey{NamespacedName: types.NamespacedName{Name: "test-pod-2", Namespace: testNamespaceTwo}}
 						Expect(informerCache.Get(context.Background(), podKey, out)).To(Succeed())
 
 						By("verifying the retrieved pod is equal to a known pod")
@@ -378,7 +475,7 @@ func CacheTest(createCacheFunc func(config *rest.Config, opts cache.Options) (ca
 				} else {
 					It("should not deep copy the object if UnsafeDisableDeepCopy is enabled", func() {
 						By("getting a specific pod from the cache twice")
-						podKey := client.ObjectKey{Name: "test-pod-2", Namespace: testNamespaceTwo}
+						podKey := client.ObjectKey{NamespacedName: types.NamespacedName{Name: "test-pod-2", Namespace: testNamespaceTwo}}
 						out1 := &corev1.Pod{}
 						Expect(informerCache.Get(context.Background(), podKey, out1)).To(Succeed())
 						out2 := &corev1.Pod{}
@@ -410,7 +507,7 @@ func CacheTest(createCacheFunc func(config *rest.Config, opts cache.Options) (ca
 				It("should return an error if the object is not found", func() {
 					By("getting a service that does not exists")
 					svc := &corev1.Service{}
-					svcKey := client.ObjectKey{Namespace: testNamespaceOne, Name: "unknown"}
+					svcKey := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: testNamespaceOne, Name: "unknown"}}
 
 					By("verifying that an error is returned")
 					err := informerCache.Get(context.Background(), svcKey, svc)
@@ -421,7 +518,7 @@ func CacheTest(createCacheFunc func(config *rest.Config, opts cache.Options) (ca
 				It("should return an error if getting object in unwatched namespace", func() {
 					By("getting a service that does not exists")
 					svc := &corev1.Service{}
-					svcKey := client.ObjectKey{Namespace: "unknown", Name: "unknown"}
+					svcKey := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "unknown", Name: "unknown"}}
 
 					By("verifying that an error is returned")
 					err := informerCache.Get(context.Background(), svcKey, svc)
@@ -492,7 +589,7 @@ func CacheTest(createCacheFunc func(config *rest.Config, opts cache.Options) (ca
 						Version: "v1",
 						Kind:    "Service",
 					})
-					svcKey := client.ObjectKey{Namespace: "default", Name: "kubernetes"}
+					svcKey := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "default", Name: "kubernetes"}}
 					Expect(informerCache.Get(context.Background(), svcKey, svc)).To(Succeed())
 
 					By("verifying that the returned service looks reasonable")
@@ -613,11 +710,11 @@ func CacheTest(createCacheFunc func(config *rest.Config, opts cache.Options) (ca
 					})
 
 					By("verifying that getting the node works with an empty namespace")
-					key1 := client.ObjectKey{Namespace: "", Name: testNodeOne}
+					key1 := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "", Name: testNodeOne}}
 					Expect(namespacedCache.Get(context.Background(), key1, node)).To(Succeed())
 
 					By("verifying that the namespace is ignored when getting a cluster-scoped resource")
-					key2 := client.ObjectKey{Namespace: "random", Name: testNodeOne}
+					key2 := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "random", Name: testNodeOne}}
 					Expect(namespacedCache.Get(context.Background(), key2, node)).To(Succeed())
 				})
 
@@ -633,7 +730,7 @@ func CacheTest(createCacheFunc func(config *rest.Config, opts cache.Options) (ca
 						uKnownPod2 := &unstructured.Unstructured{}
 						Expect(kscheme.Scheme.Convert(knownPod2, uKnownPod2, nil)).To(Succeed())
 
-						podKey := client.ObjectKey{Name: "test-pod-2", Namespace: testNamespaceTwo}
+						podKey := client.ObjectKey{NamespacedName: types.NamespacedName{Name: "test-pod-2", Namespace: testNamespaceTwo}}
 						Expect(informerCache.Get(context.Background(), podKey, out)).To(Succeed())
 
 						By("verifying the retrieved pod is equal to a known pod")
@@ -649,7 +746,7 @@ func CacheTest(createCacheFunc func(config *rest.Config, opts cache.Options) (ca
 				} else {
 					It("should not deep copy the object if UnsafeDisableDeepCopy is enabled", func() {
 						By("getting a specific pod from the cache twice")
-						podKey := client.ObjectKey{Name: "test-pod-2", Namespace: testNamespaceTwo}
+						podKey := client.ObjectKey{NamespacedName: types.NamespacedName{Name: "test-pod-2", Namespace: testNamespaceTwo}}
 						out1 := &unstructured.Unstructured{}
 						out1.SetGroupVersionKind(schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Pod"})
 						Expect(informerCache.Get(context.Background(), podKey, out1)).To(Succeed())
@@ -690,7 +787,7 @@ func CacheTest(createCacheFunc func(config *rest.Config, opts cache.Options) (ca
 						Version: "v1",
 						Kind:    "Service",
 					})
-					svcKey := client.ObjectKey{Namespace: testNamespaceOne, Name: "unknown"}
+					svcKey := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: testNamespaceOne, Name: "unknown"}}
 
 					By("verifying that an error is returned")
 					err := informerCache.Get(context.Background(), svcKey, svc)
@@ -700,7 +797,7 @@ func CacheTest(createCacheFunc func(config *rest.Config, opts cache.Options) (ca
 				It("should return an error if getting object in unwatched namespace", func() {
 					By("getting a service that does not exists")
 					svc := &corev1.Service{}
-					svcKey := client.ObjectKey{Namespace: "unknown", Name: "unknown"}
+					svcKey := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "unknown", Name: "unknown"}}
 
 					By("verifying that an error is returned")
 					err := informerCache.Get(context.Background(), svcKey, svc)
@@ -723,7 +820,7 @@ func CacheTest(createCacheFunc func(config *rest.Config, opts cache.Options) (ca
 					node := &corev1.Node{}
 
 					By("verifying that getting the node works with an empty namespace")
-					key1 := client.ObjectKey{Namespace: "", Name: testNodeOne}
+					key1 := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "", Name: testNodeOne}}
 					Expect(m.Get(context.Background(), key1, node)).To(Succeed())
 
 					By("verifying if the cluster scoped resources are not duplicated")
@@ -773,7 +870,7 @@ func CacheTest(createCacheFunc func(config *rest.Config, opts cache.Options) (ca
 						Version: "v1",
 						Kind:    "Service",
 					})
-					svcKey := client.ObjectKey{Namespace: "default", Name: "kubernetes"}
+					svcKey := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "default", Name: "kubernetes"}}
 					Expect(informerCache.Get(context.Background(), svcKey, svc)).To(Succeed())
 
 					By("verifying that the returned service looks reasonable")
@@ -894,11 +991,11 @@ func CacheTest(createCacheFunc func(config *rest.Config, opts cache.Options) (ca
 					})
 
 					By("verifying that getting the node works with an empty namespace")
-					key1 := client.ObjectKey{Namespace: "", Name: testNodeOne}
+					key1 := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "", Name: testNodeOne}}
 					Expect(namespacedCache.Get(context.Background(), key1, node)).To(Succeed())
 
 					By("verifying that the namespace is ignored when getting a cluster-scoped resource")
-					key2 := client.ObjectKey{Namespace: "random", Name: testNodeOne}
+					key2 := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "random", Name: testNodeOne}}
 					Expect(namespacedCache.Get(context.Background(), key2, node)).To(Succeed())
 				})
 
@@ -919,7 +1016,7 @@ func CacheTest(createCacheFunc func(config *rest.Config, opts cache.Options) (ca
 							Kind:    "Pod",
 						})
 
-						podKey := client.ObjectKey{Name: "test-pod-2", Namespace: testNamespaceTwo}
+						podKey := client.ObjectKey{NamespacedName: types.NamespacedName{Name: "test-pod-2", Namespace: testNamespaceTwo}}
 						Expect(informerCache.Get(context.Background(), podKey, out)).To(Succeed())
 
 						By("verifying the retrieved pod is equal to a known pod")
@@ -934,7 +1031,7 @@ func CacheTest(createCacheFunc func(config *rest.Config, opts cache.Options) (ca
 				} else {
 					It("should not deep copy the object if UnsafeDisableDeepCopy is enabled", func() {
 						By("getting a specific pod from the cache twice")
-						podKey := client.ObjectKey{Name: "test-pod-2", Namespace: testNamespaceTwo}
+						podKey := client.ObjectKey{NamespacedName: types.NamespacedName{Name: "test-pod-2", Namespace: testNamespaceTwo}}
 						out1 := &metav1.PartialObjectMetadata{}
 						out1.SetGroupVersionKind(schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Pod"})
 						Expect(informerCache.Get(context.Background(), podKey, out1)).To(Succeed())
@@ -976,7 +1073,7 @@ func CacheTest(createCacheFunc func(config *rest.Config, opts cache.Options) (ca
 						Version: "v1",
 						Kind:    "Service",
 					})
-					svcKey := client.ObjectKey{Namespace: testNamespaceOne, Name: "unknown"}
+					svcKey := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: testNamespaceOne, Name: "unknown"}}
 
 					By("verifying that an error is returned")
 					err := informerCache.Get(context.Background(), svcKey, svc)
@@ -986,7 +1083,7 @@ func CacheTest(createCacheFunc func(config *rest.Config, opts cache.Options) (ca
 				It("should return an error if getting object in unwatched namespace", func() {
 					By("getting a service that does not exists")
 					svc := &corev1.Service{}
-					svcKey := client.ObjectKey{Namespace: "unknown", Name: "unknown"}
+					svcKey := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "unknown", Name: "unknown"}}
 
 					By("verifying that an error is returned")
 					err := informerCache.Get(context.Background(), svcKey, svc)
@@ -0,0 +1,337 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	kcpcache "github.com/kcp-dev/apimachinery/pkg/cache"
+	"github.com/kcp-dev/logicalcluster"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ErrReadOnlyInformer is returned when writing to the store of an informer shared by ClusterInformers,
+// or changing its configuration.  The informers belong to the cache, which runs and configures them.
+var ErrReadOnlyInformer = errors.New("the informer is shared by the cache and is read-only")
+
+// ClusterInformers shares the informers of a cache with code using client-go informers and listers,
+// scoped to a logical cluster, so that the code doesn't watch the same objects again:
+//
+//	informer, err := cache.NewClusterInformers(c, cluster).InformerFor(ctx, &appsv1.Deployment{})
+//	...
+//	lister := appsv1listers.NewDeploymentLister(informer.GetIndexer())
+//
+// The informers are read-only views of the informers of the cache:
+//
+// - their stores and indexers only hold the objects of the logical cluster, keyed by namespace and
+// name like the stores of client-go, and return ErrReadOnlyInformer when written to;
+//
+// - their handlers are only notified of the events of the objects of the logical cluster;
+//
+// - Run blocks until its channel is closed without running the informer, which the cache runs,
+// so that informer factories can "start" them;
+//
+// - AddIndexers and SetWatchErrorHandler return ErrReadOnlyInformer, add the indexes with the
+// IndexField of the cache instead.
+//
+// The objects are the ones of the cache, they must not be modified.  The stores of the cache must
+// be keyed with the default Options.KeyFunction.
+type ClusterInformers struct {
+	informers Informers
+	cluster   logicalcluster.Name
+}
+
+// NewClusterInformers returns the ClusterInformers sharing the informers of the cache with the
+// objects of the given logical cluster.  The informers of the cache must be client-go
+// SharedIndexInformers, like the ones of the caches returned by New.
+func NewClusterInformers(informers Informers, cluster logicalcluster.Name) *ClusterInformers {
+	return &ClusterInformers{informers: informers, cluster: cluster}
+}
+
+// Cluster returns the logical cluster of the informers.
+func (c *ClusterInformers) Cluster() logicalcluster.Name {
+	return c.cluster
+}
+
+// InformerFor returns the informer of the type of obj, starting the informer of the cache
+// if needed.
+func (c *ClusterInformers) InformerFor(ctx context.Context, obj client.Object) (toolscache.SharedIndexInformer, error) {
+	informer, err := c.informers.GetInformer(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	return c.share(informer)
+}
+
+// InformerForKind returns the informer of the given GroupVersionKind, starting the informer
+// of the cache if needed.
+func (c *ClusterInformers) InformerForKind(ctx context.Context, gvk schema.GroupVersionKind) (toolscache.SharedIndexInformer, error) {
+	informer, err := c.informers.GetInformerForKind(ctx, gvk)
+	if err != nil {
+		return nil, err
+	}
+	return c.share(informer)
+}
+
+func (c *ClusterInformers) share(informer Informer) (toolscache.SharedIndexInformer, error) {
+	shared, ok := informer.(toolscache.SharedIndexInformer)
+	if !ok {
+		return nil, fmt.Errorf("informer %T of the cache is not a SharedIndexInformer and can't be shared", informer)
+	}
+	return &clusterSharedInformer{
+		informer: shared,
+		indexer:  &clusterIndexer{indexer: shared.GetIndexer(), cluster: c.cluster},
+	}, nil
+}
+
+// clusterSharedInformer is a read-only view of a shared informer restricted to a logical cluster.
+type clusterSharedInformer struct {
+	informer toolscache.SharedIndexInformer
+	indexer  *clusterIndexer
+}
+
+var _ toolscache.SharedIndexInformer = &clusterSharedInformer{}
+
+// AddEventHandler implements toolscache.SharedInformer.
+func (i *clusterSharedInformer) AddEventHandler(handler toolscache.ResourceEventHandler) {
+	i.informer.AddEventHandler(i.filter(handler))
+}
+
+// AddEventHandlerWithResyncPeriod implements toolscache.SharedInformer.
+func (i *clusterSharedInformer) AddEventHandlerWithResyncPeriod(handler toolscache.ResourceEventHandler, resyncPeriod time.Duration) {
+	i.informer.AddEventHandlerWithResyncPeriod(i.filter(handler), resyncPeriod)
+}
+
+func (i *clusterSharedInformer) filter(handler toolscache.ResourceEventHandler) toolscache.ResourceEventHandler {
+	return toolscache.FilteringResourceEventHandler{
+		FilterFunc: func(obj interface{}) bool {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			return i.indexer.contains(obj)
+		},
+		Handler: handler,
+	}
+}
+
+// GetStore implements toolscache.SharedInformer.
+func (i *clusterSharedInformer) GetStore() toolscache.Store {
+	return i.indexer
+}
+
+// GetIndexer implements toolscache.SharedIndexInformer.
+func (i *clusterSharedInformer) GetIndexer() toolscache.Indexer {
+	return i.indexer
+}
+
+// GetController implements toolscache.SharedInformer.  The informer is its own controller,
+// which can't be run either.
+func (i *clusterSharedInformer) GetController() toolscache.Controller {
+	return i
+}
+
+// Run implements toolscache.SharedInformer.  It blocks until stopCh is closed, the informer
+// is run by the cache.
+func (i *clusterSharedInformer) Run(stopCh <-chan struct{}) {
+	<-stopCh
+}
+
+// HasSynced implements toolscache.SharedInformer.
+func (i *clusterSharedInformer) HasSynced() bool {
+	return i.informer.HasSynced()
+}
+
+// LastSyncResourceVersion implements toolscache.SharedInformer.
+func (i *clusterSharedInformer) LastSyncResourceVersion() string {
+	return i.informer.LastSyncResourceVersion()
+}
+
+// SetWatchErrorHandler implements toolscache.SharedInformer.
+func (i *clusterSharedInformer) SetWatchErrorHandler(toolscache.WatchErrorHandler) error {
+	return ErrReadOnlyInformer
+}
+
+// AddIndexers implements toolscache.SharedIndexInformer.
+func (i *clusterSharedInformer) AddIndexers(toolscache.Indexers) error {
+	return ErrReadOnlyInformer
+}
+
+// clusterIndexer is a read-only view of the indexer of a shared informer restricted to a logical
+// cluster, keyed by namespace and name.
+type clusterIndexer struct {
+	indexer toolscache.Indexer
+	cluster logicalcluster.Name
+}
+
+var _ toolscache.Indexer = &clusterIndexer{}
+
+// contains returns whether obj is in the logical cluster of the indexer.
+func (i *clusterIndexer) contains(obj interface{}) bool {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return false
+	}
+	return logicalcluster.From(accessor) == i.cluster
+}
+
+// filter returns the objects in the logical cluster of the indexer.
+func (i *clusterIndexer) filter(objs []interface{}) []interface{} {
+	filtered := make([]interface{}, 0, len(objs))
+	for _, obj := range objs {
+		if i.contains(obj) {
+			filtered = append(filtered, obj)
+		}
+	}
+	return filtered
+}
+
+// keys returns the namespace and name keys of objs.
+func keys(objs []interface{}) ([]string, error) {
+	keys := make([]string, 0, len(objs))
+	for _, obj := range objs {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, namespacedKey(accessor.GetNamespace(), accessor.GetName()))
+	}
+	return keys, nil
+}
+
+func namespacedKey(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
+
+// Add implements toolscache.Store.
+func (i *clusterIndexer) Add(interface{}) error {
+	return ErrReadOnlyInformer
+}
+
+// Update implements toolscache.Store.
+func (i *clusterIndexer) Update(interface{}) error {
+	return ErrReadOnlyInformer
+}
+
+// Delete implements toolscache.Store.
+func (i *clusterIndexer) Delete(interface{}) error {
+	return ErrReadOnlyInformer
+}
+
+// Replace implements toolscache.Store.
+func (i *clusterIndexer) Replace([]interface{}, string) error {
+	return ErrReadOnlyInformer
+}
+
+// Resync implements toolscache.Store.
+func (i *clusterIndexer) Resync() error {
+	return ErrReadOnlyInformer
+}
+
+// List implements toolscache.Store.
+func (i *clusterIndexer) List() []interface{} {
+	objs, err := i.indexer.ByIndex(kcpcache.ClusterIndexName, kcpcache.ToClusterAwareKey(i.cluster.String(), "", ""))
+	if err != nil {
+		return i.filter(i.indexer.List())
+	}
+	return objs
+}
+
+// ListKeys implements toolscache.Store.
+func (i *clusterIndexer) ListKeys() []string {
+	keys, _ := keys(i.List())
+	return keys
+}
+
+// Get implements toolscache.Store.
+func (i *clusterIndexer) Get(obj interface{}) (interface{}, bool, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, false, err
+	}
+	return i.GetByKey(namespacedKey(accessor.GetNamespace(), accessor.GetName()))
+}
+
+// GetByKey implements toolscache.Store.  The key is the namespace and name of the object.
+func (i *clusterIndexer) GetByKey(key string) (interface{}, bool, error) {
+	namespace, name, err := toolscache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil, false, err
+	}
+	return i.indexer.GetByKey(kcpcache.ToClusterAwareKey(i.cluster.String(), namespace, name))
+}
+
+// Index implements toolscache.Indexer.
+func (i *clusterIndexer) Index(indexName string, obj interface{}) ([]interface{}, error) {
+	objs, err := i.indexer.Index(indexName, obj)
+	if err != nil {
+		return nil, err
+	}
+	return i.filter(objs), nil
+}
+
+// IndexKeys implements toolscache.Indexer.
+func (i *clusterIndexer) IndexKeys(indexName, indexedValue string) ([]string, error) {
+	objs, err := i.ByIndex(indexName, indexedValue)
+	if err != nil {
+		return nil, err
+	}
+	return keys(objs)
+}
+
+// ListIndexFuncValues implements toolscache.Indexer.  The values are the ones of the objects
+// of all the logical clusters of the informer.
+func (i *clusterIndexer) ListIndexFuncValues(indexName string) []string {
+	return i.indexer.ListIndexFuncValues(indexName)
+}
+
+// ByIndex implements toolscache.Indexer.  The namespace index is served by the cluster and
+// namespace index of the informer.
+func (i *clusterIndexer) ByIndex(indexName, indexedValue string) ([]interface{}, error) {
+	if indexName == toolscache.NamespaceIndex {
+		if _, ok := i.indexer.GetIndexers()[kcpcache.ClusterAndNamespaceIndexName]; ok {
+			return i.indexer.ByIndex(kcpcache.ClusterAndNamespaceIndexName, kcpcache.ToClusterAwareKey(i.cluster.String(), indexedValue, ""))
+		}
+	}
+	objs, err := i.indexer.ByIndex(indexName, indexedValue)
+	if err != nil {
+		return nil, err
+	}
+	return i.filter(objs), nil
+}
+
+// GetIndexers implements toolscache.Indexer.  It returns a copy of the indexers of the informer.
+func (i *clusterIndexer) GetIndexers() toolscache.Indexers {
+	indexers := toolscache.Indexers{}
+	for name, indexFunc := range i.indexer.GetIndexers() {
+		indexers[name] = indexFunc
+	}
+	return indexers
+}
+
+// AddIndexers implements toolscache.Indexer.
+func (i *clusterIndexer) AddIndexers(toolscache.Indexers) error {
+	return ErrReadOnlyInformer
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"sync"
+
+	kcpcache "github.com/kcp-dev/apimachinery/pkg/cache"
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+	fcache "k8s.io/client-go/tools/cache/testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// sharedInformers are Informers returning the same shared informer for every type.
+type sharedInformers struct {
+	Informers
+	informer toolscache.SharedIndexInformer
+}
+
+func (s *sharedInformers) GetInformer(context.Context, client.Object) (Informer, error) {
+	return s.informer, nil
+}
+
+func (s *sharedInformers) GetInformerForKind(context.Context, schema.GroupVersionKind) (Informer, error) {
+	return s.informer, nil
+}
+
+var _ = Describe("ClusterInformers", func() {
+	ctx := context.Background()
+	a := logicalcluster.New("root:a")
+	b := logicalcluster.New("root:b")
+	newPod := func(cluster logicalcluster.Name, namespace, name string) *corev1.Pod {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: map[string]string{"app": "test"}}}
+		pod.ClusterName = cluster.String()
+		return pod
+	}
+
+	var source *fcache.FakeControllerSource
+	var informer toolscache.SharedIndexInformer
+	var stop chan struct{}
+	BeforeEach(func() {
+		source = fcache.NewFakeControllerSource()
+		source.Add(newPod(a, "default", "a-1"))
+		source.Add(newPod(a, "other", "a-2"))
+		source.Add(newPod(b, "default", "b-1"))
+		informer = toolscache.NewSharedIndexInformerWithOptions(source, &corev1.Pod{},
+			toolscache.WithKeyFunction(kcpcache.ClusterAwareKeyFunc),
+			toolscache.WithIndexers(toolscache.Indexers{
+				toolscache.NamespaceIndex:             toolscache.MetaNamespaceIndexFunc,
+				kcpcache.ClusterIndexName:             kcpcache.ClusterIndexFunc,
+				kcpcache.ClusterAndNamespaceIndexName: kcpcache.ClusterAndNamespaceIndexFunc,
+			}))
+		stop = make(chan struct{})
+		go informer.Run(stop)
+		Eventually(informer.HasSynced).Should(BeTrue())
+	})
+	AfterEach(func() {
+		close(stop)
+	})
+
+	sharedFor := func(cluster logicalcluster.Name) toolscache.SharedIndexInformer {
+		shared, err := NewClusterInformers(&sharedInformers{informer: informer}, cluster).InformerFor(ctx, &corev1.Pod{})
+		Expect(err).NotTo(HaveOccurred())
+		return shared
+	}
+	names := func(objs []interface{}) []string {
+		var names []string
+		for _, obj := range objs {
+			names = append(names, obj.(*corev1.Pod).Name)
+		}
+		return names
+	}
+
+	It("should serve client-go listers with the objects of the logical cluster", func() {
+		lister := corev1listers.NewPodLister(sharedFor(a).GetIndexer())
+
+		pod, err := lister.Pods("default").Get("a-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.ClusterName).To(Equal(a.String()))
+		_, err = lister.Pods("default").Get("b-1")
+		Expect(err).To(HaveOccurred())
+
+		pods, err := lister.List(labels.Everything())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pods).To(HaveLen(2))
+		pods, err = lister.Pods("default").List(labels.SelectorFromSet(labels.Set{"app": "test"}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pods).To(HaveLen(1))
+		Expect(pods[0].Name).To(Equal("a-1"))
+	})
+
+	It("should key the objects by namespace and name", func() {
+		indexer := sharedFor(b).GetIndexer()
+		Expect(indexer.ListKeys()).To(ConsistOf("default/b-1"))
+		Expect(names(indexer.List())).To(ConsistOf("b-1"))
+
+		_, exists, err := indexer.Get(newPod(b, "default", "b-1"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exists).To(BeTrue())
+
+		keys, err := indexer.IndexKeys(toolscache.NamespaceIndex, "default")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keys).To(ConsistOf("default/b-1"))
+	})
+
+	It("should only notify the handlers of the events of the logical cluster", func() {
+		var mu sync.Mutex
+		var added []string
+		sharedFor(a).AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				mu.Lock()
+				defer mu.Unlock()
+				added = append(added, obj.(*corev1.Pod).Name)
+			},
+		})
+		source.Add(newPod(b, "default", "b-2"))
+		source.Add(newPod(a, "default", "a-3"))
+
+		Eventually(func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]string(nil), added...)
+		}).Should(ConsistOf("a-1", "a-2", "a-3"))
+	})
+
+	It("should be read-only", func() {
+		shared := sharedFor(a)
+		indexer := shared.GetIndexer()
+		Expect(indexer.Add(newPod(a, "default", "a-4"))).To(MatchError(ErrReadOnlyInformer))
+		Expect(indexer.Delete(newPod(a, "default", "a-1"))).To(MatchError(ErrReadOnlyInformer))
+		Expect(indexer.Replace(nil, "")).To(MatchError(ErrReadOnlyInformer))
+		Expect(shared.GetStore().Update(newPod(a, "default", "a-1"))).To(MatchError(ErrReadOnlyInformer))
+		Expect(shared.AddIndexers(toolscache.Indexers{"foo": toolscache.MetaNamespaceIndexFunc})).To(MatchError(ErrReadOnlyInformer))
+		Expect(shared.SetWatchErrorHandler(nil)).To(MatchError(ErrReadOnlyInformer))
+
+		delete(indexer.GetIndexers(), toolscache.NamespaceIndex)
+		Expect(informer.GetIndexer().GetIndexers()).To(HaveKey(toolscache.NamespaceIndex))
+		Expect(informer.GetIndexer().List()).To(HaveLen(3))
+	})
+
+	It("should not run the informer of the cache", func() {
+		stopped := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			sharedFor(a).Run(stopped)
+			close(done)
+		}()
+		Consistently(done).ShouldNot(BeClosed())
+		close(stopped)
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("should fail for the informers which are not shared index informers", func() {
+		_, err := NewClusterInformers(&sharedInformers{}, a).InformerForKind(ctx, corev1.SchemeGroupVersion.WithKind("Pod"))
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -22,7 +22,9 @@ import (
 	"reflect"
 	"strings"
 
+	"github.com/kcp-dev/logicalcluster"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -36,6 +38,7 @@ var (
 	_ Informers     = &informerCache{}
 	_ client.Reader = &informerCache{}
 	_ Cache         = &informerCache{}
+	_ QuorumSyncer  = &informerCache{}
 )
 
 // ErrCacheNotStarted is returned when trying to read from the cache that wasn't started.
@@ -45,6 +48,37 @@ func (*ErrCacheNotStarted) Error() string {
 	return "the cache is not started, can not read objects"
 }
 
+// ErrKindNotServed is returned when trying to read objects of a kind that the
+// API server doesn't serve, or doesn't serve anymore, e.g. because the APIBinding
+// exporting it was removed.  The cache keeps watching the kind and reads succeed
+// again once it is served.
+type ErrKindNotServed struct {
+	GroupVersionKind schema.GroupVersionKind
+
+	// Err is the error the kind was found not to be served with, if any, e.g. the
+	// NoKindMatchError of the RESTMapper.
+	Err error
+}
+
+func (e *ErrKindNotServed) Error() string {
+	return fmt.Sprintf("kind %s is not served by the API server", e.GroupVersionKind)
+}
+
+// Unwrap returns the error the kind was found not to be served with.
+func (e *ErrKindNotServed) Unwrap() error {
+	return e.Err
+}
+
+// ErrMetadataOnly is returned when reading the objects of a kind only the metadata of which is
+// cached, see Options.MetadataOnlyByObject, other than as metav1.PartialObjectMetadata.
+type ErrMetadataOnly struct {
+	GroupVersionKind schema.GroupVersionKind
+}
+
+func (e *ErrMetadataOnly) Error() string {
+	return fmt.Sprintf("only the metadata of kind %s is cached, read it as metav1.PartialObjectMetadata", e.GroupVersionKind)
+}
+
 // informerCache is a Kubernetes Object cache populated from InformersMap.  informerCache wraps an InformersMap.
 type informerCache struct {
 	*internal.InformersMap
@@ -56,8 +90,14 @@ func (ip *informerCache) Get(ctx context.Context, key client.ObjectKey, out clie
 	if err != nil {
 		return err
 	}
+	if _, isMetadata := out.(*metav1.PartialObjectMetadata); !isMetadata && ip.IsMetadataOnly(gvk) {
+		return &ErrMetadataOnly{GroupVersionKind: gvk}
+	}
 
 	started, cache, err := ip.InformersMap.Get(ctx, gvk, out)
+	if apimeta.IsNoMatchError(err) {
+		return &ErrKindNotServed{GroupVersionKind: gvk, Err: err}
+	}
 	if err != nil {
 		return err
 	}
@@ -65,6 +105,9 @@ func (ip *informerCache) Get(ctx context.Context, key client.ObjectKey, out clie
 	if !started {
 		return &ErrCacheNotStarted{}
 	}
+	if cache.NotServed() {
+		return &ErrKindNotServed{GroupVersionKind: gvk}
+	}
 	return cache.Reader.Get(ctx, key, out)
 }
 
@@ -74,8 +117,14 @@ func (ip *informerCache) List(ctx context.Context, out client.ObjectList, opts .
 	if err != nil {
 		return err
 	}
+	if _, isMetadata := out.(*metav1.PartialObjectMetadataList); !isMetadata && ip.IsMetadataOnly(*gvk) {
+		return &ErrMetadataOnly{GroupVersionKind: *gvk}
+	}
 
 	started, cache, err := ip.InformersMap.Get(ctx, *gvk, cacheTypeObj)
+	if apimeta.IsNoMatchError(err) {
+		return &ErrKindNotServed{GroupVersionKind: *gvk, Err: err}
+	}
 	if err != nil {
 		return err
 	}
@@ -83,6 +132,9 @@ func (ip *informerCache) List(ctx context.Context, out client.ObjectList, opts .
 	if !started {
 		return &ErrCacheNotStarted{}
 	}
+	if cache.NotServed() {
+		return &ErrKindNotServed{GroupVersionKind: *gvk}
+	}
 
 	return cache.Reader.List(ctx, out, opts...)
 }
@@ -169,6 +221,10 @@ func (ip *informerCache) NeedLeaderElection() bool {
 // to List. For one-to-one compatibility with "normal" field selectors, only return one value.
 // The values may be anything.  They will automatically be prefixed with the namespace of the
 // given object, if present.  The objects passed are guaranteed to be objects of the correct type.
+//
+// The values are also indexed per logical cluster, so that a List with a field selector and
+// a context targeting a cluster only returns the objects of that cluster.  Use a context
+// targeting logicalcluster.Wildcard, or no cluster, to match the objects of all the clusters.
 func (ip *informerCache) IndexField(ctx context.Context, obj client.Object, field string, extractValue client.IndexerFunc) error {
 	informer, err := ip.GetInformer(ctx, obj)
 	if err != nil {
@@ -189,24 +245,27 @@ func indexByField(indexer Informer, field string, extractor client.IndexerFunc)
 			return nil, err
 		}
 		ns := meta.GetNamespace()
+		cluster := logicalcluster.From(obj)
 
 		rawVals := extractor(obj)
-		var vals []string
-		if ns == "" {
-			// if we're not doubling the keys for the namespaced case, just re-use what was returned to us
-			vals = rawVals
-		} else {
-			// if we need to add non-namespaced versions too, double the length
-			vals = make([]string, len(rawVals)*2)
-		}
-		for i, rawVal := range rawVals {
+		vals := make([]string, 0, len(rawVals)*4)
+		for _, rawVal := range rawVals {
 			// save a namespaced variant, so that we can ask
 			// "what are all the object matching a given index *in a given namespace*"
-			vals[i] = internal.KeyToNamespacedKey(ns, rawVal)
+			vals = append(vals, internal.KeyToNamespacedKey(ns, rawVal))
 			if ns != "" {
 				// if we have a namespace, also inject a special index key for listing
 				// regardless of the object namespace
-				vals[i+len(rawVals)] = internal.KeyToNamespacedKey("", rawVal)
+				vals = append(vals, internal.KeyToNamespacedKey("", rawVal))
+			}
+			if cluster.Empty() {
+				continue
+			}
+			// save the same variants scoped to the logical cluster of the object, so that
+			// lists targeting a single cluster don't match the objects of the other ones
+			vals = append(vals, internal.KeyToClusterNamespacedKey(cluster, ns, rawVal))
+			if ns != "" {
+				vals = append(vals, internal.KeyToClusterNamespacedKey(cluster, "", rawVal))
 			}
 		}
 
@@ -17,17 +17,37 @@ limitations under the License.
 package cache
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	toolscache "k8s.io/client-go/tools/cache"
 
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
 	"sigs.k8s.io/controller-runtime/pkg/cache/internal"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllertest"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 	crscheme "sigs.k8s.io/controller-runtime/pkg/scheme"
 )
 
@@ -92,3 +112,612 @@ var _ = Describe("ip.objectTypeForListObject", func() {
 		})
 	})
 })
+
+var _ = Describe("indexByField", func() {
+	It("should index the values per namespace and per logical cluster", func() {
+		informer := toolscache.NewSharedIndexInformer(&toolscache.ListWatch{}, &corev1.Pod{}, 0, toolscache.Indexers{})
+		Expect(indexByField(informer, "spec.nodeName", func(obj client.Object) []string {
+			return []string{obj.(*corev1.Pod).Spec.NodeName}
+		})).To(Succeed())
+
+		for _, cluster := range []string{"root:a", "root:b"} {
+			Expect(informer.GetIndexer().Add(&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default", ClusterName: cluster},
+				Spec:       corev1.PodSpec{NodeName: "node"},
+			})).To(Succeed())
+		}
+
+		byKey := func(key string) []string {
+			objs, err := informer.GetIndexer().ByIndex(internal.FieldIndexName("spec.nodeName"), key)
+			Expect(err).NotTo(HaveOccurred())
+			var clusters []string
+			for _, obj := range objs {
+				clusters = append(clusters, obj.(*corev1.Pod).ClusterName)
+			}
+			return clusters
+		}
+		Expect(byKey(internal.KeyToNamespacedKey("default", "node"))).To(ConsistOf("root:a", "root:b"))
+		Expect(byKey(internal.KeyToNamespacedKey("", "node"))).To(ConsistOf("root:a", "root:b"))
+		Expect(byKey(internal.KeyToClusterNamespacedKey(logicalcluster.New("root:a"), "default", "node"))).To(ConsistOf("root:a"))
+		Expect(byKey(internal.KeyToClusterNamespacedKey(logicalcluster.New("root:b"), "", "node"))).To(ConsistOf("root:b"))
+	})
+})
+
+var _ = Describe("SingleObject", func() {
+	It("should select the object by name and namespace", func() {
+		listOpts := &metav1.ListOptions{}
+		internal.Selector(SingleObject("ns", "config")).ApplyToList(listOpts)
+		Expect(listOpts.FieldSelector).To(Equal("metadata.name=config,metadata.namespace=ns"))
+		Expect(listOpts.LabelSelector).To(BeEmpty())
+	})
+
+	It("should select cluster-scoped objects by name only", func() {
+		listOpts := &metav1.ListOptions{}
+		internal.Selector(SingleObject("", "cluster")).ApplyToList(listOpts)
+		Expect(listOpts.FieldSelector).To(Equal("metadata.name=cluster"))
+	})
+})
+
+var _ = Describe("MetadataOnlyByObject", func() {
+	var ip *informerCache
+
+	BeforeEach(func() {
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(corev1.SchemeGroupVersion.WithKind("Pod"), meta.RESTScopeNamespace)
+		mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+		c, err := New(&rest.Config{Host: "https://localhost"}, Options{
+			Scheme:               scheme.Scheme,
+			Mapper:               mapper,
+			MetadataOnlyByObject: MetadataOnlyByObject{&corev1.Pod{}: true},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		ip = c.(*informerCache)
+	})
+
+	It("should share the metadata-only informer of a kind whatever the requested type", func() {
+		typed, err := ip.GetInformer(context.Background(), &corev1.Pod{})
+		Expect(err).NotTo(HaveOccurred())
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Pod"))
+		unstructuredInformer, err := ip.GetInformer(context.Background(), u)
+		Expect(err).NotTo(HaveOccurred())
+		metadata := &metav1.PartialObjectMetadata{}
+		metadata.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Pod"))
+		metadataInformer, err := ip.GetInformer(context.Background(), metadata)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(typed).To(BeIdenticalTo(metadataInformer))
+		Expect(unstructuredInformer).To(BeIdenticalTo(metadataInformer))
+
+		other, err := ip.GetInformer(context.Background(), &corev1.ConfigMap{})
+		Expect(err).NotTo(HaveOccurred())
+		otherMetadata := &metav1.PartialObjectMetadata{}
+		otherMetadata.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("ConfigMap"))
+		otherMetadataInformer, err := ip.GetInformer(context.Background(), otherMetadata)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(other).NotTo(BeIdenticalTo(otherMetadataInformer))
+	})
+
+	It("should refuse to read the objects of a metadata-only kind other than as metadata", func() {
+		var errMetadataOnly *ErrMetadataOnly
+		err := ip.Get(context.Background(), client.ObjectKey{}, &corev1.Pod{})
+		Expect(errors.As(err, &errMetadataOnly)).To(BeTrue())
+		err = ip.List(context.Background(), &corev1.PodList{})
+		Expect(errors.As(err, &errMetadataOnly)).To(BeTrue())
+
+		metadataList := &metav1.PartialObjectMetadataList{}
+		metadataList.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("PodList"))
+		err = ip.List(context.Background(), metadataList)
+		Expect(errors.As(err, &errMetadataOnly)).To(BeFalse())
+		Expect(err).To(BeAssignableToTypeOf(&ErrCacheNotStarted{}))
+	})
+})
+
+var _ = Describe("Start", func() {
+	It("should return once the informers are stopped, dropping their objects", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if r.URL.Query().Get("watch") == "true" {
+				w.WriteHeader(http.StatusOK)
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+				return
+			}
+			_ = json.NewEncoder(w).Encode(&corev1.PodList{
+				TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "PodList"},
+				ListMeta: metav1.ListMeta{ResourceVersion: "1"},
+				Items:    []corev1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", ResourceVersion: "1"}}},
+			})
+		}))
+		defer server.Close()
+
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(corev1.SchemeGroupVersion.WithKind("Pod"), meta.RESTScopeNamespace)
+		c, err := New(&rest.Config{Host: server.URL}, Options{Mapper: mapper})
+		Expect(err).NotTo(HaveOccurred())
+		informer, err := c.GetInformer(context.Background(), &corev1.Pod{})
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(c.Start(ctx)).To(Succeed())
+		}()
+		Expect(c.WaitForCacheSync(ctx)).To(BeTrue())
+		Expect(informer.(toolscache.SharedIndexInformer).GetStore().ListKeys()).To(ConsistOf("/default/foo"))
+
+		cancel()
+		Eventually(done).Should(BeClosed())
+		Expect(informer.(toolscache.SharedIndexInformer).GetStore().ListKeys()).To(BeEmpty())
+	})
+})
+
+var _ = Describe("kinds not served", func() {
+	It("should fail the reads of a kind until it is served, without blocking the sync of the cache", func() {
+		var served int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if atomic.LoadInt32(&served) == 0 {
+				w.WriteHeader(http.StatusNotFound)
+				_ = json.NewEncoder(w).Encode(&metav1.Status{
+					TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Status"},
+					Status:   metav1.StatusFailure,
+					Reason:   metav1.StatusReasonNotFound,
+					Code:     http.StatusNotFound,
+				})
+				return
+			}
+			if r.URL.Query().Get("watch") == "true" {
+				w.WriteHeader(http.StatusOK)
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+				return
+			}
+			_ = json.NewEncoder(w).Encode(&corev1.ConfigMapList{
+				TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMapList"},
+				ListMeta: metav1.ListMeta{ResourceVersion: "1"},
+				Items:    []corev1.ConfigMap{{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", ResourceVersion: "1"}}},
+			})
+		}))
+		defer server.Close()
+
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+		c, err := New(&rest.Config{Host: server.URL}, Options{Mapper: mapper})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = c.GetInformer(context.Background(), &corev1.ConfigMap{})
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			defer GinkgoRecover()
+			Expect(c.Start(ctx)).To(Succeed())
+		}()
+		syncCtx, syncCancel := context.WithTimeout(ctx, 10*time.Second)
+		defer syncCancel()
+		Expect(c.WaitForCacheSync(syncCtx)).To(BeTrue())
+
+		var notServed *ErrKindNotServed
+		key := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "default", Name: "foo"}}
+		err = c.Get(ctx, key, &corev1.ConfigMap{})
+		Expect(errors.As(err, &notServed)).To(BeTrue(), "unexpected error %v", err)
+		Expect(notServed.GroupVersionKind).To(Equal(corev1.SchemeGroupVersion.WithKind("ConfigMap")))
+		err = c.List(ctx, &corev1.ConfigMapList{})
+		Expect(errors.As(err, &notServed)).To(BeTrue(), "unexpected error %v", err)
+
+		By("reading the kind again once it is served")
+		atomic.StoreInt32(&served, 1)
+		Eventually(func() error { return c.Get(ctx, key, &corev1.ConfigMap{}) }, 10*time.Second).Should(Succeed())
+		list := &corev1.ConfigMapList{}
+		Expect(c.List(ctx, list)).To(Succeed())
+		Expect(list.Items).To(HaveLen(1))
+	})
+
+	It("should fail the reads of a kind the RESTMapper doesn't know with ErrKindNotServed", func() {
+		c, err := New(&rest.Config{Host: "http://127.0.0.1:1"}, Options{Mapper: meta.NewDefaultRESTMapper(nil)})
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			defer GinkgoRecover()
+			Expect(c.Start(ctx)).To(Succeed())
+		}()
+		Expect(c.WaitForCacheSync(ctx)).To(BeTrue())
+
+		var notServed *ErrKindNotServed
+		err = c.Get(ctx, client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "default", Name: "foo"}}, &corev1.Secret{})
+		Expect(errors.As(err, &notServed)).To(BeTrue(), "unexpected error %v", err)
+		Expect(notServed.GroupVersionKind).To(Equal(corev1.SchemeGroupVersion.WithKind("Secret")))
+		Expect(meta.IsNoMatchError(notServed.Err)).To(BeTrue())
+		err = c.List(ctx, &corev1.SecretList{})
+		Expect(errors.As(err, &notServed)).To(BeTrue(), "unexpected error %v", err)
+	})
+})
+
+var _ = Describe("quorum sync", func() {
+	It("should wait until the cache observed the objects listed from the API server", func() {
+		configMap := func(name, resourceVersion string) corev1.ConfigMap {
+			return corev1.ConfigMap{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", ResourceVersion: resourceVersion},
+			}
+		}
+		quorumListed := make(chan struct{})
+		var once sync.Once
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch {
+			case r.URL.Query().Get("watch") == "true":
+				w.WriteHeader(http.StatusOK)
+				w.(http.Flusher).Flush()
+				select {
+				case <-quorumListed:
+				case <-r.Context().Done():
+					return
+				}
+				// Deliver the writes made before the quorum list late, as a lagging watch would.
+				time.Sleep(500 * time.Millisecond)
+				enc := json.NewEncoder(w)
+				foo, stale := configMap("foo", "3"), configMap("stale", "2")
+				_ = enc.Encode(map[string]interface{}{"type": "MODIFIED", "object": &foo})
+				_ = enc.Encode(map[string]interface{}{"type": "DELETED", "object": &stale})
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+			case r.URL.Query().Get("resourceVersion") == "":
+				// A quorum read.
+				_ = json.NewEncoder(w).Encode(&corev1.ConfigMapList{
+					TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMapList"},
+					ListMeta: metav1.ListMeta{ResourceVersion: "3"},
+					Items:    []corev1.ConfigMap{configMap("foo", "3")},
+				})
+				once.Do(func() { close(quorumListed) })
+			default:
+				_ = json.NewEncoder(w).Encode(&corev1.ConfigMapList{
+					TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMapList"},
+					ListMeta: metav1.ListMeta{ResourceVersion: "1"},
+					Items:    []corev1.ConfigMap{configMap("foo", "1"), configMap("stale", "1")},
+				})
+			}
+		}))
+		defer server.Close()
+
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+		c, err := New(&rest.Config{Host: server.URL}, Options{Mapper: mapper})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = c.GetInformer(context.Background(), &corev1.ConfigMap{})
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			defer GinkgoRecover()
+			Expect(c.Start(ctx)).To(Succeed())
+		}()
+		Expect(c.WaitForCacheSync(ctx)).To(BeTrue())
+		list := &corev1.ConfigMapList{}
+		Expect(c.List(ctx, list)).To(Succeed())
+		Expect(list.Items).To(HaveLen(2))
+
+		syncCtx, syncCancel := context.WithTimeout(ctx, 10*time.Second)
+		defer syncCancel()
+		Expect(WaitForQuorumSync(syncCtx, c)).To(Succeed())
+		Expect(c.List(ctx, list)).To(Succeed())
+		Expect(list.Items).To(HaveLen(1))
+		Expect(list.Items[0].Name).To(Equal("foo"))
+		Expect(list.Items[0].ResourceVersion).To(Equal("3"))
+	})
+})
+
+var _ = Describe("cluster-aware store keys", func() {
+	It("should key the objects by logical cluster, namespace and name", func() {
+		pod := func(cluster, name string) corev1.Pod {
+			return corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", ClusterName: cluster, ResourceVersion: "1"}}
+		}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if r.URL.Query().Get("watch") == "true" {
+				w.WriteHeader(http.StatusOK)
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+				return
+			}
+			_ = json.NewEncoder(w).Encode(&corev1.PodList{
+				TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "PodList"},
+				ListMeta: metav1.ListMeta{ResourceVersion: "1"},
+				Items:    []corev1.Pod{pod("root:a", "foo"), pod("root:b", "foo"), pod("root:b", "bar"), pod("", "baz")},
+			})
+		}))
+		defer server.Close()
+
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(corev1.SchemeGroupVersion.WithKind("Pod"), meta.RESTScopeNamespace)
+		c, err := New(&rest.Config{Host: server.URL}, Options{Mapper: mapper})
+		Expect(err).NotTo(HaveOccurred())
+		informer, err := c.GetInformer(context.Background(), &corev1.Pod{})
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			defer GinkgoRecover()
+			Expect(c.Start(ctx)).To(Succeed())
+		}()
+		Expect(c.WaitForCacheSync(ctx)).To(BeTrue())
+		Expect(informer.(toolscache.SharedIndexInformer).GetStore().ListKeys()).To(ConsistOf(
+			"root:a/default/foo", "root:b/default/foo", "root:b/default/bar", "/default/baz"))
+
+		key := types.NamespacedName{Namespace: "default", Name: "foo"}
+		got := &corev1.Pod{}
+		Expect(c.Get(ctx, client.ObjectKey{NamespacedName: key, Cluster: logicalcluster.New("root:b")}, got)).To(Succeed())
+		Expect(got.ClusterName).To(Equal("root:b"))
+		Expect(c.Get(kcpclient.WithCluster(ctx, logicalcluster.New("root:a")), client.ObjectKey{NamespacedName: key}, got)).To(Succeed())
+		Expect(got.ClusterName).To(Equal("root:a"))
+		Expect(c.Get(ctx, client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "default", Name: "baz"}}, got)).To(Succeed())
+
+		list := &corev1.PodList{}
+		Expect(c.List(kcpclient.WithCluster(ctx, logicalcluster.New("root:b")), list, client.InNamespace("default"))).To(Succeed())
+		Expect(list.Items).To(HaveLen(2))
+		Expect(c.List(kcpclient.WithCluster(ctx, logicalcluster.New("root:a")), list)).To(Succeed())
+		Expect(list.Items).To(HaveLen(1))
+		Expect(c.List(ctx, list)).To(Succeed())
+		Expect(list.Items).To(HaveLen(4))
+	})
+})
+
+var _ = Describe("TransformByObject", func() {
+	It("should transform the objects of the lists and watches before caching them", func() {
+		managedFields := []metav1.ManagedFieldsEntry{{Manager: "test", Operation: metav1.ManagedFieldsOperationApply}}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if r.URL.Query().Get("watch") == "true" {
+				_ = json.NewEncoder(w).Encode(&metav1.WatchEvent{Type: "ADDED", Object: runtime.RawExtension{Object: &corev1.Pod{
+					TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+					ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "default", ResourceVersion: "2", ManagedFields: managedFields},
+				}}})
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+				return
+			}
+			_ = json.NewEncoder(w).Encode(&corev1.PodList{
+				TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "PodList"},
+				ListMeta: metav1.ListMeta{ResourceVersion: "1"},
+				Items:    []corev1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", ResourceVersion: "1", ManagedFields: managedFields}}},
+			})
+		}))
+		defer server.Close()
+
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(corev1.SchemeGroupVersion.WithKind("Pod"), meta.RESTScopeNamespace)
+		c, err := New(&rest.Config{Host: server.URL}, Options{Mapper: mapper, TransformByObject: TransformByObject{
+			&corev1.Pod{}: func(obj interface{}) (interface{}, error) {
+				obj.(*corev1.Pod).SetManagedFields(nil)
+				return obj, nil
+			},
+		}})
+		Expect(err).NotTo(HaveOccurred())
+		informer, err := c.GetInformer(context.Background(), &corev1.Pod{})
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			defer GinkgoRecover()
+			Expect(c.Start(ctx)).To(Succeed())
+		}()
+		Expect(c.WaitForCacheSync(ctx)).To(BeTrue())
+
+		store := informer.(toolscache.SharedIndexInformer).GetStore()
+		Eventually(store.ListKeys).Should(ConsistOf("/default/foo", "/default/bar"))
+		for _, obj := range store.List() {
+			Expect(obj.(*corev1.Pod).GetManagedFields()).To(BeEmpty())
+		}
+	})
+})
+
+var _ = Describe("ListWatchProxy", func() {
+	It("should list and watch through the proxy, keeping the path of the logical cluster", func() {
+		paths := make(chan string, 10)
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			paths <- r.URL.Path
+			w.Header().Set("Content-Type", "application/json")
+			if r.URL.Query().Get("watch") == "true" {
+				w.WriteHeader(http.StatusOK)
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+				return
+			}
+			_ = json.NewEncoder(w).Encode(&corev1.PodList{
+				TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "PodList"},
+				ListMeta: metav1.ListMeta{ResourceVersion: "1"},
+				Items:    []corev1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", ResourceVersion: "1"}}},
+			})
+		}))
+		defer proxy.Close()
+
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(corev1.SchemeGroupVersion.WithKind("Pod"), meta.RESTScopeNamespace)
+		c, err := New(&rest.Config{Host: "https://kcp.invalid:6443/clusters/root:org"}, Options{
+			Mapper:         mapper,
+			ListWatchProxy: &Proxy{Host: proxy.URL + "/cache/"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		informer, err := c.GetInformer(context.Background(), &corev1.Pod{})
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			defer GinkgoRecover()
+			Expect(c.Start(ctx)).To(Succeed())
+		}()
+		Expect(c.WaitForCacheSync(ctx)).To(BeTrue())
+		Expect(informer.(toolscache.SharedIndexInformer).GetStore().ListKeys()).To(ConsistOf("/default/foo"))
+		Expect(<-paths).To(Equal("/cache/clusters/root:org/api/v1/pods"))
+	})
+
+	It("should refuse a proxy host which isn't a URL", func() {
+		_, err := New(&rest.Config{Host: "https://kcp.invalid:6443"}, Options{
+			Mapper:         meta.NewDefaultRESTMapper(nil),
+			ListWatchProxy: &Proxy{Host: "cache-server:6443"},
+		})
+		Expect(err).To(MatchError(ContainSubstring("invalid host")))
+	})
+})
+
+var _ = Describe("ResourceVersionStore", func() {
+	It("should not list objects older than the informers last observed after a restart", func() {
+		lists := make(chan string, 10)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if r.URL.Query().Get("watch") == "true" {
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(`{"type":"BOOKMARK","object":{"apiVersion":"v1","kind":"Pod","metadata":{"resourceVersion":"7"}}}` + "\n"))
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+				return
+			}
+			query := r.URL.Query()
+			lists <- query.Get("resourceVersion") + "/" + query.Get("resourceVersionMatch") + "/" + query.Get("limit")
+			_ = json.NewEncoder(w).Encode(&corev1.PodList{
+				TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "PodList"},
+				ListMeta: metav1.ListMeta{ResourceVersion: "5"},
+			})
+		}))
+		defer server.Close()
+
+		dir, err := ioutil.TempDir("", "resource-versions")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+		store, err := NewFileResourceVersionStore(dir)
+		Expect(err).NotTo(HaveOccurred())
+
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(corev1.SchemeGroupVersion.WithKind("Pod"), meta.RESTScopeNamespace)
+		run := func() context.CancelFunc {
+			c, err := New(&rest.Config{Host: server.URL + "/clusters/root:org"}, Options{Mapper: mapper, ResourceVersionStore: store})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = c.GetInformer(context.Background(), &corev1.Pod{})
+			Expect(err).NotTo(HaveOccurred())
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				defer GinkgoRecover()
+				Expect(c.Start(ctx)).To(Succeed())
+			}()
+			Expect(c.WaitForCacheSync(ctx)).To(BeTrue())
+			return cancel
+		}
+
+		cancel := run()
+		Expect(<-lists).To(Equal("0//500"))
+		Eventually(func() (string, error) {
+			return store.Load(logicalcluster.New("root:org"), corev1.SchemeGroupVersion.WithKind("Pod"))
+		}).Should(Equal("7"))
+		cancel()
+
+		cancel = run()
+		defer cancel()
+		Expect(<-lists).To(Equal("7/NotOlderThan/500"))
+	})
+
+	It("should return no resourceVersion for the kinds never saved", func() {
+		dir, err := ioutil.TempDir("", "resource-versions")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+		store, err := NewFileResourceVersionStore(dir)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(store.Save(logicalcluster.Wildcard, corev1.SchemeGroupVersion.WithKind("Pod"), "3")).To(Succeed())
+		Expect(store.Load(logicalcluster.Wildcard, corev1.SchemeGroupVersion.WithKind("Pod"))).To(Equal("3"))
+		Expect(store.Load(logicalcluster.New("root:org"), corev1.SchemeGroupVersion.WithKind("Pod"))).To(BeEmpty())
+		Expect(store.Load(logicalcluster.Wildcard, corev1.SchemeGroupVersion.WithKind("ConfigMap"))).To(BeEmpty())
+	})
+})
+
+var _ = Describe("cache metrics", func() {
+	// metricValue returns the value of the metric with the given labels, or -1 if there is none.
+	metricValue := func(name string, labels map[string]string) float64 {
+		families, err := metrics.Registry.Gather()
+		Expect(err).NotTo(HaveOccurred())
+		for _, family := range families {
+			if family.GetName() != name {
+				continue
+			}
+		next:
+			for _, metric := range family.GetMetric() {
+				for _, label := range metric.GetLabel() {
+					if value, ok := labels[label.GetName()]; ok && value != label.GetValue() {
+						continue next
+					}
+				}
+				if metric.GetCounter() != nil {
+					return metric.GetCounter().GetValue()
+				}
+				return metric.GetGauge().GetValue()
+			}
+		}
+		return -1
+	}
+
+	It("should expose the informers, stored objects and watch reconnects per logical cluster", func() {
+		pod := func(cluster, name string) corev1.Pod {
+			return corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", ClusterName: cluster, ResourceVersion: "1"}}
+		}
+		var watches int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if r.URL.Query().Get("watch") == "true" {
+				w.WriteHeader(http.StatusOK)
+				w.(http.Flusher).Flush()
+				if atomic.AddInt32(&watches, 1) == 1 {
+					// End the first watch, so that the informer watches again.
+					return
+				}
+				<-r.Context().Done()
+				return
+			}
+			_ = json.NewEncoder(w).Encode(&corev1.PodList{
+				TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "PodList"},
+				ListMeta: metav1.ListMeta{ResourceVersion: "1"},
+				Items:    []corev1.Pod{pod("root:metrics:a", "foo"), pod("root:metrics:b", "foo"), pod("root:metrics:b", "bar")},
+			})
+		}))
+		defer server.Close()
+
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(corev1.SchemeGroupVersion.WithKind("Pod"), meta.RESTScopeNamespace)
+		c, err := New(&rest.Config{Host: server.URL + "/clusters/*"}, Options{Mapper: mapper})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = c.GetInformer(context.Background(), &corev1.Pod{})
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		stopped := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(stopped)
+			Expect(c.Start(ctx)).To(Succeed())
+		}()
+		Expect(c.WaitForCacheSync(ctx)).To(BeTrue())
+
+		Expect(metricValue("controller_runtime_cache_informers", map[string]string{"cluster": "*"})).To(Equal(1.0))
+		pods := map[string]string{"group": "", "version": "v1", "kind": "Pod"}
+		pods["cluster"] = "root:metrics:a"
+		Expect(metricValue("controller_runtime_cache_objects", pods)).To(Equal(1.0))
+		pods["cluster"] = "root:metrics:b"
+		Expect(metricValue("controller_runtime_cache_objects", pods)).To(Equal(2.0))
+		Eventually(func() float64 {
+			return metricValue("controller_runtime_cache_watch_reconnects_total", map[string]string{"cluster": "*"})
+		}, 5*time.Second).Should(BeNumerically(">=", 1))
+
+		By("dropping the informers and objects of the caches once stopped")
+		cancel()
+		Eventually(stopped).Should(BeClosed())
+		Expect(metricValue("controller_runtime_cache_informers", map[string]string{"cluster": "*"})).To(Equal(-1.0))
+		Expect(metricValue("controller_runtime_cache_objects", pods)).To(Equal(-1.0))
+	})
+})
@@ -0,0 +1,56 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informertest
+
+import (
+	"context"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ cache.Cache = &FakeClusterCache{}
+
+// FakeClusterCache is a fake implementation of a Cache of all the logical clusters, e.g. a
+// cache started against the wildcard cluster.  Its informers are the ones of FakeInformers,
+// and objects are read from Reader, typically a fake client built with fake.NewClusterBuilder.
+//
+// Like the cache, it lists the objects of the cluster of the context, or of all the clusters
+// if the context holds no cluster or the wildcard cluster.
+type FakeClusterCache struct {
+	FakeInformers
+
+	// Reader holds the objects of the cache.
+	Reader client.Reader
+}
+
+// Get implements Cache.
+func (c *FakeClusterCache) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	return c.Reader.Get(ctx, key, obj)
+}
+
+// List implements Cache.
+func (c *FakeClusterCache) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	ctx = client.ListContext(ctx, opts...)
+	if cluster, _ := kcpclient.ClusterFromContext(ctx); cluster.Empty() {
+		ctx = kcpclient.WithCluster(ctx, logicalcluster.Wildcard)
+	}
+	return c.Reader.List(ctx, list, opts...)
+}
@@ -22,8 +22,11 @@ import (
 	"reflect"
 
 	kcpcache "github.com/kcp-dev/apimachinery/pkg/cache"
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -42,6 +45,9 @@ type CacheReader struct {
 	// indexer is the underlying indexer wrapped by this cache.
 	indexer cache.Indexer
 
+	// keyFunc is the function keying the objects in the indexer, see objectKeyToStoreKey.
+	keyFunc cache.KeyFunc
+
 	// groupVersionKind is the group-version-kind of the resource.
 	groupVersionKind schema.GroupVersionKind
 
@@ -59,7 +65,15 @@ func (c *CacheReader) Get(ctx context.Context, key client.ObjectKey, out client.
 	if c.scopeName == apimeta.RESTScopeNameRoot {
 		key.Namespace = ""
 	}
-	storeKey := objectKeyToStoreKey(key)
+	if key.Cluster.Empty() {
+		if cluster, ok := kcpclient.ClusterFromContext(ctx); ok {
+			key.Cluster = cluster
+		}
+	}
+	storeKey, err := objectKeyToStoreKey(key, c.keyFunc)
+	if err != nil {
+		return err
+	}
 
 	// Lookup the object from the indexer cache
 	obj, exists, err := c.indexer.GetByKey(storeKey)
@@ -106,13 +120,21 @@ func (c *CacheReader) Get(ctx context.Context, key client.ObjectKey, out client.
 }
 
 // List lists items out of the indexer and writes them to out.
-func (c *CacheReader) List(_ context.Context, out client.ObjectList, opts ...client.ListOption) error {
+func (c *CacheReader) List(ctx context.Context, out client.ObjectList, opts ...client.ListOption) error {
 	var objs []interface{}
 	var err error
 
 	listOpts := client.ListOptions{}
 	listOpts.ApplyOptions(opts)
 
+	// If the options or the context target a single logical cluster, only list its objects,
+	// with the indexes partitioning the objects of a wildcard watch per cluster.
+	cluster := listOpts.Cluster
+	if cluster.Empty() {
+		cluster, _ = kcpclient.ClusterFromContext(ctx)
+	}
+	singleCluster := !cluster.Empty() && cluster != logicalcluster.Wildcard
+
 	switch {
 	case listOpts.FieldSelector != nil:
 		// TODO(directxman12): support more complicated field selectors by
@@ -123,8 +145,17 @@ func (c *CacheReader) List(_ context.Context, out client.ObjectList, opts ...cli
 		}
 		// list all objects by the field selector.  If this is namespaced and we have one, ask for the
 		// namespaced index key.  Otherwise, ask for the non-namespaced variant by using the fake "all namespaces"
-		// namespace.
-		objs, err = c.indexer.ByIndex(FieldIndexName(field), KeyToNamespacedKey(listOpts.Namespace, val))
+		// namespace.  If the context targets a single logical cluster, ask for the variant of the
+		// key scoped to that cluster, otherwise match the objects of all the clusters.
+		indexKey := KeyToNamespacedKey(listOpts.Namespace, val)
+		if singleCluster {
+			indexKey = KeyToClusterNamespacedKey(cluster, listOpts.Namespace, val)
+		}
+		objs, err = c.indexer.ByIndex(FieldIndexName(field), indexKey)
+	case singleCluster && listOpts.Namespace != "":
+		objs, err = c.indexer.ByIndex(kcpcache.ClusterAndNamespaceIndexName, kcpcache.ToClusterAwareKey(cluster.String(), listOpts.Namespace, ""))
+	case singleCluster:
+		objs, err = c.indexer.ByIndex(kcpcache.ClusterIndexName, kcpcache.ToClusterAwareKey(cluster.String(), "", ""))
 	case listOpts.Namespace != "":
 		objs, err = c.indexer.ByIndex(cache.NamespaceIndex, listOpts.Namespace)
 	default:
@@ -176,12 +207,19 @@ func (c *CacheReader) List(_ context.Context, out client.ObjectList, opts ...cli
 	return apimeta.SetList(out, runtimeObjs)
 }
 
-// objectKeyToStorageKey converts an object key to store key.
-// It's akin to MetaNamespaceKeyFunc.  It's separate from
-// String to allow keeping the key format easily in sync with
-// MetaNamespaceKeyFunc.
-func objectKeyToStoreKey(k client.ObjectKey) string {
-	return kcpcache.ToClusterAwareKey(k.Cluster.String(), k.Namespace, k.Name)
+// objectKeyToStoreKey converts an object key to store key, with the key function of the
+// store.  The key of an object only depends on its logical cluster, namespace and name, the
+// key function is given an object with only these.  It defaults to the cluster-aware
+// kcpcache.ClusterAwareKeyFunc.
+func objectKeyToStoreKey(k client.ObjectKey, keyFunc cache.KeyFunc) (string, error) {
+	if keyFunc == nil {
+		return kcpcache.ToClusterAwareKey(k.Cluster.String(), k.Namespace, k.Name), nil
+	}
+	return keyFunc(&metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{
+		ClusterName: k.Cluster.String(),
+		Namespace:   k.Namespace,
+		Name:        k.Name,
+	}})
 }
 
 // requiresExactMatch checks if the given field selector is of the form `k=v` or `k==v`.
@@ -214,3 +252,9 @@ func KeyToNamespacedKey(ns string, baseKey string) string {
 	}
 	return allNamespacesNamespace + "/" + baseKey
 }
+
+// KeyToClusterNamespacedKey prefixes the given index key with a logical cluster
+// and a namespace for use in field selector indexes.
+func KeyToClusterNamespacedKey(cluster logicalcluster.Name, ns string, baseKey string) string {
+	return cluster.String() + "|" + KeyToNamespacedKey(ns, baseKey)
+}
@@ -18,6 +18,7 @@ package internal
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -27,6 +28,8 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 )
 
 // InformersMap create and caches Informers for (runtime.Object, schema.GroupVersionKind) pairs.
@@ -39,6 +42,10 @@ type InformersMap struct {
 	unstructured *specificInformersMap
 	metadata     *specificInformersMap
 
+	// metadataOnly forces the informers of some kinds to be metadata-only, whatever the
+	// type of the objects they are requested for.
+	metadataOnly MetadataOnlyByGVK
+
 	// Scheme maps runtime.Objects to GroupVersionKinds
 	Scheme *runtime.Scheme
 }
@@ -53,22 +60,33 @@ func NewInformersMap(config *rest.Config,
 	selectors SelectorsByGVK,
 	disableDeepCopy DisableDeepCopyByGVK,
 	keyFunc cache.KeyFunc,
+	strict *apiutil.StrictDecoding,
+	listOptions ListOptions,
+	metadataOnly MetadataOnlyByGVK,
+	transform TransformFuncByGVK,
 ) *InformersMap {
 	return &InformersMap{
-		structured:   newStructuredInformersMap(config, scheme, mapper, resync, namespace, selectors, disableDeepCopy, keyFunc),
-		unstructured: newUnstructuredInformersMap(config, scheme, mapper, resync, namespace, selectors, disableDeepCopy, keyFunc),
-		metadata:     newMetadataInformersMap(config, scheme, mapper, resync, namespace, selectors, disableDeepCopy, keyFunc),
+		structured:   newStructuredInformersMap(config, scheme, mapper, resync, namespace, selectors, disableDeepCopy, keyFunc, strict, listOptions, transform),
+		unstructured: newUnstructuredInformersMap(config, scheme, mapper, resync, namespace, selectors, disableDeepCopy, keyFunc, strict, listOptions, transform),
+		metadata:     newMetadataInformersMap(config, scheme, mapper, resync, namespace, selectors, disableDeepCopy, keyFunc, strict, listOptions, transform),
 
-		Scheme: scheme,
+		metadataOnly: metadataOnly,
+		Scheme:       scheme,
 	}
 }
 
-// Start calls Run on each of the informers and sets started to true.  Blocks on the context.
+// Start calls Run on each of the informers and sets started to true.  Blocks on the context,
+// then waits for the informers to stop before returning.
 func (m *InformersMap) Start(ctx context.Context) error {
-	go m.structured.Start(ctx)
-	go m.unstructured.Start(ctx)
-	go m.metadata.Start(ctx)
-	<-ctx.Done()
+	var wg sync.WaitGroup
+	for _, ip := range []*specificInformersMap{m.structured, m.unstructured, m.metadata} {
+		wg.Add(1)
+		go func(ip *specificInformersMap) {
+			defer wg.Done()
+			ip.Start(ctx)
+		}(ip)
+	}
+	wg.Wait()
 	return nil
 }
 
@@ -90,9 +108,29 @@ func (m *InformersMap) WaitForCacheSync(ctx context.Context) bool {
 	return cache.WaitForCacheSync(ctx.Done(), syncedFuncs...)
 }
 
+// WaitForQuorumSync waits until all the caches have been started and hold at least the
+// objects read from the API server with a quorum list of each of their kinds.  Unlike
+// WaitForCacheSync, it guarantees that the caches observed the writes made before the call.
+func (m *InformersMap) WaitForQuorumSync(ctx context.Context) error {
+	for _, ip := range []*specificInformersMap{m.structured, m.unstructured, m.metadata} {
+		if !ip.waitForStarted(ctx) {
+			return ctx.Err()
+		}
+		if err := ip.WaitForQuorumSync(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Get will create a new Informer and add it to the map of InformersMap if none exists.  Returns
 // the Informer from the map.
 func (m *InformersMap) Get(ctx context.Context, gvk schema.GroupVersionKind, obj runtime.Object) (bool, *MapEntry, error) {
+	if m.IsMetadataOnly(gvk) {
+		metadata := &metav1.PartialObjectMetadata{}
+		metadata.SetGroupVersionKind(gvk)
+		return m.metadata.Get(ctx, gvk, metadata)
+	}
 	switch obj.(type) {
 	case *unstructured.Unstructured:
 		return m.unstructured.Get(ctx, gvk, obj)
@@ -107,20 +145,25 @@ func (m *InformersMap) Get(ctx context.Context, gvk schema.GroupVersionKind, obj
 	}
 }
 
+// IsMetadataOnly returns whether the informer of the GroupVersionKind is forced to be metadata-only.
+func (m *InformersMap) IsMetadataOnly(gvk schema.GroupVersionKind) bool {
+	return m.metadataOnly.IsMetadataOnly(gvk)
+}
+
 // newStructuredInformersMap creates a new InformersMap for structured objects.
 func newStructuredInformersMap(config *rest.Config, scheme *runtime.Scheme, mapper meta.RESTMapper, resync time.Duration,
-	namespace string, selectors SelectorsByGVK, disableDeepCopy DisableDeepCopyByGVK, keyFunc cache.KeyFunc) *specificInformersMap {
-	return newSpecificInformersMap(config, scheme, mapper, resync, namespace, selectors, disableDeepCopy, createStructuredListWatch, keyFunc)
+	namespace string, selectors SelectorsByGVK, disableDeepCopy DisableDeepCopyByGVK, keyFunc cache.KeyFunc, strict *apiutil.StrictDecoding, listOptions ListOptions, transform TransformFuncByGVK) *specificInformersMap {
+	return newSpecificInformersMap(config, scheme, mapper, resync, namespace, selectors, disableDeepCopy, createStructuredListWatch, keyFunc, strict, listOptions, transform)
 }
 
 // newUnstructuredInformersMap creates a new InformersMap for unstructured objects.
 func newUnstructuredInformersMap(config *rest.Config, scheme *runtime.Scheme, mapper meta.RESTMapper, resync time.Duration,
-	namespace string, selectors SelectorsByGVK, disableDeepCopy DisableDeepCopyByGVK, keyFunc cache.KeyFunc) *specificInformersMap {
-	return newSpecificInformersMap(config, scheme, mapper, resync, namespace, selectors, disableDeepCopy, createUnstructuredListWatch, keyFunc)
+	namespace string, selectors SelectorsByGVK, disableDeepCopy DisableDeepCopyByGVK, keyFunc cache.KeyFunc, strict *apiutil.StrictDecoding, listOptions ListOptions, transform TransformFuncByGVK) *specificInformersMap {
+	return newSpecificInformersMap(config, scheme, mapper, resync, namespace, selectors, disableDeepCopy, createUnstructuredListWatch, keyFunc, strict, listOptions, transform)
 }
 
 // newMetadataInformersMap creates a new InformersMap for metadata-only objects.
 func newMetadataInformersMap(config *rest.Config, scheme *runtime.Scheme, mapper meta.RESTMapper, resync time.Duration,
-	namespace string, selectors SelectorsByGVK, disableDeepCopy DisableDeepCopyByGVK, keyFunc cache.KeyFunc) *specificInformersMap {
-	return newSpecificInformersMap(config, scheme, mapper, resync, namespace, selectors, disableDeepCopy, createMetadataListWatch, keyFunc)
+	namespace string, selectors SelectorsByGVK, disableDeepCopy DisableDeepCopyByGVK, keyFunc cache.KeyFunc, strict *apiutil.StrictDecoding, listOptions ListOptions, transform TransformFuncByGVK) *specificInformersMap {
+	return newSpecificInformersMap(config, scheme, mapper, resync, namespace, selectors, disableDeepCopy, createMetadataListWatch, keyFunc, strict, listOptions, transform)
 }
@@ -21,8 +21,10 @@ import (
 	"fmt"
 	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	kcpcache "github.com/kcp-dev/apimachinery/pkg/cache"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -36,8 +38,11 @@ import (
 	"k8s.io/client-go/tools/cache"
 
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/internal/log"
 )
 
+var log = logf.RuntimeLog.WithName("cache")
+
 func init() {
 	rand.Seed(time.Now().UnixNano())
 }
@@ -55,7 +60,13 @@ func newSpecificInformersMap(config *rest.Config,
 	selectors SelectorsByGVK,
 	disableDeepCopy DisableDeepCopyByGVK,
 	createListWatcher createListWatcherFunc,
-	keyFunction cache.KeyFunc) *specificInformersMap {
+	keyFunction cache.KeyFunc,
+	strict *apiutil.StrictDecoding,
+	listOptions ListOptions,
+	transform TransformFuncByGVK) *specificInformersMap {
+	if keyFunction == nil {
+		keyFunction = kcpcache.ClusterAwareKeyFunc
+	}
 
 	ip := &specificInformersMap{
 		config:            config,
@@ -70,11 +81,26 @@ func newSpecificInformersMap(config *rest.Config,
 		namespace:         namespace,
 		selectors:         selectors.forGVK,
 		disableDeepCopy:   disableDeepCopy,
-		keyFunction:       keyFunction,
+		keyFunction:       deletionHandlingKeyFunc(keyFunction),
+		strict:            strict,
+		listOptions:       listOptions,
+		transform:         transform,
 	}
 	return ip
 }
 
+// deletionHandlingKeyFunc wraps the key function of the informers so that the tombstones of
+// the objects deleted while the informers weren't watching are keyed with the key the objects
+// were stored with, like cache.DeletionHandlingMetaNamespaceKeyFunc.
+func deletionHandlingKeyFunc(keyFunc cache.KeyFunc) cache.KeyFunc {
+	return func(obj interface{}) (string, error) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			return tombstone.Key, nil
+		}
+		return keyFunc(obj)
+	}
+}
+
 // MapEntry contains the cached data for an Informer.
 type MapEntry struct {
 	// Informer is the cached informer
@@ -82,6 +108,37 @@ type MapEntry struct {
 
 	// CacheReader wraps Informer and implements the CacheReader interface for a single type
 	Reader CacheReader
+
+	// list lists the objects of the informer from the API server, without the delays and
+	// the resourceVersions of the lists of the informer, see WaitForQuorumSync.
+	list cache.ListFunc
+
+	// notServed is 1 while the API server doesn't serve the kind of the informer, e.g.
+	// because the last APIBinding exporting it was removed.
+	notServed int32
+}
+
+// NotServed returns true if the last list of the informer failed because the API
+// server doesn't serve its kind.  The informer keeps retrying with backoff and
+// resumes on its own once the kind is served again.
+func (e *MapEntry) NotServed() bool {
+	return atomic.LoadInt32(&e.notServed) == 1
+}
+
+// observeList records whether the kind of the informer is served from the result
+// of a list, and logs the transitions only, instead of every failed attempt.
+func (e *MapEntry) observeList(err error) {
+	gvk := e.Reader.groupVersionKind
+	switch {
+	case apierrors.IsNotFound(err) || meta.IsNoMatchError(err):
+		if atomic.CompareAndSwapInt32(&e.notServed, 0, 1) {
+			log.Info("kind is not served anymore, reads will fail until it is served again", "gvk", gvk)
+		}
+	case err == nil:
+		if atomic.CompareAndSwapInt32(&e.notServed, 1, 0) {
+			log.Info("kind is served again, resuming", "gvk", gvk)
+		}
+	}
 }
 
 // specificInformersMap create and caches Informers for (runtime.Object, schema.GroupVersionKind) pairs.
@@ -123,6 +180,13 @@ type specificInformersMap struct {
 	// informer has been started.
 	startWait chan struct{}
 
+	// stopped is true once the context of Start is done, no informer is started after that.
+	stopped bool
+
+	// running tracks the informers which are running, so that Start can wait for them
+	// to stop.
+	running sync.WaitGroup
+
 	// createClient knows how to create a client and a list object,
 	// and allows for abstracting over the particulars of structured vs
 	// unstructured objects.
@@ -139,11 +203,23 @@ type specificInformersMap struct {
 	// disableDeepCopy indicates not to deep copy objects during get or list objects.
 	disableDeepCopy DisableDeepCopyByGVK
 
+	// strict selects the objects whose decoding rejects unknown fields.
+	strict *apiutil.StrictDecoding
+
+	// listOptions tunes the lists of the informers.
+	listOptions ListOptions
+
+	// transform transforms the objects before they are cached.
+	transform TransformFuncByGVK
+
 	keyFunction cache.KeyFunc
 }
 
 // Start calls Run on each of the informers and sets started to true.  Blocks on the context.
 // It doesn't return start because it can't return an error, and it's not a runnable directly.
+// Once the context is done, it waits for the informers to stop and drops the objects they
+// cached, so that the memory of a stopped cache is freed even if it is still referenced,
+// e.g. by the sources of the controllers which watched it.
 func (ip *specificInformersMap) Start(ctx context.Context) {
 	func() {
 		ip.mu.Lock()
@@ -154,14 +230,39 @@ func (ip *specificInformersMap) Start(ctx context.Context) {
 
 		// Start each informer
 		for _, informer := range ip.informersByGVK {
-			go informer.Informer.Run(ctx.Done())
+			ip.run(informer)
 		}
 
 		// Set started to true so we immediately start any informers added later.
 		ip.started = true
 		close(ip.startWait)
 	}()
+	addRunningMap(ip)
+	defer removeRunningMap(ip)
 	<-ctx.Done()
+
+	ip.mu.Lock()
+	ip.stopped = true
+	ip.mu.Unlock()
+	ip.running.Wait()
+
+	ip.mu.RLock()
+	defer ip.mu.RUnlock()
+	for _, informer := range ip.informersByGVK {
+		_ = informer.Informer.GetStore().Replace(nil, "")
+	}
+}
+
+// run runs the informer until the stop channel is closed.  ip.mu must be held.
+func (ip *specificInformersMap) run(informer *MapEntry) {
+	if ip.stopped {
+		return
+	}
+	ip.running.Add(1)
+	go func() {
+		defer ip.running.Done()
+		informer.Informer.Run(ip.stop)
+	}()
 }
 
 func (ip *specificInformersMap) waitForStarted(ctx context.Context) bool {
@@ -173,13 +274,16 @@ func (ip *specificInformersMap) waitForStarted(ctx context.Context) bool {
 	}
 }
 
-// HasSyncedFuncs returns all the HasSynced functions for the informers in this map.
+// HasSyncedFuncs returns all the HasSynced functions for the informers in this map.  The
+// informers of the kinds which aren't served count as synced, as they won't sync until
+// they are served, and their readers check NotServed.
 func (ip *specificInformersMap) HasSyncedFuncs() []cache.InformerSynced {
 	ip.mu.RLock()
 	defer ip.mu.RUnlock()
 	syncedFuncs := make([]cache.InformerSynced, 0, len(ip.informersByGVK))
 	for _, informer := range ip.informersByGVK {
-		syncedFuncs = append(syncedFuncs, informer.Informer.HasSynced)
+		informer := informer
+		syncedFuncs = append(syncedFuncs, func() bool { return informer.Informer.HasSynced() || informer.NotServed() })
 	}
 	return syncedFuncs
 }
@@ -204,7 +308,9 @@ func (ip *specificInformersMap) Get(ctx context.Context, gvk schema.GroupVersion
 
 	if started && !i.Informer.HasSynced() {
 		// Wait for it to sync before returning the Informer so that folks don't read from a stale cache.
-		if !cache.WaitForCacheSync(ctx.Done(), i.Informer.HasSynced) {
+		// Don't wait for a kind that isn't served: it won't sync until it is, and readers are
+		// expected to check NotServed.
+		if !cache.WaitForCacheSync(ctx.Done(), func() bool { return i.Informer.HasSynced() || i.NotServed() }) {
 			return started, nil, apierrors.NewTimeoutError(fmt.Sprintf("failed waiting for %T Informer to sync", obj), 0)
 		}
 	}
@@ -233,33 +339,78 @@ func (ip *specificInformersMap) addInformerToMap(gvk schema.GroupVersionKind, ob
 		cache.WithResyncPeriod(resyncPeriod(ip.resync)()),
 		cache.WithKeyFunction(ip.keyFunction),
 		cache.WithIndexers(cache.Indexers{
-			cache.NamespaceIndex: cache.MetaNamespaceIndexFunc,
+			cache.NamespaceIndex:                  cache.MetaNamespaceIndexFunc,
+			kcpcache.ClusterIndexName:             kcpcache.ClusterIndexFunc,
+			kcpcache.ClusterAndNamespaceIndexName: kcpcache.ClusterAndNamespaceIndexFunc,
 		}))
 	rm, err := ip.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
 	if err != nil {
 		return nil, false, err
 	}
-
 	i := &MapEntry{
 		Informer: ni,
 		Reader: CacheReader{
 			indexer:          ni.GetIndexer(),
+			keyFunc:          ip.keyFunction,
 			groupVersionKind: gvk,
 			scopeName:        rm.Scope.Name(),
 			disableDeepCopy:  ip.disableDeepCopy.IsDisabled(gvk),
 		},
 	}
+	// A kind that isn't served is reported once by observeList, don't let the
+	// reflector log every retry.  The reflector doesn't wrap the errors of the
+	// lists, so rely on the state observed by the entry rather than the error.
+	if err := ni.SetWatchErrorHandler(func(r *cache.Reflector, err error) {
+		if i.NotServed() || apierrors.IsNotFound(err) {
+			return
+		}
+		cache.DefaultWatchErrorHandler(r, err)
+	}); err != nil {
+		return nil, false, err
+	}
+	list := lw.ListFunc
+	i.list = list
+	relist := ip.listOptions.newRelistDelay()
+	lw.ListFunc = func(opts metav1.ListOptions) (runtime.Object, error) {
+		if opts.Continue == "" {
+			// Only the first page of a list is delayed.
+			relist.wait()
+		}
+		ip.listOptions.applyToList(&opts)
+		res, err := list(opts)
+		i.observeList(err)
+		return res, err
+	}
+	if ip.listOptions.ResourceVersions != nil {
+		notOlderListWatch(lw, gvk, ip.listOptions.ResourceVersions)
+	}
+	if transform := ip.transform.Get(gvk); transform != nil {
+		transformListWatch(lw, transform)
+	}
+	countWatchReconnects(lw, ip.listOptions.Cluster.String())
 	ip.informersByGVK[gvk] = i
 
 	// Start the Informer if need by
 	// TODO(seans): write thorough tests and document what happens here - can you add indexers?
 	// can you add eventhandlers?
 	if ip.started {
-		go i.Informer.Run(ip.stop)
+		ip.run(i)
 	}
 	return i, ip.started, nil
 }
 
+// countWatchReconnects makes the ListWatch of an informer count its watches after the first one.
+func countWatchReconnects(lw *cache.ListWatch, cluster string) {
+	var watched int32
+	watchFunc := lw.WatchFunc
+	lw.WatchFunc = func(opts metav1.ListOptions) (watch.Interface, error) {
+		if !atomic.CompareAndSwapInt32(&watched, 0, 1) {
+			watchReconnects.WithLabelValues(cluster).Inc()
+		}
+		return watchFunc(opts)
+	}
+}
+
 // newListWatch returns a new ListWatch object that can be used to create a SharedIndexInformer.
 func createStructuredListWatch(gvk schema.GroupVersionKind, ip *specificInformersMap) (*cache.ListWatch, error) {
 	// Kubernetes APIs work against Resources, not GroupVersionKinds.  Map the
@@ -269,7 +420,12 @@ func createStructuredListWatch(gvk schema.GroupVersionKind, ip *specificInformer
 		return nil, err
 	}
 
-	client, err := apiutil.RESTClientForGVK(gvk, false, ip.config, ip.codecs)
+	var client rest.Interface
+	if ip.strict.AppliesTo(gvk) {
+		client, err = apiutil.StrictRESTClientForGVK(gvk, false, ip.config, ip.codecs, ip.Scheme, ip.strict)
+	} else {
+		client, err = apiutil.RESTClientForGVK(gvk, false, ip.config, ip.codecs)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,111 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ListOptions tunes the lists of the informers.
+type ListOptions struct {
+	// ChunkSize is the number of objects requested per page, or 0 for the default of the
+	// reflectors.
+	ChunkSize int64
+
+	// RelistInitial, RelistMax and RelistFactor configure the delays between the lists of
+	// an informer, see relistDelay.  The lists are not delayed if RelistInitial is 0.
+	RelistInitial time.Duration
+	RelistMax     time.Duration
+	RelistFactor  float64
+
+	// ResourceVersions, if set, saves the resourceVersion observed by the informers so that
+	// their first list isn't older, see notOlderListWatch.
+	ResourceVersions ResourceVersions
+
+	// Cluster is the logical cluster the informers list, e.g. the wildcard cluster for a
+	// cache of all the logical clusters, which labels their metrics.
+	Cluster logicalcluster.Name
+}
+
+// applyToList sets the chunk size of a list.
+func (o ListOptions) applyToList(opts *metav1.ListOptions) {
+	if o.ChunkSize > 0 {
+		opts.Limit = o.ChunkSize
+	}
+}
+
+// newRelistDelay returns the delay of the lists of a new informer, or nil if they are not
+// delayed.
+func (o ListOptions) newRelistDelay() *relistDelay {
+	if o.RelistInitial <= 0 {
+		return nil
+	}
+	d := &relistDelay{initial: o.RelistInitial, max: o.RelistMax, factor: o.RelistFactor}
+	if d.max < d.initial {
+		d.max = d.initial
+	}
+	if d.factor < 1 {
+		d.factor = 2
+	}
+	return d
+}
+
+// relistDelay delays the lists of an informer after its first one: the first relist waits
+// for the initial delay, which is multiplied by the factor for each relist following the
+// previous list by less than twice the max delay, up to the max delay.
+type relistDelay struct {
+	initial, max time.Duration
+	factor       float64
+
+	mu     sync.Mutex
+	listed bool
+	last   time.Time
+	next   time.Duration
+}
+
+// wait blocks until the next list may start.
+func (d *relistDelay) wait() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	now := time.Now()
+	var delay time.Duration
+	switch {
+	case !d.listed:
+		d.listed = true
+		d.next = d.initial
+	case now.Sub(d.last) > 2*d.max:
+		delay, d.next = d.initial, d.initial
+	default:
+		delay = d.next
+	}
+	if delay > 0 {
+		d.next = time.Duration(float64(delay) * d.factor)
+		if d.next > d.max {
+			d.next = d.max
+		}
+	}
+	d.last = now.Add(delay)
+	d.mu.Unlock()
+
+	time.Sleep(delay)
+}
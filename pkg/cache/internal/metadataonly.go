@@ -0,0 +1,32 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import "k8s.io/apimachinery/pkg/runtime/schema"
+
+// MetadataOnlyByGVK associate a GroupVersionKind to force caching only the metadata of its objects.
+type MetadataOnlyByGVK map[schema.GroupVersionKind]bool
+
+// IsMetadataOnly returns whether only the metadata of the objects of a GroupVersionKind is cached.
+func (metadataOnlyByGVK MetadataOnlyByGVK) IsMetadataOnly(gvk schema.GroupVersionKind) bool {
+	if m, ok := metadataOnlyByGVK[gvk]; ok {
+		return m
+	} else if m, ok = metadataOnlyByGVK[GroupVersionKindAll]; ok {
+		return m
+	}
+	return false
+}
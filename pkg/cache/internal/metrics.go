@@ -0,0 +1,110 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"strings"
+	"sync"
+
+	kcpcache "github.com/kcp-dev/apimachinery/pkg/cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// watchReconnects counts the watches restarted by the informers.
+	watchReconnects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "controller_runtime_cache_watch_reconnects_total",
+		Help: "Total number of watches restarted by the informers of the caches per logical cluster",
+	}, []string{"cluster"})
+
+	informersDesc = prometheus.NewDesc(
+		"controller_runtime_cache_informers",
+		"Number of informers of the running caches per logical cluster",
+		[]string{"cluster"}, nil)
+
+	objectsDesc = prometheus.NewDesc(
+		"controller_runtime_cache_objects",
+		"Number of objects stored by the informers of the running caches per logical cluster and kind",
+		[]string{"cluster", "group", "version", "kind"}, nil)
+
+	// runningMaps are the informer maps of the running caches, collected by cacheCollector.
+	runningMaps = struct {
+		sync.Mutex
+		maps map[*specificInformersMap]struct{}
+	}{maps: map[*specificInformersMap]struct{}{}}
+)
+
+func init() {
+	metrics.Registry.MustRegister(watchReconnects, cacheCollector{})
+}
+
+// cacheCollector collects the number of informers and of stored objects of the running
+// caches when scraped, rather than tracking every change of their stores.  The objects of
+// the caches of all the logical clusters are counted per logical cluster of the objects.
+type cacheCollector struct{}
+
+// Describe implements prometheus.Collector.
+func (cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- informersDesc
+	ch <- objectsDesc
+}
+
+// Collect implements prometheus.Collector.
+func (cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	informers := map[string]int{}
+	objects := map[[4]string]int{}
+	runningMaps.Lock()
+	for ip := range runningMaps.maps {
+		ip.mu.RLock()
+		cluster := ip.listOptions.Cluster.String()
+		informers[cluster] += len(ip.informersByGVK)
+		for gvk, entry := range ip.informersByGVK {
+			indexer := entry.Informer.GetIndexer()
+			for _, key := range indexer.ListIndexFuncValues(kcpcache.ClusterIndexName) {
+				objs, err := indexer.ByIndex(kcpcache.ClusterIndexName, key)
+				if err != nil {
+					continue
+				}
+				objects[[4]string{strings.TrimSuffix(key, "//"), gvk.Group, gvk.Version, gvk.Kind}] += len(objs)
+			}
+		}
+		ip.mu.RUnlock()
+	}
+	runningMaps.Unlock()
+
+	for cluster, count := range informers {
+		ch <- prometheus.MustNewConstMetric(informersDesc, prometheus.GaugeValue, float64(count), cluster)
+	}
+	for labels, count := range objects {
+		ch <- prometheus.MustNewConstMetric(objectsDesc, prometheus.GaugeValue, float64(count), labels[:]...)
+	}
+}
+
+// addRunningMap adds the informer map of a running cache to the ones collected.
+func addRunningMap(ip *specificInformersMap) {
+	runningMaps.Lock()
+	defer runningMaps.Unlock()
+	runningMaps.maps[ip] = struct{}{}
+}
+
+// removeRunningMap removes the informer map of a stopped cache from the ones collected.
+func removeRunningMap(ip *specificInformersMap) {
+	runningMaps.Lock()
+	defer runningMaps.Unlock()
+	delete(runningMaps.maps, ip)
+}
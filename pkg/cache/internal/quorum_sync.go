@@ -0,0 +1,161 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/pager"
+)
+
+// quorumSyncInterval is how often the store of an informer is checked against a quorum list.
+var quorumSyncInterval = 100 * time.Millisecond
+
+// WaitForQuorumSync waits until the informers of the map hold at least the state read with a
+// quorum list of each of their kinds, one kind after the other.
+func (ip *specificInformersMap) WaitForQuorumSync(ctx context.Context) error {
+	ip.mu.RLock()
+	entries := make([]*MapEntry, 0, len(ip.informersByGVK))
+	for _, entry := range ip.informersByGVK {
+		entries = append(entries, entry)
+	}
+	ip.mu.RUnlock()
+
+	for _, entry := range entries {
+		if err := entry.waitForQuorumSync(ctx, ip.keyFunction, ip.listOptions.ChunkSize); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitForQuorumSync lists the objects of the kind of the informer with a quorum read, i.e.
+// without a resourceVersion, and waits until the informer has observed that list.  The kinds
+// which aren't served are skipped, as they have nothing to observe.
+func (e *MapEntry) waitForQuorumSync(ctx context.Context, keyFunc cache.KeyFunc, pageSize int64) error {
+	gvk := e.Reader.groupVersionKind
+	if !cache.WaitForCacheSync(ctx.Done(), func() bool { return e.Informer.HasSynced() || e.NotServed() }) {
+		return ctx.Err()
+	}
+	if e.NotServed() {
+		return nil
+	}
+
+	p := pager.New(pager.SimplePageFunc(e.list))
+	if pageSize > 0 {
+		p.PageSize = pageSize
+	}
+	list, _, err := p.List(ctx, metav1.ListOptions{})
+	if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to list %s from the API server: %w", gvk, err)
+	}
+	listMeta, err := meta.ListAccessor(list)
+	if err != nil {
+		return err
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return err
+	}
+	listed := make(map[string]string, len(items))
+	for _, item := range items {
+		key, err := keyFunc(item)
+		if err != nil {
+			return err
+		}
+		obj, err := meta.Accessor(item)
+		if err != nil {
+			return err
+		}
+		listed[key] = obj.GetResourceVersion()
+	}
+
+	err = wait.PollImmediateUntil(quorumSyncInterval, func() (bool, error) {
+		return e.observed(keyFunc, listed, listMeta.GetResourceVersion()), nil
+	}, ctx.Done())
+	if err != nil {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// observed returns whether the informer has observed the objects listed at the resourceVersion
+// of a list: either the informer has synced to that resourceVersion, e.g. with a bookmark, or
+// its store holds every listed object at its listed resourceVersion or a later one, and no
+// other object but the ones created after the list.
+func (e *MapEntry) observed(keyFunc cache.KeyFunc, listed map[string]string, listResourceVersion string) bool {
+	if resourceVersionAtLeast(e.Informer.LastSyncResourceVersion(), listResourceVersion) {
+		return true
+	}
+	store := e.Informer.GetStore()
+	for key, resourceVersion := range listed {
+		obj, exists, err := store.GetByKey(key)
+		if err != nil || !exists || !resourceVersionAtLeast(resourceVersionOf(obj), resourceVersion) {
+			return false
+		}
+	}
+	for _, obj := range store.List() {
+		key, err := keyFunc(obj)
+		if err != nil {
+			return false
+		}
+		if _, ok := listed[key]; ok {
+			continue
+		}
+		// An object which wasn't listed was deleted before the list, unless it was created
+		// after it.
+		if resourceVersionAtLeast(listResourceVersion, resourceVersionOf(obj)) {
+			return false
+		}
+	}
+	return true
+}
+
+// resourceVersionOf returns the resourceVersion of a cached object, or "" if it has none.
+func resourceVersionOf(obj interface{}) string {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return ""
+	}
+	return accessor.GetResourceVersion()
+}
+
+// resourceVersionAtLeast returns whether the resourceVersion isn't older than the given one.
+// The resourceVersions are compared as the revisions of etcd they are, the ones which aren't
+// integers are only equal to themselves.
+func resourceVersionAtLeast(resourceVersion, than string) bool {
+	v, err := strconv.ParseUint(resourceVersion, 10, 64)
+	if err != nil {
+		return resourceVersion == than
+	}
+	t, err := strconv.ParseUint(than, 10, 64)
+	if err != nil {
+		return false
+	}
+	return v >= t
+}
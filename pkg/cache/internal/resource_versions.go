@@ -0,0 +1,100 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ResourceVersions loads and saves the last resourceVersion observed by the informer of
+// each kind.
+type ResourceVersions interface {
+	Load(gvk schema.GroupVersionKind) (string, error)
+	Save(gvk schema.GroupVersionKind, resourceVersion string) error
+}
+
+// notOlderListWatch makes the ListWatch of the informer of the kind save the resourceVersion
+// of its complete lists and of the bookmarks of its watches, and makes its first list
+// request the objects at a resourceVersion not older than the saved one, so that the
+// informer doesn't sync to a state older than the one it had observed before a restart,
+// e.g. when served by a lagging API server.  It doesn't make the restarts cheaper: the
+// objects aren't saved, so the informer still lists all of them first, paginated like its
+// other lists, and watches from the resourceVersion of that list.  The list is retried as
+// usual if the API server rejects the saved resourceVersion, e.g. if it is newer than its
+// watch cache after it restarted.
+func notOlderListWatch(lw *cache.ListWatch, gvk schema.GroupVersionKind, versions ResourceVersions) {
+	var once sync.Once
+	list := lw.ListFunc
+	lw.ListFunc = func(opts metav1.ListOptions) (runtime.Object, error) {
+		if opts.Continue == "" {
+			once.Do(func() {
+				saved, err := versions.Load(gvk)
+				if err != nil {
+					log.Error(err, "unable to load the resourceVersion to list from, listing from scratch", "gvk", gvk)
+					return
+				}
+				if saved != "" {
+					opts.ResourceVersion = saved
+					opts.ResourceVersionMatch = metav1.ResourceVersionMatchNotOlderThan
+				}
+			})
+		}
+		res, err := list(opts)
+		if err != nil {
+			return res, err
+		}
+		if listMeta, err := meta.ListAccessor(res); err == nil && listMeta.GetContinue() == "" {
+			saveResourceVersion(versions, gvk, listMeta.GetResourceVersion())
+		}
+		return res, nil
+	}
+
+	watchFunc := lw.WatchFunc
+	lw.WatchFunc = func(opts metav1.ListOptions) (watch.Interface, error) {
+		w, err := watchFunc(opts)
+		if err != nil {
+			return nil, err
+		}
+		return watch.Filter(w, func(e watch.Event) (watch.Event, bool) {
+			if e.Type == watch.Bookmark {
+				if obj, err := meta.Accessor(e.Object); err == nil {
+					saveResourceVersion(versions, gvk, obj.GetResourceVersion())
+				}
+			}
+			return e, true
+		}), nil
+	}
+}
+
+// saveResourceVersion saves the resourceVersion observed by the informer of the kind.  The
+// errors are logged only: the informer works without, its first list after a restart just
+// isn't bound to a resourceVersion.
+func saveResourceVersion(versions ResourceVersions, gvk schema.GroupVersionKind, resourceVersion string) {
+	if resourceVersion == "" {
+		return
+	}
+	if err := versions.Save(gvk, resourceVersion); err != nil {
+		log.Error(err, "unable to save the resourceVersion to list from", "gvk", gvk)
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// TransformFuncByGVK associates a GroupVersionKind to the function transforming its objects
+// before they are cached.
+type TransformFuncByGVK map[schema.GroupVersionKind]cache.TransformFunc
+
+// Get returns the function transforming the objects of the GroupVersionKind, defaulting to
+// the one of GroupVersionKindAll, or nil.
+func (t TransformFuncByGVK) Get(gvk schema.GroupVersionKind) cache.TransformFunc {
+	if f, ok := t[gvk]; ok {
+		return f
+	}
+	return t[GroupVersionKindAll]
+}
+
+// transformListWatch makes the lists and watches of lw transform their objects.
+func transformListWatch(lw *cache.ListWatch, transform cache.TransformFunc) {
+	list := lw.ListFunc
+	lw.ListFunc = func(opts metav1.ListOptions) (runtime.Object, error) {
+		res, err := list(opts)
+		if err != nil {
+			return res, err
+		}
+		items, err := meta.ExtractList(res)
+		if err != nil {
+			return nil, err
+		}
+		for i := range items {
+			if items[i], err = transformObject(items[i], transform); err != nil {
+				return nil, err
+			}
+		}
+		if err := meta.SetList(res, items); err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+
+	watchFunc := lw.WatchFunc
+	lw.WatchFunc = func(opts metav1.ListOptions) (watch.Interface, error) {
+		w, err := watchFunc(opts)
+		if err != nil {
+			return nil, err
+		}
+		return watch.Filter(w, func(e watch.Event) (watch.Event, bool) {
+			if e.Type == watch.Error || e.Type == watch.Bookmark {
+				return e, true
+			}
+			obj, err := transformObject(e.Object, transform)
+			if err != nil {
+				// the reflector restarts the watch.
+				return watch.Event{Type: watch.Error, Object: &apierrors.NewInternalError(err).ErrStatus}, true
+			}
+			e.Object = obj
+			return e, true
+		}), nil
+	}
+}
+
+func transformObject(obj runtime.Object, transform cache.TransformFunc) (runtime.Object, error) {
+	transformed, err := transform(obj)
+	if err != nil {
+		return nil, err
+	}
+	res, ok := transformed.(runtime.Object)
+	if !ok {
+		return nil, fmt.Errorf("transform returned %T rather than a runtime.Object", transformed)
+	}
+	return res, nil
+}
@@ -19,6 +19,7 @@ package cache
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
@@ -44,6 +45,12 @@ const globalCache = "_cluster-scope"
 // to be used for excluding namespaces, this is better done via a Predicate. Also note that
 // you may face performance issues when using this with a high number of namespaces.
 func MultiNamespacedCacheBuilder(namespaces []string) NewCacheFunc {
+	return multiNamespacedCacheBuilder(namespaces, New)
+}
+
+// multiNamespacedCacheBuilder is MultiNamespacedCacheBuilder, creating the cache of
+// each namespace and the global cache with newCache.
+func multiNamespacedCacheBuilder(namespaces []string, newCache NewCacheFunc) NewCacheFunc {
 	return func(config *rest.Config, opts Options) (Cache, error) {
 		opts, err := defaultOpts(config, opts)
 		if err != nil {
@@ -53,14 +60,14 @@ func MultiNamespacedCacheBuilder(namespaces []string) NewCacheFunc {
 		caches := map[string]Cache{}
 
 		// create a cache for cluster scoped resources
-		gCache, err := New(config, opts)
+		gCache, err := newCache(config, opts)
 		if err != nil {
 			return nil, fmt.Errorf("error creating global cache %v", err)
 		}
 
 		for _, ns := range namespaces {
 			opts.Namespace = ns
-			c, err := New(config, opts)
+			c, err := newCache(config, opts)
 			if err != nil {
 				return nil, err
 			}
@@ -82,6 +89,7 @@ type multiNamespaceCache struct {
 }
 
 var _ Cache = &multiNamespaceCache{}
+var _ QuorumSyncer = &multiNamespaceCache{}
 
 // Methods for multiNamespaceCache to conform to the Informers interface.
 func (c *multiNamespaceCache) GetInformer(ctx context.Context, obj client.Object) (Informer, error) {
@@ -145,8 +153,12 @@ func (c *multiNamespaceCache) GetInformerForKind(ctx context.Context, gvk schema
 }
 
 func (c *multiNamespaceCache) Start(ctx context.Context) error {
+	var wg sync.WaitGroup
+
 	// start global cache
+	wg.Add(1)
 	go func() {
+		defer wg.Done()
 		err := c.clusterCache.Start(ctx)
 		if err != nil {
 			log.Error(err, "cluster scoped cache failed to start")
@@ -155,7 +167,9 @@ func (c *multiNamespaceCache) Start(ctx context.Context) error {
 
 	// start namespaced caches
 	for ns, cache := range c.namespaceToCache {
+		wg.Add(1)
 		go func(ns string, cache Cache) {
+			defer wg.Done()
 			err := cache.Start(ctx)
 			if err != nil {
 				log.Error(err, "multinamespace cache failed to start namespaced informer", "namespace", ns)
@@ -164,6 +178,7 @@ func (c *multiNamespaceCache) Start(ctx context.Context) error {
 	}
 
 	<-ctx.Done()
+	wg.Wait()
 	return nil
 }
 
@@ -182,6 +197,16 @@ func (c *multiNamespaceCache) WaitForCacheSync(ctx context.Context) bool {
 	return synced
 }
 
+// WaitForQuorumSync implements QuorumSyncer.
+func (c *multiNamespaceCache) WaitForQuorumSync(ctx context.Context) error {
+	for _, cache := range c.namespaceToCache {
+		if err := WaitForQuorumSync(ctx, cache); err != nil {
+			return err
+		}
+	}
+	return WaitForQuorumSync(ctx, c.clusterCache)
+}
+
 func (c *multiNamespaceCache) IndexField(ctx context.Context, obj client.Object, field string, extractValue client.IndexerFunc) error {
 	isNamespaced, err := objectutil.IsAPINamespaced(obj, c.Scheme, c.RESTMapper)
 	if err != nil {
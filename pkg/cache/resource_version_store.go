@@ -0,0 +1,117 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kcp-dev/logicalcluster"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/cache/internal"
+)
+
+// ResourceVersionStore persists the last resourceVersion observed by the informers of the
+// caches, per logical cluster and kind, across restarts.
+type ResourceVersionStore interface {
+	// Load returns the resourceVersion last saved for the kind in the logical cluster, or ""
+	// if none was saved.
+	Load(cluster logicalcluster.Name, gvk schema.GroupVersionKind) (string, error)
+
+	// Save saves the resourceVersion observed for the kind in the logical cluster.
+	Save(cluster logicalcluster.Name, gvk schema.GroupVersionKind, resourceVersion string) error
+}
+
+// NewFileResourceVersionStore returns a ResourceVersionStore saving the resourceVersions
+// in the files of the given directory, one per logical cluster and kind, e.g. on a volume
+// kept across the restarts of the controller.  The directory is created if it doesn't
+// exist.
+func NewFileResourceVersionStore(dir string) (ResourceVersionStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("unable to create the directory of the resourceVersions: %w", err)
+	}
+	return &fileResourceVersionStore{dir: dir}, nil
+}
+
+// fileResourceVersionStore saves each resourceVersion in a file of its own, so that the
+// informers of the many logical clusters and kinds saving them don't contend.
+type fileResourceVersionStore struct {
+	dir string
+}
+
+var _ ResourceVersionStore = &fileResourceVersionStore{}
+
+// path returns the path of the file of the kind of the logical cluster.
+func (s *fileResourceVersionStore) path(cluster logicalcluster.Name, gvk schema.GroupVersionKind) string {
+	name := cluster.String()
+	if cluster.Empty() {
+		name = "default"
+	}
+	kind := strings.Join([]string{gvk.Kind, gvk.Version, gvk.Group}, ".")
+	return filepath.Join(s.dir, url.PathEscape(name), url.PathEscape(kind))
+}
+
+// Load implements ResourceVersionStore.
+func (s *fileResourceVersionStore) Load(cluster logicalcluster.Name, gvk schema.GroupVersionKind) (string, error) {
+	data, err := ioutil.ReadFile(s.path(cluster, gvk))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return strings.TrimSpace(string(data)), err
+}
+
+// Save implements ResourceVersionStore.  The file is replaced atomically, so that a
+// restart while saving doesn't leave it truncated.
+func (s *fileResourceVersionStore) Save(cluster logicalcluster.Name, gvk schema.GroupVersionKind, resourceVersion string) error {
+	path := s.path(cluster, gvk)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(resourceVersion); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// clusterResourceVersions binds a ResourceVersionStore to the logical cluster of a cache.
+type clusterResourceVersions struct {
+	store   ResourceVersionStore
+	cluster logicalcluster.Name
+}
+
+var _ internal.ResourceVersions = clusterResourceVersions{}
+
+func (v clusterResourceVersions) Load(gvk schema.GroupVersionKind) (string, error) {
+	return v.store.Load(v.cluster, gvk)
+}
+
+func (v clusterResourceVersions) Save(gvk schema.GroupVersionKind, resourceVersion string) error {
+	return v.store.Save(v.cluster, gvk, resourceVersion)
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiutil
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+)
+
+// ClusterRESTMapperFunc returns the RESTMapper of a logical cluster.
+type ClusterRESTMapperFunc func(cluster logicalcluster.Name) (meta.RESTMapper, error)
+
+// MultiClusterRESTMapper holds one RESTMapper per logical cluster, so that kinds are resolved
+// with the discovery of the logical cluster they are used in: different kcp workspaces can
+// serve different resources, e.g. because they have different CRDs or APIBindings.
+//
+// The RESTMapper of a logical cluster is created the first time it is needed, and dropped by
+// Invalidate, e.g. when the CRDs of the logical cluster change.  A MultiClusterRESTMapper is
+// also a meta.RESTMapper itself, which resolves kinds with the RESTMappers of the logical
+// clusters created so far, see ForCluster to target a single logical cluster.
+type MultiClusterRESTMapper struct {
+	newMapper ClusterRESTMapperFunc
+
+	mu      sync.RWMutex
+	mappers map[logicalcluster.Name]meta.RESTMapper
+}
+
+var _ meta.RESTMapper = &MultiClusterRESTMapper{}
+
+// NewMultiClusterRESTMapper returns a MultiClusterRESTMapper creating a lazy dynamic RESTMapper
+// per logical cluster, targeting the /clusters/<name> path of the kcp server cfg targets.
+// opts configure the dynamic RESTMappers.
+func NewMultiClusterRESTMapper(cfg *rest.Config, opts ...DynamicRESTMapperOption) (*MultiClusterRESTMapper, error) {
+	if cfg == nil {
+		return nil, errors.New("must specify Config")
+	}
+	opts = append([]DynamicRESTMapperOption{WithLazyDiscovery}, opts...)
+	return NewMultiClusterRESTMapperFor(func(cluster logicalcluster.Name) (meta.RESTMapper, error) {
+		config := rest.CopyConfig(cfg)
+		config.Host = strings.TrimSuffix(config.Host, "/") + cluster.Path()
+		return NewDynamicRESTMapper(config, opts...)
+	}), nil
+}
+
+// NewMultiClusterRESTMapperFor returns a MultiClusterRESTMapper creating the RESTMapper of each
+// logical cluster with newMapper.
+func NewMultiClusterRESTMapperFor(newMapper ClusterRESTMapperFunc) *MultiClusterRESTMapper {
+	return &MultiClusterRESTMapper{
+		newMapper: newMapper,
+		mappers:   map[logicalcluster.Name]meta.RESTMapper{},
+	}
+}
+
+// ForCluster returns the RESTMapper of the logical cluster, creating it if needed.
+func (m *MultiClusterRESTMapper) ForCluster(cluster logicalcluster.Name) (meta.RESTMapper, error) {
+	if cluster.Empty() || cluster == logicalcluster.Wildcard {
+		return nil, errors.New("must specify a single logical cluster")
+	}
+
+	m.mu.RLock()
+	mapper, ok := m.mappers[cluster]
+	m.mu.RUnlock()
+	if ok {
+		return mapper, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if mapper, ok := m.mappers[cluster]; ok {
+		return mapper, nil
+	}
+	mapper, err := m.newMapper(cluster)
+	if err != nil {
+		return nil, err
+	}
+	m.mappers[cluster] = mapper
+	return mapper, nil
+}
+
+// ForContext returns the RESTMapper of the logical cluster of the context, or the
+// MultiClusterRESTMapper itself if the context doesn't target a single logical cluster.
+func (m *MultiClusterRESTMapper) ForContext(ctx context.Context) (meta.RESTMapper, error) {
+	cluster, ok := kcpclient.ClusterFromContext(ctx)
+	if !ok || cluster.Empty() || cluster == logicalcluster.Wildcard {
+		return m, nil
+	}
+	return m.ForCluster(cluster)
+}
+
+// Invalidate drops the RESTMapper of the logical cluster, so that the next resolution in the
+// logical cluster discovers its resources again.
+func (m *MultiClusterRESTMapper) Invalidate(cluster logicalcluster.Name) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.mappers, cluster)
+}
+
+// Clusters returns the logical clusters the RESTMappers of which are created, sorted.
+func (m *MultiClusterRESTMapper) Clusters() []logicalcluster.Name {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	clusters := make([]logicalcluster.Name, 0, len(m.mappers))
+	for cluster := range m.mappers {
+		clusters = append(clusters, cluster)
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].String() < clusters[j].String() })
+	return clusters
+}
+
+// created returns the RESTMappers created so far, sorted by logical cluster.
+func (m *MultiClusterRESTMapper) created() meta.MultiRESTMapper {
+	clusters := m.Clusters()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	mappers := make(meta.MultiRESTMapper, 0, len(clusters))
+	for _, cluster := range clusters {
+		if mapper, ok := m.mappers[cluster]; ok {
+			mappers = append(mappers, mapper)
+		}
+	}
+	return mappers
+}
+
+// KindFor implements meta.RESTMapper, it returns the kind of the first logical cluster
+// resolving the resource.
+func (m *MultiClusterRESTMapper) KindFor(resource schema.GroupVersionResource) (schema.GroupVersionKind, error) {
+	var err error = &meta.NoResourceMatchError{PartialResource: resource}
+	for _, mapper := range m.created() {
+		var gvk schema.GroupVersionKind
+		if gvk, err = mapper.KindFor(resource); err == nil {
+			return gvk, nil
+		}
+	}
+	return schema.GroupVersionKind{}, err
+}
+
+// KindsFor implements meta.RESTMapper, it returns the kinds of all the logical clusters.
+func (m *MultiClusterRESTMapper) KindsFor(resource schema.GroupVersionResource) ([]schema.GroupVersionKind, error) {
+	return m.created().KindsFor(resource)
+}
+
+// ResourceFor implements meta.RESTMapper, it returns the resource of the first logical cluster
+// resolving the partial resource.
+func (m *MultiClusterRESTMapper) ResourceFor(input schema.GroupVersionResource) (schema.GroupVersionResource, error) {
+	var err error = &meta.NoResourceMatchError{PartialResource: input}
+	for _, mapper := range m.created() {
+		var gvr schema.GroupVersionResource
+		if gvr, err = mapper.ResourceFor(input); err == nil {
+			return gvr, nil
+		}
+	}
+	return schema.GroupVersionResource{}, err
+}
+
+// ResourcesFor implements meta.RESTMapper, it returns the resources of all the logical clusters.
+func (m *MultiClusterRESTMapper) ResourcesFor(input schema.GroupVersionResource) ([]schema.GroupVersionResource, error) {
+	return m.created().ResourcesFor(input)
+}
+
+// RESTMapping implements meta.RESTMapper, it returns the mapping of the first logical cluster
+// serving the kind.
+func (m *MultiClusterRESTMapper) RESTMapping(gk schema.GroupKind, versions ...string) (*meta.RESTMapping, error) {
+	var err error = &meta.NoKindMatchError{GroupKind: gk, SearchedVersions: versions}
+	for _, mapper := range m.created() {
+		var mapping *meta.RESTMapping
+		if mapping, err = mapper.RESTMapping(gk, versions...); err == nil {
+			return mapping, nil
+		}
+	}
+	return nil, err
+}
+
+// RESTMappings implements meta.RESTMapper, it returns the mappings of all the logical clusters.
+func (m *MultiClusterRESTMapper) RESTMappings(gk schema.GroupKind, versions ...string) ([]*meta.RESTMapping, error) {
+	return m.created().RESTMappings(gk, versions...)
+}
+
+// ResourceSingularizer implements meta.RESTMapper.
+func (m *MultiClusterRESTMapper) ResourceSingularizer(resource string) (string, error) {
+	return m.created().ResourceSingularizer(resource)
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiutil_test
+
+import (
+	"context"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+var _ = Describe("Multi-cluster REST Mapper", func() {
+	a := logicalcluster.New("root:a")
+	b := logicalcluster.New("root:b")
+	var mapper *apiutil.MultiClusterRESTMapper
+	var created map[logicalcluster.Name]int
+
+	BeforeEach(func() {
+		created = map[logicalcluster.Name]int{}
+		mapper = apiutil.NewMultiClusterRESTMapperFor(func(cluster logicalcluster.Name) (meta.RESTMapper, error) {
+			created[cluster]++
+			baseMapper := meta.NewDefaultRESTMapper(nil)
+			switch cluster {
+			case a:
+				baseMapper.Add(targetGVK, meta.RESTScopeNamespace)
+			case b:
+				baseMapper.Add(secondGVK, meta.RESTScopeNamespace)
+			}
+			return baseMapper, nil
+		})
+	})
+
+	It("should resolve the kinds with the RESTMapper of the logical cluster", func() {
+		mapperA, err := mapper.ForCluster(a)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = mapperA.RESTMapping(targetGVK.GroupKind(), targetGVK.Version)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = mapperA.RESTMapping(secondGVK.GroupKind(), secondGVK.Version)
+		Expect(meta.IsNoMatchError(err)).To(BeTrue())
+
+		mapperB, err := mapper.ForContext(kcpclient.WithCluster(context.Background(), b))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = mapperB.RESTMapping(secondGVK.GroupKind(), secondGVK.Version)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = mapper.ForCluster(logicalcluster.Wildcard)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should create the RESTMapper of a logical cluster once, until it is invalidated", func() {
+		_, err := mapper.ForCluster(a)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = mapper.ForCluster(a)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(created[a]).To(Equal(1))
+
+		mapper.Invalidate(a)
+		Expect(mapper.Clusters()).To(BeEmpty())
+		_, err = mapper.ForCluster(a)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(created[a]).To(Equal(2))
+	})
+
+	It("should resolve the kinds with the RESTMappers of all the logical clusters as a RESTMapper", func() {
+		_, err := mapper.RESTMapping(targetGVK.GroupKind(), targetGVK.Version)
+		Expect(meta.IsNoMatchError(err)).To(BeTrue())
+
+		_, err = mapper.ForCluster(a)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = mapper.ForCluster(b)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mapper.Clusters()).To(Equal([]logicalcluster.Name{a, b}))
+
+		mapping, err := mapper.RESTMapping(targetGVK.GroupKind(), targetGVK.Version)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mapping.Resource).To(Equal(targetGVR))
+		gvk, err := mapper.KindFor(secondGVR)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gvk).To(Equal(secondGVK))
+
+		root, err := mapper.ForContext(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(root).To(BeIdenticalTo(mapper))
+	})
+})
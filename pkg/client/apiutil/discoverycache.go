@@ -0,0 +1,251 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiutil
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	openapi_v2 "github.com/googleapis/gnostic/openapiv2"
+	"github.com/kcp-dev/logicalcluster"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+)
+
+// DefaultDiscoveryTTL is the default time the documents of a DiscoveryCache are cached.
+const DefaultDiscoveryTTL = 10 * time.Minute
+
+// ClusterDiscoveryFunc returns the discovery client of a logical cluster.
+type ClusterDiscoveryFunc func(cluster logicalcluster.Name) (discovery.DiscoveryInterface, error)
+
+// DiscoveryCache caches the discovery and OpenAPI documents of each logical cluster, so that
+// the RESTMappers and clients of a controller contacting hundreds of workspaces share them
+// rather than fetching the same documents again and again.
+//
+// The documents of a logical cluster are fetched again once they are older than the TTL, or
+// once they are invalidated, e.g. by InvalidateOnError when a kind is not found.
+type DiscoveryCache struct {
+	// TTL is how long the documents of a logical cluster are cached, it defaults to
+	// DefaultDiscoveryTTL.
+	TTL time.Duration
+
+	newDiscovery ClusterDiscoveryFunc
+
+	mu       sync.Mutex
+	clusters map[logicalcluster.Name]*clusterDiscovery
+}
+
+// NewDiscoveryCache returns a DiscoveryCache discovering each logical cluster at the
+// /clusters/<name> path of the kcp server cfg targets.
+func NewDiscoveryCache(cfg *rest.Config) (*DiscoveryCache, error) {
+	if cfg == nil {
+		return nil, errors.New("must specify Config")
+	}
+	return NewDiscoveryCacheFor(func(cluster logicalcluster.Name) (discovery.DiscoveryInterface, error) {
+		config := rest.CopyConfig(cfg)
+		config.Host = strings.TrimSuffix(config.Host, "/") + cluster.Path()
+		return discovery.NewDiscoveryClientForConfig(config)
+	}), nil
+}
+
+// NewDiscoveryCacheFor returns a DiscoveryCache discovering each logical cluster with the
+// discovery client returned by newDiscovery.
+func NewDiscoveryCacheFor(newDiscovery ClusterDiscoveryFunc) *DiscoveryCache {
+	return &DiscoveryCache{
+		TTL:          DefaultDiscoveryTTL,
+		newDiscovery: newDiscovery,
+		clusters:     map[logicalcluster.Name]*clusterDiscovery{},
+	}
+}
+
+// ForCluster returns the cached discovery client of the logical cluster.
+func (c *DiscoveryCache) ForCluster(cluster logicalcluster.Name) (discovery.CachedDiscoveryInterface, error) {
+	return c.forCluster(cluster)
+}
+
+func (c *DiscoveryCache) forCluster(cluster logicalcluster.Name) (*clusterDiscovery, error) {
+	if cluster.Empty() || cluster == logicalcluster.Wildcard {
+		return nil, errors.New("must specify a single logical cluster")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if d, ok := c.clusters[cluster]; ok {
+		return d, nil
+	}
+	delegate, err := c.newDiscovery(cluster)
+	if err != nil {
+		return nil, err
+	}
+	d := &clusterDiscovery{CachedDiscoveryInterface: memory.NewMemCacheClient(delegate), cache: c}
+	d.Invalidate()
+	c.clusters[cluster] = d
+	return d, nil
+}
+
+// Invalidate drops the documents of the logical cluster, so that they are fetched again.
+func (c *DiscoveryCache) Invalidate(cluster logicalcluster.Name) {
+	c.mu.Lock()
+	d, ok := c.clusters[cluster]
+	c.mu.Unlock()
+	if ok {
+		d.Invalidate()
+	}
+}
+
+// InvalidateOnError invalidates the documents of the logical cluster if err shows that they
+// are stale: a NoKindMatchError or NoResourceMatchError, or a NotFound error for a resource
+// rather than for an object, e.g. because the APIBinding serving it was removed.  It returns
+// whether the documents were invalidated.
+func (c *DiscoveryCache) InvalidateOnError(cluster logicalcluster.Name, err error) bool {
+	if !meta.IsNoMatchError(err) && !isResourceNotFound(err) {
+		return false
+	}
+	c.Invalidate(cluster)
+	return true
+}
+
+func isResourceNotFound(err error) bool {
+	var status apierrors.APIStatus
+	if !apierrors.IsNotFound(err) || !errors.As(err, &status) {
+		return false
+	}
+	details := status.Status().Details
+	return details == nil || details.Name == ""
+}
+
+// RESTMapperFunc returns a ClusterRESTMapperFunc creating dynamic RESTMappers which read the
+// documents of the cache, e.g. for NewMultiClusterRESTMapperFor.  The documents are
+// invalidated whenever a RESTMapper reloads them because a kind is not found, or is created
+// again after MultiClusterRESTMapper.Invalidate.  opts configure the dynamic RESTMappers.
+func (c *DiscoveryCache) RESTMapperFunc(opts ...DynamicRESTMapperOption) ClusterRESTMapperFunc {
+	return func(cluster logicalcluster.Name) (meta.RESTMapper, error) {
+		d, err := c.forCluster(cluster)
+		if err != nil {
+			return nil, err
+		}
+		return newDynamicRESTMapper(func() (meta.RESTMapper, error) {
+			if d.mapped() {
+				d.Invalidate()
+			}
+			groupResources, err := restmapper.GetAPIGroupResources(d)
+			if err != nil {
+				return nil, err
+			}
+			return restmapper.NewDiscoveryRESTMapper(groupResources), nil
+		}, append([]DynamicRESTMapperOption{WithLazyDiscovery}, opts...)...)
+	}
+}
+
+// clusterDiscovery is the cached discovery client of a logical cluster.  It caches the
+// OpenAPI document, which the memory cached discovery client doesn't, and expires the
+// documents after the TTL of the cache.
+type clusterDiscovery struct {
+	discovery.CachedDiscoveryInterface
+	cache *DiscoveryCache
+
+	// since is when the documents were last invalidated, they expire after the TTL.
+	mu         sync.Mutex
+	since      time.Time
+	openAPI    *openapi_v2.Document
+	mappedOnce bool
+}
+
+// expire invalidates the documents once they are older than the TTL.
+func (d *clusterDiscovery) expire() {
+	ttl := d.cache.TTL
+	if ttl <= 0 {
+		ttl = DefaultDiscoveryTTL
+	}
+	d.mu.Lock()
+	expired := time.Now().Sub(d.since) > ttl
+	d.mu.Unlock()
+	if expired {
+		d.Invalidate()
+	}
+}
+
+// mapped records that a RESTMapper loads the documents, and returns whether one did before.
+func (d *clusterDiscovery) mapped() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	before := d.mappedOnce
+	d.mappedOnce = true
+	return before
+}
+
+// Invalidate implements discovery.CachedDiscoveryInterface.
+func (d *clusterDiscovery) Invalidate() {
+	d.mu.Lock()
+	d.since = time.Now()
+	d.openAPI = nil
+	d.mu.Unlock()
+	d.CachedDiscoveryInterface.Invalidate()
+}
+
+// ServerGroups implements discovery.DiscoveryInterface.
+func (d *clusterDiscovery) ServerGroups() (*metav1.APIGroupList, error) {
+	d.expire()
+	return d.CachedDiscoveryInterface.ServerGroups()
+}
+
+// ServerResourcesForGroupVersion implements discovery.DiscoveryInterface.
+func (d *clusterDiscovery) ServerResourcesForGroupVersion(groupVersion string) (*metav1.APIResourceList, error) {
+	d.expire()
+	return d.CachedDiscoveryInterface.ServerResourcesForGroupVersion(groupVersion)
+}
+
+// ServerGroupsAndResources implements discovery.DiscoveryInterface.
+func (d *clusterDiscovery) ServerGroupsAndResources() ([]*metav1.APIGroup, []*metav1.APIResourceList, error) {
+	d.expire()
+	return discovery.ServerGroupsAndResources(d)
+}
+
+// ServerPreferredResources implements discovery.DiscoveryInterface.
+func (d *clusterDiscovery) ServerPreferredResources() ([]*metav1.APIResourceList, error) {
+	d.expire()
+	return discovery.ServerPreferredResources(d)
+}
+
+// ServerPreferredNamespacedResources implements discovery.DiscoveryInterface.
+func (d *clusterDiscovery) ServerPreferredNamespacedResources() ([]*metav1.APIResourceList, error) {
+	d.expire()
+	return discovery.ServerPreferredNamespacedResources(d)
+}
+
+// OpenAPISchema implements discovery.DiscoveryInterface, it caches the OpenAPI document.
+func (d *clusterDiscovery) OpenAPISchema() (*openapi_v2.Document, error) {
+	d.expire()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.openAPI != nil {
+		return d.openAPI, nil
+	}
+	doc, err := d.CachedDiscoveryInterface.OpenAPISchema()
+	if err != nil {
+		return nil, err
+	}
+	d.openAPI = doc
+	return doc, nil
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiutil_test
+
+import (
+	"time"
+
+	openapi_v2 "github.com/googleapis/gnostic/openapiv2"
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// countingDiscovery counts the OpenAPI documents it serves, which the fake discovery
+// client doesn't record.
+type countingDiscovery struct {
+	*fakediscovery.FakeDiscovery
+	openAPI int
+}
+
+func (d *countingDiscovery) OpenAPISchema() (*openapi_v2.Document, error) {
+	d.openAPI++
+	return d.FakeDiscovery.OpenAPISchema()
+}
+
+var _ = Describe("DiscoveryCache", func() {
+	a := logicalcluster.New("root:a")
+	b := logicalcluster.New("root:b")
+	var cache *apiutil.DiscoveryCache
+	var discoveries map[logicalcluster.Name]*countingDiscovery
+
+	fetches := func(cluster logicalcluster.Name) int {
+		return len(discoveries[cluster].Actions())
+	}
+
+	BeforeEach(func() {
+		discoveries = map[logicalcluster.Name]*countingDiscovery{}
+		cache = apiutil.NewDiscoveryCacheFor(func(cluster logicalcluster.Name) (discovery.DiscoveryInterface, error) {
+			d := &countingDiscovery{FakeDiscovery: &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{}}}
+			d.Resources = []*metav1.APIResourceList{{
+				GroupVersion: targetGVK.GroupVersion().String(),
+				APIResources: []metav1.APIResource{{Name: "targets", Kind: targetGVK.Kind, Namespaced: true}},
+			}}
+			discoveries[cluster] = d
+			return d, nil
+		})
+	})
+
+	It("should cache the documents of each logical cluster", func() {
+		da, err := cache.ForCluster(a)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = da.ServerGroups()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = da.OpenAPISchema()
+		Expect(err).NotTo(HaveOccurred())
+		fetched := fetches(a)
+		Expect(fetched).NotTo(BeZero())
+
+		again, err := cache.ForCluster(a)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = again.ServerResourcesForGroupVersion(targetGVK.GroupVersion().String())
+		Expect(err).NotTo(HaveOccurred())
+		_, err = again.OpenAPISchema()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fetches(a)).To(Equal(fetched))
+		Expect(discoveries[a].openAPI).To(Equal(1))
+
+		db, err := cache.ForCluster(b)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = db.ServerGroups()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fetches(b)).To(Equal(fetched))
+
+		_, err = cache.ForCluster(logicalcluster.Wildcard)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should fetch the documents again once they are older than the TTL", func() {
+		cache.TTL = 10 * time.Millisecond
+		da, err := cache.ForCluster(a)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = da.OpenAPISchema()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = da.OpenAPISchema()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(discoveries[a].openAPI).To(Equal(1))
+
+		time.Sleep(20 * time.Millisecond)
+		_, err = da.OpenAPISchema()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(discoveries[a].openAPI).To(Equal(2))
+	})
+
+	It("should invalidate the documents of a logical cluster on the errors showing they are stale", func() {
+		da, err := cache.ForCluster(a)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = da.ServerGroups()
+		Expect(err).NotTo(HaveOccurred())
+		fetched := fetches(a)
+
+		gr := schema.GroupResource{Group: targetGVK.Group, Resource: "targets"}
+		Expect(cache.InvalidateOnError(a, apierrors.NewNotFound(gr, "some-object"))).To(BeFalse())
+		Expect(cache.InvalidateOnError(a, apierrors.NewBadRequest("bad"))).To(BeFalse())
+		_, err = da.ServerGroups()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fetches(a)).To(Equal(fetched))
+
+		Expect(cache.InvalidateOnError(a, &meta.NoKindMatchError{GroupKind: targetGVK.GroupKind()})).To(BeTrue())
+		_, err = da.ServerGroups()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fetches(a)).To(BeNumerically(">", fetched))
+
+		fetched = fetches(a)
+		Expect(cache.InvalidateOnError(a, apierrors.NewNotFound(gr, ""))).To(BeTrue())
+		_, err = da.ServerGroups()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fetches(a)).To(BeNumerically(">", fetched))
+	})
+
+	It("should back the RESTMappers of a MultiClusterRESTMapper, discovering again once invalidated", func() {
+		mapper := apiutil.NewMultiClusterRESTMapperFor(cache.RESTMapperFunc())
+		mapperA, err := mapper.ForCluster(a)
+		Expect(err).NotTo(HaveOccurred())
+		mapping, err := mapperA.RESTMapping(targetGVK.GroupKind())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mapping.Resource.Resource).To(Equal("targets"))
+		fetched := fetches(a)
+
+		da, err := cache.ForCluster(a)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = da.ServerGroups()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fetches(a)).To(Equal(fetched), "the documents are shared with the RESTMapper")
+
+		mapper.Invalidate(a)
+		mapperA, err = mapper.ForCluster(a)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = mapperA.RESTMapping(targetGVK.GroupKind())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fetches(a)).To(BeNumerically(">", fetched))
+	})
+})
@@ -0,0 +1,199 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/kcp-dev/logicalcluster"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	jsonserializer "k8s.io/apimachinery/pkg/runtime/serializer/json"
+	"k8s.io/client-go/rest"
+)
+
+// StrictDecoding selects the objects whose decoding rejects unknown and duplicate fields,
+// e.g. to catch the drift between the schema of a kind in a logical cluster and the Go
+// type of the kind early in development.  Only the kinds registered in the scheme are
+// checked.
+type StrictDecoding struct {
+	// Kinds are the kinds whose objects are decoded strictly.
+	Kinds []schema.GroupVersionKind
+
+	// Clusters overrides Kinds for the objects of the given logical clusters.  An empty
+	// list of kinds disables strict decoding in a logical cluster.
+	Clusters map[logicalcluster.Name][]schema.GroupVersionKind
+}
+
+// IsStrict returns whether the objects of the given kind in the given logical cluster are
+// decoded strictly.
+func (s *StrictDecoding) IsStrict(cluster logicalcluster.Name, gvk schema.GroupVersionKind) bool {
+	if s == nil {
+		return false
+	}
+	kinds := s.Kinds
+	if clusterKinds, ok := s.Clusters[cluster]; ok {
+		kinds = clusterKinds
+	}
+	return containsKind(kinds, gvk)
+}
+
+// AppliesTo returns whether the objects of the given kind are decoded strictly in at least
+// one logical cluster.
+func (s *StrictDecoding) AppliesTo(gvk schema.GroupVersionKind) bool {
+	if s == nil {
+		return false
+	}
+	if containsKind(s.Kinds, gvk) {
+		return true
+	}
+	for _, kinds := range s.Clusters {
+		if containsKind(kinds, gvk) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check decodes the JSON data of an object, or of a list of objects, strictly if their kind
+// and logical cluster require it, and returns the strict decoding error.  The kind of the
+// data defaults to gvk if it isn't set in the data, like in the items of a list.
+func (s *StrictDecoding) Check(scheme *runtime.Scheme, data []byte, gvk *schema.GroupVersionKind) error {
+	if s == nil {
+		return nil
+	}
+	head, err := decodeHead(data, gvk)
+	if err != nil {
+		// Not JSON, e.g. protobuf, which has no unknown fields to reject.
+		return nil //nolint:nilerr
+	}
+	if head.Items == nil || !strings.HasSuffix(head.gvk.Kind, "List") {
+		return s.checkObject(scheme, data, head)
+	}
+
+	itemGVK := head.gvk.GroupVersion().WithKind(strings.TrimSuffix(head.gvk.Kind, "List"))
+	for _, item := range head.Items {
+		itemHead, err := decodeHead(item, &itemGVK)
+		if err != nil {
+			return nil //nolint:nilerr
+		}
+		if err := s.checkObject(scheme, item, itemHead); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *StrictDecoding) checkObject(scheme *runtime.Scheme, data []byte, head objectHead) error {
+	cluster := logicalcluster.New(head.Metadata.ClusterName)
+	if !s.IsStrict(cluster, head.gvk) {
+		return nil
+	}
+	into, err := scheme.New(head.gvk)
+	if err != nil {
+		return nil //nolint:nilerr // unregistered kinds are not checked
+	}
+	strict := jsonserializer.NewSerializerWithOptions(jsonserializer.DefaultMetaFactory, scheme, scheme,
+		jsonserializer.SerializerOptions{Strict: true})
+	if _, _, err := strict.Decode(data, &head.gvk, into); runtime.IsStrictDecodingError(err) {
+		return fmt.Errorf("%s %s/%s in logical cluster %q: %w", head.gvk.Kind, head.Metadata.Namespace, head.Metadata.Name, cluster, err)
+	}
+	// Other errors are reported by the regular decoding.
+	return nil
+}
+
+// objectHead is the part of the JSON data of an object, or of a list, needed to check it.
+type objectHead struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+	Metadata   struct {
+		Name        string `json:"name,omitempty"`
+		Namespace   string `json:"namespace,omitempty"`
+		ClusterName string `json:"clusterName,omitempty"`
+	} `json:"metadata,omitempty"`
+	Items []json.RawMessage `json:"items,omitempty"`
+
+	gvk schema.GroupVersionKind
+}
+
+func decodeHead(data []byte, defaults *schema.GroupVersionKind) (objectHead, error) {
+	head := objectHead{}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return head, err
+	}
+	head.gvk = schema.FromAPIVersionAndKind(head.APIVersion, head.Kind)
+	if defaults != nil {
+		if head.gvk.Kind == "" {
+			head.gvk.Kind = defaults.Kind
+		}
+		if head.gvk.Version == "" && head.gvk.Group == "" {
+			head.gvk.Group, head.gvk.Version = defaults.Group, defaults.Version
+		}
+	}
+	return head, nil
+}
+
+func containsKind(kinds []schema.GroupVersionKind, gvk schema.GroupVersionKind) bool {
+	for _, kind := range kinds {
+		if kind == gvk {
+			return true
+		}
+	}
+	return false
+}
+
+// StrictRESTClientForGVK is like RESTClientForGVK, but the client requests JSON, and checks
+// the objects it decodes with strict, failing the requests returning objects with unknown
+// fields.
+func StrictRESTClientForGVK(gvk schema.GroupVersionKind, isUnstructured bool, baseConfig *rest.Config, codecs serializer.CodecFactory, scheme *runtime.Scheme, strict *StrictDecoding) (rest.Interface, error) {
+	cfg := createRestConfig(gvk, isUnstructured, baseConfig, codecs)
+	cfg.ContentType = runtime.ContentTypeJSON
+	cfg.NegotiatedSerializer = serializerWithStrictCheck{NegotiatedSerializer: cfg.NegotiatedSerializer, scheme: scheme, strict: strict}
+	return rest.RESTClientFor(cfg)
+}
+
+// serializerWithStrictCheck is a NegotiatedSerializer whose decoders check the data they
+// decode, be it the object of a request, a list, or the object of a watch event.
+type serializerWithStrictCheck struct {
+	runtime.NegotiatedSerializer
+	scheme *runtime.Scheme
+	strict *StrictDecoding
+}
+
+func (s serializerWithStrictCheck) DecoderToVersion(serializer runtime.Decoder, gv runtime.GroupVersioner) runtime.Decoder {
+	return strictCheckingDecoder{upstream: s.NegotiatedSerializer.DecoderToVersion(serializer, gv), scheme: s.scheme, strict: s.strict}
+}
+
+type strictCheckingDecoder struct {
+	upstream runtime.Decoder
+	scheme   *runtime.Scheme
+	strict   *StrictDecoding
+}
+
+func (d strictCheckingDecoder) Decode(data []byte, defaults *schema.GroupVersionKind, into runtime.Object) (runtime.Object, *schema.GroupVersionKind, error) {
+	obj, gvk, err := d.upstream.Decode(data, defaults, into)
+	if err != nil {
+		return obj, gvk, err
+	}
+	if err := d.strict.Check(d.scheme, data, gvk); err != nil {
+		return nil, gvk, err
+	}
+	return obj, gvk, nil
+}
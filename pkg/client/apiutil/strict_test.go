@@ -0,0 +1,101 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiutil_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+var _ = Describe("StrictDecoding", func() {
+	podGVK := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	dev := logicalcluster.New("root:org:dev")
+	prod := logicalcluster.New("root:org:prod")
+
+	strict := &apiutil.StrictDecoding{
+		Kinds:    []schema.GroupVersionKind{podGVK},
+		Clusters: map[logicalcluster.Name][]schema.GroupVersionKind{prod: nil},
+	}
+
+	podList := func(cluster logicalcluster.Name) []byte {
+		return []byte(`{"apiVersion": "v1", "kind": "PodList", "metadata": {}, "items": [
+			{"metadata": {"name": "valid", "clusterName": "root:org:dev"}},
+			{"metadata": {"name": "drifted", "clusterName": "` + cluster.String() + `"}, "spec": {"unknownField": true}}
+		]}`)
+	}
+
+	It("should select the kinds per logical cluster", func() {
+		Expect(strict.IsStrict(dev, podGVK)).To(BeTrue())
+		Expect(strict.IsStrict(prod, podGVK)).To(BeFalse())
+		Expect(strict.IsStrict(dev, podGVK.GroupVersion().WithKind("Node"))).To(BeFalse())
+		Expect(strict.AppliesTo(podGVK)).To(BeTrue())
+
+		var unset *apiutil.StrictDecoding
+		Expect(unset.IsStrict(dev, podGVK)).To(BeFalse())
+		Expect(unset.Check(scheme.Scheme, podList(dev), nil)).To(Succeed())
+	})
+
+	It("should reject the items of a list with unknown fields", func() {
+		err := strict.Check(scheme.Scheme, podList(dev), nil)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("drifted"))
+		Expect(err.Error()).To(ContainSubstring("unknownField"))
+
+		By("accepting them in the logical clusters where strict decoding is disabled")
+		Expect(strict.Check(scheme.Scheme, podList(prod), nil)).To(Succeed())
+	})
+
+	It("should not check the kinds unknown to the scheme", func() {
+		unknown := &apiutil.StrictDecoding{Kinds: []schema.GroupVersionKind{{Group: "example.com", Version: "v1", Kind: "Unknown"}}}
+		Expect(unknown.Check(scheme.Scheme, []byte(`{"apiVersion": "example.com/v1", "kind": "Unknown", "unknownField": true}`), nil)).To(Succeed())
+	})
+
+	It("should make a REST client fail the requests returning objects with unknown fields", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if req.URL.Query().Get("cluster") == prod.String() {
+				_, _ = w.Write(podList(prod))
+				return
+			}
+			_, _ = w.Write(podList(dev))
+		}))
+		defer server.Close()
+
+		client, err := apiutil.StrictRESTClientForGVK(podGVK, false, &rest.Config{Host: server.URL}, serializer.NewCodecFactory(scheme.Scheme), scheme.Scheme, strict)
+		Expect(err).NotTo(HaveOccurred())
+
+		list := &corev1.PodList{}
+		err = client.Get().Resource("pods").Do(context.Background()).Into(list)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("unknownField"))
+
+		Expect(client.Get().Resource("pods").Param("cluster", prod.String()).Do(context.Background()).Into(list)).To(Succeed())
+		Expect(list.Items).To(HaveLen(2))
+	})
+})
@@ -18,10 +18,13 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -63,6 +66,13 @@ type Options struct {
 	// Opts is used to configure the warning handler responsible for
 	// surfacing and handling warnings messages sent by the API server.
 	Opts WarningHandlerOptions
+
+	// ImpersonateByCluster, if set, makes the requests to each logical cluster impersonate the
+	// user it returns for the cluster, so that a multi-tenant controller acts with the RBAC of
+	// the tenant of each workspace rather than with its own credentials, which must be allowed
+	// to impersonate the users.  The cluster of a request is the one of its context, or else of
+	// the /clusters/<name> path of the config.
+	ImpersonateByCluster ClusterImpersonation
 }
 
 // New returns a new Client using the provided config and Options.
@@ -106,6 +116,18 @@ func newClient(config *rest.Config, options Options) (*client, error) {
 		options.Scheme = scheme.Scheme
 	}
 
+	// Init a HTTPClient if none provided, it is shared by the clients of all the types
+	if options.HTTPClient == nil {
+		var err error
+		options.HTTPClient, err = rest.HTTPClientFor(config)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if options.ImpersonateByCluster != nil {
+		options.HTTPClient = impersonatingHTTPClient(options.HTTPClient, options.ImpersonateByCluster)
+	}
+
 	// Init a Mapper if none provided
 	if options.Mapper == nil {
 		var err error
@@ -116,10 +138,11 @@ func newClient(config *rest.Config, options Options) (*client, error) {
 	}
 
 	clientcache := &clientCache{
-		config: config,
-		scheme: options.Scheme,
-		mapper: options.Mapper,
-		codecs: serializer.NewCodecFactory(options.Scheme),
+		config:     config,
+		httpclient: options.HTTPClient,
+		scheme:     options.Scheme,
+		mapper:     options.Mapper,
+		codecs:     serializer.NewCodecFactory(options.Scheme),
 
 		structuredResourceByType:   make(map[schema.GroupVersionKind]*resourceMeta),
 		unstructuredResourceByType: make(map[schema.GroupVersionKind]*resourceMeta),
@@ -151,6 +174,7 @@ func newClient(config *rest.Config, options Options) (*client, error) {
 }
 
 var _ Client = &client{}
+var _ SubResourceCreator = &client{}
 
 // client is a client.Client that reads and writes directly from/to an API server.  It lazily initializes
 // new clients at the time they are used, and caches the client.
@@ -183,6 +207,7 @@ func (c *client) RESTMapper() meta.RESTMapper {
 
 // Create implements client.Client.
 func (c *client) Create(ctx context.Context, obj Object, opts ...CreateOption) error {
+	ctx = withCluster(ctx, logicalcluster.From(obj))
 	switch obj.(type) {
 	case *unstructured.Unstructured:
 		return c.unstructuredClient.Create(ctx, obj, opts...)
@@ -195,6 +220,7 @@ func (c *client) Create(ctx context.Context, obj Object, opts ...CreateOption) e
 
 // Update implements client.Client.
 func (c *client) Update(ctx context.Context, obj Object, opts ...UpdateOption) error {
+	ctx = withCluster(ctx, logicalcluster.From(obj))
 	defer c.resetGroupVersionKind(obj, obj.GetObjectKind().GroupVersionKind())
 	switch obj.(type) {
 	case *unstructured.Unstructured:
@@ -208,6 +234,7 @@ func (c *client) Update(ctx context.Context, obj Object, opts ...UpdateOption) e
 
 // Delete implements client.Client.
 func (c *client) Delete(ctx context.Context, obj Object, opts ...DeleteOption) error {
+	ctx = withCluster(ctx, logicalcluster.From(obj))
 	switch obj.(type) {
 	case *unstructured.Unstructured:
 		return c.unstructuredClient.Delete(ctx, obj, opts...)
@@ -218,8 +245,18 @@ func (c *client) Delete(ctx context.Context, obj Object, opts ...DeleteOption) e
 	}
 }
 
-// DeleteAllOf implements client.Client.
+// DeleteAllOf implements client.Client.  The objects are deleted in the logical
+// cluster of obj, defaulting to the cluster of the context.
 func (c *client) DeleteAllOf(ctx context.Context, obj Object, opts ...DeleteAllOfOption) error {
+	deleteAllOfOpts := (&DeleteAllOfOptions{}).ApplyOptions(opts)
+	if deleteAllOfOpts.AllClusters {
+		return ErrAllClustersUnsupported
+	}
+	cluster, err := deleteAllOfCluster(obj, deleteAllOfOpts)
+	if err != nil {
+		return err
+	}
+	ctx = withCluster(ctx, cluster)
 	switch obj.(type) {
 	case *unstructured.Unstructured:
 		return c.unstructuredClient.DeleteAllOf(ctx, obj, opts...)
@@ -232,6 +269,7 @@ func (c *client) DeleteAllOf(ctx context.Context, obj Object, opts ...DeleteAllO
 
 // Patch implements client.Client.
 func (c *client) Patch(ctx context.Context, obj Object, patch Patch, opts ...PatchOption) error {
+	ctx = withCluster(ctx, logicalcluster.From(obj))
 	defer c.resetGroupVersionKind(obj, obj.GetObjectKind().GroupVersionKind())
 	switch obj.(type) {
 	case *unstructured.Unstructured:
@@ -243,8 +281,23 @@ func (c *client) Patch(ctx context.Context, obj Object, patch Patch, opts ...Pat
 	}
 }
 
+// CreateSubResource implements client.SubResourceCreator.
+func (c *client) CreateSubResource(ctx context.Context, obj Object, subResource string, subResourceObj Object, opts ...CreateOption) error {
+	ctx = withCluster(ctx, logicalcluster.From(obj))
+	defer c.resetGroupVersionKind(subResourceObj, subResourceObj.GetObjectKind().GroupVersionKind())
+	switch obj.(type) {
+	case *unstructured.Unstructured:
+		return c.unstructuredClient.CreateSubResource(ctx, obj, subResource, subResourceObj, opts...)
+	case *metav1.PartialObjectMetadata:
+		return fmt.Errorf("cannot create subresource %q using only metadata", subResource)
+	default:
+		return c.typedClient.CreateSubResource(ctx, obj, subResource, subResourceObj, opts...)
+	}
+}
+
 // Get implements client.Client.
 func (c *client) Get(ctx context.Context, key ObjectKey, obj Object) error {
+	ctx = withCluster(ctx, key.Cluster)
 	switch obj.(type) {
 	case *unstructured.Unstructured:
 		return c.unstructuredClient.Get(ctx, key, obj)
@@ -259,6 +312,7 @@ func (c *client) Get(ctx context.Context, key ObjectKey, obj Object) error {
 
 // List implements client.Client.
 func (c *client) List(ctx context.Context, obj ObjectList, opts ...ListOption) error {
+	ctx = ListContext(ctx, opts...)
 	switch x := obj.(type) {
 	case *unstructured.UnstructuredList:
 		return c.unstructuredClient.List(ctx, obj, opts...)
@@ -291,6 +345,106 @@ func (c *client) List(ctx context.Context, obj ObjectList, opts ...ListOption) e
 	}
 }
 
+// ErrAllClustersUnsupported is returned by the clients which can't fan a
+// DeleteAllOf out across every logical cluster, see AllClusters.
+var ErrAllClustersUnsupported = errors.New("client cannot delete across all logical clusters, use the client of a set of clusters")
+
+// withCluster returns a context targeting the given logical cluster, or the
+// given context if the cluster is empty.  The cluster of an object or of an
+// ObjectKey takes precedence over the cluster of the context.
+func withCluster(ctx context.Context, cluster logicalcluster.Name) context.Context {
+	if cluster.Empty() {
+		return ctx
+	}
+	return kcpclient.WithCluster(ctx, cluster)
+}
+
+// deleteAllOfCluster returns the logical cluster of a DeleteAllOf: the one of obj, or else the
+// one of the InCluster option.  It fails if they differ.
+func deleteAllOfCluster(obj Object, opts *DeleteAllOfOptions) (logicalcluster.Name, error) {
+	cluster := logicalcluster.From(obj)
+	switch {
+	case opts.Cluster.Empty():
+		return cluster, nil
+	case cluster.Empty():
+		return opts.Cluster, nil
+	case cluster != opts.Cluster:
+		return cluster, fmt.Errorf("logical cluster %s of the object does not match the logical cluster %s of the options", cluster, opts.Cluster)
+	}
+	return cluster, nil
+}
+
+// ListContext returns the context of a List with the given options: it targets the logical
+// cluster of their InCluster or AllClusters option, if any, rather than the one of ctx.
+func ListContext(ctx context.Context, opts ...ListOption) context.Context {
+	return withCluster(ctx, (&ListOptions{}).ApplyOptions(opts).Cluster)
+}
+
+// SubResource implements client.SubResourceClientProvider.
+func (c *client) SubResource(subResource string) SubResourceClient {
+	return &subResourceClient{client: c, subResource: subResource}
+}
+
+// subResourceClient is the client.SubResourceClient of a subresource of a client.
+type subResourceClient struct {
+	client      *client
+	subResource string
+}
+
+// ensure subResourceClient implements client.SubResourceClient.
+var _ SubResourceClient = &subResourceClient{}
+
+// Get implements client.SubResourceClient.
+func (sc *subResourceClient) Get(ctx context.Context, obj Object, subResourceObj Object) error {
+	ctx = withCluster(ctx, logicalcluster.From(obj))
+	defer sc.client.resetGroupVersionKind(subResourceObj, subResourceObj.GetObjectKind().GroupVersionKind())
+	switch obj.(type) {
+	case *unstructured.Unstructured:
+		return sc.client.unstructuredClient.GetSubResource(ctx, obj, sc.subResource, subResourceObj)
+	case *metav1.PartialObjectMetadata:
+		return fmt.Errorf("cannot get subresource %q using only metadata", sc.subResource)
+	default:
+		return sc.client.typedClient.GetSubResource(ctx, obj, sc.subResource, subResourceObj)
+	}
+}
+
+// Update implements client.SubResourceClient.
+func (sc *subResourceClient) Update(ctx context.Context, obj Object, subResourceObj Object, opts ...UpdateOption) error {
+	if subResourceObj == nil {
+		subResourceObj = obj
+	}
+	ctx = withCluster(ctx, logicalcluster.From(obj))
+	defer sc.client.resetGroupVersionKind(subResourceObj, subResourceObj.GetObjectKind().GroupVersionKind())
+	switch obj.(type) {
+	case *unstructured.Unstructured:
+		return sc.client.unstructuredClient.UpdateSubResource(ctx, obj, sc.subResource, subResourceObj, opts...)
+	case *metav1.PartialObjectMetadata:
+		return fmt.Errorf("cannot update subresource %q using only metadata -- did you mean to patch?", sc.subResource)
+	default:
+		return sc.client.typedClient.UpdateSubResource(ctx, obj, sc.subResource, subResourceObj, opts...)
+	}
+}
+
+// Patch implements client.SubResourceClient.
+func (sc *subResourceClient) Patch(ctx context.Context, obj Object, subResourceObj Object, patch Patch, opts ...PatchOption) error {
+	if subResourceObj == nil {
+		subResourceObj = obj
+	}
+	ctx = withCluster(ctx, logicalcluster.From(obj))
+	defer sc.client.resetGroupVersionKind(subResourceObj, subResourceObj.GetObjectKind().GroupVersionKind())
+	switch obj.(type) {
+	case *unstructured.Unstructured:
+		return sc.client.unstructuredClient.PatchSubResource(ctx, obj, sc.subResource, subResourceObj, patch, opts...)
+	case *metav1.PartialObjectMetadata:
+		if sc.subResource == "status" {
+			return sc.client.metadataClient.PatchStatus(ctx, obj, patch, opts...)
+		}
+		return fmt.Errorf("cannot patch subresource %q using only metadata", sc.subResource)
+	default:
+		return sc.client.typedClient.PatchSubResource(ctx, obj, sc.subResource, subResourceObj, patch, opts...)
+	}
+}
+
 // Status implements client.StatusClient.
 func (c *client) Status() StatusWriter {
 	return &statusWriter{client: c}
@@ -306,6 +460,7 @@ var _ StatusWriter = &statusWriter{}
 
 // Update implements client.StatusWriter.
 func (sw *statusWriter) Update(ctx context.Context, obj Object, opts ...UpdateOption) error {
+	ctx = withCluster(ctx, logicalcluster.From(obj))
 	defer sw.client.resetGroupVersionKind(obj, obj.GetObjectKind().GroupVersionKind())
 	switch obj.(type) {
 	case *unstructured.Unstructured:
@@ -319,6 +474,7 @@ func (sw *statusWriter) Update(ctx context.Context, obj Object, opts ...UpdateOp
 
 // Patch implements client.Client.
 func (sw *statusWriter) Patch(ctx context.Context, obj Object, patch Patch, opts ...PatchOption) error {
+	ctx = withCluster(ctx, logicalcluster.From(obj))
 	defer sw.client.resetGroupVersionKind(obj, obj.GetObjectKind().GroupVersionKind())
 	switch obj.(type) {
 	case *unstructured.Unstructured:
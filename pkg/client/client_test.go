@@ -22,6 +22,8 @@ import (
 	"sync/atomic"
 	"time"
 
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
@@ -1332,7 +1334,7 @@ var _ = Describe("Client", func() {
 
 				By("fetching the created Deployment")
 				var actual appsv1.Deployment
-				key := client.ObjectKey{Namespace: ns, Name: dep.Name}
+				key := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: ns, Name: dep.Name}}
 				err = cl.Get(context.TODO(), key, &actual)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(actual).NotTo(BeNil())
@@ -1352,7 +1354,7 @@ var _ = Describe("Client", func() {
 
 				By("retrieving node through client")
 				var actual corev1.Node
-				key := client.ObjectKey{Namespace: ns, Name: node.Name}
+				key := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: ns, Name: node.Name}}
 				err = cl.Get(context.TODO(), key, &actual)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(actual).NotTo(BeNil())
@@ -1366,7 +1368,7 @@ var _ = Describe("Client", func() {
 				Expect(cl).NotTo(BeNil())
 
 				By("fetching object that has not been created yet")
-				key := client.ObjectKey{Namespace: ns, Name: dep.Name}
+				key := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: ns, Name: dep.Name}}
 				var actual appsv1.Deployment
 				err = cl.Get(context.TODO(), key, &actual)
 				Expect(err).To(HaveOccurred())
@@ -1389,7 +1391,7 @@ var _ = Describe("Client", func() {
 
 				By("fetching the created Deployment fails")
 				var actual appsv1.Deployment
-				key := client.ObjectKey{Namespace: ns, Name: dep.Name}
+				key := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: ns, Name: dep.Name}}
 				err = cl.Get(context.TODO(), key, &actual)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("no kind is registered for the type"))
@@ -1448,7 +1450,7 @@ var _ = Describe("Client", func() {
 					Kind:    "Deployment",
 					Version: "v1",
 				})
-				key := client.ObjectKey{Namespace: ns, Name: dep.Name}
+				key := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: ns, Name: dep.Name}}
 				err = cl.Get(context.TODO(), key, &actual)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(actual).NotTo(BeNil())
@@ -1477,7 +1479,7 @@ var _ = Describe("Client", func() {
 					Kind:    "Node",
 					Version: "v1",
 				})
-				key := client.ObjectKey{Namespace: ns, Name: node.Name}
+				key := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: ns, Name: node.Name}}
 				err = cl.Get(context.TODO(), key, &actual)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(actual).NotTo(BeNil())
@@ -1492,7 +1494,7 @@ var _ = Describe("Client", func() {
 				Expect(cl).NotTo(BeNil())
 
 				By("fetching object that has not been created yet")
-				key := client.ObjectKey{Namespace: ns, Name: dep.Name}
+				key := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: ns, Name: dep.Name}}
 				u := &unstructured.Unstructured{}
 				err = cl.Get(context.TODO(), key, u)
 				Expect(err).To(HaveOccurred())
@@ -1540,7 +1542,7 @@ var _ = Describe("Client", func() {
 					Kind:    "Deployment",
 				}
 				actual.SetGroupVersionKind(gvk)
-				key := client.ObjectKey{Namespace: ns, Name: dep.Name}
+				key := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: ns, Name: dep.Name}}
 				err = cl.Get(context.TODO(), key, &actual)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(actual).NotTo(BeNil())
@@ -1567,7 +1569,7 @@ var _ = Describe("Client", func() {
 					Version: "v1",
 					Kind:    "Node",
 				})
-				key := client.ObjectKey{Namespace: ns, Name: node.Name}
+				key := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: ns, Name: node.Name}}
 				err = cl.Get(context.TODO(), key, &actual)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(actual).NotTo(BeNil())
@@ -1581,7 +1583,7 @@ var _ = Describe("Client", func() {
 				Expect(cl).NotTo(BeNil())
 
 				By("fetching object that has not been created yet")
-				key := client.ObjectKey{Namespace: ns, Name: dep.Name}
+				key := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: ns, Name: dep.Name}}
 				var actual metav1.PartialObjectMetadata
 				actual.SetGroupVersionKind(schema.GroupVersionKind{
 					Group:   "apps",
@@ -3076,7 +3078,7 @@ var _ = Describe("DelegatingClient", func() {
 			})
 			Expect(err).NotTo(HaveOccurred())
 			var actual appsv1.Deployment
-			key := client.ObjectKey{Namespace: "ns", Name: "name"}
+			key := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "name"}}
 			Expect(dReader.Get(context.TODO(), key, &actual)).To(Succeed())
 			Expect(1).To(Equal(cachedReader.Called))
 		})
@@ -3128,7 +3130,7 @@ var _ = Describe("DelegatingClient", func() {
 					Version: "v1",
 				})
 				actual.SetName(dep.Name)
-				key := client.ObjectKey{Namespace: dep.Namespace, Name: dep.Name}
+				key := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: dep.Namespace, Name: dep.Name}}
 				Expect(dReader.Get(context.TODO(), key, actual)).To(Succeed())
 				Expect(0).To(Equal(cachedReader.Called))
 			})
@@ -3150,7 +3152,7 @@ var _ = Describe("DelegatingClient", func() {
 					Version: "v1",
 				})
 				actual.SetName(dep.Name)
-				key := client.ObjectKey{Namespace: dep.Namespace, Name: dep.Name}
+				key := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: dep.Namespace, Name: dep.Name}}
 				Expect(dReader.Get(context.TODO(), key, actual)).To(Succeed())
 				Expect(1).To(Equal(cachedReader.Called))
 			})
@@ -3213,6 +3215,44 @@ var _ = Describe("DelegatingClient", func() {
 			})
 		})
 	})
+	Describe("UncachedByCluster", func() {
+		It("should read the uncached objects of the logical clusters with the live reader", func() {
+			a, b, c := logicalcluster.New("root:a"), logicalcluster.New("root:b"), logicalcluster.New("root:c")
+			cachedReader, liveReader := &fakeReader{}, &fakeReader{}
+			cl, err := client.New(cfg, client.Options{})
+			Expect(err).NotTo(HaveOccurred())
+			dClient, err := client.NewDelegatingClient(client.NewDelegatingClientInput{
+				CacheReader: cachedReader,
+				Client:      cl,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			dClient, err = client.WithUncachedByCluster(dClient, liveReader, client.UncachedByCluster{
+				logicalcluster.Wildcard: {&corev1.ConfigMap{}},
+				a:                       {&corev1.Secret{}},
+				c:                       nil,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			key := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "name"}}
+			By("reading the kinds of a cluster live in that cluster only")
+			Expect(dClient.Get(context.Background(), client.ObjectKey{NamespacedName: key.NamespacedName, Cluster: a}, &corev1.Secret{})).To(Succeed())
+			Expect(liveReader.Called).To(Equal(1))
+			Expect(dClient.List(kcpclient.WithCluster(context.Background(), a), &corev1.SecretList{})).To(Succeed())
+			Expect(liveReader.Called).To(Equal(2))
+			Expect(dClient.Get(kcpclient.WithCluster(context.Background(), b), key, &corev1.Secret{})).To(Succeed())
+			Expect(cachedReader.Called).To(Equal(1))
+
+			By("reading the kinds of the wildcard cluster live in all the clusters")
+			Expect(dClient.List(kcpclient.WithCluster(context.Background(), b), &corev1.ConfigMapList{})).To(Succeed())
+			Expect(liveReader.Called).To(Equal(3))
+
+			By("reading all the kinds of a cluster without kinds live")
+			Expect(dClient.Get(context.Background(), client.ObjectKey{NamespacedName: key.NamespacedName, Cluster: c}, &appsv1.Deployment{})).To(Succeed())
+			Expect(liveReader.Called).To(Equal(4))
+			Expect(dClient.Get(context.Background(), client.ObjectKey{NamespacedName: key.NamespacedName, Cluster: b}, &appsv1.Deployment{})).To(Succeed())
+			Expect(cachedReader.Called).To(Equal(2))
+		})
+	})
 })
 
 var _ = Describe("Patch", func() {
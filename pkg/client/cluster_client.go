@@ -0,0 +1,219 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// WithCluster wraps an existing client pinning all its calls to the given logical cluster:
+// the objects and keys without a logical cluster are read and written in the cluster, and
+// the lists list the objects of the cluster, without setting their ClusterName or the cluster
+// of the context.  Objects and keys of another logical cluster are refused.
+func WithCluster(c Client, cluster logicalcluster.Name) Client {
+	return &clusterClient{
+		client:  c,
+		cluster: cluster,
+	}
+}
+
+var _ Client = &clusterClient{}
+
+// clusterClient is a Client that wraps another Client in order to pin the calls to a logical cluster.
+type clusterClient struct {
+	client  Client
+	cluster logicalcluster.Name
+}
+
+// context returns the context of a call about an object or key of the given logical cluster.
+func (c *clusterClient) context(ctx context.Context, cluster logicalcluster.Name, what string) (context.Context, error) {
+	if !cluster.Empty() && cluster != c.cluster {
+		return nil, fmt.Errorf("logical cluster %s of %s does not match the logical cluster %s of the client", cluster, what, c.cluster)
+	}
+	return kcpclient.WithCluster(ctx, c.cluster), nil
+}
+
+// objectContext returns the context of a call about the object.
+func (c *clusterClient) objectContext(ctx context.Context, obj Object) (context.Context, error) {
+	return c.context(ctx, logicalcluster.From(obj), fmt.Sprintf("the object %s", obj.GetName()))
+}
+
+// Scheme returns the scheme this client is using.
+func (c *clusterClient) Scheme() *runtime.Scheme {
+	return c.client.Scheme()
+}
+
+// RESTMapper returns the rest mapper this client is using.
+func (c *clusterClient) RESTMapper() meta.RESTMapper {
+	return c.client.RESTMapper()
+}
+
+// Create implements client.Client.
+func (c *clusterClient) Create(ctx context.Context, obj Object, opts ...CreateOption) error {
+	ctx, err := c.objectContext(ctx, obj)
+	if err != nil {
+		return err
+	}
+	return c.client.Create(ctx, obj, opts...)
+}
+
+// CreateSubResource implements client.SubResourceCreator.
+func (c *clusterClient) CreateSubResource(ctx context.Context, obj Object, subResource string, subResourceObj Object, opts ...CreateOption) error {
+	ctx, err := c.objectContext(ctx, obj)
+	if err != nil {
+		return err
+	}
+	return CreateSubResource(ctx, c.client, obj, subResource, subResourceObj, opts...)
+}
+
+// Update implements client.Client.
+func (c *clusterClient) Update(ctx context.Context, obj Object, opts ...UpdateOption) error {
+	ctx, err := c.objectContext(ctx, obj)
+	if err != nil {
+		return err
+	}
+	return c.client.Update(ctx, obj, opts...)
+}
+
+// Delete implements client.Client.
+func (c *clusterClient) Delete(ctx context.Context, obj Object, opts ...DeleteOption) error {
+	ctx, err := c.objectContext(ctx, obj)
+	if err != nil {
+		return err
+	}
+	return c.client.Delete(ctx, obj, opts...)
+}
+
+// DeleteAllOf implements client.Client.
+func (c *clusterClient) DeleteAllOf(ctx context.Context, obj Object, opts ...DeleteAllOfOption) error {
+	deleteAllOfOpts := (&DeleteAllOfOptions{}).ApplyOptions(opts)
+	if deleteAllOfOpts.AllClusters {
+		return ErrAllClustersUnsupported
+	}
+	if _, err := c.context(ctx, deleteAllOfOpts.Cluster, "the options"); err != nil {
+		return err
+	}
+	ctx, err := c.objectContext(ctx, obj)
+	if err != nil {
+		return err
+	}
+	return c.client.DeleteAllOf(ctx, obj, opts...)
+}
+
+// Patch implements client.Client.
+func (c *clusterClient) Patch(ctx context.Context, obj Object, patch Patch, opts ...PatchOption) error {
+	ctx, err := c.objectContext(ctx, obj)
+	if err != nil {
+		return err
+	}
+	return c.client.Patch(ctx, obj, patch, opts...)
+}
+
+// Get implements client.Client.
+func (c *clusterClient) Get(ctx context.Context, key ObjectKey, obj Object) error {
+	ctx, err := c.context(ctx, key.Cluster, fmt.Sprintf("the key %s", key.NamespacedName))
+	if err != nil {
+		return err
+	}
+	return c.client.Get(ctx, key, obj)
+}
+
+// List implements client.Client.
+func (c *clusterClient) List(ctx context.Context, obj ObjectList, opts ...ListOption) error {
+	ctx, err := c.context(ctx, (&ListOptions{}).ApplyOptions(opts).Cluster, "the options")
+	if err != nil {
+		return err
+	}
+	return c.client.List(ctx, obj, opts...)
+}
+
+// Status implements client.StatusClient.
+func (c *clusterClient) Status() StatusWriter {
+	return &clusterStatusWriter{client: c.client.Status(), clusterClient: c}
+}
+
+// ensure clusterStatusWriter implements client.StatusWriter.
+var _ StatusWriter = &clusterStatusWriter{}
+
+type clusterStatusWriter struct {
+	client        StatusWriter
+	clusterClient *clusterClient
+}
+
+// Update implements client.StatusWriter.
+func (sw *clusterStatusWriter) Update(ctx context.Context, obj Object, opts ...UpdateOption) error {
+	ctx, err := sw.clusterClient.objectContext(ctx, obj)
+	if err != nil {
+		return err
+	}
+	return sw.client.Update(ctx, obj, opts...)
+}
+
+// Patch implements client.StatusWriter.
+func (sw *clusterStatusWriter) Patch(ctx context.Context, obj Object, patch Patch, opts ...PatchOption) error {
+	ctx, err := sw.clusterClient.objectContext(ctx, obj)
+	if err != nil {
+		return err
+	}
+	return sw.client.Patch(ctx, obj, patch, opts...)
+}
+
+// SubResource implements client.SubResourceClientProvider.
+func (c *clusterClient) SubResource(subResource string) SubResourceClient {
+	return &clusterSubResourceClient{client: SubResource(c.client, subResource), clusterClient: c}
+}
+
+// ensure clusterSubResourceClient implements client.SubResourceClient.
+var _ SubResourceClient = &clusterSubResourceClient{}
+
+type clusterSubResourceClient struct {
+	client        SubResourceClient
+	clusterClient *clusterClient
+}
+
+// Get implements client.SubResourceClient.
+func (sc *clusterSubResourceClient) Get(ctx context.Context, obj Object, subResourceObj Object) error {
+	ctx, err := sc.clusterClient.objectContext(ctx, obj)
+	if err != nil {
+		return err
+	}
+	return sc.client.Get(ctx, obj, subResourceObj)
+}
+
+// Update implements client.SubResourceClient.
+func (sc *clusterSubResourceClient) Update(ctx context.Context, obj Object, subResourceObj Object, opts ...UpdateOption) error {
+	ctx, err := sc.clusterClient.objectContext(ctx, obj)
+	if err != nil {
+		return err
+	}
+	return sc.client.Update(ctx, obj, subResourceObj, opts...)
+}
+
+// Patch implements client.SubResourceClient.
+func (sc *clusterSubResourceClient) Patch(ctx context.Context, obj Object, subResourceObj Object, patch Patch, opts ...PatchOption) error {
+	ctx, err := sc.clusterClient.objectContext(ctx, obj)
+	if err != nil {
+		return err
+	}
+	return sc.client.Patch(ctx, obj, subResourceObj, patch, opts...)
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client_test
+
+import (
+	"context"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("WithCluster", func() {
+	clusterA := logicalcluster.New("root:org:a")
+	clusterB := logicalcluster.New("root:org:b")
+	ctx := context.Background()
+	var base, c client.Client
+
+	newConfigMap := func(name, data string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Data:       map[string]string{"key": data},
+		}
+	}
+
+	BeforeEach(func() {
+		mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{corev1.SchemeGroupVersion})
+		mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+		base = fake.NewClusterBuilder().
+			WithRESTMapper(mapper).
+			WithObjects(clusterA, newConfigMap("cm", "a")).
+			WithObjects(clusterB, newConfigMap("cm", "b")).
+			Build()
+		c = client.WithCluster(base, clusterA)
+	})
+
+	It("should read and list the objects of its logical cluster", func() {
+		cm := &corev1.ConfigMap{}
+		Expect(c.Get(ctx, client.ObjectKey{NamespacedName: types.NamespacedName{Name: "cm", Namespace: "default"}}, cm)).To(Succeed())
+		Expect(cm.Data).To(HaveKeyWithValue("key", "a"))
+
+		list := &corev1.ConfigMapList{}
+		Expect(c.List(ctx, list)).To(Succeed())
+		Expect(list.Items).To(HaveLen(1))
+		Expect(list.Items[0].Data).To(HaveKeyWithValue("key", "a"))
+	})
+
+	It("should write the objects without a logical cluster in its logical cluster", func() {
+		Expect(c.Create(ctx, newConfigMap("new", "created"))).To(Succeed())
+
+		cm := &corev1.ConfigMap{}
+		Expect(base.Get(ctx, client.ObjectKey{NamespacedName: types.NamespacedName{Name: "new", Namespace: "default"}, Cluster: clusterA}, cm)).To(Succeed())
+		Expect(cm.Data).To(HaveKeyWithValue("key", "created"))
+	})
+
+	It("should refuse the objects and keys of other logical clusters", func() {
+		cm := newConfigMap("cm", "b")
+		cm.ClusterName = clusterB.String()
+		Expect(c.Update(ctx, cm)).To(MatchError(ContainSubstring("does not match the logical cluster root:org:a of the client")))
+		Expect(c.Status().Update(ctx, cm)).To(MatchError(ContainSubstring("does not match")))
+		Expect(c.Get(ctx, client.ObjectKey{NamespacedName: types.NamespacedName{Name: "cm", Namespace: "default"}, Cluster: clusterB}, cm)).To(MatchError(ContainSubstring("does not match")))
+		Expect(c.List(ctx, &corev1.ConfigMapList{}, client.InCluster(clusterB))).To(MatchError(ContainSubstring("does not match")))
+		Expect(c.List(ctx, &corev1.ConfigMapList{}, client.AllClusters)).To(MatchError(ContainSubstring("does not match")))
+		Expect(c.DeleteAllOf(ctx, &corev1.ConfigMap{}, client.InNamespace("default"), client.InCluster(clusterB))).To(MatchError(ContainSubstring("does not match")))
+
+		list := &corev1.ConfigMapList{}
+		Expect(c.List(ctx, list, client.InCluster(clusterA))).To(Succeed())
+		Expect(list.Items).To(HaveLen(1))
+	})
+
+	It("should keep its logical cluster through the other wrappers", func() {
+		wrapped := client.WithFieldOwner(client.NewDryRunClient(client.NewNamespacedClient(c, "default")), "owner")
+		cm := &corev1.ConfigMap{}
+		Expect(wrapped.Get(ctx, client.ObjectKey{NamespacedName: types.NamespacedName{Name: "cm"}}, cm)).To(Succeed())
+		Expect(cm.Data).To(HaveKeyWithValue("key", "a"))
+
+		cm.Data["key"] = "changed"
+		Expect(wrapped.Update(ctx, cm)).To(Succeed())
+		Expect(base.Get(ctx, client.ObjectKey{NamespacedName: types.NamespacedName{Name: "cm", Namespace: "default"}, Cluster: clusterA}, cm)).To(Succeed())
+		Expect(cm.Data).To(HaveKeyWithValue("key", "a"))
+	})
+})
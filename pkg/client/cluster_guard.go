@@ -0,0 +1,204 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// WithClusterGuard wraps an existing client rejecting the writes, including the ones of
+// its status and subresources, whose logical cluster differs from the one of their
+// context, e.g. the cluster of the request being reconciled, or which target no logical
+// cluster at all.  Writes of a context returned by AllowCrossClusterWrites are never
+// rejected; reads are left alone.
+func WithClusterGuard(c Client) Client {
+	return &clusterGuardClient{client: c}
+}
+
+type allowCrossClusterWritesKey struct{}
+
+// AllowCrossClusterWrites returns a context whose writes are allowed by the clients
+// returned by WithClusterGuard to target any logical cluster.
+func AllowCrossClusterWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allowCrossClusterWritesKey{}, true)
+}
+
+// guardCluster checks that a write of ctx to the given logical cluster is allowed.
+func guardCluster(ctx context.Context, cluster logicalcluster.Name, what string) error {
+	if allowed, _ := ctx.Value(allowCrossClusterWritesKey{}).(bool); allowed {
+		return nil
+	}
+	ctxCluster, _ := kcpclient.ClusterFromContext(ctx)
+	switch {
+	case cluster.Empty() && ctxCluster.Empty():
+		return fmt.Errorf("write of %s does not target any logical cluster", what)
+	case !cluster.Empty() && !ctxCluster.Empty() && cluster != ctxCluster:
+		return fmt.Errorf("logical cluster %s of %s does not match the logical cluster %s of the context", cluster, what, ctxCluster)
+	}
+	return nil
+}
+
+// guardObject checks that a write of ctx to the given object is allowed.
+func guardObject(ctx context.Context, obj Object) error {
+	return guardCluster(ctx, logicalcluster.From(obj), fmt.Sprintf("the object %s", obj.GetName()))
+}
+
+var _ Client = &clusterGuardClient{}
+
+// clusterGuardClient is a Client that wraps another Client in order to reject the cross-cluster writes.
+type clusterGuardClient struct {
+	client Client
+}
+
+// Scheme returns the scheme this client is using.
+func (c *clusterGuardClient) Scheme() *runtime.Scheme {
+	return c.client.Scheme()
+}
+
+// RESTMapper returns the rest mapper this client is using.
+func (c *clusterGuardClient) RESTMapper() meta.RESTMapper {
+	return c.client.RESTMapper()
+}
+
+// Create implements client.Client.
+func (c *clusterGuardClient) Create(ctx context.Context, obj Object, opts ...CreateOption) error {
+	if err := guardObject(ctx, obj); err != nil {
+		return err
+	}
+	return c.client.Create(ctx, obj, opts...)
+}
+
+// CreateSubResource implements client.SubResourceCreator.
+func (c *clusterGuardClient) CreateSubResource(ctx context.Context, obj Object, subResource string, subResourceObj Object, opts ...CreateOption) error {
+	if err := guardObject(ctx, obj); err != nil {
+		return err
+	}
+	return CreateSubResource(ctx, c.client, obj, subResource, subResourceObj, opts...)
+}
+
+// Update implements client.Client.
+func (c *clusterGuardClient) Update(ctx context.Context, obj Object, opts ...UpdateOption) error {
+	if err := guardObject(ctx, obj); err != nil {
+		return err
+	}
+	return c.client.Update(ctx, obj, opts...)
+}
+
+// Delete implements client.Client.
+func (c *clusterGuardClient) Delete(ctx context.Context, obj Object, opts ...DeleteOption) error {
+	if err := guardObject(ctx, obj); err != nil {
+		return err
+	}
+	return c.client.Delete(ctx, obj, opts...)
+}
+
+// DeleteAllOf implements client.Client.
+func (c *clusterGuardClient) DeleteAllOf(ctx context.Context, obj Object, opts ...DeleteAllOfOption) error {
+	cluster, err := deleteAllOfCluster(obj, (&DeleteAllOfOptions{}).ApplyOptions(opts))
+	if err != nil {
+		return err
+	}
+	if err := guardCluster(ctx, cluster, "the collection"); err != nil {
+		return err
+	}
+	return c.client.DeleteAllOf(ctx, obj, opts...)
+}
+
+// Patch implements client.Client.
+func (c *clusterGuardClient) Patch(ctx context.Context, obj Object, patch Patch, opts ...PatchOption) error {
+	if err := guardObject(ctx, obj); err != nil {
+		return err
+	}
+	return c.client.Patch(ctx, obj, patch, opts...)
+}
+
+// Get implements client.Client.
+func (c *clusterGuardClient) Get(ctx context.Context, key ObjectKey, obj Object) error {
+	return c.client.Get(ctx, key, obj)
+}
+
+// List implements client.Client.
+func (c *clusterGuardClient) List(ctx context.Context, obj ObjectList, opts ...ListOption) error {
+	return c.client.List(ctx, obj, opts...)
+}
+
+// Status implements client.StatusClient.
+func (c *clusterGuardClient) Status() StatusWriter {
+	return &clusterGuardStatusWriter{client: c.client.Status()}
+}
+
+// ensure clusterGuardStatusWriter implements client.StatusWriter.
+var _ StatusWriter = &clusterGuardStatusWriter{}
+
+type clusterGuardStatusWriter struct {
+	client StatusWriter
+}
+
+// Update implements client.StatusWriter.
+func (sw *clusterGuardStatusWriter) Update(ctx context.Context, obj Object, opts ...UpdateOption) error {
+	if err := guardObject(ctx, obj); err != nil {
+		return err
+	}
+	return sw.client.Update(ctx, obj, opts...)
+}
+
+// Patch implements client.StatusWriter.
+func (sw *clusterGuardStatusWriter) Patch(ctx context.Context, obj Object, patch Patch, opts ...PatchOption) error {
+	if err := guardObject(ctx, obj); err != nil {
+		return err
+	}
+	return sw.client.Patch(ctx, obj, patch, opts...)
+}
+
+// SubResource implements client.SubResourceClientProvider.
+func (c *clusterGuardClient) SubResource(subResource string) SubResourceClient {
+	return &clusterGuardSubResourceClient{client: SubResource(c.client, subResource)}
+}
+
+// ensure clusterGuardSubResourceClient implements client.SubResourceClient.
+var _ SubResourceClient = &clusterGuardSubResourceClient{}
+
+type clusterGuardSubResourceClient struct {
+	client SubResourceClient
+}
+
+// Get implements client.SubResourceClient.
+func (sc *clusterGuardSubResourceClient) Get(ctx context.Context, obj Object, subResourceObj Object) error {
+	return sc.client.Get(ctx, obj, subResourceObj)
+}
+
+// Update implements client.SubResourceClient.
+func (sc *clusterGuardSubResourceClient) Update(ctx context.Context, obj Object, subResourceObj Object, opts ...UpdateOption) error {
+	if err := guardObject(ctx, obj); err != nil {
+		return err
+	}
+	return sc.client.Update(ctx, obj, subResourceObj, opts...)
+}
+
+// Patch implements client.SubResourceClient.
+func (sc *clusterGuardSubResourceClient) Patch(ctx context.Context, obj Object, subResourceObj Object, patch Patch, opts ...PatchOption) error {
+	if err := guardObject(ctx, obj); err != nil {
+		return err
+	}
+	return sc.client.Patch(ctx, obj, subResourceObj, patch, opts...)
+}
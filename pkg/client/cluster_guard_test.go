@@ -0,0 +1,90 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client_test
+
+import (
+	"context"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("WithClusterGuard", func() {
+	clusterA := logicalcluster.New("root:org:a")
+	clusterB := logicalcluster.New("root:org:b")
+	ctx := kcpclient.WithCluster(context.Background(), clusterA)
+	var base, c client.Client
+
+	newConfigMap := func(name string, cluster logicalcluster.Name) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", ClusterName: cluster.String()},
+		}
+	}
+
+	BeforeEach(func() {
+		mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{corev1.SchemeGroupVersion})
+		mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+		base = fake.NewClusterBuilder().
+			WithRESTMapper(mapper).
+			WithObjects(clusterB, newConfigMap("cm", logicalcluster.Name{})).
+			Build()
+		c = client.WithClusterGuard(base)
+	})
+
+	It("should allow the writes to the logical cluster of the context", func() {
+		Expect(c.Create(ctx, newConfigMap("same", clusterA))).To(Succeed())
+		Expect(c.Create(ctx, newConfigMap("implicit", logicalcluster.Name{}))).To(Succeed())
+
+		list := &corev1.ConfigMapList{}
+		Expect(base.List(context.Background(), list, client.InCluster(clusterA))).To(Succeed())
+		Expect(list.Items).To(HaveLen(2))
+	})
+
+	It("should reject the writes to other logical clusters", func() {
+		err := c.Create(ctx, newConfigMap("other", clusterB))
+		Expect(err).To(MatchError(ContainSubstring("does not match the logical cluster root:org:a of the context")))
+
+		cm := &corev1.ConfigMap{}
+		Expect(base.Get(ctx, client.ObjectKey{NamespacedName: types.NamespacedName{Name: "cm", Namespace: "default"}, Cluster: clusterB}, cm)).To(Succeed())
+		Expect(c.Delete(ctx, cm)).NotTo(Succeed())
+		Expect(c.Status().Update(ctx, cm)).NotTo(Succeed())
+		Expect(c.DeleteAllOf(ctx, &corev1.ConfigMap{}, client.InNamespace("default"), client.InCluster(clusterB))).NotTo(Succeed())
+		Expect(base.Get(ctx, client.ObjectKeyFromObject(cm), cm)).To(Succeed())
+	})
+
+	It("should reject the writes without any logical cluster", func() {
+		err := c.Create(context.Background(), newConfigMap("nowhere", logicalcluster.Name{}))
+		Expect(err).To(MatchError(ContainSubstring("does not target any logical cluster")))
+	})
+
+	It("should allow the cross-cluster writes explicitly allowed", func() {
+		Expect(c.Create(client.AllowCrossClusterWrites(ctx), newConfigMap("other", clusterB))).To(Succeed())
+
+		cm := &corev1.ConfigMap{}
+		Expect(base.Get(ctx, client.ObjectKey{NamespacedName: types.NamespacedName{Name: "other", Namespace: "default"}, Cluster: clusterB}, cm)).To(Succeed())
+	})
+})
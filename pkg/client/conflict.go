@@ -0,0 +1,240 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ConflictCause is the likely cause of a conflict, as diagnosed by a conflict diagnosing client.
+type ConflictCause string
+
+const (
+	// ConflictStaleCache means the object written was read from a cache which had not observed
+	// the last write of the object yet.
+	ConflictStaleCache ConflictCause = "StaleCache"
+	// ConflictStaleObject means the object written was older than the one in the cache, which
+	// was up to date, e.g. because it was not read again after a previous write.
+	ConflictStaleObject ConflictCause = "StaleObject"
+	// ConflictConcurrentWrite means the object was written concurrently by another writer after
+	// the cache observed it.
+	ConflictConcurrentWrite ConflictCause = "ConcurrentWrite"
+	// ConflictUnknown means the objects could not be read to diagnose the conflict.
+	ConflictUnknown ConflictCause = "Unknown"
+)
+
+// ConflictDiagnosis compares the versions of an object whose write failed with a conflict.
+type ConflictDiagnosis struct {
+	// GroupVersionKind is the kind of the object.
+	GroupVersionKind schema.GroupVersionKind
+
+	// Key identifies the object, including its logical cluster.
+	Key ObjectKey
+
+	// WrittenResourceVersion is the resourceVersion of the object written.
+	WrittenResourceVersion string
+
+	// CachedResourceVersion is the resourceVersion of the object in the cache, if it has one.
+	CachedResourceVersion string
+
+	// CachedLastWrite is the time of the last write of the object in the cache, from its
+	// managed fields, if known.
+	CachedLastWrite time.Time
+
+	// ServerResourceVersion is the resourceVersion of the object on the API server.
+	ServerResourceVersion string
+
+	// ServerLastWrite is the time of the last write of the object on the API server, and
+	// ServerLastManager the field manager which made it, from its managed fields, if known.
+	ServerLastWrite   time.Time
+	ServerLastManager string
+
+	// Cause is the likely cause of the conflict.
+	Cause ConflictCause
+}
+
+// CacheLag returns for how long the cache had not observed the last write of the object, if
+// the conflict is caused by a stale cache.
+func (d *ConflictDiagnosis) CacheLag() time.Duration {
+	if d.Cause != ConflictStaleCache || d.ServerLastWrite.IsZero() {
+		return 0
+	}
+	return time.Since(d.ServerLastWrite)
+}
+
+// ConflictError is a conflict error, along with its diagnosis.  It wraps the conflict error
+// returned by the API server, so that apierrors.IsConflict still holds.
+type ConflictError struct {
+	Err       error
+	Diagnosis ConflictDiagnosis
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%v (likely cause: %s, written resourceVersion %q, cached %q, server %q)", e.Err,
+		e.Diagnosis.Cause, e.Diagnosis.WrittenResourceVersion, e.Diagnosis.CachedResourceVersion, e.Diagnosis.ServerResourceVersion)
+}
+
+// Unwrap returns the conflict error returned by the API server.
+func (e *ConflictError) Unwrap() error {
+	return e.Err
+}
+
+// NewConflictDiagnosingClient wraps an existing client, so that when an update or a patch fails
+// with a conflict, the object is read from the cache and from the API server to diagnose whether
+// the conflict was caused by a stale cache or by a concurrent writer.  The diagnosis is logged,
+// and returned in a ConflictError wrapping the conflict error.
+func NewConflictDiagnosingClient(c Client, cache Reader, apiReader Reader) Client {
+	return &conflictDiagnosingClient{Client: c, cache: cache, apiReader: apiReader}
+}
+
+var _ Client = &conflictDiagnosingClient{}
+
+// conflictDiagnosingClient is a Client that wraps another Client in order to diagnose conflicts.
+type conflictDiagnosingClient struct {
+	Client
+	cache     Reader
+	apiReader Reader
+}
+
+// Update implements client.Client.
+func (c *conflictDiagnosingClient) Update(ctx context.Context, obj Object, opts ...UpdateOption) error {
+	written := obj.GetResourceVersion()
+	return c.diagnose(ctx, obj, written, c.Client.Update(ctx, obj, opts...))
+}
+
+// Patch implements client.Client.
+func (c *conflictDiagnosingClient) Patch(ctx context.Context, obj Object, patch Patch, opts ...PatchOption) error {
+	written := obj.GetResourceVersion()
+	return c.diagnose(ctx, obj, written, c.Client.Patch(ctx, obj, patch, opts...))
+}
+
+// CreateSubResource implements client.SubResourceCreator.
+func (c *conflictDiagnosingClient) CreateSubResource(ctx context.Context, obj Object, subResource string, subResourceObj Object, opts ...CreateOption) error {
+	return CreateSubResource(ctx, c.Client, obj, subResource, subResourceObj, opts...)
+}
+
+// SubResource implements client.SubResourceClientProvider.
+func (c *conflictDiagnosingClient) SubResource(subResource string) SubResourceClient {
+	return SubResource(c.Client, subResource)
+}
+
+// Status implements client.StatusClient.
+func (c *conflictDiagnosingClient) Status() StatusWriter {
+	return &conflictDiagnosingStatusWriter{StatusWriter: c.Client.Status(), client: c}
+}
+
+// conflictDiagnosingStatusWriter is a StatusWriter that diagnoses the conflicts of status writes.
+type conflictDiagnosingStatusWriter struct {
+	StatusWriter
+	client *conflictDiagnosingClient
+}
+
+// Update implements client.StatusWriter.
+func (sw *conflictDiagnosingStatusWriter) Update(ctx context.Context, obj Object, opts ...UpdateOption) error {
+	written := obj.GetResourceVersion()
+	return sw.client.diagnose(ctx, obj, written, sw.StatusWriter.Update(ctx, obj, opts...))
+}
+
+// Patch implements client.StatusWriter.
+func (sw *conflictDiagnosingStatusWriter) Patch(ctx context.Context, obj Object, patch Patch, opts ...PatchOption) error {
+	written := obj.GetResourceVersion()
+	return sw.client.diagnose(ctx, obj, written, sw.StatusWriter.Patch(ctx, obj, patch, opts...))
+}
+
+// diagnose returns the given error, along with a diagnosis if it is a conflict.
+func (c *conflictDiagnosingClient) diagnose(ctx context.Context, obj Object, written string, err error) error {
+	if !apierrors.IsConflict(err) {
+		return err
+	}
+
+	key := ObjectKeyFromObject(obj)
+	if key.Cluster.Empty() {
+		key.Cluster, _ = kcpclient.ClusterFromContext(ctx)
+	}
+	d := ConflictDiagnosis{Key: key, WrittenResourceVersion: written, Cause: ConflictUnknown}
+	d.GroupVersionKind, _ = apiutil.GVKForObject(obj, c.Scheme())
+
+	cached, cacheErr := c.read(ctx, c.cache, key, obj)
+	if cacheErr == nil {
+		d.CachedResourceVersion = cached.GetResourceVersion()
+		d.CachedLastWrite, _ = lastWrite(cached)
+	}
+	server, serverErr := c.read(ctx, c.apiReader, key, obj)
+	if serverErr == nil {
+		d.ServerResourceVersion = server.GetResourceVersion()
+		d.ServerLastWrite, d.ServerLastManager = lastWrite(server)
+	}
+
+	switch {
+	case cacheErr != nil || serverErr != nil:
+	case d.CachedResourceVersion == d.ServerResourceVersion:
+		d.Cause = ConflictStaleObject
+	case d.WrittenResourceVersion == d.CachedResourceVersion:
+		d.Cause = ConflictStaleCache
+	default:
+		d.Cause = ConflictConcurrentWrite
+	}
+
+	log.FromContext(ctx).WithName("conflicts").Info("Write failed with a conflict",
+		"kind", d.GroupVersionKind, "cluster", key.Cluster, "namespace", key.Namespace, "name", key.Name,
+		"cause", d.Cause, "writtenResourceVersion", d.WrittenResourceVersion,
+		"cachedResourceVersion", d.CachedResourceVersion, "serverResourceVersion", d.ServerResourceVersion,
+		"serverLastManager", d.ServerLastManager, "cacheLag", d.CacheLag())
+	return &ConflictError{Err: err, Diagnosis: d}
+}
+
+// read reads the object with the given key and type from the given reader.
+func (c *conflictDiagnosingClient) read(ctx context.Context, reader Reader, key ObjectKey, obj Object) (Object, error) {
+	if reader == nil {
+		return nil, fmt.Errorf("no reader")
+	}
+	out, ok := obj.DeepCopyObject().(Object)
+	if !ok {
+		return nil, fmt.Errorf("%T is not an Object", obj)
+	}
+	if err := reader.Get(withCluster(ctx, key.Cluster), key, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// lastWrite returns the time and the manager of the last write of the object, from its managed fields.
+func lastWrite(obj runtime.Object) (time.Time, string) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return time.Time{}, ""
+	}
+	var last time.Time
+	var manager string
+	for _, entry := range accessor.GetManagedFields() {
+		if entry.Time != nil && !entry.Time.Time.Before(last) {
+			last, manager = entry.Time.Time, entry.Manager
+		}
+	}
+	return last, manager
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("ConflictDiagnosingClient", func() {
+	var (
+		ctx           context.Context
+		server, cache client.Client
+		c             client.Client
+		cluster       logicalcluster.Name
+	)
+
+	read := func(reader client.Reader) *corev1.ConfigMap {
+		cm := &corev1.ConfigMap{}
+		key := client.ObjectKey{Cluster: cluster}
+		key.Namespace, key.Name = "ns", "cm"
+		Expect(reader.Get(ctx, key, cm)).To(Succeed())
+		return cm
+	}
+	write := func(writer client.Client, data string) {
+		cm := read(writer)
+		cm.Data = map[string]string{"k": data}
+		cm.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "other-writer", Time: &metav1.Time{Time: time.Now().Add(-time.Minute)}}}
+		Expect(writer.Update(ctx, cm)).To(Succeed())
+	}
+	diagnosis := func(err error) client.ConflictDiagnosis {
+		Expect(apierrors.IsConflict(err)).To(BeTrue())
+		var conflict *client.ConflictError
+		Expect(errors.As(err, &conflict)).To(BeTrue())
+		return conflict.Diagnosis
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		cluster = logicalcluster.New("root:a")
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "ns"}}
+		server = fake.NewClusterBuilder().WithObjects(cluster, cm.DeepCopy()).Build()
+		cache = fake.NewClusterBuilder().WithObjects(cluster, cm.DeepCopy()).Build()
+		c = client.NewConflictDiagnosingClient(server, cache, server)
+	})
+
+	It("should diagnose a conflict caused by a stale cache", func() {
+		cached := read(cache)
+		write(server, "concurrent")
+
+		cached.Data = map[string]string{"k": "mine"}
+		d := diagnosis(c.Update(ctx, cached))
+		Expect(d.Cause).To(Equal(client.ConflictStaleCache))
+		Expect(d.Key.Cluster).To(Equal(cluster))
+		Expect(d.Key.Name).To(Equal("cm"))
+		Expect(d.GroupVersionKind.Kind).To(Equal("ConfigMap"))
+		Expect(d.WrittenResourceVersion).To(Equal(d.CachedResourceVersion))
+		Expect(d.ServerResourceVersion).NotTo(Equal(d.CachedResourceVersion))
+		Expect(d.ServerLastManager).To(Equal("other-writer"))
+		Expect(d.CacheLag()).To(BeNumerically(">=", time.Minute))
+	})
+
+	It("should diagnose a conflict caused by a stale object", func() {
+		stale := read(server)
+		write(server, "concurrent")
+		write(cache, "concurrent")
+
+		stale.Data = map[string]string{"k": "mine"}
+		d := diagnosis(c.Update(ctx, stale))
+		Expect(d.Cause).To(Equal(client.ConflictStaleObject))
+		Expect(d.CacheLag()).To(BeZero())
+	})
+
+	It("should diagnose a conflict caused by a concurrent writer", func() {
+		stale := read(server)
+		write(server, "first")
+		write(cache, "first")
+		write(server, "second")
+
+		stale.Data = map[string]string{"k": "mine"}
+		d := diagnosis(c.Status().Update(ctx, stale))
+		Expect(d.Cause).To(Equal(client.ConflictConcurrentWrite))
+	})
+
+	It("should return the other errors as is", func() {
+		err := c.Update(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "missing", Namespace: "ns", ClusterName: cluster.String()}})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		var conflict *client.ConflictError
+		Expect(errors.As(err, &conflict)).To(BeFalse())
+	})
+})
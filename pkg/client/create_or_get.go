@@ -0,0 +1,43 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// CreateOrGet creates the object, or, if it already exists, gets it into obj from the same
+// logical cluster.  It returns whether the object was created.
+//
+// This replaces checking for an AlreadyExists error after a Create, which races with the
+// other writers of the object, and often leaves obj without the state of the server.
+func CreateOrGet(ctx context.Context, c Client, obj Object, opts ...CreateOption) (bool, error) {
+	// The key is taken before the Create, so that the Get targets the cluster of the object
+	// even if the failed Create modified it.  Without a cluster, both target the cluster of
+	// the context.
+	key := ObjectKeyFromObject(obj)
+	err := c.Create(ctx, obj, opts...)
+	if err == nil {
+		return true, nil
+	}
+	if !apierrors.IsAlreadyExists(err) {
+		return false, err
+	}
+	return false, c.Get(ctx, key, obj)
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client_test
+
+import (
+	"context"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("CreateOrGet", func() {
+	clusterA := logicalcluster.New("root:org:a")
+	clusterB := logicalcluster.New("root:org:b")
+	var c client.Client
+	ctx := context.Background()
+
+	newConfigMap := func(data string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "create-or-get", Namespace: "default"},
+			Data:       map[string]string{"key": data},
+		}
+	}
+
+	BeforeEach(func() {
+		existing := newConfigMap("existing")
+		c = fake.NewClusterBuilder().WithObjects(clusterA, existing).Build()
+	})
+
+	It("should create the object if it doesn't exist", func() {
+		cm := newConfigMap("new")
+		created, err := client.CreateOrGet(kcpclient.WithCluster(ctx, clusterB), c, cm)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(created).To(BeTrue())
+
+		actual := &corev1.ConfigMap{}
+		Expect(c.Get(ctx, client.ObjectKey{NamespacedName: client.ObjectKeyFromObject(cm).NamespacedName, Cluster: clusterB}, actual)).To(Succeed())
+		Expect(actual.Data).To(HaveKeyWithValue("key", "new"))
+	})
+
+	It("should get the existing object of the cluster of the context", func() {
+		cm := newConfigMap("new")
+		created, err := client.CreateOrGet(kcpclient.WithCluster(ctx, clusterA), c, cm)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(created).To(BeFalse())
+		Expect(cm.Data).To(HaveKeyWithValue("key", "existing"))
+		Expect(cm.ResourceVersion).NotTo(BeEmpty())
+	})
+
+	It("should get the existing object of the cluster of the object", func() {
+		cm := newConfigMap("new")
+		cm.ClusterName = clusterA.String()
+		created, err := client.CreateOrGet(kcpclient.WithCluster(ctx, clusterB), c, cm)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(created).To(BeFalse())
+		Expect(cm.Data).To(HaveKeyWithValue("key", "existing"))
+	})
+
+	It("should return the other errors of the Create", func() {
+		cm := newConfigMap("new")
+		cm.Name = ""
+		created, err := client.CreateOrGet(kcpclient.WithCluster(ctx, clusterB), c, cm)
+		Expect(err).To(HaveOccurred())
+		Expect(apierrors.IsAlreadyExists(err)).To(BeFalse())
+		Expect(created).To(BeFalse())
+	})
+})
@@ -51,6 +51,11 @@ func (c *dryRunClient) Create(ctx context.Context, obj Object, opts ...CreateOpt
 	return c.client.Create(ctx, obj, append(opts, DryRunAll)...)
 }
 
+// CreateSubResource implements client.SubResourceCreator.
+func (c *dryRunClient) CreateSubResource(ctx context.Context, obj Object, subResource string, subResourceObj Object, opts ...CreateOption) error {
+	return CreateSubResource(ctx, c.client, obj, subResource, subResourceObj, append(opts, DryRunAll)...)
+}
+
 // Update implements client.Client.
 func (c *dryRunClient) Update(ctx context.Context, obj Object, opts ...UpdateOption) error {
 	return c.client.Update(ctx, obj, append(opts, DryRunAll)...)
@@ -81,6 +86,32 @@ func (c *dryRunClient) List(ctx context.Context, obj ObjectList, opts ...ListOpt
 	return c.client.List(ctx, obj, opts...)
 }
 
+// SubResource implements client.SubResourceClientProvider.
+func (c *dryRunClient) SubResource(subResource string) SubResourceClient {
+	return &dryRunSubResourceClient{client: SubResource(c.client, subResource)}
+}
+
+// dryRunSubResourceClient is client.SubResourceClient that writes subresources with dryRun
+// mode enforced.
+type dryRunSubResourceClient struct {
+	client SubResourceClient
+}
+
+// Get implements client.SubResourceClient.
+func (sc *dryRunSubResourceClient) Get(ctx context.Context, obj Object, subResourceObj Object) error {
+	return sc.client.Get(ctx, obj, subResourceObj)
+}
+
+// Update implements client.SubResourceClient.
+func (sc *dryRunSubResourceClient) Update(ctx context.Context, obj Object, subResourceObj Object, opts ...UpdateOption) error {
+	return sc.client.Update(ctx, obj, subResourceObj, append(opts, DryRunAll)...)
+}
+
+// Patch implements client.SubResourceClient.
+func (sc *dryRunSubResourceClient) Patch(ctx context.Context, obj Object, subResourceObj Object, patch Patch, opts ...PatchOption) error {
+	return sc.client.Patch(ctx, obj, subResourceObj, patch, append(opts, DryRunAll)...)
+}
+
 // Status implements client.StatusClient.
 func (c *dryRunClient) Status() StatusWriter {
 	return &dryRunStatusWriter{client: c.client.Status()}
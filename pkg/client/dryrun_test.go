@@ -79,7 +79,7 @@ var _ = Describe("DryRunClient", func() {
 		name := types.NamespacedName{Namespace: ns, Name: dep.Name}
 		result := &appsv1.Deployment{}
 
-		Expect(getClient().Get(ctx, name, result)).NotTo(HaveOccurred())
+		Expect(getClient().Get(ctx, client.ObjectKey{NamespacedName: name}, result)).NotTo(HaveOccurred())
 		Expect(result).To(BeEquivalentTo(dep))
 	})
 
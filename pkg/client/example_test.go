@@ -58,10 +58,10 @@ func ExampleClient_get() {
 	// Using a typed object.
 	pod := &corev1.Pod{}
 	// c is a created client.
-	_ = c.Get(context.Background(), client.ObjectKey{
+	_ = c.Get(context.Background(), client.ObjectKey{NamespacedName: types.NamespacedName{
 		Namespace: "namespace",
 		Name:      "name",
-	}, pod)
+	}}, pod)
 
 	// Using a unstructured object.
 	u := &unstructured.Unstructured{}
@@ -70,10 +70,10 @@ func ExampleClient_get() {
 		Kind:    "Deployment",
 		Version: "v1",
 	})
-	_ = c.Get(context.Background(), client.ObjectKey{
+	_ = c.Get(context.Background(), client.ObjectKey{NamespacedName: types.NamespacedName{
 		Namespace: "namespace",
 		Name:      "name",
-	}, u)
+	}}, u)
 }
 
 // This example shows how to use the client with typed and unstructured objects to create objects.
@@ -155,10 +155,10 @@ func ExampleClient_update() {
 	// Using a typed object.
 	pod := &corev1.Pod{}
 	// c is a created client.
-	_ = c.Get(context.Background(), client.ObjectKey{
+	_ = c.Get(context.Background(), client.ObjectKey{NamespacedName: types.NamespacedName{
 		Namespace: "namespace",
 		Name:      "name",
-	}, pod)
+	}}, pod)
 	pod.SetFinalizers(append(pod.GetFinalizers(), "new-finalizer"))
 	_ = c.Update(context.Background(), pod)
 
@@ -169,10 +169,10 @@ func ExampleClient_update() {
 		Kind:    "Deployment",
 		Version: "v1",
 	})
-	_ = c.Get(context.Background(), client.ObjectKey{
+	_ = c.Get(context.Background(), client.ObjectKey{NamespacedName: types.NamespacedName{
 		Namespace: "namespace",
 		Name:      "name",
-	}, u)
+	}}, u)
 	u.SetFinalizers(append(u.GetFinalizers(), "new-finalizer"))
 	_ = c.Update(context.Background(), u)
 }
@@ -22,10 +22,15 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -49,13 +54,18 @@ type versionedTracker struct {
 }
 
 type fakeClient struct {
-	tracker         versionedTracker
+	// trackers holds one tracker per logical cluster.  Objects without a cluster
+	// are stored in the tracker of the empty cluster name.
+	trackers     map[logicalcluster.Name]versionedTracker
+	trackersLock sync.Mutex
+
 	scheme          *runtime.Scheme
 	restMapper      meta.RESTMapper
 	schemeWriteLock sync.Mutex
 }
 
 var _ client.WithWatch = &fakeClient{}
+var _ client.SubResourceCreator = &fakeClient{}
 
 const (
 	maxNameLength          = 63
@@ -137,27 +147,126 @@ func (f *ClientBuilder) Build() client.WithWatch {
 		f.restMapper = meta.NewDefaultRESTMapper([]schema.GroupVersion{})
 	}
 
-	tracker := versionedTracker{ObjectTracker: testing.NewObjectTracker(f.scheme, scheme.Codecs.UniversalDecoder()), scheme: f.scheme}
+	c := &fakeClient{
+		trackers:   map[logicalcluster.Name]versionedTracker{},
+		scheme:     f.scheme,
+		restMapper: f.restMapper,
+	}
 	for _, obj := range f.initObject {
-		if err := tracker.Add(obj); err != nil {
+		if err := c.add(obj); err != nil {
 			panic(fmt.Errorf("failed to add object %v to fake client: %w", obj, err))
 		}
 	}
 	for _, obj := range f.initLists {
-		if err := tracker.Add(obj); err != nil {
+		if err := c.add(obj); err != nil {
 			panic(fmt.Errorf("failed to add list %v to fake client: %w", obj, err))
 		}
 	}
 	for _, obj := range f.initRuntimeObjects {
-		if err := tracker.Add(obj); err != nil {
+		if err := c.add(obj); err != nil {
 			panic(fmt.Errorf("failed to add runtime object %v to fake client: %w", obj, err))
 		}
 	}
-	return &fakeClient{
-		tracker:    tracker,
-		scheme:     f.scheme,
-		restMapper: f.restMapper,
+	return c
+}
+
+// add adds the given object, or the items of the given list, to the tracker
+// of their logical cluster.
+func (c *fakeClient) add(obj runtime.Object) error {
+	if !meta.IsListType(obj) {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return fmt.Errorf("failed to get accessor for object: %w", err)
+		}
+		return c.trackerFor(logicalcluster.From(accessor)).Add(obj)
 	}
+	objects, err := meta.ExtractList(obj)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objects {
+		if err := c.add(obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// trackerFor returns the tracker of the given logical cluster, creating it
+// on first use.
+func (c *fakeClient) trackerFor(cluster logicalcluster.Name) versionedTracker {
+	c.trackersLock.Lock()
+	defer c.trackersLock.Unlock()
+	tracker, ok := c.trackers[cluster]
+	if !ok {
+		tracker = versionedTracker{ObjectTracker: testing.NewObjectTracker(c.scheme, scheme.Codecs.UniversalDecoder()), scheme: c.scheme}
+		c.trackers[cluster] = tracker
+	}
+	return tracker
+}
+
+// clusterFor returns the given cluster, defaulting to the cluster of the context.
+func clusterFor(ctx context.Context, cluster logicalcluster.Name) logicalcluster.Name {
+	if cluster.Empty() {
+		if fromCtx, ok := kcpclient.ClusterFromContext(ctx); ok {
+			return fromCtx
+		}
+	}
+	return cluster
+}
+
+// singleClusterFor returns the given cluster, defaulting to the cluster of the context, and
+// fails if it is the wildcard cluster, through which single objects can't be read or written.
+func singleClusterFor(ctx context.Context, cluster logicalcluster.Name) (logicalcluster.Name, error) {
+	cluster = clusterFor(ctx, cluster)
+	if cluster == logicalcluster.Wildcard {
+		return cluster, apierrors.NewBadRequest("a single logical cluster is required, objects cannot be read or written across logical clusters")
+	}
+	return cluster, nil
+}
+
+// clusters returns the logical clusters holding objects, sorted by name.
+func (c *fakeClient) clusters() []logicalcluster.Name {
+	c.trackersLock.Lock()
+	clusters := make([]logicalcluster.Name, 0, len(c.trackers))
+	for cluster := range c.trackers {
+		clusters = append(clusters, cluster)
+	}
+	c.trackersLock.Unlock()
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].String() < clusters[j].String() })
+	return clusters
+}
+
+// list lists the objects of the given kind in the cluster of the context, or
+// in all the clusters if the context holds the wildcard cluster.
+func (c *fakeClient) list(ctx context.Context, gvr schema.GroupVersionResource, gvk schema.GroupVersionKind, ns string) (runtime.Object, error) {
+	cluster := clusterFor(ctx, logicalcluster.Name{})
+	if cluster != logicalcluster.Wildcard {
+		return c.trackerFor(cluster).List(gvr, gvk, ns)
+	}
+
+	clusters := c.clusters()
+
+	var list runtime.Object
+	var items []runtime.Object
+	for _, cluster := range clusters {
+		o, err := c.trackerFor(cluster).List(gvr, gvk, ns)
+		if err != nil {
+			return nil, err
+		}
+		clusterItems, err := meta.ExtractList(o)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, clusterItems...)
+		if list == nil {
+			list = o
+		}
+	}
+	if list == nil {
+		return c.trackerFor(logicalcluster.Name{}).List(gvr, gvk, ns)
+	}
+	return list, meta.SetList(list, items)
 }
 
 const trackerAddResourceVersion = "999"
@@ -320,7 +429,11 @@ func (c *fakeClient) Get(ctx context.Context, key client.ObjectKey, obj client.O
 	if err != nil {
 		return err
 	}
-	o, err := c.tracker.Get(gvr, key.Namespace, key.Name)
+	cluster, err := singleClusterFor(ctx, key.Cluster)
+	if err != nil {
+		return err
+	}
+	o, err := c.trackerFor(cluster).Get(gvr, key.Namespace, key.Name)
 	if err != nil {
 		return err
 	}
@@ -359,8 +472,13 @@ func (c *fakeClient) Watch(ctx context.Context, list client.ObjectList, opts ...
 	listOpts := client.ListOptions{}
 	listOpts.ApplyOptions(opts)
 
+	cluster := clusterFor(ctx, logicalcluster.Name{})
+	if cluster == logicalcluster.Wildcard {
+		return nil, errors.New("watching all logical clusters is not supported by the fake client")
+	}
+
 	gvr, _ := meta.UnsafeGuessKindToResource(gvk)
-	return c.tracker.Watch(gvr, listOpts.Namespace)
+	return c.trackerFor(cluster).Watch(gvr, listOpts.Namespace)
 }
 
 func (c *fakeClient) List(ctx context.Context, obj client.ObjectList, opts ...client.ListOption) error {
@@ -387,7 +505,7 @@ func (c *fakeClient) List(ctx context.Context, obj client.ObjectList, opts ...cl
 	listOpts.ApplyOptions(opts)
 
 	gvr, _ := meta.UnsafeGuessKindToResource(gvk)
-	o, err := c.tracker.List(gvr, gvk, listOpts.Namespace)
+	o, err := c.list(client.ListContext(ctx, opts...), gvr, gvk, listOpts.Namespace)
 	if err != nil {
 		return err
 	}
@@ -462,7 +580,14 @@ func (c *fakeClient) Create(ctx context.Context, obj client.Object, opts ...clie
 		accessor.SetName(fmt.Sprintf("%s%s", base, utilrand.String(randomLength)))
 	}
 
-	return c.tracker.Create(gvr, obj, accessor.GetNamespace())
+	// Like the API server, record the logical cluster the object was created in.
+	cluster, err := singleClusterFor(ctx, logicalcluster.From(accessor))
+	if err != nil {
+		return err
+	}
+	accessor.SetClusterName(cluster.String())
+
+	return c.trackerFor(cluster).Create(gvr, obj, accessor.GetNamespace())
 }
 
 func (c *fakeClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
@@ -476,11 +601,16 @@ func (c *fakeClient) Delete(ctx context.Context, obj client.Object, opts ...clie
 	}
 	delOptions := client.DeleteOptions{}
 	delOptions.ApplyOptions(opts)
+	cluster, err := singleClusterFor(ctx, logicalcluster.From(accessor))
+	if err != nil {
+		return err
+	}
+	tracker := c.trackerFor(cluster)
 
 	// Check the ResourceVersion if that Precondition was specified.
 	if delOptions.Preconditions != nil && delOptions.Preconditions.ResourceVersion != nil {
 		name := accessor.GetName()
-		dbObj, err := c.tracker.Get(gvr, accessor.GetNamespace(), name)
+		dbObj, err := tracker.Get(gvr, accessor.GetNamespace(), name)
 		if err != nil {
 			return err
 		}
@@ -499,7 +629,7 @@ func (c *fakeClient) Delete(ctx context.Context, obj client.Object, opts ...clie
 		}
 	}
 
-	return c.deleteObject(gvr, accessor)
+	return c.deleteObject(tracker, gvr, accessor)
 }
 
 func (c *fakeClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
@@ -512,7 +642,29 @@ func (c *fakeClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ..
 	dcOptions.ApplyOptions(opts)
 
 	gvr, _ := meta.UnsafeGuessKindToResource(gvk)
-	o, err := c.tracker.List(gvr, gvk, dcOptions.Namespace)
+	if dcOptions.AllClusters {
+		for _, cluster := range c.clusters() {
+			if err := c.deleteAllOf(ctx, cluster, gvr, gvk, &dcOptions); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	cluster := logicalcluster.From(obj)
+	if cluster.Empty() {
+		cluster = dcOptions.Cluster
+	}
+	cluster, err = singleClusterFor(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	return c.deleteAllOf(ctx, cluster, gvr, gvk, &dcOptions)
+}
+
+// deleteAllOf deletes the objects of the given kind matching the options in the given cluster.
+func (c *fakeClient) deleteAllOf(ctx context.Context, cluster logicalcluster.Name, gvr schema.GroupVersionResource, gvk schema.GroupVersionKind, dcOptions *client.DeleteAllOfOptions) error {
+	o, err := c.list(kcpclient.WithCluster(ctx, cluster), gvr, gvk, dcOptions.Namespace)
 	if err != nil {
 		return err
 	}
@@ -525,12 +677,16 @@ func (c *fakeClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ..
 	if err != nil {
 		return err
 	}
+	filteredObjs, err = objectutil.FilterWithFields(filteredObjs, dcOptions.FieldSelector)
+	if err != nil {
+		return err
+	}
 	for _, o := range filteredObjs {
 		accessor, err := meta.Accessor(o)
 		if err != nil {
 			return err
 		}
-		err = c.deleteObject(gvr, accessor)
+		err = c.deleteObject(c.trackerFor(cluster), gvr, accessor)
 		if err != nil {
 			return err
 		}
@@ -538,6 +694,43 @@ func (c *fakeClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ..
 	return nil
 }
 
+// CreateSubResource implements client.SubResourceCreator.  It supports the
+// eviction of Pods, which deletes them, and their binding, which sets their node.
+func (c *fakeClient) CreateSubResource(ctx context.Context, obj client.Object, subResource string, subResourceObj client.Object, opts ...client.CreateOption) error {
+	createOptions := &client.CreateOptions{}
+	createOptions.ApplyOptions(opts)
+
+	for _, dryRunOpt := range createOptions.DryRun {
+		if dryRunOpt == metav1.DryRunAll {
+			return nil
+		}
+	}
+
+	pod, isPod := obj.(*corev1.Pod)
+	switch sub := subResourceObj.(type) {
+	case *policyv1.Eviction:
+		if isPod && subResource == "eviction" {
+			var deleteOpts []client.DeleteOption
+			if sub.DeleteOptions != nil && sub.DeleteOptions.Preconditions != nil {
+				deleteOpts = append(deleteOpts, client.Preconditions(*sub.DeleteOptions.Preconditions))
+			}
+			return c.Delete(ctx, pod, deleteOpts...)
+		}
+	case *corev1.Binding:
+		if isPod && subResource == "binding" {
+			if err := c.Get(ctx, client.ObjectKeyFromObject(pod), pod); err != nil {
+				return err
+			}
+			if pod.Spec.NodeName != "" {
+				return apierrors.NewConflict(corev1.Resource("pods"), pod.Name, fmt.Errorf("pod %s is already assigned to node %q", pod.Name, pod.Spec.NodeName))
+			}
+			pod.Spec.NodeName = sub.Target.Name
+			return c.Update(ctx, pod)
+		}
+	}
+	return fmt.Errorf("fake client cannot create subresource %q of %T with %T", subResource, obj, subResourceObj)
+}
+
 func (c *fakeClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
 	updateOptions := &client.UpdateOptions{}
 	updateOptions.ApplyOptions(opts)
@@ -556,7 +749,11 @@ func (c *fakeClient) Update(ctx context.Context, obj client.Object, opts ...clie
 	if err != nil {
 		return err
 	}
-	return c.tracker.Update(gvr, obj, accessor.GetNamespace())
+	cluster, err := singleClusterFor(ctx, logicalcluster.From(accessor))
+	if err != nil {
+		return err
+	}
+	return c.trackerFor(cluster).Update(gvr, obj, accessor.GetNamespace())
 }
 
 func (c *fakeClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
@@ -582,7 +779,11 @@ func (c *fakeClient) Patch(ctx context.Context, obj client.Object, patch client.
 		return err
 	}
 
-	reaction := testing.ObjectReaction(c.tracker)
+	cluster, err := singleClusterFor(ctx, logicalcluster.From(accessor))
+	if err != nil {
+		return err
+	}
+	reaction := testing.ObjectReaction(c.trackerFor(cluster))
 	handled, o, err := reaction(testing.NewPatchAction(gvr, accessor.GetNamespace(), accessor.GetName(), patch.Type(), data))
 	if err != nil {
 		return err
@@ -616,21 +817,21 @@ func (c *fakeClient) Status() client.StatusWriter {
 	return &fakeStatusWriter{client: c}
 }
 
-func (c *fakeClient) deleteObject(gvr schema.GroupVersionResource, accessor metav1.Object) error {
-	old, err := c.tracker.Get(gvr, accessor.GetNamespace(), accessor.GetName())
+func (c *fakeClient) deleteObject(tracker versionedTracker, gvr schema.GroupVersionResource, accessor metav1.Object) error {
+	old, err := tracker.Get(gvr, accessor.GetNamespace(), accessor.GetName())
 	if err == nil {
 		oldAccessor, err := meta.Accessor(old)
 		if err == nil {
 			if len(oldAccessor.GetFinalizers()) > 0 {
 				now := metav1.Now()
 				oldAccessor.SetDeletionTimestamp(&now)
-				return c.tracker.Update(gvr, old, accessor.GetNamespace())
+				return tracker.Update(gvr, old, accessor.GetNamespace())
 			}
 		}
 	}
 
 	//TODO: implement propagation
-	return c.tracker.Delete(gvr, accessor.GetNamespace(), accessor.GetName())
+	return tracker.Delete(gvr, accessor.GetNamespace(), accessor.GetName())
 }
 
 func getGVRFromObject(obj runtime.Object, scheme *runtime.Scheme) (schema.GroupVersionResource, error) {
@@ -22,6 +22,8 @@ import (
 	"fmt"
 	"time"
 
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -93,7 +95,7 @@ var _ = Describe("Fake client", func() {
 				Namespace: "ns1",
 			}
 			obj := &appsv1.Deployment{}
-			err := cl.Get(context.Background(), namespacedName, obj)
+			err := cl.Get(context.Background(), client.ObjectKey{NamespacedName: namespacedName}, obj)
 			Expect(err).To(BeNil())
 			Expect(obj).To(Equal(dep))
 		})
@@ -107,7 +109,7 @@ var _ = Describe("Fake client", func() {
 			obj := &unstructured.Unstructured{}
 			obj.SetAPIVersion("apps/v1")
 			obj.SetKind("Deployment")
-			err := cl.Get(context.Background(), namespacedName, obj)
+			err := cl.Get(context.Background(), client.ObjectKey{NamespacedName: namespacedName}, obj)
 			Expect(err).To(BeNil())
 		})
 
@@ -354,7 +356,7 @@ var _ = Describe("Fake client", func() {
 				Namespace: "ns2",
 			}
 			obj := &corev1.ConfigMap{}
-			err = cl.Get(context.Background(), namespacedName, obj)
+			err = cl.Get(context.Background(), client.ObjectKey{NamespacedName: namespacedName}, obj)
 			Expect(err).To(BeNil())
 			Expect(obj).To(Equal(newcm))
 			Expect(obj.ObjectMeta.ResourceVersion).To(Equal("1"))
@@ -468,7 +470,7 @@ var _ = Describe("Fake client", func() {
 				Namespace: "ns2",
 			}
 			obj := &corev1.ConfigMap{}
-			err = cl.Get(context.Background(), namespacedName, obj)
+			err = cl.Get(context.Background(), client.ObjectKey{NamespacedName: namespacedName}, obj)
 			Expect(err).To(BeNil())
 			Expect(obj).To(Equal(newcm))
 			Expect(obj.ObjectMeta.ResourceVersion).To(Equal("1000"))
@@ -498,7 +500,7 @@ var _ = Describe("Fake client", func() {
 				Namespace: "ns2",
 			}
 			obj := &corev1.ConfigMap{}
-			err = cl.Get(context.Background(), namespacedName, obj)
+			err = cl.Get(context.Background(), client.ObjectKey{NamespacedName: namespacedName}, obj)
 			Expect(err).To(BeNil())
 			Expect(obj).To(Equal(newcm))
 			Expect(obj.ObjectMeta.ResourceVersion).To(Equal("1000"))
@@ -563,7 +565,7 @@ var _ = Describe("Fake client", func() {
 				Namespace: lease.Namespace,
 			}
 			obj := &coordinationv1.Lease{}
-			Expect(cl.Get(context.Background(), namespacedName, obj)).To(Succeed())
+			Expect(cl.Get(context.Background(), client.ObjectKey{NamespacedName: namespacedName}, obj)).To(Succeed())
 			Expect(obj).To(Equal(lease))
 			Expect(obj.ObjectMeta.ResourceVersion).To(Equal("1"))
 		})
@@ -607,7 +609,7 @@ var _ = Describe("Fake client", func() {
 				Namespace: "ns2",
 			}
 			obj := &corev1.ConfigMap{}
-			err = cl.Get(context.Background(), namespacedName, obj)
+			err = cl.Get(context.Background(), client.ObjectKey{NamespacedName: namespacedName}, obj)
 			Expect(err).To(BeNil())
 			Expect(obj).To(Equal(cm))
 			Expect(obj.ObjectMeta.ResourceVersion).To(Equal(trackerAddResourceVersion))
@@ -692,7 +694,7 @@ var _ = Describe("Fake client", func() {
 
 			By("Getting the object")
 			obj := &corev1.ConfigMap{}
-			err = cl.Get(context.Background(), namespacedName, obj)
+			err = cl.Get(context.Background(), client.ObjectKey{NamespacedName: namespacedName}, obj)
 			Expect(err).To(BeNil())
 			Expect(obj.DeletionTimestamp).NotTo(BeNil())
 
@@ -703,7 +705,7 @@ var _ = Describe("Fake client", func() {
 
 			By("Getting the object")
 			obj = &corev1.ConfigMap{}
-			err = cl.Get(context.Background(), namespacedName, obj)
+			err = cl.Get(context.Background(), client.ObjectKey{NamespacedName: namespacedName}, obj)
 			Expect(apierrors.IsNotFound(err)).To(BeTrue())
 		})
 
@@ -798,7 +800,7 @@ var _ = Describe("Fake client", func() {
 					Namespace: "ns2",
 				}
 				obj := &corev1.ConfigMap{}
-				err = cl.Get(context.Background(), namespacedName, obj)
+				err = cl.Get(context.Background(), client.ObjectKey{NamespacedName: namespacedName}, obj)
 				Expect(err).To(HaveOccurred())
 				Expect(apierrors.IsNotFound(err)).To(BeTrue())
 				Expect(obj).NotTo(Equal(newcm))
@@ -825,7 +827,7 @@ var _ = Describe("Fake client", func() {
 					Namespace: "ns2",
 				}
 				obj := &corev1.ConfigMap{}
-				err = cl.Get(context.Background(), namespacedName, obj)
+				err = cl.Get(context.Background(), client.ObjectKey{NamespacedName: namespacedName}, obj)
 				Expect(err).To(BeNil())
 				Expect(obj).To(Equal(cm))
 				Expect(obj.ObjectMeta.ResourceVersion).To(Equal(trackerAddResourceVersion))
@@ -851,7 +853,7 @@ var _ = Describe("Fake client", func() {
 				Namespace: "ns1",
 			}
 			obj := &appsv1.Deployment{}
-			err = cl.Get(context.Background(), namespacedName, obj)
+			err = cl.Get(context.Background(), client.ObjectKey{NamespacedName: namespacedName}, obj)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(obj.Annotations["foo"]).To(Equal("bar"))
 			Expect(obj.ObjectMeta.ResourceVersion).To(Equal("1000"))
@@ -893,7 +895,7 @@ var _ = Describe("Fake client", func() {
 
 			By("Getting the object")
 			obj = &corev1.ConfigMap{}
-			err = cl.Get(context.Background(), namespacedName, obj)
+			err = cl.Get(context.Background(), client.ObjectKey{NamespacedName: namespacedName}, obj)
 			Expect(apierrors.IsNotFound(err)).To(BeTrue())
 		})
 
@@ -933,7 +935,7 @@ var _ = Describe("Fake client", func() {
 
 			By("Check the finalizer has been removed in client")
 			newObj := &corev1.ConfigMap{}
-			err = cl.Get(context.Background(), namespacedName, newObj)
+			err = cl.Get(context.Background(), client.ObjectKey{NamespacedName: namespacedName}, newObj)
 			Expect(err).To(BeNil())
 			Expect(len(newObj.Finalizers)).To(Equal(0))
 		})
@@ -967,7 +969,7 @@ var _ = Describe("Fake client", func() {
 		cl := NewClientBuilder().WithObjects(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "cm"}}).Build()
 
 		retrieved := &corev1.Secret{}
-		Expect(cl.Get(context.Background(), types.NamespacedName{Name: "cm"}, retrieved)).To(Succeed())
+		Expect(cl.Get(context.Background(), client.ObjectKey{NamespacedName: types.NamespacedName{Name: "cm"}}, retrieved)).To(Succeed())
 
 		reference := &corev1.Secret{
 			TypeMeta: metav1.TypeMeta{
@@ -981,4 +983,154 @@ var _ = Describe("Fake client", func() {
 		}
 		Expect(retrieved).To(Equal(reference))
 	})
+
+	It("should keep the objects of different logical clusters apart", func() {
+		ctx := context.Background()
+		cl := NewClientBuilder().WithObjects(
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "ns", ClusterName: "root:a"}, Data: map[string]string{"cluster": "a"}},
+		).Build()
+
+		By("creating an object with the same name in another cluster through the context")
+		Expect(cl.Create(kcpclient.WithCluster(ctx, logicalcluster.New("root:b")), &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "ns"},
+			Data:       map[string]string{"cluster": "b"},
+		})).To(Succeed())
+
+		By("getting each object by its cluster-aware key")
+		for _, cluster := range []string{"a", "b"} {
+			retrieved := &corev1.ConfigMap{}
+			key := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "cm"}, Cluster: logicalcluster.New("root:" + cluster)}
+			Expect(cl.Get(ctx, key, retrieved)).To(Succeed())
+			Expect(retrieved.Data).To(HaveKeyWithValue("cluster", cluster))
+			Expect(client.ObjectKeyFromObject(retrieved)).To(Equal(key))
+		}
+
+		By("listing the objects of all the clusters")
+		list := &corev1.ConfigMapList{}
+		Expect(cl.List(kcpclient.WithCluster(ctx, logicalcluster.Wildcard), list)).To(Succeed())
+		Expect(list.Items).To(HaveLen(2))
+
+		By("deleting the object of a single cluster")
+		Expect(cl.Delete(ctx, &list.Items[0])).To(Succeed())
+		err := cl.Get(ctx, client.ObjectKeyFromObject(&list.Items[0]), &corev1.ConfigMap{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(&list.Items[1]), &corev1.ConfigMap{})).To(Succeed())
+	})
+
+	It("should seed the objects of several logical clusters", func() {
+		ctx := context.Background()
+		a, b := logicalcluster.New("root:a"), logicalcluster.New("root:b")
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "ns"}}
+		cl := NewClusterBuilder().
+			WithObjects(a, cm).
+			WithLists(b, &corev1.ConfigMapList{Items: []corev1.ConfigMap{*cm}}).
+			Build()
+		Expect(cm.ClusterName).To(BeEmpty())
+
+		By("reading the objects of the cluster of the context")
+		for _, cluster := range []logicalcluster.Name{a, b} {
+			list := &corev1.ConfigMapList{}
+			Expect(cl.List(kcpclient.WithCluster(ctx, cluster), list)).To(Succeed())
+			Expect(list.Items).To(HaveLen(1))
+			Expect(logicalcluster.From(&list.Items[0])).To(Equal(cluster))
+		}
+		err := cl.Get(kcpclient.WithCluster(ctx, logicalcluster.New("root:c")), client.ObjectKeyFromObject(cm), &corev1.ConfigMap{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		By("listing the objects of all the clusters")
+		list := &corev1.ConfigMapList{}
+		Expect(cl.List(kcpclient.WithCluster(ctx, logicalcluster.Wildcard), list)).To(Succeed())
+		Expect(list.Items).To(HaveLen(2))
+
+		By("listing the objects of the cluster of the options rather than of the context")
+		list = &corev1.ConfigMapList{}
+		Expect(cl.List(kcpclient.WithCluster(ctx, a), list, client.InCluster(b))).To(Succeed())
+		Expect(list.Items).To(HaveLen(1))
+		Expect(logicalcluster.From(&list.Items[0])).To(Equal(b))
+		list = &corev1.ConfigMapList{}
+		Expect(cl.List(kcpclient.WithCluster(ctx, a), list, client.AllClusters)).To(Succeed())
+		Expect(list.Items).To(HaveLen(2))
+
+		By("deleting the objects of the cluster of the options")
+		Expect(cl.DeleteAllOf(ctx, &corev1.ConfigMap{}, client.InNamespace("ns"), client.InCluster(b))).To(Succeed())
+		list = &corev1.ConfigMapList{}
+		Expect(cl.List(ctx, list, client.AllClusters)).To(Succeed())
+		Expect(list.Items).To(HaveLen(1))
+		Expect(logicalcluster.From(&list.Items[0])).To(Equal(a))
+
+		By("refusing to read or write single objects through the wildcard cluster")
+		wildcard := kcpclient.WithCluster(ctx, logicalcluster.Wildcard)
+		err = cl.Get(wildcard, client.ObjectKeyFromObject(cm), &corev1.ConfigMap{})
+		Expect(apierrors.IsBadRequest(err)).To(BeTrue())
+		err = cl.Create(wildcard, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "ns"}})
+		Expect(apierrors.IsBadRequest(err)).To(BeTrue())
+		err = cl.DeleteAllOf(wildcard, &corev1.ConfigMap{}, client.InNamespace("ns"))
+		Expect(apierrors.IsBadRequest(err)).To(BeTrue())
+	})
+
+	It("should delete all of the matching objects of a logical cluster, or of all of them", func() {
+		ctx := context.Background()
+		a, b := logicalcluster.New("root:a"), logicalcluster.New("root:b")
+		objects := func(cluster logicalcluster.Name) []client.Object {
+			return []client.Object{
+				&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "ns", Labels: map[string]string{"app": "x"}}},
+				&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "ns", Labels: map[string]string{"app": "x"}}},
+				&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "baz", Namespace: "ns"}},
+			}
+		}
+		cl := NewClusterBuilder().WithObjects(a, objects(a)...).WithObjects(b, objects(b)...).Build()
+		names := func(cluster logicalcluster.Name) []string {
+			list := &corev1.ConfigMapList{}
+			Expect(cl.List(kcpclient.WithCluster(ctx, cluster), list)).To(Succeed())
+			var names []string
+			for _, cm := range list.Items {
+				names = append(names, cm.Name)
+			}
+			return names
+		}
+
+		By("deleting in the logical cluster of the object with label and field selectors")
+		Expect(cl.DeleteAllOf(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{ClusterName: a.String()}},
+			client.InNamespace("ns"), client.MatchingLabels{"app": "x"}, client.MatchingFields{"metadata.name": "foo"})).To(Succeed())
+		Expect(names(a)).To(ConsistOf("bar", "baz"))
+		Expect(names(b)).To(ConsistOf("foo", "bar", "baz"))
+
+		By("fanning the delete out across all the logical clusters")
+		Expect(cl.DeleteAllOf(ctx, &corev1.ConfigMap{}, client.InNamespace("ns"), client.MatchingLabels{"app": "x"}, client.AllClusters)).To(Succeed())
+		Expect(names(a)).To(ConsistOf("baz"))
+		Expect(names(b)).To(ConsistOf("baz"))
+	})
+
+	It("should evict and bind the Pods of a logical cluster", func() {
+		ctx := context.Background()
+		a, b := logicalcluster.New("root:a"), logicalcluster.New("root:b")
+		pod := func() *corev1.Pod {
+			return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"}}
+		}
+		cl := NewClusterBuilder().WithObjects(a, pod()).WithObjects(b, pod()).Build()
+
+		By("binding the Pod of a cluster to a node")
+		bound := pod()
+		bound.ClusterName = a.String()
+		Expect(client.BindPod(ctx, cl, bound, "node-1")).To(Succeed())
+		Expect(bound.Spec.NodeName).To(Equal("node-1"))
+		err := client.BindPod(ctx, cl, bound, "node-2")
+		Expect(apierrors.IsConflict(err)).To(BeTrue())
+
+		By("evicting the Pod of the cluster of the context")
+		Expect(client.EvictPod(kcpclient.WithCluster(ctx, b), cl, pod())).To(Succeed())
+		err = cl.Get(kcpclient.WithCluster(ctx, b), client.ObjectKeyFromObject(pod()), &corev1.Pod{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		Expect(cl.Get(kcpclient.WithCluster(ctx, a), client.ObjectKeyFromObject(pod()), &corev1.Pod{})).To(Succeed())
+
+		By("refusing other subresources")
+		err = client.CreateSubResource(ctx, cl, bound, "exec", &corev1.ConfigMap{})
+		Expect(err).To(MatchError(ContainSubstring(`cannot create subresource "exec"`)))
+	})
+
+	It("should refuse to seed an object into another logical cluster", func() {
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "ns", ClusterName: "root:a"}}
+		Expect(func() { NewClusterBuilder().WithObjects(logicalcluster.New("root:b"), cm) }).To(Panic())
+		Expect(func() { NewClusterBuilder().WithObjects(logicalcluster.Wildcard, cm) }).To(Panic())
+	})
 })
@@ -0,0 +1,118 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"fmt"
+
+	"github.com/kcp-dev/logicalcluster"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NewClusterBuilder returns a new builder to create a fake client holding the objects
+// of several logical clusters.
+func NewClusterBuilder() *ClusterBuilder {
+	return &ClusterBuilder{builder: NewClientBuilder()}
+}
+
+// ClusterBuilder builds a fake client seeded with the objects of several logical clusters.
+//
+// Like with kcp, the objects of each logical cluster are kept apart: the client reads and
+// writes the objects of the cluster of the key or object, or else of the cluster of the
+// context, see kcpclient.WithCluster.  Objects are only listed across clusters when the
+// context holds the wildcard cluster, and can't be read or written through it.
+type ClusterBuilder struct {
+	builder *ClientBuilder
+}
+
+// WithScheme sets this builder's internal scheme.
+// If not set, defaults to client-go's global scheme.Scheme.
+func (f *ClusterBuilder) WithScheme(scheme *runtime.Scheme) *ClusterBuilder {
+	f.builder.WithScheme(scheme)
+	return f
+}
+
+// WithRESTMapper sets this builder's restMapper, see ClientBuilder.WithRESTMapper.
+func (f *ClusterBuilder) WithRESTMapper(restMapper meta.RESTMapper) *ClusterBuilder {
+	f.builder.WithRESTMapper(restMapper)
+	return f
+}
+
+// WithObjects seeds the logical cluster with copies of the given objects.  It panics if an
+// object already belongs to another logical cluster.
+func (f *ClusterBuilder) WithObjects(cluster logicalcluster.Name, initObjs ...client.Object) *ClusterBuilder {
+	for _, obj := range initObjs {
+		f.builder.WithObjects(inCluster(cluster, obj).(client.Object))
+	}
+	return f
+}
+
+// WithLists seeds the logical cluster with copies of the items of the given lists.  It panics
+// if an item already belongs to another logical cluster.
+func (f *ClusterBuilder) WithLists(cluster logicalcluster.Name, initLists ...client.ObjectList) *ClusterBuilder {
+	for _, list := range initLists {
+		f.builder.WithLists(inCluster(cluster, list).(client.ObjectList))
+	}
+	return f
+}
+
+// WithRuntimeObjects seeds the logical cluster with copies of the given runtime.Object(s).  It
+// panics if an object already belongs to another logical cluster.
+func (f *ClusterBuilder) WithRuntimeObjects(cluster logicalcluster.Name, initRuntimeObjs ...runtime.Object) *ClusterBuilder {
+	for _, obj := range initRuntimeObjs {
+		f.builder.WithRuntimeObjects(inCluster(cluster, obj))
+	}
+	return f
+}
+
+// Build builds and returns a new fake client.
+func (f *ClusterBuilder) Build() client.WithWatch {
+	return f.builder.Build()
+}
+
+// inCluster returns a copy of the object, or of the list, whose objects are stamped with the
+// logical cluster.
+func inCluster(cluster logicalcluster.Name, obj runtime.Object) runtime.Object {
+	if cluster.Empty() || cluster == logicalcluster.Wildcard {
+		panic(fmt.Errorf("cannot seed objects into logical cluster %q, a single logical cluster is required", cluster))
+	}
+	obj = obj.DeepCopyObject()
+	stamp := func(o runtime.Object) error {
+		accessor, err := meta.Accessor(o)
+		if err != nil {
+			return fmt.Errorf("failed to get accessor for object: %w", err)
+		}
+		if current := logicalcluster.From(accessor); !current.Empty() && current != cluster {
+			return fmt.Errorf("object %s belongs to logical cluster %s, not %s", accessor.GetName(), current, cluster)
+		}
+		accessor.SetClusterName(cluster.String())
+		return nil
+	}
+	var err error
+	if meta.IsListType(obj) {
+		err = meta.EachListItem(obj, stamp)
+	} else {
+		err = stamp(obj)
+	}
+	if err != nil {
+		panic(fmt.Errorf("failed to seed logical cluster %s: %w", cluster, err))
+	}
+	return obj
+}
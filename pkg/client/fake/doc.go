@@ -24,6 +24,11 @@ You can create a fake client with optional objects.
 
 You can invoke the methods defined in the Client interface.
 
+To test code which depends on kcp logical clusters, a fake client can be seeded with the objects
+of several logical clusters, which are kept apart like kcp does.
+
+	client := NewClusterBuilder().WithObjects(logicalcluster.New("root:org:ws"), initObjs...).Build()
+
 When in doubt, it's almost always better not to use this package and instead use
 envtest.Environment with a real client and API server.
 
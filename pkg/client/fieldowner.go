@@ -0,0 +1,144 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// WithFieldOwner wraps an existing client setting the given field owner on all its
+// writes, including the ones of its status and subresources, unless a write sets
+// another FieldOwner.  Like the other wrappers, it doesn't change the logical cluster
+// of the calls.
+func WithFieldOwner(c Client, fieldOwner string) Client {
+	return &fieldOwnerClient{
+		client: c,
+		owner:  FieldOwner(fieldOwner),
+	}
+}
+
+var _ Client = &fieldOwnerClient{}
+
+// fieldOwnerClient is a Client that wraps another Client in order to set the field owner of the writes.
+type fieldOwnerClient struct {
+	client Client
+	owner  FieldOwner
+}
+
+// Scheme returns the scheme this client is using.
+func (c *fieldOwnerClient) Scheme() *runtime.Scheme {
+	return c.client.Scheme()
+}
+
+// RESTMapper returns the rest mapper this client is using.
+func (c *fieldOwnerClient) RESTMapper() meta.RESTMapper {
+	return c.client.RESTMapper()
+}
+
+// Create implements client.Client.
+func (c *fieldOwnerClient) Create(ctx context.Context, obj Object, opts ...CreateOption) error {
+	return c.client.Create(ctx, obj, append([]CreateOption{c.owner}, opts...)...)
+}
+
+// CreateSubResource implements client.SubResourceCreator.
+func (c *fieldOwnerClient) CreateSubResource(ctx context.Context, obj Object, subResource string, subResourceObj Object, opts ...CreateOption) error {
+	return CreateSubResource(ctx, c.client, obj, subResource, subResourceObj, append([]CreateOption{c.owner}, opts...)...)
+}
+
+// Update implements client.Client.
+func (c *fieldOwnerClient) Update(ctx context.Context, obj Object, opts ...UpdateOption) error {
+	return c.client.Update(ctx, obj, append([]UpdateOption{c.owner}, opts...)...)
+}
+
+// Delete implements client.Client.
+func (c *fieldOwnerClient) Delete(ctx context.Context, obj Object, opts ...DeleteOption) error {
+	return c.client.Delete(ctx, obj, opts...)
+}
+
+// DeleteAllOf implements client.Client.
+func (c *fieldOwnerClient) DeleteAllOf(ctx context.Context, obj Object, opts ...DeleteAllOfOption) error {
+	return c.client.DeleteAllOf(ctx, obj, opts...)
+}
+
+// Patch implements client.Client.
+func (c *fieldOwnerClient) Patch(ctx context.Context, obj Object, patch Patch, opts ...PatchOption) error {
+	return c.client.Patch(ctx, obj, patch, append([]PatchOption{c.owner}, opts...)...)
+}
+
+// Get implements client.Client.
+func (c *fieldOwnerClient) Get(ctx context.Context, key ObjectKey, obj Object) error {
+	return c.client.Get(ctx, key, obj)
+}
+
+// List implements client.Client.
+func (c *fieldOwnerClient) List(ctx context.Context, obj ObjectList, opts ...ListOption) error {
+	return c.client.List(ctx, obj, opts...)
+}
+
+// Status implements client.StatusClient.
+func (c *fieldOwnerClient) Status() StatusWriter {
+	return &fieldOwnerStatusWriter{client: c.client.Status(), owner: c.owner}
+}
+
+// ensure fieldOwnerStatusWriter implements client.StatusWriter.
+var _ StatusWriter = &fieldOwnerStatusWriter{}
+
+type fieldOwnerStatusWriter struct {
+	client StatusWriter
+	owner  FieldOwner
+}
+
+// Update implements client.StatusWriter.
+func (sw *fieldOwnerStatusWriter) Update(ctx context.Context, obj Object, opts ...UpdateOption) error {
+	return sw.client.Update(ctx, obj, append([]UpdateOption{sw.owner}, opts...)...)
+}
+
+// Patch implements client.StatusWriter.
+func (sw *fieldOwnerStatusWriter) Patch(ctx context.Context, obj Object, patch Patch, opts ...PatchOption) error {
+	return sw.client.Patch(ctx, obj, patch, append([]PatchOption{sw.owner}, opts...)...)
+}
+
+// SubResource implements client.SubResourceClientProvider.
+func (c *fieldOwnerClient) SubResource(subResource string) SubResourceClient {
+	return &fieldOwnerSubResourceClient{client: SubResource(c.client, subResource), owner: c.owner}
+}
+
+// ensure fieldOwnerSubResourceClient implements client.SubResourceClient.
+var _ SubResourceClient = &fieldOwnerSubResourceClient{}
+
+type fieldOwnerSubResourceClient struct {
+	client SubResourceClient
+	owner  FieldOwner
+}
+
+// Get implements client.SubResourceClient.
+func (sc *fieldOwnerSubResourceClient) Get(ctx context.Context, obj Object, subResourceObj Object) error {
+	return sc.client.Get(ctx, obj, subResourceObj)
+}
+
+// Update implements client.SubResourceClient.
+func (sc *fieldOwnerSubResourceClient) Update(ctx context.Context, obj Object, subResourceObj Object, opts ...UpdateOption) error {
+	return sc.client.Update(ctx, obj, subResourceObj, append([]UpdateOption{sc.owner}, opts...)...)
+}
+
+// Patch implements client.SubResourceClient.
+func (sc *fieldOwnerSubResourceClient) Patch(ctx context.Context, obj Object, subResourceObj Object, patch Patch, opts ...PatchOption) error {
+	return sc.client.Patch(ctx, obj, subResourceObj, patch, append([]PatchOption{sc.owner}, opts...)...)
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fieldManagerClient records the field managers of the writes of a client.
+type fieldManagerClient struct {
+	client.Client
+	managers []string
+}
+
+func (c *fieldManagerClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	c.managers = append(c.managers, (&client.CreateOptions{}).ApplyOptions(opts).FieldManager)
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *fieldManagerClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	c.managers = append(c.managers, (&client.PatchOptions{}).ApplyOptions(opts).FieldManager)
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+var _ = Describe("WithFieldOwner", func() {
+	It("should set the field owner of the writes, unless they set another one", func() {
+		recorder := &fieldManagerClient{Client: fake.NewClientBuilder().Build()}
+		c := client.WithFieldOwner(recorder, "owner")
+
+		cm := &corev1.ConfigMap{}
+		cm.Name = "cm"
+		cm.Namespace = "default"
+		Expect(c.Create(context.Background(), cm)).To(Succeed())
+		patch := client.MergeFrom(cm.DeepCopy())
+		cm.Data = map[string]string{"key": "value"}
+		Expect(c.Patch(context.Background(), cm, patch, client.FieldOwner("other"))).To(Succeed())
+
+		Expect(recorder.managers).To(Equal([]string{"owner", "other"}))
+	})
+})
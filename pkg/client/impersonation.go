@@ -0,0 +1,85 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"net/http"
+	"strings"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport"
+)
+
+// ClusterImpersonation returns the user the requests to the logical cluster impersonate, e.g.
+// the service account of the tenant of the workspace, and whether they impersonate one.
+type ClusterImpersonation func(cluster logicalcluster.Name) (rest.ImpersonationConfig, bool)
+
+// impersonatingHTTPClient returns a copy of the HTTP client whose requests impersonate the user
+// of the logical cluster they are sent to: the cluster of their context, or else of the
+// /clusters/<name> path of their URL.
+func impersonatingHTTPClient(httpClient *http.Client, impersonate ClusterImpersonation) *http.Client {
+	delegate := httpClient.Transport
+	if delegate == nil {
+		delegate = http.DefaultTransport
+	}
+	impersonatingClient := *httpClient
+	impersonatingClient.Transport = &clusterImpersonatingRoundTripper{delegate: delegate, impersonate: impersonate}
+	return &impersonatingClient
+}
+
+// clusterImpersonatingRoundTripper sets the impersonation headers of the user of the logical
+// cluster of each request.
+type clusterImpersonatingRoundTripper struct {
+	delegate    http.RoundTripper
+	impersonate ClusterImpersonation
+}
+
+// RoundTrip implements http.RoundTripper.
+func (rt *clusterImpersonatingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	cluster, ok := kcpclient.ClusterFromContext(req.Context())
+	if !ok || cluster.Empty() {
+		cluster = pathCluster(req.URL.Path)
+	}
+	if cluster.Empty() {
+		return rt.delegate.RoundTrip(req)
+	}
+	config, ok := rt.impersonate(cluster)
+	if !ok {
+		return rt.delegate.RoundTrip(req)
+	}
+	return transport.NewImpersonatingRoundTripper(transport.ImpersonationConfig{
+		UserName: config.UserName,
+		Groups:   config.Groups,
+		Extra:    config.Extra,
+	}, rt.delegate).RoundTrip(req)
+}
+
+// pathCluster returns the logical cluster of a request path of the form /clusters/<name>/...,
+// if any.
+func pathCluster(path string) logicalcluster.Name {
+	i := strings.Index(path, "/clusters/")
+	if i < 0 {
+		return logicalcluster.Name{}
+	}
+	name := path[i+len("/clusters/"):]
+	if j := strings.Index(name, "/"); j >= 0 {
+		name = name[:j]
+	}
+	return logicalcluster.New(name)
+}
@@ -70,6 +70,24 @@ func (n *namespacedClient) Create(ctx context.Context, obj Object, opts ...Creat
 	return n.client.Create(ctx, obj, opts...)
 }
 
+// CreateSubResource implements client.SubResourceCreator.
+func (n *namespacedClient) CreateSubResource(ctx context.Context, obj Object, subResource string, subResourceObj Object, opts ...CreateOption) error {
+	isNamespaceScoped, err := objectutil.IsAPINamespaced(obj, n.Scheme(), n.RESTMapper())
+	if err != nil {
+		return fmt.Errorf("error finding the scope of the object: %v", err)
+	}
+
+	objectNamespace := obj.GetNamespace()
+	if objectNamespace != n.namespace && objectNamespace != "" {
+		return fmt.Errorf("namespace %s of the object %s does not match the namespace %s on the client", objectNamespace, obj.GetName(), n.namespace)
+	}
+
+	if isNamespaceScoped && objectNamespace == "" {
+		obj.SetNamespace(n.namespace)
+	}
+	return CreateSubResource(ctx, n.client, obj, subResource, subResourceObj, opts...)
+}
+
 // Update implements client.Client.
 func (n *namespacedClient) Update(ctx context.Context, obj Object, opts ...UpdateOption) error {
 	isNamespaceScoped, err := objectutil.IsAPINamespaced(obj, n.Scheme(), n.RESTMapper())
@@ -160,6 +178,63 @@ func (n *namespacedClient) List(ctx context.Context, obj ObjectList, opts ...Lis
 	return n.client.List(ctx, obj, opts...)
 }
 
+// SubResource implements client.SubResourceClientProvider.
+func (n *namespacedClient) SubResource(subResource string) SubResourceClient {
+	return &namespacedSubResourceClient{client: SubResource(n.client, subResource), namespace: n.namespace, namespacedclient: n}
+}
+
+// ensure namespacedSubResourceClient implements client.SubResourceClient.
+var _ SubResourceClient = &namespacedSubResourceClient{}
+
+type namespacedSubResourceClient struct {
+	client           SubResourceClient
+	namespace        string
+	namespacedclient Client
+}
+
+// setNamespace defaults the namespace of obj to the one of the client, or returns an
+// error if obj is in another namespace.
+func (nsc *namespacedSubResourceClient) setNamespace(obj Object) error {
+	isNamespaceScoped, err := objectutil.IsAPINamespaced(obj, nsc.namespacedclient.Scheme(), nsc.namespacedclient.RESTMapper())
+	if err != nil {
+		return fmt.Errorf("error finding the scope of the object: %v", err)
+	}
+
+	objectNamespace := obj.GetNamespace()
+	if objectNamespace != nsc.namespace && objectNamespace != "" {
+		return fmt.Errorf("namespace %s of the object %s does not match the namespace %s on the client", objectNamespace, obj.GetName(), nsc.namespace)
+	}
+
+	if isNamespaceScoped && objectNamespace == "" {
+		obj.SetNamespace(nsc.namespace)
+	}
+	return nil
+}
+
+// Get implements client.SubResourceClient.
+func (nsc *namespacedSubResourceClient) Get(ctx context.Context, obj Object, subResourceObj Object) error {
+	if err := nsc.setNamespace(obj); err != nil {
+		return err
+	}
+	return nsc.client.Get(ctx, obj, subResourceObj)
+}
+
+// Update implements client.SubResourceClient.
+func (nsc *namespacedSubResourceClient) Update(ctx context.Context, obj Object, subResourceObj Object, opts ...UpdateOption) error {
+	if err := nsc.setNamespace(obj); err != nil {
+		return err
+	}
+	return nsc.client.Update(ctx, obj, subResourceObj, opts...)
+}
+
+// Patch implements client.SubResourceClient.
+func (nsc *namespacedSubResourceClient) Patch(ctx context.Context, obj Object, subResourceObj Object, patch Patch, opts ...PatchOption) error {
+	if err := nsc.setNamespace(obj); err != nil {
+		return err
+	}
+	return nsc.client.Patch(ctx, obj, subResourceObj, patch, opts...)
+}
+
 // Status implements client.StatusClient.
 func (n *namespacedClient) Status() StatusWriter {
 	return &namespacedClientStatusWriter{StatusClient: n.client.Status(), namespace: n.namespace, namespacedclient: n}
@@ -92,7 +92,7 @@ var _ = Describe("NamespacedClient", func() {
 			name := types.NamespacedName{Name: dep.Name}
 			result := &appsv1.Deployment{}
 
-			Expect(getClient().Get(ctx, name, result)).NotTo(HaveOccurred())
+			Expect(getClient().Get(ctx, client.ObjectKey{NamespacedName: name}, result)).NotTo(HaveOccurred())
 			Expect(result).To(BeEquivalentTo(dep))
 		})
 
@@ -101,7 +101,7 @@ var _ = Describe("NamespacedClient", func() {
 			name := types.NamespacedName{Name: dep.Name, Namespace: "non-default"}
 			result := &appsv1.Deployment{}
 
-			Expect(getClient().Get(ctx, name, result)).To(HaveOccurred())
+			Expect(getClient().Get(ctx, client.ObjectKey{NamespacedName: name}, result)).To(HaveOccurred())
 		})
 	})
 
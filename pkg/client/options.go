@@ -17,6 +17,7 @@ limitations under the License.
 package client
 
 import (
+	"github.com/kcp-dev/logicalcluster"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
@@ -345,6 +346,12 @@ type ListOptions struct {
 	// that these may not be respected by all implementations of interface,
 	// and the LabelSelector, FieldSelector, Limit and Continue fields are ignored.
 	Raw *metav1.ListOptions
+
+	// Cluster is the logical cluster to list, or logicalcluster.Wildcard to list
+	// across all the logical clusters.  It takes precedence over the logical cluster
+	// of the context, which is listed if it is empty, see ListContext.  The clients
+	// and readers pinned to a logical cluster refuse to list another one.
+	Cluster logicalcluster.Name
 }
 
 var _ ListOption = &ListOptions{}
@@ -369,6 +376,9 @@ func (o *ListOptions) ApplyToList(lo *ListOptions) {
 	if o.Continue != "" {
 		lo.Continue = o.Continue
 	}
+	if !o.Cluster.Empty() {
+		lo.Cluster = o.Cluster
+	}
 }
 
 // AsListOptions returns these options as a flattened metav1.ListOptions.
@@ -501,6 +511,20 @@ func (n InNamespace) ApplyToDeleteAllOf(opts *DeleteAllOfOptions) {
 	n.ApplyToList(&opts.ListOptions)
 }
 
+// InCluster restricts the list/delete operation to the given logical cluster,
+// rather than the one of the context.  See ListOptions.Cluster.
+type InCluster logicalcluster.Name
+
+// ApplyToList applies this configuration to the given list options.
+func (c InCluster) ApplyToList(opts *ListOptions) {
+	opts.Cluster = logicalcluster.Name(c)
+}
+
+// ApplyToDeleteAllOf applies this configuration to the given an List options.
+func (c InCluster) ApplyToDeleteAllOf(opts *DeleteAllOfOptions) {
+	c.ApplyToList(&opts.ListOptions)
+}
+
 // Limit specifies the maximum number of results to return from the server.
 // Limit does not implement DeleteAllOfOption interface because the server
 // does not support setting it for deletecollection operations.
@@ -675,6 +699,10 @@ func (forceOwnership) ApplyToPatch(opts *PatchOptions) {
 type DeleteAllOfOptions struct {
 	ListOptions
 	DeleteOptions
+
+	// AllClusters fans the delete out across every logical cluster known to
+	// the client, see AllClusters.
+	AllClusters bool
 }
 
 // ApplyOptions applies the given deleteallof options on these options,
@@ -692,6 +720,28 @@ var _ DeleteAllOfOption = &DeleteAllOfOptions{}
 func (o *DeleteAllOfOptions) ApplyToDeleteAllOf(do *DeleteAllOfOptions) {
 	o.ApplyToList(&do.ListOptions)
 	o.ApplyToDelete(&do.DeleteOptions)
+	if o.AllClusters {
+		do.AllClusters = true
+	}
+}
+
+// AllClusters fans a DeleteAllOf out across every logical cluster known to the
+// client, e.g. every cluster of a ClusterSet, instead of deleting in the cluster
+// of the object or of the context.  Clients which can't enumerate the logical
+// clusters fail the delete.  As a ListOption, it lists across all the logical
+// clusters, like InCluster(logicalcluster.Wildcard).
+var AllClusters = allClusters{}
+
+type allClusters struct{}
+
+// ApplyToList applies this configuration to the given list options.
+func (allClusters) ApplyToList(opts *ListOptions) {
+	opts.Cluster = logicalcluster.Wildcard
+}
+
+// ApplyToDeleteAllOf applies this configuration to the given deleteallof options.
+func (allClusters) ApplyToDeleteAllOf(opts *DeleteAllOfOptions) {
+	opts.AllClusters = true
 }
 
 // }}}
@@ -17,6 +17,10 @@ limitations under the License.
 package client_test
 
 import (
+	"context"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
@@ -66,6 +70,12 @@ var _ = Describe("ListOptions", func() {
 		o.ApplyToList(newListOpts)
 		Expect(newListOpts).To(Equal(o))
 	})
+	It("Should set Cluster", func() {
+		o := &client.ListOptions{Cluster: logicalcluster.New("root:org")}
+		newListOpts := &client.ListOptions{}
+		o.ApplyToList(newListOpts)
+		Expect(newListOpts).To(Equal(o))
+	})
 	It("Should not set anything", func() {
 		o := &client.ListOptions{}
 		newListOpts := &client.ListOptions{}
@@ -74,6 +84,36 @@ var _ = Describe("ListOptions", func() {
 	})
 })
 
+var _ = Describe("InCluster", func() {
+	It("Should set the Cluster of the list and deleteallof options", func() {
+		listOpts := &client.ListOptions{}
+		client.InCluster(logicalcluster.New("root:org")).ApplyToList(listOpts)
+		Expect(listOpts.Cluster).To(Equal(logicalcluster.New("root:org")))
+
+		deleteAllOfOpts := &client.DeleteAllOfOptions{}
+		client.InCluster(logicalcluster.New("root:org")).ApplyToDeleteAllOf(deleteAllOfOpts)
+		Expect(deleteAllOfOpts.Cluster).To(Equal(logicalcluster.New("root:org")))
+	})
+	It("Should list across clusters with AllClusters", func() {
+		listOpts := &client.ListOptions{}
+		client.AllClusters.ApplyToList(listOpts)
+		Expect(listOpts.Cluster).To(Equal(logicalcluster.Wildcard))
+	})
+})
+
+var _ = Describe("ListContext", func() {
+	It("Should target the cluster of the options, or else keep the cluster of the context", func() {
+		ctx := kcpclient.WithCluster(context.Background(), logicalcluster.New("root:ctx"))
+
+		cluster, _ := kcpclient.ClusterFromContext(client.ListContext(ctx, client.InNamespace("ns")))
+		Expect(cluster).To(Equal(logicalcluster.New("root:ctx")))
+		cluster, _ = kcpclient.ClusterFromContext(client.ListContext(ctx, client.InCluster(logicalcluster.New("root:org"))))
+		Expect(cluster).To(Equal(logicalcluster.New("root:org")))
+		cluster, _ = kcpclient.ClusterFromContext(client.ListContext(ctx, client.AllClusters))
+		Expect(cluster).To(Equal(logicalcluster.Wildcard))
+	})
+})
+
 var _ = Describe("CreateOptions", func() {
 	It("Should set DryRun", func() {
 		o := &client.CreateOptions{DryRun: []string{"Hello", "Theodore"}}
@@ -214,6 +254,16 @@ var _ = Describe("DeleteAllOfOptions", func() {
 		o.ApplyToDeleteAllOf(newDeleteAllOfOpts)
 		Expect(newDeleteAllOfOpts).To(Equal(o))
 	})
+	It("Should set AllClusters", func() {
+		o := &client.DeleteAllOfOptions{AllClusters: true}
+		newDeleteAllOfOpts := &client.DeleteAllOfOptions{}
+		o.ApplyToDeleteAllOf(newDeleteAllOfOpts)
+		Expect(newDeleteAllOfOpts).To(Equal(o))
+
+		newDeleteAllOfOpts = &client.DeleteAllOfOptions{}
+		client.AllClusters.ApplyToDeleteAllOf(newDeleteAllOfOpts)
+		Expect(newDeleteAllOfOpts.AllClusters).To(BeTrue())
+	})
 })
 
 var _ = Describe("MatchingLabels", func() {
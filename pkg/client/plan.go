@@ -0,0 +1,307 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/json"
+
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// PlanOperation is the kind of a write recorded in a Plan.
+type PlanOperation string
+
+const (
+	// PlanCreate is a recorded Create.
+	PlanCreate PlanOperation = "Create"
+	// PlanUpdate is a recorded Update.
+	PlanUpdate PlanOperation = "Update"
+	// PlanPatch is a recorded Patch.
+	PlanPatch PlanOperation = "Patch"
+	// PlanDelete is a recorded Delete.
+	PlanDelete PlanOperation = "Delete"
+	// PlanDeleteAllOf is a recorded DeleteAllOf.
+	PlanDeleteAllOf PlanOperation = "DeleteAllOf"
+)
+
+// PlannedWrite is a write that was recorded by a plan client instead of being sent
+// to the API server.
+type PlannedWrite struct {
+	// Operation is the kind of write.
+	Operation PlanOperation
+
+	// GroupVersionKind is the kind of the written object.
+	GroupVersionKind schema.GroupVersionKind
+
+	// Key identifies the written object, including its logical cluster.
+	// For DeleteAllOf only the namespace and the cluster are set.
+	Key ObjectKey
+
+	// Subresource is the written subresource, e.g. "status", or empty for the object itself.
+	Subresource string
+
+	// Diff describes the change.  It is the whole object for a Create, a JSON merge
+	// patch from the current to the desired object for an Update, and the patch data
+	// for a Patch.  It is empty for deletions.
+	Diff []byte
+
+	// PatchType is the type of the patch for a Patch.
+	PatchType types.PatchType
+
+	// Object is a copy of the object as passed to the client.
+	Object Object
+}
+
+// Plan holds the writes recorded by a plan client.  It is safe for concurrent use.
+type Plan struct {
+	mu     sync.Mutex
+	writes []PlannedWrite
+}
+
+// Writes returns the recorded writes, in the order they were made.
+func (p *Plan) Writes() []PlannedWrite {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]PlannedWrite(nil), p.writes...)
+}
+
+// ByCluster returns the recorded writes grouped by logical cluster.
+func (p *Plan) ByCluster() map[logicalcluster.Name][]PlannedWrite {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	res := map[logicalcluster.Name][]PlannedWrite{}
+	for _, w := range p.writes {
+		res[w.Key.Cluster] = append(res[w.Key.Cluster], w)
+	}
+	return res
+}
+
+// Reset drops all the recorded writes.
+func (p *Plan) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.writes = nil
+}
+
+func (p *Plan) record(w PlannedWrite) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.writes = append(p.writes, w)
+}
+
+// NewPlanClient wraps an existing client and records all mutating api calls
+// into the given Plan instead of executing them.  Reads are served by the
+// wrapped client, which is also used to compute the diff of updates.
+func NewPlanClient(c Client, plan *Plan) Client {
+	return &planClient{client: c, plan: plan}
+}
+
+var _ Client = &planClient{}
+
+// planClient is a Client that wraps another Client in order to record writes into a Plan.
+type planClient struct {
+	client Client
+	plan   *Plan
+}
+
+// Scheme returns the scheme this client is using.
+func (c *planClient) Scheme() *runtime.Scheme {
+	return c.client.Scheme()
+}
+
+// RESTMapper returns the rest mapper this client is using.
+func (c *planClient) RESTMapper() meta.RESTMapper {
+	return c.client.RESTMapper()
+}
+
+// Create implements client.Client.
+func (c *planClient) Create(ctx context.Context, obj Object, opts ...CreateOption) error {
+	w, err := c.newWrite(ctx, PlanCreate, obj, "")
+	if err != nil {
+		return err
+	}
+	if w.Diff, err = json.Marshal(obj); err != nil {
+		return err
+	}
+	c.plan.record(w)
+	return nil
+}
+
+// CreateSubResource implements client.SubResourceCreator.  The write is recorded as
+// a Create of the subresource of obj, with the subresource object as its diff.
+func (c *planClient) CreateSubResource(ctx context.Context, obj Object, subResource string, subResourceObj Object, opts ...CreateOption) error {
+	w, err := c.newWrite(ctx, PlanCreate, obj, subResource)
+	if err != nil {
+		return err
+	}
+	if w.Diff, err = json.Marshal(subResourceObj); err != nil {
+		return err
+	}
+	c.plan.record(w)
+	return nil
+}
+
+// Update implements client.Client.
+func (c *planClient) Update(ctx context.Context, obj Object, opts ...UpdateOption) error {
+	return c.update(ctx, obj, "")
+}
+
+// Delete implements client.Client.
+func (c *planClient) Delete(ctx context.Context, obj Object, opts ...DeleteOption) error {
+	w, err := c.newWrite(ctx, PlanDelete, obj, "")
+	if err != nil {
+		return err
+	}
+	c.plan.record(w)
+	return nil
+}
+
+// DeleteAllOf implements client.Client.
+func (c *planClient) DeleteAllOf(ctx context.Context, obj Object, opts ...DeleteAllOfOption) error {
+	w, err := c.newWrite(ctx, PlanDeleteAllOf, obj, "")
+	if err != nil {
+		return err
+	}
+	deleteAllOfOpts := DeleteAllOfOptions{}
+	deleteAllOfOpts.ApplyOptions(opts)
+	w.Key.Name = ""
+	w.Key.Namespace = deleteAllOfOpts.Namespace
+	c.plan.record(w)
+	return nil
+}
+
+// Patch implements client.Client.
+func (c *planClient) Patch(ctx context.Context, obj Object, patch Patch, opts ...PatchOption) error {
+	return c.patch(ctx, obj, patch, "")
+}
+
+// Get implements client.Client.
+func (c *planClient) Get(ctx context.Context, key ObjectKey, obj Object) error {
+	return c.client.Get(ctx, key, obj)
+}
+
+// List implements client.Client.
+func (c *planClient) List(ctx context.Context, obj ObjectList, opts ...ListOption) error {
+	return c.client.List(ctx, obj, opts...)
+}
+
+// Status implements client.StatusClient.
+func (c *planClient) Status() StatusWriter {
+	return &planStatusWriter{client: c}
+}
+
+func (c *planClient) update(ctx context.Context, obj Object, subresource string) error {
+	w, err := c.newWrite(ctx, PlanUpdate, obj, subresource)
+	if err != nil {
+		return err
+	}
+	desired, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	current, ok := obj.DeepCopyObject().(Object)
+	if !ok {
+		return fmt.Errorf("object %T does not implement client.Object", obj)
+	}
+	if err := c.client.Get(withCluster(ctx, w.Key.Cluster), w.Key, current); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		w.Diff = desired
+		c.plan.record(w)
+		return nil
+	}
+	currentJSON, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	if w.Diff, err = jsonpatch.CreateMergePatch(currentJSON, desired); err != nil {
+		return err
+	}
+	c.plan.record(w)
+	return nil
+}
+
+func (c *planClient) patch(ctx context.Context, obj Object, patch Patch, subresource string) error {
+	w, err := c.newWrite(ctx, PlanPatch, obj, subresource)
+	if err != nil {
+		return err
+	}
+	if w.Diff, err = patch.Data(obj); err != nil {
+		return err
+	}
+	w.PatchType = patch.Type()
+	c.plan.record(w)
+	return nil
+}
+
+// newWrite builds a PlannedWrite for the given object.  The cluster of the
+// object takes precedence over the cluster in the context.
+func (c *planClient) newWrite(ctx context.Context, op PlanOperation, obj Object, subresource string) (PlannedWrite, error) {
+	gvk, err := apiutil.GVKForObject(obj, c.client.Scheme())
+	if err != nil {
+		return PlannedWrite{}, err
+	}
+	key := ObjectKeyFromObject(obj)
+	if key.Cluster.Empty() {
+		if cluster, ok := kcpclient.ClusterFromContext(ctx); ok {
+			key.Cluster = cluster
+		}
+	}
+	copied, ok := obj.DeepCopyObject().(Object)
+	if !ok {
+		return PlannedWrite{}, fmt.Errorf("object %T does not implement client.Object", obj)
+	}
+	return PlannedWrite{
+		Operation:        op,
+		GroupVersionKind: gvk,
+		Key:              key,
+		Subresource:      subresource,
+		Object:           copied,
+	}, nil
+}
+
+// ensure planStatusWriter implements client.StatusWriter.
+var _ StatusWriter = &planStatusWriter{}
+
+// planStatusWriter is client.StatusWriter that records status writes into a Plan.
+type planStatusWriter struct {
+	client *planClient
+}
+
+// Update implements client.StatusWriter.
+func (sw *planStatusWriter) Update(ctx context.Context, obj Object, opts ...UpdateOption) error {
+	return sw.client.update(ctx, obj, "status")
+}
+
+// Patch implements client.StatusWriter.
+func (sw *planStatusWriter) Patch(ctx context.Context, obj Object, patch Patch, opts ...PatchOption) error {
+	return sw.client.patch(ctx, obj, patch, "status")
+}
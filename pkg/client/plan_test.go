@@ -0,0 +1,114 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client_test
+
+import (
+	"context"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("PlanClient", func() {
+	var cm *corev1.ConfigMap
+	var delegate client.Client
+	var plan *client.Plan
+	ctx := context.Background()
+
+	BeforeEach(func() {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "plan-configmap",
+				Namespace:   "default",
+				ClusterName: "root:org:ws",
+			},
+			Data: map[string]string{"foo": "bar"},
+		}
+		delegate = fake.NewClientBuilder().WithObjects(cm.DeepCopy()).Build()
+		plan = &client.Plan{}
+	})
+
+	It("should record a Create without executing it", func() {
+		c := client.NewPlanClient(delegate, plan)
+		created := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "new", Namespace: "default"}}
+		Expect(c.Create(ctx, created)).To(Succeed())
+
+		err := delegate.Get(ctx, client.ObjectKeyFromObject(created), &corev1.ConfigMap{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		writes := plan.Writes()
+		Expect(writes).To(HaveLen(1))
+		Expect(writes[0].Operation).To(Equal(client.PlanCreate))
+		Expect(writes[0].GroupVersionKind.Kind).To(Equal("ConfigMap"))
+		Expect(writes[0].Key.Name).To(Equal("new"))
+	})
+
+	It("should record the diff of an Update in the cluster of the object", func() {
+		c := client.NewPlanClient(delegate, plan)
+		updated := &corev1.ConfigMap{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(cm), updated)).To(Succeed())
+		updated.Data["foo"] = "baz"
+		Expect(c.Update(ctx, updated)).To(Succeed())
+
+		actual := &corev1.ConfigMap{}
+		Expect(delegate.Get(ctx, client.ObjectKeyFromObject(cm), actual)).To(Succeed())
+		Expect(actual.Data["foo"]).To(Equal("bar"))
+
+		byCluster := plan.ByCluster()
+		Expect(byCluster).To(HaveKey(logicalcluster.New("root:org:ws")))
+		writes := byCluster[logicalcluster.New("root:org:ws")]
+		Expect(writes).To(HaveLen(1))
+		Expect(writes[0].Operation).To(Equal(client.PlanUpdate))
+		Expect(string(writes[0].Diff)).To(Equal(`{"data":{"foo":"baz"}}`))
+	})
+
+	It("should record status patches and deletions", func() {
+		c := client.NewPlanClient(delegate, plan)
+		Expect(c.Status().Patch(ctx, cm, client.RawPatch("application/merge-patch+json", []byte(`{}`)))).To(Succeed())
+		Expect(c.Delete(ctx, cm)).To(Succeed())
+		Expect(delegate.Get(ctx, client.ObjectKeyFromObject(cm), &corev1.ConfigMap{})).To(Succeed())
+
+		writes := plan.Writes()
+		Expect(writes).To(HaveLen(2))
+		Expect(writes[0].Operation).To(Equal(client.PlanPatch))
+		Expect(writes[0].Subresource).To(Equal("status"))
+		Expect(writes[1].Operation).To(Equal(client.PlanDelete))
+
+		plan.Reset()
+		Expect(plan.Writes()).To(BeEmpty())
+	})
+
+	It("should record the creation of subresources, e.g. evictions", func() {
+		c := client.NewPlanClient(delegate, plan)
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default", ClusterName: "root:org:ws"}}
+		Expect(client.EvictPod(ctx, c, pod)).To(Succeed())
+
+		writes := plan.ByCluster()[logicalcluster.New("root:org:ws")]
+		Expect(writes).To(HaveLen(1))
+		Expect(writes[0].Operation).To(Equal(client.PlanCreate))
+		Expect(writes[0].GroupVersionKind.Kind).To(Equal("Pod"))
+		Expect(writes[0].Subresource).To(Equal("eviction"))
+		Expect(string(writes[0].Diff)).To(ContainSubstring(`"name":"pod"`))
+	})
+})
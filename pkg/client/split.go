@@ -18,8 +18,11 @@ package client
 
 import (
 	"context"
+	"fmt"
 	"strings"
 
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -34,6 +37,65 @@ type NewDelegatingClientInput struct {
 	Client            Client
 	UncachedObjects   []Object
 	CacheUnstructured bool
+
+	// UncachedByCluster are the kinds of objects read live, bypassing CacheReader, in some
+	// logical clusters only.
+	UncachedByCluster UncachedByCluster
+}
+
+// UncachedByCluster associates logical clusters with the kinds of objects a delegating client
+// reads live from the API server rather than from its cache, e.g. to read the Secrets of the
+// tenant workspaces live while caching the rest.  The kinds of logicalcluster.Wildcard are read
+// live in all the clusters, and all the reads of a cluster with no kinds are live.  The cluster
+// of a read is the one of its key, or else the one of its context.
+type UncachedByCluster map[logicalcluster.Name][]Object
+
+// gvks returns the GroupVersionKinds of the kinds of each cluster, a nil map meaning all of them.
+func (u UncachedByCluster) gvks(scheme *runtime.Scheme) (map[logicalcluster.Name]map[schema.GroupVersionKind]struct{}, error) {
+	if len(u) == 0 {
+		return nil, nil
+	}
+	byCluster := make(map[logicalcluster.Name]map[schema.GroupVersionKind]struct{}, len(u))
+	for cluster, objs := range u {
+		if len(objs) == 0 {
+			byCluster[cluster] = nil
+			continue
+		}
+		gvks := make(map[schema.GroupVersionKind]struct{}, len(objs))
+		for _, obj := range objs {
+			gvk, err := apiutil.GVKForObject(obj, scheme)
+			if err != nil {
+				return nil, err
+			}
+			gvks[gvk] = struct{}{}
+		}
+		byCluster[cluster] = gvks
+	}
+	return byCluster, nil
+}
+
+// WithUncachedByCluster returns a client reading the kinds of objects of UncachedByCluster
+// live with the given reader, e.g. the API reader of a manager, rather than with the client.
+// The other reads, and all the writes, use the client.
+func WithUncachedByCluster(c Client, liveReader Reader, uncached UncachedByCluster) (Client, error) {
+	uncachedByCluster, err := uncached.gvks(c.Scheme())
+	if err != nil {
+		return nil, err
+	}
+	return &delegatingClient{
+		scheme: c.Scheme(),
+		mapper: c.RESTMapper(),
+		Reader: &delegatingReader{
+			CacheReader:       c,
+			ClientReader:      liveReader,
+			scheme:            c.Scheme(),
+			uncachedByCluster: uncachedByCluster,
+			// The client decides how to read the unstructured objects.
+			cacheUnstructured: true,
+		},
+		Writer:       c,
+		StatusClient: c,
+	}, nil
 }
 
 // NewDelegatingClient creates a new delegating client.
@@ -50,6 +112,10 @@ func NewDelegatingClient(in NewDelegatingClientInput) (Client, error) {
 		}
 		uncachedGVKs[gvk] = struct{}{}
 	}
+	uncachedByCluster, err := in.UncachedByCluster.gvks(in.Client.Scheme())
+	if err != nil {
+		return nil, err
+	}
 
 	return &delegatingClient{
 		scheme: in.Client.Scheme(),
@@ -59,6 +125,7 @@ func NewDelegatingClient(in NewDelegatingClientInput) (Client, error) {
 			ClientReader:      in.Client,
 			scheme:            in.Client.Scheme(),
 			uncachedGVKs:      uncachedGVKs,
+			uncachedByCluster: uncachedByCluster,
 			cacheUnstructured: in.CacheUnstructured,
 		},
 		Writer:       in.Client,
@@ -75,6 +142,21 @@ type delegatingClient struct {
 	mapper meta.RESTMapper
 }
 
+// CreateSubResource implements client.SubResourceCreator.
+func (d *delegatingClient) CreateSubResource(ctx context.Context, obj Object, subResource string, subResourceObj Object, opts ...CreateOption) error {
+	creator, ok := d.Writer.(SubResourceCreator)
+	if !ok {
+		return fmt.Errorf("client %T cannot create subresources", d.Writer)
+	}
+	return creator.CreateSubResource(ctx, obj, subResource, subResourceObj, opts...)
+}
+
+// SubResource implements client.SubResourceClientProvider.  The subresources are read
+// and written with the client writing the objects, in the logical cluster of the objects.
+func (d *delegatingClient) SubResource(subResource string) SubResourceClient {
+	return subResourceClientOf(d.Writer, subResource)
+}
+
 // Scheme returns the scheme this client is using.
 func (d *delegatingClient) Scheme() *runtime.Scheme {
 	return d.scheme
@@ -95,11 +177,12 @@ type delegatingReader struct {
 	ClientReader Reader
 
 	uncachedGVKs      map[schema.GroupVersionKind]struct{}
+	uncachedByCluster map[logicalcluster.Name]map[schema.GroupVersionKind]struct{}
 	scheme            *runtime.Scheme
 	cacheUnstructured bool
 }
 
-func (d *delegatingReader) shouldBypassCache(obj runtime.Object) (bool, error) {
+func (d *delegatingReader) shouldBypassCache(cluster logicalcluster.Name, obj runtime.Object) (bool, error) {
 	gvk, err := apiutil.GVKForObject(obj, d.scheme)
 	if err != nil {
 		return false, err
@@ -112,6 +195,9 @@ func (d *delegatingReader) shouldBypassCache(obj runtime.Object) (bool, error) {
 	if _, isUncached := d.uncachedGVKs[gvk]; isUncached {
 		return true, nil
 	}
+	if d.uncachedInCluster(logicalcluster.Wildcard, gvk) || (!cluster.Empty() && d.uncachedInCluster(cluster, gvk)) {
+		return true, nil
+	}
 	if !d.cacheUnstructured {
 		_, isUnstructured := obj.(*unstructured.Unstructured)
 		_, isUnstructuredList := obj.(*unstructured.UnstructuredList)
@@ -120,9 +206,26 @@ func (d *delegatingReader) shouldBypassCache(obj runtime.Object) (bool, error) {
 	return false, nil
 }
 
+// uncachedInCluster returns whether the objects of the kind are read live in the logical cluster.
+func (d *delegatingReader) uncachedInCluster(cluster logicalcluster.Name, gvk schema.GroupVersionKind) bool {
+	gvks, ok := d.uncachedByCluster[cluster]
+	if !ok {
+		return false
+	}
+	if gvks == nil {
+		return true
+	}
+	_, ok = gvks[gvk]
+	return ok
+}
+
 // Get retrieves an obj for a given object key from the Kubernetes Cluster.
 func (d *delegatingReader) Get(ctx context.Context, key ObjectKey, obj Object) error {
-	if isUncached, err := d.shouldBypassCache(obj); err != nil {
+	cluster := key.Cluster
+	if cluster.Empty() {
+		cluster, _ = kcpclient.ClusterFromContext(ctx)
+	}
+	if isUncached, err := d.shouldBypassCache(cluster, obj); err != nil {
 		return err
 	} else if isUncached {
 		return d.ClientReader.Get(ctx, key, obj)
@@ -132,7 +235,9 @@ func (d *delegatingReader) Get(ctx context.Context, key ObjectKey, obj Object) e
 
 // List retrieves list of objects for a given namespace and list options.
 func (d *delegatingReader) List(ctx context.Context, list ObjectList, opts ...ListOption) error {
-	if isUncached, err := d.shouldBypassCache(list); err != nil {
+	ctx = ListContext(ctx, opts...)
+	cluster, _ := kcpclient.ClusterFromContext(ctx)
+	if isUncached, err := d.shouldBypassCache(cluster, list); err != nil {
 		return err
 	} else if isUncached {
 		return d.ClientReader.List(ctx, list, opts...)
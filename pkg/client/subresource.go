@@ -0,0 +1,134 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SubResourceCreator creates the action-style subresources of objects, e.g. the
+// evictions or the bindings of Pods.
+type SubResourceCreator interface {
+	// CreateSubResource POSTs subResourceObj to the given subresource of obj, in the
+	// logical cluster of obj, and decodes the response into subResourceObj.
+	CreateSubResource(ctx context.Context, obj Object, subResource string, subResourceObj Object, opts ...CreateOption) error
+}
+
+// CreateSubResource creates the given subresource of obj with the client, e.g. the
+// "eviction" of a Pod, or returns an error if the client doesn't implement
+// SubResourceCreator.
+func CreateSubResource(ctx context.Context, c Client, obj Object, subResource string, subResourceObj Object, opts ...CreateOption) error {
+	creator, ok := c.(SubResourceCreator)
+	if !ok {
+		return fmt.Errorf("client %T cannot create subresources", c)
+	}
+	return creator.CreateSubResource(ctx, obj, subResource, subResourceObj, opts...)
+}
+
+// SubResourceClient reads and writes a subresource of objects, e.g. the "scale" of
+// Deployments, in the logical cluster of the objects.
+type SubResourceClient interface {
+	// Get reads the subresource of obj into subResourceObj.
+	Get(ctx context.Context, obj Object, subResourceObj Object) error
+	// Update PUTs subResourceObj to the subresource of obj, and decodes the response
+	// into subResourceObj.  obj itself is sent if subResourceObj is nil, like for the
+	// status subresource.
+	Update(ctx context.Context, obj Object, subResourceObj Object, opts ...UpdateOption) error
+	// Patch patches the subresource of obj with the patch computed from subResourceObj,
+	// and decodes the response into subResourceObj.  obj itself is used if subResourceObj
+	// is nil, like for the status subresource.
+	Patch(ctx context.Context, obj Object, subResourceObj Object, patch Patch, opts ...PatchOption) error
+}
+
+// SubResourceClientProvider provides the SubResourceClient of the subresources of
+// the given name.
+type SubResourceClientProvider interface {
+	SubResource(subResource string) SubResourceClient
+}
+
+// SubResource returns the SubResourceClient of the client for the subresources of the
+// given name, e.g. "scale".  The returned client fails every call if the client doesn't
+// implement SubResourceClientProvider.
+func SubResource(c Client, subResource string) SubResourceClient {
+	return subResourceClientOf(c, subResource)
+}
+
+// subResourceClientOf returns the SubResourceClient of c if it is a SubResourceClientProvider.
+func subResourceClientOf(c interface{}, subResource string) SubResourceClient {
+	provider, ok := c.(SubResourceClientProvider)
+	if !ok {
+		return &unsupportedSubResourceClient{err: fmt.Errorf("client %T cannot read or write subresources", c)}
+	}
+	return provider.SubResource(subResource)
+}
+
+// unsupportedSubResourceClient is the SubResourceClient of the clients which aren't
+// SubResourceClientProviders.
+type unsupportedSubResourceClient struct {
+	err error
+}
+
+// Get implements client.SubResourceClient.
+func (c *unsupportedSubResourceClient) Get(ctx context.Context, obj Object, subResourceObj Object) error {
+	return c.err
+}
+
+// Update implements client.SubResourceClient.
+func (c *unsupportedSubResourceClient) Update(ctx context.Context, obj Object, subResourceObj Object, opts ...UpdateOption) error {
+	return c.err
+}
+
+// Patch implements client.SubResourceClient.
+func (c *unsupportedSubResourceClient) Patch(ctx context.Context, obj Object, subResourceObj Object, patch Patch, opts ...PatchOption) error {
+	return c.err
+}
+
+// EvictPod evicts the Pod, honoring its PodDisruptionBudgets, in the logical cluster
+// of the Pod.  The eviction is refused with a TooManyRequests error while it would
+// violate a budget.
+func EvictPod(ctx context.Context, c Client, pod *corev1.Pod, opts ...DeleteOption) error {
+	deleteOpts := &DeleteOptions{}
+	deleteOpts.ApplyOptions(opts)
+	eviction := &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        pod.Name,
+			Namespace:   pod.Namespace,
+			ClusterName: pod.ClusterName,
+		},
+		DeleteOptions: deleteOpts.AsDeleteOptions(),
+	}
+	return CreateSubResource(ctx, c, pod, "eviction", eviction)
+}
+
+// BindPod binds the Pod to the given Node, in the logical cluster of the Pod, like
+// a scheduler does.
+func BindPod(ctx context.Context, c Client, pod *corev1.Pod, nodeName string, opts ...CreateOption) error {
+	binding := &corev1.Binding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        pod.Name,
+			Namespace:   pod.Namespace,
+			ClusterName: pod.ClusterName,
+		},
+		Target: corev1.ObjectReference{Kind: "Node", Name: nodeName},
+	}
+	return CreateSubResource(ctx, c, pod, "binding", binding, opts...)
+}
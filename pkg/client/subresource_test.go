@@ -0,0 +1,161 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("CreateSubResource", func() {
+	var server *httptest.Server
+	var requests chan string
+	var bodies chan map[string]interface{}
+	var c client.Client
+
+	BeforeEach(func() {
+		requests = make(chan string, 1)
+		bodies = make(chan map[string]interface{}, 1)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body := map[string]interface{}{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			requests <- r.Method + " " + r.URL.Path
+			bodies <- body
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(body)
+		}))
+
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(corev1.SchemeGroupVersion.WithKind("Pod"), meta.RESTScopeNamespace)
+		httpClient := &http.Client{Transport: kcpclient.NewClusterRoundTripper(http.DefaultTransport)}
+		var err error
+		c, err = client.New(&rest.Config{Host: server.URL, ContentConfig: rest.ContentConfig{ContentType: "application/json"}}, client.Options{HTTPClient: httpClient, Mapper: mapper})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should POST evictions to the logical cluster of the Pod", func() {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns", ClusterName: "root:org:ws"}}
+		Expect(client.EvictPod(context.Background(), c, pod, client.GracePeriodSeconds(5))).To(Succeed())
+
+		Expect(<-requests).To(Equal("POST /clusters/root:org:ws/api/v1/namespaces/ns/pods/pod/eviction"))
+		body := <-bodies
+		Expect(body).To(HaveKeyWithValue("kind", "Eviction"))
+		Expect(body).To(HaveKeyWithValue("apiVersion", policyv1.SchemeGroupVersion.String()))
+		Expect(body).To(HaveKeyWithValue("deleteOptions", HaveKeyWithValue("gracePeriodSeconds", BeNumerically("==", 5))))
+	})
+
+	It("should POST bindings to the logical cluster of the Pod, defaulting to the one of the context", func() {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"}}
+		ctx := kcpclient.WithCluster(context.Background(), logicalcluster.New("root:other"))
+		Expect(client.BindPod(ctx, c, pod, "node-1")).To(Succeed())
+
+		Expect(<-requests).To(Equal("POST /clusters/root:other/api/v1/namespaces/ns/pods/pod/binding"))
+		Expect(<-bodies).To(HaveKeyWithValue("target", HaveKeyWithValue("name", "node-1")))
+	})
+
+	It("should refuse the clients which can't create subresources", func() {
+		err := client.CreateSubResource(context.Background(), struct{ client.Client }{c}, &corev1.Pod{}, "eviction", &policyv1.Eviction{})
+		Expect(err).To(MatchError(ContainSubstring("cannot create subresources")))
+	})
+})
+
+var _ = Describe("SubResource", func() {
+	var server *httptest.Server
+	var requests chan string
+	var c client.Client
+
+	BeforeEach(func() {
+		requests = make(chan string, 1)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body := map[string]interface{}{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			requests <- r.Method + " " + r.URL.Path
+			if r.Method == http.MethodGet {
+				body = map[string]interface{}{"kind": "Scale", "apiVersion": "autoscaling/v1", "spec": map[string]interface{}{"replicas": 3}}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(body)
+		}))
+
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(appsv1.SchemeGroupVersion.WithKind("Deployment"), meta.RESTScopeNamespace)
+		httpClient := &http.Client{Transport: kcpclient.NewClusterRoundTripper(http.DefaultTransport)}
+		var err error
+		c, err = client.New(&rest.Config{Host: server.URL, ContentConfig: rest.ContentConfig{ContentType: "application/json"}}, client.Options{HTTPClient: httpClient, Mapper: mapper})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should read and write the scale of a Deployment in its logical cluster", func() {
+		deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "ns", ClusterName: "root:org:ws"}}
+		scale := &autoscalingv1.Scale{}
+		Expect(client.SubResource(c, "scale").Get(context.Background(), deployment, scale)).To(Succeed())
+		Expect(<-requests).To(Equal("GET /clusters/root:org:ws/apis/apps/v1/namespaces/ns/deployments/deploy/scale"))
+		Expect(scale.Spec.Replicas).To(BeEquivalentTo(3))
+
+		scale.Spec.Replicas = 5
+		Expect(client.SubResource(c, "scale").Update(context.Background(), deployment, scale)).To(Succeed())
+		Expect(<-requests).To(Equal("PUT /clusters/root:org:ws/apis/apps/v1/namespaces/ns/deployments/deploy/scale"))
+		Expect(scale.Spec.Replicas).To(BeEquivalentTo(5))
+	})
+
+	It("should write the object itself without a subresource object, defaulting to the cluster of the context", func() {
+		deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "ns"}}
+		ctx := kcpclient.WithCluster(context.Background(), logicalcluster.New("root:other"))
+		patch := client.MergeFrom(deployment.DeepCopy())
+		deployment.Status.Replicas = 2
+		Expect(client.SubResource(c, "status").Patch(ctx, deployment, nil, patch)).To(Succeed())
+		Expect(<-requests).To(Equal("PATCH /clusters/root:other/apis/apps/v1/namespaces/ns/deployments/deploy/status"))
+	})
+
+	It("should read and write the subresources through the delegating client", func() {
+		dc, err := client.NewDelegatingClient(client.NewDelegatingClientInput{CacheReader: c, Client: c})
+		Expect(err).NotTo(HaveOccurred())
+		deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "ns", ClusterName: "root:org:ws"}}
+		Expect(client.SubResource(dc, "scale").Update(context.Background(), deployment, &autoscalingv1.Scale{})).To(Succeed())
+		Expect(<-requests).To(Equal("PUT /clusters/root:org:ws/apis/apps/v1/namespaces/ns/deployments/deploy/scale"))
+	})
+
+	It("should refuse the clients which can't read or write subresources", func() {
+		err := client.SubResource(struct{ client.Client }{c}, "scale").Get(context.Background(), &appsv1.Deployment{}, &autoscalingv1.Scale{})
+		Expect(err).To(MatchError(ContainSubstring("cannot read or write subresources")))
+	})
+})
@@ -0,0 +1,205 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/tracing"
+)
+
+// tracerName is the name of the Tracer of the clients.
+const tracerName = "sigs.k8s.io/controller-runtime/pkg/client"
+
+// WithTracing wraps an existing client starting a span around each of its calls, named after
+// the call, e.g. "client.Get", with the logical cluster, kind and key of the object as
+// attributes.  The spans are children of the span of the context of the calls, e.g. the
+// span of the reconcile calling them.  The requests to the API server are only traced, and
+// the span propagated to it, if the transport of the client is wrapped, see
+// tracing.WrapConfig.
+func WithTracing(c Client, provider trace.TracerProvider) Client {
+	return &tracingClient{
+		client: c,
+		tracer: provider.Tracer(tracerName),
+	}
+}
+
+var _ Client = &tracingClient{}
+
+// tracingClient is a Client that wraps another Client in order to trace its calls.
+type tracingClient struct {
+	client Client
+	tracer trace.Tracer
+}
+
+// start starts the span of a call for an object, which may be a list, in the given cluster,
+// or in the cluster of the context if it is empty.
+func (c *tracingClient) start(ctx context.Context, call string, obj runtime.Object, cluster logicalcluster.Name, key ObjectKey) (context.Context, trace.Span) {
+	if cluster.Empty() {
+		cluster, _ = kcpclient.ClusterFromContext(ctx)
+	}
+	var attributes []attribute.KeyValue
+	if !cluster.Empty() {
+		attributes = append(attributes, tracing.AttributeCluster.String(cluster.String()))
+	}
+	if gvk, err := apiutil.GVKForObject(obj, c.client.Scheme()); err == nil {
+		attributes = append(attributes, tracing.AttributeGroupVersionKind.String(gvk.String()))
+	}
+	if key.Namespace != "" {
+		attributes = append(attributes, tracing.AttributeNamespace.String(key.Namespace))
+	}
+	if key.Name != "" {
+		attributes = append(attributes, tracing.AttributeName.String(key.Name))
+	}
+	return c.tracer.Start(ctx, "client."+call, trace.WithAttributes(attributes...))
+}
+
+// startFor starts the span of a call for an object, in its logical cluster.
+func (c *tracingClient) startFor(ctx context.Context, call string, obj Object) (context.Context, trace.Span) {
+	return c.start(ctx, call, obj, logicalcluster.From(obj), ObjectKeyFromObject(obj))
+}
+
+// endSpan records the error of the call, if any, and ends its span.
+func endSpan(span trace.Span, err error) error {
+	tracing.End(span, err)
+	return err
+}
+
+// Scheme returns the scheme this client is using.
+func (c *tracingClient) Scheme() *runtime.Scheme {
+	return c.client.Scheme()
+}
+
+// RESTMapper returns the rest mapper this client is using.
+func (c *tracingClient) RESTMapper() meta.RESTMapper {
+	return c.client.RESTMapper()
+}
+
+// Create implements client.Client.
+func (c *tracingClient) Create(ctx context.Context, obj Object, opts ...CreateOption) error {
+	ctx, span := c.startFor(ctx, "Create", obj)
+	return endSpan(span, c.client.Create(ctx, obj, opts...))
+}
+
+// CreateSubResource implements client.SubResourceCreator.
+func (c *tracingClient) CreateSubResource(ctx context.Context, obj Object, subResource string, subResourceObj Object, opts ...CreateOption) error {
+	ctx, span := c.startFor(ctx, "CreateSubResource", obj)
+	return endSpan(span, CreateSubResource(ctx, c.client, obj, subResource, subResourceObj, opts...))
+}
+
+// Update implements client.Client.
+func (c *tracingClient) Update(ctx context.Context, obj Object, opts ...UpdateOption) error {
+	ctx, span := c.startFor(ctx, "Update", obj)
+	return endSpan(span, c.client.Update(ctx, obj, opts...))
+}
+
+// Delete implements client.Client.
+func (c *tracingClient) Delete(ctx context.Context, obj Object, opts ...DeleteOption) error {
+	ctx, span := c.startFor(ctx, "Delete", obj)
+	return endSpan(span, c.client.Delete(ctx, obj, opts...))
+}
+
+// DeleteAllOf implements client.Client.
+func (c *tracingClient) DeleteAllOf(ctx context.Context, obj Object, opts ...DeleteAllOfOption) error {
+	ctx, span := c.start(ctx, "DeleteAllOf", obj, logicalcluster.From(obj), ObjectKey{})
+	return endSpan(span, c.client.DeleteAllOf(ctx, obj, opts...))
+}
+
+// Patch implements client.Client.
+func (c *tracingClient) Patch(ctx context.Context, obj Object, patch Patch, opts ...PatchOption) error {
+	ctx, span := c.startFor(ctx, "Patch", obj)
+	return endSpan(span, c.client.Patch(ctx, obj, patch, opts...))
+}
+
+// Get implements client.Client.
+func (c *tracingClient) Get(ctx context.Context, key ObjectKey, obj Object) error {
+	ctx, span := c.start(ctx, "Get", obj, key.Cluster, key)
+	return endSpan(span, c.client.Get(ctx, key, obj))
+}
+
+// List implements client.Client.
+func (c *tracingClient) List(ctx context.Context, obj ObjectList, opts ...ListOption) error {
+	listOpts := (&ListOptions{}).ApplyOptions(opts)
+	ctx, span := c.start(ctx, "List", obj, listOpts.Cluster, ObjectKey{NamespacedName: types.NamespacedName{Namespace: listOpts.Namespace}})
+	return endSpan(span, c.client.List(ctx, obj, opts...))
+}
+
+// Status implements client.StatusClient.
+func (c *tracingClient) Status() StatusWriter {
+	return &tracingStatusWriter{client: c.client.Status(), parent: c}
+}
+
+// ensure tracingStatusWriter implements client.StatusWriter.
+var _ StatusWriter = &tracingStatusWriter{}
+
+type tracingStatusWriter struct {
+	client StatusWriter
+	parent *tracingClient
+}
+
+// Update implements client.StatusWriter.
+func (sw *tracingStatusWriter) Update(ctx context.Context, obj Object, opts ...UpdateOption) error {
+	ctx, span := sw.parent.startFor(ctx, "Status.Update", obj)
+	return endSpan(span, sw.client.Update(ctx, obj, opts...))
+}
+
+// Patch implements client.StatusWriter.
+func (sw *tracingStatusWriter) Patch(ctx context.Context, obj Object, patch Patch, opts ...PatchOption) error {
+	ctx, span := sw.parent.startFor(ctx, "Status.Patch", obj)
+	return endSpan(span, sw.client.Patch(ctx, obj, patch, opts...))
+}
+
+// SubResource implements client.SubResourceClientProvider.
+func (c *tracingClient) SubResource(subResource string) SubResourceClient {
+	return &tracingSubResourceClient{client: SubResource(c.client, subResource), parent: c, call: "SubResource(" + subResource + ")."}
+}
+
+// ensure tracingSubResourceClient implements client.SubResourceClient.
+var _ SubResourceClient = &tracingSubResourceClient{}
+
+type tracingSubResourceClient struct {
+	client SubResourceClient
+	parent *tracingClient
+	call   string
+}
+
+// Get implements client.SubResourceClient.
+func (sc *tracingSubResourceClient) Get(ctx context.Context, obj Object, subResourceObj Object) error {
+	ctx, span := sc.parent.startFor(ctx, sc.call+"Get", obj)
+	return endSpan(span, sc.client.Get(ctx, obj, subResourceObj))
+}
+
+// Update implements client.SubResourceClient.
+func (sc *tracingSubResourceClient) Update(ctx context.Context, obj Object, subResourceObj Object, opts ...UpdateOption) error {
+	ctx, span := sc.parent.startFor(ctx, sc.call+"Update", obj)
+	return endSpan(span, sc.client.Update(ctx, obj, subResourceObj, opts...))
+}
+
+// Patch implements client.SubResourceClient.
+func (sc *tracingSubResourceClient) Patch(ctx context.Context, obj Object, subResourceObj Object, patch Patch, opts ...PatchOption) error {
+	ctx, span := sc.parent.startFor(ctx, sc.call+"Patch", obj)
+	return endSpan(span, sc.client.Patch(ctx, obj, subResourceObj, patch, opts...))
+}
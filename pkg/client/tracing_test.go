@@ -0,0 +1,68 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client_test
+
+import (
+	"context"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/oteltest"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/tracing"
+)
+
+var _ = Describe("WithTracing", func() {
+	It("should start a span around each call, child of the span of the context", func() {
+		sr := new(oteltest.SpanRecorder)
+		provider := oteltest.NewTracerProvider(oteltest.WithSpanRecorder(sr))
+		c := client.WithTracing(fake.NewClientBuilder().Build(), provider)
+
+		ctx, parent := provider.Tracer("test").Start(kcpclient.WithCluster(context.Background(), logicalcluster.New("root:org")), "reconcile")
+		cm := &corev1.ConfigMap{}
+		cm.Name = "cm"
+		cm.Namespace = "default"
+		Expect(c.Create(ctx, cm)).To(Succeed())
+		err := c.Get(ctx, client.ObjectKey{NamespacedName: client.ObjectKeyFromObject(cm).NamespacedName}, &corev1.Secret{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		parent.End()
+
+		spans := sr.Completed()
+		Expect(spans).To(HaveLen(3))
+		Expect(spans[0].Name()).To(Equal("client.Create"))
+		Expect(spans[0].ParentSpanID()).To(Equal(parent.SpanContext().SpanID()))
+		Expect(spans[0].Attributes()).To(Equal(map[attribute.Key]attribute.Value{
+			tracing.AttributeCluster:          attribute.StringValue("root:org"),
+			tracing.AttributeGroupVersionKind: attribute.StringValue("/v1, Kind=ConfigMap"),
+			tracing.AttributeNamespace:        attribute.StringValue("default"),
+			tracing.AttributeName:             attribute.StringValue("cm"),
+		}))
+		Expect(spans[0].StatusCode()).To(Equal(codes.Unset))
+
+		Expect(spans[1].Name()).To(Equal("client.Get"))
+		Expect(spans[1].Attributes()).To(HaveKeyWithValue(tracing.AttributeGroupVersionKind, attribute.StringValue("/v1, Kind=Secret")))
+		Expect(spans[1].StatusCode()).To(Equal(codes.Error))
+	})
+})
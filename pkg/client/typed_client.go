@@ -51,6 +51,26 @@ func (c *typedClient) Create(ctx context.Context, obj Object, opts ...CreateOpti
 		Into(obj)
 }
 
+// CreateSubResource used by client.SubResourceCreator to create subresources.
+func (c *typedClient) CreateSubResource(ctx context.Context, obj Object, subResource string, subResourceObj Object, opts ...CreateOption) error {
+	o, err := c.cache.getObjMeta(obj)
+	if err != nil {
+		return err
+	}
+
+	createOpts := &CreateOptions{}
+	createOpts.ApplyOptions(opts)
+	return o.Post().
+		NamespaceIfScoped(o.GetNamespace(), o.isNamespaced()).
+		Resource(o.resource()).
+		Name(o.GetName()).
+		SubResource(subResource).
+		Body(subResourceObj).
+		VersionedParams(createOpts.AsCreateOptions(), c.paramCodec).
+		Do(ctx).
+		Into(subResourceObj)
+}
+
 // Update implements client.Client.
 func (c *typedClient) Update(ctx context.Context, obj Object, opts ...UpdateOption) error {
 	o, err := c.cache.getObjMeta(obj)
@@ -161,6 +181,32 @@ func (c *typedClient) List(ctx context.Context, obj ObjectList, opts ...ListOpti
 
 // UpdateStatus used by StatusWriter to write status.
 func (c *typedClient) UpdateStatus(ctx context.Context, obj Object, opts ...UpdateOption) error {
+	return c.UpdateSubResource(ctx, obj, "status", obj, opts...)
+}
+
+// PatchStatus used by StatusWriter to write status.
+func (c *typedClient) PatchStatus(ctx context.Context, obj Object, patch Patch, opts ...PatchOption) error {
+	return c.PatchSubResource(ctx, obj, "status", obj, patch, opts...)
+}
+
+// GetSubResource used by client.SubResourceClient to read subresources.
+func (c *typedClient) GetSubResource(ctx context.Context, obj Object, subResource string, subResourceObj Object) error {
+	o, err := c.cache.getObjMeta(obj)
+	if err != nil {
+		return err
+	}
+
+	return o.Get().
+		NamespaceIfScoped(o.GetNamespace(), o.isNamespaced()).
+		Resource(o.resource()).
+		Name(o.GetName()).
+		SubResource(subResource).
+		Do(ctx).
+		Into(subResourceObj)
+}
+
+// UpdateSubResource used by client.SubResourceClient to write subresources.
+func (c *typedClient) UpdateSubResource(ctx context.Context, obj Object, subResource string, subResourceObj Object, opts ...UpdateOption) error {
 	o, err := c.cache.getObjMeta(obj)
 	if err != nil {
 		return err
@@ -173,21 +219,21 @@ func (c *typedClient) UpdateStatus(ctx context.Context, obj Object, opts ...Upda
 		NamespaceIfScoped(o.GetNamespace(), o.isNamespaced()).
 		Resource(o.resource()).
 		Name(o.GetName()).
-		SubResource("status").
-		Body(obj).
+		SubResource(subResource).
+		Body(subResourceObj).
 		VersionedParams((&UpdateOptions{}).ApplyOptions(opts).AsUpdateOptions(), c.paramCodec).
 		Do(ctx).
-		Into(obj)
+		Into(subResourceObj)
 }
 
-// PatchStatus used by StatusWriter to write status.
-func (c *typedClient) PatchStatus(ctx context.Context, obj Object, patch Patch, opts ...PatchOption) error {
+// PatchSubResource used by client.SubResourceClient to patch subresources.
+func (c *typedClient) PatchSubResource(ctx context.Context, obj Object, subResource string, subResourceObj Object, patch Patch, opts ...PatchOption) error {
 	o, err := c.cache.getObjMeta(obj)
 	if err != nil {
 		return err
 	}
 
-	data, err := patch.Data(obj)
+	data, err := patch.Data(subResourceObj)
 	if err != nil {
 		return err
 	}
@@ -197,9 +243,9 @@ func (c *typedClient) PatchStatus(ctx context.Context, obj Object, patch Patch,
 		NamespaceIfScoped(o.GetNamespace(), o.isNamespaced()).
 		Resource(o.resource()).
 		Name(o.GetName()).
-		SubResource("status").
+		SubResource(subResource).
 		Body(data).
 		VersionedParams(patchOpts.ApplyOptions(opts).AsPatchOptions(), c.paramCodec).
 		Do(ctx).
-		Into(obj)
+		Into(subResourceObj)
 }
@@ -64,6 +64,39 @@ func (uc *unstructuredClient) Create(ctx context.Context, obj Object, opts ...Cr
 	return result
 }
 
+// CreateSubResource used by client.SubResourceCreator to create subresources.
+func (uc *unstructuredClient) CreateSubResource(ctx context.Context, obj Object, subResource string, subResourceObj Object, opts ...CreateOption) error {
+	if _, ok := obj.(*unstructured.Unstructured); !ok {
+		return fmt.Errorf("unstructured client did not understand object: %T", obj)
+	}
+	u, ok := subResourceObj.(*unstructured.Unstructured)
+	if !ok {
+		return fmt.Errorf("unstructured client did not understand subresource object: %T", subResourceObj)
+	}
+
+	gvk := u.GroupVersionKind()
+
+	o, err := uc.cache.getObjMeta(obj)
+	if err != nil {
+		return err
+	}
+
+	createOpts := &CreateOptions{}
+	createOpts.ApplyOptions(opts)
+	result := o.Post().
+		NamespaceIfScoped(o.GetNamespace(), o.isNamespaced()).
+		Resource(o.resource()).
+		Name(o.GetName()).
+		SubResource(subResource).
+		Body(subResourceObj).
+		VersionedParams(createOpts.AsCreateOptions(), uc.paramCodec).
+		Do(ctx).
+		Into(subResourceObj)
+
+	u.SetGroupVersionKind(gvk)
+	return result
+}
+
 // Update implements client.Client.
 func (uc *unstructuredClient) Update(ctx context.Context, obj Object, opts ...UpdateOption) error {
 	u, ok := obj.(*unstructured.Unstructured)
@@ -222,10 +255,44 @@ func (uc *unstructuredClient) List(ctx context.Context, obj ObjectList, opts ...
 }
 
 func (uc *unstructuredClient) UpdateStatus(ctx context.Context, obj Object, opts ...UpdateOption) error {
-	_, ok := obj.(*unstructured.Unstructured)
-	if !ok {
+	return uc.UpdateSubResource(ctx, obj, "status", obj, opts...)
+}
+
+func (uc *unstructuredClient) PatchStatus(ctx context.Context, obj Object, patch Patch, opts ...PatchOption) error {
+	return uc.PatchSubResource(ctx, obj, "status", obj, patch, opts...)
+}
+
+// GetSubResource used by client.SubResourceClient to read subresources.
+func (uc *unstructuredClient) GetSubResource(ctx context.Context, obj Object, subResource string, subResourceObj Object) error {
+	if _, ok := obj.(*unstructured.Unstructured); !ok {
+		return fmt.Errorf("unstructured client did not understand object: %T", obj)
+	}
+	if _, ok := subResourceObj.(*unstructured.Unstructured); !ok {
+		return fmt.Errorf("unstructured client did not understand subresource object: %T", subResourceObj)
+	}
+
+	o, err := uc.cache.getObjMeta(obj)
+	if err != nil {
+		return err
+	}
+
+	return o.Get().
+		NamespaceIfScoped(o.GetNamespace(), o.isNamespaced()).
+		Resource(o.resource()).
+		Name(o.GetName()).
+		SubResource(subResource).
+		Do(ctx).
+		Into(subResourceObj)
+}
+
+// UpdateSubResource used by client.SubResourceClient to write subresources.
+func (uc *unstructuredClient) UpdateSubResource(ctx context.Context, obj Object, subResource string, subResourceObj Object, opts ...UpdateOption) error {
+	if _, ok := obj.(*unstructured.Unstructured); !ok {
 		return fmt.Errorf("unstructured client did not understand object: %T", obj)
 	}
+	if _, ok := subResourceObj.(*unstructured.Unstructured); !ok {
+		return fmt.Errorf("unstructured client did not understand subresource object: %T", subResourceObj)
+	}
 
 	o, err := uc.cache.getObjMeta(obj)
 	if err != nil {
@@ -236,18 +303,22 @@ func (uc *unstructuredClient) UpdateStatus(ctx context.Context, obj Object, opts
 		NamespaceIfScoped(o.GetNamespace(), o.isNamespaced()).
 		Resource(o.resource()).
 		Name(o.GetName()).
-		SubResource("status").
-		Body(obj).
+		SubResource(subResource).
+		Body(subResourceObj).
 		VersionedParams((&UpdateOptions{}).ApplyOptions(opts).AsUpdateOptions(), uc.paramCodec).
 		Do(ctx).
-		Into(obj)
+		Into(subResourceObj)
 }
 
-func (uc *unstructuredClient) PatchStatus(ctx context.Context, obj Object, patch Patch, opts ...PatchOption) error {
-	u, ok := obj.(*unstructured.Unstructured)
-	if !ok {
+// PatchSubResource used by client.SubResourceClient to patch subresources.
+func (uc *unstructuredClient) PatchSubResource(ctx context.Context, obj Object, subResource string, subResourceObj Object, patch Patch, opts ...PatchOption) error {
+	if _, ok := obj.(*unstructured.Unstructured); !ok {
 		return fmt.Errorf("unstructured client did not understand object: %T", obj)
 	}
+	u, ok := subResourceObj.(*unstructured.Unstructured)
+	if !ok {
+		return fmt.Errorf("unstructured client did not understand subresource object: %T", subResourceObj)
+	}
 
 	gvk := u.GroupVersionKind()
 
@@ -256,7 +327,7 @@ func (uc *unstructuredClient) PatchStatus(ctx context.Context, obj Object, patch
 		return err
 	}
 
-	data, err := patch.Data(obj)
+	data, err := patch.Data(subResourceObj)
 	if err != nil {
 		return err
 	}
@@ -266,7 +337,7 @@ func (uc *unstructuredClient) PatchStatus(ctx context.Context, obj Object, patch
 		NamespaceIfScoped(o.GetNamespace(), o.isNamespaced()).
 		Resource(o.resource()).
 		Name(o.GetName()).
-		SubResource("status").
+		SubResource(subResource).
 		Body(data).
 		VersionedParams(patchOpts.ApplyOptions(opts).AsPatchOptions(), uc.paramCodec).
 		Do(ctx).
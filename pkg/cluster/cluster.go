@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/trace"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -33,6 +34,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	intrec "sigs.k8s.io/controller-runtime/pkg/internal/recorder"
+	"sigs.k8s.io/controller-runtime/pkg/tracing"
 )
 
 // Cluster provides various methods to interact with a cluster.
@@ -114,14 +116,47 @@ type Options struct {
 	// use the cache for reads and the client for writes.
 	NewClient NewClientFunc
 
+	// NewAPIReader is the func that creates the reader returned by GetAPIReader, reading
+	// from the API server rather than the cache.  If not set this will use a client
+	// built with client.New.
+	NewAPIReader NewAPIReaderFunc
+
 	// ClientDisableCacheFor tells the client that, if any cache is used, to bypass it
 	// for the given objects.
 	ClientDisableCacheFor []client.Object
 
+	// ClientDisableCacheByCluster tells the client to bypass the cache for the given objects
+	// of the given logical clusters only, and to read them with the API reader instead, e.g.
+	// to read the Secrets of some workspaces live.  See client.UncachedByCluster.
+	ClientDisableCacheByCluster client.UncachedByCluster
+
 	// DryRunClient specifies whether the client should be configured to enforce
 	// dryRun mode.
 	DryRunClient bool
 
+	// DiagnoseConflicts makes the client diagnose the conflicts of updates and patches,
+	// comparing the resourceVersion of the written object to those of the cache and of
+	// the API server, to tell stale caches from concurrent writers.  The diagnoses are
+	// logged, and returned in client.ConflictErrors.
+	DiagnoseConflicts bool
+
+	// FieldOwner is the field manager set on the creations, updates and patches of the client,
+	// unless they set another one, see client.WithFieldOwner.  Use it with
+	// predicate.IgnoreUpdatesBy to ignore the updates of objects caused by the writes of the
+	// client.
+	FieldOwner string
+
+	// TracerProvider, if set, makes the client start a span around each of its calls, see
+	// client.WithTracing, and propagate it in the headers of its requests to the API
+	// server, see tracing.WrapConfig.
+	TracerProvider trace.TracerProvider
+
+	// ImpersonateByCluster, if set, makes the requests of the client and of the API reader to
+	// each logical cluster impersonate the user it returns for the cluster, e.g. the service
+	// account of the tenant of the workspace.  The cache keeps the credentials of the config.
+	// See client.Options.ImpersonateByCluster.
+	ImpersonateByCluster client.ClusterImpersonation
+
 	// EventBroadcaster records Events emitted by the manager and sends them to the Kubernetes API
 	// Use this to customize the event correlator and spam filter
 	//
@@ -167,22 +202,46 @@ func New(config *rest.Config, opts ...Option) (Cluster, error) {
 		return nil, err
 	}
 
-	clientOptions := client.Options{Scheme: options.Scheme, Mapper: mapper}
+	clientOptions := client.Options{Scheme: options.Scheme, Mapper: mapper, ImpersonateByCluster: options.ImpersonateByCluster}
+
+	clientConfig := config
+	if options.TracerProvider != nil {
+		clientConfig = tracing.WrapConfig(config, options.TracerProvider)
+	}
 
-	apiReader, err := client.New(config, clientOptions)
+	apiReader, err := options.NewAPIReader(clientConfig, clientOptions)
 	if err != nil {
 		return nil, err
 	}
 
-	writeObj, err := options.NewClient(cache, config, clientOptions, options.ClientDisableCacheFor...)
+	writeObj, err := options.NewClient(cache, clientConfig, clientOptions, options.ClientDisableCacheFor...)
 	if err != nil {
 		return nil, err
 	}
 
+	if len(options.ClientDisableCacheByCluster) > 0 {
+		writeObj, err = client.WithUncachedByCluster(writeObj, apiReader, options.ClientDisableCacheByCluster)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if options.FieldOwner != "" {
+		writeObj = client.WithFieldOwner(writeObj, options.FieldOwner)
+	}
+
+	if options.DiagnoseConflicts {
+		writeObj = client.NewConflictDiagnosingClient(writeObj, cache, apiReader)
+	}
+
 	if options.DryRunClient {
 		writeObj = client.NewDryRunClient(writeObj)
 	}
 
+	if options.TracerProvider != nil {
+		writeObj = client.WithTracing(writeObj, options.TracerProvider)
+	}
+
 	// Create the recorder provider to inject event recorders for the components.
 	// TODO(directxman12): the log for the event provider should have a context (name, tags, etc) specific
 	// to the particular controller that it's being injected into, rather than a generic one like is here.
@@ -222,6 +281,10 @@ func setOptionsDefaults(options Options) Options {
 		options.NewClient = DefaultNewClient
 	}
 
+	if options.NewAPIReader == nil {
+		options.NewAPIReader = DefaultNewAPIReader
+	}
+
 	// Allow newCache to be mocked
 	if options.NewCache == nil {
 		options.NewCache = cache.New
@@ -252,6 +315,14 @@ func setOptionsDefaults(options Options) Options {
 	return options
 }
 
+// NewAPIReaderFunc allows a user to define how to create the reader reading from the API server.
+type NewAPIReaderFunc func(config *rest.Config, options client.Options) (client.Reader, error)
+
+// DefaultNewAPIReader creates the default reader reading from the API server.
+func DefaultNewAPIReader(config *rest.Config, options client.Options) (client.Reader, error) {
+	return client.New(config, options)
+}
+
 // NewClientFunc allows a user to define how to create a client.
 type NewClientFunc func(cache cache.Cache, config *rest.Config, options client.Options, uncachedObjects ...client.Object) (client.Client, error)
 
@@ -0,0 +1,811 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	"k8s.io/apimachinery/pkg/runtime"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	logf "sigs.k8s.io/controller-runtime/pkg/internal/log"
+)
+
+var setLog = logf.RuntimeLog.WithName("cluster-set")
+
+// ClusterSetHandler is notified when clusters are added to, or removed from, a ClusterSet.
+// Controllers typically use it to start watching the cache of a cluster when it is added.
+type ClusterSetHandler interface {
+	// ClusterAdded is called once the Cluster of a logical cluster has been created.  If the
+	// ClusterSet is started, the Cluster is starting too.
+	ClusterAdded(name logicalcluster.Name, cl Cluster)
+
+	// ClusterRemoved is called once the Cluster of a logical cluster has been stopped: the
+	// informers of its cache are stopped, so the event handlers registered on them, e.g. by
+	// the sources of controllers, won't be called anymore.
+	ClusterRemoved(name logicalcluster.Name)
+}
+
+// ClusterFailedHandler is implemented by the ClusterSetHandlers which are notified when the
+// Cluster of a logical cluster fails, e.g. because its cache stopped with an error.  The
+// Cluster stays in the set, stopped, until it is removed.
+type ClusterFailedHandler interface {
+	ClusterFailed(name logicalcluster.Name, err error)
+}
+
+// ClusterSetHandlerFuncs is an adaptor to let you easily specify as many or as few of the
+// notification functions as you want while still implementing ClusterSetHandler.
+type ClusterSetHandlerFuncs struct {
+	AddFunc    func(name logicalcluster.Name, cl Cluster)
+	RemoveFunc func(name logicalcluster.Name)
+}
+
+// ClusterAdded calls AddFunc if it's not nil.
+func (f ClusterSetHandlerFuncs) ClusterAdded(name logicalcluster.Name, cl Cluster) {
+	if f.AddFunc != nil {
+		f.AddFunc(name, cl)
+	}
+}
+
+// ClusterRemoved calls RemoveFunc if it's not nil.
+func (f ClusterSetHandlerFuncs) ClusterRemoved(name logicalcluster.Name) {
+	if f.RemoveFunc != nil {
+		f.RemoveFunc(name)
+	}
+}
+
+// ClusterConfigFunc returns the config of the Cluster of the given cluster name, from the base
+// config of a ClusterSet.  It must not modify the base config.
+type ClusterConfigFunc func(base *rest.Config, clusterName string) (*rest.Config, error)
+
+// ClusterConfigs are the configs of some clusters, each used instead of the base config, e.g.
+// for a fleet of physical clusters.  Their ClusterConfig method is a ClusterConfigFunc.
+type ClusterConfigs map[logicalcluster.Name]*rest.Config
+
+var _ ClusterConfigFunc = ClusterConfigs(nil).ClusterConfig
+
+// ClusterConfig returns a copy of the config of the cluster name, ignoring the base config.
+// It fails for the cluster names without a config.
+func (c ClusterConfigs) ClusterConfig(_ *rest.Config, clusterName string) (*rest.Config, error) {
+	config, ok := c[logicalcluster.New(clusterName)]
+	if !ok {
+		return nil, fmt.Errorf("no config for cluster %s", clusterName)
+	}
+	return rest.CopyConfig(config), nil
+}
+
+// ClusterSchemes are the schemes of some logical clusters, see ClusterSetOptions.Schemes.
+type ClusterSchemes map[logicalcluster.Name]*runtime.Scheme
+
+// KCPClusterConfig is the default ClusterConfigFunc of a ClusterSet: it targets the
+// /clusters/<name> path of the kcp server the base config targets.
+func KCPClusterConfig(base *rest.Config, clusterName string) (*rest.Config, error) {
+	config := rest.CopyConfig(base)
+	config.Host = strings.TrimSuffix(config.Host, "/") + logicalcluster.New(clusterName).Path()
+	return config, nil
+}
+
+// ClusterSetOptions are the options of a ClusterSet.
+type ClusterSetOptions struct {
+	// ClusterConfig returns the config of the Cluster of each cluster name, so that the
+	// clusters can use their own host, credentials, TLS settings or impersonation, e.g.
+	// to span several physical clusters rather than the logical clusters of a single
+	// kcp server, see ClusterConfigs.  It defaults to KCPClusterConfig.
+	ClusterConfig ClusterConfigFunc
+
+	// CacheFactory, if set, builds the cache of the Cluster of each logical cluster instead of
+	// the NewCache of its options, e.g. to substitute the caches of another topology than the
+	// logical clusters of a kcp server.  CacheByCluster still scopes the caches it builds.
+	CacheFactory cache.CacheFactory
+
+	// Schemes are the schemes of the Clusters of some logical clusters, e.g. of the workspaces
+	// with API groups of their own, used by their caches and clients instead of the Scheme of
+	// the options of the set, which the other logical clusters keep using.  A scheme usually
+	// holds the types of the Scheme of the options too.  The caches shared across the logical
+	// clusters, e.g. those of a kcp.SharedWildcardCache, keep their own scheme.
+	Schemes ClusterSchemes
+
+	// ClusterOptions, if set, returns options for the Cluster of each logical cluster, which
+	// are applied after the options of the set.  It allows e.g. building the caches of some
+	// logical clusters with their own cache.Options, such as transforms stripping more of
+	// the objects of the tenant workspaces, with cache.BuilderWithOptions.
+	ClusterOptions func(name logicalcluster.Name) []Option
+
+	// CacheByCluster, if set, scopes the cache of the Cluster of each logical cluster with
+	// its namespaces and selectors, e.g. to only cache the labeled objects of the tenant
+	// workspaces.  It wraps the NewCache of the options of the cluster, see cache.ByCluster.
+	CacheByCluster cache.ByCluster
+
+	// MaxFailedClusters, if set, is the number of clusters which may fail, i.e. whose Start
+	// returns an error while they are part of the set, before the set stops all of its
+	// clusters and Start returns their errors.  0 fails fast, on the first failing cluster.
+	// Defaults to tolerating any number of failures.  The clusters which failed are reported
+	// by Failed.
+	MaxFailedClusters *int
+
+	// RateLimit, if set, returns the QPS and Burst of the client-go rate limiter of the
+	// Cluster of each logical cluster, overriding those of its config, e.g. so that the
+	// clusters of a large set don't multiply the traffic allowed by the base config.  A
+	// zero QPS or Burst keeps the one of the config.
+	RateLimit func(name logicalcluster.Name) (qps float32, burst int)
+
+	// Impersonate, if set, makes the Cluster of each logical cluster impersonate the user it
+	// returns for the cluster, e.g. the service account of the tenant of the workspace, with
+	// the config of the cluster, so that its cache and client are limited to the RBAC of the
+	// tenant.  The credentials of the base config must be allowed to impersonate the users.
+	Impersonate client.ClusterImpersonation
+
+	// ShareTransport makes the clusters whose configs target the same server, e.g. the kcp
+	// front proxy, with the same TLS settings use a single HTTP transport, and so a single
+	// pool of connections, rather than one per cluster.  The credentials of the configs
+	// still apply per cluster.  The configs setting a Transport or an ExecProvider keep
+	// their own transport.
+	ShareTransport bool
+
+	// MaxConnsPerHost limits the number of connections of each shared transport, see
+	// ShareTransport.  0 means no limit.
+	MaxConnsPerHost int
+
+	// Gate, if set, holds the requests of each cluster from the time it is started until
+	// its cache is synced, so that the controllers using it as their SyncGate don't
+	// reconcile the requests of the clusters which are (re)added with stale data.
+	Gate *Gate
+
+	// RetryConstruction, if set, makes the set retry creating the Cluster of the logical
+	// clusters for which it fails, e.g. because the discovery of their APIs fails, in the
+	// background with the given backoff, rather than only failing Add.  The retries go on
+	// until the Cluster is created or the logical cluster is removed, the delay growing by
+	// the Factor of the backoff for its first Steps retries, up to its Cap.  Meanwhile Sync doesn't
+	// return the error, the set serves the other clusters, and the logical cluster is reported
+	// as not synced by ClusterStatuses, with its error, and by Pending.
+	RetryConstruction *wait.Backoff
+}
+
+// ClusterSet manages one Cluster per kcp logical cluster, for logical clusters which are
+// only known at runtime.  Clusters are added and removed with Add, Remove and Sync, and
+// are running as long as they are part of the set and the set is started.
+//
+// A ClusterSet is a Runnable which doesn't need leader election, so that it can be added
+// to a manager.  It is a healthz.ClusterStatuser reporting the state of the caches of its
+// clusters.
+type ClusterSet struct {
+	options ClusterSetOptions
+
+	config     *rest.Config
+	opts       []Option
+	transports transportPool
+
+	// newCluster constructs the clusters, it is New unless overridden in tests.
+	newCluster func(config *rest.Config, opts ...Option) (Cluster, error)
+
+	mu       sync.Mutex
+	ctx      context.Context
+	abort    context.CancelFunc
+	members  map[logicalcluster.Name]*setMember
+	adding   map[logicalcluster.Name]*addition
+	failed   map[logicalcluster.Name]error
+	pending  map[logicalcluster.Name]*pendingCluster
+	handlers []ClusterSetHandler
+	indexes  []setIndex
+
+	// registrations are the event handlers registered on the informers of the clusters,
+	// and of the clusters added later on, see AddEventHandler.
+	registrations []*EventHandlerRegistration
+}
+
+// addition is a logical cluster the Cluster of which is being created by Add, without the
+// mutex of the set held.
+type addition struct {
+	// done is closed once the Cluster is added, or failed to be.
+	done chan struct{}
+
+	// removed is whether the logical cluster was removed meanwhile, so that the Cluster must
+	// not be added.
+	removed bool
+}
+
+// setIndex is an index added to the caches of the clusters of a ClusterSet, see IndexField.
+type setIndex struct {
+	obj          client.Object
+	field        string
+	extractValue client.IndexerFunc
+}
+
+var _ client.FieldIndexer = &ClusterSet{}
+
+// pendingCluster is a logical cluster the Cluster of which couldn't be created, and is
+// created again later on, see ClusterSetOptions.RetryConstruction.
+type pendingCluster struct {
+	err     error
+	backoff wait.Backoff
+	timer   *time.Timer
+}
+
+// setMember is a Cluster of a ClusterSet, along with what is needed to stop it.
+type setMember struct {
+	name    logicalcluster.Name
+	cluster Cluster
+
+	// cancel stops the cluster, and done is closed once it is stopped.  They are
+	// nil until the cluster is started.
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// synced is whether the cache of the cluster is synced.  It is guarded by the mutex of
+	// the set.
+	synced bool
+}
+
+// NewClusterSet returns an empty ClusterSet.  By default, the config must target the root of
+// the kcp server, and the Cluster of each logical cluster targets the /clusters/<name> path
+// under it, see ClusterSetOptions.ClusterConfig.  The opts are used to create every Cluster.
+func NewClusterSet(config *rest.Config, options ClusterSetOptions, opts ...Option) (*ClusterSet, error) {
+	if config == nil {
+		return nil, errors.New("must specify Config")
+	}
+	if options.ClusterConfig == nil {
+		options.ClusterConfig = KCPClusterConfig
+	}
+	return &ClusterSet{
+		options:    options,
+		config:     config,
+		opts:       opts,
+		newCluster: New,
+		members:    map[logicalcluster.Name]*setMember{},
+		adding:     map[logicalcluster.Name]*addition{},
+		failed:     map[logicalcluster.Name]error{},
+		pending:    map[logicalcluster.Name]*pendingCluster{},
+	}, nil
+}
+
+// Add creates the Cluster of the logical cluster, starts it if the set is started, and
+// notifies the handlers.  If the set already has a Cluster for the logical cluster, it is
+// returned and nothing else happens.  If the Cluster can't be created, Add fails, and the
+// set retries creating it in the background with RetryConstruction, if set.
+//
+// The Cluster is created without blocking the set, e.g. while the APIs of the logical
+// cluster are discovered: the concurrent calls adding the same logical cluster wait for
+// the first one, and a Remove of the logical cluster meanwhile makes Add fail.
+func (s *ClusterSet) Add(name logicalcluster.Name) (Cluster, error) {
+	if name.Empty() || name == logicalcluster.Wildcard {
+		return nil, errors.New("must specify a single logical cluster")
+	}
+
+	s.mu.Lock()
+	for {
+		if m, ok := s.members[name]; ok {
+			s.mu.Unlock()
+			return m.cluster, nil
+		}
+		a, ok := s.adding[name]
+		if !ok {
+			break
+		}
+		s.mu.Unlock()
+		<-a.done
+		s.mu.Lock()
+	}
+	a := &addition{done: make(chan struct{})}
+	s.adding[name] = a
+	indexes := s.indexes
+	s.mu.Unlock()
+
+	cl, err := s.construct(name, indexes)
+
+	s.mu.Lock()
+	delete(s.adding, name)
+	close(a.done)
+	if a.removed {
+		// don't retry creating the cluster either, whatever the error.
+		s.mu.Unlock()
+		return nil, fmt.Errorf("cluster %s was removed while being added", name)
+	}
+	if err == nil {
+		// index the cluster with the indexes added meanwhile too.
+		err = indexCluster(name, cl, s.indexes[len(indexes):])
+	}
+	if err != nil {
+		if s.options.RetryConstruction != nil {
+			s.retryLater(name, err)
+		}
+		s.mu.Unlock()
+		return nil, err
+	}
+	if p, ok := s.pending[name]; ok {
+		p.timer.Stop()
+		delete(s.pending, name)
+	}
+	m := &setMember{name: name, cluster: cl}
+	s.members[name] = m
+	if s.ctx != nil {
+		s.start(m)
+	}
+	handlers := append([]ClusterSetHandler(nil), s.handlers...)
+	registrations := append([]*EventHandlerRegistration(nil), s.registrations...)
+	s.mu.Unlock()
+
+	for _, r := range registrations {
+		r.register(name, cl)
+	}
+	for _, h := range handlers {
+		h.ClusterAdded(name, cl)
+	}
+	return cl, nil
+}
+
+// construct creates the Cluster of the logical cluster, with the given indexes.  s.mu must
+// not be held.
+func (s *ClusterSet) construct(name logicalcluster.Name, indexes []setIndex) (Cluster, error) {
+	config, err := s.options.ClusterConfig(s.config, name.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the config of cluster %s: %w", name, err)
+	}
+	config, err = s.tuneConfig(name, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the config of cluster %s: %w", name, err)
+	}
+	opts := append([]Option(nil), s.opts...)
+	if scheme := s.options.Schemes[name]; scheme != nil {
+		opts = append(opts, func(o *Options) { o.Scheme = scheme })
+	}
+	if s.options.ClusterOptions != nil {
+		opts = append(opts, s.options.ClusterOptions(name)...)
+	}
+	if s.options.CacheFactory != nil {
+		factory := s.options.CacheFactory
+		opts = append(opts, func(o *Options) { o.NewCache = cache.BuilderFor(factory, name) })
+	}
+	if s.options.CacheByCluster != nil {
+		byCluster := s.options.CacheByCluster
+		opts = append(opts, func(o *Options) { o.NewCache = byCluster.Builder(name, o.NewCache) })
+	}
+	cl, err := s.newCluster(config, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := indexCluster(name, cl, indexes); err != nil {
+		return nil, err
+	}
+	return cl, nil
+}
+
+// indexCluster adds the indexes to the cache of the Cluster of the logical cluster.  The
+// cluster isn't started yet, so adding the indexes doesn't wait for its informers.
+func indexCluster(name logicalcluster.Name, cl Cluster, indexes []setIndex) error {
+	for _, index := range indexes {
+		if err := cl.GetCache().IndexField(context.Background(), index.obj, index.field, index.extractValue); err != nil {
+			return fmt.Errorf("failed to index field %q of cluster %s: %w", index.field, name, err)
+		}
+	}
+	return nil
+}
+
+// retryLater records that the Cluster of the logical cluster couldn't be created, and
+// retries creating it after the next delay of its backoff.  s.mu must be held.
+func (s *ClusterSet) retryLater(name logicalcluster.Name, err error) {
+	p, ok := s.pending[name]
+	if ok {
+		p.timer.Stop()
+	} else {
+		p = &pendingCluster{backoff: *s.options.RetryConstruction}
+		s.pending[name] = p
+	}
+	p.err = err
+	delay := p.backoff.Step()
+	setLog.Error(err, "Failed to create cluster, retrying", "cluster", name.String(), "after", delay)
+	p.timer = time.AfterFunc(delay, func() {
+		s.mu.Lock()
+		current := s.pending[name]
+		s.mu.Unlock()
+		if current != p {
+			// the cluster was created or removed meanwhile.
+			return
+		}
+		_, _ = s.Add(name)
+	})
+}
+
+// Pending returns the errors of the logical clusters the Cluster of which couldn't be created,
+// and is being created again, see RetryConstruction.
+func (s *ClusterSet) Pending() map[logicalcluster.Name]error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending := make(map[logicalcluster.Name]error, len(s.pending))
+	for name, p := range s.pending {
+		pending[name] = p.err
+	}
+	return pending
+}
+
+// Remove stops the Cluster of the logical cluster, removes it from the set, and notifies
+// the handlers.  It returns false if the set has no Cluster for the logical cluster.  It
+// stops retrying to create the Cluster of the logical cluster too, see RetryConstruction,
+// and makes the Add creating it meanwhile, if any, fail.
+//
+// It's meant for the logical clusters which are deleted, e.g. workspaces: Remove cancels the
+// context of the Cluster and returns once its informers are stopped and the objects they
+// cached are dropped, so that nothing keeps running or holds the memory of the cluster.
+func (s *ClusterSet) Remove(name logicalcluster.Name) bool {
+	s.mu.Lock()
+	if p, ok := s.pending[name]; ok {
+		p.timer.Stop()
+		delete(s.pending, name)
+	}
+	if a, ok := s.adding[name]; ok {
+		a.removed = true
+	}
+	m, ok := s.members[name]
+	if !ok {
+		s.mu.Unlock()
+		return false
+	}
+	delete(s.members, name)
+	delete(s.failed, name)
+	handlers := append([]ClusterSetHandler(nil), s.handlers...)
+	registrations := append([]*EventHandlerRegistration(nil), s.registrations...)
+	s.mu.Unlock()
+
+	for _, r := range registrations {
+		r.unregister(name)
+	}
+	m.stop()
+	for _, h := range handlers {
+		h.ClusterRemoved(name)
+	}
+	return true
+}
+
+// Sync makes the set hold a Cluster for exactly the given logical clusters: the missing
+// ones are added, and the ones which are not given anymore are removed.  It returns the
+// errors of the clusters which could not be added, unless they are retried, see
+// RetryConstruction.
+func (s *ClusterSet) Sync(names []logicalcluster.Name) error {
+	wanted := make(map[logicalcluster.Name]struct{}, len(names))
+	for _, name := range names {
+		wanted[name] = struct{}{}
+	}
+	for _, name := range s.Names() {
+		if _, ok := wanted[name]; !ok {
+			s.Remove(name)
+		}
+	}
+	for name := range s.Pending() {
+		if _, ok := wanted[name]; !ok {
+			s.Remove(name)
+		}
+	}
+
+	var errs []error
+	for _, name := range names {
+		if _, err := s.Add(name); err != nil && s.options.RetryConstruction == nil {
+			errs = append(errs, err)
+		}
+	}
+	return kerrors.NewAggregate(errs)
+}
+
+// Get returns the Cluster of the logical cluster, if the set has one.
+func (s *ClusterSet) Get(name logicalcluster.Name) (Cluster, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.members[name]
+	if !ok {
+		return nil, false
+	}
+	return m.cluster, true
+}
+
+// Reader returns the cache of the Cluster of the logical cluster, and fails if the set doesn't
+// have one.  It can be used as the ClusterReader of admission webhooks.
+func (s *ClusterSet) Reader(name logicalcluster.Name) (client.Reader, error) {
+	cl, ok := s.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("logical cluster %q is not in the cluster set", name)
+	}
+	return cl.GetCache(), nil
+}
+
+// ClusterInformers returns the informers of the cache of the Cluster of the logical cluster, to
+// share with code using client-go listers, see cache.ClusterInformers.  It fails if the set
+// doesn't have the cluster.
+func (s *ClusterSet) ClusterInformers(name logicalcluster.Name) (*cache.ClusterInformers, error) {
+	cl, ok := s.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("logical cluster %q is not in the cluster set", name)
+	}
+	return cache.NewClusterInformers(cl.GetCache(), name), nil
+}
+
+// DeleteAllOf deletes the objects of the type of obj matching the options with the client of
+// the Cluster of the logical cluster of obj, defaulting to the one of the client.InCluster
+// option, then to the cluster of the context.  With the client.AllClusters option, it fans
+// the delete out across every cluster of the set and aggregates the errors.
+func (s *ClusterSet) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	deleteAllOfOpts := &client.DeleteAllOfOptions{}
+	deleteAllOfOpts.ApplyOptions(opts)
+	if !deleteAllOfOpts.AllClusters {
+		name := logicalcluster.From(obj)
+		if name.Empty() {
+			name = deleteAllOfOpts.Cluster
+		}
+		if name.Empty() {
+			name, _ = kcpclient.ClusterFromContext(ctx)
+		}
+		cl, ok := s.Get(name)
+		if !ok {
+			return fmt.Errorf("logical cluster %q is not in the cluster set", name)
+		}
+		return cl.GetClient().DeleteAllOf(kcpclient.WithCluster(ctx, name), obj, deleteAllOfOpts)
+	}
+
+	// the clients of the clusters delete in their own logical cluster.
+	deleteAllOfOpts.AllClusters = false
+	obj = obj.DeepCopyObject().(client.Object)
+	obj.SetClusterName("")
+	var errs []error
+	for _, name := range s.Names() {
+		cl, ok := s.Get(name)
+		if !ok {
+			continue
+		}
+		if err := cl.GetClient().DeleteAllOf(kcpclient.WithCluster(ctx, name), obj, deleteAllOfOpts); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete in logical cluster %q: %w", name, err))
+		}
+	}
+	return kerrors.NewAggregate(errs)
+}
+
+// Names returns the logical clusters of the set, sorted.
+func (s *ClusterSet) Names() []logicalcluster.Name {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]logicalcluster.Name, 0, len(s.members))
+	for name := range s.members {
+		names = append(names, name)
+	}
+	return sortNames(names)
+}
+
+// sortedNames returns the logical clusters of the failures, sorted.
+func sortedNames(failed map[logicalcluster.Name]error) []logicalcluster.Name {
+	names := make([]logicalcluster.Name, 0, len(failed))
+	for name := range failed {
+		names = append(names, name)
+	}
+	return sortNames(names)
+}
+
+func sortNames(names []logicalcluster.Name) []logicalcluster.Name {
+	sort.Slice(names, func(i, j int) bool { return names[i].String() < names[j].String() })
+	return names
+}
+
+// IndexField adds the index to the caches of the clusters of the set, and of the clusters
+// added later on, so that the controllers listing by field in any cluster don't have to
+// index the cache of each cluster themselves.  It implements client.FieldIndexer, and
+// aggregates the errors of the clusters of the set.  As for a single cache, the indexes
+// should be added before the clusters are started: the caches of the running clusters
+// can't be indexed anymore.
+func (s *ClusterSet) IndexField(ctx context.Context, obj client.Object, field string, extractValue client.IndexerFunc) error {
+	s.mu.Lock()
+	s.indexes = append(s.indexes, setIndex{obj: obj, field: field, extractValue: extractValue})
+	members := make([]*setMember, 0, len(s.members))
+	for _, m := range s.members {
+		members = append(members, m)
+	}
+	s.mu.Unlock()
+
+	var errs []error
+	for _, m := range members {
+		if err := m.cluster.GetCache().IndexField(ctx, obj, field, extractValue); err != nil {
+			errs = append(errs, fmt.Errorf("failed to index field %q of cluster %s: %w", field, m.name, err))
+		}
+	}
+	return kerrors.NewAggregate(errs)
+}
+
+// AddHandler registers a handler for the clusters added to and removed from the set.  It
+// is immediately notified of the clusters the set already has.
+func (s *ClusterSet) AddHandler(h ClusterSetHandler) {
+	s.mu.Lock()
+	s.handlers = append(s.handlers, h)
+	members := make([]*setMember, 0, len(s.members))
+	for _, m := range s.members {
+		members = append(members, m)
+	}
+	s.mu.Unlock()
+
+	for _, m := range members {
+		h.ClusterAdded(m.name, m.cluster)
+	}
+}
+
+// RemoveHandler unregisters a handler registered with AddHandler, e.g. once the controller it
+// belongs to is stopped, so that the set doesn't notify nor reference it anymore.  The handler
+// must be comparable, e.g. a pointer: the ClusterSetHandlerFuncs can't be removed.
+func (s *ClusterSet) RemoveHandler(h ClusterSetHandler) {
+	if h == nil || !reflect.TypeOf(h).Comparable() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, registered := range s.handlers {
+		if registered == h {
+			s.handlers = append(s.handlers[:i:i], s.handlers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Failed returns the errors of the clusters of the set which failed, by logical cluster.  A
+// failed cluster stays in the set, stopped, until it is removed.
+func (s *ClusterSet) Failed() map[logicalcluster.Name]error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	failed := make(map[logicalcluster.Name]error, len(s.failed))
+	for name, err := range s.failed {
+		failed[name] = err
+	}
+	return failed
+}
+
+// ClusterStatuses implements healthz.ClusterStatuser, it returns whether the cache of each
+// cluster of the set is synced, and the error of the clusters which failed, or which couldn't
+// be created and are retried.  Its statuses are
+// typically served with healthz.ClustersSynced and healthz.ClustersHandler, see
+// manager.Options.ClusterStatuser.
+func (s *ClusterSet) ClusterStatuses() []healthz.ClusterStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]healthz.ClusterStatus, 0, len(s.members))
+	for name, m := range s.members {
+		status := healthz.ClusterStatus{Cluster: name, Synced: m.synced}
+		if err := s.failed[name]; err != nil {
+			status.Error = err.Error()
+		}
+		statuses = append(statuses, status)
+	}
+	for name, p := range s.pending {
+		statuses = append(statuses, healthz.ClusterStatus{Cluster: name, Error: p.err.Error()})
+	}
+	return statuses
+}
+
+// Start starts all the clusters of the set, and the ones added later on, until the context
+// is done or more than MaxFailedClusters clusters failed.  It then stops all of them and
+// returns once they are stopped, with the errors of the failed clusters in the latter case.
+func (s *ClusterSet) Start(ctx context.Context) error {
+	s.mu.Lock()
+	if s.ctx != nil {
+		s.mu.Unlock()
+		return errors.New("cluster set was started more than once")
+	}
+	setCtx, abort := context.WithCancel(ctx)
+	defer abort()
+	s.ctx, s.abort = setCtx, abort
+	for _, m := range s.members {
+		s.start(m)
+	}
+	s.mu.Unlock()
+
+	<-setCtx.Done()
+
+	s.mu.Lock()
+	members := make([]*setMember, 0, len(s.members))
+	for _, m := range s.members {
+		members = append(members, m)
+	}
+	s.mu.Unlock()
+	for _, m := range members {
+		m.stop()
+	}
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	errs := make([]error, 0, len(s.failed))
+	for _, name := range sortedNames(s.failed) {
+		errs = append(errs, fmt.Errorf("logical cluster %q failed: %w", name, s.failed[name]))
+	}
+	return kerrors.NewAggregate(errs)
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, the caches of the clusters
+// must be running whether this instance is the leader or not.
+func (s *ClusterSet) NeedLeaderElection() bool {
+	return false
+}
+
+// start starts the cluster of the member with the context of the set.  s.mu must be held.
+func (s *ClusterSet) start(m *setMember) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	m.cancel = cancel
+	m.done = make(chan struct{})
+	var held chan struct{}
+	if s.options.Gate != nil {
+		held = s.options.Gate.hold(m.name)
+	}
+	go func() {
+		synced := m.cluster.GetCache().WaitForCacheSync(ctx)
+		if s.options.Gate != nil {
+			// the requests are released once the cluster is removed too.
+			s.options.Gate.release(m.name, held)
+		}
+		if synced {
+			s.mu.Lock()
+			m.synced = true
+			s.mu.Unlock()
+		}
+	}()
+	go func() {
+		defer close(m.done)
+		if err := m.cluster.Start(ctx); err != nil {
+			setLog.Error(err, "Cluster stopped with an error", "cluster", m.name.String())
+			if ctx.Err() == nil {
+				s.fail(m, err)
+			}
+		}
+	}()
+}
+
+// fail records the failure of the cluster of the member, and stops the set if too many of
+// its clusters failed.
+func (s *ClusterSet) fail(m *setMember, err error) {
+	s.mu.Lock()
+	if s.members[m.name] != m {
+		// the cluster was removed meanwhile.
+		s.mu.Unlock()
+		return
+	}
+	s.failed[m.name] = err
+	if max := s.options.MaxFailedClusters; max != nil && len(s.failed) > *max {
+		s.abort()
+	}
+	handlers := append([]ClusterSetHandler(nil), s.handlers...)
+	s.mu.Unlock()
+
+	for _, h := range handlers {
+		if h, ok := h.(ClusterFailedHandler); ok {
+			h.ClusterFailed(m.name, err)
+		}
+	}
+}
+
+// stop stops the cluster of the member, if it was started, and waits until it is stopped.
+func (m *setMember) stop() {
+	if m.cancel == nil {
+		return
+	}
+	m.cancel()
+	<-m.done
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/kcp-dev/logicalcluster"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	toolscache "k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// EventHandlerRegistration is the registration of an event handler on the informers of a kind
+// in the caches of the clusters of a ClusterSet, see ClusterSet.AddEventHandler.
+type EventHandlerRegistration struct {
+	ctx     context.Context
+	obj     client.Object
+	handler toolscache.ResourceEventHandler
+
+	mu       sync.Mutex
+	removed  bool
+	clusters map[logicalcluster.Name]*clusterRegistration
+}
+
+// clusterRegistration is the registration of an event handler on the informer of a cluster.
+type clusterRegistration struct {
+	handler *removableHandler
+
+	// done is closed once the handler is registered and the informer is synced, or failed
+	// to, with err.
+	done     chan struct{}
+	informer cache.Informer
+	err      error
+}
+
+// removableHandler passes the events of an informer to a handler until it is removed.  The
+// informers of client-go can't remove their handlers, so a removed handler stays registered,
+// dropping the events, until the cache of its cluster is stopped.
+type removableHandler struct {
+	handler toolscache.ResourceEventHandler
+	removed int32
+}
+
+var _ toolscache.ResourceEventHandler = &removableHandler{}
+
+func (h *removableHandler) remove() {
+	atomic.StoreInt32(&h.removed, 1)
+}
+
+func (h *removableHandler) active() bool {
+	return atomic.LoadInt32(&h.removed) == 0
+}
+
+// OnAdd implements toolscache.ResourceEventHandler.
+func (h *removableHandler) OnAdd(obj interface{}) {
+	if h.active() {
+		h.handler.OnAdd(obj)
+	}
+}
+
+// OnUpdate implements toolscache.ResourceEventHandler.
+func (h *removableHandler) OnUpdate(oldObj, newObj interface{}) {
+	if h.active() {
+		h.handler.OnUpdate(oldObj, newObj)
+	}
+}
+
+// OnDelete implements toolscache.ResourceEventHandler.
+func (h *removableHandler) OnDelete(obj interface{}) {
+	if h.active() {
+		h.handler.OnDelete(obj)
+	}
+}
+
+// AddEventHandler registers the handler on the informer of the kind of the object in the cache
+// of each cluster of the set, and of the clusters added later on, which passes the objects it
+// already has to the handler as added.  The handler of a cluster is removed when the cluster is
+// removed from the set, and all of them with RemoveEventHandler.
+//
+// The handler is registered in the background, as getting the informer of a started cache
+// waits for it to sync, until the context is done: see WaitForSync.
+func (s *ClusterSet) AddEventHandler(ctx context.Context, obj client.Object, handler toolscache.ResourceEventHandler) *EventHandlerRegistration {
+	r := &EventHandlerRegistration{
+		ctx:      ctx,
+		obj:      obj,
+		handler:  handler,
+		clusters: map[logicalcluster.Name]*clusterRegistration{},
+	}
+	s.mu.Lock()
+	s.registrations = append(s.registrations, r)
+	members := make([]*setMember, 0, len(s.members))
+	for _, m := range s.members {
+		members = append(members, m)
+	}
+	s.mu.Unlock()
+
+	for _, m := range members {
+		r.register(m.name, m.cluster)
+	}
+	return r
+}
+
+// RemoveEventHandler removes the handler of the registration from the informers of all the
+// clusters of the set, and stops registering it on the clusters added later on.
+func (s *ClusterSet) RemoveEventHandler(r *EventHandlerRegistration) {
+	s.mu.Lock()
+	for i, registration := range s.registrations {
+		if registration == r {
+			s.registrations = append(s.registrations[:i:i], s.registrations[i+1:]...)
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.removed = true
+	for name, cr := range r.clusters {
+		cr.handler.remove()
+		delete(r.clusters, name)
+	}
+}
+
+// register registers the handler on the informer of the cluster, replacing its former
+// registration on the logical cluster, if any.
+func (r *EventHandlerRegistration) register(name logicalcluster.Name, cl Cluster) {
+	r.mu.Lock()
+	if r.removed {
+		r.mu.Unlock()
+		return
+	}
+	if old, ok := r.clusters[name]; ok {
+		old.handler.remove()
+	}
+	cr := &clusterRegistration{handler: &removableHandler{handler: r.handler}, done: make(chan struct{})}
+	r.clusters[name] = cr
+	r.mu.Unlock()
+
+	go func() {
+		defer close(cr.done)
+		informer, err := cl.GetCache().GetInformer(r.ctx, r.obj)
+		if err != nil {
+			cr.err = err
+		} else {
+			informer.AddEventHandler(cr.handler)
+			cr.informer = informer
+			if !cl.GetCache().WaitForCacheSync(r.ctx) {
+				cr.err = errors.New("cache did not sync")
+			}
+		}
+		if cr.err != nil && r.ctx.Err() == nil {
+			setLog.Error(cr.err, "Failed to register an event handler", "cluster", name.String(), "type", fmt.Sprintf("%T", r.obj))
+		}
+	}()
+}
+
+// unregister removes the handler from the informer of the cluster.
+func (r *EventHandlerRegistration) unregister(name logicalcluster.Name) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cr, ok := r.clusters[name]; ok {
+		cr.handler.remove()
+		delete(r.clusters, name)
+	}
+}
+
+// WaitForSync waits until the handler is registered on the informers of the clusters of the
+// set, and the informers are synced.  It returns the errors of the clusters for which it
+// failed.
+func (r *EventHandlerRegistration) WaitForSync(ctx context.Context) error {
+	r.mu.Lock()
+	clusters := make(map[logicalcluster.Name]*clusterRegistration, len(r.clusters))
+	for name, cr := range r.clusters {
+		clusters[name] = cr
+	}
+	r.mu.Unlock()
+
+	var errs []error
+	for name, cr := range clusters {
+		select {
+		case <-cr.done:
+			if cr.err != nil {
+				errs = append(errs, fmt.Errorf("cluster %s: %w", name, cr.err))
+			}
+		case <-ctx.Done():
+			return errors.New("timed out waiting for cache to be synced")
+		}
+	}
+	return kerrors.NewAggregate(errs)
+}
+
+// Informers returns the informers the handler is registered on, by logical cluster.
+func (r *EventHandlerRegistration) Informers() map[logicalcluster.Name]cache.Informer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	informers := make(map[logicalcluster.Name]cache.Informer, len(r.clusters))
+	for name, cr := range r.clusters {
+		select {
+		case <-cr.done:
+			if cr.informer != nil {
+				informers[name] = cr.informer
+			}
+		default:
+		}
+	}
+	return informers
+}
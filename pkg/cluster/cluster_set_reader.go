@@ -0,0 +1,376 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// AcrossClusters is a ListOption making the reader of a ClusterSet list the objects of all
+// its clusters, rather than the ones of the cluster of the context.
+type AcrossClusters struct{}
+
+// ApplyToList implements client.ListOption.  The option is only understood by the reader of
+// a ClusterSet, it doesn't change the ListOptions.
+func (AcrossClusters) ApplyToList(*client.ListOptions) {}
+
+// GetReader returns a client.Reader reading from the caches of the clusters of the set.
+//
+// Get reads from the cluster of the key, or of the context if the key has none.  List lists
+// the objects of the cluster of the client.InCluster option or of the context, or, given the
+// AcrossClusters or client.AllClusters option, the objects of all the clusters, ordered by cluster, namespace and name.  Limit and Continue are
+// supported across clusters, the continue token being only valid for the same reader.
+//
+// The objects returned carry the name of their cluster, see logicalcluster.From.
+func (s *ClusterSet) GetReader() client.Reader {
+	return &setReader{set: s}
+}
+
+// ReadPreference decides which copy of an object is returned by the reader of a ClusterSet
+// merged with a wildcard reader, when both the cache of the cluster of the object and the
+// wildcard cache have one, see GetMergedReader.
+type ReadPreference string
+
+const (
+	// PreferClusterCaches returns the copy of the cache of the cluster of the object when the
+	// cluster is part of the set, and the copy of the wildcard cache otherwise.
+	PreferClusterCaches ReadPreference = "ClusterCaches"
+
+	// PreferWildcardCache returns the copy of the wildcard cache, the caches of the clusters
+	// only serve the objects the wildcard cache doesn't have when listing across clusters.
+	PreferWildcardCache ReadPreference = "WildcardCache"
+)
+
+// GetMergedReader returns a client.Reader reading from the caches of the clusters of the set,
+// like GetReader, and from the given wildcard reader, e.g. the cache of a manager built with
+// kcp.NewClusterAwareCache, for the logical clusters which aren't part of the set.
+//
+// Get and List read from the cache preferred by the given ReadPreference, PreferClusterCaches
+// if empty, for the cluster of the key or of the context.  Lists across clusters return the
+// objects of both, deduplicated by logical cluster and UID, or namespace and name for the
+// objects without UID: an object cached by both is returned once, from the preferred cache.
+func (s *ClusterSet) GetMergedReader(wildcard client.Reader, preference ReadPreference) client.Reader {
+	if preference == "" {
+		preference = PreferClusterCaches
+	}
+	return &setReader{set: s, wildcard: wildcard, preference: preference}
+}
+
+// setReader is the client.Reader of a ClusterSet.
+type setReader struct {
+	set *ClusterSet
+
+	// wildcard, if set, is merged with the caches of the clusters as decided by preference.
+	wildcard   client.Reader
+	preference ReadPreference
+}
+
+var _ client.Reader = &setReader{}
+
+// Get implements client.Reader.
+func (r *setReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	name := key.Cluster
+	if name.Empty() {
+		name, _ = kcpclient.ClusterFromContext(ctx)
+	}
+	if r.readsWildcard(name) {
+		key.Cluster = name
+		return r.wildcard.Get(ctx, key, obj)
+	}
+	cl, err := r.clusterFor(name)
+	if err != nil {
+		return err
+	}
+
+	key.Cluster = logicalcluster.Name{}
+	if err := cl.GetCache().Get(withoutCluster(ctx), key, obj); err != nil {
+		return err
+	}
+	obj.SetClusterName(name.String())
+	return nil
+}
+
+// List implements client.Reader.
+func (r *setReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listOpts := client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	across := listOpts.Cluster == logicalcluster.Wildcard
+	for _, opt := range opts {
+		if _, ok := opt.(AcrossClusters); ok {
+			across = true
+			break
+		}
+	}
+	if !across {
+		name := listOpts.Cluster
+		if name.Empty() {
+			name, _ = kcpclient.ClusterFromContext(ctx)
+		}
+		if r.readsWildcard(name) {
+			return r.wildcard.List(ctx, list, opts...)
+		}
+		cl, err := r.clusterFor(name)
+		if err != nil {
+			return err
+		}
+		// the objects of the caches of the clusters don't carry their cluster.
+		clusterOpts := append(append([]client.ListOption(nil), opts...), client.InCluster(logicalcluster.Name{}))
+		if err := cl.GetCache().List(withoutCluster(ctx), list, clusterOpts...); err != nil {
+			return err
+		}
+		return stampItems(list, name)
+	}
+
+	limit, from := listOpts.Limit, listOpts.Continue
+	listOpts.Limit, listOpts.Continue, listOpts.Cluster = 0, "", logicalcluster.Name{}
+
+	var after itemKey
+	if from != "" {
+		var err error
+		if after, err = decodeContinue(from); err != nil {
+			return apierrors.NewBadRequest(err.Error())
+		}
+	}
+
+	var items []runtime.Object
+	for _, name := range r.set.Names() {
+		if from != "" && name.String() < after.Cluster {
+			continue
+		}
+		cl, ok := r.set.Get(name)
+		if !ok {
+			continue
+		}
+		clusterList := list.DeepCopyObject().(client.ObjectList)
+		if err := cl.GetCache().List(withoutCluster(ctx), clusterList, &listOpts); err != nil {
+			return fmt.Errorf("failed to list cluster %s: %w", name, err)
+		}
+		if err := stampItems(clusterList, name); err != nil {
+			return err
+		}
+		clusterItems, err := meta.ExtractList(clusterList)
+		if err != nil {
+			return err
+		}
+		items = append(items, clusterItems...)
+	}
+	if r.wildcard != nil {
+		wildcardList := list.DeepCopyObject().(client.ObjectList)
+		if err := r.wildcard.List(withoutCluster(ctx), wildcardList, &listOpts); err != nil {
+			return fmt.Errorf("failed to list across clusters: %w", err)
+		}
+		wildcardItems, err := meta.ExtractList(wildcardList)
+		if err != nil {
+			return err
+		}
+		if r.preference == PreferWildcardCache {
+			items, err = mergeItems(wildcardItems, items)
+		} else {
+			items, err = mergeItems(items, wildcardItems)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	keys := make([]itemKey, len(items))
+	for i, item := range items {
+		obj, err := meta.Accessor(item)
+		if err != nil {
+			return err
+		}
+		keys[i] = itemKey{Cluster: obj.GetClusterName(), Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	}
+	sort.Sort(byItemKey{items: items, keys: keys})
+
+	start := 0
+	if from != "" {
+		start = sort.Search(len(keys), func(i int) bool { return after.less(keys[i]) })
+	}
+	items, keys = items[start:], keys[start:]
+
+	continueToken := ""
+	if limit > 0 && int64(len(items)) > limit {
+		items = items[:limit]
+		var err error
+		if continueToken, err = encodeContinue(keys[limit-1]); err != nil {
+			return err
+		}
+	}
+
+	if err := meta.SetList(list, items); err != nil {
+		return err
+	}
+	listAccessor, err := meta.ListAccessor(list)
+	if err != nil {
+		return err
+	}
+	listAccessor.SetContinue(continueToken)
+	listAccessor.SetResourceVersion("")
+	return nil
+}
+
+// readsWildcard returns whether the objects of the given logical cluster are read from the
+// wildcard reader.
+func (r *setReader) readsWildcard(name logicalcluster.Name) bool {
+	if r.wildcard == nil || name.Empty() || name == logicalcluster.Wildcard {
+		return false
+	}
+	if r.preference == PreferWildcardCache {
+		return true
+	}
+	_, ok := r.set.Get(name)
+	return !ok
+}
+
+// identity identifies an object across the caches listing it.
+type identity struct {
+	cluster         string
+	uid             types.UID
+	namespace, name string
+}
+
+// identityOf returns the identity of an object, by logical cluster and UID, or namespace and
+// name if it has no UID.
+func identityOf(obj metav1.Object) identity {
+	if uid := obj.GetUID(); uid != "" {
+		return identity{cluster: obj.GetClusterName(), uid: uid}
+	}
+	return identity{cluster: obj.GetClusterName(), namespace: obj.GetNamespace(), name: obj.GetName()}
+}
+
+// mergeItems returns the preferred items, followed by the other items which aren't among them.
+func mergeItems(preferred, others []runtime.Object) ([]runtime.Object, error) {
+	seen := make(map[identity]struct{}, len(preferred))
+	for _, item := range preferred {
+		obj, err := meta.Accessor(item)
+		if err != nil {
+			return nil, err
+		}
+		seen[identityOf(obj)] = struct{}{}
+	}
+	merged := preferred
+	for _, item := range others {
+		obj, err := meta.Accessor(item)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := seen[identityOf(obj)]; ok {
+			continue
+		}
+		merged = append(merged, item)
+	}
+	return merged, nil
+}
+
+// clusterFor returns the Cluster of the given logical cluster, which must be part of the set.
+func (r *setReader) clusterFor(name logicalcluster.Name) (Cluster, error) {
+	if name.Empty() || name == logicalcluster.Wildcard {
+		return nil, fmt.Errorf("a single logical cluster is required to read from a cluster set, use AcrossClusters to list all of them")
+	}
+	cl, ok := r.set.Get(name)
+	if !ok {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "clusters"}, name.String())
+	}
+	return cl, nil
+}
+
+// withoutCluster returns a context without logical cluster, so that the caches of the clusters,
+// whose objects don't carry any, don't filter by it.
+func withoutCluster(ctx context.Context) context.Context {
+	if _, ok := kcpclient.ClusterFromContext(ctx); !ok {
+		return ctx
+	}
+	return kcpclient.WithCluster(ctx, logicalcluster.Name{})
+}
+
+// stampItems sets the cluster name of the items of the list.
+func stampItems(list client.ObjectList, name logicalcluster.Name) error {
+	return meta.EachListItem(list, func(item runtime.Object) error {
+		obj, err := meta.Accessor(item)
+		if err != nil {
+			return err
+		}
+		obj.SetClusterName(name.String())
+		return nil
+	})
+}
+
+// itemKey orders the items listed across clusters.
+type itemKey struct {
+	Cluster   string `json:"c"`
+	Namespace string `json:"ns"`
+	Name      string `json:"n"`
+}
+
+func (k itemKey) less(o itemKey) bool {
+	if k.Cluster != o.Cluster {
+		return k.Cluster < o.Cluster
+	}
+	if k.Namespace != o.Namespace {
+		return k.Namespace < o.Namespace
+	}
+	return k.Name < o.Name
+}
+
+type byItemKey struct {
+	items []runtime.Object
+	keys  []itemKey
+}
+
+func (b byItemKey) Len() int           { return len(b.items) }
+func (b byItemKey) Less(i, j int) bool { return b.keys[i].less(b.keys[j]) }
+func (b byItemKey) Swap(i, j int) {
+	b.items[i], b.items[j] = b.items[j], b.items[i]
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+}
+
+// encodeContinue returns the continue token resuming a list after the given item.
+func encodeContinue(last itemKey) (string, error) {
+	data, err := json.Marshal(last)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeContinue(token string) (itemKey, error) {
+	var key itemKey
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return key, fmt.Errorf("invalid continue token: %w", err)
+	}
+	if err := json.Unmarshal(data, &key); err != nil {
+		return key, fmt.Errorf("invalid continue token: %w", err)
+	}
+	return key, nil
+}
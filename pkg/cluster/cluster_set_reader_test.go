@@ -0,0 +1,205 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"strings"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeReaderCache is a cache reading from a client.
+type fakeReaderCache struct {
+	cache.Cache
+	client.Reader
+}
+
+func (c fakeReaderCache) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	return c.Reader.Get(ctx, key, obj)
+}
+
+func (c fakeReaderCache) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return c.Reader.List(ctx, list, opts...)
+}
+
+// wildcardReader is a client.Reader of the config maps of several logical clusters.
+type wildcardReader struct {
+	items []corev1.ConfigMap
+}
+
+func (r wildcardReader) Get(_ context.Context, key client.ObjectKey, obj client.Object) error {
+	for _, item := range r.items {
+		if item.ClusterName == key.Cluster.String() && item.Namespace == key.Namespace && item.Name == key.Name {
+			item.DeepCopyInto(obj.(*corev1.ConfigMap))
+			return nil
+		}
+	}
+	return apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, key.Name)
+}
+
+func (r wildcardReader) List(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+	list.(*corev1.ConfigMapList).Items = append([]corev1.ConfigMap(nil), r.items...)
+	return nil
+}
+
+var _ = Describe("cluster.ClusterSet reader", func() {
+	var set *ClusterSet
+	var reader client.Reader
+	a := logicalcluster.New("root:a")
+	b := logicalcluster.New("root:b")
+
+	configMap := func(namespace, name string) client.Object {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	}
+	names := func(list *corev1.ConfigMapList) []string {
+		var names []string
+		for _, cm := range list.Items {
+			names = append(names, cm.ClusterName+"/"+cm.Namespace+"/"+cm.Name)
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		objects := map[string][]client.Object{
+			a.String(): {configMap("ns", "z"), configMap("ns", "a"), configMap("other", "b")},
+			b.String(): {configMap("ns", "c")},
+		}
+		var err error
+		set, err = NewClusterSet(&rest.Config{Host: "https://kcp.example.com"}, ClusterSetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		set.newCluster = func(config *rest.Config, _ ...Option) (Cluster, error) {
+			name := config.Host[strings.LastIndex(config.Host, "/")+1:]
+			c := fake.NewClientBuilder().WithObjects(objects[name]...).Build()
+			return &fakeSetCluster{config: config, cache: fakeReaderCache{Reader: c}}, nil
+		}
+		Expect(set.Sync([]logicalcluster.Name{b, a})).To(Succeed())
+		reader = set.GetReader()
+	})
+
+	It("should get the objects of the cluster of the key or of the context", func() {
+		cm := &corev1.ConfigMap{}
+		Expect(reader.Get(context.Background(), client.ObjectKey{NamespacedName: client.ObjectKeyFromObject(configMap("ns", "c")).NamespacedName, Cluster: b}, cm)).To(Succeed())
+		Expect(logicalcluster.From(cm)).To(Equal(b))
+
+		ctx := kcpclient.WithCluster(context.Background(), a)
+		Expect(reader.Get(ctx, client.ObjectKeyFromObject(configMap("ns", "a")), cm)).To(Succeed())
+		Expect(logicalcluster.From(cm)).To(Equal(a))
+
+		err := reader.Get(kcpclient.WithCluster(context.Background(), logicalcluster.New("root:unknown")), client.ObjectKeyFromObject(configMap("ns", "a")), cm)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should list the objects of the cluster of the context unless listing across clusters", func() {
+		list := &corev1.ConfigMapList{}
+		Expect(reader.List(kcpclient.WithCluster(context.Background(), b), list)).To(Succeed())
+		Expect(names(list)).To(Equal([]string{"root:b/ns/c"}))
+
+		Expect(reader.List(context.Background(), list)).NotTo(Succeed())
+	})
+
+	It("should list the objects of the cluster of the options rather than of the context", func() {
+		list := &corev1.ConfigMapList{}
+		Expect(reader.List(kcpclient.WithCluster(context.Background(), b), list, client.InCluster(a), client.InNamespace("ns"))).To(Succeed())
+		Expect(names(list)).To(Equal([]string{"root:a/ns/a", "root:a/ns/z"}))
+
+		Expect(reader.List(kcpclient.WithCluster(context.Background(), b), list, client.AllClusters)).To(Succeed())
+		Expect(names(list)).To(Equal([]string{"root:a/ns/a", "root:a/ns/z", "root:a/other/b", "root:b/ns/c"}))
+
+		err := reader.List(context.Background(), list, client.InCluster(logicalcluster.New("root:unknown")))
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should list the objects of all the clusters in order", func() {
+		list := &corev1.ConfigMapList{}
+		Expect(reader.List(context.Background(), list, AcrossClusters{})).To(Succeed())
+		Expect(names(list)).To(Equal([]string{"root:a/ns/a", "root:a/ns/z", "root:a/other/b", "root:b/ns/c"}))
+		Expect(list.Continue).To(BeEmpty())
+
+		Expect(reader.List(context.Background(), list, AcrossClusters{}, client.InNamespace("ns"))).To(Succeed())
+		Expect(names(list)).To(Equal([]string{"root:a/ns/a", "root:a/ns/z", "root:b/ns/c"}))
+	})
+
+	It("should list the objects of all the clusters in chunks", func() {
+		var all []string
+		list := &corev1.ConfigMapList{}
+		Expect(reader.List(context.Background(), list, AcrossClusters{}, client.Limit(3))).To(Succeed())
+		Expect(list.Items).To(HaveLen(3))
+		Expect(list.Continue).NotTo(BeEmpty())
+		all = append(all, names(list)...)
+
+		Expect(reader.List(context.Background(), list, AcrossClusters{}, client.Limit(3), client.Continue(list.Continue))).To(Succeed())
+		Expect(list.Continue).To(BeEmpty())
+		all = append(all, names(list)...)
+		Expect(all).To(Equal([]string{"root:a/ns/a", "root:a/ns/z", "root:a/other/b", "root:b/ns/c"}))
+
+		err := reader.List(context.Background(), list, AcrossClusters{}, client.Continue("not a token"))
+		Expect(apierrors.IsBadRequest(err)).To(BeTrue())
+	})
+
+	Describe("merged with a wildcard reader", func() {
+		var wildcard wildcardReader
+
+		BeforeEach(func() {
+			copyOf := func(cluster logicalcluster.Name, namespace, name string) corev1.ConfigMap {
+				return corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+					ClusterName: cluster.String(), Namespace: namespace, Name: name, Labels: map[string]string{"copy": "wildcard"},
+				}}
+			}
+			wildcard = wildcardReader{items: []corev1.ConfigMap{copyOf(a, "ns", "a"), copyOf(logicalcluster.New("root:c"), "ns", "d")}}
+		})
+
+		It("should return the objects cached by both once, from the cluster caches by default", func() {
+			reader = set.GetMergedReader(wildcard, "")
+			list := &corev1.ConfigMapList{}
+			Expect(reader.List(context.Background(), list, AcrossClusters{})).To(Succeed())
+			Expect(names(list)).To(Equal([]string{"root:a/ns/a", "root:a/ns/z", "root:a/other/b", "root:b/ns/c", "root:c/ns/d"}))
+			Expect(list.Items[0].Labels).To(BeEmpty())
+			Expect(list.Items[4].Labels).To(HaveKey("copy"))
+
+			cm := &corev1.ConfigMap{}
+			Expect(reader.Get(kcpclient.WithCluster(context.Background(), a), client.ObjectKeyFromObject(configMap("ns", "a")), cm)).To(Succeed())
+			Expect(cm.Labels).To(BeEmpty())
+			Expect(reader.Get(kcpclient.WithCluster(context.Background(), logicalcluster.New("root:c")), client.ObjectKeyFromObject(configMap("ns", "d")), cm)).To(Succeed())
+			Expect(cm.Labels).To(HaveKey("copy"))
+		})
+
+		It("should return the copies of the wildcard cache when preferred", func() {
+			reader = set.GetMergedReader(wildcard, PreferWildcardCache)
+			list := &corev1.ConfigMapList{}
+			Expect(reader.List(context.Background(), list, AcrossClusters{})).To(Succeed())
+			Expect(names(list)).To(Equal([]string{"root:a/ns/a", "root:a/ns/z", "root:a/other/b", "root:b/ns/c", "root:c/ns/d"}))
+			Expect(list.Items[0].Labels).To(HaveKey("copy"))
+
+			cm := &corev1.ConfigMap{}
+			Expect(reader.Get(kcpclient.WithCluster(context.Background(), a), client.ObjectKeyFromObject(configMap("ns", "a")), cm)).To(Succeed())
+			Expect(cm.Labels).To(HaveKey("copy"))
+		})
+	})
+})
@@ -0,0 +1,855 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/utils/pointer"
+
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+// fakeSetCluster is a Cluster which records whether it is running.
+type fakeClusterResyncer func(name logicalcluster.Name) error
+
+func (f fakeClusterResyncer) TriggerClusterResync(name logicalcluster.Name) error {
+	return f(name)
+}
+
+// failedHandler is a ClusterSetHandler only notified of the failed clusters.
+type failedHandler func(name logicalcluster.Name, err error)
+
+func (failedHandler) ClusterAdded(logicalcluster.Name, Cluster) {}
+
+func (failedHandler) ClusterRemoved(logicalcluster.Name) {}
+
+func (f failedHandler) ClusterFailed(name logicalcluster.Name, err error) { f(name, err) }
+
+// recordingHandler records the clusters it is notified of.
+type recordingHandler struct {
+	added []logicalcluster.Name
+}
+
+func (h *recordingHandler) ClusterAdded(name logicalcluster.Name, _ Cluster) {
+	h.added = append(h.added, name)
+}
+
+func (h *recordingHandler) ClusterRemoved(logicalcluster.Name) {}
+
+type fakeSetCluster struct {
+	Cluster
+	config *rest.Config
+	cache  cache.Cache
+	client client.Client
+
+	// err is returned by Start right away if set.
+	err error
+
+	mu      sync.Mutex
+	running bool
+}
+
+func (c *fakeSetCluster) Start(ctx context.Context) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.setRunning(true)
+	<-ctx.Done()
+	c.setRunning(false)
+	return nil
+}
+
+func (c *fakeSetCluster) GetCache() cache.Cache {
+	if c.cache == nil {
+		return &informertest.FakeInformers{}
+	}
+	return c.cache
+}
+
+func (c *fakeSetCluster) GetClient() client.Client {
+	return c.client
+}
+
+func (c *fakeSetCluster) setRunning(running bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.running = running
+}
+
+// syncingCache is a cache the WaitForCacheSync of which blocks until synced is closed.
+type syncingCache struct {
+	informertest.FakeInformers
+	synced chan struct{}
+}
+
+func (c *syncingCache) WaitForCacheSync(ctx context.Context) bool {
+	select {
+	case <-c.synced:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// indexingCache is a cache recording the fields it indexes, and failing to index if err is set.
+type indexingCache struct {
+	informertest.FakeInformers
+	err    error
+	fields []string
+}
+
+func (c *indexingCache) IndexField(_ context.Context, _ client.Object, field string, _ client.IndexerFunc) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.fields = append(c.fields, field)
+	return nil
+}
+
+func (c *fakeSetCluster) isRunning() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.running
+}
+
+var _ = Describe("cluster.ClusterSet", func() {
+	var set *ClusterSet
+	var events chan string
+	a := logicalcluster.New("root:a")
+	b := logicalcluster.New("root:b")
+
+	BeforeEach(func() {
+		var err error
+		set, err = NewClusterSet(&rest.Config{Host: "https://kcp.example.com/"}, ClusterSetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		set.newCluster = func(config *rest.Config, _ ...Option) (Cluster, error) {
+			return &fakeSetCluster{config: config}, nil
+		}
+		events = make(chan string, 10)
+		set.AddHandler(ClusterSetHandlerFuncs{
+			AddFunc:    func(name logicalcluster.Name, _ Cluster) { events <- "add " + name.String() },
+			RemoveFunc: func(name logicalcluster.Name) { events <- "remove " + name.String() },
+		})
+	})
+
+	It("should create the cluster of a logical cluster once, targeting its path", func() {
+		cl, err := set.Add(a)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cl.(*fakeSetCluster).config.Host).To(Equal("https://kcp.example.com/clusters/root:a"))
+		Expect(<-events).To(Equal("add root:a"))
+
+		again, err := set.Add(a)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(again).To(BeIdenticalTo(cl))
+		Expect(events).To(BeEmpty())
+
+		_, err = set.Add(logicalcluster.Wildcard)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should not notify the handlers once they are removed", func() {
+		removed := &recordingHandler{}
+		set.AddHandler(removed)
+		set.RemoveHandler(removed)
+		set.RemoveHandler(ClusterSetHandlerFuncs{})
+
+		_, err := set.Add(a)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(<-events).To(Equal("add root:a"))
+		Expect(removed.added).To(BeEmpty())
+	})
+
+	It("should create the clusters without blocking the set, once per logical cluster", func() {
+		constructing := make(chan struct{})
+		release := make(chan struct{})
+		var constructions int32
+		set.newCluster = func(config *rest.Config, _ ...Option) (Cluster, error) {
+			if strings.HasSuffix(config.Host, a.String()) {
+				if atomic.AddInt32(&constructions, 1) == 1 {
+					close(constructing)
+				}
+				<-release
+			}
+			return &fakeSetCluster{config: config}, nil
+		}
+
+		added := make(chan Cluster, 2)
+		for i := 0; i < 2; i++ {
+			go func() {
+				defer GinkgoRecover()
+				cl, err := set.Add(a)
+				Expect(err).NotTo(HaveOccurred())
+				added <- cl
+			}()
+		}
+		Eventually(constructing).Should(BeClosed())
+
+		By("serving the other clusters meanwhile")
+		_, err := set.Add(b)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(set.Names()).To(ConsistOf(b))
+		Expect(<-events).To(Equal("add root:b"))
+
+		close(release)
+		var first, second Cluster
+		Eventually(added).Should(Receive(&first))
+		Eventually(added).Should(Receive(&second))
+		Expect(second).To(BeIdenticalTo(first))
+		Expect(atomic.LoadInt32(&constructions)).To(BeEquivalentTo(1))
+		Expect(<-events).To(Equal("add root:a"))
+		Expect(events).To(BeEmpty())
+	})
+
+	It("should not add the clusters removed while they are created", func() {
+		constructing := make(chan struct{})
+		release := make(chan struct{})
+		set.newCluster = func(config *rest.Config, _ ...Option) (Cluster, error) {
+			close(constructing)
+			<-release
+			return &fakeSetCluster{config: config}, nil
+		}
+
+		errs := make(chan error, 1)
+		go func() {
+			_, err := set.Add(a)
+			errs <- err
+		}()
+		Eventually(constructing).Should(BeClosed())
+		Expect(set.Remove(a)).To(BeFalse())
+		close(release)
+		Eventually(errs).Should(Receive(MatchError(ContainSubstring("was removed while being added"))))
+		Expect(set.Names()).To(BeEmpty())
+		Expect(events).To(BeEmpty())
+	})
+
+	It("should return the cache of the cluster of a logical cluster as its reader", func() {
+		informers := &informertest.FakeInformers{}
+		set.newCluster = func(config *rest.Config, _ ...Option) (Cluster, error) {
+			return &fakeSetCluster{config: config, cache: informers}, nil
+		}
+		_, err := set.Add(a)
+		Expect(err).NotTo(HaveOccurred())
+
+		reader, err := set.Reader(a)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reader).To(BeIdenticalTo(informers))
+
+		_, err = set.Reader(b)
+		Expect(err).To(MatchError(ContainSubstring("not in the cluster set")))
+	})
+
+	It("should share the informers of the cache of the cluster of a logical cluster", func() {
+		_, err := set.Add(a)
+		Expect(err).NotTo(HaveOccurred())
+
+		informers, err := set.ClusterInformers(a)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(informers.Cluster()).To(Equal(a))
+
+		_, err = set.ClusterInformers(b)
+		Expect(err).To(MatchError(ContainSubstring("not in the cluster set")))
+	})
+
+	It("should index the caches of the clusters of the set, and of the clusters added later on", func() {
+		caches := map[string]*indexingCache{}
+		set.newCluster = func(config *rest.Config, _ ...Option) (Cluster, error) {
+			c := &indexingCache{}
+			if strings.HasSuffix(config.Host, "root:c") {
+				c.err = errors.New("informer already started")
+			}
+			caches[config.Host] = c
+			return &fakeSetCluster{config: config, cache: c}, nil
+		}
+		_, err := set.Add(a)
+		Expect(err).NotTo(HaveOccurred())
+		byName := func(obj client.Object) []string { return []string{obj.GetName()} }
+		Expect(set.IndexField(context.Background(), &corev1.ConfigMap{}, "name", byName)).To(Succeed())
+		Expect(caches["https://kcp.example.com/clusters/root:a"].fields).To(Equal([]string{"name"}))
+
+		_, err = set.Add(b)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(caches["https://kcp.example.com/clusters/root:b"].fields).To(Equal([]string{"name"}))
+
+		_, err = set.Add(logicalcluster.New("root:c"))
+		Expect(err).To(MatchError(ContainSubstring(`failed to index field "name" of cluster root:c`)))
+		Expect(set.Names()).To(Equal([]logicalcluster.Name{a, b}))
+	})
+
+	It("should register the event handlers on the clusters of the set, and of the clusters added later on, until removed", func() {
+		caches := map[string]*informertest.FakeInformers{}
+		set.newCluster = func(config *rest.Config, _ ...Option) (Cluster, error) {
+			caches[config.Host] = &informertest.FakeInformers{}
+			return &fakeSetCluster{config: config, cache: caches[config.Host]}, nil
+		}
+		add := func(name logicalcluster.Name, objName string) {
+			i, err := caches["https://kcp.example.com/clusters/"+name.String()].FakeInformerFor(&corev1.ConfigMap{})
+			Expect(err).NotTo(HaveOccurred())
+			i.Add(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: objName, ClusterName: name.String()}})
+		}
+		added := make(chan string, 10)
+		handler := toolscache.ResourceEventHandlerFuncs{AddFunc: func(obj interface{}) {
+			cm := obj.(*corev1.ConfigMap)
+			added <- cm.ClusterName + "/" + cm.Name
+		}}
+
+		_, err := set.Add(a)
+		Expect(err).NotTo(HaveOccurred())
+		ctx := context.Background()
+		registration := set.AddEventHandler(ctx, &corev1.ConfigMap{}, handler)
+		Expect(registration.WaitForSync(ctx)).To(Succeed())
+		add(a, "foo")
+		Expect(added).To(Receive(Equal("root:a/foo")))
+
+		_, err = set.Add(b)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(registration.WaitForSync(ctx)).To(Succeed())
+		Expect(registration.Informers()).To(HaveLen(2))
+		add(b, "bar")
+		Expect(added).To(Receive(Equal("root:b/bar")))
+
+		Expect(set.Remove(a)).To(BeTrue())
+		add(a, "removed")
+		Expect(added).NotTo(Receive())
+		Expect(registration.Informers()).To(HaveLen(1))
+
+		set.RemoveEventHandler(registration)
+		add(b, "baz")
+		Expect(added).NotTo(Receive())
+		Expect(registration.Informers()).To(BeEmpty())
+
+		_, err = set.Add(logicalcluster.New("root:c"))
+		Expect(err).NotTo(HaveOccurred())
+		add(logicalcluster.New("root:c"), "qux")
+		Expect(added).NotTo(Receive())
+	})
+
+	It("should delete all of the objects of a logical cluster, or of all of them, with their clients", func() {
+		ctx := context.Background()
+		clients := map[string]client.Client{}
+		set.newCluster = func(config *rest.Config, _ ...Option) (Cluster, error) {
+			name := strings.TrimPrefix(config.Host, "https://kcp.example.com/clusters/")
+			c := fake.NewClusterBuilder().WithObjects(logicalcluster.New(name),
+				&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "ns"}},
+				&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "other"}},
+			).Build()
+			clients[config.Host] = c
+			return &fakeSetCluster{config: config, client: c}, nil
+		}
+		Expect(set.Sync([]logicalcluster.Name{a, b})).To(Succeed())
+		count := func(name logicalcluster.Name) int {
+			list := &corev1.ConfigMapList{}
+			Expect(clients["https://kcp.example.com/clusters/"+name.String()].List(kcpclient.WithCluster(ctx, name), list)).To(Succeed())
+			return len(list.Items)
+		}
+
+		Expect(set.DeleteAllOf(kcpclient.WithCluster(ctx, a), &corev1.ConfigMap{}, client.InNamespace("ns"))).To(Succeed())
+		Expect(count(a)).To(Equal(1))
+		Expect(count(b)).To(Equal(2))
+
+		Expect(set.DeleteAllOf(ctx, &corev1.ConfigMap{}, client.InNamespace("other"), client.AllClusters)).To(Succeed())
+		Expect(count(a)).To(Equal(0))
+		Expect(count(b)).To(Equal(1))
+
+		err := set.DeleteAllOf(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{ClusterName: "root:c"}})
+		Expect(err).To(MatchError(ContainSubstring("not in the cluster set")))
+	})
+
+	It("should create the clusters with the configs returned by ClusterConfig", func() {
+		set.options.ClusterConfig = func(base *rest.Config, clusterName string) (*rest.Config, error) {
+			if clusterName == b.String() {
+				return nil, errors.New("unknown cluster")
+			}
+			config := rest.CopyConfig(base)
+			config.Host = "https://" + strings.ReplaceAll(clusterName, ":", "-") + ".example.com"
+			config.Impersonate.UserName = "system:serviceaccount:default:" + clusterName
+			return config, nil
+		}
+
+		cl, err := set.Add(a)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cl.(*fakeSetCluster).config.Host).To(Equal("https://root-a.example.com"))
+		Expect(cl.(*fakeSetCluster).config.Impersonate.UserName).To(Equal("system:serviceaccount:default:root:a"))
+
+		_, err = set.Add(b)
+		Expect(err).To(MatchError(ContainSubstring("unknown cluster")))
+		_, ok := set.Get(b)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should resolve the configs of the clusters with the ClusterConfig of ClusterConfigs", func() {
+		set.options.ClusterConfig = ClusterConfigs{a: &rest.Config{Host: "https://physical-a.example.com"}}.ClusterConfig
+
+		cl, err := set.Add(a)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cl.(*fakeSetCluster).config.Host).To(Equal("https://physical-a.example.com"))
+
+		_, err = set.Add(b)
+		Expect(err).To(MatchError(ContainSubstring("no config for cluster root:b")))
+	})
+
+	It("should override the rate limits of the clusters with RateLimit", func() {
+		set.options.RateLimit = func(name logicalcluster.Name) (float32, int) {
+			if name == a {
+				return 2, 4
+			}
+			return 0, 0
+		}
+		set.config.QPS, set.config.Burst = 20, 40
+
+		cl, err := set.Add(a)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cl.(*fakeSetCluster).config.QPS).To(BeEquivalentTo(2))
+		Expect(cl.(*fakeSetCluster).config.Burst).To(Equal(4))
+
+		cl, err = set.Add(b)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cl.(*fakeSetCluster).config.QPS).To(BeEquivalentTo(20))
+		Expect(cl.(*fakeSetCluster).config.Burst).To(Equal(40))
+	})
+
+	It("should make the clusters impersonate the users returned by Impersonate", func() {
+		set.options.Impersonate = func(name logicalcluster.Name) (rest.ImpersonationConfig, bool) {
+			return rest.ImpersonationConfig{UserName: "system:serviceaccount:tenant:controller"}, name == a
+		}
+
+		cl, err := set.Add(a)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cl.(*fakeSetCluster).config.Impersonate.UserName).To(Equal("system:serviceaccount:tenant:controller"))
+
+		cl, err = set.Add(b)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cl.(*fakeSetCluster).config.Impersonate).To(Equal(rest.ImpersonationConfig{}))
+	})
+
+	It("should share the transports of the clusters targeting the same server with ShareTransport", func() {
+		set.options.ShareTransport = true
+		set.options.MaxConnsPerHost = 10
+		set.config.BearerToken = "token"
+		set.options.ClusterConfig = func(base *rest.Config, clusterName string) (*rest.Config, error) {
+			config, err := KCPClusterConfig(base, clusterName)
+			if clusterName == "root:c" {
+				config.Insecure = true
+			}
+			return config, err
+		}
+
+		clA, err := set.Add(a)
+		Expect(err).NotTo(HaveOccurred())
+		clB, err := set.Add(b)
+		Expect(err).NotTo(HaveOccurred())
+		clC, err := set.Add(logicalcluster.New("root:c"))
+		Expect(err).NotTo(HaveOccurred())
+
+		configA, configB, configC := clA.(*fakeSetCluster).config, clB.(*fakeSetCluster).config, clC.(*fakeSetCluster).config
+		Expect(configA.Transport).NotTo(BeNil())
+		Expect(configA.Transport).To(BeIdenticalTo(configB.Transport))
+		Expect(configA.Transport.(*http.Transport).MaxConnsPerHost).To(Equal(10))
+		Expect(configA.BearerToken).To(Equal("token"))
+		Expect(configC.Transport).NotTo(BeIdenticalTo(configA.Transport))
+		Expect(configC.Insecure).To(BeFalse(), "the TLS settings are those of the transport")
+		Expect(configC.Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify).To(BeTrue())
+
+		_, err = rest.HTTPClientFor(configA)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should create the clusters with the options returned by ClusterOptions", func() {
+		var opts []Option
+		set.newCluster = func(config *rest.Config, clusterOpts ...Option) (Cluster, error) {
+			opts = clusterOpts
+			return &fakeSetCluster{config: config}, nil
+		}
+		set.options.ClusterOptions = func(name logicalcluster.Name) []Option {
+			if name != a {
+				return nil
+			}
+			return []Option{func(o *Options) { o.Namespace = "tenant" }}
+		}
+
+		_, err := set.Add(a)
+		Expect(err).NotTo(HaveOccurred())
+		options := &Options{}
+		for _, opt := range opts {
+			opt(options)
+		}
+		Expect(options.Namespace).To(Equal("tenant"))
+
+		_, err = set.Add(b)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(opts).To(BeEmpty())
+	})
+
+	It("should use the scheme of the logical clusters with one in Schemes, and the scheme of the options otherwise", func() {
+		base := runtime.NewScheme()
+		custom := runtime.NewScheme()
+		set.opts = []Option{func(o *Options) { o.Scheme = base }}
+		var opts []Option
+		set.newCluster = func(config *rest.Config, clusterOpts ...Option) (Cluster, error) {
+			opts = clusterOpts
+			return &fakeSetCluster{config: config}, nil
+		}
+		set.options.Schemes = ClusterSchemes{a: custom}
+
+		schemes := map[logicalcluster.Name]*runtime.Scheme{}
+		for _, name := range []logicalcluster.Name{a, b} {
+			_, err := set.Add(name)
+			Expect(err).NotTo(HaveOccurred())
+			options := &Options{}
+			for _, opt := range opts {
+				opt(options)
+			}
+			schemes[name] = options.Scheme
+		}
+		Expect(schemes[a]).To(BeIdenticalTo(custom))
+		Expect(schemes[b]).To(BeIdenticalTo(base))
+	})
+
+	It("should scope the caches of the clusters with CacheByCluster", func() {
+		var namespaces []string
+		set.opts = []Option{func(o *Options) {
+			o.NewCache = func(_ *rest.Config, opts cache.Options) (cache.Cache, error) {
+				namespaces = append(namespaces, opts.Namespace)
+				return &informertest.FakeInformers{}, nil
+			}
+		}}
+		var opts []Option
+		set.newCluster = func(config *rest.Config, clusterOpts ...Option) (Cluster, error) {
+			opts = clusterOpts
+			return &fakeSetCluster{config: config}, nil
+		}
+		set.options.CacheByCluster = cache.ByCluster{a: {Namespaces: []string{"tenant"}}}
+
+		for _, name := range []logicalcluster.Name{a, b} {
+			_, err := set.Add(name)
+			Expect(err).NotTo(HaveOccurred())
+			options := &Options{}
+			for _, opt := range opts {
+				opt(options)
+			}
+			_, err = options.NewCache(&rest.Config{}, cache.Options{})
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(namespaces).To(Equal([]string{"tenant", ""}))
+	})
+
+	It("should build the caches of the clusters with CacheFactory, scoped with CacheByCluster", func() {
+		built := map[logicalcluster.Name]string{}
+		set.options.CacheFactory = cache.CacheFactoryFunc(func(name logicalcluster.Name, _ *rest.Config, opts cache.Options) (cache.Cache, error) {
+			built[name] = opts.Namespace
+			return &informertest.FakeInformers{}, nil
+		})
+		var opts []Option
+		set.newCluster = func(config *rest.Config, clusterOpts ...Option) (Cluster, error) {
+			opts = clusterOpts
+			return &fakeSetCluster{config: config}, nil
+		}
+		set.options.CacheByCluster = cache.ByCluster{a: {Namespaces: []string{"tenant"}}}
+
+		for _, name := range []logicalcluster.Name{a, b} {
+			_, err := set.Add(name)
+			Expect(err).NotTo(HaveOccurred())
+			options := &Options{}
+			for _, opt := range opts {
+				opt(options)
+			}
+			_, err = options.NewCache(&rest.Config{}, cache.Options{})
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(built).To(Equal(map[logicalcluster.Name]string{a: "tenant", b: ""}))
+	})
+
+	It("should start and stop the clusters as they are added and removed", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(set.Start(ctx)).To(Succeed())
+		}()
+
+		cl, err := set.Add(a)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(cl.(*fakeSetCluster).isRunning).Should(BeTrue())
+
+		Expect(set.Remove(a)).To(BeTrue())
+		Expect(cl.(*fakeSetCluster).isRunning()).To(BeFalse())
+		Expect(set.Remove(a)).To(BeFalse())
+
+		cl, err = set.Add(b)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(cl.(*fakeSetCluster).isRunning).Should(BeTrue())
+		cancel()
+		Eventually(done).Should(BeClosed())
+		Expect(cl.(*fakeSetCluster).isRunning()).To(BeFalse())
+	})
+
+	It("should report the failed clusters, and tolerate them by default", func() {
+		set.newCluster = func(config *rest.Config, _ ...Option) (Cluster, error) {
+			if strings.HasSuffix(config.Host, a.String()) {
+				return &fakeSetCluster{config: config, err: errors.New("failed to sync")}, nil
+			}
+			return &fakeSetCluster{config: config}, nil
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(set.Start(ctx)).To(Succeed())
+		}()
+
+		failures := make(chan logicalcluster.Name, 2)
+		set.AddHandler(failedHandler(func(name logicalcluster.Name, _ error) { failures <- name }))
+
+		Expect(set.Sync([]logicalcluster.Name{a, b})).To(Succeed())
+		Eventually(set.Failed).Should(HaveKeyWithValue(a, MatchError("failed to sync")))
+		Consistently(done).ShouldNot(BeClosed())
+		Expect(failures).To(Receive(Equal(a)))
+		Expect(failures).NotTo(Receive())
+
+		Expect(set.Remove(a)).To(BeTrue())
+		Expect(set.Failed()).To(BeEmpty())
+		cancel()
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("should retry creating the clusters which couldn't be created with RetryConstruction", func() {
+		var mu sync.Mutex
+		attempts := 0
+		set.newCluster = func(config *rest.Config, _ ...Option) (Cluster, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if strings.HasSuffix(config.Host, a.String()) {
+				if attempts++; attempts < 3 {
+					return nil, errors.New("discovery failed")
+				}
+			}
+			return &fakeSetCluster{config: config}, nil
+		}
+		set.options.RetryConstruction = &wait.Backoff{Duration: 10 * time.Millisecond, Factor: 2, Steps: 5}
+
+		Expect(set.Sync([]logicalcluster.Name{a, b})).To(Succeed())
+		Expect(<-events).To(Equal("add root:b"))
+		Expect(set.Names()).To(Equal([]logicalcluster.Name{b}))
+		Expect(set.Pending()).To(HaveKeyWithValue(a, MatchError("discovery failed")))
+		Expect(set.ClusterStatuses()).To(ContainElement(healthz.ClusterStatus{Cluster: a, Error: "discovery failed"}))
+
+		Eventually(events).Should(Receive(Equal("add root:a")))
+		Expect(set.Names()).To(Equal([]logicalcluster.Name{a, b}))
+		Expect(set.Pending()).To(BeEmpty())
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(attempts).To(Equal(3))
+	})
+
+	It("should stop retrying to create the clusters which are removed", func() {
+		set.newCluster = func(config *rest.Config, _ ...Option) (Cluster, error) {
+			return nil, errors.New("discovery failed")
+		}
+		set.options.RetryConstruction = &wait.Backoff{Duration: 10 * time.Millisecond}
+
+		_, err := set.Add(a)
+		Expect(err).To(MatchError("discovery failed"))
+		Expect(set.Pending()).To(HaveKey(a))
+		Expect(set.Sync(nil)).To(Succeed())
+		Expect(set.Pending()).To(BeEmpty())
+		Consistently(set.Pending, 50*time.Millisecond).Should(BeEmpty())
+	})
+
+	It("should not retry creating the clusters removed while their creation fails", func() {
+		constructing := make(chan struct{}, 1)
+		release := make(chan struct{})
+		set.newCluster = func(config *rest.Config, _ ...Option) (Cluster, error) {
+			constructing <- struct{}{}
+			<-release
+			return nil, errors.New("discovery failed")
+		}
+		set.options.RetryConstruction = &wait.Backoff{Duration: 10 * time.Millisecond}
+
+		errs := make(chan error, 1)
+		go func() {
+			_, err := set.Add(a)
+			errs <- err
+		}()
+		Eventually(constructing).Should(Receive())
+		Expect(set.Remove(a)).To(BeFalse())
+		close(release)
+		Eventually(errs).Should(Receive(MatchError(ContainSubstring("was removed while being added"))))
+		Expect(set.Pending()).To(BeEmpty())
+		Consistently(constructing, 50*time.Millisecond).ShouldNot(Receive())
+		Expect(set.Names()).To(BeEmpty())
+		Expect(events).To(BeEmpty())
+	})
+
+	It("should report whether the caches of the clusters are synced, and their errors", func() {
+		notSynced := false
+		set.newCluster = func(config *rest.Config, _ ...Option) (Cluster, error) {
+			if strings.HasSuffix(config.Host, a.String()) {
+				return &fakeSetCluster{config: config, err: errors.New("failed to sync"), cache: &informertest.FakeInformers{Synced: &notSynced}}, nil
+			}
+			return &fakeSetCluster{config: config}, nil
+		}
+		Expect(set.Sync([]logicalcluster.Name{a, b})).To(Succeed())
+		Expect(set.ClusterStatuses()).To(ConsistOf(
+			healthz.ClusterStatus{Cluster: a},
+			healthz.ClusterStatus{Cluster: b},
+		))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			defer GinkgoRecover()
+			Expect(set.Start(ctx)).To(Succeed())
+		}()
+		Eventually(set.ClusterStatuses).Should(ConsistOf(
+			healthz.ClusterStatus{Cluster: a, Error: "failed to sync"},
+			healthz.ClusterStatus{Cluster: b, Synced: true},
+		))
+	})
+
+	It("should hold the requests of the clusters with Gate until their caches are synced", func() {
+		set.options.Gate = NewGate()
+		syncing := &syncingCache{synced: make(chan struct{})}
+		set.newCluster = func(config *rest.Config, _ ...Option) (Cluster, error) {
+			if strings.HasSuffix(config.Host, a.String()) {
+				return &fakeSetCluster{config: config, cache: syncing}, nil
+			}
+			return &fakeSetCluster{config: config}, nil
+		}
+		Expect(set.Sync([]logicalcluster.Name{a, b})).To(Succeed())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			defer GinkgoRecover()
+			Expect(set.Start(ctx)).To(Succeed())
+		}()
+		Eventually(func() <-chan struct{} { return set.options.Gate.Synced(a) }).ShouldNot(BeNil())
+		Eventually(func() <-chan struct{} { return set.options.Gate.Synced(b) }).Should(BeNil())
+		held := set.options.Gate.Synced(a)
+		Consistently(held).ShouldNot(BeClosed())
+
+		close(syncing.synced)
+		Eventually(held).Should(BeClosed())
+		Expect(set.options.Gate.Synced(a)).To(BeNil())
+	})
+
+	It("should hold the requests of a cluster until it is released", func() {
+		gate := NewGate()
+		Expect(gate.Synced(a)).To(BeNil())
+
+		gate.Hold(a)
+		held := gate.Synced(a)
+		Expect(held).NotTo(BeNil())
+		Expect(gate.Synced(b)).To(BeNil())
+
+		gate.Release(a)
+		Expect(held).To(BeClosed())
+		Expect(gate.Synced(a)).To(BeNil())
+	})
+
+	It("should hold the requests of the suspended clusters and resync them when resumed", func() {
+		suspensions := NewSuspensions()
+		var resynced []logicalcluster.Name
+		suspensions.AddResyncer(fakeClusterResyncer(func(name logicalcluster.Name) error {
+			resynced = append(resynced, name)
+			return nil
+		}))
+		suspensions.AddResyncer(fakeClusterResyncer(func(logicalcluster.Name) error {
+			return errors.New("failed to resync")
+		}))
+
+		suspensions.Suspend(a)
+		Expect(suspensions.Suspended(a)).To(BeTrue())
+		Expect(suspensions.Suspended(b)).To(BeFalse())
+		held := suspensions.Synced(a)
+		Expect(held).NotTo(BeNil())
+
+		Expect(suspensions.Resume(a)).To(MatchError("failed to resync"))
+		Expect(held).To(BeClosed())
+		Expect(suspensions.Suspended(a)).To(BeFalse())
+		Expect(resynced).To(Equal([]logicalcluster.Name{a}))
+
+		Expect(suspensions.Resume(b)).To(Succeed())
+		Expect(resynced).To(Equal([]logicalcluster.Name{a}))
+	})
+
+	It("should stop and return the errors of the failed clusters once more than MaxFailedClusters failed", func() {
+		set.options.MaxFailedClusters = pointer.Int(0)
+		set.newCluster = func(config *rest.Config, _ ...Option) (Cluster, error) {
+			if strings.HasSuffix(config.Host, a.String()) {
+				return &fakeSetCluster{config: config, err: errors.New("failed to sync")}, nil
+			}
+			return &fakeSetCluster{config: config}, nil
+		}
+		cl, err := set.Add(b)
+		Expect(err).NotTo(HaveOccurred())
+		errs := make(chan error, 1)
+		go func() {
+			errs <- set.Start(context.Background())
+		}()
+		Eventually(cl.(*fakeSetCluster).isRunning).Should(BeTrue())
+
+		_, err = set.Add(a)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(errs).Should(Receive(MatchError(ContainSubstring(`logical cluster "root:a" failed: failed to sync`))))
+		Expect(cl.(*fakeSetCluster).isRunning()).To(BeFalse())
+	})
+
+	It("should add the missing clusters and remove the stale ones on Sync", func() {
+		_, err := set.Add(a)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(<-events).To(Equal("add root:a"))
+
+		Expect(set.Sync([]logicalcluster.Name{b})).To(Succeed())
+		Expect(<-events).To(Equal("remove root:a"))
+		Expect(<-events).To(Equal("add root:b"))
+		Expect(set.Names()).To(Equal([]logicalcluster.Name{b}))
+	})
+
+	It("should notify the handlers of the clusters added before them", func() {
+		_, err := set.Add(a)
+		Expect(err).NotTo(HaveOccurred())
+
+		var added []logicalcluster.Name
+		set.AddHandler(ClusterSetHandlerFuncs{
+			AddFunc: func(name logicalcluster.Name, _ Cluster) { added = append(added, name) },
+		})
+		Expect(added).To(Equal([]logicalcluster.Name{a}))
+	})
+})
@@ -0,0 +1,109 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"sync"
+
+	"github.com/kcp-dev/logicalcluster"
+
+	"sigs.k8s.io/controller-runtime/pkg/leaderelection"
+)
+
+// shardedHandler forwards to a ClusterSetHandler the clusters of the shards the elector leads.
+type shardedHandler struct {
+	elector *leaderelection.ShardedElector
+	handler ClusterSetHandler
+
+	// mu serializes the notifications of the ClusterSet and of the elector, so that the
+	// clusters are forwarded in order.
+	mu        sync.Mutex
+	clusters  map[logicalcluster.Name]Cluster
+	forwarded map[logicalcluster.Name]bool
+	leading   map[int]bool
+}
+
+// NewShardedHandler returns a ClusterSetHandler which only notifies h of the clusters of the
+// shards the elector leads: h is notified that a cluster is added once it is in the set and
+// its shard is acquired, and that it is removed once it is removed from the set or its shard
+// is released.  This lets the replicas of a manager split the logical clusters of a
+// ClusterSet between their controllers, e.g.
+//
+//	set.AddHandler(cluster.NewShardedHandler(elector, cluster.ClusterSetHandlerFuncs{
+//		AddFunc:    func(name logicalcluster.Name, cl cluster.Cluster) { /* watch the cluster */ },
+//		RemoveFunc: func(name logicalcluster.Name) { /* stop watching the cluster */ },
+//	}))
+func NewShardedHandler(elector *leaderelection.ShardedElector, h ClusterSetHandler) ClusterSetHandler {
+	s := &shardedHandler{
+		elector:   elector,
+		handler:   h,
+		clusters:  map[logicalcluster.Name]Cluster{},
+		forwarded: map[logicalcluster.Name]bool{},
+		leading:   map[int]bool{},
+	}
+	elector.AddHandler(leaderelection.ShardHandlerFuncs{
+		AcquiredFunc: s.shardAcquired,
+		ReleasedFunc: s.shardReleased,
+	})
+	return s
+}
+
+// ClusterAdded implements ClusterSetHandler.
+func (s *shardedHandler) ClusterAdded(name logicalcluster.Name, cl Cluster) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clusters[name] = cl
+	if s.leading[s.elector.ShardFor(name)] && !s.forwarded[name] {
+		s.forwarded[name] = true
+		s.handler.ClusterAdded(name, cl)
+	}
+}
+
+// ClusterRemoved implements ClusterSetHandler.
+func (s *shardedHandler) ClusterRemoved(name logicalcluster.Name) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.clusters, name)
+	if s.forwarded[name] {
+		delete(s.forwarded, name)
+		s.handler.ClusterRemoved(name)
+	}
+}
+
+func (s *shardedHandler) shardAcquired(shard int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.leading[shard] = true
+	for name, cl := range s.clusters {
+		if s.elector.ShardFor(name) == shard && !s.forwarded[name] {
+			s.forwarded[name] = true
+			s.handler.ClusterAdded(name, cl)
+		}
+	}
+}
+
+func (s *shardedHandler) shardReleased(shard int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.leading, shard)
+	for name := range s.forwarded {
+		if s.elector.ShardFor(name) == shard {
+			delete(s.forwarded, name)
+			s.handler.ClusterRemoved(name)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"sync"
+
+	"github.com/kcp-dev/logicalcluster"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// ClusterResyncer replays the objects of a logical cluster, e.g. a controller.Controller.
+type ClusterResyncer interface {
+	// TriggerClusterResync reconciles all the objects of the logical cluster again.
+	TriggerClusterResync(cluster logicalcluster.Name) error
+}
+
+// Suspensions suspend the event delivery and the reconciles of logical clusters, e.g. while a
+// workspace is migrated or under maintenance, without restarting the controllers.  The events
+// of the objects of a suspended cluster are dropped, and the requests of the cluster already
+// queued are held, as by a SyncGate.  When the cluster is resumed, the held requests are
+// released and the objects of the cluster are resynced by the registered ClusterResyncers,
+// to catch up with the dropped events.
+type Suspensions struct {
+	gate *Gate
+
+	mu        sync.Mutex
+	resyncers []ClusterResyncer
+}
+
+var _ SyncGate = &Suspensions{}
+
+// NewSuspensions returns Suspensions suspending no logical cluster.
+func NewSuspensions() *Suspensions {
+	return &Suspensions{gate: NewGate()}
+}
+
+// Suspend suspends the event delivery and the reconciles of the logical cluster.  The
+// reconciles in flight are not interrupted.
+func (s *Suspensions) Suspend(cluster logicalcluster.Name) {
+	s.gate.Hold(cluster)
+}
+
+// Resume resumes the event delivery and the reconciles of the logical cluster, and resyncs its
+// objects with the ClusterResyncers.  It does nothing if the cluster isn't suspended.
+func (s *Suspensions) Resume(cluster logicalcluster.Name) error {
+	if !s.Suspended(cluster) {
+		return nil
+	}
+	s.gate.Release(cluster)
+
+	s.mu.Lock()
+	resyncers := append([]ClusterResyncer(nil), s.resyncers...)
+	s.mu.Unlock()
+	var errs []error
+	for _, r := range resyncers {
+		if err := r.TriggerClusterResync(cluster); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return kerrors.NewAggregate(errs)
+}
+
+// Suspended returns whether the logical cluster is suspended.
+func (s *Suspensions) Suspended(cluster logicalcluster.Name) bool {
+	return s.gate.Synced(cluster) != nil
+}
+
+// Synced implements SyncGate, it holds the requests of the suspended clusters until they are
+// resumed.
+func (s *Suspensions) Synced(cluster logicalcluster.Name) <-chan struct{} {
+	return s.gate.Synced(cluster)
+}
+
+// AddResyncer registers a ClusterResyncer resyncing the objects of the clusters when they are
+// resumed.
+func (s *Suspensions) AddResyncer(r ClusterResyncer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resyncers = append(s.resyncers, r)
+}
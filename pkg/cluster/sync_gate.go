@@ -0,0 +1,116 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kcp-dev/logicalcluster"
+
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+)
+
+// SyncGate tells the controllers whether the cache of a logical cluster is synced, so that
+// they hold the requests of the logical clusters whose caches are syncing, e.g. after an
+// outage, rather than reconciling them with stale data.
+type SyncGate interface {
+	// Synced returns nil if the requests of the logical cluster can be reconciled, or
+	// otherwise a channel closed once they can.
+	Synced(cluster logicalcluster.Name) <-chan struct{}
+}
+
+// Gate is a SyncGate which holds the requests of the logical clusters explicitly, e.g. from
+// the detection of an outage of a logical cluster until its cache is synced again.  The
+// requests of the logical clusters which are not held can be reconciled.
+type Gate struct {
+	mu   sync.Mutex
+	held map[logicalcluster.Name]chan struct{}
+}
+
+var _ SyncGate = &Gate{}
+
+// NewGate returns a Gate holding no logical cluster.
+func NewGate() *Gate {
+	return &Gate{held: map[logicalcluster.Name]chan struct{}{}}
+}
+
+// Hold holds the requests of the logical cluster until it is released.
+func (g *Gate) Hold(cluster logicalcluster.Name) {
+	g.hold(cluster)
+}
+
+// Release releases the requests of the logical cluster.
+func (g *Gate) Release(cluster logicalcluster.Name) {
+	g.release(cluster, nil)
+}
+
+// hold holds the requests of the logical cluster, and returns the channel released with them.
+func (g *Gate) hold(cluster logicalcluster.Name) chan struct{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	synced, ok := g.held[cluster]
+	if !ok {
+		synced = make(chan struct{})
+		g.held[cluster] = synced
+	}
+	return synced
+}
+
+// release releases the requests of the logical cluster, unless they are held with another
+// channel than the given one, if not nil, i.e. they were released and held again since.
+func (g *Gate) release(cluster logicalcluster.Name, heldWith chan struct{}) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if synced, ok := g.held[cluster]; ok && (heldWith == nil || synced == heldWith) {
+		close(synced)
+		delete(g.held, cluster)
+	}
+}
+
+// Synced implements SyncGate.
+func (g *Gate) Synced(cluster logicalcluster.Name) <-chan struct{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if synced, ok := g.held[cluster]; ok {
+		return synced
+	}
+	return nil
+}
+
+// HoldUntilSynced holds the requests of the logical cluster until its cache is synced, or
+// the context is done.  It blocks until then, and returns whether the cache is synced.
+func (g *Gate) HoldUntilSynced(ctx context.Context, cluster logicalcluster.Name, c cache.Cache) bool {
+	synced := g.hold(cluster)
+	defer g.release(cluster, synced)
+	return c.WaitForCacheSync(ctx)
+}
+
+// SyncGates is a SyncGate holding the requests of a logical cluster while any of its gates
+// holds them, e.g. for controllers waiting both for the Gate of a ClusterSet and for the
+// bootstrap of the logical clusters.
+type SyncGates []SyncGate
+
+// Synced implements SyncGate.
+func (g SyncGates) Synced(cluster logicalcluster.Name) <-chan struct{} {
+	for _, gate := range g {
+		if synced := gate.Synced(cluster); synced != nil {
+			return synced
+		}
+	}
+	return nil
+}
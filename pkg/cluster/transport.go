@@ -0,0 +1,98 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/kcp-dev/logicalcluster"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/client-go/rest"
+)
+
+// tuneConfig applies the rate limit and the shared transport of the set to the config of
+// the Cluster of a logical cluster.
+func (s *ClusterSet) tuneConfig(name logicalcluster.Name, config *rest.Config) (*rest.Config, error) {
+	config = rest.CopyConfig(config)
+	if s.options.RateLimit != nil {
+		qps, burst := s.options.RateLimit(name)
+		if qps > 0 {
+			config.QPS = qps
+			config.RateLimiter = nil
+		}
+		if burst > 0 {
+			config.Burst = burst
+			config.RateLimiter = nil
+		}
+	}
+	if s.options.Impersonate != nil {
+		if impersonate, ok := s.options.Impersonate(name); ok {
+			config.Impersonate = impersonate
+		}
+	}
+	if !s.options.ShareTransport || config.Transport != nil || config.ExecProvider != nil {
+		return config, nil
+	}
+	transport, err := s.transports.get(config, s.options.MaxConnsPerHost)
+	if err != nil {
+		return nil, err
+	}
+	config.Transport = transport
+	// the TLS settings are those of the shared transport, and client-go refuses configs
+	// setting both
+	config.TLSClientConfig = rest.TLSClientConfig{}
+	return config, nil
+}
+
+// transportPool holds the HTTP transports shared by the configs targeting the same server
+// with the same TLS settings.
+type transportPool struct {
+	mu         sync.Mutex
+	transports map[string]*http.Transport
+}
+
+func (p *transportPool) get(config *rest.Config, maxConnsPerHost int) (*http.Transport, error) {
+	host, err := url.Parse(config.Host)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("%s://%s %#v", host.Scheme, host.Host, config.TLSClientConfig)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if transport, ok := p.transports[key]; ok {
+		return transport, nil
+	}
+
+	tlsConfig, err := rest.TLSConfigFor(config)
+	if err != nil {
+		return nil, err
+	}
+	transport := utilnet.SetTransportDefaults(&http.Transport{
+		TLSClientConfig: tlsConfig,
+		Proxy:           config.Proxy,
+		DialContext:     config.Dial,
+		MaxConnsPerHost: maxConnsPerHost,
+	})
+	if p.transports == nil {
+		p.transports = map[string]*http.Transport{}
+	}
+	p.transports[key] = transport
+	return transport, nil
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compare
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// Comparer compares desired objects with actual ones, ignoring the fields matched by the
+// rules of its profiles.
+type Comparer struct {
+	// Scheme resolves the GroupVersionKinds of the typed objects.
+	Scheme *runtime.Scheme
+
+	// Default is the profile applied to all the kinds.
+	Default Profile
+
+	// Profiles are the profiles applied to each kind, in addition to Default.
+	Profiles map[schema.GroupKind]Profile
+}
+
+// New returns a Comparer applying ServerPopulatedProfile to all the kinds, and the profiles of
+// the built-in kinds it knows, e.g. DeploymentProfile.
+func New(scheme *runtime.Scheme) *Comparer {
+	return &Comparer{
+		Scheme:  scheme,
+		Default: ServerPopulatedProfile,
+		Profiles: map[schema.GroupKind]Profile{
+			appsv1.SchemeGroupVersion.WithKind("Deployment").GroupKind(): DeploymentProfile,
+			corev1.SchemeGroupVersion.WithKind("Service").GroupKind():    ServiceProfile,
+		},
+	}
+}
+
+// WithProfile adds the rules to the profile of the kind, and returns the Comparer.
+func (c *Comparer) WithProfile(gk schema.GroupKind, rules ...Rule) *Comparer {
+	if c.Profiles == nil {
+		c.Profiles = map[schema.GroupKind]Profile{}
+	}
+	c.Profiles[gk] = append(append(Profile(nil), c.Profiles[gk]...), rules...)
+	return c
+}
+
+// NeedsUpdate returns whether actual differs from desired in the fields which aren't ignored,
+// i.e. whether actual should be updated or patched to match desired.
+func (c *Comparer) NeedsUpdate(desired, actual client.Object) (bool, error) {
+	diffs, err := c.Diff(desired, actual)
+	return len(diffs) > 0, err
+}
+
+// Diff returns the dot-separated paths of the fields which differ between desired and actual
+// and aren't ignored, sorted.  A field set to its zero value is the same as an unset one.
+func (c *Comparer) Diff(desired, actual client.Object) ([]string, error) {
+	gvk, err := apiutil.GVKForObject(desired, c.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	desiredMap, err := toMap(desired)
+	if err != nil {
+		return nil, err
+	}
+	actualMap, err := toMap(actual)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := append(append(Profile(nil), c.Default...), c.Profiles[gvk.GroupKind()]...)
+	var diffs []string
+	compareValues(rules, nil, desiredMap, actualMap, &diffs)
+	sort.Strings(diffs)
+	return diffs, nil
+}
+
+// toMap returns the unstructured content of obj.
+func toMap(obj client.Object) (map[string]interface{}, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return u.Object, nil
+	}
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+}
+
+// compareValues appends to diffs the paths of the fields which differ between the desired and
+// actual values at the path, except the ignored ones.
+func compareValues(rules Profile, path []string, desired, actual interface{}, diffs *[]string) {
+	for _, rule := range rules {
+		if rule.matches(path) && (!rule.IfUnset || isUnset(desired)) {
+			return
+		}
+	}
+
+	desiredMap, desiredIsMap := desired.(map[string]interface{})
+	actualMap, actualIsMap := actual.(map[string]interface{})
+	if (desiredIsMap || isZero(desired)) && (actualIsMap || isZero(actual)) && (desiredIsMap || actualIsMap) {
+		keys := map[string]struct{}{}
+		for key := range desiredMap {
+			keys[key] = struct{}{}
+		}
+		for key := range actualMap {
+			keys[key] = struct{}{}
+		}
+		for key := range keys {
+			compareValues(rules, append(path[:len(path):len(path)], key), desiredMap[key], actualMap[key], diffs)
+		}
+		return
+	}
+
+	desiredList, desiredIsList := desired.([]interface{})
+	actualList, actualIsList := actual.([]interface{})
+	if desiredIsList && actualIsList && len(desiredList) == len(actualList) {
+		for i := range desiredList {
+			compareValues(rules, append(path[:len(path):len(path)], strconv.Itoa(i)), desiredList[i], actualList[i], diffs)
+		}
+		return
+	}
+
+	if isZero(desired) && isZero(actual) {
+		return
+	}
+	if !reflect.DeepEqual(desired, actual) {
+		*diffs = append(*diffs, strings.Join(path, "."))
+	}
+}
+
+// isUnset returns whether the unstructured value is unset, or an empty struct, map or list.
+// Unlike isZero, it tells the explicit zero values of the pointers, e.g. 0 replicas, apart.
+func isUnset(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	default:
+		return false
+	}
+}
+
+// isZero returns whether the unstructured value is unset or set to its zero value.
+func isZero(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compare_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+
+	"sigs.k8s.io/controller-runtime/pkg/compare"
+)
+
+var _ = Describe("Comparer", func() {
+	desiredDeployment := func() *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", Labels: map[string]string{"app": "web"}},
+			Spec: appsv1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{
+						Name:  "web",
+						Image: "nginx:1.21",
+						Ports: []corev1.ContainerPort{{ContainerPort: 80}},
+					}}},
+				},
+			},
+		}
+	}
+
+	// actualDeployment returns the deployment as read back from the API server, with its
+	// server-populated and defaulted fields.
+	actualDeployment := func() *appsv1.Deployment {
+		deploy := desiredDeployment()
+		deploy.UID = "1234"
+		deploy.ResourceVersion = "42"
+		deploy.Generation = 3
+		deploy.CreationTimestamp = metav1.Now()
+		deploy.ClusterName = "root:org:ws"
+		deploy.Annotations = map[string]string{"deployment.kubernetes.io/revision": "1"}
+		deploy.Spec.Replicas = pointer.Int32(1)
+		deploy.Spec.RevisionHistoryLimit = pointer.Int32(10)
+		deploy.Spec.ProgressDeadlineSeconds = pointer.Int32(600)
+		deploy.Spec.Strategy.Type = appsv1.RollingUpdateDeploymentStrategyType
+		pod := &deploy.Spec.Template.Spec
+		pod.RestartPolicy = corev1.RestartPolicyAlways
+		pod.DNSPolicy = corev1.DNSClusterFirst
+		pod.SchedulerName = "default-scheduler"
+		pod.TerminationGracePeriodSeconds = pointer.Int64(30)
+		pod.SecurityContext = &corev1.PodSecurityContext{}
+		pod.Containers[0].ImagePullPolicy = corev1.PullIfNotPresent
+		pod.Containers[0].TerminationMessagePath = "/dev/termination-log"
+		pod.Containers[0].TerminationMessagePolicy = corev1.TerminationMessageReadFile
+		pod.Containers[0].Ports[0].Protocol = corev1.ProtocolTCP
+		deploy.Status.ReadyReplicas = 1
+		return deploy
+	}
+
+	It("should ignore the server-populated and defaulted fields", func() {
+		c := compare.New(scheme.Scheme)
+		Expect(c.Diff(desiredDeployment(), actualDeployment())).To(BeEmpty())
+		Expect(c.NeedsUpdate(desiredDeployment(), actualDeployment())).To(BeFalse())
+	})
+
+	It("should report the fields which differ", func() {
+		c := compare.New(scheme.Scheme)
+		desired := desiredDeployment()
+		desired.Spec.Template.Spec.Containers[0].Image = "nginx:1.22"
+		desired.Labels["tier"] = "frontend"
+		Expect(c.Diff(desired, actualDeployment())).To(Equal([]string{
+			"metadata.labels.tier",
+			"spec.template.spec.containers.0.image",
+		}))
+		Expect(c.NeedsUpdate(desired, actualDeployment())).To(BeTrue())
+	})
+
+	It("should compare the defaulted fields set in the desired object", func() {
+		c := compare.New(scheme.Scheme)
+		desired := desiredDeployment()
+		desired.Spec.Replicas = pointer.Int32(0)
+		desired.Spec.Template.Spec.Containers[0].ImagePullPolicy = corev1.PullAlways
+		Expect(c.Diff(desired, actualDeployment())).To(Equal([]string{
+			"spec.replicas",
+			"spec.template.spec.containers.0.imagePullPolicy",
+		}))
+	})
+
+	It("should apply the rules added to the profile of a kind", func() {
+		c := compare.New(scheme.Scheme).WithProfile(schema.GroupKind{Group: "apps", Kind: "Deployment"},
+			compare.Ignore("metadata.labels"),
+			compare.Ignore("spec.template.spec.containers.*.image"),
+		)
+		desired := desiredDeployment()
+		desired.Spec.Template.Spec.Containers[0].Image = "nginx:1.22"
+		desired.Labels["tier"] = "frontend"
+		Expect(c.Diff(desired, actualDeployment())).To(BeEmpty())
+	})
+
+	It("should compare unstructured objects", func() {
+		c := compare.New(scheme.Scheme)
+		desired := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "example.com/v1",
+			"kind":       "Widget",
+			"metadata":   map[string]interface{}{"name": "w"},
+			"spec":       map[string]interface{}{"size": int64(2), "tags": []interface{}{"a"}},
+		}}
+		actual := desired.DeepCopy()
+		actual.SetResourceVersion("7")
+		Expect(c.Diff(desired, actual)).To(BeEmpty())
+
+		Expect(unstructured.SetNestedSlice(actual.Object, []interface{}{"a", "b"}, "spec", "tags")).To(Succeed())
+		Expect(c.Diff(desired, actual)).To(Equal([]string{"spec.tags"}))
+	})
+})
@@ -0,0 +1,26 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package compare compares the desired state of objects with their actual state, to tell whether
+an Update or a Patch is needed, so that reconcilers don't issue no-op writes on every resync.
+
+The fields which the desired objects don't manage are ignored with rules, e.g. the fields
+populated by the API server, such as metadata.resourceVersion, or defaulted by it, such as the
+imagePullPolicy of the containers.  A Comparer applies a default profile of rules to all the
+kinds, and a profile of its own to each kind it knows, e.g. DeploymentProfile.
+*/
+package compare
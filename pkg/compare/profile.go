@@ -0,0 +1,129 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compare
+
+import (
+	"strings"
+)
+
+// Rule ignores a field when comparing objects.
+type Rule struct {
+	// Path is the path of the field, e.g. ["spec", "replicas"].  "*" matches any key of a map or
+	// any item of a list, e.g. ["spec", "ports", "*", "protocol"].
+	Path []string
+
+	// IfUnset only ignores the field if the desired object doesn't set it, e.g. for the
+	// fields defaulted by the API server.
+	IfUnset bool
+}
+
+// Ignore returns a Rule ignoring the field of the dot-separated path, e.g.
+// "spec.ports.*.protocol".  Build the Rule directly for the paths with keys containing dots,
+// e.g. annotations.
+func Ignore(path string) Rule {
+	return Rule{Path: strings.Split(path, ".")}
+}
+
+// IgnoreIfUnset returns a Rule ignoring the field of the dot-separated path if the desired
+// object doesn't set it, see Ignore.
+func IgnoreIfUnset(path string) Rule {
+	return Rule{Path: strings.Split(path, "."), IfUnset: true}
+}
+
+// matches returns whether the rule matches the path.
+func (r Rule) matches(path []string) bool {
+	if len(r.Path) != len(path) {
+		return false
+	}
+	for i, segment := range r.Path {
+		if segment != "*" && segment != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Profile is the set of rules for a kind of objects.
+type Profile []Rule
+
+// ServerPopulatedProfile ignores the fields populated by the API server: the server-managed
+// metadata, the type meta and the logical cluster unless they are set in the desired object,
+// and the status.
+var ServerPopulatedProfile = Profile{
+	IgnoreIfUnset("apiVersion"),
+	IgnoreIfUnset("kind"),
+	Ignore("metadata.uid"),
+	Ignore("metadata.resourceVersion"),
+	Ignore("metadata.generation"),
+	Ignore("metadata.creationTimestamp"),
+	Ignore("metadata.deletionTimestamp"),
+	Ignore("metadata.deletionGracePeriodSeconds"),
+	Ignore("metadata.managedFields"),
+	Ignore("metadata.selfLink"),
+	IgnoreIfUnset("metadata.clusterName"),
+	{Path: []string{"metadata", "annotations", "kcp.dev/cluster"}, IfUnset: true},
+	Ignore("status"),
+}
+
+// PodTemplateProfile returns the rules ignoring the fields of the pod template at the path,
+// e.g. "spec.template", which the API server defaults.
+func PodTemplateProfile(path string) Profile {
+	var profile Profile
+	for _, field := range []string{
+		"metadata.creationTimestamp",
+		"spec.dnsPolicy",
+		"spec.restartPolicy",
+		"spec.schedulerName",
+		"spec.securityContext",
+		"spec.terminationGracePeriodSeconds",
+		"spec.containers.*.imagePullPolicy",
+		"spec.containers.*.resources",
+		"spec.containers.*.terminationMessagePath",
+		"spec.containers.*.terminationMessagePolicy",
+		"spec.containers.*.ports.*.protocol",
+		"spec.initContainers.*.imagePullPolicy",
+		"spec.initContainers.*.resources",
+		"spec.initContainers.*.terminationMessagePath",
+		"spec.initContainers.*.terminationMessagePolicy",
+	} {
+		profile = append(profile, IgnoreIfUnset(path+"."+field))
+	}
+	return profile
+}
+
+// DeploymentProfile ignores the fields of Deployments which the API server defaults.
+var DeploymentProfile = append(Profile{
+	IgnoreIfUnset("spec.progressDeadlineSeconds"),
+	IgnoreIfUnset("spec.replicas"),
+	IgnoreIfUnset("spec.revisionHistoryLimit"),
+	IgnoreIfUnset("spec.strategy"),
+	{Path: []string{"metadata", "annotations", "deployment.kubernetes.io/revision"}, IfUnset: true},
+}, PodTemplateProfile("spec.template")...)
+
+// ServiceProfile ignores the fields of Services which the API server defaults or allocates.
+var ServiceProfile = Profile{
+	IgnoreIfUnset("spec.clusterIP"),
+	IgnoreIfUnset("spec.clusterIPs"),
+	IgnoreIfUnset("spec.internalTrafficPolicy"),
+	IgnoreIfUnset("spec.ipFamilies"),
+	IgnoreIfUnset("spec.ipFamilyPolicy"),
+	IgnoreIfUnset("spec.sessionAffinity"),
+	IgnoreIfUnset("spec.type"),
+	IgnoreIfUnset("spec.ports.*.protocol"),
+	IgnoreIfUnset("spec.ports.*.targetPort"),
+	IgnoreIfUnset("spec.ports.*.nodePort"),
+}
@@ -0,0 +1,298 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configwatcher
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/internal/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var log = logf.RuntimeLog.WithName("configwatcher")
+
+// retryPeriod is how long to wait before watching the ConfigMap again after the watch failed.
+var retryPeriod = time.Second
+
+// Callback is called with the data of the configuration when it changes.  The data is the
+// data of the ConfigMap, or the contents of the file keyed by its base name.  It is empty if the
+// ConfigMap was deleted.
+type Callback func(ctx context.Context, data map[string]string) error
+
+// Resyncer is implemented by the controllers, see controller.Controller.
+type Resyncer interface {
+	// TriggerResync makes the controller reconcile all its objects again.
+	TriggerResync() error
+}
+
+// ConfigWatcher watches a configuration, a ConfigMap or a file, and calls its callbacks
+// when the configuration changes.  It is a Runnable, to be added to the manager.
+type ConfigWatcher struct {
+	mu        sync.RWMutex
+	data      map[string]string
+	loaded    bool
+	callbacks []Callback
+
+	// watch watches the configuration and calls update until the context is done.
+	watch func(ctx context.Context) error
+}
+
+// NewForConfigMap returns a ConfigWatcher watching the ConfigMap with the given key.  The
+// cluster of the key is the logical cluster of the ConfigMap.
+func NewForConfigMap(c client.WithWatch, key client.ObjectKey) *ConfigWatcher {
+	w := &ConfigWatcher{}
+	w.watch = func(ctx context.Context) error {
+		if !key.Cluster.Empty() {
+			ctx = kcpclient.WithCluster(ctx, key.Cluster)
+		}
+		wait.UntilWithContext(ctx, func(ctx context.Context) {
+			if err := w.watchConfigMap(ctx, c, key); err != nil {
+				log.Error(err, "error watching the ConfigMap", "key", key)
+			}
+		}, retryPeriod)
+		return nil
+	}
+	return w
+}
+
+// NewForFile returns a ConfigWatcher watching the file at the given path, e.g. a ConfigMap
+// mounted as a volume.
+func NewForFile(path string) *ConfigWatcher {
+	w := &ConfigWatcher{}
+	w.watch = func(ctx context.Context) error {
+		return w.watchFile(ctx, path)
+	}
+	return w
+}
+
+// AddCallback registers a callback called when the configuration changes.  Callbacks are
+// called sequentially, in the order they were registered, with the initial configuration
+// once it is loaded, and then with every change.
+func (w *ConfigWatcher) AddCallback(callback Callback) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.callbacks = append(w.callbacks, callback)
+}
+
+// ResyncOnChange makes the given controllers reconcile all their objects, in all the logical
+// clusters, when the configuration changes.
+func (w *ConfigWatcher) ResyncOnChange(controllers ...Resyncer) {
+	w.AddCallback(func(context.Context, map[string]string) error {
+		for _, ctrl := range controllers {
+			if err := ctrl.TriggerResync(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DecodeFunc decodes the data of the configuration into the value of a reconcile.Config.
+type DecodeFunc func(data map[string]string) (interface{}, error)
+
+// DecodeYAML returns a DecodeFunc unmarshalling the YAML, or JSON, document under the given key
+// of the data into the value returned by newValue, e.g. a pointer to a typed configuration struct
+// holding its defaults.  The value is left as returned by newValue if the key is missing.
+func DecodeYAML(key string, newValue func() interface{}) DecodeFunc {
+	return func(data map[string]string) (interface{}, error) {
+		value := newValue()
+		if err := yaml.Unmarshal([]byte(data[key]), value); err != nil {
+			return nil, fmt.Errorf("failed to decode %q: %w", key, err)
+		}
+		return value, nil
+	}
+}
+
+// UpdateOnChange replaces the value of the given controller configuration with the decoded
+// configuration when it changes.  The configuration keeps its previous value if it can't be
+// decoded.  It should be registered before ResyncOnChange, so that the resynced objects are
+// reconciled with the new configuration.
+func (w *ConfigWatcher) UpdateOnChange(config *reconcile.Config, decode DecodeFunc) {
+	w.AddCallback(func(_ context.Context, data map[string]string) error {
+		value, err := decode(data)
+		if err != nil {
+			return err
+		}
+		config.Set(value)
+		return nil
+	})
+}
+
+// Data returns the current data of the configuration, and whether it was loaded.
+func (w *ConfigWatcher) Data() (map[string]string, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.data, w.loaded
+}
+
+// Start watches the configuration until the context is done.
+func (w *ConfigWatcher) Start(ctx context.Context) error {
+	log.Info("Starting configuration watcher")
+	return w.watch(ctx)
+}
+
+// NeedLeaderElection implements the LeaderElectionRunnable interface, so that the
+// configuration is watched by all the replicas of the manager.
+func (w *ConfigWatcher) NeedLeaderElection() bool {
+	return false
+}
+
+// update records the data of the configuration, and calls the callbacks if it changed.
+func (w *ConfigWatcher) update(ctx context.Context, data map[string]string) {
+	if data == nil {
+		data = map[string]string{}
+	}
+	w.mu.Lock()
+	if w.loaded && reflect.DeepEqual(w.data, data) {
+		w.mu.Unlock()
+		return
+	}
+	w.data, w.loaded = data, true
+	callbacks := w.callbacks
+	w.mu.Unlock()
+
+	log.Info("Updated configuration")
+	for _, callback := range callbacks {
+		if err := callback(ctx, data); err != nil {
+			log.Error(err, "error calling a configuration callback")
+		}
+	}
+}
+
+// watchConfigMap reads the ConfigMap, then watches it until the watch ends.
+func (w *ConfigWatcher) watchConfigMap(ctx context.Context, c client.WithWatch, key client.ObjectKey) error {
+	cm := &corev1.ConfigMap{}
+	resourceVersion := ""
+	switch err := c.Get(ctx, key, cm); {
+	case apierrors.IsNotFound(err):
+		w.update(ctx, nil)
+	case err != nil:
+		return err
+	default:
+		w.update(ctx, configMapData(cm))
+		resourceVersion = cm.ResourceVersion
+	}
+
+	watcher, err := c.Watch(ctx, &corev1.ConfigMapList{}, &client.ListOptions{
+		Namespace:     key.Namespace,
+		FieldSelector: fields.OneTermEqualSelector("metadata.name", key.Name),
+		Raw:           &metav1.ListOptions{ResourceVersion: resourceVersion},
+	})
+	if err != nil {
+		return err
+	}
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case evt, ok := <-watcher.ResultChan():
+			if !ok {
+				return nil
+			}
+			switch evt.Type {
+			case watch.Error:
+				return apierrors.FromObject(evt.Object)
+			case watch.Added, watch.Modified, watch.Deleted:
+				cm, ok := evt.Object.(*corev1.ConfigMap)
+				if !ok || cm.Name != key.Name {
+					continue
+				}
+				if evt.Type == watch.Deleted {
+					w.update(ctx, nil)
+				} else {
+					w.update(ctx, configMapData(cm))
+				}
+			}
+		}
+	}
+}
+
+// configMapData returns the data and the binary data of the ConfigMap.
+func configMapData(cm *corev1.ConfigMap) map[string]string {
+	data := make(map[string]string, len(cm.Data)+len(cm.BinaryData))
+	for k, v := range cm.Data {
+		data[k] = v
+	}
+	for k, v := range cm.BinaryData {
+		data[k] = string(v)
+	}
+	return data
+}
+
+// watchFile reads the file, then reads it again whenever its directory changes, which also
+// catches the atomic updates of the ConfigMaps mounted as volumes.
+func (w *ConfigWatcher) watchFile(ctx context.Context, path string) error {
+	read := func() error {
+		contents, err := ioutil.ReadFile(path) //nolint:gosec // the path is the configuration file
+		if err != nil {
+			return err
+		}
+		w.update(ctx, map[string]string{filepath.Base(path): string(contents)})
+		return nil
+	}
+	if err := read(); err != nil {
+		return fmt.Errorf("unable to read the configuration file: %w", err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			log.V(1).Info("configuration file event", "event", event)
+			if err := read(); err != nil {
+				log.Error(err, "error re-reading the configuration file")
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Error(err, "configuration file watch error")
+		}
+	}
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configwatcher_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/configwatcher"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type fakeResyncer struct {
+	resyncs int32
+}
+
+func (r *fakeResyncer) TriggerResync() error {
+	atomic.AddInt32(&r.resyncs, 1)
+	return nil
+}
+
+var _ = Describe("ConfigWatcher", func() {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	var changes chan map[string]string
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		changes = make(chan map[string]string, 10)
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	record := func(_ context.Context, data map[string]string) error {
+		changes <- data
+		return nil
+	}
+
+	Describe("for a file", func() {
+		var dir, path string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "configwatcher-")
+			Expect(err).NotTo(HaveOccurred())
+			path = filepath.Join(dir, "config.yaml")
+			Expect(ioutil.WriteFile(path, []byte("quota: 1"), 0600)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		It("should call the callbacks with the initial contents, then on every change", func() {
+			watcher := configwatcher.NewForFile(path)
+			watcher.AddCallback(record)
+			resyncer := &fakeResyncer{}
+			watcher.ResyncOnChange(resyncer)
+			go func() {
+				defer GinkgoRecover()
+				Expect(watcher.Start(ctx)).To(Succeed())
+			}()
+
+			Eventually(changes).Should(Receive(Equal(map[string]string{"config.yaml": "quota: 1"})))
+
+			By("replacing the file atomically")
+			Expect(ioutil.WriteFile(filepath.Join(dir, "new"), []byte("quota: 2"), 0600)).To(Succeed())
+			Expect(os.Rename(filepath.Join(dir, "new"), path)).To(Succeed())
+			Eventually(changes).Should(Receive(Equal(map[string]string{"config.yaml": "quota: 2"})))
+
+			data, loaded := watcher.Data()
+			Expect(loaded).To(BeTrue())
+			Expect(data).To(HaveKeyWithValue("config.yaml", "quota: 2"))
+			Expect(atomic.LoadInt32(&resyncer.resyncs)).To(BeEquivalentTo(2))
+		})
+
+		It("should update the controller configuration with the decoded contents", func() {
+			type quotas struct {
+				PerCluster int `json:"perCluster"`
+				Max        int `json:"max"`
+			}
+			config := reconcile.NewConfig(nil)
+			watcher := configwatcher.NewForFile(path)
+			watcher.UpdateOnChange(config, configwatcher.DecodeYAML("config.yaml", func() interface{} {
+				return &quotas{Max: 10}
+			}))
+			watcher.AddCallback(record)
+			Expect(ioutil.WriteFile(path, []byte("perCluster: 1"), 0600)).To(Succeed())
+			go func() {
+				defer GinkgoRecover()
+				Expect(watcher.Start(ctx)).To(Succeed())
+			}()
+
+			Eventually(changes).Should(Receive())
+			Expect(config.Get()).To(Equal(&quotas{PerCluster: 1, Max: 10}))
+
+			By("keeping the previous configuration if the new one can't be decoded")
+			Expect(ioutil.WriteFile(filepath.Join(dir, "new"), []byte("perCluster: [}"), 0600)).To(Succeed())
+			Expect(os.Rename(filepath.Join(dir, "new"), path)).To(Succeed())
+			Eventually(changes).Should(Receive())
+			Expect(config.Get()).To(Equal(&quotas{PerCluster: 1, Max: 10}))
+
+			Expect(ioutil.WriteFile(filepath.Join(dir, "new"), []byte("perCluster: 2\nmax: 3"), 0600)).To(Succeed())
+			Expect(os.Rename(filepath.Join(dir, "new"), path)).To(Succeed())
+			Eventually(changes).Should(Receive())
+			Expect(config.Get()).To(Equal(&quotas{PerCluster: 2, Max: 3}))
+		})
+
+		It("should fail to start if the file can't be read", func() {
+			watcher := configwatcher.NewForFile(filepath.Join(dir, "missing"))
+			Expect(watcher.Start(ctx)).NotTo(Succeed())
+		})
+	})
+
+	Describe("for a ConfigMap", func() {
+		cluster := logicalcluster.New("root:org:ws")
+		key := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "default", Name: "config"}, Cluster: cluster}
+		var c client.WithWatch
+
+		BeforeEach(func() {
+			c = fake.NewClusterBuilder().WithObjects(cluster, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "config"},
+				Data:       map[string]string{"quota": "1"},
+			}).Build()
+		})
+
+		It("should call the callbacks with the initial data, then on every change", func() {
+			watcher := configwatcher.NewForConfigMap(c, key)
+			watcher.AddCallback(record)
+			go func() {
+				defer GinkgoRecover()
+				Expect(watcher.Start(ctx)).To(Succeed())
+			}()
+			Eventually(changes).Should(Receive(Equal(map[string]string{"quota": "1"})))
+
+			By("updating the ConfigMap")
+			cm := &corev1.ConfigMap{}
+			Expect(c.Get(ctx, key, cm)).To(Succeed())
+			cm.Data["quota"] = "2"
+			Expect(c.Update(ctx, cm)).To(Succeed())
+			Eventually(changes).Should(Receive(Equal(map[string]string{"quota": "2"})))
+
+			By("ignoring the updates which don't change the data")
+			cm.Labels = map[string]string{"foo": "bar"}
+			Expect(c.Update(ctx, cm)).To(Succeed())
+			Consistently(changes, "100ms").ShouldNot(Receive())
+
+			By("deleting the ConfigMap")
+			Expect(c.Delete(ctx, cm)).To(Succeed())
+			Eventually(changes).Should(Receive(BeEmpty()))
+		})
+	})
+})
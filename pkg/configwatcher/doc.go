@@ -0,0 +1,39 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package configwatcher is a helper for reloading the configuration of controllers
+without restarting them.  A ConfigWatcher watches a ConfigMap, or a file on disk,
+and calls its callbacks whenever the configuration changes, e.g. to make controllers
+reconcile all their objects in all the logical clusters with the new configuration:
+
+	watcher := configwatcher.NewForConfigMap(c, client.ObjectKey{...})
+	watcher.ResyncOnChange(ctrl)
+	err := mgr.Add(watcher)
+
+The configuration can also be decoded into a typed struct passed to the Reconciler of a
+controller in the context of each request, and replaced on every change:
+
+	config := reconcile.NewConfig(&QuotaConfig{PerCluster: 10})
+	err := builder.ControllerManagedBy(mgr).For(&v1.Quota{}).WithConfig(config).Complete(r)
+	...
+	watcher.UpdateOnChange(config, configwatcher.DecodeYAML("config.yaml", func() interface{} {
+		return &QuotaConfig{PerCluster: 10}
+	}))
+
+The Reconciler then reads it with reconcile.ConfigFrom(ctx).
+*/
+package configwatcher
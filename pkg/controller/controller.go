@@ -22,14 +22,24 @@ import (
 	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/kcp-dev/logicalcluster"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/audit"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/internal/controller"
+	"sigs.k8s.io/controller-runtime/pkg/leaderelection"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/objectlock"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/ratelimiter"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
+	"sigs.k8s.io/controller-runtime/pkg/workerpool"
 )
 
 // Options are the arguments for creating a new Controller.
@@ -37,14 +47,75 @@ type Options struct {
 	// MaxConcurrentReconciles is the maximum number of concurrent Reconciles which can be run. Defaults to 1.
 	MaxConcurrentReconciles int
 
+	// MaxConcurrentReconcilesPerCluster is the maximum number of concurrent Reconciles which can be run
+	// against the same logical cluster, so that a controller with a high MaxConcurrentReconciles doesn't
+	// overwhelm small or slow workspaces.  Defaults to 0, which means no per-cluster limit.
+	MaxConcurrentReconcilesPerCluster int
+
+	// MaxConcurrentReconcilesByCluster overrides MaxConcurrentReconcilesPerCluster for the given logical
+	// clusters, e.g. to give a large workspace more reconciles, or to throttle a tenant workspace stuck
+	// in a failure loop.  A limit of 0 or less means no limit for the cluster.
+	MaxConcurrentReconcilesByCluster map[logicalcluster.Name]int
+
 	// Reconciler reconciles an object
 	Reconciler reconcile.Reconciler
 
+	// BatchReconciler, if set, is passed the requests of the controller in batches, across logical
+	// clusters, rather than Reconciler being passed each request, e.g. to compute fleet-wide state
+	// in one pass.  The batches are reconciled one at a time, so MaxConcurrentReconciles must be 1.
+	// Reconciler may be left unset, it defaults to passing BatchReconciler a batch of one request.
+	// SyncGate, MaxConcurrentReconcilesPerCluster, ObjectLocks, WaitForCacheConsistency and
+	// AuditSink apply to single requests, not to batches.
+	BatchReconciler reconcile.BatchReconciler
+
+	// MaxBatchSize is the maximum number of requests passed to BatchReconciler at once.  Defaults
+	// to 100.
+	MaxBatchSize int
+
+	// BatchWindow is how long the controller waits after the first request of a batch for more
+	// requests to be queued, e.g. for the events of a change affecting many logical clusters.
+	// Defaults to 0: the batch holds the requests already queued only.
+	BatchWindow time.Duration
+
 	// RateLimiter is used to limit how frequently requests may be queued.
 	// Defaults to MaxOfRateLimiter which has both overall and per-item rate limiting.
 	// The overall is a token bucket and the per-item is exponential.
 	RateLimiter ratelimiter.RateLimiter
 
+	// NewClusterRateLimiter, if set, builds a separate rate limiter for each logical cluster, so that
+	// the failures of a noisy cluster don't exhaust the overall rate limit of the others, see
+	// ratelimiter.NewPerCluster.  It can't be set along with RateLimiter.
+	NewClusterRateLimiter func() ratelimiter.RateLimiter
+
+	// RateLimiterByCluster overrides the rate limiter of the requests of the given logical clusters,
+	// e.g. to back off faster in a tenant workspace generating failure loops, so that it doesn't use
+	// up the retry budget of the others.  See ratelimiter.NewByCluster.
+	RateLimiterByCluster map[logicalcluster.Name]ratelimiter.RateLimiter
+
+	// FairQueueByCluster makes the queue of the controller serve the requests of the logical clusters
+	// in turn, rather than in the order they were added, so that a noisy cluster doesn't starve the
+	// reconciles of the others.  The number of clusters with requests waiting is exposed in the
+	// controller_runtime_fair_queue_clusters metric.  Combined with PrioritizeDeletes, the requests
+	// for Delete events are served first within their cluster.
+	FairQueueByCluster bool
+
+	// DirectNotification hands the requests enqueued by the event handlers over to the workers of the
+	// controller right away, for latency-critical reactions, rather than through a rate limited queue:
+	// the MaxConcurrentReconciles workers form a bounded pool, and at most DirectNotificationCapacity
+	// requests wait for one of them, beyond which DirectNotificationOverflow applies.  The failed
+	// requests are still retried with the backoff of RateLimiter.  It can't be combined with
+	// FairQueueByCluster, PrioritizeDeletes nor DeprioritizeInitialSync.
+	DirectNotification bool
+
+	// DirectNotificationCapacity is the number of requests which can wait for a worker with
+	// DirectNotification.  Defaults to 100.
+	DirectNotificationCapacity int
+
+	// DirectNotificationOverflow is what happens to the requests enqueued while DirectNotificationCapacity
+	// requests are waiting.  Defaults to DropNewest.  The dropped requests are counted in the
+	// controller_runtime_direct_queue_dropped_total metric.
+	DirectNotificationOverflow OverflowPolicy
+
 	// Log is the logger used for this controller and passed to each reconciliation
 	// request via the context field.
 	Log logr.Logger
@@ -53,10 +124,130 @@ type Options struct {
 	// Defaults to 2 minutes if not set.
 	CacheSyncTimeout time.Duration
 
+	// CacheSyncTimeoutByObject overrides CacheSyncTimeout for the Kind sources watching
+	// the GroupVersionKind of the given objects, so that a single very large type doesn't
+	// require a large timeout for every watch of the controller.
+	CacheSyncTimeoutByObject map[client.Object]time.Duration
+
 	// RecoverPanic indicates whether the panic caused by reconcile should be recovered.
 	RecoverPanic bool
+
+	// PrioritizeDeletes makes the controller process the requests enqueued for Delete
+	// events before the ones enqueued for other events, so that cleanup, e.g. finalizer
+	// processing, isn't starved by a storm of updates from busy workspaces.
+	PrioritizeDeletes bool
+
+	// DeprioritizeInitialSync makes the controller process the requests enqueued for the Create
+	// events of the objects which existed before their logical cluster was first seen by the
+	// controller after the requests enqueued for the other events.  This way, the flood of
+	// events of the initial list of a newly added cluster doesn't crowd out the changes in the
+	// existing clusters.  The objects listed when the controller starts are deprioritized too.
+	DeprioritizeInitialSync bool
+
+	// TombstoneDeletes makes the controller enqueue tombstone requests for Delete events: the
+	// requests enqueued by the event handlers are flagged as deletions and carry the last known
+	// labels of the deleted object, see reconcile.Request.IsTombstone and TombstoneLabels.  This
+	// lets reconcilers clean up external state without adding finalizers to objects they don't
+	// own.  Tombstones are distinct from the requests enqueued for the other events, so both
+	// may be reconciled for the same object.
+	TombstoneDeletes bool
+
+	// WaitForCacheConsistency makes the controller record, when a request is enqueued, the highest
+	// resourceVersion observed by the informers of its Kind sources, and wait until all of them have
+	// observed it before reconciling the request.  This keeps the reconciler from reading objects
+	// from the cache which are older than the event that triggered it, e.g. the primary object
+	// after an event for one of its owned objects.  The resourceVersion is passed to the reconciler
+	// in the reconcile.RequestInfo of the context.
+	WaitForCacheConsistency bool
+
+	// CacheConsistencyTimeout is the longest time to wait for the informers to catch up with a
+	// request, after which it is reconciled anyway.  Defaults to 10 seconds if not set.
+	CacheConsistencyTimeout time.Duration
+
+	// DependsOn are the Runnables the controller depends on, e.g. a ClusterSet whose clusters it
+	// watches.  The manager starts the controller once they are ready and stops it before them,
+	// see manager.DependentRunnable.  They must be added to the manager too.
+	DependsOn []manager.Runnable
+
+	// Config is the configuration of the Reconciler.  Its value is passed to the Reconciler in the
+	// context of each request, see reconcile.ConfigFrom, so that it can be updated while the
+	// controller runs, e.g. by a configwatcher.ConfigWatcher.
+	Config *reconcile.Config
+
+	// QueueHandover, if set, hands the requests pending in the queue of the controller over to
+	// the next leader when the controller stops, along with the backoff they have left, and takes
+	// the ones handed over by the previous leader when it starts.  This keeps a graceful leader
+	// transition, e.g. during a rolling update with LeaderElectionReleaseOnCancel, from dropping
+	// pending work or resetting long backoffs.  See leaderelection.NewConfigMapQueueHandover.
+	QueueHandover leaderelection.QueueHandover
+
+	// AuditSink, if set, receives a record of every reconcile of the controller: the key and logical
+	// cluster of the request, what triggered it, its duration, result and error class.  See the
+	// audit package.
+	AuditSink audit.Sink
+
+	// ObjectLocks, if set, are locked for the object of each request before reconciling it, so that
+	// the controllers sharing them never reconcile the same object concurrently.  Defaults to the
+	// locks of the manager, see manager.Options.SerializeReconciles.
+	ObjectLocks *objectlock.Locks
+
+	// GroupKind is the kind of the objects reconciled by the controller, which identifies them in
+	// ObjectLocks along with the requests.  It is set by the builder to the kind passed to For.  The
+	// requests of the controllers without a GroupKind are only locked if they carry their own.
+	GroupKind schema.GroupKind
+
+	// WorkerPool, if set, bounds the reconciles running concurrently across the controllers sharing
+	// it: each reconcile waits for a worker of the pool, beyond the MaxConcurrentReconciles of the
+	// controller.  Defaults to the pool of the manager, see manager.Options.ReconcileWorkerPoolSize.
+	WorkerPool *workerpool.Pool
+
+	// WorkerPoolWeight is the share of the workers of WorkerPool the controller gets while other
+	// controllers wait for them too, e.g. 4 for a critical controller which must not be starved by
+	// a housekeeping controller of weight 1.  The shares are measured in worker time.  Defaults to 1.
+	WorkerPoolWeight int
+
+	// SyncGate, if set, holds the requests of the logical clusters whose caches are not synced, e.g.
+	// while they resync after an outage, instead of reconciling them against a stale cache.  The
+	// held requests are not failed: they are processed once the cache of their cluster is synced.
+	// Set it to the Gate of the ClusterSet of the clusters, see cluster.ClusterSetOptions.Gate.
+	SyncGate cluster.SyncGate
+
+	// ClusterSuspensions, if set, suspend the event delivery and the reconciles of the logical
+	// clusters suspended with it, and resync the objects of the clusters when they are resumed.
+	// Defaults to the suspensions of the manager, see manager.Options.EnableClusterSuspensions.
+	ClusterSuspensions *cluster.Suspensions
+
+	// ClusterSet, if set, is the set of the logical clusters the controller watches, e.g. the
+	// ClusterSet of the manager.  Once a cluster is removed from it, e.g. because its workspace
+	// was deleted, the requests of the cluster still queued are dropped rather than failing
+	// against a stopped cache, and the reconciles of the cluster in flight are not retried.
+	ClusterSet *cluster.ClusterSet
+
+	// ReconcileRemovedClusters makes the controller reconcile a final request for each logical
+	// cluster removed from ClusterSet, so that the reconciler can clean up the external state of
+	// the cluster, see reconcile.ClusterRemovedRequest and reconcile.Request.IsClusterRemoved.
+	ReconcileRemovedClusters bool
+
+	// TracerProvider, if set, provides the Tracer starting a span around every reconcile of the
+	// controller, with its reconcileID and the logical cluster, kind and key of the request.
+	// Defaults to the provider of the manager, see manager.Options.TracerProvider.
+	TracerProvider trace.TracerProvider
 }
 
+// OverflowPolicy is what happens to the requests enqueued while the queue of a controller with
+// DirectNotification is full.
+type OverflowPolicy = controller.OverflowPolicy
+
+const (
+	// DropNewest drops the requests enqueued while the queue is full.
+	DropNewest = controller.DropNewest
+	// DropOldest drops the request waiting for the longest time to make room for the request enqueued.
+	DropOldest = controller.DropOldest
+	// Block blocks the event handler enqueuing the request until there is room.  This delays the
+	// events of all the controllers sharing the informer.
+	Block = controller.Block
+)
+
 // Controller implements a Kubernetes API.  A Controller manages a work queue fed reconcile.Requests
 // from source.Sources.  Work is performed through the reconcile.Reconciler for each enqueued item.
 // Work typically is reads and writes Kubernetes objects to make the system state match the state specified
@@ -77,6 +268,15 @@ type Controller interface {
 	// controller has an error starting.
 	Start(ctx context.Context) error
 
+	// TriggerResync makes the controller reconcile all the objects observed by its sources again,
+	// e.g. after a change of the configuration of the reconciler.  It does nothing if the controller
+	// isn't started.
+	TriggerResync() error
+
+	// TriggerClusterResync is TriggerResync for the objects of a single logical cluster, e.g. once
+	// the cluster is resumed after a maintenance.
+	TriggerClusterResync(cluster logicalcluster.Name) error
+
 	// GetLogger returns this controller logger prefilled with basic information.
 	GetLogger() logr.Logger
 }
@@ -96,6 +296,24 @@ func New(name string, mgr manager.Manager, options Options) (Controller, error)
 // NewUnmanaged returns a new controller without adding it to the manager. The
 // caller is responsible for starting the returned controller.
 func NewUnmanaged(name string, mgr manager.Manager, options Options) (Controller, error) {
+	if options.BatchReconciler != nil {
+		if options.MaxConcurrentReconciles > 1 {
+			return nil, fmt.Errorf("must not specify MaxConcurrentReconciles along with BatchReconciler")
+		}
+		if options.MaxBatchSize <= 0 {
+			options.MaxBatchSize = 100
+		}
+		if options.Reconciler == nil {
+			batch := options.BatchReconciler
+			options.Reconciler = reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+				return batch.ReconcileBatch(ctx, []reconcile.Request{req})
+			})
+		}
+		if err := mgr.SetFields(options.BatchReconciler); err != nil {
+			return nil, err
+		}
+	}
+
 	if options.Reconciler == nil {
 		return nil, fmt.Errorf("must specify Reconciler")
 	}
@@ -116,10 +334,76 @@ func NewUnmanaged(name string, mgr manager.Manager, options Options) (Controller
 		options.CacheSyncTimeout = 2 * time.Minute
 	}
 
+	if options.CacheConsistencyTimeout == 0 {
+		options.CacheConsistencyTimeout = 10 * time.Second
+	}
+
+	if options.NewClusterRateLimiter != nil {
+		if options.RateLimiter != nil {
+			return nil, fmt.Errorf("must not specify both RateLimiter and NewClusterRateLimiter")
+		}
+		options.RateLimiter = ratelimiter.NewPerCluster(options.NewClusterRateLimiter)
+	}
+
 	if options.RateLimiter == nil {
 		options.RateLimiter = workqueue.DefaultControllerRateLimiter()
 	}
 
+	if len(options.RateLimiterByCluster) > 0 {
+		options.RateLimiter = ratelimiter.NewByCluster(options.RateLimiterByCluster, options.RateLimiter)
+	}
+
+	if options.DirectNotification {
+		if options.FairQueueByCluster || options.PrioritizeDeletes || options.DeprioritizeInitialSync {
+			return nil, fmt.Errorf("must not specify DirectNotification along with FairQueueByCluster, PrioritizeDeletes or DeprioritizeInitialSync")
+		}
+		if options.DirectNotificationCapacity <= 0 {
+			options.DirectNotificationCapacity = 100
+		}
+	}
+
+	if options.ReconcileRemovedClusters && options.ClusterSet == nil {
+		return nil, fmt.Errorf("must specify the ClusterSet of the removed clusters to reconcile")
+	}
+
+	if options.ObjectLocks == nil {
+		options.ObjectLocks = mgr.GetObjectLocks()
+	}
+
+	if options.WorkerPool == nil {
+		options.WorkerPool = mgr.GetWorkerPool()
+	}
+
+	if options.ClusterSuspensions == nil {
+		options.ClusterSuspensions = mgr.GetClusterSuspensions()
+	}
+
+	if options.TracerProvider == nil {
+		options.TracerProvider = mgr.GetTracerProvider()
+	}
+	var tracer trace.Tracer
+	if options.TracerProvider != nil {
+		tracer = options.TracerProvider.Tracer("sigs.k8s.io/controller-runtime/pkg/controller")
+	}
+
+	// Hold the requests of the suspended clusters already queued.
+	if options.ClusterSuspensions != nil {
+		if options.SyncGate != nil {
+			options.SyncGate = cluster.SyncGates{options.ClusterSuspensions, options.SyncGate}
+		} else {
+			options.SyncGate = options.ClusterSuspensions
+		}
+	}
+
+	cacheSyncTimeoutByGVK := make(map[schema.GroupVersionKind]time.Duration, len(options.CacheSyncTimeoutByObject))
+	for obj, timeout := range options.CacheSyncTimeoutByObject {
+		gvk, err := apiutil.GVKForObject(obj, mgr.GetScheme())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get GroupVersionKind for CacheSyncTimeoutByObject entry %T: %w", obj, err)
+		}
+		cacheSyncTimeoutByGVK[gvk] = timeout
+	}
+
 	// Inject dependencies into Reconciler
 	if err := mgr.SetFields(options.Reconciler); err != nil {
 		return nil, err
@@ -127,15 +411,50 @@ func NewUnmanaged(name string, mgr manager.Manager, options Options) (Controller
 
 	// Create controller with dependencies set
 	return &controller.Controller{
-		Do: options.Reconciler,
+		Do:           options.Reconciler,
+		BatchDo:      options.BatchReconciler,
+		MaxBatchSize: options.MaxBatchSize,
+		BatchWindow:  options.BatchWindow,
 		MakeQueue: func() workqueue.RateLimitingInterface {
+			if options.DirectNotification {
+				return controller.NewDirectQueue(options.RateLimiter, options.DirectNotificationCapacity, options.DirectNotificationOverflow, name)
+			}
+			if options.FairQueueByCluster {
+				return controller.NewFairRateLimitingQueue(options.RateLimiter, name)
+			}
+			if options.PrioritizeDeletes || options.DeprioritizeInitialSync {
+				return controller.NewPriorityRateLimitingQueue(options.RateLimiter, name)
+			}
 			return workqueue.NewNamedRateLimitingQueue(options.RateLimiter, name)
 		},
-		MaxConcurrentReconciles: options.MaxConcurrentReconciles,
-		CacheSyncTimeout:        options.CacheSyncTimeout,
-		SetFields:               mgr.SetFields,
-		Name:                    name,
-		Log:                     options.Log.WithName("controller").WithName(name),
-		RecoverPanic:            options.RecoverPanic,
+		MaxConcurrentReconciles:           options.MaxConcurrentReconciles,
+		MaxConcurrentReconcilesPerCluster: options.MaxConcurrentReconcilesPerCluster,
+		MaxConcurrentReconcilesByCluster:  options.MaxConcurrentReconcilesByCluster,
+		CacheSyncTimeout:                  options.CacheSyncTimeout,
+		CacheSyncTimeoutByGVK:             cacheSyncTimeoutByGVK,
+		Scheme:                            mgr.GetScheme(),
+		SetFields:                         mgr.SetFields,
+		Name:                              name,
+		Log:                               options.Log.WithName("controller").WithName(name),
+		RecoverPanic:                      options.RecoverPanic,
+		PrioritizeDeletes:                 options.PrioritizeDeletes,
+		DeprioritizeInitialSync:           options.DeprioritizeInitialSync,
+		TombstoneDeletes:                  options.TombstoneDeletes,
+		WaitForCacheConsistency:           options.WaitForCacheConsistency,
+		CacheConsistencyTimeout:           options.CacheConsistencyTimeout,
+		Dependencies:                      options.DependsOn,
+		Config:                            options.Config,
+		QueueHandover:                     options.QueueHandover,
+		AuditSink:                         options.AuditSink,
+		ObjectLocks:                       options.ObjectLocks,
+		WorkerPool:                        options.WorkerPool,
+		WorkerPoolWeight:                  options.WorkerPoolWeight,
+		GroupKind:                         options.GroupKind,
+		SyncGate:                          options.SyncGate,
+		Suspensions:                       options.ClusterSuspensions,
+		ClusterSet:                        options.ClusterSet,
+		ReconcileRemovedClusters:          options.ReconcileRemovedClusters,
+		RateLimiter:                       options.RateLimiter,
+		Tracer:                            tracer,
 	}, nil
 }
@@ -25,6 +25,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllertest"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -72,7 +73,7 @@ var _ = Describe("controller", func() {
 			err = instance.Watch(&source.Kind{Type: &appsv1.Deployment{}}, &handler.EnqueueRequestForObject{})
 			Expect(err).NotTo(HaveOccurred())
 
-			err = cm.GetClient().Get(ctx, types.NamespacedName{Name: "foo"}, &corev1.Namespace{})
+			err = cm.GetClient().Get(ctx, client.ObjectKey{NamespacedName: types.NamespacedName{Name: "foo"}}, &corev1.Namespace{})
 			Expect(err).To(Equal(&cache.ErrCacheNotStarted{}))
 			err = cm.GetClient().List(ctx, &corev1.NamespaceList{})
 			Expect(err).To(Equal(&cache.ErrCacheNotStarted{}))
@@ -107,10 +108,10 @@ var _ = Describe("controller", func() {
 					},
 				},
 			}
-			expectedReconcileRequest := reconcile.Request{NamespacedName: types.NamespacedName{
+			expectedReconcileRequest := reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: types.NamespacedName{
 				Namespace: "default",
 				Name:      "deployment-name",
-			}}
+			}}}
 
 			By("Invoking Reconciling for Create")
 			deployment, err = clientset.AppsV1().Deployments("default").Create(ctx, deployment, metav1.CreateOptions{})
@@ -59,6 +59,22 @@ var _ = Describe("controller.Controller", func() {
 			Expect(err.Error()).To(ContainSubstring("must specify Reconciler"))
 		})
 
+		It("should default the Reconciler of a BatchReconciler, and refuse concurrent reconciles", func() {
+			m, err := manager.New(cfg, manager.Options{})
+			Expect(err).NotTo(HaveOccurred())
+			batch := reconcile.BatchFunc(func(context.Context, []reconcile.Request) (reconcile.Result, error) {
+				return reconcile.Result{}, nil
+			})
+
+			c, err := controller.New("batch", m, controller.Options{BatchReconciler: batch})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(c.Reconcile(context.Background(), reconcile.Request{})).To(Equal(reconcile.Result{}))
+
+			c, err = controller.New("concurrent-batch", m, controller.Options{BatchReconciler: batch, MaxConcurrentReconciles: 2})
+			Expect(c).To(BeNil())
+			Expect(err.Error()).To(ContainSubstring("must not specify MaxConcurrentReconciles along with BatchReconciler"))
+		})
+
 		It("NewController should return an error if injecting Reconciler fails", func() {
 			m, err := manager.New(cfg, manager.Options{})
 			Expect(err).NotTo(HaveOccurred())
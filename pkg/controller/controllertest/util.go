@@ -19,6 +19,7 @@ package controllertest
 import (
 	"time"
 
+	kcpcache "github.com/kcp-dev/apimachinery/pkg/cache"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/cache"
 )
@@ -34,6 +35,9 @@ type FakeInformer struct {
 	RunCount int
 
 	handlers []cache.ResourceEventHandler
+
+	// store holds the objects of the faked events, keyed by logical cluster, namespace and name.
+	store cache.Store
 }
 
 // AddIndexers does nothing.  TODO(community): Implement this.
@@ -68,6 +72,7 @@ func (f *FakeInformer) Run(<-chan struct{}) {
 
 // Add fakes an Add event for obj.
 func (f *FakeInformer) Add(obj metav1.Object) {
+	_ = f.GetStore().Add(obj)
 	for _, h := range f.handlers {
 		h.OnAdd(obj)
 	}
@@ -75,6 +80,7 @@ func (f *FakeInformer) Add(obj metav1.Object) {
 
 // Update fakes an Update event for obj.
 func (f *FakeInformer) Update(oldObj, newObj metav1.Object) {
+	_ = f.GetStore().Update(newObj)
 	for _, h := range f.handlers {
 		h.OnUpdate(oldObj, newObj)
 	}
@@ -82,6 +88,7 @@ func (f *FakeInformer) Update(oldObj, newObj metav1.Object) {
 
 // Delete fakes an Delete event for obj.
 func (f *FakeInformer) Delete(obj metav1.Object) {
+	_ = f.GetStore().Delete(obj)
 	for _, h := range f.handlers {
 		h.OnDelete(obj)
 	}
@@ -92,9 +99,12 @@ func (f *FakeInformer) AddEventHandlerWithResyncPeriod(handler cache.ResourceEve
 
 }
 
-// GetStore does nothing.  TODO(community): Implement this.
+// GetStore returns a store holding the objects of the faked events.
 func (f *FakeInformer) GetStore() cache.Store {
-	return nil
+	if f.store == nil {
+		f.store = cache.NewStore(kcpcache.ClusterAwareKeyFunc)
+	}
+	return f.store
 }
 
 // GetController does nothing.  TODO(community): Implement this.
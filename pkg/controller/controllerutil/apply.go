@@ -0,0 +1,278 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllerutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// ApplyBundleOptions configures ApplyBundle.
+type ApplyBundleOptions struct {
+	// FieldOwner is the field manager used to server-side apply the objects.  Required.
+	FieldOwner string
+
+	// Reader reads the objects before they are applied, to report whether they were created,
+	// updated or left unchanged.  Required.  It must not be backed by a cache, which may
+	// be stale and would start an informer for the metadata of every kind of the bundle,
+	// e.g. it is the APIReader of the manager.
+	Reader client.Reader
+
+	// Force makes the apply take the ownership of the fields managed by other field
+	// managers, instead of failing with a conflict.
+	Force bool
+
+	// PruneLabels are set on all the objects of the bundle, and identify the objects
+	// previously applied as part of it.
+	PruneLabels map[string]string
+
+	// PruneTypes are the list types of the objects to prune, e.g. &corev1.ConfigMapList{}.
+	// The objects of these types which carry all the PruneLabels but are not part of the
+	// bundle anymore are deleted.  PruneLabels are required to prune.
+	PruneTypes []client.ObjectList
+}
+
+// ApplyResult is the result of applying, or pruning, one object of a bundle.
+type ApplyResult struct {
+	// Object is the object as returned by the server, or as given if the operation failed.
+	Object client.Object
+
+	// Operation is what was done to the object.
+	Operation OperationResult
+
+	// Error is the error the operation failed with, if any.
+	Error error
+}
+
+// ApplyBundle server-side applies a set of objects into the given logical cluster, or into
+// the cluster of the context if it is empty.  It fails if neither is a single logical
+// cluster, so that the objects of the other clusters are never pruned.  The objects are applied in dependency order:
+// CustomResourceDefinitions and Namespaces first, webhook configurations last.  The objects
+// given are not modified.
+//
+// If PruneTypes are given and all the objects were applied successfully, the objects
+// previously applied with the same PruneLabels which are not part of the bundle anymore are
+// deleted, in the reverse order.
+//
+// It returns the result of each operation, in the order they were made, and an aggregate of
+// their errors.
+func ApplyBundle(ctx context.Context, c client.Client, cluster logicalcluster.Name, objects []client.Object, opts ApplyBundleOptions) ([]ApplyResult, error) {
+	if opts.FieldOwner == "" {
+		return nil, errors.New("a field owner is required to apply a bundle")
+	}
+	if opts.Reader == nil {
+		return nil, errors.New("an uncached reader is required to apply a bundle")
+	}
+	if len(opts.PruneTypes) > 0 && len(opts.PruneLabels) == 0 {
+		return nil, errors.New("prune labels are required to prune a bundle")
+	}
+	if cluster.Empty() {
+		cluster, _ = kcpclient.ClusterFromContext(ctx)
+	}
+	if cluster.Empty() || cluster == logicalcluster.Wildcard {
+		return nil, errors.New("a bundle must be applied to a single logical cluster")
+	}
+	ctx = kcpclient.WithCluster(ctx, cluster)
+
+	bundle := make([]bundleObject, 0, len(objects))
+	for _, obj := range objects {
+		gvk, err := apiutil.GVKForObject(obj, c.Scheme())
+		if err != nil {
+			return nil, err
+		}
+		if objCluster := logicalcluster.From(obj); !objCluster.Empty() && objCluster != cluster {
+			return nil, fmt.Errorf("%s %s belongs to cluster %s, not %s", gvk.Kind, nameOf(obj), objCluster, cluster)
+		}
+		bundle = append(bundle, bundleObject{obj: obj.DeepCopyObject().(client.Object), gvk: gvk})
+	}
+	sort.SliceStable(bundle, func(i, j int) bool {
+		return applyRank(bundle[i].gvk.GroupKind()) < applyRank(bundle[j].gvk.GroupKind())
+	})
+
+	patchOpts := []client.PatchOption{client.FieldOwner(opts.FieldOwner)}
+	if opts.Force {
+		patchOpts = append(patchOpts, client.ForceOwnership)
+	}
+
+	results := make([]ApplyResult, 0, len(bundle))
+	applied := make(map[bundleKey]struct{}, len(bundle))
+	var errs []error
+	for _, o := range bundle {
+		applied[o.key()] = struct{}{}
+		op, err := applyObject(ctx, c, opts.Reader, o, opts.PruneLabels, patchOpts)
+		if err != nil {
+			err = fmt.Errorf("failed to apply %s %s: %w", o.gvk.Kind, nameOf(o.obj), err)
+			errs = append(errs, err)
+		}
+		results = append(results, ApplyResult{Object: o.obj, Operation: op, Error: err})
+	}
+	if len(errs) > 0 || len(opts.PruneTypes) == 0 {
+		// Don't prune anything unless the whole bundle was applied, the objects which
+		// failed may still depend on the ones which would be pruned.
+		return results, kerrors.NewAggregate(errs)
+	}
+
+	stale, err := staleObjects(ctx, c, applied, opts)
+	if err != nil {
+		return results, err
+	}
+	for _, o := range stale {
+		err := client.IgnoreNotFound(c.Delete(ctx, o.obj, client.PropagationPolicy(metav1.DeletePropagationBackground)))
+		if err != nil {
+			err = fmt.Errorf("failed to prune %s %s: %w", o.gvk.Kind, nameOf(o.obj), err)
+			errs = append(errs, err)
+		}
+		results = append(results, ApplyResult{Object: o.obj, Operation: OperationResultPruned, Error: err})
+	}
+	return results, kerrors.NewAggregate(errs)
+}
+
+// applyObject server-side applies the object and reports whether it was created, updated or unchanged.
+func applyObject(ctx context.Context, c client.Client, reader client.Reader, o bundleObject, labels map[string]string, opts []client.PatchOption) (OperationResult, error) {
+	obj := o.obj
+	obj.GetObjectKind().SetGroupVersionKind(o.gvk)
+	obj.SetManagedFields(nil)
+	if len(labels) > 0 {
+		objLabels := obj.GetLabels()
+		if objLabels == nil {
+			objLabels = make(map[string]string, len(labels))
+		}
+		for k, v := range labels {
+			objLabels[k] = v
+		}
+		obj.SetLabels(objLabels)
+	}
+
+	existing := &metav1.PartialObjectMetadata{}
+	existing.SetGroupVersionKind(o.gvk)
+	existed := true
+	if err := reader.Get(ctx, client.ObjectKeyFromObject(obj), existing); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return OperationResultNone, err
+		}
+		existed = false
+	}
+
+	if err := c.Patch(ctx, obj, client.Apply, opts...); err != nil {
+		return OperationResultNone, err
+	}
+	switch {
+	case !existed:
+		return OperationResultCreated, nil
+	case obj.GetResourceVersion() != existing.GetResourceVersion():
+		return OperationResultUpdated, nil
+	default:
+		return OperationResultNone, nil
+	}
+}
+
+// staleObjects lists the objects carrying the prune labels which were not applied, in the order
+// they should be deleted.
+func staleObjects(ctx context.Context, c client.Client, applied map[bundleKey]struct{}, opts ApplyBundleOptions) ([]bundleObject, error) {
+	var stale []bundleObject
+	for _, list := range opts.PruneTypes {
+		list = list.DeepCopyObject().(client.ObjectList)
+		gvk, err := apiutil.GVKForObject(list, c.Scheme())
+		if err != nil {
+			return nil, err
+		}
+		gvk.Kind = strings.TrimSuffix(gvk.Kind, "List")
+
+		if err := c.List(ctx, list, client.MatchingLabels(opts.PruneLabels)); err != nil {
+			return nil, fmt.Errorf("failed to list %s to prune: %w", gvk.Kind, err)
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			obj, ok := item.(client.Object)
+			if !ok {
+				return nil, fmt.Errorf("%T is not a client.Object", item)
+			}
+			o := bundleObject{obj: obj, gvk: gvk}
+			if _, ok := applied[o.key()]; !ok {
+				stale = append(stale, o)
+			}
+		}
+	}
+	sort.SliceStable(stale, func(i, j int) bool {
+		return applyRank(stale[i].gvk.GroupKind()) > applyRank(stale[j].gvk.GroupKind())
+	})
+	return stale, nil
+}
+
+// bundleObject is an object of a bundle along with its GroupVersionKind.
+type bundleObject struct {
+	obj client.Object
+	gvk schema.GroupVersionKind
+}
+
+// bundleKey identifies an object of a bundle regardless of its version.
+type bundleKey struct {
+	schema.GroupKind
+	types.NamespacedName
+}
+
+func (o bundleObject) key() bundleKey {
+	return bundleKey{GroupKind: o.gvk.GroupKind(), NamespacedName: nameOf(o.obj)}
+}
+
+func nameOf(obj client.Object) types.NamespacedName {
+	return types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+}
+
+// applyOrder ranks the kinds which other objects commonly depend on.  Kinds which are not
+// listed are applied after them, but before the webhook configurations, so that webhooks
+// served by the bundle don't block the rest of it.
+var applyOrder = map[schema.GroupKind]int{
+	{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}: 0,
+	{Kind: "Namespace"}:      1,
+	{Kind: "ResourceQuota"}:  2,
+	{Kind: "LimitRange"}:     2,
+	{Kind: "ServiceAccount"}: 3,
+	{Kind: "Secret"}:         3,
+	{Kind: "ConfigMap"}:      3,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"}:        4,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"}: 4,
+	{Group: "rbac.authorization.k8s.io", Kind: "Role"}:               4,
+	{Group: "rbac.authorization.k8s.io", Kind: "RoleBinding"}:        4,
+	{Kind: "Service"}: 5,
+	{Group: "admissionregistration.k8s.io", Kind: "MutatingWebhookConfiguration"}:   7,
+	{Group: "admissionregistration.k8s.io", Kind: "ValidatingWebhookConfiguration"}: 7,
+}
+
+func applyRank(gk schema.GroupKind) int {
+	if rank, ok := applyOrder[gk]; ok {
+		return rank
+	}
+	return 6
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllerutil
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/kcp-dev/logicalcluster"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// ClusterOwnersAnnotation is the annotation recording the owners of an object set with
+// SetClusterOwnerReference, as a JSON list of ClusterOwnerReferences.
+const ClusterOwnersAnnotation = "controller-runtime.kcp.dev/owners"
+
+// ClusterOwnerReference refers to the owner of an object, which may be in another logical
+// cluster than the object.
+type ClusterOwnerReference struct {
+	// Cluster is the logical cluster of the owner.
+	Cluster logicalcluster.Name `json:"cluster"`
+	// APIVersion is the API version of the owner.
+	APIVersion string `json:"apiVersion"`
+	// Kind is the kind of the owner.
+	Kind string `json:"kind"`
+	// Namespace is the namespace of the owner, empty if the owner is cluster-scoped.
+	Namespace string `json:"namespace,omitempty"`
+	// Name is the name of the owner.
+	Name string `json:"name"`
+	// UID is the UID of the owner.
+	UID types.UID `json:"uid,omitempty"`
+	// Controller is true if the owner is the managing controller of the object.
+	Controller bool `json:"controller,omitempty"`
+}
+
+// SetClusterOwnerReference records owner as an owner of object in the ClusterOwnersAnnotation
+// of object.  Unlike OwnerReferences, the owner may be in another logical cluster or namespace
+// than object, so that controllers can track the objects they manage across workspaces.
+// If a reference to the same owner already exists, it'll be overwritten with the newly
+// provided version.
+//
+// The annotation is not known to the garbage collector: it never deletes object when owner is
+// deleted, e.g. owner should have a finalizer deleting object.  A native OwnerReference to an
+// owner in another logical cluster, on the contrary, makes the garbage collector delete object
+// since the owner isn't found in the logical cluster of object.
+func SetClusterOwnerReference(owner, object metav1.Object, scheme *runtime.Scheme) error {
+	ref, err := newClusterOwnerReference(owner, scheme)
+	if err != nil {
+		return err
+	}
+	return upsertClusterOwnerRef(ref, object)
+}
+
+// SetClusterControllerReference records owner as the managing controller of object in the
+// ClusterOwnersAnnotation of object, see SetClusterOwnerReference.  Since only one owner can
+// be a controller, it returns an AlreadyOwnedError if another owner is the controller.
+func SetClusterControllerReference(owner, object metav1.Object, scheme *runtime.Scheme) error {
+	ref, err := newClusterOwnerReference(owner, scheme)
+	if err != nil {
+		return err
+	}
+	ref.Controller = true
+
+	refs, err := GetClusterOwnerReferences(object)
+	if err != nil {
+		return err
+	}
+	for _, existing := range refs {
+		if existing.Controller && !referSameClusterObject(existing, ref) {
+			return newAlreadyOwnedError(object, metav1.OwnerReference{
+				APIVersion: existing.APIVersion,
+				Kind:       existing.Kind,
+				Name:       existing.Name,
+				UID:        existing.UID,
+				Controller: &existing.Controller,
+			})
+		}
+	}
+	return upsertClusterOwnerRef(ref, object)
+}
+
+// RemoveClusterOwnerReference removes the reference to owner from the ClusterOwnersAnnotation
+// of object, and the annotation if it was the last reference.  It returns whether a reference
+// was removed.
+func RemoveClusterOwnerReference(owner, object metav1.Object, scheme *runtime.Scheme) (bool, error) {
+	ref, err := newClusterOwnerReference(owner, scheme)
+	if err != nil {
+		return false, err
+	}
+	refs, err := GetClusterOwnerReferences(object)
+	if err != nil {
+		return false, err
+	}
+	kept := refs[:0]
+	for _, r := range refs {
+		if !referSameClusterObject(r, ref) {
+			kept = append(kept, r)
+		}
+	}
+	if len(kept) == len(refs) {
+		return false, nil
+	}
+	return true, setClusterOwnerRefs(kept, object)
+}
+
+// GetClusterOwnerReferences returns the owners recorded in the ClusterOwnersAnnotation of object.
+func GetClusterOwnerReferences(object metav1.Object) ([]ClusterOwnerReference, error) {
+	value, ok := object.GetAnnotations()[ClusterOwnersAnnotation]
+	if !ok || value == "" {
+		return nil, nil
+	}
+	var refs []ClusterOwnerReference
+	if err := json.Unmarshal([]byte(value), &refs); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation on %s/%s: %w", ClusterOwnersAnnotation, object.GetNamespace(), object.GetName(), err)
+	}
+	return refs, nil
+}
+
+// GetClusterControllerOf returns the owner recorded as the managing controller of object in
+// its ClusterOwnersAnnotation, or nil if there is none.
+func GetClusterControllerOf(object metav1.Object) (*ClusterOwnerReference, error) {
+	refs, err := GetClusterOwnerReferences(object)
+	if err != nil {
+		return nil, err
+	}
+	for i := range refs {
+		if refs[i].Controller {
+			return &refs[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func newClusterOwnerReference(owner metav1.Object, scheme *runtime.Scheme) (ClusterOwnerReference, error) {
+	ro, ok := owner.(runtime.Object)
+	if !ok {
+		return ClusterOwnerReference{}, fmt.Errorf("%T is not a runtime.Object, cannot record it as a cluster owner", owner)
+	}
+	cluster := logicalcluster.From(owner)
+	if cluster.Empty() {
+		return ClusterOwnerReference{}, fmt.Errorf("owner %s/%s has no logical cluster, cannot record it as a cluster owner", owner.GetNamespace(), owner.GetName())
+	}
+	gvk, err := apiutil.GVKForObject(ro, scheme)
+	if err != nil {
+		return ClusterOwnerReference{}, err
+	}
+	return ClusterOwnerReference{
+		Cluster:    cluster,
+		APIVersion: gvk.GroupVersion().String(),
+		Kind:       gvk.Kind,
+		Namespace:  owner.GetNamespace(),
+		Name:       owner.GetName(),
+		UID:        owner.GetUID(),
+	}, nil
+}
+
+func upsertClusterOwnerRef(ref ClusterOwnerReference, object metav1.Object) error {
+	refs, err := GetClusterOwnerReferences(object)
+	if err != nil {
+		return err
+	}
+	found := false
+	for i := range refs {
+		if referSameClusterObject(refs[i], ref) {
+			refs[i] = ref
+			found = true
+		}
+	}
+	if !found {
+		refs = append(refs, ref)
+	}
+	return setClusterOwnerRefs(refs, object)
+}
+
+func setClusterOwnerRefs(refs []ClusterOwnerReference, object metav1.Object) error {
+	annotations := object.GetAnnotations()
+	if len(refs) == 0 {
+		delete(annotations, ClusterOwnersAnnotation)
+		object.SetAnnotations(annotations)
+		return nil
+	}
+	value, err := json.Marshal(refs)
+	if err != nil {
+		return err
+	}
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[ClusterOwnersAnnotation] = string(value)
+	object.SetAnnotations(annotations)
+	return nil
+}
+
+// Returns true if a and b point to the same object, across logical clusters.
+func referSameClusterObject(a, b ClusterOwnerReference) bool {
+	aGV, err := schema.ParseGroupVersion(a.APIVersion)
+	if err != nil {
+		return false
+	}
+
+	bGV, err := schema.ParseGroupVersion(b.APIVersion)
+	if err != nil {
+		return false
+	}
+
+	return a.Cluster == b.Cluster && aGV.Group == bGV.Group && a.Kind == b.Kind &&
+		a.Namespace == b.Namespace && a.Name == b.Name
+}
@@ -21,6 +21,8 @@ import (
 	"fmt"
 	"reflect"
 
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -56,6 +58,8 @@ func newAlreadyOwnedError(obj metav1.Object, owner metav1.OwnerReference) *Alrea
 // reconciling the owner object on changes to controlled (with a Watch + EnqueueRequestForOwner).
 // Since only one OwnerReference can be a controller, it returns an error if
 // there is another OwnerReference with Controller flag set.
+// OwnerReferences can't cross logical clusters, so it returns an error if owner and
+// controlled are in different logical clusters.
 func SetControllerReference(owner, controlled metav1.Object, scheme *runtime.Scheme) error {
 	// Validate the owner.
 	ro, ok := owner.(runtime.Object)
@@ -93,6 +97,7 @@ func SetControllerReference(owner, controlled metav1.Object, scheme *runtime.Sch
 // SetOwnerReference is a helper method to make sure the given object contains an object reference to the object provided.
 // This allows you to declare that owner has a dependency on the object without specifying it as a controller.
 // If a reference to the same object already exists, it'll be overwritten with the newly provided version.
+// It returns an error if owner and object are in different logical clusters.
 func SetOwnerReference(owner, object metav1.Object, scheme *runtime.Scheme) error {
 	// Validate the owner.
 	ro, ok := owner.(runtime.Object)
@@ -141,6 +146,12 @@ func indexOwnerRef(ownerReferences []metav1.OwnerReference, ref metav1.OwnerRefe
 }
 
 func validateOwner(owner, object metav1.Object) error {
+	// Objects without a logical cluster, e.g. ones not created yet, are written to the logical
+	// cluster of the context, which can't be checked here.
+	ownerCluster, objCluster := logicalcluster.From(owner), logicalcluster.From(object)
+	if !ownerCluster.Empty() && !objCluster.Empty() && ownerCluster != objCluster {
+		return fmt.Errorf("cross-cluster owner references are disallowed, owner's logical cluster %s, obj's logical cluster %s", ownerCluster, objCluster)
+	}
 	ownerNs := owner.GetNamespace()
 	if ownerNs != "" {
 		objNs := object.GetNamespace()
@@ -183,6 +194,8 @@ const ( // They should complete the sentence "Deployment default/foo has been ..
 	OperationResultUpdatedStatus OperationResult = "updatedStatus"
 	// OperationResultUpdatedStatusOnly means that only an existing status is updated.
 	OperationResultUpdatedStatusOnly OperationResult = "updatedStatusOnly"
+	// OperationResultPruned means that a resource which is not part of a bundle anymore is deleted.
+	OperationResultPruned OperationResult = "pruned"
 )
 
 // CreateOrUpdate creates or updates the given object in the Kubernetes
@@ -191,14 +204,17 @@ const ( // They should complete the sentence "Deployment default/foo has been ..
 //
 // The MutateFn is called regardless of creating or updating an object.
 //
+// The object is read from and written to its logical cluster, or the logical
+// cluster of the context if it has none.
+//
 // It returns the executed operation and an error.
 func CreateOrUpdate(ctx context.Context, c client.Client, obj client.Object, f MutateFn) (OperationResult, error) {
-	key := client.ObjectKeyFromObject(obj)
+	key := objectKey(ctx, obj)
 	if err := c.Get(ctx, key, obj); err != nil {
 		if !apierrors.IsNotFound(err) {
 			return OperationResultNone, err
 		}
-		if err := mutate(f, key, obj); err != nil {
+		if err := mutate(ctx, f, key, obj); err != nil {
 			return OperationResultNone, err
 		}
 		if err := c.Create(ctx, obj); err != nil {
@@ -208,7 +224,7 @@ func CreateOrUpdate(ctx context.Context, c client.Client, obj client.Object, f M
 	}
 
 	existing := obj.DeepCopyObject() //nolint
-	if err := mutate(f, key, obj); err != nil {
+	if err := mutate(ctx, f, key, obj); err != nil {
 		return OperationResultNone, err
 	}
 
@@ -228,15 +244,18 @@ func CreateOrUpdate(ctx context.Context, c client.Client, obj client.Object, f M
 //
 // The MutateFn is called regardless of creating or updating an object.
 //
+// The object is read from and written to its logical cluster, or the logical
+// cluster of the context if it has none.
+//
 // It returns the executed operation and an error.
 func CreateOrPatch(ctx context.Context, c client.Client, obj client.Object, f MutateFn) (OperationResult, error) {
-	key := client.ObjectKeyFromObject(obj)
+	key := objectKey(ctx, obj)
 	if err := c.Get(ctx, key, obj); err != nil {
 		if !apierrors.IsNotFound(err) {
 			return OperationResultNone, err
 		}
 		if f != nil {
-			if err := mutate(f, key, obj); err != nil {
+			if err := mutate(ctx, f, key, obj); err != nil {
 				return OperationResultNone, err
 			}
 		}
@@ -271,7 +290,7 @@ func CreateOrPatch(ctx context.Context, c client.Client, obj client.Object, f Mu
 
 	// Mutate the original object.
 	if f != nil {
-		if err := mutate(f, key, obj); err != nil {
+		if err := mutate(ctx, f, key, obj); err != nil {
 			return OperationResultNone, err
 		}
 	}
@@ -335,16 +354,28 @@ func CreateOrPatch(ctx context.Context, c client.Client, obj client.Object, f Mu
 }
 
 // mutate wraps a MutateFn and applies validation to its result.
-func mutate(f MutateFn, key client.ObjectKey, obj client.Object) error {
+func mutate(ctx context.Context, f MutateFn, key client.ObjectKey, obj client.Object) error {
 	if err := f(); err != nil {
 		return err
 	}
-	if newKey := client.ObjectKeyFromObject(obj); key != newKey {
-		return fmt.Errorf("MutateFn cannot mutate object name and/or object namespace")
+	if newKey := objectKey(ctx, obj); key != newKey {
+		return fmt.Errorf("MutateFn cannot mutate object name, object namespace and/or object logical cluster")
 	}
 	return nil
 }
 
+// objectKey returns the key of obj, in the logical cluster of the context if obj has none,
+// so that the key doesn't change when reading obj sets its logical cluster.
+func objectKey(ctx context.Context, obj client.Object) client.ObjectKey {
+	key := client.ObjectKeyFromObject(obj)
+	if key.Cluster.Empty() {
+		if cluster, ok := kcpclient.ClusterFromContext(ctx); ok {
+			key.Cluster = cluster
+		}
+	}
+	return key
+}
+
 // MutateFn is a function which mutates the existing object into its desired state.
 type MutateFn func() error
 
@@ -382,6 +413,74 @@ func ContainsFinalizer(o client.Object, finalizer string) bool {
 	return false
 }
 
+// ClusterFinalizer adds and removes a finalizer with patches sent to the logical cluster the
+// object was read from, which owns the finalizer work.  With shared informers, the objects of
+// all the logical clusters are read from the same cache: writing them back in the logical
+// cluster of the context, e.g. of another request, rather than in their own one fails with
+// conflicts or, worse, changes another object.
+//
+// The patches are merge patches with optimistic locking, so that they don't overwrite the
+// finalizers changed concurrently.
+type ClusterFinalizer struct {
+	client    client.Client
+	finalizer string
+}
+
+// NewClusterFinalizer returns a ClusterFinalizer managing the finalizer with the client.
+func NewClusterFinalizer(c client.Client, finalizer string) *ClusterFinalizer {
+	return &ClusterFinalizer{client: c, finalizer: finalizer}
+}
+
+// AddFinalizer adds the finalizer to obj if it is missing, and patches obj in its logical
+// cluster.  It returns whether obj was patched.
+func (f *ClusterFinalizer) AddFinalizer(ctx context.Context, obj client.Object) (bool, error) {
+	if ContainsFinalizer(obj, f.finalizer) {
+		return false, nil
+	}
+	return true, f.patch(ctx, obj, func() { AddFinalizer(obj, f.finalizer) })
+}
+
+// RemoveFinalizer removes the finalizer from obj if it is present, and patches obj in its
+// logical cluster.  It returns whether obj was patched.
+func (f *ClusterFinalizer) RemoveFinalizer(ctx context.Context, obj client.Object) (bool, error) {
+	if !ContainsFinalizer(obj, f.finalizer) {
+		return false, nil
+	}
+	return true, f.patch(ctx, obj, func() { RemoveFinalizer(obj, f.finalizer) })
+}
+
+// patch patches the changes made to obj by mutate in the logical cluster of obj.
+func (f *ClusterFinalizer) patch(ctx context.Context, obj client.Object, mutate func()) error {
+	cluster, err := finalizerCluster(ctx, obj)
+	if err != nil {
+		return err
+	}
+	if !cluster.Empty() {
+		ctx = kcpclient.WithCluster(ctx, cluster)
+	}
+	base := obj.DeepCopyObject().(client.Object)
+	mutate()
+	return f.client.Patch(ctx, obj, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{}))
+}
+
+// finalizerCluster returns the logical cluster obj was read from, which must be the one of
+// the context if the context targets a single logical cluster.
+func finalizerCluster(ctx context.Context, obj client.Object) (logicalcluster.Name, error) {
+	objCluster := logicalcluster.From(obj)
+	ctxCluster, ok := kcpclient.ClusterFromContext(ctx)
+	if !ok || ctxCluster == logicalcluster.Wildcard {
+		return objCluster, nil
+	}
+	if objCluster.Empty() {
+		return ctxCluster, nil
+	}
+	if objCluster != ctxCluster {
+		return logicalcluster.Name{}, fmt.Errorf("cannot change the finalizers of %s/%s of logical cluster %s in the logical cluster %s of the context",
+			obj.GetNamespace(), obj.GetName(), objCluster, ctxCluster)
+	}
+	return objCluster, nil
+}
+
 // Object allows functions to work indistinctly with any resource that
 // implements both Object interfaces.
 //
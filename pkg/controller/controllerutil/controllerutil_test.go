@@ -21,17 +21,23 @@ import (
 	"fmt"
 	"math/rand"
 
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
@@ -220,6 +226,22 @@ var _ = Describe("Controllerutil", func() {
 			Expect(err).To(HaveOccurred())
 		})
 
+		It("should return an error if it's setting a cross-cluster owner reference", func() {
+			rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", ClusterName: "root:org:a"}}
+			dep := &extensionsv1beta1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", ClusterName: "root:org:b", UID: "foo-uid"}}
+
+			err := controllerutil.SetControllerReference(dep, rs, scheme.Scheme)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("cross-cluster"))
+			Expect(controllerutil.SetOwnerReference(dep, rs, scheme.Scheme)).NotTo(Succeed())
+
+			By("allowing owner references in the same logical cluster, or to objects without one")
+			rs.ClusterName = "root:org:b"
+			Expect(controllerutil.SetControllerReference(dep, rs, scheme.Scheme)).To(Succeed())
+			rs.ClusterName = ""
+			Expect(controllerutil.SetControllerReference(dep, rs, scheme.Scheme)).To(Succeed())
+		})
+
 		It("should return an error if it's owner is namespaced resource but dependant is cluster-scoped resource", func() {
 			pv := &corev1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
 			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "foo-uid"}}
@@ -258,6 +280,102 @@ var _ = Describe("Controllerutil", func() {
 		})
 	})
 
+	Describe("SetClusterOwnerReference", func() {
+		newOwner := func() *extensionsv1beta1.Deployment {
+			dep := &extensionsv1beta1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo", UID: "foo-uid"},
+			}
+			dep.ClusterName = "root:provider"
+			return dep
+		}
+		ownerRef := controllerutil.ClusterOwnerReference{
+			Cluster:    logicalcluster.New("root:provider"),
+			APIVersion: "extensions/v1beta1",
+			Kind:       "Deployment",
+			Namespace:  "default",
+			Name:       "foo",
+			UID:        "foo-uid",
+		}
+
+		It("should record an owner in another logical cluster and namespace", func() {
+			rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "bar"}}
+			rs.ClusterName = "root:consumer"
+
+			Expect(controllerutil.SetClusterOwnerReference(newOwner(), rs, scheme.Scheme)).To(Succeed())
+			Expect(rs.OwnerReferences).To(BeEmpty())
+			Expect(rs.Annotations).To(HaveKey(controllerutil.ClusterOwnersAnnotation))
+			Expect(controllerutil.GetClusterOwnerReferences(rs)).To(ConsistOf(ownerRef))
+		})
+
+		It("should update the reference to the same owner", func() {
+			rs := &appsv1.ReplicaSet{}
+			Expect(controllerutil.SetClusterOwnerReference(newOwner(), rs, scheme.Scheme)).To(Succeed())
+
+			owner := newOwner()
+			owner.UID = "foo-uid-2"
+			Expect(controllerutil.SetClusterOwnerReference(owner, rs, scheme.Scheme)).To(Succeed())
+
+			expected := ownerRef
+			expected.UID = "foo-uid-2"
+			Expect(controllerutil.GetClusterOwnerReferences(rs)).To(ConsistOf(expected))
+		})
+
+		It("should keep the owners with the same name in other logical clusters", func() {
+			rs := &appsv1.ReplicaSet{}
+			Expect(controllerutil.SetClusterOwnerReference(newOwner(), rs, scheme.Scheme)).To(Succeed())
+
+			owner := newOwner()
+			owner.ClusterName = "root:other"
+			Expect(controllerutil.SetClusterOwnerReference(owner, rs, scheme.Scheme)).To(Succeed())
+
+			other := ownerRef
+			other.Cluster = logicalcluster.New("root:other")
+			Expect(controllerutil.GetClusterOwnerReferences(rs)).To(ConsistOf(ownerRef, other))
+		})
+
+		It("should return an error if the owner has no logical cluster", func() {
+			owner := newOwner()
+			owner.ClusterName = ""
+			Expect(controllerutil.SetClusterOwnerReference(owner, &appsv1.ReplicaSet{}, scheme.Scheme)).NotTo(Succeed())
+		})
+
+		It("should return an error if the annotation is invalid", func() {
+			rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{controllerutil.ClusterOwnersAnnotation: "{"},
+			}}
+			Expect(controllerutil.SetClusterOwnerReference(newOwner(), rs, scheme.Scheme)).NotTo(Succeed())
+		})
+
+		It("should return an AlreadyOwnedError if another owner is the controller", func() {
+			rs := &appsv1.ReplicaSet{}
+			Expect(controllerutil.SetClusterControllerReference(newOwner(), rs, scheme.Scheme)).To(Succeed())
+			Expect(controllerutil.SetClusterControllerReference(newOwner(), rs, scheme.Scheme)).To(Succeed())
+
+			controller := ownerRef
+			controller.Controller = true
+			Expect(controllerutil.GetClusterControllerOf(rs)).To(Equal(&controller))
+
+			owner := newOwner()
+			owner.ClusterName = "root:other"
+			err := controllerutil.SetClusterControllerReference(owner, rs, scheme.Scheme)
+			Expect(err).To(BeAssignableToTypeOf(&controllerutil.AlreadyOwnedError{}))
+			Expect(controllerutil.SetClusterOwnerReference(owner, rs, scheme.Scheme)).To(Succeed())
+		})
+
+		It("should remove the reference and the annotation with the last owner", func() {
+			rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{"foo": "bar"}}}
+			Expect(controllerutil.SetClusterOwnerReference(newOwner(), rs, scheme.Scheme)).To(Succeed())
+
+			owner := newOwner()
+			owner.ClusterName = "root:other"
+			Expect(controllerutil.RemoveClusterOwnerReference(owner, rs, scheme.Scheme)).To(BeFalse())
+			Expect(controllerutil.GetClusterOwnerReferences(rs)).To(HaveLen(1))
+
+			Expect(controllerutil.RemoveClusterOwnerReference(newOwner(), rs, scheme.Scheme)).To(BeTrue())
+			Expect(rs.Annotations).To(Equal(map[string]string{"foo": "bar"}))
+		})
+	})
+
 	Describe("CreateOrUpdate", func() {
 		var deploy *appsv1.Deployment
 		var deplSpec appsv1.DeploymentSpec
@@ -312,7 +430,7 @@ var _ = Describe("Controllerutil", func() {
 
 			By("actually having the deployment created")
 			fetched := &appsv1.Deployment{}
-			Expect(c.Get(context.TODO(), deplKey, fetched)).To(Succeed())
+			Expect(c.Get(context.TODO(), client.ObjectKey{NamespacedName: deplKey}, fetched)).To(Succeed())
 
 			By("being mutated by MutateFn")
 			Expect(fetched.Spec.Template.Spec.Containers).To(HaveLen(1))
@@ -335,7 +453,7 @@ var _ = Describe("Controllerutil", func() {
 
 			By("actually having the deployment scaled")
 			fetched := &appsv1.Deployment{}
-			Expect(c.Get(context.TODO(), deplKey, fetched)).To(Succeed())
+			Expect(c.Get(context.TODO(), client.ObjectKey{NamespacedName: deplKey}, fetched)).To(Succeed())
 			Expect(*fetched.Spec.Replicas).To(Equal(scale))
 		})
 
@@ -396,6 +514,38 @@ var _ = Describe("Controllerutil", func() {
 			Expect(op).To(BeEquivalentTo(controllerutil.OperationResultNone))
 		})
 
+		Context("in a logical cluster", func() {
+			cluster := logicalcluster.New("root:org:ws")
+			var ctx context.Context
+			var fc client.Client
+
+			BeforeEach(func() {
+				ctx = kcpclient.WithCluster(context.TODO(), cluster)
+				existing := deploy.DeepCopy()
+				existing.Spec = deplSpec
+				fc = fake.NewClusterBuilder().WithObjects(cluster, existing).Build()
+			})
+
+			It("updates the object in the logical cluster of the context", func() {
+				op, err := controllerutil.CreateOrUpdate(ctx, fc, deploy, deploymentScaler(deploy, 5))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(op).To(BeEquivalentTo(controllerutil.OperationResultUpdated))
+
+				fetched := &appsv1.Deployment{}
+				Expect(fc.Get(ctx, client.ObjectKeyFromObject(deploy), fetched)).To(Succeed())
+				Expect(*fetched.Spec.Replicas).To(BeEquivalentTo(5))
+			})
+
+			It("errors when the object logical cluster changes", func() {
+				op, err := controllerutil.CreateOrUpdate(ctx, fc, deploy, func() error {
+					deploy.ClusterName = "root:org:other"
+					return nil
+				})
+				Expect(err).To(HaveOccurred())
+				Expect(op).To(BeEquivalentTo(controllerutil.OperationResultNone))
+			})
+		})
+
 		It("aborts immediately if there was an error initially retrieving the object", func() {
 			op, err := controllerutil.CreateOrUpdate(context.TODO(), errorReader{c}, deploy, func() error {
 				Fail("Mutation method should not run")
@@ -454,7 +604,7 @@ var _ = Describe("Controllerutil", func() {
 			By("local deploy object was updated during patch & has same spec, status, resource version as fetched")
 			if fetched == nil {
 				fetched = &appsv1.Deployment{}
-				ExpectWithOffset(1, c.Get(context.TODO(), deplKey, fetched)).To(Succeed())
+				ExpectWithOffset(1, c.Get(context.TODO(), client.ObjectKey{NamespacedName: deplKey}, fetched)).To(Succeed())
 			}
 			ExpectWithOffset(1, fetched.ResourceVersion).To(Equal(deploy.ResourceVersion))
 			ExpectWithOffset(1, fetched.Spec).To(BeEquivalentTo(deploy.Spec))
@@ -465,7 +615,7 @@ var _ = Describe("Controllerutil", func() {
 			By("local deploy object was updated during patch & has same spec, status, resource version as fetched")
 			if fetched == nil {
 				fetched = &appsv1.Deployment{}
-				ExpectWithOffset(1, c.Get(context.TODO(), deplKey, fetched)).To(Succeed())
+				ExpectWithOffset(1, c.Get(context.TODO(), client.ObjectKey{NamespacedName: deplKey}, fetched)).To(Succeed())
 			}
 			ExpectWithOffset(1, fetched.ResourceVersion).To(Equal(deploy.ResourceVersion))
 			ExpectWithOffset(1, *fetched.Spec.Replicas).To(BeEquivalentTo(int32(5)))
@@ -484,7 +634,7 @@ var _ = Describe("Controllerutil", func() {
 
 			By("actually having the deployment created")
 			fetched := &appsv1.Deployment{}
-			Expect(c.Get(context.TODO(), deplKey, fetched)).To(Succeed())
+			Expect(c.Get(context.TODO(), client.ObjectKey{NamespacedName: deplKey}, fetched)).To(Succeed())
 
 			By("being mutated by MutateFn")
 			Expect(fetched.Spec.Template.Spec.Containers).To(HaveLen(1))
@@ -507,7 +657,7 @@ var _ = Describe("Controllerutil", func() {
 
 			By("actually having the deployment scaled")
 			fetched := &appsv1.Deployment{}
-			Expect(c.Get(context.TODO(), deplKey, fetched)).To(Succeed())
+			Expect(c.Get(context.TODO(), client.ObjectKey{NamespacedName: deplKey}, fetched)).To(Succeed())
 			Expect(*fetched.Spec.Replicas).To(Equal(scale))
 			assertLocalDeployWasUpdated(fetched)
 		})
@@ -666,6 +816,80 @@ var _ = Describe("Controllerutil", func() {
 		})
 	})
 
+	Describe("ApplyBundle", func() {
+		var ns *corev1.Namespace
+		var opts controllerutil.ApplyBundleOptions
+		cluster := logicalcluster.New("root:bundle")
+
+		BeforeEach(func() {
+			ns = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("bundle-%d", rand.Int31())}} //nolint:gosec
+			opts = controllerutil.ApplyBundleOptions{
+				FieldOwner:  "bundle-test",
+				Reader:      c,
+				PruneLabels: map[string]string{"bundle": ns.Name},
+				PruneTypes:  []client.ObjectList{&corev1.ConfigMapList{}},
+			}
+		})
+
+		configMap := func(name, value string) *corev1.ConfigMap {
+			return &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, Name: name},
+				Data:       map[string]string{"value": value},
+			}
+		}
+
+		It("should apply the namespace before the objects it contains", func() {
+			results, err := controllerutil.ApplyBundle(context.TODO(), c, cluster, []client.Object{configMap("a", "1"), ns}, opts)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(2))
+			Expect(results[0].Object.GetName()).To(Equal(ns.Name))
+			Expect(results[0].Operation).To(Equal(controllerutil.OperationResultCreated))
+			Expect(results[1].Object.GetName()).To(Equal("a"))
+			Expect(results[1].Operation).To(Equal(controllerutil.OperationResultCreated))
+
+			cm := &corev1.ConfigMap{}
+			Expect(c.Get(context.TODO(), client.ObjectKeyFromObject(configMap("a", "")), cm)).To(Succeed())
+			Expect(cm.Labels).To(HaveKeyWithValue("bundle", ns.Name))
+		})
+
+		It("should report unchanged and updated objects, and prune the removed ones", func() {
+			_, err := controllerutil.ApplyBundle(context.TODO(), c, cluster, []client.Object{ns, configMap("a", "1"), configMap("b", "1")}, opts)
+			Expect(err).NotTo(HaveOccurred())
+
+			results, err := controllerutil.ApplyBundle(context.TODO(), c, cluster, []client.Object{ns, configMap("a", "2")}, opts)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(3))
+			Expect(results[0].Operation).To(Equal(controllerutil.OperationResultNone))
+			Expect(results[1].Operation).To(Equal(controllerutil.OperationResultUpdated))
+			Expect(results[2].Object.GetName()).To(Equal("b"))
+			Expect(results[2].Operation).To(Equal(controllerutil.OperationResultPruned))
+
+			err = c.Get(context.TODO(), client.ObjectKeyFromObject(configMap("b", "")), &corev1.ConfigMap{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should require a field owner and a reader", func() {
+			opts.FieldOwner = ""
+			_, err := controllerutil.ApplyBundle(context.TODO(), c, cluster, []client.Object{ns}, opts)
+			Expect(err).To(HaveOccurred())
+
+			opts.FieldOwner, opts.Reader = "bundle-test", nil
+			_, err = controllerutil.ApplyBundle(context.TODO(), c, cluster, []client.Object{ns}, opts)
+			Expect(err).To(MatchError(ContainSubstring("reader is required")))
+		})
+
+		It("should require a single logical cluster", func() {
+			_, err := controllerutil.ApplyBundle(context.TODO(), c, logicalcluster.Name{}, []client.Object{ns}, opts)
+			Expect(err).To(MatchError(ContainSubstring("single logical cluster")))
+
+			_, err = controllerutil.ApplyBundle(kcpclient.WithCluster(context.TODO(), logicalcluster.Wildcard), c, logicalcluster.Name{}, []client.Object{ns}, opts)
+			Expect(err).To(MatchError(ContainSubstring("single logical cluster")))
+
+			_, err = controllerutil.ApplyBundle(context.TODO(), c, logicalcluster.Wildcard, []client.Object{ns}, opts)
+			Expect(err).To(MatchError(ContainSubstring("single logical cluster")))
+		})
+	})
+
 	Describe("Finalizers", func() {
 		var deploy *appsv1.Deployment
 
@@ -700,6 +924,71 @@ var _ = Describe("Controllerutil", func() {
 			})
 		})
 
+		Describe("ClusterFinalizer", func() {
+			a := logicalcluster.New("root:a")
+			b := logicalcluster.New("root:b")
+			var cl client.Client
+
+			BeforeEach(func() {
+				newDeploy := func() *appsv1.Deployment {
+					return &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "deploy"}}
+				}
+				cl = fake.NewClusterBuilder().
+					WithRESTMapper(meta.NewDefaultRESTMapper([]schema.GroupVersion{appsv1.SchemeGroupVersion})).
+					WithObjects(a, newDeploy()).
+					WithObjects(b, newDeploy()).
+					Build()
+			})
+
+			get := func(cluster logicalcluster.Name) *appsv1.Deployment {
+				deploy := &appsv1.Deployment{}
+				key := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "default", Name: "deploy"}, Cluster: cluster}
+				Expect(cl.Get(context.Background(), key, deploy)).To(Succeed())
+				return deploy
+			}
+
+			It("should add and remove the finalizer in the logical cluster of the object", func() {
+				f := controllerutil.NewClusterFinalizer(cl, testFinalizer)
+				deploy := get(b)
+
+				patched, err := f.AddFinalizer(context.Background(), deploy)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(patched).To(BeTrue())
+				Expect(get(b).Finalizers).To(ConsistOf(testFinalizer))
+				Expect(get(a).Finalizers).To(BeEmpty())
+
+				patched, err = f.AddFinalizer(context.Background(), deploy)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(patched).To(BeFalse())
+
+				patched, err = f.RemoveFinalizer(kcpclient.WithCluster(context.Background(), b), deploy)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(patched).To(BeTrue())
+				Expect(get(b).Finalizers).To(BeEmpty())
+			})
+
+			It("should refuse to patch an object in another logical cluster than its own", func() {
+				f := controllerutil.NewClusterFinalizer(cl, testFinalizer)
+				deploy := get(b)
+
+				_, err := f.AddFinalizer(kcpclient.WithCluster(context.Background(), a), deploy)
+				Expect(err).To(MatchError(ContainSubstring("of logical cluster root:b in the logical cluster root:a")))
+				Expect(get(a).Finalizers).To(BeEmpty())
+				Expect(get(b).Finalizers).To(BeEmpty())
+			})
+
+			It("should not overwrite the finalizers changed concurrently", func() {
+				f := controllerutil.NewClusterFinalizer(cl, testFinalizer)
+				stale := get(a)
+				fresh := get(a)
+				controllerutil.AddFinalizer(fresh, "other")
+				Expect(cl.Update(context.Background(), fresh)).To(Succeed())
+
+				_, err := f.AddFinalizer(context.Background(), stale)
+				Expect(apierrors.IsConflict(err)).To(BeTrue())
+			})
+		})
+
 		Describe("ContainsFinalizer", func() {
 			It("should check that finalizer is present", func() {
 				controllerutil.AddFinalizer(deploy, testFinalizer)
@@ -0,0 +1,211 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conversion
+
+import (
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// ConvertFunc converts src into dst, an object of the destination kind.
+type ConvertFunc func(src, dst runtime.Object) error
+
+// FieldMapping copies the field at the From path of the source object to the To path
+// of the destination object, e.g. {From: ["spec", "greeting"], To: ["spec", "message"]}.
+type FieldMapping struct {
+	From []string
+	To   []string
+}
+
+type kindPair struct {
+	from, to schema.GroupVersionKind
+}
+
+// Registry converts objects between the versions of a kind with conversion functions
+// registered at runtime, e.g. once the versions served by a logical cluster are known,
+// so that a controller can work with a single hub type whatever the version it reads.
+//
+// Objects are converted, in order, by:
+// - copying them if both are of the same GroupVersionKind, e.g. from unstructured to typed;
+// - the function registered from the source to the destination GroupVersionKind;
+// - ConvertTo or ConvertFrom if one of the types is the Hub of the other, which is Convertible.
+//
+// Unstructured objects of the kinds of the scheme are converted to their typed objects
+// first, so that the Hub and Convertible implementations also apply to them.
+type Registry struct {
+	scheme *runtime.Scheme
+
+	mu    sync.RWMutex
+	funcs map[kindPair]ConvertFunc
+}
+
+// NewRegistry returns an empty Registry resolving the kinds of typed objects with the scheme.
+func NewRegistry(scheme *runtime.Scheme) *Registry {
+	return &Registry{scheme: scheme, funcs: map[kindPair]ConvertFunc{}}
+}
+
+// Register registers the function converting the objects of the from kind to the to
+// kind, replacing the one registered before if any.
+func (r *Registry) Register(from, to schema.GroupVersionKind, fn ConvertFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.funcs[kindPair{from: from, to: to}] = fn
+}
+
+// RegisterFieldMappings registers a conversion from the from kind to the to kind declared
+// by field mappings, which needs no Go type for either of the kinds.  The metadata and the
+// mapped fields only are carried over: map a whole subtree such as ["status"] to keep it.
+func (r *Registry) RegisterFieldMappings(from, to schema.GroupVersionKind, mappings ...FieldMapping) {
+	r.Register(from, to, func(src, dst runtime.Object) error {
+		content, err := toUnstructured(src)
+		if err != nil {
+			return err
+		}
+		out := map[string]interface{}{}
+		if metadata, ok := content["metadata"]; ok {
+			out["metadata"] = metadata
+		}
+		for _, m := range mappings {
+			value, found, err := unstructured.NestedFieldNoCopy(content, m.From...)
+			if err != nil {
+				return fmt.Errorf("cannot map field %v of %s: %w", m.From, from, err)
+			}
+			if !found {
+				continue
+			}
+			if err := unstructured.SetNestedField(out, value, m.To...); err != nil {
+				return fmt.Errorf("cannot map field %v of %s to %v of %s: %w", m.From, from, m.To, to, err)
+			}
+		}
+		return fromUnstructured(out, dst, to)
+	})
+}
+
+// Unregister removes the function converting the objects of the from kind to the to kind.
+func (r *Registry) Unregister(from, to schema.GroupVersionKind) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.funcs, kindPair{from: from, to: to})
+}
+
+// Convert converts src into dst.  The GroupVersionKind of unstructured objects, including
+// dst, must be set.
+func (r *Registry) Convert(src, dst runtime.Object) error {
+	srcGVK, err := r.gvkFor(src)
+	if err != nil {
+		return err
+	}
+	dstGVK, err := r.gvkFor(dst)
+	if err != nil {
+		return err
+	}
+	if srcGVK == dstGVK {
+		return copyInto(src, dst, dstGVK)
+	}
+
+	r.mu.RLock()
+	fn, ok := r.funcs[kindPair{from: srcGVK, to: dstGVK}]
+	r.mu.RUnlock()
+	if ok {
+		return fn(src, dst)
+	}
+
+	typedSrc, err := r.typed(src, srcGVK)
+	if err != nil {
+		return err
+	}
+	typedDst, err := r.typed(dst, dstGVK)
+	if err != nil {
+		return err
+	}
+	srcConvertible, srcIsConvertible := typedSrc.(Convertible)
+	dstConvertible, dstIsConvertible := typedDst.(Convertible)
+	srcHub, srcIsHub := typedSrc.(Hub)
+	dstHub, dstIsHub := typedDst.(Hub)
+	switch {
+	case srcIsConvertible && dstIsHub:
+		err = srcConvertible.ConvertTo(dstHub)
+	case srcIsHub && dstIsConvertible:
+		err = dstConvertible.ConvertFrom(srcHub)
+	default:
+		return fmt.Errorf("no conversion registered from %s to %s", srcGVK, dstGVK)
+	}
+	if err != nil {
+		return err
+	}
+	if typedDst != dst {
+		return copyInto(typedDst, dst, dstGVK)
+	}
+	return nil
+}
+
+func (r *Registry) gvkFor(obj runtime.Object) (schema.GroupVersionKind, error) {
+	if _, isUnstructured := obj.(runtime.Unstructured); isUnstructured {
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		if gvk.Empty() {
+			return gvk, fmt.Errorf("the GroupVersionKind of the unstructured object is not set")
+		}
+		return gvk, nil
+	}
+	return apiutil.GVKForObject(obj, r.scheme)
+}
+
+// typed returns the typed object of the scheme for an unstructured object, or the
+// object itself.
+func (r *Registry) typed(obj runtime.Object, gvk schema.GroupVersionKind) (runtime.Object, error) {
+	if _, isUnstructured := obj.(runtime.Unstructured); !isUnstructured || !r.scheme.Recognizes(gvk) {
+		return obj, nil
+	}
+	typed, err := r.scheme.New(gvk)
+	if err != nil {
+		return nil, err
+	}
+	if err := copyInto(obj, typed, gvk); err != nil {
+		return nil, err
+	}
+	return typed, nil
+}
+
+func toUnstructured(obj runtime.Object) (map[string]interface{}, error) {
+	if u, ok := obj.(runtime.Unstructured); ok {
+		return runtime.DeepCopyJSON(u.UnstructuredContent()), nil
+	}
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+}
+
+func fromUnstructured(content map[string]interface{}, obj runtime.Object, gvk schema.GroupVersionKind) error {
+	if u, ok := obj.(runtime.Unstructured); ok {
+		u.SetUnstructuredContent(content)
+	} else if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, obj); err != nil {
+		return err
+	}
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+	return nil
+}
+
+func copyInto(src, dst runtime.Object, gvk schema.GroupVersionKind) error {
+	content, err := toUnstructured(src)
+	if err != nil {
+		return err
+	}
+	return fromUnstructured(content, dst, gvk)
+}
@@ -23,4 +23,7 @@ limitations under the License.
 //
 // Environment can also be configured to work with an existing cluster, and
 // simply load CRDs and provide client configuration.
+//
+// To test against kcp logical clusters rather than a kube-apiserver, see the kcp
+// subpackage.
 package envtest
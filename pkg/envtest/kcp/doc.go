@@ -0,0 +1,33 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package kcp provides libraries for integration testing against a local kcp server,
+// with a set of logical clusters.
+//
+// The kcp binary is loaded like the control plane binaries of envtest, by default from
+// /usr/local/kubebuilder/bin, which can be overridden by setting the KUBEBUILDER_ASSETS
+// or TEST_ASSET_KCP environment variables.  It can also be downloaded from the releases
+// of kcp.
+//
+//	env := &kcp.Environment{
+//		DownloadVersion: "0.5.0",
+//		Workspaces:      []logicalcluster.Name{logicalcluster.New("root:test:a"), logicalcluster.New("root:test:b")},
+//	}
+//	cfg, err := env.Start()
+//	...
+//	defer env.Stop()
+//	clusterA := env.ClusterConfigs[logicalcluster.New("root:test:a")]
+package kcp
@@ -0,0 +1,81 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kcp
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// releaseURL is the URL of the archive of a kcp release, from its version, OS and architecture.
+var releaseURL = "https://github.com/kcp-dev/kcp/releases/download/v%[1]s/kcp_%[1]s_%[2]s_%[3]s.tar.gz"
+
+// download downloads the kcp binary of the given version to the directory, and returns its path.
+func download(version, dir string) (string, error) {
+	url := fmt.Sprintf(releaseURL, strings.TrimPrefix(version, "v"), runtime.GOOS, runtime.GOARCH)
+	log.V(1).Info("downloading kcp", "url", url)
+
+	res, err := http.Get(url) //nolint:gosec // the URL is built from the release of kcp
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unable to download %s: %s", url, res.Status)
+	}
+
+	gz, err := gzip.NewReader(res.Body)
+	if err != nil {
+		return "", err
+	}
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("the archive %s has no kcp binary", url)
+		}
+		if err != nil {
+			return "", err
+		}
+		if header.Typeflag != tar.TypeReg || path.Base(header.Name) != "kcp" {
+			continue
+		}
+
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", err
+		}
+		binPath := filepath.Join(dir, "kcp")
+		bin, err := os.OpenFile(binPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0755) //nolint:gosec // the binary must be executable
+		if err != nil {
+			return "", err
+		}
+		if _, err := io.Copy(bin, archive); err != nil { //nolint:gosec // the archive is a kcp release
+			bin.Close()
+			return "", err
+		}
+		return binPath, bin.Close()
+	}
+}
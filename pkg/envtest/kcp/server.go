@@ -0,0 +1,250 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kcp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"sigs.k8s.io/controller-runtime/pkg/internal/clusterhost"
+	logf "sigs.k8s.io/controller-runtime/pkg/internal/log"
+	"sigs.k8s.io/controller-runtime/pkg/internal/testing/addr"
+	"sigs.k8s.io/controller-runtime/pkg/internal/testing/process"
+)
+
+var log = logf.RuntimeLog.WithName("test-env").WithName("kcp")
+
+const (
+	defaultStartTimeout = 60 * time.Second
+	defaultStopTimeout  = 20 * time.Second
+)
+
+// clusterWorkspaces is the resource of the workspaces of kcp.
+var clusterWorkspaces = schema.GroupVersionResource{Group: "tenancy.kcp.dev", Version: "v1alpha1", Resource: "clusterworkspaces"}
+
+// Environment starts a kcp server for integration tests, and provisions logical clusters
+// in it, so that multi-cluster controllers can be tested without a kcp deployment.
+type Environment struct {
+	// Path is the path to the kcp binary.  If empty, it is looked up like the control plane
+	// binaries of envtest: TEST_ASSET_KCP, then KUBEBUILDER_ASSETS, then BinaryAssetsDirectory,
+	// then /usr/local/kubebuilder/bin.
+	Path string
+
+	// BinaryAssetsDirectory is the directory the kcp binary is looked up in, and downloaded to.
+	BinaryAssetsDirectory string
+
+	// DownloadVersion is the version of the kcp release to download, e.g. "0.5.0", if the
+	// binary can't be found.  If empty, the binary must be installed beforehand.
+	DownloadVersion string
+
+	// Workspaces are the logical clusters to provision, e.g. root:test:a.  Their missing
+	// parents are provisioned too.
+	Workspaces []logicalcluster.Name
+
+	// WorkspaceType is the type of the provisioned workspaces.  If empty, kcp defaults it.
+	WorkspaceType string
+
+	// RootDirectory is the directory kcp stores its state in.  If empty, a temporary
+	// directory is used, and removed when the server is stopped.
+	RootDirectory string
+
+	// Args are additional arguments passed to kcp start.
+	Args []string
+
+	// StartTimeout is how long kcp may take to start, and the workspaces to be provisioned.
+	// Defaults to 60 seconds.
+	StartTimeout time.Duration
+
+	// StopTimeout is how long kcp may take to stop.  Defaults to 20 seconds.
+	StopTimeout time.Duration
+
+	// Out, Err specify where kcp should write its StdOut, StdErr to.  If not specified,
+	// the output is discarded.
+	Out io.Writer
+	Err io.Writer
+
+	// Config is the admin config of the kcp server, targeting the root of the server, e.g.
+	// for a ClusterSet.  It is populated by Start.
+	Config *rest.Config
+
+	// ClusterConfigs are the admin configs of the provisioned logical clusters.  They are
+	// populated by Start.
+	ClusterConfigs map[logicalcluster.Name]*rest.Config
+
+	processState *process.State
+}
+
+// Start starts kcp, provisions the workspaces, and returns the config of the server.
+func (e *Environment) Start() (*rest.Config, error) {
+	if e.StartTimeout == 0 {
+		e.StartTimeout = defaultStartTimeout
+	}
+	if e.StopTimeout == 0 {
+		e.StopTimeout = defaultStopTimeout
+	}
+	if err := e.ensureBinary(); err != nil {
+		return nil, err
+	}
+
+	port, host, err := addr.Suggest("")
+	if err != nil {
+		return nil, fmt.Errorf("unable to find a port for kcp: %w", err)
+	}
+	e.processState = &process.State{
+		Dir:          e.RootDirectory,
+		Path:         e.Path,
+		StartTimeout: e.StartTimeout,
+		StopTimeout:  e.StopTimeout,
+	}
+	if err := e.processState.Init("kcp"); err != nil {
+		return nil, err
+	}
+	e.processState.Args = append([]string{
+		"start",
+		"--root-directory=" + e.processState.Dir,
+		"--bind-address=" + host,
+		"--secure-port=" + strconv.Itoa(port),
+	}, e.Args...)
+	e.processState.HealthCheck.Scheme = "https"
+	e.processState.HealthCheck.Host = net.JoinHostPort(host, strconv.Itoa(port))
+	e.processState.HealthCheck.Path = "/readyz"
+
+	log.V(1).Info("starting kcp", "path", e.Path, "dir", e.processState.Dir)
+	if err := e.processState.Start(e.Out, e.Err); err != nil {
+		return nil, fmt.Errorf("unable to start kcp: %w", err)
+	}
+
+	config, err := clientcmd.BuildConfigFromFlags("", filepath.Join(e.processState.Dir, "admin.kubeconfig"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to load the admin kubeconfig of kcp: %w", err)
+	}
+	// gotta go fast during tests -- we don't really care about overwhelming our test server
+	config.QPS, config.Burst = 1000.0, 2000.0
+	config.Host = clusterhost.Server(config.Host)
+	e.Config = config
+
+	e.ClusterConfigs = make(map[logicalcluster.Name]*rest.Config, len(e.Workspaces))
+	ctx, cancel := context.WithTimeout(context.Background(), e.StartTimeout)
+	defer cancel()
+	for _, name := range e.Workspaces {
+		if err := e.provision(ctx, name); err != nil {
+			return nil, fmt.Errorf("unable to provision workspace %s: %w", name, err)
+		}
+		e.ClusterConfigs[name] = e.ConfigFor(name)
+	}
+	return e.Config, nil
+}
+
+// Stop stops kcp, and removes its state if it was stored in a temporary directory.
+func (e *Environment) Stop() error {
+	if e.processState == nil {
+		return nil
+	}
+	return e.processState.Stop()
+}
+
+// ConfigFor returns the admin config of the given logical cluster.
+func (e *Environment) ConfigFor(name logicalcluster.Name) *rest.Config {
+	config := rest.CopyConfig(e.Config)
+	config.Host += name.Path()
+	return config
+}
+
+// ensureBinary finds the kcp binary, or downloads it.
+func (e *Environment) ensureBinary() error {
+	if e.Path == "" {
+		e.Path = process.BinPathFinder("kcp", e.BinaryAssetsDirectory)
+	}
+	_, err := os.Stat(e.Path)
+	if err == nil {
+		return nil
+	}
+	if !os.IsNotExist(err) || e.DownloadVersion == "" {
+		return fmt.Errorf("unable to find the kcp binary: %w", err)
+	}
+
+	dir := e.BinaryAssetsDirectory
+	if dir == "" {
+		if dir, err = ioutil.TempDir("", "kcp-"); err != nil {
+			return err
+		}
+	}
+	path, err := download(e.DownloadVersion, dir)
+	if err != nil {
+		return fmt.Errorf("unable to download kcp %s: %w", e.DownloadVersion, err)
+	}
+	e.Path = path
+	return nil
+}
+
+// provision creates the workspace of the logical cluster, and of its missing parents, and
+// waits until they are ready.
+func (e *Environment) provision(ctx context.Context, name logicalcluster.Name) error {
+	parent, ok := name.Parent()
+	if !ok {
+		// The root workspace always exists.
+		return nil
+	}
+	if err := e.provision(ctx, parent); err != nil {
+		return err
+	}
+
+	client, err := dynamic.NewForConfig(e.ConfigFor(parent))
+	if err != nil {
+		return err
+	}
+	workspaces := client.Resource(clusterWorkspaces)
+
+	ws := &unstructured.Unstructured{}
+	ws.SetAPIVersion(clusterWorkspaces.GroupVersion().String())
+	ws.SetKind("ClusterWorkspace")
+	ws.SetName(name.Base())
+	if e.WorkspaceType != "" {
+		if err := unstructured.SetNestedField(ws.Object, e.WorkspaceType, "spec", "type"); err != nil {
+			return err
+		}
+	}
+	if _, err := workspaces.Create(ctx, ws, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	return wait.PollImmediateUntilWithContext(ctx, 100*time.Millisecond, func(ctx context.Context) (bool, error) {
+		ws, err := workspaces.Get(ctx, name.Base(), metav1.GetOptions{})
+		if err != nil {
+			return false, nil //nolint:nilerr // the workspace may not be visible yet
+		}
+		phase, _, _ := unstructured.NestedString(ws.Object, "status", "phase")
+		return phase == "Ready", nil
+	})
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kcp
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/controller-runtime/pkg/internal/clusterhost"
+)
+
+var _ = Describe("kcp Environment", func() {
+	It("should return the configs of the logical clusters", func() {
+		env := &Environment{Config: &rest.Config{Host: clusterhost.Server("https://127.0.0.1:6443/clusters/root")}}
+		Expect(env.Config.Host).To(Equal("https://127.0.0.1:6443"))
+		Expect(env.ConfigFor(logicalcluster.New("root:test:a")).Host).To(Equal("https://127.0.0.1:6443/clusters/root:test:a"))
+		Expect(env.Config.Host).To(Equal("https://127.0.0.1:6443"))
+	})
+
+	It("should fail to start without kcp binary", func() {
+		dir, err := ioutil.TempDir("", "kcp-test-")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		env := &Environment{Path: filepath.Join(dir, "kcp")}
+		_, err = env.Start()
+		Expect(err).To(MatchError(ContainSubstring("unable to find the kcp binary")))
+	})
+
+	Describe("download", func() {
+		var server *httptest.Server
+		var requested string
+		originalURL := releaseURL
+
+		BeforeEach(func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requested = r.URL.Path
+				gz := gzip.NewWriter(w)
+				archive := tar.NewWriter(gz)
+				for name, content := range map[string]string{"LICENSE": "license", "bin/kcp": "#!/bin/sh\n"} {
+					Expect(archive.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg})).To(Succeed())
+					_, err := archive.Write([]byte(content))
+					Expect(err).NotTo(HaveOccurred())
+				}
+				Expect(archive.Close()).To(Succeed())
+				Expect(gz.Close()).To(Succeed())
+			}))
+			releaseURL = server.URL + "/v%[1]s/kcp_%[1]s_%[2]s_%[3]s.tar.gz"
+		})
+
+		AfterEach(func() {
+			server.Close()
+			releaseURL = originalURL
+		})
+
+		It("should extract the kcp binary of the release", func() {
+			dir, err := ioutil.TempDir("", "kcp-test-")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			env := &Environment{BinaryAssetsDirectory: dir, DownloadVersion: "v0.5.0", Path: filepath.Join(dir, "kcp")}
+			Expect(env.ensureBinary()).To(Succeed())
+			Expect(requested).To(Equal("/v0.5.0/kcp_0.5.0_" + runtime.GOOS + "_" + runtime.GOARCH + ".tar.gz"))
+
+			Expect(env.Path).To(Equal(filepath.Join(dir, "kcp")))
+			content, err := ioutil.ReadFile(env.Path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("#!/bin/sh\n"))
+			info, err := os.Stat(env.Path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Perm() & 0100).NotTo(BeZero())
+		})
+	})
+})
@@ -0,0 +1,126 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package komega
+
+import (
+	"context"
+
+	"github.com/kcp-dev/logicalcluster"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// defaultK is the Komega used by the package global functions.
+var defaultK = &komega{ctx: context.Background()}
+
+// SetClient sets the client used by the package global functions.
+func SetClient(c client.Client) {
+	defaultK.client = c
+}
+
+// SetContext sets the context used by the package global functions.
+func SetContext(c context.Context) {
+	defaultK.ctx = c
+}
+
+// SetCluster sets the logical cluster used by the package global functions,
+// unless they are given another one with InCluster.
+func SetCluster(cluster logicalcluster.Name) {
+	defaultK.cluster = cluster
+}
+
+func checkDefaultClient() {
+	if defaultK.client == nil {
+		panic("Default Komega's client is not set. Use SetClient to set it.")
+	}
+}
+
+// Get returns a function that fetches a resource and returns the occurring error.
+// It can be used with gomega.Eventually() like this
+//
+//	deployment := appsv1.Deployment{ ... }
+//	gomega.Eventually(komega.Get(&deployment, komega.InCluster("root:org:a"))).To(gomega.Succeed())
+//
+// By calling the returned function directly it can also be used with gomega.Expect(komega.Get(...)()).To(...)
+func Get(obj client.Object, opts ...Option) func() error {
+	checkDefaultClient()
+	return defaultK.Get(obj, opts...)
+}
+
+// List returns a function that lists resources and returns the occurring error.
+// It can be used with gomega.Eventually() like this
+//
+//	deployments := v1.DeploymentList{ ... }
+//	gomega.Eventually(komega.List(&deployments, komega.InCluster("root:org:a"))).To(gomega.Succeed())
+//
+// By calling the returned function directly it can also be used as gomega.Expect(komega.List(...)()).To(...)
+func List(list client.ObjectList, opts ...client.ListOption) func() error {
+	checkDefaultClient()
+	return defaultK.List(list, opts...)
+}
+
+// Update returns a function that fetches a resource, applies the provided update function and then updates the resource.
+// It can be used with gomega.Eventually() like this:
+//
+//	deployment := appsv1.Deployment{ ... }
+//	gomega.Eventually(komega.Update(&deployment, func() {
+//	  deployment.Spec.Replicas = 3
+//	}, komega.InCluster("root:org:a"))).To(gomega.Succeed())
+//
+// By calling the returned function directly it can also be used as gomega.Expect(komega.Update(...)()).To(...)
+func Update(obj client.Object, f func(), opts ...Option) func() error {
+	checkDefaultClient()
+	return defaultK.Update(obj, f, opts...)
+}
+
+// UpdateStatus returns a function that fetches a resource, applies the provided update function and then updates the resource's status.
+// It can be used with gomega.Eventually() like this:
+//
+//	deployment := appsv1.Deployment{ ... }
+//	gomega.Eventually(komega.UpdateStatus(&deployment, func() {
+//	  deployment.Status.AvailableReplicas = 1
+//	}, komega.InCluster("root:org:a"))).To(gomega.Succeed())
+//
+// By calling the returned function directly it can also be used as gomega.Expect(komega.UpdateStatus(...)()).To(...)
+func UpdateStatus(obj client.Object, f func(), opts ...Option) func() error {
+	checkDefaultClient()
+	return defaultK.UpdateStatus(obj, f, opts...)
+}
+
+// Object returns a function that fetches a resource and returns the object.
+// It can be used with gomega.Eventually() like this:
+//
+//	deployment := appsv1.Deployment{ ... }
+//	gomega.Eventually(komega.Object(&deployment, komega.InCluster("root:org:a"))).To(gstruct.PointTo(gomega.HaveField("Spec.Replicas", gomega.Equal(pointer.Int32(3)))))
+//
+// By calling the returned function directly it can also be used as gomega.Expect(komega.Object(...)()).To(...)
+func Object(obj client.Object, opts ...Option) func() (client.Object, error) {
+	checkDefaultClient()
+	return defaultK.Object(obj, opts...)
+}
+
+// ObjectList returns a function that fetches a resource and returns the object.
+// It can be used with gomega.Eventually() like this:
+//
+//	deployments := appsv1.DeploymentList{ ... }
+//	gomega.Eventually(komega.ObjectList(&deployments, komega.InCluster("root:org:a"))).To(gstruct.PointTo(gomega.HaveField("Items", gomega.HaveLen(1))))
+//
+// By calling the returned function directly it can also be used as gomega.Expect(komega.ObjectList(...)()).To(...)
+func ObjectList(list client.ObjectList, opts ...client.ListOption) func() (client.ObjectList, error) {
+	checkDefaultClient()
+	return defaultK.ObjectList(list, opts...)
+}
@@ -0,0 +1,32 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package komega contains a set of helpers for asserting the state of the
+// objects of logical clusters with gomega.
+//
+// The helpers return functions to be polled by gomega's Eventually and
+// Consistently, so that tests don't need to write their own polling loops:
+//
+//	komega.SetClient(k8sClient)
+//	Eventually(komega.Object(deployment, komega.InCluster("root:org:a"))).Should(gstruct.PointTo(HaveField("Status.ReadyReplicas", Equal(int32(1)))))
+//	Eventually(komega.Update(deployment, func() {
+//		deployment.Spec.Replicas = pointer.Int32(2)
+//	}, komega.InCluster("root:org:a"))).Should(Succeed())
+//
+// Update and UpdateStatus fetch the object again before applying the update
+// function each time they are called, so polling them with Eventually retries
+// the update on conflicts.
+package komega
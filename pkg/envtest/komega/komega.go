@@ -0,0 +1,151 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package komega
+
+import (
+	"context"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// komega is a collection of utilites for writing tests involving a mocked
+// Kubernetes API.
+type komega struct {
+	ctx     context.Context
+	client  client.Client
+	cluster logicalcluster.Name
+}
+
+var _ Komega = &komega{}
+
+// New creates a new Komega instance with the given client.
+func New(c client.Client) Komega {
+	return &komega{
+		client: c,
+		ctx:    context.Background(),
+	}
+}
+
+// WithContext returns a copy that uses the given context.
+func (k komega) WithContext(ctx context.Context) Komega {
+	k.ctx = ctx
+	return &k
+}
+
+// WithCluster returns a copy that reads and writes the objects of the given logical cluster.
+func (k komega) WithCluster(cluster logicalcluster.Name) Komega {
+	k.cluster = cluster
+	return &k
+}
+
+// clusterContext returns the context of the requests to the logical cluster
+// selected by the options, along with that cluster.
+func (k *komega) clusterContext(opts *Options) (context.Context, logicalcluster.Name) {
+	cluster := opts.Cluster
+	if cluster.Empty() {
+		cluster = k.cluster
+	}
+	if cluster.Empty() {
+		return k.ctx, cluster
+	}
+	return kcpclient.WithCluster(k.ctx, cluster), cluster
+}
+
+// objectRequest returns the context and the key to read obj in the logical
+// cluster selected by opts, defaulting to the one of obj.
+func (k *komega) objectRequest(obj client.Object, opts []Option) (context.Context, client.ObjectKey) {
+	ctx, cluster := k.clusterContext((&Options{}).ApplyOptions(opts))
+	key := client.ObjectKeyFromObject(obj)
+	if !cluster.Empty() {
+		key.Cluster = cluster
+	}
+	return ctx, key
+}
+
+// listRequest returns the context of the list requests to the logical cluster
+// selected by the InCluster options among opts.
+func (k *komega) listRequest(opts []client.ListOption) context.Context {
+	o := &Options{}
+	for _, opt := range opts {
+		if c, ok := opt.(InCluster); ok {
+			c.ApplyToKomega(o)
+		}
+	}
+	ctx, _ := k.clusterContext(o)
+	return ctx
+}
+
+// Get returns a function that fetches a resource and returns the occurring error.
+func (k *komega) Get(obj client.Object, opts ...Option) func() error {
+	ctx, key := k.objectRequest(obj, opts)
+	return func() error {
+		return k.client.Get(ctx, key, obj)
+	}
+}
+
+// List returns a function that lists resources and returns the occurring error.
+func (k *komega) List(obj client.ObjectList, opts ...client.ListOption) func() error {
+	ctx := k.listRequest(opts)
+	return func() error {
+		return k.client.List(ctx, obj, opts...)
+	}
+}
+
+// Update returns a function that fetches a resource, applies the provided update function and then updates the resource.
+func (k *komega) Update(obj client.Object, updateFunc func(), opts ...Option) func() error {
+	ctx, key := k.objectRequest(obj, opts)
+	return func() error {
+		if err := k.client.Get(ctx, key, obj); err != nil {
+			return err
+		}
+		updateFunc()
+		return k.client.Update(ctx, obj)
+	}
+}
+
+// UpdateStatus returns a function that fetches a resource, applies the provided update function and then updates the resource's status.
+func (k *komega) UpdateStatus(obj client.Object, updateFunc func(), opts ...Option) func() error {
+	ctx, key := k.objectRequest(obj, opts)
+	return func() error {
+		if err := k.client.Get(ctx, key, obj); err != nil {
+			return err
+		}
+		updateFunc()
+		return k.client.Status().Update(ctx, obj)
+	}
+}
+
+// Object returns a function that fetches a resource and returns the object.
+func (k *komega) Object(obj client.Object, opts ...Option) func() (client.Object, error) {
+	ctx, key := k.objectRequest(obj, opts)
+	return func() (client.Object, error) {
+		err := k.client.Get(ctx, key, obj)
+		return obj, err
+	}
+}
+
+// ObjectList returns a function that fetches a resource and returns the object.
+func (k *komega) ObjectList(obj client.ObjectList, opts ...client.ListOption) func() (client.ObjectList, error) {
+	ctx := k.listRequest(opts)
+	return func() (client.ObjectList, error) {
+		err := k.client.List(ctx, obj, opts...)
+		return obj, err
+	}
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package komega
+
+import (
+	"context"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gstruct"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Komega", func() {
+	clusterA := logicalcluster.New("root:org:a")
+	clusterB := logicalcluster.New("root:org:b")
+
+	deployment := func(cluster logicalcluster.Name, replicas int32) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", ClusterName: cluster.String()},
+			Spec:       appsv1.DeploymentSpec{Replicas: pointer.Int32(replicas)},
+		}
+	}
+
+	var c client.Client
+
+	BeforeEach(func() {
+		c = fake.NewClientBuilder().WithObjects(deployment(clusterA, 1), deployment(clusterB, 2)).Build()
+		SetClient(c)
+		SetContext(context.Background())
+		SetCluster(logicalcluster.Name{})
+	})
+
+	It("should get the objects of the given logical cluster", func() {
+		obj := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+		Expect(Get(obj, InCluster("root:org:b"))()).To(Succeed())
+		Expect(obj.Spec.Replicas).To(Equal(pointer.Int32(2)))
+
+		Eventually(Object(obj, InCluster("root:org:a"))).Should(gstruct.PointTo(HaveField("Spec.Replicas", Equal(pointer.Int32(1)))))
+	})
+
+	It("should default to the logical cluster of the objects", func() {
+		Eventually(Object(deployment(clusterB, 0))).Should(gstruct.PointTo(HaveField("Spec.Replicas", Equal(pointer.Int32(2)))))
+	})
+
+	It("should default to the logical cluster of the helpers", func() {
+		k := New(c).WithCluster(clusterB)
+		obj := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+		Eventually(k.Object(obj)).Should(gstruct.PointTo(HaveField("Spec.Replicas", Equal(pointer.Int32(2)))))
+		Eventually(k.Object(obj, InCluster("root:org:a"))).Should(gstruct.PointTo(HaveField("Spec.Replicas", Equal(pointer.Int32(1)))))
+
+		SetCluster(clusterA)
+		Eventually(Object(obj)).Should(gstruct.PointTo(HaveField("Spec.Replicas", Equal(pointer.Int32(1)))))
+	})
+
+	It("should list the objects of the given logical cluster", func() {
+		list := &appsv1.DeploymentList{}
+		Eventually(ObjectList(list, InCluster("root:org:a"), client.InNamespace("default"))).Should(gstruct.PointTo(HaveField("Items", HaveLen(1))))
+		Expect(List(list, InCluster("root:org:b"))()).To(Succeed())
+		Expect(list.Items).To(HaveLen(1))
+		Expect(list.Items[0].Spec.Replicas).To(Equal(pointer.Int32(2)))
+
+		By("listing the objects of all the logical clusters")
+		Eventually(ObjectList(list, InCluster(logicalcluster.Wildcard.String()))).Should(gstruct.PointTo(HaveField("Items", HaveLen(2))))
+	})
+
+	It("should update the objects of the given logical cluster", func() {
+		obj := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+		Eventually(Update(obj, func() {
+			obj.Spec.Replicas = pointer.Int32(5)
+		}, InCluster("root:org:a"))).Should(Succeed())
+
+		Expect(Object(deployment(clusterA, 0))()).To(gstruct.PointTo(HaveField("Spec.Replicas", Equal(pointer.Int32(5)))))
+		Expect(Object(deployment(clusterB, 0))()).To(gstruct.PointTo(HaveField("Spec.Replicas", Equal(pointer.Int32(2)))))
+	})
+
+	It("should retry the updates on conflicts when polled", func() {
+		obj := deployment(clusterA, 0)
+		conflicted := false
+		Eventually(Update(obj, func() {
+			if !conflicted {
+				conflicted = true
+				other := deployment(clusterA, 0)
+				Expect(Get(other)()).To(Succeed())
+				other.Spec.Replicas = pointer.Int32(3)
+				Expect(c.Update(context.Background(), other)).To(Succeed())
+			}
+			obj.Spec.Replicas = pointer.Int32(4)
+		})).Should(Succeed())
+
+		Expect(conflicted).To(BeTrue())
+		Expect(Object(deployment(clusterA, 0))()).To(gstruct.PointTo(HaveField("Spec.Replicas", Equal(pointer.Int32(4)))))
+	})
+
+	It("should update the status of the objects of the given logical cluster", func() {
+		obj := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+		Eventually(UpdateStatus(obj, func() {
+			obj.Status.ReadyReplicas = 1
+		}, InCluster("root:org:b"))).Should(Succeed())
+
+		Expect(Object(deployment(clusterB, 0))()).To(gstruct.PointTo(HaveField("Status.ReadyReplicas", Equal(int32(1)))))
+		Expect(Object(deployment(clusterA, 0))()).To(gstruct.PointTo(HaveField("Status.ReadyReplicas", Equal(int32(0)))))
+	})
+
+	It("should fail to get objects missing from the logical cluster", func() {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+		Consistently(Get(pod, InCluster("root:org:a")), "100ms").ShouldNot(Succeed())
+	})
+})
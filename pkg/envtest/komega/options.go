@@ -0,0 +1,60 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package komega
+
+import (
+	"github.com/kcp-dev/logicalcluster"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Option is some configuration that modifies the requests of the helpers.
+type Option interface {
+	// ApplyToKomega applies this configuration to the given options.
+	ApplyToKomega(*Options)
+}
+
+// Options contains the configuration of the requests of the helpers.
+type Options struct {
+	// Cluster is the logical cluster of the objects.  If empty, the cluster
+	// of the helpers is used, or else the one of the objects.
+	Cluster logicalcluster.Name
+}
+
+// ApplyOptions applies the given options on these options, and then returns itself.
+func (o *Options) ApplyOptions(opts []Option) *Options {
+	for _, opt := range opts {
+		opt.ApplyToKomega(o)
+	}
+	return o
+}
+
+// InCluster reads and writes the objects of the given logical cluster.  It can
+// be passed to List and ObjectList along with the client.ListOptions, and can be
+// the wildcard cluster to list the objects of all the logical clusters.
+type InCluster string
+
+// ApplyToKomega implements Option.
+func (c InCluster) ApplyToKomega(opts *Options) {
+	opts.Cluster = logicalcluster.New(string(c))
+}
+
+// ApplyToList implements client.ListOption.  The logical cluster is selected
+// through the context of the request rather than the list options.
+func (c InCluster) ApplyToList(*client.ListOptions) {}
+
+var _ client.ListOption = InCluster("")
@@ -27,6 +27,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -234,8 +235,7 @@ func (p *webhookPoller) poll() (done bool, err error) {
 			var obj = &unstructured.Unstructured{}
 			obj.SetGroupVersionKind(gvk)
 			err := c.Get(context.Background(), client.ObjectKey{
-				Namespace: "",
-				Name:      name,
+				NamespacedName: types.NamespacedName{Name: name},
 			}, obj)
 
 			if err == nil {
@@ -315,7 +315,7 @@ func createWebhooks(config *rest.Config, mutHooks []*admissionv1.MutatingWebhook
 // ensureCreated creates or update object if already exists in the cluster.
 func ensureCreated(cs client.Client, obj client.Object) error {
 	existing := obj.DeepCopyObject().(client.Object)
-	err := cs.Get(context.Background(), client.ObjectKey{Name: obj.GetName()}, existing)
+	err := cs.Get(context.Background(), client.ObjectKeyFromObject(obj), existing)
 	switch {
 	case apierrors.IsNotFound(err):
 		if err := cs.Create(context.Background(), obj); err != nil {
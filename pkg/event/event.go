@@ -16,13 +16,20 @@ limitations under the License.
 
 package event
 
-import "sigs.k8s.io/controller-runtime/pkg/client"
+import (
+	"github.com/kcp-dev/logicalcluster"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
 
 // CreateEvent is an event where a Kubernetes object was created.  CreateEvent should be generated
 // by a source.Source and transformed into a reconcile.Request by an handler.EventHandler.
 type CreateEvent struct {
 	// Object is the object from the event
 	Object client.Object
+
+	// Cluster is the logical cluster the event originates from, if known.
+	Cluster logicalcluster.Name
 }
 
 // UpdateEvent is an event where a Kubernetes object was updated.  UpdateEvent should be generated
@@ -33,6 +40,9 @@ type UpdateEvent struct {
 
 	// ObjectNew is the object from the event
 	ObjectNew client.Object
+
+	// Cluster is the logical cluster the event originates from, if known.
+	Cluster logicalcluster.Name
 }
 
 // DeleteEvent is an event where a Kubernetes object was deleted.  DeleteEvent should be generated
@@ -44,6 +54,9 @@ type DeleteEvent struct {
 	// DeleteStateUnknown is true if the Delete event was missed but we identified the object
 	// as having been deleted.
 	DeleteStateUnknown bool
+
+	// Cluster is the logical cluster the event originates from, if known.
+	Cluster logicalcluster.Name
 }
 
 // GenericEvent is an event where the operation type is unknown (e.g. polling or event originating outside the cluster).
@@ -52,4 +65,8 @@ type DeleteEvent struct {
 type GenericEvent struct {
 	// Object is the object from the event
 	Object client.Object
+
+	// Cluster is the logical cluster the event originates from, if known.  Sources fill it
+	// in from the object if it is not set.
+	Cluster logicalcluster.Name
 }
@@ -0,0 +1,47 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"context"
+
+	"github.com/kcp-dev/logicalcluster"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SecretCredentials returns a CredentialsResolver which reads the credentials from the data of a
+// Secret of the logical cluster of each object.  If namespace is empty, the Secret is read from the
+// namespace of the object, so that each namespace and each cluster may use its own credentials.
+func SecretCredentials(c client.Reader, namespace, name string) CredentialsResolver {
+	return CredentialsResolverFunc(func(ctx context.Context, cluster logicalcluster.Name, obj client.Object) (Credentials, error) {
+		key := client.ObjectKey{
+			NamespacedName: types.NamespacedName{Namespace: namespace, Name: name},
+			Cluster:        cluster,
+		}
+		if key.Namespace == "" {
+			key.Namespace = obj.GetNamespace()
+		}
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, key, secret); err != nil {
+			return nil, err
+		}
+		return secret.Data, nil
+	})
+}
@@ -0,0 +1,26 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package external reconciles resources living outside of Kubernetes, e.g. in a cloud API,
+from the objects describing them in any logical cluster.
+
+An ExternalReconciler implements the observe, create, update and delete phases for one
+kind of external resource.  NewReconciler turns it into a reconcile.Reconciler which
+drives the phases, keeps a finalizer on the objects until their external resource is
+deleted, and resolves the credentials to use for the logical cluster of each object.
+*/
+package external
@@ -0,0 +1,149 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// DefaultFinalizer is the finalizer kept on the objects until their external resource is deleted,
+// unless Options.Finalizer is set.
+const DefaultFinalizer = "controller-runtime.sigs.k8s.io/external-resource"
+
+// Options configures the Reconciler returned by NewReconciler.
+type Options struct {
+	// Finalizer is kept on the objects until their external resource is deleted.
+	// Defaults to DefaultFinalizer.
+	Finalizer string
+
+	// Credentials resolves the credentials passed to the ExternalReconciler.  If nil, the
+	// ExternalReconciler is given no credentials.
+	Credentials CredentialsResolver
+
+	// PollInterval is how often the external resource of an object is observed, to detect
+	// changes made outside of the controller.  Defaults to 0, which means the objects are
+	// only reconciled when they change.
+	PollInterval time.Duration
+
+	// DeletePollInterval is how often the external resource of an object being deleted is
+	// observed, until it doesn't exist anymore.  Defaults to 5 seconds.
+	DeletePollInterval time.Duration
+}
+
+// NewReconciler returns a reconcile.Reconciler driving the phases of the ExternalReconciler for
+// the objects built by newObject, read and written with the client c.
+//
+// While an object exists, its external resource is created if it doesn't exist, and updated if it
+// isn't up to date.  Once the object is deleted, its external resource is deleted before its
+// finalizer is removed.  The credentials are resolved for the logical cluster of the request.
+func NewReconciler(c client.Client, newObject func() client.Object, ext ExternalReconciler, opts Options) reconcile.Reconciler {
+	if opts.Finalizer == "" {
+		opts.Finalizer = DefaultFinalizer
+	}
+	if opts.DeletePollInterval <= 0 {
+		opts.DeletePollInterval = 5 * time.Second
+	}
+	return &reconciler{client: c, newObject: newObject, external: ext, opts: opts}
+}
+
+var _ reconcile.Reconciler = &reconciler{}
+
+type reconciler struct {
+	client    client.Client
+	newObject func() client.Object
+	external  ExternalReconciler
+	opts      Options
+}
+
+// Reconcile implements reconcile.Reconciler.
+func (r *reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	log := logf.FromContext(ctx)
+
+	obj := r.newObject()
+	if err := r.client.Get(ctx, req.ObjectKey, obj); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+
+	cluster := req.Cluster
+	if cluster.Empty() {
+		cluster = logicalcluster.From(obj)
+	}
+	var creds Credentials
+	if r.opts.Credentials != nil {
+		var err error
+		if creds, err = r.opts.Credentials.Resolve(ctx, cluster, obj); err != nil {
+			return reconcile.Result{}, fmt.Errorf("failed to resolve credentials: %w", err)
+		}
+	}
+
+	observation, err := r.external.Observe(ctx, obj, creds)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("failed to observe external resource: %w", err)
+	}
+
+	if !obj.GetDeletionTimestamp().IsZero() {
+		if !controllerutil.ContainsFinalizer(obj, r.opts.Finalizer) {
+			return reconcile.Result{}, nil
+		}
+		if observation.Exists {
+			log.V(1).Info("Deleting external resource")
+			if err := r.external.Delete(ctx, obj, creds); err != nil {
+				return reconcile.Result{}, fmt.Errorf("failed to delete external resource: %w", err)
+			}
+			return reconcile.Result{RequeueAfter: r.opts.DeletePollInterval}, r.updateStatus(ctx, obj)
+		}
+		controllerutil.RemoveFinalizer(obj, r.opts.Finalizer)
+		return reconcile.Result{}, client.IgnoreNotFound(r.client.Update(ctx, obj))
+	}
+
+	if !controllerutil.ContainsFinalizer(obj, r.opts.Finalizer) {
+		controllerutil.AddFinalizer(obj, r.opts.Finalizer)
+		if err := r.client.Update(ctx, obj); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	switch {
+	case !observation.Exists:
+		log.V(1).Info("Creating external resource")
+		if err := r.external.Create(ctx, obj, creds); err != nil {
+			return reconcile.Result{}, fmt.Errorf("failed to create external resource: %w", err)
+		}
+	case !observation.UpToDate:
+		log.V(1).Info("Updating external resource")
+		if err := r.external.Update(ctx, obj, creds); err != nil {
+			return reconcile.Result{}, fmt.Errorf("failed to update external resource: %w", err)
+		}
+	default:
+		return reconcile.Result{RequeueAfter: r.opts.PollInterval}, nil
+	}
+	return reconcile.Result{RequeueAfter: r.opts.PollInterval}, r.updateStatus(ctx, obj)
+}
+
+// updateStatus persists the status the ExternalReconciler may have recorded on the object.
+// Objects without a status subresource are ignored.
+func (r *reconciler) updateStatus(ctx context.Context, obj client.Object) error {
+	return client.IgnoreNotFound(r.client.Status().Update(ctx, obj))
+}
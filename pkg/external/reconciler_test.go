@@ -0,0 +1,145 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external_test
+
+import (
+	"context"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/external"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// fakeAPI is an external API storing the data of ConfigMaps, per token.
+type fakeAPI struct {
+	resources map[string]map[string]string
+}
+
+func (a *fakeAPI) key(obj client.Object, creds external.Credentials) string {
+	return string(creds["token"]) + "/" + obj.GetName()
+}
+
+func (a *fakeAPI) Observe(_ context.Context, obj client.Object, creds external.Credentials) (external.Observation, error) {
+	data, ok := a.resources[a.key(obj, creds)]
+	if !ok {
+		return external.Observation{}, nil
+	}
+	return external.Observation{Exists: true, UpToDate: data["value"] == obj.(*corev1.ConfigMap).Data["value"]}, nil
+}
+
+func (a *fakeAPI) Create(_ context.Context, obj client.Object, creds external.Credentials) error {
+	a.resources[a.key(obj, creds)] = map[string]string{"value": obj.(*corev1.ConfigMap).Data["value"]}
+	return nil
+}
+
+func (a *fakeAPI) Update(ctx context.Context, obj client.Object, creds external.Credentials) error {
+	return a.Create(ctx, obj, creds)
+}
+
+func (a *fakeAPI) Delete(_ context.Context, obj client.Object, creds external.Credentials) error {
+	delete(a.resources, a.key(obj, creds))
+	return nil
+}
+
+var _ = Describe("external.NewReconciler", func() {
+	var cl client.Client
+	var api *fakeAPI
+	var r reconcile.Reconciler
+	ctx := context.Background()
+	cluster := logicalcluster.New("root:org:ws")
+	req := reconcile.Request{ObjectKey: client.ObjectKey{
+		NamespacedName: types.NamespacedName{Namespace: "ns", Name: "cm"},
+		Cluster:        cluster,
+	}}
+
+	BeforeEach(func() {
+		cl = fake.NewClientBuilder().WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "creds", ClusterName: cluster.String()},
+				Data:       map[string][]byte{"token": []byte("ws")},
+			},
+			&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "cm", ClusterName: cluster.String()},
+				Data:       map[string]string{"value": "1"},
+			},
+		).Build()
+		api = &fakeAPI{resources: map[string]map[string]string{}}
+		r = external.NewReconciler(cl, func() client.Object { return &corev1.ConfigMap{} }, api, external.Options{
+			Credentials: external.SecretCredentials(cl, "", "creds"),
+		})
+	})
+
+	It("should create and update the external resource with the credentials of the cluster", func() {
+		_, err := r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(api.resources).To(HaveKeyWithValue("ws/cm", map[string]string{"value": "1"}))
+
+		cm := &corev1.ConfigMap{}
+		Expect(cl.Get(ctx, req.ObjectKey, cm)).To(Succeed())
+		Expect(controllerutil.ContainsFinalizer(cm, external.DefaultFinalizer)).To(BeTrue())
+
+		cm.Data["value"] = "2"
+		Expect(cl.Update(ctx, cm)).To(Succeed())
+		_, err = r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(api.resources).To(HaveKeyWithValue("ws/cm", map[string]string{"value": "2"}))
+	})
+
+	It("should delete the external resource before releasing the object", func() {
+		_, err := r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+
+		cm := &corev1.ConfigMap{}
+		Expect(cl.Get(ctx, req.ObjectKey, cm)).To(Succeed())
+		Expect(cl.Delete(ctx, cm)).To(Succeed())
+
+		By("deleting the external resource")
+		result, err := r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+		Expect(api.resources).To(BeEmpty())
+		Expect(cl.Get(ctx, req.ObjectKey, cm)).To(Succeed())
+
+		By("removing the finalizer once the external resource is gone")
+		_, err = r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		err = cl.Get(ctx, req.ObjectKey, cm)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should fail if the credentials can't be resolved", func() {
+		other := req
+		other.Cluster = logicalcluster.New("root:other")
+		Expect(cl.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "cm", ClusterName: other.Cluster.String()},
+		})).To(Succeed())
+
+		_, err := r.Reconcile(ctx, other)
+		Expect(err).To(HaveOccurred())
+		Expect(api.resources).To(BeEmpty())
+	})
+})
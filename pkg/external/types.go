@@ -0,0 +1,72 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"context"
+
+	"github.com/kcp-dev/logicalcluster"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Observation is the state of the external resource of an object.
+type Observation struct {
+	// Exists is whether the external resource exists.
+	Exists bool
+
+	// UpToDate is whether the external resource matches the desired state of the object.
+	// It is ignored if the resource doesn't exist.
+	UpToDate bool
+}
+
+// Credentials are the secret values used to access the external API, e.g. the data of a Secret.
+type Credentials map[string][]byte
+
+// ExternalReconciler manages the external resources of one kind of objects.  Its methods are
+// given the credentials resolved for the logical cluster of the object, if any.
+//
+// The object may be modified, e.g. to record the identifier of the external resource in
+// its status: the status of the object is updated after each phase that returns no error.
+type ExternalReconciler interface { //nolint:revive
+	// Observe returns the state of the external resource of the object.
+	Observe(ctx context.Context, obj client.Object, creds Credentials) (Observation, error)
+
+	// Create creates the external resource of the object.
+	Create(ctx context.Context, obj client.Object, creds Credentials) error
+
+	// Update updates the external resource of the object to match its desired state.
+	Update(ctx context.Context, obj client.Object, creds Credentials) error
+
+	// Delete deletes the external resource of the object.  The deletion may be asynchronous:
+	// the object is released once Observe reports that the resource doesn't exist anymore.
+	Delete(ctx context.Context, obj client.Object, creds Credentials) error
+}
+
+// CredentialsResolver returns the credentials to use for the external resource of an object.
+type CredentialsResolver interface {
+	// Resolve returns the credentials for the object of the given logical cluster.
+	Resolve(ctx context.Context, cluster logicalcluster.Name, obj client.Object) (Credentials, error)
+}
+
+// CredentialsResolverFunc is a function that implements CredentialsResolver.
+type CredentialsResolverFunc func(ctx context.Context, cluster logicalcluster.Name, obj client.Object) (Credentials, error)
+
+// Resolve implements CredentialsResolver.
+func (f CredentialsResolverFunc) Resolve(ctx context.Context, cluster logicalcluster.Name, obj client.Object) (Credentials, error) {
+	return f(ctx, cluster, obj)
+}
@@ -77,7 +77,7 @@ func (e *EnqueueRequestForObject) Generic(evt event.GenericEvent, q workqueue.Ra
 }
 
 func request(obj client.Object) reconcile.Request {
-	return reconcile.Request{client.ObjectKey{
+	return reconcile.Request{ObjectKey: client.ObjectKey{
 		Cluster: logicalcluster.From(obj),
 		NamespacedName: types.NamespacedName{
 			Namespace: obj.GetNamespace(),
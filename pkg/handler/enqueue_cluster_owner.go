@@ -0,0 +1,143 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	logf "sigs.k8s.io/controller-runtime/pkg/internal/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
+)
+
+var _ EventHandler = &EnqueueRequestForClusterOwner{}
+
+var clusterOwnerLog = logf.RuntimeLog.WithName("eventhandler").WithName("EnqueueRequestForClusterOwner")
+
+// EnqueueRequestForClusterOwner enqueues Requests for the owners of an object recorded with
+// controllerutil.SetClusterOwnerReference, which may be in other logical clusters than the object.
+//
+// If a controller reconciling APIExports creates objects in the workspaces binding them, it may
+// reconcile the APIExports in response to the events of these objects using:
+//
+// - a source.Kind Source with the Type of the objects, watching all the workspaces.
+//
+// - a handler.EnqueueRequestForClusterOwner EventHandler with an OwnerType of APIExport.
+//
+// The Requests are for the logical cluster and namespace recorded for the owners, rather than the
+// ones of the Event.
+type EnqueueRequestForClusterOwner struct {
+	// OwnerType is the type of the Owner object to look for in the owners annotation.  Only Group and
+	// Kind are compared.
+	OwnerType runtime.Object
+
+	// IsController if set will only look at the owner recorded as the controller.
+	IsController bool
+
+	// groupKind is the cached Group and Kind from OwnerType
+	groupKind schema.GroupKind
+}
+
+// Create implements EventHandler.
+func (e *EnqueueRequestForClusterOwner) Create(evt event.CreateEvent, q workqueue.RateLimitingInterface) {
+	reqs := map[reconcile.Request]empty{}
+	e.getOwnerReconcileRequest(evt.Object, reqs)
+	for req := range reqs {
+		q.Add(req)
+	}
+}
+
+// Update implements EventHandler.
+func (e *EnqueueRequestForClusterOwner) Update(evt event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	reqs := map[reconcile.Request]empty{}
+	e.getOwnerReconcileRequest(evt.ObjectOld, reqs)
+	e.getOwnerReconcileRequest(evt.ObjectNew, reqs)
+	for req := range reqs {
+		q.Add(req)
+	}
+}
+
+// Delete implements EventHandler.
+func (e *EnqueueRequestForClusterOwner) Delete(evt event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	reqs := map[reconcile.Request]empty{}
+	e.getOwnerReconcileRequest(evt.Object, reqs)
+	for req := range reqs {
+		q.Add(req)
+	}
+}
+
+// Generic implements EventHandler.
+func (e *EnqueueRequestForClusterOwner) Generic(evt event.GenericEvent, q workqueue.RateLimitingInterface) {
+	reqs := map[reconcile.Request]empty{}
+	e.getOwnerReconcileRequest(evt.Object, reqs)
+	for req := range reqs {
+		q.Add(req)
+	}
+}
+
+// getOwnerReconcileRequest looks at object and builds a map of reconcile.Request to reconcile
+// the owners of object that match e.OwnerType, in their logical clusters.
+func (e *EnqueueRequestForClusterOwner) getOwnerReconcileRequest(object client.Object, result map[reconcile.Request]empty) {
+	if object == nil {
+		return
+	}
+	refs, err := controllerutil.GetClusterOwnerReferences(object)
+	if err != nil {
+		clusterOwnerLog.Error(err, "Could not get the cluster owners", "object", client.ObjectKeyFromObject(object))
+		return
+	}
+	for _, ref := range refs {
+		if e.IsController && !ref.Controller {
+			continue
+		}
+		refGV, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil {
+			clusterOwnerLog.Error(err, "Could not parse owner APIVersion", "api version", ref.APIVersion)
+			continue
+		}
+		if ref.Kind != e.groupKind.Kind || refGV.Group != e.groupKind.Group {
+			continue
+		}
+		result[reconcile.Request{ObjectKey: client.ObjectKey{
+			Cluster:        ref.Cluster,
+			NamespacedName: types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name},
+		}}] = empty{}
+	}
+}
+
+var _ inject.Scheme = &EnqueueRequestForClusterOwner{}
+
+// InjectScheme is called by the Controller to provide a singleton scheme to the EnqueueRequestForClusterOwner.
+func (e *EnqueueRequestForClusterOwner) InjectScheme(s *runtime.Scheme) error {
+	kinds, _, err := s.ObjectKinds(e.OwnerType)
+	if err != nil {
+		clusterOwnerLog.Error(err, "Could not get ObjectKinds for OwnerType", "owner type", fmt.Sprintf("%T", e.OwnerType))
+		return err
+	}
+	if len(kinds) != 1 {
+		return fmt.Errorf("expected exactly 1 kind for OwnerType %T, but found %s kinds", e.OwnerType, kinds)
+	}
+	e.groupKind = schema.GroupKind{Group: kinds[0].Group, Kind: kinds[0].Kind}
+	return nil
+}
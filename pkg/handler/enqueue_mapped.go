@@ -17,9 +17,11 @@ limitations under the License.
 package handler
 
 import (
+	"github.com/kcp-dev/logicalcluster"
 	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	logf "sigs.k8s.io/controller-runtime/pkg/internal/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
 )
@@ -28,6 +30,10 @@ import (
 // This type is usually used with EnqueueRequestsFromMapFunc when registering an event handler.
 type MapFunc func(client.Object) []reconcile.Request
 
+// ClusterMapFunc is the signature required for enqueueing requests from a generic function which needs the
+// logical cluster of the object of the event, see EnqueueRequestsFromClusterMapFunc.
+type ClusterMapFunc func(logicalcluster.Name, client.Object) []reconcile.Request
+
 // EnqueueRequestsFromMapFunc enqueues Requests by running a transformation function that outputs a collection
 // of reconcile.Requests on each Event.  The reconcile.Requests may be for an arbitrary set of objects
 // defined by some user specified transformation of the source Event.  (e.g. trigger Reconciler for a set of objects
@@ -38,17 +44,56 @@ type MapFunc func(client.Object) []reconcile.Request
 //
 // For UpdateEvents which contain both a new and old object, the transformation function is run on both
 // objects and both sets of Requests are enqueue.
+//
+// Requests returned without a Cluster are for objects in the logical cluster of the object of the Event,
+// so that Requests for objects with the same namespace and name in different logical clusters don't collide.
 func EnqueueRequestsFromMapFunc(fn MapFunc) EventHandler {
 	return &enqueueRequestsFromMapFunc{
 		toRequests: fn,
 	}
 }
 
+// EnqueueRequestsFromClusterMapFunc enqueues Requests like EnqueueRequestsFromMapFunc, passing fn the
+// logical cluster of the object of the Event along with the object, e.g. to look up related objects in
+// the same cluster, or to map it to objects in other clusters.
+//
+// Requests returned without a Cluster are for objects in the logical cluster of the object of the Event,
+// while the Requests with a Cluster are enqueued for that cluster.  Requests with the wildcard cluster
+// are dropped and logged.
+func EnqueueRequestsFromClusterMapFunc(fn ClusterMapFunc) EventHandler {
+	return &enqueueRequestsFromMapFunc{
+		toClusterRequests: fn,
+	}
+}
+
+// EnqueueRequestsAcrossClusters enqueues the Requests returned by fn like EnqueueRequestsFromMapFunc, for
+// Requests targeting objects in other logical clusters than the object of the Event, e.g. the object of an
+// API export in its own workspace for the events of its bindings in the workspaces of tenants.
+//
+// fn must set the Cluster of each Request explicitly: the Requests returned without a Cluster, or with the
+// wildcard cluster, are dropped and logged, rather than defaulted to the cluster of the object.
+// Controllers reconcile each Request with a context targeting its cluster, so that the client of the
+// manager reads and writes the objects of the mapped cluster.
+func EnqueueRequestsAcrossClusters(fn MapFunc) EventHandler {
+	return &enqueueRequestsFromMapFunc{
+		toRequests:     fn,
+		acrossClusters: true,
+	}
+}
+
 var _ EventHandler = &enqueueRequestsFromMapFunc{}
 
+var mapLog = logf.RuntimeLog.WithName("eventhandler").WithName("EnqueueRequestsAcrossClusters")
+
 type enqueueRequestsFromMapFunc struct {
 	// Mapper transforms the argument into a slice of keys to be reconciled
 	toRequests MapFunc
+
+	// toClusterRequests is used instead of toRequests if set, see EnqueueRequestsFromClusterMapFunc.
+	toClusterRequests ClusterMapFunc
+
+	// acrossClusters requires the Requests to carry their cluster, instead of defaulting it.
+	acrossClusters bool
 }
 
 // Create implements EventHandler.
@@ -77,7 +122,27 @@ func (e *enqueueRequestsFromMapFunc) Generic(evt event.GenericEvent, q workqueue
 }
 
 func (e *enqueueRequestsFromMapFunc) mapAndEnqueue(q workqueue.RateLimitingInterface, object client.Object, reqs map[reconcile.Request]empty) {
-	for _, req := range e.toRequests(object) {
+	cluster := logicalcluster.From(object)
+	var requests []reconcile.Request
+	if e.toClusterRequests != nil {
+		requests = e.toClusterRequests(cluster, object)
+	} else {
+		requests = e.toRequests(object)
+	}
+	for _, req := range requests {
+		if e.acrossClusters && (req.Cluster.Empty() || req.Cluster == logicalcluster.Wildcard) {
+			mapLog.Error(nil, "Dropping Request without an explicit logical cluster",
+				"request", req, "cluster", cluster.String())
+			continue
+		}
+		if e.toClusterRequests != nil && req.Cluster == logicalcluster.Wildcard {
+			mapLog.Error(nil, "Dropping Request for the wildcard logical cluster",
+				"request", req, "cluster", cluster.String())
+			continue
+		}
+		if req.Cluster.Empty() {
+			req.Cluster = cluster
+		}
 		_, ok := reqs[req]
 		if !ok {
 			q.Add(req)
@@ -93,5 +158,8 @@ func (e *enqueueRequestsFromMapFunc) InjectFunc(f inject.Func) error {
 	if f == nil {
 		return nil
 	}
+	if e.toClusterRequests != nil {
+		return f(e.toClusterRequests)
+	}
 	return f(e.toRequests)
 }
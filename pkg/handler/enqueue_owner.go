@@ -45,6 +45,10 @@ var log = logf.RuntimeLog.WithName("eventhandler").WithName("EnqueueRequestForOw
 // - a source.Kind Source with Type of Pod.
 //
 // - a handler.EnqueueRequestForOwner EventHandler with an OwnerType of ReplicaSet and IsController set to true.
+//
+// OwnerReferences can't refer to objects in other logical clusters, so the Requests are for the owners
+// in the logical cluster of the Event, or of the object if the Event has no cluster.  Owners with the
+// same name in other logical clusters are never enqueued.
 type EnqueueRequestForOwner struct {
 	// OwnerType is the type of the Owner object to look for in OwnerReferences.  Only Group and Kind are compared.
 	OwnerType runtime.Object
@@ -62,7 +66,7 @@ type EnqueueRequestForOwner struct {
 // Create implements EventHandler.
 func (e *EnqueueRequestForOwner) Create(evt event.CreateEvent, q workqueue.RateLimitingInterface) {
 	reqs := map[reconcile.Request]empty{}
-	e.getOwnerReconcileRequest(evt.Object, reqs)
+	e.getOwnerReconcileRequest(evt.Cluster, evt.Object, reqs)
 	for req := range reqs {
 		q.Add(req)
 	}
@@ -71,8 +75,8 @@ func (e *EnqueueRequestForOwner) Create(evt event.CreateEvent, q workqueue.RateL
 // Update implements EventHandler.
 func (e *EnqueueRequestForOwner) Update(evt event.UpdateEvent, q workqueue.RateLimitingInterface) {
 	reqs := map[reconcile.Request]empty{}
-	e.getOwnerReconcileRequest(evt.ObjectOld, reqs)
-	e.getOwnerReconcileRequest(evt.ObjectNew, reqs)
+	e.getOwnerReconcileRequest(evt.Cluster, evt.ObjectOld, reqs)
+	e.getOwnerReconcileRequest(evt.Cluster, evt.ObjectNew, reqs)
 	for req := range reqs {
 		q.Add(req)
 	}
@@ -81,7 +85,7 @@ func (e *EnqueueRequestForOwner) Update(evt event.UpdateEvent, q workqueue.RateL
 // Delete implements EventHandler.
 func (e *EnqueueRequestForOwner) Delete(evt event.DeleteEvent, q workqueue.RateLimitingInterface) {
 	reqs := map[reconcile.Request]empty{}
-	e.getOwnerReconcileRequest(evt.Object, reqs)
+	e.getOwnerReconcileRequest(evt.Cluster, evt.Object, reqs)
 	for req := range reqs {
 		q.Add(req)
 	}
@@ -90,7 +94,7 @@ func (e *EnqueueRequestForOwner) Delete(evt event.DeleteEvent, q workqueue.RateL
 // Generic implements EventHandler.
 func (e *EnqueueRequestForOwner) Generic(evt event.GenericEvent, q workqueue.RateLimitingInterface) {
 	reqs := map[reconcile.Request]empty{}
-	e.getOwnerReconcileRequest(evt.Object, reqs)
+	e.getOwnerReconcileRequest(evt.Cluster, evt.Object, reqs)
 	for req := range reqs {
 		q.Add(req)
 	}
@@ -117,8 +121,14 @@ func (e *EnqueueRequestForOwner) parseOwnerTypeGroupKind(scheme *runtime.Scheme)
 }
 
 // getOwnerReconcileRequest looks at object and builds a map of reconcile.Request to reconcile
-// owners of object that match e.OwnerType.
-func (e *EnqueueRequestForOwner) getOwnerReconcileRequest(object metav1.Object, result map[reconcile.Request]empty) {
+// owners of object that match e.OwnerType, in the logical cluster of the event, or of object if the event has none.
+func (e *EnqueueRequestForOwner) getOwnerReconcileRequest(cluster logicalcluster.Name, object metav1.Object, result map[reconcile.Request]empty) {
+	if object == nil {
+		return
+	}
+	if cluster.Empty() {
+		cluster = logicalcluster.From(object)
+	}
 	// Iterate through the OwnerReferences looking for a match on Group and Kind against what was requested
 	// by the user
 	for _, ref := range e.getOwnersReferences(object) {
@@ -138,7 +148,7 @@ func (e *EnqueueRequestForOwner) getOwnerReconcileRequest(object metav1.Object,
 			// Match found - add a Request for the object referred to in the OwnerReference
 			request := reconcile.Request{
 				ObjectKey: client.ObjectKey{
-					Cluster: logicalcluster.From(object),
+					Cluster: cluster,
 					NamespacedName: types.NamespacedName{
 						Name: ref.Name,
 					},
@@ -17,6 +17,7 @@
 package handler_test
 
 import (
+	"github.com/kcp-dev/logicalcluster"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
@@ -30,6 +31,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllertest"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -177,12 +179,12 @@ var _ = Describe("Eventhandler", func() {
 				defer GinkgoRecover()
 				Expect(a).To(Equal(pod))
 				req = []reconcile.Request{
-					{
+					{ObjectKey: client.ObjectKey{
 						NamespacedName: types.NamespacedName{Namespace: "foo", Name: "bar"},
-					},
-					{
+					}},
+					{ObjectKey: client.ObjectKey{
 						NamespacedName: types.NamespacedName{Namespace: "biz", Name: "baz"},
-					},
+					}},
 				}
 				return req
 			})
@@ -196,10 +198,10 @@ var _ = Describe("Eventhandler", func() {
 			i1, _ := q.Get()
 			i2, _ := q.Get()
 			Expect([]interface{}{i1, i2}).To(ConsistOf(
-				reconcile.Request{
-					NamespacedName: types.NamespacedName{Namespace: "foo", Name: "bar"}},
-				reconcile.Request{
-					NamespacedName: types.NamespacedName{Namespace: "biz", Name: "baz"}},
+				reconcile.Request{ObjectKey: client.ObjectKey{
+					NamespacedName: types.NamespacedName{Namespace: "foo", Name: "bar"}}},
+				reconcile.Request{ObjectKey: client.ObjectKey{
+					NamespacedName: types.NamespacedName{Namespace: "biz", Name: "baz"}}},
 			))
 		})
 
@@ -209,12 +211,12 @@ var _ = Describe("Eventhandler", func() {
 				defer GinkgoRecover()
 				Expect(a).To(Equal(pod))
 				req = []reconcile.Request{
-					{
+					{ObjectKey: client.ObjectKey{
 						NamespacedName: types.NamespacedName{Namespace: "foo", Name: "bar"},
-					},
-					{
+					}},
+					{ObjectKey: client.ObjectKey{
 						NamespacedName: types.NamespacedName{Namespace: "biz", Name: "baz"},
-					},
+					}},
 				}
 				return req
 			})
@@ -228,10 +230,10 @@ var _ = Describe("Eventhandler", func() {
 			i1, _ := q.Get()
 			i2, _ := q.Get()
 			Expect([]interface{}{i1, i2}).To(ConsistOf(
-				reconcile.Request{
-					NamespacedName: types.NamespacedName{Namespace: "foo", Name: "bar"}},
-				reconcile.Request{
-					NamespacedName: types.NamespacedName{Namespace: "biz", Name: "baz"}},
+				reconcile.Request{ObjectKey: client.ObjectKey{
+					NamespacedName: types.NamespacedName{Namespace: "foo", Name: "bar"}}},
+				reconcile.Request{ObjectKey: client.ObjectKey{
+					NamespacedName: types.NamespacedName{Namespace: "biz", Name: "baz"}}},
 			))
 		})
 
@@ -244,12 +246,12 @@ var _ = Describe("Eventhandler", func() {
 				instance := handler.EnqueueRequestsFromMapFunc(func(a client.Object) []reconcile.Request {
 					defer GinkgoRecover()
 					req = []reconcile.Request{
-						{
+						{ObjectKey: client.ObjectKey{
 							NamespacedName: types.NamespacedName{Namespace: "foo", Name: a.GetName() + "-bar"},
-						},
-						{
+						}},
+						{ObjectKey: client.ObjectKey{
 							NamespacedName: types.NamespacedName{Namespace: "biz", Name: a.GetName() + "-baz"},
-						},
+						}},
 					}
 					return req
 				})
@@ -262,10 +264,10 @@ var _ = Describe("Eventhandler", func() {
 				Expect(q.Len()).To(Equal(2))
 
 				i, _ := q.Get()
-				Expect(i).To(Equal(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "foo", Name: "baz-bar"}}))
+				Expect(i).To(Equal(reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "foo", Name: "baz-bar"}}}))
 
 				i, _ = q.Get()
-				Expect(i).To(Equal(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "biz", Name: "baz-baz"}}))
+				Expect(i).To(Equal(reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "biz", Name: "baz-baz"}}}))
 			})
 
 		It("should enqueue a Request with the function applied to the GenericEvent.", func() {
@@ -274,12 +276,12 @@ var _ = Describe("Eventhandler", func() {
 				defer GinkgoRecover()
 				Expect(a).To(Equal(pod))
 				req = []reconcile.Request{
-					{
+					{ObjectKey: client.ObjectKey{
 						NamespacedName: types.NamespacedName{Namespace: "foo", Name: "bar"},
-					},
-					{
+					}},
+					{ObjectKey: client.ObjectKey{
 						NamespacedName: types.NamespacedName{Namespace: "biz", Name: "baz"},
-					},
+					}},
 				}
 				return req
 			})
@@ -293,10 +295,93 @@ var _ = Describe("Eventhandler", func() {
 			i1, _ := q.Get()
 			i2, _ := q.Get()
 			Expect([]interface{}{i1, i2}).To(ConsistOf(
-				reconcile.Request{
-					NamespacedName: types.NamespacedName{Namespace: "foo", Name: "bar"}},
-				reconcile.Request{
-					NamespacedName: types.NamespacedName{Namespace: "biz", Name: "baz"}},
+				reconcile.Request{ObjectKey: client.ObjectKey{
+					NamespacedName: types.NamespacedName{Namespace: "foo", Name: "bar"}}},
+				reconcile.Request{ObjectKey: client.ObjectKey{
+					NamespacedName: types.NamespacedName{Namespace: "biz", Name: "baz"}}},
+			))
+		})
+
+		It("should default the cluster of the Requests to the cluster of the object.", func() {
+			instance := handler.EnqueueRequestsFromMapFunc(func(a client.Object) []reconcile.Request {
+				return []reconcile.Request{
+					{ObjectKey: client.ObjectKey{
+						NamespacedName: types.NamespacedName{Namespace: "foo", Name: "bar"},
+					}},
+					{ObjectKey: client.ObjectKey{
+						NamespacedName: types.NamespacedName{Namespace: "foo", Name: "bar"},
+						Cluster:        logicalcluster.New("root:c"),
+					}},
+				}
+			})
+
+			podA := pod.DeepCopy()
+			podA.ClusterName = "root:a"
+			podB := pod.DeepCopy()
+			podB.ClusterName = "root:b"
+			instance.Generic(event.GenericEvent{Object: podA}, q)
+			instance.Generic(event.GenericEvent{Object: podB}, q)
+			Expect(q.Len()).To(Equal(3))
+
+			i1, _ := q.Get()
+			i2, _ := q.Get()
+			i3, _ := q.Get()
+			key := types.NamespacedName{Namespace: "foo", Name: "bar"}
+			Expect([]interface{}{i1, i2, i3}).To(ConsistOf(
+				reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: key, Cluster: logicalcluster.New("root:a")}},
+				reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: key, Cluster: logicalcluster.New("root:b")}},
+				reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: key, Cluster: logicalcluster.New("root:c")}},
+			))
+		})
+
+		It("should only enqueue the Requests with an explicit cluster when mapping across clusters.", func() {
+			key := types.NamespacedName{Namespace: "foo", Name: "bar"}
+			instance := handler.EnqueueRequestsAcrossClusters(func(a client.Object) []reconcile.Request {
+				return []reconcile.Request{
+					{ObjectKey: client.ObjectKey{NamespacedName: key}},
+					{ObjectKey: client.ObjectKey{NamespacedName: key, Cluster: logicalcluster.Wildcard}},
+					{ObjectKey: client.ObjectKey{NamespacedName: key, Cluster: logicalcluster.New("root:provider")}},
+				}
+			})
+
+			podA := pod.DeepCopy()
+			podA.ClusterName = "root:a"
+			instance.Create(event.CreateEvent{Object: podA}, q)
+			Expect(q.Len()).To(Equal(1))
+
+			i, _ := q.Get()
+			Expect(i).To(Equal(reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: key, Cluster: logicalcluster.New("root:provider")}}))
+		})
+	})
+
+	Describe("EnqueueRequestsFromClusterMapFunc", func() {
+		It("should pass the cluster of the object to the map function and keep the clusters of the Requests.", func() {
+			key := types.NamespacedName{Namespace: "foo", Name: "bar"}
+			var clusters []logicalcluster.Name
+			instance := handler.EnqueueRequestsFromClusterMapFunc(func(cluster logicalcluster.Name, a client.Object) []reconcile.Request {
+				clusters = append(clusters, cluster)
+				return []reconcile.Request{
+					{ObjectKey: client.ObjectKey{NamespacedName: key}},
+					{ObjectKey: client.ObjectKey{NamespacedName: key, Cluster: logicalcluster.Wildcard}},
+					{ObjectKey: client.ObjectKey{NamespacedName: key, Cluster: logicalcluster.New("root:provider")}},
+				}
+			})
+
+			podA := pod.DeepCopy()
+			podA.ClusterName = "root:a"
+			podB := pod.DeepCopy()
+			podB.ClusterName = "root:b"
+			instance.Update(event.UpdateEvent{ObjectOld: podA, ObjectNew: podB}, q)
+			Expect(clusters).To(Equal([]logicalcluster.Name{logicalcluster.New("root:a"), logicalcluster.New("root:b")}))
+			Expect(q.Len()).To(Equal(3))
+
+			i1, _ := q.Get()
+			i2, _ := q.Get()
+			i3, _ := q.Get()
+			Expect([]interface{}{i1, i2, i3}).To(ConsistOf(
+				reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: key, Cluster: logicalcluster.New("root:a")}},
+				reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: key, Cluster: logicalcluster.New("root:b")}},
+				reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: key, Cluster: logicalcluster.New("root:provider")}},
 			))
 		})
 	})
@@ -323,8 +408,8 @@ var _ = Describe("Eventhandler", func() {
 			Expect(q.Len()).To(Equal(1))
 
 			i, _ := q.Get()
-			Expect(i).To(Equal(reconcile.Request{
-				NamespacedName: types.NamespacedName{Namespace: pod.GetNamespace(), Name: "foo-parent"}}))
+			Expect(i).To(Equal(reconcile.Request{ObjectKey: client.ObjectKey{
+				NamespacedName: types.NamespacedName{Namespace: pod.GetNamespace(), Name: "foo-parent"}}}))
 		})
 
 		It("should enqueue a Request with the Owner of the object in the DeleteEvent.", func() {
@@ -348,8 +433,8 @@ var _ = Describe("Eventhandler", func() {
 			Expect(q.Len()).To(Equal(1))
 
 			i, _ := q.Get()
-			Expect(i).To(Equal(reconcile.Request{
-				NamespacedName: types.NamespacedName{Namespace: pod.GetNamespace(), Name: "foo-parent"}}))
+			Expect(i).To(Equal(reconcile.Request{ObjectKey: client.ObjectKey{
+				NamespacedName: types.NamespacedName{Namespace: pod.GetNamespace(), Name: "foo-parent"}}}))
 		})
 
 		It("should enqueue a Request with the Owners of both objects in the UpdateEvent.", func() {
@@ -387,10 +472,10 @@ var _ = Describe("Eventhandler", func() {
 			i1, _ := q.Get()
 			i2, _ := q.Get()
 			Expect([]interface{}{i1, i2}).To(ConsistOf(
-				reconcile.Request{
-					NamespacedName: types.NamespacedName{Namespace: pod.GetNamespace(), Name: "foo1-parent"}},
-				reconcile.Request{
-					NamespacedName: types.NamespacedName{Namespace: newPod.GetNamespace(), Name: "foo2-parent"}},
+				reconcile.Request{ObjectKey: client.ObjectKey{
+					NamespacedName: types.NamespacedName{Namespace: pod.GetNamespace(), Name: "foo1-parent"}}},
+				reconcile.Request{ObjectKey: client.ObjectKey{
+					NamespacedName: types.NamespacedName{Namespace: newPod.GetNamespace(), Name: "foo2-parent"}}},
 			))
 		})
 
@@ -426,8 +511,8 @@ var _ = Describe("Eventhandler", func() {
 			Expect(q.Len()).To(Equal(1))
 
 			i, _ := q.Get()
-			Expect(i).To(Equal(reconcile.Request{
-				NamespacedName: types.NamespacedName{Namespace: pod.GetNamespace(), Name: "foo-parent"}}))
+			Expect(i).To(Equal(reconcile.Request{ObjectKey: client.ObjectKey{
+				NamespacedName: types.NamespacedName{Namespace: pod.GetNamespace(), Name: "foo-parent"}}}))
 		})
 
 		It("should enqueue a Request with the Owner of the object in the GenericEvent.", func() {
@@ -451,8 +536,8 @@ var _ = Describe("Eventhandler", func() {
 			Expect(q.Len()).To(Equal(1))
 
 			i, _ := q.Get()
-			Expect(i).To(Equal(reconcile.Request{
-				NamespacedName: types.NamespacedName{Namespace: pod.GetNamespace(), Name: "foo-parent"}}))
+			Expect(i).To(Equal(reconcile.Request{ObjectKey: client.ObjectKey{
+				NamespacedName: types.NamespacedName{Namespace: pod.GetNamespace(), Name: "foo-parent"}}}))
 		})
 
 		It("should not enqueue a Request if there are no owners matching Group and Kind.", func() {
@@ -502,8 +587,8 @@ var _ = Describe("Eventhandler", func() {
 			Expect(q.Len()).To(Equal(1))
 
 			i, _ := q.Get()
-			Expect(i).To(Equal(reconcile.Request{
-				NamespacedName: types.NamespacedName{Namespace: pod.GetNamespace(), Name: "foo-parent"}}))
+			Expect(i).To(Equal(reconcile.Request{ObjectKey: client.ObjectKey{
+				NamespacedName: types.NamespacedName{Namespace: pod.GetNamespace(), Name: "foo-parent"}}}))
 		})
 
 		It("should enqueue a Request for a owner that is cluster scoped", func() {
@@ -526,11 +611,56 @@ var _ = Describe("Eventhandler", func() {
 			Expect(q.Len()).To(Equal(1))
 
 			i, _ := q.Get()
-			Expect(i).To(Equal(reconcile.Request{
-				NamespacedName: types.NamespacedName{Namespace: "", Name: "node-1"}}))
+			Expect(i).To(Equal(reconcile.Request{ObjectKey: client.ObjectKey{
+				NamespacedName: types.NamespacedName{Namespace: "", Name: "node-1"}}}))
 
 		})
 
+		It("should enqueue Requests for the Owners in the logical cluster of the objects.", func() {
+			instance := handler.EnqueueRequestForOwner{
+				OwnerType: &appsv1.ReplicaSet{},
+			}
+			Expect(instance.InjectScheme(scheme.Scheme)).To(Succeed())
+			Expect(instance.InjectMapper(mapper)).To(Succeed())
+
+			ownerRefs := []metav1.OwnerReference{{Name: "foo-parent", Kind: "ReplicaSet", APIVersion: "apps/v1"}}
+			oldPod := pod.DeepCopy()
+			oldPod.ClusterName = "root:a"
+			oldPod.OwnerReferences = ownerRefs
+			newPod := pod.DeepCopy()
+			newPod.ClusterName = "root:b"
+			newPod.OwnerReferences = ownerRefs
+			instance.Update(event.UpdateEvent{ObjectOld: oldPod, ObjectNew: newPod}, q)
+			Expect(q.Len()).To(Equal(2))
+
+			key := types.NamespacedName{Namespace: pod.GetNamespace(), Name: "foo-parent"}
+			i1, _ := q.Get()
+			i2, _ := q.Get()
+			Expect([]interface{}{i1, i2}).To(ConsistOf(
+				reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: key, Cluster: logicalcluster.New("root:a")}},
+				reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: key, Cluster: logicalcluster.New("root:b")}},
+			))
+		})
+
+		It("should enqueue a Request for the Owner in the logical cluster of the Event.", func() {
+			instance := handler.EnqueueRequestForOwner{
+				OwnerType: &appsv1.ReplicaSet{},
+			}
+			Expect(instance.InjectScheme(scheme.Scheme)).To(Succeed())
+			Expect(instance.InjectMapper(mapper)).To(Succeed())
+
+			pod.ClusterName = "root:a"
+			pod.OwnerReferences = []metav1.OwnerReference{{Name: "foo-parent", Kind: "ReplicaSet", APIVersion: "apps/v1"}}
+			instance.Generic(event.GenericEvent{Object: pod, Cluster: logicalcluster.New("root:c")}, q)
+			Expect(q.Len()).To(Equal(1))
+
+			i, _ := q.Get()
+			Expect(i).To(Equal(reconcile.Request{ObjectKey: client.ObjectKey{
+				NamespacedName: types.NamespacedName{Namespace: pod.GetNamespace(), Name: "foo-parent"},
+				Cluster:        logicalcluster.New("root:c"),
+			}}))
+		})
+
 		It("should not enqueue a Request if there are no owners.", func() {
 			instance := handler.EnqueueRequestForOwner{
 				OwnerType: &appsv1.ReplicaSet{},
@@ -588,8 +718,8 @@ var _ = Describe("Eventhandler", func() {
 				instance.Create(evt, q)
 				Expect(q.Len()).To(Equal(1))
 				i, _ := q.Get()
-				Expect(i).To(Equal(reconcile.Request{
-					NamespacedName: types.NamespacedName{Namespace: pod.GetNamespace(), Name: "foo2-parent"}}))
+				Expect(i).To(Equal(reconcile.Request{ObjectKey: client.ObjectKey{
+					NamespacedName: types.NamespacedName{Namespace: pod.GetNamespace(), Name: "foo2-parent"}}}))
 			})
 
 			It("should not enqueue reconcile.Requests if there are no Controller owners.", func() {
@@ -672,12 +802,12 @@ var _ = Describe("Eventhandler", func() {
 				i2, _ := q.Get()
 				i3, _ := q.Get()
 				Expect([]interface{}{i1, i2, i3}).To(ConsistOf(
-					reconcile.Request{
-						NamespacedName: types.NamespacedName{Namespace: pod.GetNamespace(), Name: "foo1-parent"}},
-					reconcile.Request{
-						NamespacedName: types.NamespacedName{Namespace: pod.GetNamespace(), Name: "foo2-parent"}},
-					reconcile.Request{
-						NamespacedName: types.NamespacedName{Namespace: pod.GetNamespace(), Name: "foo3-parent"}},
+					reconcile.Request{ObjectKey: client.ObjectKey{
+						NamespacedName: types.NamespacedName{Namespace: pod.GetNamespace(), Name: "foo1-parent"}}},
+					reconcile.Request{ObjectKey: client.ObjectKey{
+						NamespacedName: types.NamespacedName{Namespace: pod.GetNamespace(), Name: "foo2-parent"}}},
+					reconcile.Request{ObjectKey: client.ObjectKey{
+						NamespacedName: types.NamespacedName{Namespace: pod.GetNamespace(), Name: "foo3-parent"}}},
 				))
 			})
 		})
@@ -790,6 +920,88 @@ var _ = Describe("Eventhandler", func() {
 		})
 	})
 
+	Describe("EnqueueRequestForClusterOwner", func() {
+		setOwner := func(obj *corev1.Pod, cluster, name string, controller bool) {
+			rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Namespace: "provider", Name: name}}
+			rs.ClusterName = cluster
+			if controller {
+				Expect(controllerutil.SetClusterControllerReference(rs, obj, scheme.Scheme)).To(Succeed())
+				return
+			}
+			Expect(controllerutil.SetClusterOwnerReference(rs, obj, scheme.Scheme)).To(Succeed())
+		}
+
+		It("should enqueue Requests for the owners in their logical clusters and namespaces.", func() {
+			instance := handler.EnqueueRequestForClusterOwner{
+				OwnerType: &appsv1.ReplicaSet{},
+			}
+			Expect(instance.InjectScheme(scheme.Scheme)).To(Succeed())
+
+			pod.ClusterName = "root:consumer"
+			setOwner(pod, "root:a", "foo-parent", false)
+			setOwner(pod, "root:b", "foo-parent", false)
+			dep := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "provider", Name: "dep"}}
+			dep.ClusterName = "root:a"
+			Expect(controllerutil.SetClusterOwnerReference(dep, pod, scheme.Scheme)).To(Succeed())
+
+			instance.Create(event.CreateEvent{Object: pod, Cluster: logicalcluster.New("root:consumer")}, q)
+			Expect(q.Len()).To(Equal(2))
+
+			i1, _ := q.Get()
+			i2, _ := q.Get()
+			Expect([]interface{}{i1, i2}).To(ConsistOf(
+				reconcile.Request{ObjectKey: client.ObjectKey{Cluster: logicalcluster.New("root:a"),
+					NamespacedName: types.NamespacedName{Namespace: "provider", Name: "foo-parent"}}},
+				reconcile.Request{ObjectKey: client.ObjectKey{Cluster: logicalcluster.New("root:b"),
+					NamespacedName: types.NamespacedName{Namespace: "provider", Name: "foo-parent"}}},
+			))
+		})
+
+		It("should enqueue a Request for the owners of both objects in the UpdateEvent.", func() {
+			instance := handler.EnqueueRequestForClusterOwner{
+				OwnerType: &appsv1.ReplicaSet{},
+			}
+			Expect(instance.InjectScheme(scheme.Scheme)).To(Succeed())
+
+			newPod := pod.DeepCopy()
+			setOwner(pod, "root:a", "foo-parent", false)
+			setOwner(newPod, "root:a", "foo-parent", false)
+			setOwner(newPod, "root:b", "bar-parent", false)
+
+			instance.Update(event.UpdateEvent{ObjectOld: pod, ObjectNew: newPod}, q)
+			Expect(q.Len()).To(Equal(2))
+		})
+
+		It("should only enqueue a Request for the controller if IsController is set.", func() {
+			instance := handler.EnqueueRequestForClusterOwner{
+				OwnerType:    &appsv1.ReplicaSet{},
+				IsController: true,
+			}
+			Expect(instance.InjectScheme(scheme.Scheme)).To(Succeed())
+
+			setOwner(pod, "root:a", "foo-parent", false)
+			setOwner(pod, "root:b", "bar-parent", true)
+
+			instance.Delete(event.DeleteEvent{Object: pod}, q)
+			Expect(q.Len()).To(Equal(1))
+
+			i, _ := q.Get()
+			Expect(i).To(Equal(reconcile.Request{ObjectKey: client.ObjectKey{Cluster: logicalcluster.New("root:b"),
+				NamespacedName: types.NamespacedName{Namespace: "provider", Name: "bar-parent"}}}))
+		})
+
+		It("should not enqueue a Request if the annotation is invalid.", func() {
+			instance := handler.EnqueueRequestForClusterOwner{
+				OwnerType: &appsv1.ReplicaSet{},
+			}
+			Expect(instance.InjectScheme(scheme.Scheme)).To(Succeed())
+
+			pod.Annotations = map[string]string{controllerutil.ClusterOwnersAnnotation: "{"}
+			instance.Generic(event.GenericEvent{Object: pod}, q)
+			Expect(q.Len()).To(Equal(0))
+		})
+	})
+
 	Describe("Funcs", func() {
 		failingFuncs := handler.Funcs{
 			CreateFunc: func(event.CreateEvent, workqueue.RateLimitingInterface) {
@@ -68,14 +68,14 @@ func ExampleEnqueueRequestsFromMapFunc() {
 		&source.Kind{Type: &appsv1.Deployment{}},
 		handler.EnqueueRequestsFromMapFunc(func(a client.Object) []reconcile.Request {
 			return []reconcile.Request{
-				{NamespacedName: types.NamespacedName{
+				{ObjectKey: client.ObjectKey{NamespacedName: types.NamespacedName{
 					Name:      a.GetName() + "-1",
 					Namespace: a.GetNamespace(),
-				}},
-				{NamespacedName: types.NamespacedName{
+				}}},
+				{ObjectKey: client.ObjectKey{NamespacedName: types.NamespacedName{
 					Name:      a.GetName() + "-2",
 					Namespace: a.GetNamespace(),
-				}},
+				}}},
 			}
 		}),
 	)
@@ -91,28 +91,28 @@ func ExampleFuncs() {
 		&source.Kind{Type: &corev1.Pod{}},
 		handler.Funcs{
 			CreateFunc: func(e event.CreateEvent, q workqueue.RateLimitingInterface) {
-				q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+				q.Add(reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: types.NamespacedName{
 					Name:      e.Object.GetName(),
 					Namespace: e.Object.GetNamespace(),
-				}})
+				}}})
 			},
 			UpdateFunc: func(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
-				q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+				q.Add(reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: types.NamespacedName{
 					Name:      e.ObjectNew.GetName(),
 					Namespace: e.ObjectNew.GetNamespace(),
-				}})
+				}}})
 			},
 			DeleteFunc: func(e event.DeleteEvent, q workqueue.RateLimitingInterface) {
-				q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+				q.Add(reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: types.NamespacedName{
 					Name:      e.Object.GetName(),
 					Namespace: e.Object.GetNamespace(),
-				}})
+				}}})
 			},
 			GenericFunc: func(e event.GenericEvent, q workqueue.RateLimitingInterface) {
-				q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+				q.Add(reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: types.NamespacedName{
 					Name:      e.Object.GetName(),
 					Namespace: e.Object.GetNamespace(),
-				}})
+				}}})
 			},
 		},
 	)
@@ -0,0 +1,95 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package healthz
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/kcp-dev/logicalcluster"
+)
+
+// maxReportedClusters is the number of unsynced clusters named by the error of ClustersSynced.
+const maxReportedClusters = 10
+
+// ClusterStatus is the state of the cache of a logical cluster.
+type ClusterStatus struct {
+	// Cluster is the logical cluster.
+	Cluster logicalcluster.Name `json:"cluster"`
+
+	// Synced is whether the informers of the cache of the cluster are synced.
+	Synced bool `json:"synced"`
+
+	// Error is the error the cluster failed with, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// ClusterStatuser reports the state of the caches of logical clusters, e.g. a cluster.ClusterSet.
+type ClusterStatuser interface {
+	// ClusterStatuses returns the state of the cache of each logical cluster.
+	ClusterStatuses() []ClusterStatus
+}
+
+// ClustersSynced returns a Checker which fails until the caches of all the logical clusters are
+// synced.  The error names the unsynced clusters, up to 10 of them.
+func ClustersSynced(statuser ClusterStatuser) Checker {
+	return func(_ *http.Request) error {
+		var unsynced []string
+		for _, status := range statuser.ClusterStatuses() {
+			if !status.Synced {
+				unsynced = append(unsynced, status.Cluster.String())
+			}
+		}
+		if len(unsynced) == 0 {
+			return nil
+		}
+		sort.Strings(unsynced)
+		if len(unsynced) > maxReportedClusters {
+			return fmt.Errorf("caches of %d clusters not synced: %s and %d more", len(unsynced),
+				strings.Join(unsynced[:maxReportedClusters], ", "), len(unsynced)-maxReportedClusters)
+		}
+		return fmt.Errorf("caches of %d clusters not synced: %s", len(unsynced), strings.Join(unsynced, ", "))
+	}
+}
+
+// ClustersHandler is an http.Handler that writes the state of the caches of the logical
+// clusters as a JSON list, sorted by cluster, for debugging.  With the unsynced query
+// parameter, it only writes the clusters which are not synced.
+type ClustersHandler struct {
+	Statuser ClusterStatuser
+}
+
+func (h ClustersHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	_, unsyncedOnly := req.URL.Query()["unsynced"]
+	statuses := make([]ClusterStatus, 0)
+	for _, status := range h.Statuser.ClusterStatuses() {
+		if unsyncedOnly && status.Synced {
+			continue
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Cluster.String() < statuses[j].Cluster.String() })
+
+	resp.Header().Set("Content-Type", "application/json")
+	resp.Header().Set("X-Content-Type-Options", "nosniff")
+	if err := json.NewEncoder(resp).Encode(statuses); err != nil {
+		log.Error(err, "failed to write cluster statuses")
+	}
+}
@@ -18,9 +18,11 @@ package healthz_test
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 
+	"github.com/kcp-dev/logicalcluster"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
@@ -200,4 +202,48 @@ var _ = Describe("Healthz Handler", func() {
 			Expect(resp.Code).To(Equal(http.StatusOK))
 		})
 	})
+
+	Describe("Clusters", func() {
+		statuser := fakeClusterStatuser{
+			{Cluster: logicalcluster.New("root:b"), Synced: true},
+			{Cluster: logicalcluster.New("root:c"), Error: "forbidden"},
+			{Cluster: logicalcluster.New("root:a")},
+		}
+
+		It("should fail the check naming the clusters which aren't synced", func() {
+			Expect(healthz.ClustersSynced(statuser)(nil)).To(MatchError("caches of 2 clusters not synced: root:a, root:c"))
+			Expect(healthz.ClustersSynced(statuser[:1])(nil)).To(Succeed())
+		})
+
+		It("should only name the first unsynced clusters", func() {
+			var many fakeClusterStatuser
+			for i := 0; i < 12; i++ {
+				many = append(many, healthz.ClusterStatus{Cluster: logicalcluster.New(fmt.Sprintf("root:%02d", i))})
+			}
+			Expect(healthz.ClustersSynced(many)(nil)).To(MatchError(HaveSuffix("root:09 and 2 more")))
+		})
+
+		It("should serve the state of the clusters, sorted", func() {
+			resp := requestTo(healthz.ClustersHandler{Statuser: statuser}, "/")
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(resp.Header().Get("Content-Type")).To(Equal("application/json"))
+			Expect(resp.Body.String()).To(MatchJSON(`[
+				{"cluster": "root:a", "synced": false},
+				{"cluster": "root:b", "synced": true},
+				{"cluster": "root:c", "synced": false, "error": "forbidden"}
+			]`))
+
+			resp = requestTo(healthz.ClustersHandler{Statuser: statuser}, "/?unsynced")
+			Expect(resp.Body.String()).To(MatchJSON(`[
+				{"cluster": "root:a", "synced": false},
+				{"cluster": "root:c", "synced": false, "error": "forbidden"}
+			]`))
+		})
+	})
 })
+
+type fakeClusterStatuser []healthz.ClusterStatus
+
+func (s fakeClusterStatuser) ClusterStatuses() []healthz.ClusterStatus {
+	return append([]healthz.ClusterStatus(nil), s...)
+}
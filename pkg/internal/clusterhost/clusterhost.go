@@ -0,0 +1,48 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package clusterhost parses the hosts of the configs targeting a logical cluster of kcp,
+// of the form https://<server>/clusters/<name>.
+package clusterhost
+
+import (
+	"strings"
+
+	"github.com/kcp-dev/logicalcluster"
+)
+
+// clustersPath prefixes the path of the logical cluster in a host.
+const clustersPath = "/clusters/"
+
+// Cluster returns the logical cluster the host targets, e.g. the wildcard cluster for the
+// /clusters/* path of a cache of all the logical clusters, or the empty name if it targets
+// none.
+func Cluster(host string) logicalcluster.Name {
+	i := strings.Index(host, clustersPath)
+	if i < 0 {
+		return logicalcluster.Name{}
+	}
+	return logicalcluster.New(strings.TrimSuffix(host[i+len(clustersPath):], "/"))
+}
+
+// Server returns the host of the server without the path of the logical cluster the host
+// targets, if any, and without trailing slash.
+func Server(host string) string {
+	if i := strings.Index(host, clustersPath); i >= 0 {
+		host = host[:i]
+	}
+	return strings.TrimSuffix(host, "/")
+}
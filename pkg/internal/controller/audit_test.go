@@ -0,0 +1,144 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+
+	"sigs.k8s.io/controller-runtime/pkg/audit"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/internal/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// memorySink is an audit.Sink keeping the records in memory.
+type memorySink struct {
+	mu      sync.Mutex
+	records []audit.Record
+}
+
+func (s *memorySink) Write(rec audit.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, rec)
+	return nil
+}
+
+func (s *memorySink) recorded() []audit.Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]audit.Record(nil), s.records...)
+}
+
+var _ = Describe("AuditSink", func() {
+	It("should record every reconcile with its trigger, result and error class", func() {
+		req := reconcile.Request{ObjectKey: client.ObjectKey{
+			NamespacedName: types.NamespacedName{Namespace: "ns", Name: "foo"},
+			Cluster:        logicalcluster.New("root:org:ws"),
+		}}
+		attempts := 0
+		sink := &memorySink{}
+		ctrl := &Controller{
+			Name:                    "audited",
+			MaxConcurrentReconciles: 1,
+			RecoverPanic:            true,
+			Do: reconcile.Func(func(context.Context, reconcile.Request) (reconcile.Result, error) {
+				attempts++
+				switch attempts {
+				case 1:
+					return reconcile.Result{}, errors.New("failed")
+				case 2:
+					panic("boom")
+				case 3:
+					return reconcile.Result{RequeueAfter: time.Millisecond}, nil
+				}
+				return reconcile.Result{}, nil
+			}),
+			MakeQueue: func() workqueue.RateLimitingInterface {
+				return workqueue.NewRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, time.Millisecond))
+			},
+			AuditSink: sink,
+			Log:       log.RuntimeLog.WithName("controller").WithName("audited"),
+		}
+		Expect(ctrl.InjectFunc(func(interface{}) error { return nil })).To(Succeed())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			defer GinkgoRecover()
+			Expect(ctrl.Start(ctx)).To(Succeed())
+		}()
+		Eventually(func() bool {
+			ctrl.mu.Lock()
+			defer ctrl.mu.Unlock()
+			return ctrl.Started
+		}).Should(BeTrue())
+		ctrl.mu.Lock()
+		ctrl.Queue.Add(req)
+		ctrl.mu.Unlock()
+
+		Eventually(func() []audit.Record { return sink.recorded() }).Should(HaveLen(4))
+		records := sink.recorded()
+		for _, rec := range records {
+			Expect(rec.Controller).To(Equal("audited"))
+			Expect(rec.Key).To(Equal(req.Key()))
+			Expect(rec.Cluster).To(Equal("root:org:ws"))
+		}
+		Expect(records[0].Trigger).To(Equal(audit.TriggerEvent))
+		Expect(records[0].Result).To(Equal(audit.ResultError))
+		Expect(records[0].ErrorClass).To(Equal(audit.ErrorClassUnknown))
+		Expect(records[0].Error).To(Equal("failed"))
+
+		Expect(records[1].Trigger).To(Equal(audit.TriggerRequeue))
+		Expect(records[1].Retries).To(Equal(1))
+		Expect(records[1].ErrorClass).To(Equal(audit.ErrorClassPanic))
+		Expect(records[1].Error).To(Equal("panic: boom [recovered]"))
+
+		Expect(records[2].Result).To(Equal(audit.ResultRequeueAfter))
+		Expect(records[2].RequeueAfter).To(Equal(time.Millisecond))
+		Expect(records[2].ErrorClass).To(BeEmpty())
+
+		Expect(records[3].Trigger).To(Equal(audit.TriggerRequeueAfter))
+		Expect(records[3].Result).To(Equal(audit.ResultSuccess))
+		Expect(records[3].Retries).To(BeZero())
+	})
+
+	It("should record the requests added with priority as triggered by a deletion", func() {
+		q := newEnqueueTimesQueue(workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()), "test")
+		defer q.ShutDown()
+		q.trackTriggers()
+
+		req := reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: types.NamespacedName{Name: "foo"}}}
+		q.AddWithPriority(req)
+		q.Add(req)
+		item, _ := q.Get()
+		trigger, ok := q.takeTrigger(item)
+		Expect(ok).To(BeTrue())
+		Expect(trigger).To(Equal(audit.TriggerDelete))
+		_, ok = q.takeTrigger(item)
+		Expect(ok).To(BeFalse())
+	})
+})
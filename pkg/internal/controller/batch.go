@@ -0,0 +1,158 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+	"go.opentelemetry.io/otel/trace"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/internal/controller/metrics"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/tracing"
+)
+
+// processNextBatch reads a request off the workqueue, waits for BatchWindow, and then reads
+// the requests queued meanwhile, up to MaxBatchSize, to reconcile them all at once with
+// BatchDo.  It must be the only worker reading the queue, so that reading the requests
+// counted by Len never blocks.
+func (c *Controller) processNextBatch(ctx context.Context) bool {
+	obj, shutdown := c.Queue.Get()
+	if shutdown {
+		return false
+	}
+	batch := []interface{}{obj}
+	defer func() {
+		for _, obj := range batch {
+			c.Queue.Done(obj)
+		}
+	}()
+
+	if c.BatchWindow > 0 {
+		select {
+		case <-ctx.Done():
+		case <-time.After(c.BatchWindow):
+		}
+	}
+	for len(batch) < c.MaxBatchSize && c.Queue.Len() > 0 {
+		obj, shutdown := c.Queue.Get()
+		if shutdown {
+			break
+		}
+		batch = append(batch, obj)
+	}
+
+	if c.WorkerPool != nil {
+		release, err := c.WorkerPool.Acquire(ctx, c.Name)
+		if err != nil {
+			return false
+		}
+		defer release()
+	}
+
+	ctrlmetrics.ActiveWorkers.WithLabelValues(c.Name).Add(1)
+	defer ctrlmetrics.ActiveWorkers.WithLabelValues(c.Name).Add(-1)
+
+	c.batchHandler(ctx, batch)
+	return true
+}
+
+// batchHandler reconciles a batch of requests, and requeues or forgets them all depending on
+// the result.
+func (c *Controller) batchHandler(ctx context.Context, batch []interface{}) {
+	reqs := make([]reconcile.Request, 0, len(batch))
+	for _, obj := range batch {
+		req, ok := obj.(reconcile.Request)
+		if !ok {
+			c.Queue.Forget(obj)
+			c.Log.Error(nil, "Queue item was not a Request", "type", fmt.Sprintf("%T", obj), "value", obj)
+			continue
+		}
+		if c.dropIfRemoved(obj) {
+			continue
+		}
+		reqs = append(reqs, req)
+	}
+	if len(reqs) == 0 {
+		return
+	}
+
+	reconcileID := string(uuid.NewUUID())
+	log := c.Log.WithValues("controller", c.Name, "reconcileID", reconcileID, "batchSize", len(reqs))
+	ctx = logf.IntoContext(ctx, log)
+	ctx = tracing.WithReconcileID(ctx, reconcileID)
+	if c.Config != nil {
+		ctx = reconcile.WithConfig(ctx, c.Config.Get())
+	}
+
+	reconcileStart := time.Now()
+	result, err := c.reconcileBatch(ctx, reqs, reconcileID)
+	c.updateMetrics(logicalcluster.Name{}, time.Since(reconcileStart))
+
+	var label string
+	switch {
+	case err != nil:
+		label = labelError
+		log.Error(err, "Reconciler error")
+	case result.RequeueAfter > 0:
+		label = labelRequeueAfter
+	case result.Requeue:
+		label = labelRequeue
+	default:
+		label = labelSuccess
+	}
+	for _, req := range reqs {
+		switch label {
+		case labelError, labelRequeue:
+			c.Queue.AddRateLimited(req)
+		case labelRequeueAfter:
+			c.Queue.Forget(req)
+			c.Queue.AddAfter(req, result.RequeueAfter)
+		default:
+			c.Queue.Forget(req)
+		}
+		c.recordResult(req.Cluster, label)
+	}
+}
+
+// reconcileBatch calls BatchDo with the requests, in a span of the Tracer if set, recovering
+// its panics if RecoverPanic is set.
+func (c *Controller) reconcileBatch(ctx context.Context, reqs []reconcile.Request, reconcileID string) (_ reconcile.Result, err error) {
+	if c.Tracer != nil {
+		var span trace.Span
+		ctx, span = c.Tracer.Start(ctx, "ReconcileBatch", trace.WithAttributes(
+			tracing.AttributeController.String(c.Name),
+			tracing.AttributeReconcileID.String(reconcileID)))
+		defer func() { tracing.End(span, err) }()
+	}
+	if c.RecoverPanic {
+		defer func() {
+			if r := recover(); r != nil {
+				for _, fn := range utilruntime.PanicHandlers {
+					fn(r)
+				}
+				err = &reconcilePanic{value: r}
+			}
+		}()
+	}
+	return c.BatchDo.ReconcileBatch(ctx, reqs)
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/internal/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("BatchDo", func() {
+	var mu sync.Mutex
+	var batches [][]reconcile.Request
+	var fail bool
+
+	newController := func() *Controller {
+		batches = nil
+		ctrl := &Controller{
+			Name:                    "batch",
+			MaxConcurrentReconciles: 1,
+			Do: reconcile.Func(func(context.Context, reconcile.Request) (reconcile.Result, error) {
+				return reconcile.Result{}, errors.New("the requests should be reconciled in batches")
+			}),
+			BatchDo: reconcile.BatchFunc(func(_ context.Context, reqs []reconcile.Request) (reconcile.Result, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				batches = append(batches, reqs)
+				if fail {
+					fail = false
+					return reconcile.Result{}, errors.New("placement failed")
+				}
+				return reconcile.Result{}, nil
+			}),
+			MaxBatchSize: 3,
+			BatchWindow:  100 * time.Millisecond,
+			MakeQueue: func() workqueue.RateLimitingInterface {
+				return workqueue.NewRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, time.Millisecond))
+			},
+			Log: log.RuntimeLog.WithName("controller").WithName("batch"),
+		}
+		Expect(ctrl.InjectFunc(func(interface{}) error { return nil })).To(Succeed())
+		return ctrl
+	}
+
+	start := func(ctx context.Context, ctrl *Controller) {
+		go func() {
+			defer GinkgoRecover()
+			Expect(ctrl.Start(ctx)).To(Succeed())
+		}()
+		Eventually(func() bool {
+			ctrl.mu.Lock()
+			defer ctrl.mu.Unlock()
+			return ctrl.Started
+		}).Should(BeTrue())
+	}
+
+	request := func(cluster string, i int) reconcile.Request {
+		return reconcile.Request{ObjectKey: client.ObjectKey{
+			NamespacedName: types.NamespacedName{Namespace: "ns", Name: fmt.Sprintf("obj-%d", i)},
+			Cluster:        logicalcluster.New(cluster),
+		}}
+	}
+
+	reconciled := func() []int {
+		mu.Lock()
+		defer mu.Unlock()
+		sizes := make([]int, 0, len(batches))
+		for _, batch := range batches {
+			sizes = append(sizes, len(batch))
+		}
+		return sizes
+	}
+
+	It("should pass the requests queued across clusters in batches of at most MaxBatchSize", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ctrl := newController()
+		start(ctx, ctrl)
+
+		for i := 0; i < 5; i++ {
+			ctrl.Queue.Add(request(fmt.Sprintf("root:%d", i), i))
+		}
+		Eventually(reconciled).Should(Equal([]int{3, 2}))
+	})
+
+	It("should requeue all the requests of a failed batch", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ctrl := newController()
+		mu.Lock()
+		fail = true
+		mu.Unlock()
+		start(ctx, ctrl)
+
+		ctrl.Queue.Add(request("root:a", 0))
+		ctrl.Queue.Add(request("root:b", 1))
+		Eventually(reconciled).Should(Equal([]int{2, 2}))
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(batches[1]).To(ConsistOf(request("root:a", 0), request("root:b", 1)))
+	})
+})
@@ -0,0 +1,98 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sync"
+
+	"github.com/kcp-dev/logicalcluster"
+)
+
+// clusterLimiter limits the number of reconciles running concurrently against
+// the same logical cluster.  Requests which would exceed the limit are parked
+// until a reconcile of their cluster finishes, so that they don't hold a worker
+// that could serve another cluster in the meantime.
+type clusterLimiter struct {
+	mu  sync.Mutex
+	max int
+	// byCluster overrides max for some clusters.  A limit of 0 or less means no limit.
+	byCluster map[logicalcluster.Name]int
+	active    map[logicalcluster.Name]int
+	parked    map[logicalcluster.Name][]interface{}
+	// isParked deduplicates the parked items, as an item may be handed out by
+	// the queue again while it is parked.
+	isParked map[interface{}]struct{}
+}
+
+func newClusterLimiter(max int, byCluster map[logicalcluster.Name]int) *clusterLimiter {
+	return &clusterLimiter{
+		max:       max,
+		byCluster: byCluster,
+		active:    map[logicalcluster.Name]int{},
+		parked:    map[logicalcluster.Name][]interface{}{},
+		isParked:  map[interface{}]struct{}{},
+	}
+}
+
+// tryAcquire reserves a reconcile slot for the cluster and returns true, or parks
+// the item and returns false if the cluster has no free slot.
+func (l *clusterLimiter) tryAcquire(cluster logicalcluster.Name, item interface{}) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if limit := l.limitFor(cluster); limit <= 0 || l.active[cluster] < limit {
+		l.active[cluster]++
+		return true
+	}
+	if _, ok := l.isParked[item]; !ok {
+		l.isParked[item] = struct{}{}
+		l.parked[cluster] = append(l.parked[cluster], item)
+	}
+	return false
+}
+
+// limitFor returns the maximum number of concurrent reconciles of the cluster.
+func (l *clusterLimiter) limitFor(cluster logicalcluster.Name) int {
+	if limit, ok := l.byCluster[cluster]; ok {
+		return limit
+	}
+	return l.max
+}
+
+// release frees a reconcile slot of the cluster and returns the next item parked
+// for it, if any, which should be added back to the queue.
+func (l *clusterLimiter) release(cluster logicalcluster.Name) (interface{}, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active[cluster]--
+	if l.active[cluster] <= 0 {
+		delete(l.active, cluster)
+	}
+
+	parked := l.parked[cluster]
+	if len(parked) == 0 {
+		return nil, false
+	}
+	item := parked[0]
+	parked[0] = nil
+	if len(parked) == 1 {
+		delete(l.parked, cluster)
+	} else {
+		l.parked[cluster] = parked[1:]
+	}
+	delete(l.isParked, item)
+	return item, true
+}
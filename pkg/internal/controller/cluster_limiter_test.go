@@ -0,0 +1,70 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("clusterLimiter", func() {
+	a := logicalcluster.New("root:a")
+	b := logicalcluster.New("root:b")
+
+	It("should limit the reconciles per cluster independently", func() {
+		l := newClusterLimiter(1, nil)
+		Expect(l.tryAcquire(a, "a1")).To(BeTrue())
+		Expect(l.tryAcquire(a, "a2")).To(BeFalse())
+		Expect(l.tryAcquire(b, "b1")).To(BeTrue())
+	})
+
+	It("should hand out the parked items once, in order, when a slot is released", func() {
+		l := newClusterLimiter(1, nil)
+		Expect(l.tryAcquire(a, "a1")).To(BeTrue())
+		Expect(l.tryAcquire(a, "a2")).To(BeFalse())
+		Expect(l.tryAcquire(a, "a3")).To(BeFalse())
+		Expect(l.tryAcquire(a, "a2")).To(BeFalse())
+
+		next, ok := l.release(a)
+		Expect(ok).To(BeTrue())
+		Expect(next).To(Equal("a2"))
+		Expect(l.tryAcquire(a, next)).To(BeTrue())
+
+		next, ok = l.release(a)
+		Expect(ok).To(BeTrue())
+		Expect(next).To(Equal("a3"))
+		Expect(l.tryAcquire(a, next)).To(BeTrue())
+
+		_, ok = l.release(a)
+		Expect(ok).To(BeFalse())
+		Expect(l.active).To(BeEmpty())
+	})
+
+	It("should apply the limits of the given clusters over the default one", func() {
+		l := newClusterLimiter(1, map[logicalcluster.Name]int{a: 2, b: 0})
+		Expect(l.tryAcquire(a, "a1")).To(BeTrue())
+		Expect(l.tryAcquire(a, "a2")).To(BeTrue())
+		Expect(l.tryAcquire(a, "a3")).To(BeFalse())
+		for _, item := range []string{"b1", "b2", "b3"} {
+			Expect(l.tryAcquire(b, item)).To(BeTrue())
+		}
+		c := logicalcluster.New("root:c")
+		Expect(l.tryAcquire(c, "c1")).To(BeTrue())
+		Expect(l.tryAcquire(c, "c2")).To(BeFalse())
+	})
+})
@@ -0,0 +1,169 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// consistencyPollInterval is how often the sources are checked while waiting for them
+// to catch up with a request.
+const consistencyPollInterval = 10 * time.Millisecond
+
+// resourceVersionSource is a source which knows the last resourceVersion observed by its
+// informer, e.g. source.Kind.
+type resourceVersionSource interface {
+	LastSyncResourceVersion() string
+}
+
+var (
+	_ priorityAdder    = &consistencyQueue{}
+	_ lowPriorityAdder = &consistencyQueue{}
+)
+
+// consistencyQueue wraps a queue to record, for each item, the highest resourceVersion observed
+// by the sources of the controller when it was added.  The item is then only processed once all
+// the sources have observed it, so that the reconciler doesn't read objects from the cache which
+// are older than the ones the controller already knew of when the item was added.
+//
+// All the logical clusters are served by the same informers, so this is the high-water mark of
+// the cache of the cluster of the item too.
+type consistencyQueue struct {
+	workqueue.RateLimitingInterface
+
+	mu      sync.Mutex
+	sources []resourceVersionSource
+	tokens  map[interface{}]uint64
+}
+
+func newConsistencyQueue(q workqueue.RateLimitingInterface) *consistencyQueue {
+	return &consistencyQueue{
+		RateLimitingInterface: q,
+		tokens:                map[interface{}]uint64{},
+	}
+}
+
+// addSource makes the queue track the resourceVersion of the source.
+func (q *consistencyQueue) addSource(src resourceVersionSource) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.sources = append(q.sources, src)
+}
+
+// waterMarks returns the highest and the lowest resourceVersions observed by the sources whose
+// resourceVersion is known, and whether there is any.
+func (q *consistencyQueue) waterMarks() (high, low uint64, known bool) {
+	q.mu.Lock()
+	sources := q.sources
+	q.mu.Unlock()
+
+	for _, src := range sources {
+		rv, err := strconv.ParseUint(src.LastSyncResourceVersion(), 10, 64)
+		if err != nil {
+			continue
+		}
+		if rv > high {
+			high = rv
+		}
+		if !known || rv < low {
+			low = rv
+		}
+		known = true
+	}
+	return high, low, known
+}
+
+func (q *consistencyQueue) record(item interface{}) {
+	high, _, known := q.waterMarks()
+	if !known {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if high > q.tokens[item] {
+		q.tokens[item] = high
+	}
+}
+
+// takeToken returns the resourceVersion the sources must have observed before the item is
+// processed, and forgets it.
+func (q *consistencyQueue) takeToken(item interface{}) (uint64, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	token, ok := q.tokens[item]
+	delete(q.tokens, item)
+	return token, ok
+}
+
+// waitFor waits until all the sources have observed the given resourceVersion, or the timeout
+// expires.  It returns whether they caught up.  Sources whose resourceVersion isn't known,
+// e.g. because their informer doesn't expose it, are not waited for.
+func (q *consistencyQueue) waitFor(ctx context.Context, token uint64, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := wait.PollImmediateUntilWithContext(ctx, consistencyPollInterval, func(context.Context) (bool, error) {
+		_, low, known := q.waterMarks()
+		return !known || low >= token, nil
+	})
+	return err == nil
+}
+
+// Add implements workqueue.Interface.
+func (q *consistencyQueue) Add(item interface{}) {
+	q.record(item)
+	q.RateLimitingInterface.Add(item)
+}
+
+// AddAfter implements workqueue.DelayingInterface.
+func (q *consistencyQueue) AddAfter(item interface{}, duration time.Duration) {
+	q.record(item)
+	q.RateLimitingInterface.AddAfter(item, duration)
+}
+
+// AddRateLimited implements workqueue.RateLimitingInterface.
+func (q *consistencyQueue) AddRateLimited(item interface{}) {
+	q.record(item)
+	q.RateLimitingInterface.AddRateLimited(item)
+}
+
+// AddWithPriority implements priorityAdder, falling back to Add if the wrapped
+// queue doesn't support priorities.
+func (q *consistencyQueue) AddWithPriority(item interface{}) {
+	q.record(item)
+	if pq, ok := q.RateLimitingInterface.(priorityAdder); ok {
+		pq.AddWithPriority(item)
+		return
+	}
+	q.RateLimitingInterface.Add(item)
+}
+
+// AddWithLowPriority implements lowPriorityAdder, falling back to Add if the wrapped
+// queue doesn't support priorities.
+func (q *consistencyQueue) AddWithLowPriority(item interface{}) {
+	q.record(item)
+	if pq, ok := q.RateLimitingInterface.(lowPriorityAdder); ok {
+		pq.AddWithLowPriority(item)
+		return
+	}
+	q.RateLimitingInterface.Add(item)
+}
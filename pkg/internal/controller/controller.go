@@ -20,19 +20,36 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/kcp-dev/logicalcluster"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/audit"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/internal/controller/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/kcp"
+	"sigs.k8s.io/controller-runtime/pkg/leaderelection"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/objectlock"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
 	"sigs.k8s.io/controller-runtime/pkg/source"
+	"sigs.k8s.io/controller-runtime/pkg/tracing"
+	"sigs.k8s.io/controller-runtime/pkg/workerpool"
 )
 
 var _ inject.Injector = &Controller{}
@@ -45,11 +62,58 @@ type Controller struct {
 	// MaxConcurrentReconciles is the maximum number of concurrent Reconciles which can be run. Defaults to 1.
 	MaxConcurrentReconciles int
 
+	// MaxConcurrentReconcilesPerCluster is the maximum number of concurrent Reconciles which can be run
+	// against the same logical cluster.  Defaults to 0, which means no limit other than MaxConcurrentReconciles.
+	MaxConcurrentReconcilesPerCluster int
+
+	// MaxConcurrentReconcilesByCluster overrides MaxConcurrentReconcilesPerCluster for the given
+	// logical clusters.
+	MaxConcurrentReconcilesByCluster map[logicalcluster.Name]int
+
+	// clusterLimiter enforces MaxConcurrentReconcilesPerCluster and MaxConcurrentReconcilesByCluster.
+	clusterLimiter *clusterLimiter
+
+	// SyncGate, if set, holds the requests of the logical clusters whose caches are not synced,
+	// e.g. while they resync after an outage, until they are synced.  The held requests are
+	// not failed: they are added back to the queue once their cluster is synced.
+	SyncGate cluster.SyncGate
+
+	// heldRequests are the requests held by SyncGate.
+	heldRequests *heldRequests
+
+	// Suspensions, if set, drops the events of the objects of the suspended logical clusters,
+	// and resyncs the objects of the clusters when they are resumed.  Set SyncGate to hold the
+	// requests of the suspended clusters already queued too.
+	Suspensions *cluster.Suspensions
+
+	// ClusterSet, if set, is the set of the logical clusters the controller watches: once a
+	// cluster is removed from it, the requests of the cluster still queued are dropped, and
+	// the reconciles of the cluster in flight are not retried if they fail.
+	ClusterSet *cluster.ClusterSet
+
+	// ReconcileRemovedClusters makes the controller reconcile the ClusterRemovedRequest of the
+	// logical clusters removed from ClusterSet, see reconcile.ClusterRemovedRequest.
+	ReconcileRemovedClusters bool
+
+	// removedClusters are the clusters removed from ClusterSet.
+	removedClusters *removedClusters
+
 	// Reconciler is a function that can be called at any time with the Name / Namespace of an object and
 	// ensures that the state of the system matches the state specified in the object.
 	// Defaults to the DefaultReconcileFunc.
 	Do reconcile.Reconciler
 
+	// BatchDo, if set, is passed batches of up to MaxBatchSize requests instead of Do being
+	// passed each request.  The batches are read by a single worker.
+	BatchDo reconcile.BatchReconciler
+
+	// MaxBatchSize is the maximum number of requests of a batch passed to BatchDo.
+	MaxBatchSize int
+
+	// BatchWindow is how long the worker waits after reading the first request of a batch
+	// for more requests to be queued.
+	BatchWindow time.Duration
+
 	// MakeQueue constructs the queue for this controller once the controller is ready to start.
 	// This exists because the standard Kubernetes workqueues start themselves immediately, which
 	// leads to goroutine leaks if something calls controller.New repeatedly.
@@ -59,6 +123,13 @@ type Controller struct {
 	// the Queue for processing
 	Queue workqueue.RateLimitingInterface
 
+	// enqueueTimes wraps the queue built by MakeQueue to record when the requests were first enqueued.
+	enqueueTimes *enqueueTimesQueue
+
+	// consistency wraps enqueueTimes to record the resourceVersion the sources must have observed
+	// before the requests are reconciled.  It is nil unless WaitForCacheConsistency is set.
+	consistency *consistencyQueue
+
 	// SetFields is used to inject dependencies into other objects such as Sources, EventHandlers and Predicates
 	// Deprecated: the caller should handle injected fields itself.
 	SetFields func(i interface{}) error
@@ -80,14 +151,94 @@ type Controller struct {
 	// Defaults to 2 minutes if not set.
 	CacheSyncTimeout time.Duration
 
+	// CacheSyncTimeoutByGVK overrides CacheSyncTimeout for Kind sources of the given
+	// GroupVersionKinds.
+	CacheSyncTimeoutByGVK map[schema.GroupVersionKind]time.Duration
+
+	// Scheme is used to resolve the GroupVersionKind of Kind sources when
+	// CacheSyncTimeoutByGVK is set.
+	Scheme *runtime.Scheme
+
 	// startWatches maintains a list of sources, handlers, and predicates to start when the controller is started.
 	startWatches []watchDescription
 
+	// resyncWatches are the started watches whose source can replay its objects, for TriggerResync.
+	resyncWatches []watchDescription
+
+	// watchManifests describe all the watches of the controller, for the manifest of the manager.
+	watchManifests   []manager.WatchManifest
+	watchManifestsMu sync.Mutex
+
 	// Log is used to log messages to users during reconciliation, or for example when a watch is started.
 	Log logr.Logger
 
 	// RecoverPanic indicates whether the panic caused by reconcile should be recovered.
 	RecoverPanic bool
+
+	// PrioritizeDeletes indicates whether the requests enqueued for Delete events should
+	// be added with priority.  It has no effect unless the queue built by MakeQueue
+	// supports priorities, see NewPriorityRateLimitingQueue and NewFairRateLimitingQueue.
+	PrioritizeDeletes bool
+
+	// DeprioritizeInitialSync indicates whether the requests enqueued for the Create events
+	// of the objects which existed before their logical cluster was first seen, i.e. the
+	// initial lists of newly added clusters, should be added with low priority.  It has no
+	// effect unless the queue built by MakeQueue supports priorities.
+	DeprioritizeInitialSync bool
+
+	// clustersSeen records when the logical clusters were first seen by the watches, if
+	// DeprioritizeInitialSync is set.
+	clustersSeen *clustersSeen
+
+	// TombstoneDeletes indicates whether the requests enqueued for Delete events should
+	// be tombstones, see reconcile.Request.Tombstone.
+	TombstoneDeletes bool
+
+	// WaitForCacheConsistency makes the controller wait, before reconciling a request, until all
+	// the sources which expose the resourceVersion of their informer have observed the highest
+	// resourceVersion any of them had observed when the request was enqueued.
+	WaitForCacheConsistency bool
+
+	// CacheConsistencyTimeout is the longest time to wait for the sources to catch up with a
+	// request, after which it is reconciled anyway.  Defaults to 10 seconds if not set.
+	CacheConsistencyTimeout time.Duration
+
+	// Dependencies are the runnables which the manager must start before the controller,
+	// and stop after it.
+	Dependencies []manager.Runnable
+
+	// Config holds the configuration passed to the Reconciler in the context of each request.
+	Config *reconcile.Config
+
+	// QueueHandover, if set, saves the requests pending in the queue once the workers are
+	// stopped, and adds the requests it saved before back to the queue when the controller
+	// starts, so that they survive leader transitions.
+	QueueHandover leaderelection.QueueHandover
+
+	// RateLimiter is the rate limiter of the queue built by MakeQueue, if known.  It is used
+	// to record when the requests requeued with a backoff are due, so that QueueHandover
+	// hands their backoff over too, and DumpQueue tells when they are retried.
+	RateLimiter workqueue.RateLimiter
+
+	// AuditSink, if set, receives a record of every reconcile.
+	AuditSink audit.Sink
+
+	// ObjectLocks, if set, are locked for the object of each request while it is reconciled.
+	ObjectLocks *objectlock.Locks
+
+	// GroupKind is the kind of the reconciled objects in ObjectLocks, for the requests which
+	// don't carry their GroupVersionKind.
+	GroupKind schema.GroupKind
+
+	// WorkerPool, if set, is waited for a worker before each reconcile, to bound the reconciles
+	// running concurrently across the controllers sharing it.
+	WorkerPool *workerpool.Pool
+
+	// WorkerPoolWeight is the weight of the controller in WorkerPool.
+	WorkerPoolWeight int
+
+	// Tracer, if set, starts a span around every reconcile.
+	Tracer trace.Tracer
 }
 
 // watchDescription contains all the information necessary to start a watch.
@@ -99,19 +250,48 @@ type watchDescription struct {
 
 // Reconcile implements reconcile.Reconciler.
 func (c *Controller) Reconcile(ctx context.Context, req reconcile.Request) (_ reconcile.Result, err error) {
+	reconcileID := tracing.ReconcileIDFrom(ctx)
+	if reconcileID == "" {
+		reconcileID = string(uuid.NewUUID())
+		ctx = tracing.WithReconcileID(ctx, reconcileID)
+	}
+	if c.Tracer != nil {
+		var span trace.Span
+		ctx, span = c.Tracer.Start(ctx, "Reconcile", trace.WithAttributes(c.spanAttributes(req, reconcileID)...))
+		defer func() { tracing.End(span, err) }()
+	}
 	if c.RecoverPanic {
 		defer func() {
 			if r := recover(); r != nil {
 				for _, fn := range utilruntime.PanicHandlers {
 					fn(r)
 				}
-				err = fmt.Errorf("panic: %v [recovered]", r)
+				err = &reconcilePanic{value: r}
 			}
 		}()
 	}
-	log := c.Log.WithValues("name", req.Name, "namespace", req.Namespace)
+	log := c.requestLogger(req, reconcileID)
+	if !req.Cluster.Empty() {
+		ctx = kcp.WithCluster(ctx, req.Cluster)
+	}
 	ctx = logf.IntoContext(ctx, log)
-	return c.Do.Reconcile(ctx, req)
+	if c.Config != nil {
+		ctx = reconcile.WithConfig(ctx, c.Config.Get())
+	}
+	ctx, deferrals := reconcile.WithDeferrals(ctx)
+	result, err := c.Do.Reconcile(ctx, req)
+	steps := deferrals.List()
+	if len(steps) == 0 {
+		return result, err
+	}
+	reasons := make([]string, 0, len(steps))
+	for _, step := range steps {
+		ctrlmetrics.ReconcileDeferred.WithLabelValues(c.Name, step.Reason).Inc()
+		reasons = append(reasons, step.Reason)
+	}
+	result = deferrals.Merge(result)
+	log.V(1).Info("Deferred reconcile steps", "reasons", reasons, "requeueAfter", result.RequeueAfter)
+	return result, err
 }
 
 // Watch implements controller.Controller.
@@ -132,16 +312,39 @@ func (c *Controller) Watch(src source.Source, evthdler handler.EventHandler, prc
 		}
 	}
 
+	c.recordWatchManifest(src)
+
 	// Controller hasn't started yet, store the watches locally and return.
 	//
 	// These watches are going to be held on the controller struct until the manager or user calls Start(...).
+	if c.TombstoneDeletes {
+		evthdler = &tombstoneHandler{EventHandler: evthdler}
+	}
+	if c.PrioritizeDeletes {
+		evthdler = &deletePriorityHandler{EventHandler: evthdler}
+	}
+	if c.Suspensions != nil {
+		evthdler = &suspendedHandler{EventHandler: evthdler, suspensions: c.Suspensions}
+	}
+	if c.DeprioritizeInitialSync {
+		if c.clustersSeen == nil {
+			c.clustersSeen = newClustersSeen()
+		}
+		evthdler = &initialSyncHandler{EventHandler: evthdler, clusters: c.clustersSeen}
+	}
+
 	if !c.Started {
 		c.startWatches = append(c.startWatches, watchDescription{src: src, handler: evthdler, predicates: prct})
 		return nil
 	}
 
 	c.Log.Info("Starting EventSource", "source", src)
-	return src.Start(c.ctx, evthdler, c.Queue, prct...)
+	c.trackResourceVersion(src)
+	if err := src.Start(c.ctx, evthdler, c.Queue, prct...); err != nil {
+		return err
+	}
+	c.trackResync(watchDescription{src: src, handler: evthdler, predicates: prct})
+	return nil
 }
 
 // Start implements controller.Controller.
@@ -158,9 +361,38 @@ func (c *Controller) Start(ctx context.Context) error {
 	// Set the internal context.
 	c.ctx = ctx
 
-	c.Queue = c.MakeQueue()
+	c.enqueueTimes = newEnqueueTimesQueue(c.MakeQueue(), c.Name)
+	c.enqueueTimes.rateLimiter = c.RateLimiter
+	if c.AuditSink != nil {
+		c.enqueueTimes.trackTriggers()
+	}
+	c.Queue = c.enqueueTimes
+	if c.WaitForCacheConsistency {
+		c.consistency = newConsistencyQueue(c.enqueueTimes)
+		c.Queue = c.consistency
+	}
+	if c.MaxConcurrentReconcilesPerCluster > 0 || len(c.MaxConcurrentReconcilesByCluster) > 0 {
+		c.clusterLimiter = newClusterLimiter(c.MaxConcurrentReconcilesPerCluster, c.MaxConcurrentReconcilesByCluster)
+	}
+	if c.SyncGate != nil {
+		c.heldRequests = newHeldRequests()
+	}
+	if c.Suspensions != nil {
+		c.Suspensions.AddResyncer(c)
+	}
+	if c.ClusterSet != nil {
+		c.removedClusters = newRemovedClusters()
+		c.enqueueTimes.onClusterDrained = c.removedClusters.remove
+		c.ClusterSet.AddHandler(c)
+	}
+	if c.WorkerPool != nil {
+		c.WorkerPool.SetWeight(c.Name, c.WorkerPoolWeight)
+	}
 	go func() {
 		<-ctx.Done()
+		if c.ClusterSet != nil {
+			c.ClusterSet.RemoveHandler(c)
+		}
 		c.Queue.ShutDown()
 	}()
 
@@ -176,10 +408,12 @@ func (c *Controller) Start(ctx context.Context) error {
 		// caches.
 		for _, watch := range c.startWatches {
 			c.Log.Info("Starting EventSource", "source", fmt.Sprintf("%s", watch.src))
+			c.trackResourceVersion(watch.src)
 
 			if err := watch.src.Start(ctx, watch.handler, c.Queue, watch.predicates...); err != nil {
 				return err
 			}
+			c.trackResync(watch)
 		}
 
 		// Start the SharedIndexInformer factories to begin populating the SharedIndexInformer caches
@@ -193,7 +427,7 @@ func (c *Controller) Start(ctx context.Context) error {
 
 			if err := func() error {
 				// use a context with timeout for launching sources and syncing caches.
-				sourceStartCtx, cancel := context.WithTimeout(ctx, c.CacheSyncTimeout)
+				sourceStartCtx, cancel := context.WithTimeout(ctx, c.cacheSyncTimeoutFor(watch.src))
 				defer cancel()
 
 				// WaitForSync waits for a definitive timeout, and returns if there
@@ -216,6 +450,8 @@ func (c *Controller) Start(ctx context.Context) error {
 		// which won't be garbage collected if we hold a reference to it.
 		c.startWatches = nil
 
+		c.takeHandedOverQueue(ctx)
+
 		// Launch workers to process resources
 		c.Log.Info("Starting workers", "worker count", c.MaxConcurrentReconciles)
 		wg.Add(c.MaxConcurrentReconciles)
@@ -224,6 +460,11 @@ func (c *Controller) Start(ctx context.Context) error {
 				defer wg.Done()
 				// Run a worker thread that just dequeues items, processes them, and marks them done.
 				// It enforces that the reconcileHandler is never invoked concurrently with the same object.
+				if c.BatchDo != nil {
+					for c.processNextBatch(ctx) {
+					}
+					return
+				}
 				for c.processNextWorkItem(ctx) {
 				}
 			}()
@@ -240,9 +481,142 @@ func (c *Controller) Start(ctx context.Context) error {
 	c.Log.Info("Shutdown signal received, waiting for all workers to finish")
 	wg.Wait()
 	c.Log.Info("All workers finished")
+	c.handOverQueue()
 	return nil
 }
 
+// takeHandedOverQueue adds the requests saved by the QueueHandover back to the queue, after
+// the backoff they had left.
+func (c *Controller) takeHandedOverQueue(ctx context.Context) {
+	if c.QueueHandover == nil {
+		return
+	}
+	reqs, err := c.QueueHandover.Take(ctx, c.Name)
+	if err != nil {
+		c.Log.Error(err, "Failed to take the requests handed over by the previous leader")
+		return
+	}
+	now := time.Now()
+	for _, req := range reqs {
+		if delay := req.ReadyAt.Sub(now); delay > 0 {
+			c.Queue.AddAfter(req.Request, delay)
+			continue
+		}
+		c.Queue.Add(req.Request)
+	}
+	if len(reqs) > 0 {
+		c.Log.Info("Took the requests handed over by the previous leader", "count", len(reqs))
+	}
+}
+
+// queueHandoverTimeout bounds the time spent saving the pending requests on shutdown.
+const queueHandoverTimeout = 10 * time.Second
+
+// handOverQueue saves the requests pending in the queue with the QueueHandover.  The context
+// of the controller is done by then, so it uses its own.
+func (c *Controller) handOverQueue() {
+	if c.QueueHandover == nil {
+		return
+	}
+	items, readyAt := c.enqueueTimes.pending()
+	reqs := make([]leaderelection.PendingRequest, 0, len(items))
+	for i, item := range items {
+		if req, ok := item.(reconcile.Request); ok {
+			reqs = append(reqs, leaderelection.PendingRequest{Request: req, ReadyAt: readyAt[i]})
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), queueHandoverTimeout)
+	defer cancel()
+	if err := c.QueueHandover.Save(ctx, c.Name, reqs); err != nil {
+		c.Log.Error(err, "Failed to hand the pending requests over to the next leader", "count", len(reqs))
+		return
+	}
+	c.Log.Info("Handed the pending requests over to the next leader", "count", len(reqs))
+}
+
+// DumpQueue implements manager.QueueDumper.  The requests being reconciled are included.
+func (c *Controller) DumpQueue() manager.ControllerQueue {
+	queue := manager.ControllerQueue{Name: c.Name, Clusters: []manager.ClusterQueue{}}
+	c.mu.Lock()
+	started := c.Started
+	c.mu.Unlock()
+	if !started {
+		return queue
+	}
+
+	items, readyAt := c.enqueueTimes.pending()
+	byCluster := map[logicalcluster.Name]*manager.ClusterQueue{}
+	var clusters []logicalcluster.Name
+	for i, item := range items {
+		req, ok := item.(reconcile.Request)
+		if !ok {
+			continue
+		}
+		cq, ok := byCluster[req.Cluster]
+		if !ok {
+			cq = &manager.ClusterQueue{Cluster: req.Cluster.String()}
+			byCluster[req.Cluster] = cq
+			clusters = append(clusters, req.Cluster)
+		}
+		queued := manager.QueuedRequest{Key: req.Key(), Retries: c.Queue.NumRequeues(item)}
+		queued.FirstEnqueued, _ = c.enqueueTimes.firstAddedTime(item)
+		if !readyAt[i].IsZero() {
+			queued.NextRetry = &readyAt[i]
+		}
+		cq.Retries += queued.Retries
+		cq.Requests = append(cq.Requests, queued)
+	}
+	for _, cluster := range clusters {
+		queue.Clusters = append(queue.Clusters, *byCluster[cluster])
+	}
+	manager.SortClusterQueues(queue.Clusters)
+	return queue
+}
+
+// DescribeController implements manager.ControllerDescriber.
+func (c *Controller) DescribeController() manager.ControllerManifest {
+	c.watchManifestsMu.Lock()
+	defer c.watchManifestsMu.Unlock()
+	return manager.ControllerManifest{
+		Name:                    c.Name,
+		MaxConcurrentReconciles: c.MaxConcurrentReconciles,
+		Watches:                 append([]manager.WatchManifest(nil), c.watchManifests...),
+	}
+}
+
+// recordWatchManifest records the description of the watched source for DescribeController.
+func (c *Controller) recordWatchManifest(src source.Source) {
+	watch := manager.WatchManifest{Source: fmt.Sprintf("%T", src)}
+	if stringer, ok := src.(fmt.Stringer); ok {
+		watch.Source = stringer.String()
+	}
+	if kind, ok := src.(*source.Kind); ok && kind.Type != nil && c.Scheme != nil {
+		if gvk, err := apiutil.GVKForObject(kind.Type, c.Scheme); err == nil {
+			watch.GroupVersionKind = gvk.String()
+		}
+	}
+
+	c.watchManifestsMu.Lock()
+	defer c.watchManifestsMu.Unlock()
+	c.watchManifests = append(c.watchManifests, watch)
+}
+
+// cacheSyncTimeoutFor returns the time limit set on waiting for the given source to sync.
+func (c *Controller) cacheSyncTimeoutFor(src source.Source) time.Duration {
+	kind, ok := src.(*source.Kind)
+	if !ok || kind.Type == nil || len(c.CacheSyncTimeoutByGVK) == 0 || c.Scheme == nil {
+		return c.CacheSyncTimeout
+	}
+	gvk, err := apiutil.GVKForObject(kind.Type, c.Scheme)
+	if err != nil {
+		return c.CacheSyncTimeout
+	}
+	if timeout, ok := c.CacheSyncTimeoutByGVK[gvk]; ok && timeout > 0 {
+		return timeout
+	}
+	return c.CacheSyncTimeout
+}
+
 // processNextWorkItem will read a single work item off the workqueue and
 // attempt to process it, by calling the reconcileHandler.
 func (c *Controller) processNextWorkItem(ctx context.Context) bool {
@@ -252,6 +626,51 @@ func (c *Controller) processNextWorkItem(ctx context.Context) bool {
 		return false
 	}
 
+	// Drop the requests of the logical clusters removed from the ClusterSet.
+	if c.dropIfRemoved(obj) {
+		c.Queue.Done(obj)
+		return true
+	}
+
+	// If the cache of the logical cluster of the request is not synced, hold the
+	// request: it is added back to the queue once the cache is synced.
+	if req, ok := obj.(reconcile.Request); ok && c.SyncGate != nil && !req.Cluster.Empty() {
+		if synced := c.SyncGate.Synced(req.Cluster); synced != nil {
+			if c.heldRequests.hold(ctx, c.Queue, req.Cluster, synced, obj) {
+				c.Log.V(1).Info("Holding the requests of the cluster until its cache is synced", "cluster", req.Cluster.String())
+			}
+			c.Queue.Done(obj)
+			return true
+		}
+	}
+
+	// If the logical cluster of the request already has as many reconciles in
+	// flight as allowed, park the request: it is added back to the queue once
+	// one of them finishes.
+	if req, ok := obj.(reconcile.Request); ok && c.clusterLimiter != nil {
+		if !c.clusterLimiter.tryAcquire(req.Cluster, obj) {
+			c.Queue.Done(obj)
+			return true
+		}
+		defer func() {
+			if next, ok := c.clusterLimiter.release(req.Cluster); ok {
+				c.Queue.Add(next)
+			}
+		}()
+	}
+
+	// If the controller shares a worker pool, wait for a worker of the pool.  The
+	// request isn't forgotten if the controller stops meanwhile, so that it is
+	// handed over with the requests pending in the queue.
+	if c.WorkerPool != nil {
+		release, err := c.WorkerPool.Acquire(ctx, c.Name)
+		if err != nil {
+			c.Queue.Done(obj)
+			return false
+		}
+		defer release()
+	}
+
 	// We call Done here so the workqueue knows we have finished
 	// processing this item. We also must remember to call Forget if we
 	// do not want this work item being re-queued. For example, we do
@@ -287,12 +706,12 @@ func (c *Controller) initMetrics() {
 func (c *Controller) reconcileHandler(ctx context.Context, obj interface{}) {
 	// Update metrics after processing each item
 	reconcileStartTS := time.Now()
-	defer func() {
-		c.updateMetrics(time.Since(reconcileStartTS))
-	}()
 
 	// Make sure that the the object is a valid request.
 	req, ok := obj.(reconcile.Request)
+	defer func() {
+		c.updateMetrics(req.Cluster, time.Since(reconcileStartTS))
+	}()
 	if !ok {
 		// As the item in the workqueue is actually invalid, we call
 		// Forget here else we'd go into a loop of attempting to
@@ -303,17 +722,40 @@ func (c *Controller) reconcileHandler(ctx context.Context, obj interface{}) {
 		return
 	}
 
-	log := c.Log.WithValues("name", req.Name, "namespace", req.Namespace)
+	reconcileID := string(uuid.NewUUID())
+	log := c.requestLogger(req, reconcileID)
 	ctx = logf.IntoContext(ctx, log)
+	ctx = tracing.WithReconcileID(ctx, reconcileID)
+	info := c.requestInfo(req)
+	var trigger string
+	if c.AuditSink != nil {
+		trigger, _ = c.enqueueTimes.takeTrigger(obj)
+	}
+	if c.consistency != nil {
+		if token, ok := c.consistency.takeToken(obj); ok {
+			info.CacheResourceVersion = strconv.FormatUint(token, 10)
+			if !c.consistency.waitFor(ctx, token, c.CacheConsistencyTimeout) {
+				log.V(1).Info("Cache did not catch up with the request, reconciling anyway", "resourceVersion", info.CacheResourceVersion)
+			}
+		}
+	}
+	ctx = reconcile.WithRequestInfo(ctx, info)
 
 	// RunInformersAndControllers the syncHandler, passing it the Namespace/Name string of the
 	// resource to be synced.
-	result, err := c.Reconcile(ctx, req)
+	reconcileStart := time.Now()
+	result, err := c.lockAndReconcile(ctx, req)
+	duration := time.Since(reconcileStart)
+	var label string
 	switch {
+	case err != nil && c.fromRemovedCluster(req):
+		// The cluster was removed while reconciling, don't retry.
+		c.Queue.Forget(obj)
+		label = labelError
+		log.V(1).Info("Reconciler error in a removed cluster", "error", err.Error())
 	case err != nil:
 		c.Queue.AddRateLimited(req)
-		ctrlmetrics.ReconcileErrors.WithLabelValues(c.Name).Inc()
-		ctrlmetrics.ReconcileTotal.WithLabelValues(c.Name, labelError).Inc()
+		label = labelError
 		log.Error(err, "Reconciler error")
 	case result.RequeueAfter > 0:
 		// The result.RequeueAfter request will be lost, if it is returned
@@ -322,15 +764,192 @@ func (c *Controller) reconcileHandler(ctx context.Context, obj interface{}) {
 		// to result.RequestAfter
 		c.Queue.Forget(obj)
 		c.Queue.AddAfter(req, result.RequeueAfter)
-		ctrlmetrics.ReconcileTotal.WithLabelValues(c.Name, labelRequeueAfter).Inc()
+		label = labelRequeueAfter
 	case result.Requeue:
 		c.Queue.AddRateLimited(req)
-		ctrlmetrics.ReconcileTotal.WithLabelValues(c.Name, labelRequeue).Inc()
+		label = labelRequeue
 	default:
 		// Finally, if no error occurs we Forget this item so it does not
 		// get queued again until another change happens.
 		c.Queue.Forget(obj)
-		ctrlmetrics.ReconcileTotal.WithLabelValues(c.Name, labelSuccess).Inc()
+		label = labelSuccess
+	}
+	c.recordResult(req.Cluster, label)
+
+	if c.AuditSink != nil {
+		if req.IsTombstone() {
+			trigger = audit.TriggerDelete
+		}
+		rec := audit.Record{
+			Time:       reconcileStart,
+			Controller: c.Name,
+			Key:        req.Key(),
+			Cluster:    req.Cluster.String(),
+			Trigger:    trigger,
+			Retries:    info.Retries,
+			Duration:   duration,
+			Result:     label,
+			ErrorClass: errorClass(err),
+		}
+		if label == labelRequeueAfter {
+			rec.RequeueAfter = result.RequeueAfter
+		}
+		if err != nil {
+			rec.Error = err.Error()
+		}
+		if err := c.AuditSink.Write(rec); err != nil {
+			log.Error(err, "Failed to write audit record")
+		}
+	}
+}
+
+// lockAndReconcile reconciles the request holding the lock of its object in ObjectLocks, if
+// set.  The reconciler can lock other objects with the Owner passed in the context, which
+// releases them all once it returns.
+func (c *Controller) lockAndReconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	if c.ObjectLocks == nil {
+		return c.Reconcile(ctx, req)
+	}
+	gk := req.GroupVersionKind.GroupKind()
+	if gk.Empty() {
+		gk = c.GroupKind
+	}
+	if gk.Empty() {
+		return c.Reconcile(ctx, req)
+	}
+
+	owner := c.ObjectLocks.NewOwner(c.Name)
+	defer owner.UnlockAll()
+	if err := owner.Lock(ctx, objectlock.KeyFor(gk, req.ObjectKey)); err != nil {
+		return reconcile.Result{}, fmt.Errorf("failed to lock %s %s: %w", gk, req.ObjectKey, err)
+	}
+	return c.Reconcile(objectlock.WithOwner(ctx, owner), req)
+}
+
+// reconcilePanic is the error returned by Reconcile for a recovered panic of the Reconciler.
+type reconcilePanic struct {
+	value interface{}
+}
+
+func (p *reconcilePanic) Error() string {
+	return fmt.Sprintf("panic: %v [recovered]", p.value)
+}
+
+// errorClass returns the class of an error returned by Reconcile for the audit records.
+func errorClass(err error) string {
+	var p *reconcilePanic
+	if errors.As(err, &p) {
+		return audit.ErrorClassPanic
+	}
+	return audit.ErrorClass(err)
+}
+
+// requestLogger returns the logger of the Controller with the fields of the Request which are set,
+// and the reconcileID telling apart the log lines of the successive reconciliations of the Request.
+func (c *Controller) requestLogger(req reconcile.Request, reconcileID string) logr.Logger {
+	log := c.Log.WithValues("controller", c.Name, "reconcileID", reconcileID, "name", req.Name, "namespace", req.Namespace)
+	if !req.Cluster.Empty() {
+		log = log.WithValues("cluster", req.Cluster.String())
+	}
+	if !req.GroupVersionKind.Empty() {
+		log = log.WithValues("gvk", req.GroupVersionKind.String())
+	}
+	if req.Shard != "" {
+		log = log.WithValues("shard", req.Shard)
+	}
+	if req.Extra != "" {
+		log = log.WithValues("extra", req.Extra)
+	}
+	return log
+}
+
+// spanAttributes returns the attributes of the span of the reconcile of a Request.
+func (c *Controller) spanAttributes(req reconcile.Request, reconcileID string) []attribute.KeyValue {
+	attributes := []attribute.KeyValue{
+		tracing.AttributeController.String(c.Name),
+		tracing.AttributeReconcileID.String(reconcileID),
+		tracing.AttributeNamespace.String(req.Namespace),
+		tracing.AttributeName.String(req.Name),
+	}
+	if !req.Cluster.Empty() {
+		attributes = append(attributes, tracing.AttributeCluster.String(req.Cluster.String()))
+	}
+	switch {
+	case !req.GroupVersionKind.Empty():
+		attributes = append(attributes, tracing.AttributeGroupVersionKind.String(req.GroupVersionKind.String()))
+	case !c.GroupKind.Empty():
+		attributes = append(attributes, tracing.AttributeGroupVersionKind.String(c.GroupKind.String()))
+	}
+	return attributes
+}
+
+// requestInfo returns how many times the request was retried and when it was first enqueued.
+func (c *Controller) requestInfo(req reconcile.Request) reconcile.RequestInfo {
+	info := reconcile.RequestInfo{Retries: c.Queue.NumRequeues(req)}
+	if c.enqueueTimes != nil {
+		info.FirstEnqueued, _ = c.enqueueTimes.firstAddedTime(req)
+	}
+	return info
+}
+
+// trackResourceVersion makes the consistency queue track the resourceVersion of the source, if
+// it exposes it.
+func (c *Controller) trackResourceVersion(src source.Source) {
+	if c.consistency == nil {
+		return
+	}
+	if rvSource, ok := src.(resourceVersionSource); ok {
+		c.consistency.addSource(rvSource)
+	}
+}
+
+// DependsOn implements manager.DependentRunnable.
+func (c *Controller) DependsOn() []manager.Runnable {
+	return c.Dependencies
+}
+
+// TriggerResync implements controller.Controller.
+func (c *Controller) TriggerResync() error {
+	return c.resync(logicalcluster.Name{})
+}
+
+// TriggerClusterResync implements controller.Controller.
+func (c *Controller) TriggerClusterResync(cluster logicalcluster.Name) error {
+	return c.resync(cluster)
+}
+
+// resync replays the objects of the watches of the given logical cluster, or of all the
+// clusters if empty.
+func (c *Controller) resync(cluster logicalcluster.Name) error {
+	c.mu.Lock()
+	started, queue, watches := c.Started, c.Queue, c.resyncWatches
+	c.mu.Unlock()
+	if !started {
+		// The controller reconciles all the objects when it starts anyway.
+		return nil
+	}
+
+	var filter []predicate.Predicate
+	if cluster.Empty() {
+		c.Log.Info("Resyncing all objects")
+	} else {
+		c.Log.Info("Resyncing the objects of a cluster", "cluster", cluster.String())
+		filter = []predicate.Predicate{predicate.InClusters(cluster)}
+	}
+	var errs []error
+	for _, watch := range watches {
+		predicates := append(filter[:len(filter):len(filter)], watch.predicates...)
+		if err := watch.src.(source.ResyncingSource).Resync(watch.handler, queue, predicates...); err != nil {
+			errs = append(errs, fmt.Errorf("failed to resync %s: %w", watch.src, err))
+		}
+	}
+	return kerrors.NewAggregate(errs)
+}
+
+// trackResync records the watch for TriggerResync if its source can replay its objects.
+func (c *Controller) trackResync(watch watchDescription) {
+	if _, ok := watch.src.(source.ResyncingSource); ok {
+		c.resyncWatches = append(c.resyncWatches, watch)
 	}
 }
 
@@ -346,6 +965,25 @@ func (c *Controller) InjectFunc(f inject.Func) error {
 }
 
 // updateMetrics updates prometheus metrics within the controller.
-func (c *Controller) updateMetrics(reconcileTime time.Duration) {
+func (c *Controller) updateMetrics(cluster logicalcluster.Name, reconcileTime time.Duration) {
 	ctrlmetrics.ReconcileTime.WithLabelValues(c.Name).Observe(reconcileTime.Seconds())
+	if ctrlmetrics.ClusterLabelEnabled() {
+		ctrlmetrics.ReconcileTimeByCluster.WithLabelValues(c.Name, cluster.String()).Observe(reconcileTime.Seconds())
+	}
+}
+
+// recordResult counts a reconciliation of a request of the logical cluster with
+// the given result.
+func (c *Controller) recordResult(cluster logicalcluster.Name, result string) {
+	ctrlmetrics.ReconcileTotal.WithLabelValues(c.Name, result).Inc()
+	if result == labelError {
+		ctrlmetrics.ReconcileErrors.WithLabelValues(c.Name).Inc()
+	}
+	if !ctrlmetrics.ClusterLabelEnabled() {
+		return
+	}
+	ctrlmetrics.ReconcileTotalByCluster.WithLabelValues(c.Name, cluster.String(), result).Inc()
+	if result == labelError {
+		ctrlmetrics.ReconcileErrorsByCluster.WithLabelValues(c.Name, cluster.String()).Inc()
+	}
 }
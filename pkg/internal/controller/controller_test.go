@@ -23,27 +23,39 @@ import (
 	"sync"
 	"time"
 
+	"github.com/go-logr/logr/funcr"
+	"github.com/kcp-dev/logicalcluster"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/prometheus/client_golang/prometheus"
 	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/oteltest"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllertest"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/internal/controller/metrics"
 	"sigs.k8s.io/controller-runtime/pkg/internal/log"
+	"sigs.k8s.io/controller-runtime/pkg/kcp"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
 	"sigs.k8s.io/controller-runtime/pkg/source"
+	"sigs.k8s.io/controller-runtime/pkg/tracing"
 )
 
 var _ = Describe("controller", func() {
@@ -52,9 +64,9 @@ var _ = Describe("controller", func() {
 	var queue *controllertest.Queue
 	var informers *informertest.FakeInformers
 	var reconciled chan reconcile.Request
-	var request = reconcile.Request{
+	var request = reconcile.Request{ObjectKey: client.ObjectKey{
 		NamespacedName: types.NamespacedName{Namespace: "foo", Name: "bar"},
-	}
+	}}
 
 	BeforeEach(func() {
 		reconciled = make(chan reconcile.Request)
@@ -84,11 +96,112 @@ var _ = Describe("controller", func() {
 				return reconcile.Result{Requeue: true}, nil
 			})
 			result, err := ctrl.Reconcile(ctx,
-				reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "foo", Name: "bar"}})
+				reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "foo", Name: "bar"}}})
 			Expect(err).NotTo(HaveOccurred())
 			Expect(result).To(Equal(reconcile.Result{Requeue: true}))
 		})
 
+		It("should pass the cluster of the request in the context", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var cluster logicalcluster.Name
+			ctrl.Do = reconcile.Func(func(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+				cluster, _ = kcp.ClusterFrom(ctx)
+				return reconcile.Result{}, nil
+			})
+			_, err := ctrl.Reconcile(ctx, reconcile.Request{ObjectKey: client.ObjectKey{
+				NamespacedName: types.NamespacedName{Namespace: "foo", Name: "bar"},
+				Cluster:        logicalcluster.New("root:org:ws"),
+			}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cluster).To(Equal(logicalcluster.New("root:org:ws")))
+		})
+
+		It("should start a span around the reconcile and pass its reconcileID in the context", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			sr := new(oteltest.SpanRecorder)
+			provider := oteltest.NewTracerProvider(oteltest.WithSpanRecorder(sr))
+			ctrl.Name = "trace-test"
+			ctrl.Tracer = provider.Tracer("test")
+			var reconcileID string
+			ctrl.Do = reconcile.Func(func(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+				reconcileID = tracing.ReconcileIDFrom(ctx)
+				_, span := provider.Tracer("client").Start(ctx, "client.Get")
+				span.End()
+				return reconcile.Result{}, errors.New("failed")
+			})
+			_, err := ctrl.Reconcile(ctx, reconcile.Request{
+				ObjectKey: client.ObjectKey{
+					NamespacedName: types.NamespacedName{Namespace: "foo", Name: "bar"},
+					Cluster:        logicalcluster.New("root:org:ws"),
+				},
+				GroupVersionKind: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+			})
+			Expect(err).To(MatchError("failed"))
+			Expect(reconcileID).NotTo(BeEmpty())
+
+			spans := sr.Completed()
+			Expect(spans).To(HaveLen(2))
+			Expect(spans[1].Name()).To(Equal("Reconcile"))
+			Expect(spans[1].Attributes()).To(Equal(map[attribute.Key]attribute.Value{
+				tracing.AttributeController:       attribute.StringValue("trace-test"),
+				tracing.AttributeReconcileID:      attribute.StringValue(reconcileID),
+				tracing.AttributeCluster:          attribute.StringValue("root:org:ws"),
+				tracing.AttributeGroupVersionKind: attribute.StringValue("apps/v1, Kind=Deployment"),
+				tracing.AttributeNamespace:        attribute.StringValue("foo"),
+				tracing.AttributeName:             attribute.StringValue("bar"),
+			}))
+			Expect(spans[1].StatusCode()).To(Equal(codes.Error))
+			Expect(spans[1].StatusMessage()).To(Equal("failed"))
+			Expect(spans[0].ParentSpanID()).To(Equal(spans[1].SpanContext().SpanID()))
+		})
+
+		It("should pass the current value of the configuration in the context", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var values []interface{}
+			ctrl.Config = reconcile.NewConfig("initial")
+			ctrl.Do = reconcile.Func(func(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+				value, ok := reconcile.ConfigFrom(ctx)
+				Expect(ok).To(BeTrue())
+				values = append(values, value)
+				return reconcile.Result{}, nil
+			})
+			_, err := ctrl.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			ctrl.Config.Set("reloaded")
+			_, err = ctrl.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(values).To(Equal([]interface{}{"initial", "reloaded"}))
+		})
+
+		It("should merge the deferred steps into the result and count them per reason", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			ctrl.Name = "defer-test"
+			ctrl.Do = reconcile.Func(func(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+				reconcile.Defer(ctx, time.Minute, "WaitingForQuota")
+				reconcile.Defer(ctx, 10*time.Second, "WaitingForAPIBinding")
+				reconcile.Defer(ctx, 2*time.Minute, "WaitingForQuota")
+				return reconcile.Result{RequeueAfter: time.Hour}, nil
+			})
+			result, err := ctrl.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(reconcile.Result{RequeueAfter: 10 * time.Second}))
+
+			var metric dto.Metric
+			Expect(ctrlmetrics.ReconcileDeferred.WithLabelValues("defer-test", "WaitingForQuota").Write(&metric)).To(Succeed())
+			Expect(metric.GetCounter().GetValue()).To(Equal(2.0))
+			Expect(ctrlmetrics.ReconcileDeferred.WithLabelValues("defer-test", "WaitingForAPIBinding").Write(&metric)).To(Succeed())
+			Expect(metric.GetCounter().GetValue()).To(Equal(1.0))
+		})
+
 		It("should not recover panic if RecoverPanic is false by default", func() {
 			ctx, cancel := context.WithCancel(context.Background())
 			defer cancel()
@@ -101,7 +214,7 @@ var _ = Describe("controller", func() {
 				return *res, nil
 			})
 			_, _ = ctrl.Reconcile(ctx,
-				reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "foo", Name: "bar"}})
+				reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "foo", Name: "bar"}}})
 		})
 
 		It("should recover panic if RecoverPanic is true", func() {
@@ -117,7 +230,7 @@ var _ = Describe("controller", func() {
 				return *res, nil
 			})
 			_, err := ctrl.Reconcile(ctx,
-				reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "foo", Name: "bar"}})
+				reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "foo", Name: "bar"}}})
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("[recovered]"))
 		})
@@ -154,6 +267,27 @@ var _ = Describe("controller", func() {
 			Expect(err.Error()).To(ContainSubstring("failed to wait for testcontroller caches to sync: timed out waiting for cache to be synced"))
 		})
 
+		It("should error when the cache sync timeout for the GVK of a Kind source occurs", func() {
+			ctrl.CacheSyncTimeout = 1 * time.Minute
+			ctrl.CacheSyncTimeoutByGVK = map[schema.GroupVersionKind]time.Duration{
+				appsv1.SchemeGroupVersion.WithKind("Deployment"): 10 * time.Nanosecond,
+			}
+			ctrl.Scheme = scheme.Scheme
+
+			c, err := cache.New(cfg, cache.Options{})
+			Expect(err).NotTo(HaveOccurred())
+			c = &cacheWithIndefinitelyBlockingGetInformer{c}
+
+			src := &source.Kind{Type: &appsv1.Deployment{}}
+			Expect(src.InjectCache(c)).To(Succeed())
+			ctrl.startWatches = []watchDescription{{src: src}}
+			ctrl.Name = "testcontroller"
+
+			err = ctrl.Start(context.TODO())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to wait for testcontroller caches to sync: timed out waiting for cache to be synced"))
+		})
+
 		It("should not error when cache sync timeout is of sufficiently high", func() {
 			ctrl.CacheSyncTimeout = 1 * time.Second
 
@@ -426,6 +560,84 @@ var _ = Describe("controller", func() {
 		})
 	})
 
+	Describe("TriggerResync", func() {
+		It("should do nothing if the controller isn't started", func() {
+			Expect(ctrl.TriggerResync()).To(Succeed())
+		})
+
+		It("should enqueue the objects of the Kind sources again", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			reqs := make(chan reconcile.Request, 10)
+			ctrl.CacheSyncTimeout = 10 * time.Second
+			ctrl.Do = reconcile.Func(func(_ context.Context, req reconcile.Request) (reconcile.Result, error) {
+				reqs <- req
+				return reconcile.Result{}, nil
+			})
+			Expect(ctrl.Watch(source.NewKindWithCache(&corev1.Pod{}, informers), &handler.EnqueueRequestForObject{})).To(Succeed())
+			go func() {
+				defer GinkgoRecover()
+				Expect(ctrl.Start(ctx)).To(Succeed())
+			}()
+			Eventually(func() bool {
+				ctrl.mu.Lock()
+				defer ctrl.mu.Unlock()
+				return ctrl.Started
+			}).Should(BeTrue())
+
+			fakeInformer, err := informers.FakeInformerFor(&corev1.Pod{})
+			Expect(err).NotTo(HaveOccurred())
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "bar", ClusterName: "root:org:ws"}}
+			fakeInformer.Add(pod)
+			expected := reconcile.Request{ObjectKey: client.ObjectKey{
+				NamespacedName: types.NamespacedName{Namespace: "foo", Name: "bar"},
+				Cluster:        logicalcluster.New("root:org:ws"),
+			}}
+			Eventually(reqs).Should(Receive(Equal(expected)))
+
+			Expect(ctrl.TriggerResync()).To(Succeed())
+			Eventually(reqs).Should(Receive(Equal(expected)))
+		})
+
+		It("should drop the events of the suspended clusters and resync their objects when resumed", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			reqs := make(chan reconcile.Request, 10)
+			ctrl.CacheSyncTimeout = 10 * time.Second
+			ctrl.Do = reconcile.Func(func(_ context.Context, req reconcile.Request) (reconcile.Result, error) {
+				reqs <- req
+				return reconcile.Result{}, nil
+			})
+			ctrl.Suspensions = cluster.NewSuspensions()
+			ctrl.SyncGate = ctrl.Suspensions
+			Expect(ctrl.Watch(source.NewKindWithCache(&corev1.Pod{}, informers), &handler.EnqueueRequestForObject{})).To(Succeed())
+			go func() {
+				defer GinkgoRecover()
+				Expect(ctrl.Start(ctx)).To(Succeed())
+			}()
+			Eventually(func() bool {
+				ctrl.mu.Lock()
+				defer ctrl.mu.Unlock()
+				return ctrl.Started
+			}).Should(BeTrue())
+
+			suspended, other := logicalcluster.New("root:org:ws"), logicalcluster.New("root:org:other")
+			ctrl.Suspensions.Suspend(suspended)
+			fakeInformer, err := informers.FakeInformerFor(&corev1.Pod{})
+			Expect(err).NotTo(HaveOccurred())
+			fakeInformer.Add(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "bar", ClusterName: suspended.String()}})
+			fakeInformer.Add(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "bar", ClusterName: other.String()}})
+			Eventually(reqs).Should(Receive(HaveField("Cluster", other)))
+			Consistently(reqs).ShouldNot(Receive())
+
+			Expect(ctrl.Suspensions.Resume(suspended)).To(Succeed())
+			Eventually(reqs).Should(Receive(HaveField("Cluster", suspended)))
+			Consistently(reqs).ShouldNot(Receive())
+		})
+	})
+
 	Describe("Processing queue items from a Controller", func() {
 		It("should call Reconciler if an item is enqueued", func() {
 			ctx, cancel := context.WithCancel(context.Background())
@@ -445,6 +657,153 @@ var _ = Describe("controller", func() {
 			Eventually(func() int { return queue.NumRequeues(request) }).Should(Equal(0))
 		})
 
+		It("should pass the retries and the first enqueue time of the request in the context", func() {
+			ctrl.enqueueTimes = newEnqueueTimesQueue(workqueue.NewRateLimitingQueue(
+				workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, time.Millisecond)), ctrl.Name)
+			ctrl.Queue = ctrl.enqueueTimes
+			defer ctrl.Queue.ShutDown()
+
+			var infos []reconcile.RequestInfo
+			ctrl.Do = reconcile.Func(func(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+				info, ok := reconcile.RequestInfoFrom(ctx)
+				Expect(ok).To(BeTrue())
+				infos = append(infos, info)
+				if len(infos) < 3 {
+					return reconcile.Result{}, fmt.Errorf("something's wrong")
+				}
+				return reconcile.Result{}, nil
+			})
+
+			before := time.Now()
+			ctrl.Queue.Add(request)
+			for i := 0; i < 3; i++ {
+				Expect(ctrl.processNextWorkItem(context.Background())).To(BeTrue())
+			}
+
+			Expect(infos).To(HaveLen(3))
+			Expect(infos[0].FirstEnqueued).To(BeTemporally(">=", before))
+			for i, info := range infos {
+				Expect(info.Retries).To(Equal(i))
+				Expect(info.FirstEnqueued).To(Equal(infos[0].FirstEnqueued))
+			}
+
+			By("Forgetting the first enqueue time once the request succeeded")
+			_, ok := ctrl.enqueueTimes.firstAddedTime(request)
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should log the cluster of the request in the reconcile context and in the reconciler errors", func() {
+			var lines []string
+			ctrl.Name = "test"
+			ctrl.Log = funcr.New(func(prefix, args string) {
+				lines = append(lines, args)
+			}, funcr.Options{})
+			ctrl.Queue = queue
+			ctrl.Do = reconcile.Func(func(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+				logf.FromContext(ctx).Info("reconciling")
+				return reconcile.Result{}, fmt.Errorf("something's wrong")
+			})
+
+			req := reconcile.Request{ObjectKey: client.ObjectKey{
+				Cluster:        logicalcluster.New("root:org:ws"),
+				NamespacedName: types.NamespacedName{Namespace: "foo", Name: "bar"},
+			}}
+			queue.Add(req)
+			Expect(ctrl.processNextWorkItem(context.Background())).To(BeTrue())
+
+			Expect(lines).To(HaveLen(2))
+			for _, line := range lines {
+				Expect(line).To(ContainSubstring(`"controller"="test"`))
+				Expect(line).To(ContainSubstring(`"reconcileID"=`))
+				Expect(line).To(ContainSubstring(`"cluster"="root:org:ws"`))
+			}
+			Expect(lines[1]).To(ContainSubstring(`"msg"="Reconciler error"`))
+		})
+
+		It("should wait for the sources to catch up with the request before reconciling it", func() {
+			primary := &fakeResourceVersionSource{rv: "10"}
+			owned := &fakeResourceVersionSource{rv: "15"}
+			ctrl.WaitForCacheConsistency = true
+			ctrl.CacheConsistencyTimeout = 10 * time.Second
+			ctrl.enqueueTimes = newEnqueueTimesQueue(queue, ctrl.Name)
+			ctrl.consistency = newConsistencyQueue(ctrl.enqueueTimes)
+			ctrl.Queue = ctrl.consistency
+			ctrl.consistency.addSource(primary)
+			ctrl.consistency.addSource(owned)
+			defer ctrl.Queue.ShutDown()
+
+			infos := make(chan reconcile.RequestInfo, 1)
+			ctrl.Do = reconcile.Func(func(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+				info, _ := reconcile.RequestInfoFrom(ctx)
+				infos <- info
+				return reconcile.Result{}, nil
+			})
+
+			ctrl.Queue.Add(request)
+			go func() {
+				defer GinkgoRecover()
+				Expect(ctrl.processNextWorkItem(context.Background())).To(BeTrue())
+			}()
+			Consistently(infos, 100*time.Millisecond).ShouldNot(Receive())
+
+			primary.set("16")
+			var info reconcile.RequestInfo
+			Eventually(infos).Should(Receive(&info))
+			Expect(info.CacheResourceVersion).To(Equal("15"))
+		})
+
+		It("should reconcile the request anyway once the cache consistency timeout expires", func() {
+			ctrl.WaitForCacheConsistency = true
+			ctrl.CacheConsistencyTimeout = 100 * time.Millisecond
+			ctrl.enqueueTimes = newEnqueueTimesQueue(queue, ctrl.Name)
+			ctrl.consistency = newConsistencyQueue(ctrl.enqueueTimes)
+			ctrl.Queue = ctrl.consistency
+			ctrl.consistency.addSource(&fakeResourceVersionSource{rv: "10"})
+			ctrl.consistency.addSource(&fakeResourceVersionSource{rv: "15"})
+			defer ctrl.Queue.ShutDown()
+
+			reconciled := false
+			ctrl.Do = reconcile.Func(func(context.Context, reconcile.Request) (reconcile.Result, error) {
+				reconciled = true
+				return reconcile.Result{}, nil
+			})
+
+			ctrl.Queue.Add(request)
+			Expect(ctrl.processNextWorkItem(context.Background())).To(BeTrue())
+			Expect(reconciled).To(BeTrue())
+		})
+
+		It("should hold the requests of the clusters whose caches are not synced until they are", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			ctrl.Queue = queue
+			gate := cluster.NewGate()
+			ctrl.SyncGate = gate
+			ctrl.heldRequests = newHeldRequests()
+
+			reconciled := false
+			ctrl.Do = reconcile.Func(func(context.Context, reconcile.Request) (reconcile.Result, error) {
+				reconciled = true
+				return reconcile.Result{}, nil
+			})
+
+			By("holding the request of the cluster being resynced")
+			ws := logicalcluster.New("root:org:ws")
+			gate.Hold(ws)
+			held := reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: request.NamespacedName, Cluster: ws}}
+			queue.Add(held)
+			Expect(ctrl.processNextWorkItem(ctx)).To(BeTrue())
+			Expect(reconciled).To(BeFalse())
+			Expect(queue.Len()).To(Equal(0))
+			Expect(queue.NumRequeues(held)).To(Equal(0))
+
+			By("reconciling the request once the cache of the cluster is synced")
+			gate.Release(ws)
+			Eventually(queue.Len).Should(Equal(1))
+			Expect(ctrl.processNextWorkItem(ctx)).To(BeTrue())
+			Expect(reconciled).To(BeTrue())
+		})
+
 		It("should continue to process additional queue items after the first", func() {
 			ctrl.Do = reconcile.Func(func(context.Context, reconcile.Request) (reconcile.Result, error) {
 				defer GinkgoRecover()
@@ -840,6 +1199,81 @@ var _ = Describe("controller", func() {
 					return nil
 				}, 2.0).Should(Succeed())
 			}, 4.0)
+
+			It("should label the metrics with the logical cluster of the request once enabled", func() {
+				ctrlmetrics.EnableClusterLabel()
+				Expect(ctrlmetrics.ClusterLabelEnabled()).To(BeTrue())
+
+				clusterRequest := request
+				clusterRequest.Cluster = logicalcluster.New("root:org:ws")
+
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+				go func() {
+					defer GinkgoRecover()
+					Expect(ctrl.Start(ctx)).NotTo(HaveOccurred())
+				}()
+				queue.Add(clusterRequest)
+
+				By("Invoking Reconciler which will give an error")
+				fakeReconcile.AddResult(reconcile.Result{}, fmt.Errorf("expected error: reconcile"))
+				Expect(<-reconciled).To(Equal(clusterRequest))
+
+				By("Invoking Reconciler a second time without error")
+				fakeReconcile.AddResult(reconcile.Result{}, nil)
+				Expect(<-reconciled).To(Equal(clusterRequest))
+
+				Eventually(func() float64 {
+					var m dto.Metric
+					Expect(ctrlmetrics.ReconcileTotalByCluster.WithLabelValues(ctrl.Name, "root:org:ws", labelSuccess).Write(&m)).To(Succeed())
+					return m.GetCounter().GetValue()
+				}).Should(Equal(1.0))
+
+				var reconcileErrors dto.Metric
+				Expect(ctrlmetrics.ReconcileErrorsByCluster.WithLabelValues(ctrl.Name, "root:org:ws").Write(&reconcileErrors)).To(Succeed())
+				Expect(reconcileErrors.GetCounter().GetValue()).To(Equal(1.0))
+
+				var reconcileTime dto.Metric
+				Expect(ctrlmetrics.ReconcileTimeByCluster.WithLabelValues(ctrl.Name, "root:org:ws").(prometheus.Histogram).Write(&reconcileTime)).To(Succeed())
+				Expect(reconcileTime.GetHistogram().GetSampleCount()).To(Equal(uint64(2)))
+
+				By("Exposing the labelled metrics in the registry")
+				families, err := metrics.Registry.Gather()
+				Expect(err).NotTo(HaveOccurred())
+				var labels []string
+				for _, family := range families {
+					if family.GetName() != "controller_runtime_reconcile_total" {
+						continue
+					}
+					for _, label := range family.GetMetric()[0].GetLabel() {
+						labels = append(labels, label.GetName())
+					}
+				}
+				Expect(labels).To(ConsistOf("controller", "cluster", "result"))
+			}, 4.0)
+
+			It("should track the queued requests per logical cluster once enabled", func() {
+				ctrlmetrics.EnableClusterLabel()
+
+				clusterRequest := request
+				clusterRequest.Cluster = logicalcluster.New("root:org:ws")
+				queued := func() float64 {
+					var m dto.Metric
+					Expect(ctrlmetrics.QueuedRequestsByCluster.WithLabelValues("queued-test", "root:org:ws").Write(&m)).To(Succeed())
+					return m.GetGauge().GetValue()
+				}
+
+				q := newEnqueueTimesQueue(workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()), "queued-test")
+				defer q.ShutDown()
+
+				q.Add(clusterRequest)
+				q.AddRateLimited(clusterRequest)
+				Expect(queued()).To(Equal(1.0))
+
+				q.Forget(clusterRequest)
+				q.Forget(clusterRequest)
+				Expect(queued()).To(Equal(0.0))
+			})
 		})
 	})
 })
@@ -950,3 +1384,20 @@ func (c *cacheWithIndefinitelyBlockingGetInformer) GetInformer(ctx context.Conte
 	<-ctx.Done()
 	return nil, errors.New("GetInformer timed out")
 }
+
+type fakeResourceVersionSource struct {
+	mu sync.Mutex
+	rv string
+}
+
+func (s *fakeResourceVersionSource) set(rv string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rv = rv
+}
+
+func (s *fakeResourceVersionSource) LastSyncResourceVersion() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rv
+}
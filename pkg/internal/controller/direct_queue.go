@@ -0,0 +1,239 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/internal/controller/metrics"
+)
+
+// OverflowPolicy is what a direct queue does with an item added while it is full.
+type OverflowPolicy int
+
+const (
+	// DropNewest drops the item added while the queue is full.
+	DropNewest OverflowPolicy = iota
+	// DropOldest drops the item waiting for the longest time to make room for the item added.
+	DropOldest
+	// Block blocks the caller adding the item, i.e. the event handler, until there is room.
+	Block
+)
+
+// NewDirectQueue constructs a queue handing the items added to it over to the workers of the
+// controller right away: the items added with Add are never delayed nor rate limited, and at
+// most capacity items wait for a worker, beyond which the items are handled according to
+// overflow.  The items dropped are counted in the controller_runtime_direct_queue_dropped_total
+// metric.  Like workqueue.Type, an item is never processed by two workers at once, and an item
+// added multiple times before being processed is only processed once.
+//
+// The failed items requeued with AddRateLimited are still added after the delay of the rate
+// limiter, so that a failing reconcile doesn't spin.
+func NewDirectQueue(rateLimiter workqueue.RateLimiter, capacity int, overflow OverflowPolicy, name string) workqueue.RateLimitingInterface {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &directQueue{
+		cond:        sync.NewCond(&sync.Mutex{}),
+		dirty:       map[interface{}]struct{}{},
+		processing:  map[interface{}]struct{}{},
+		capacity:    capacity,
+		overflow:    overflow,
+		rateLimiter: rateLimiter,
+		name:        name,
+	}
+}
+
+var _ workqueue.RateLimitingInterface = &directQueue{}
+
+// directQueue is a bounded workqueue.RateLimitingInterface without delaying nor rate limiting
+// of the items added with Add, see NewDirectQueue.
+type directQueue struct {
+	cond *sync.Cond
+
+	// waiting holds the items waiting for a worker, in the order they were added.
+	waiting []interface{}
+
+	// dirty holds the items that need to be processed, waiting or being processed.  Its size
+	// is bounded by capacity.
+	dirty map[interface{}]struct{}
+
+	// processing holds the items that are currently being processed.
+	processing map[interface{}]struct{}
+
+	capacity int
+	overflow OverflowPolicy
+
+	rateLimiter workqueue.RateLimiter
+
+	// name is the name of the queue in the metrics.
+	name string
+
+	shuttingDown bool
+	drain        bool
+}
+
+// Add marks item as needing processing, handing it over to the next idle worker.
+func (q *directQueue) Add(item interface{}) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	if q.shuttingDown {
+		return
+	}
+	if _, dirty := q.dirty[item]; dirty {
+		return
+	}
+	for len(q.dirty) >= q.capacity {
+		switch q.overflow {
+		case Block:
+			q.cond.Wait()
+			if q.shuttingDown {
+				return
+			}
+			if _, dirty := q.dirty[item]; dirty {
+				return
+			}
+			continue
+		case DropOldest:
+			if len(q.waiting) > 0 {
+				oldest := q.waiting[0]
+				q.waiting[0] = nil
+				q.waiting = q.waiting[1:]
+				delete(q.dirty, oldest)
+				q.dropped()
+				continue
+			}
+		}
+		// DropNewest, or DropOldest with only items being processed marked dirty again.
+		q.dropped()
+		return
+	}
+
+	q.dirty[item] = struct{}{}
+	if _, processing := q.processing[item]; processing {
+		return
+	}
+	q.waiting = append(q.waiting, item)
+	q.cond.Broadcast()
+}
+
+// dropped counts an item dropped.
+func (q *directQueue) dropped() {
+	ctrlmetrics.DirectQueueDropped.WithLabelValues(q.name).Inc()
+}
+
+// AddAfter adds item once the duration has passed.
+func (q *directQueue) AddAfter(item interface{}, duration time.Duration) {
+	if q.ShuttingDown() {
+		return
+	}
+	if duration <= 0 {
+		q.Add(item)
+		return
+	}
+	time.AfterFunc(duration, func() { q.Add(item) })
+}
+
+// AddRateLimited adds the item after the rate limiter says it's ok.
+func (q *directQueue) AddRateLimited(item interface{}) {
+	q.AddAfter(item, q.rateLimiter.When(item))
+}
+
+// Forget stops the rate limiter from tracking the item.
+func (q *directQueue) Forget(item interface{}) {
+	q.rateLimiter.Forget(item)
+}
+
+// NumRequeues returns how many times the item was requeued.
+func (q *directQueue) NumRequeues(item interface{}) int {
+	return q.rateLimiter.NumRequeues(item)
+}
+
+// Len returns the number of items waiting for a worker.
+func (q *directQueue) Len() int {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	return len(q.waiting)
+}
+
+// Get blocks until it can return an item to be processed.  If shutdown is true, the caller
+// should end their goroutine.  You must call Done with item when you have finished
+// processing it.
+func (q *directQueue) Get() (item interface{}, shutdown bool) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	for len(q.waiting) == 0 && !q.shuttingDown {
+		q.cond.Wait()
+	}
+	if len(q.waiting) == 0 {
+		// We must be shutting down.
+		return nil, true
+	}
+
+	item = q.waiting[0]
+	q.waiting[0] = nil
+	q.waiting = q.waiting[1:]
+	q.processing[item] = struct{}{}
+	delete(q.dirty, item)
+	// make room for the Adds blocked on a full queue.
+	q.cond.Broadcast()
+	return item, false
+}
+
+// Done marks item as done processing, and if it has been marked as dirty again
+// while it was being processed, it will be re-added to the queue for re-processing.
+func (q *directQueue) Done(item interface{}) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	delete(q.processing, item)
+	if _, dirty := q.dirty[item]; dirty {
+		q.waiting = append(q.waiting, item)
+	}
+	q.cond.Broadcast()
+}
+
+// ShutDown will cause q to ignore all new items added to it and immediately
+// instruct the worker goroutines to exit.
+func (q *directQueue) ShutDown() {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	q.drain = false
+	q.shuttingDown = true
+	q.cond.Broadcast()
+}
+
+// ShutDownWithDrain will cause q to ignore all new items added to it, and returns
+// once all the items being processed are marked as done.
+func (q *directQueue) ShutDownWithDrain() {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	q.drain = true
+	q.shuttingDown = true
+	q.cond.Broadcast()
+	for len(q.processing) != 0 && q.drain {
+		q.cond.Wait()
+	}
+}
+
+// ShuttingDown returns whether the queue is shutting down.
+func (q *directQueue) ShuttingDown() bool {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	return q.shuttingDown
+}
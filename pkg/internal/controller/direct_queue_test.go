@@ -0,0 +1,125 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/client-go/util/workqueue"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/internal/controller/metrics"
+)
+
+var _ = Describe("directQueue", func() {
+	rateLimiter := func() workqueue.RateLimiter {
+		return workqueue.NewItemExponentialFailureRateLimiter(time.Hour, time.Hour)
+	}
+
+	It("should hand the items over in order, without delaying them, and only once while they wait", func() {
+		q := NewDirectQueue(rateLimiter(), 10, DropNewest, "direct-order")
+		defer q.ShutDown()
+		q.Add("a")
+		q.Add("b")
+		q.Add("a")
+		Expect(q.Len()).To(Equal(2))
+
+		for _, expected := range []string{"a", "b"} {
+			item, shutdown := q.Get()
+			Expect(shutdown).To(BeFalse())
+			Expect(item).To(Equal(expected))
+			q.Done(item)
+		}
+	})
+
+	It("should requeue an item added while it is processed once it is done", func() {
+		q := NewDirectQueue(rateLimiter(), 10, DropNewest, "direct-requeue")
+		defer q.ShutDown()
+		q.Add("a")
+		item, _ := q.Get()
+		q.Add("a")
+		Expect(q.Len()).To(Equal(0))
+
+		q.Done(item)
+		Expect(q.Len()).To(Equal(1))
+	})
+
+	It("should drop the items added while it is full with DropNewest", func() {
+		q := NewDirectQueue(rateLimiter(), 2, DropNewest, "direct-drop-newest")
+		defer q.ShutDown()
+		q.Add("a")
+		q.Add("b")
+		q.Add("c")
+		Expect(testutil.ToFloat64(ctrlmetrics.DirectQueueDropped.WithLabelValues("direct-drop-newest"))).To(Equal(1.0))
+
+		for _, expected := range []string{"a", "b"} {
+			item, _ := q.Get()
+			Expect(item).To(Equal(expected))
+			q.Done(item)
+		}
+		Expect(q.Len()).To(Equal(0))
+	})
+
+	It("should drop the oldest items waiting to make room for the items added with DropOldest", func() {
+		q := NewDirectQueue(rateLimiter(), 2, DropOldest, "direct-drop-oldest")
+		defer q.ShutDown()
+		q.Add("a")
+		q.Add("b")
+		q.Add("c")
+		Expect(testutil.ToFloat64(ctrlmetrics.DirectQueueDropped.WithLabelValues("direct-drop-oldest"))).To(Equal(1.0))
+
+		for _, expected := range []string{"b", "c"} {
+			item, _ := q.Get()
+			Expect(item).To(Equal(expected))
+			q.Done(item)
+		}
+	})
+
+	It("should block the items added while it is full until there is room with Block", func() {
+		q := NewDirectQueue(rateLimiter(), 1, Block, "direct-block")
+		defer q.ShutDown()
+		q.Add("a")
+		added := make(chan struct{})
+		go func() {
+			defer close(added)
+			q.Add("b")
+		}()
+		Consistently(added).ShouldNot(BeClosed())
+
+		item, _ := q.Get()
+		Expect(item).To(Equal("a"))
+		Eventually(added).Should(BeClosed())
+		Expect(q.Len()).To(Equal(1))
+	})
+
+	It("should still back off the items requeued with AddRateLimited", func() {
+		q := NewDirectQueue(rateLimiter(), 10, DropNewest, "direct-rate-limited")
+		defer q.ShutDown()
+		q.AddRateLimited("a")
+		Expect(q.Len()).To(Equal(0))
+		Expect(q.NumRequeues("a")).To(Equal(1))
+	})
+
+	It("should return shutdown from Get once shut down", func() {
+		q := NewDirectQueue(rateLimiter(), 10, DropNewest, "direct-shutdown")
+		q.ShutDown()
+		q.Add("a")
+		_, shutdown := q.Get()
+		Expect(shutdown).To(BeTrue())
+	})
+})
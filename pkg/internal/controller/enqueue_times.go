@@ -0,0 +1,266 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/util/workqueue"
+
+	"sigs.k8s.io/controller-runtime/pkg/audit"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/internal/controller/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var (
+	_ priorityAdder    = &enqueueTimesQueue{}
+	_ lowPriorityAdder = &enqueueTimesQueue{}
+)
+
+// enqueueTimesQueue wraps a queue to record when each item was first added to it since
+// it was last forgotten, i.e. since its last successful reconcile, and when the items
+// added with a delay are due.  Items are always either forgotten or requeued once
+// processed, so the records don't outlive the items in the queue.
+type enqueueTimesQueue struct {
+	workqueue.RateLimitingInterface
+
+	// rateLimiter, if set, is the rate limiter of the wrapped queue.  It is used to
+	// record when the items added with AddRateLimited are due.
+	rateLimiter workqueue.RateLimiter
+
+	mu         sync.Mutex
+	firstAdded map[interface{}]time.Time
+
+	// clusters counts the recorded requests per logical cluster, and onClusterDrained, if
+	// set, is called when the last recorded request of a logical cluster is forgotten.
+	clusters         map[logicalcluster.Name]int
+	onClusterDrained func(logicalcluster.Name)
+
+	// readyAt holds when the items waiting in the queue are due.  It drops the items
+	// handed out by Get until they are added again.
+	readyAt map[interface{}]time.Time
+
+	// triggers, if not nil, holds how the items waiting in the queue were first added
+	// since they were last handed out by Get, which moves it to dequeuedTriggers until
+	// takeTrigger is called.
+	triggers         map[interface{}]string
+	dequeuedTriggers map[interface{}]string
+
+	// queued is the gauge of the recorded requests per logical cluster, and requeues the
+	// counter of the requests added with AddRateLimited, if the cluster label of the metrics
+	// is enabled.
+	queued   *prometheus.GaugeVec
+	requeues *prometheus.CounterVec
+	name     string
+}
+
+func newEnqueueTimesQueue(q workqueue.RateLimitingInterface, name string) *enqueueTimesQueue {
+	etq := &enqueueTimesQueue{
+		RateLimitingInterface: q,
+		firstAdded:            map[interface{}]time.Time{},
+		clusters:              map[logicalcluster.Name]int{},
+		readyAt:               map[interface{}]time.Time{},
+		name:                  name,
+	}
+	if ctrlmetrics.ClusterLabelEnabled() {
+		etq.queued = ctrlmetrics.QueuedRequestsByCluster
+		etq.requeues = ctrlmetrics.RateLimitedRequeuesByCluster
+	}
+	return etq
+}
+
+// trackTriggers makes the queue record how the items were added, see takeTrigger.
+func (q *enqueueTimesQueue) trackTriggers() {
+	q.triggers = map[interface{}]string{}
+	q.dequeuedTriggers = map[interface{}]string{}
+}
+
+// record records that the item was added by the given trigger, to be due after the given
+// delay.  An item which is added several times is due at the earliest of the times, like
+// in a delaying queue.
+func (q *enqueueTimesQueue) record(item interface{}, trigger string, delay time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := time.Now()
+	if _, ok := q.triggers[item]; !ok && q.triggers != nil {
+		q.triggers[item] = trigger
+	}
+	if _, ok := q.firstAdded[item]; !ok {
+		q.firstAdded[item] = now
+		q.updateQueued(item, 1)
+		if req, ok := item.(reconcile.Request); ok {
+			q.clusters[req.Cluster]++
+		}
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	if readyAt, ok := q.readyAt[item]; !ok || now.Add(delay).Before(readyAt) {
+		q.readyAt[item] = now.Add(delay)
+	}
+}
+
+// updateQueued adds delta to the queued requests of the logical cluster of item.
+func (q *enqueueTimesQueue) updateQueued(item interface{}, delta float64) {
+	if q.queued == nil {
+		return
+	}
+	if req, ok := item.(reconcile.Request); ok {
+		q.queued.WithLabelValues(q.name, req.Cluster.String()).Add(delta)
+	}
+}
+
+// firstAddedTime returns when the item was first added since it was last forgotten.
+func (q *enqueueTimesQueue) firstAddedTime(item interface{}) (time.Time, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	t, ok := q.firstAdded[item]
+	return t, ok
+}
+
+// takeTrigger returns how the item handed out by Get was added, if the queue tracks it.
+func (q *enqueueTimesQueue) takeTrigger(item interface{}) (string, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	trigger, ok := q.dequeuedTriggers[item]
+	delete(q.dequeuedTriggers, item)
+	return trigger, ok
+}
+
+// pending returns the items which were added and not forgotten since, in the order they
+// were first added, along with when they are due, or the zero time if they are due now.
+func (q *enqueueTimesQueue) pending() ([]interface{}, []time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	items := make([]interface{}, 0, len(q.firstAdded))
+	for item := range q.firstAdded {
+		items = append(items, item)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return q.firstAdded[items[i]].Before(q.firstAdded[items[j]])
+	})
+	now := time.Now()
+	readyAt := make([]time.Time, len(items))
+	for i, item := range items {
+		if t := q.readyAt[item]; t.After(now) {
+			readyAt[i] = t
+		}
+	}
+	return items, readyAt
+}
+
+// Get implements workqueue.Interface.
+func (q *enqueueTimesQueue) Get() (interface{}, bool) {
+	item, shutdown := q.RateLimitingInterface.Get()
+	if !shutdown {
+		q.mu.Lock()
+		delete(q.readyAt, item)
+		if trigger, ok := q.triggers[item]; ok {
+			q.dequeuedTriggers[item] = trigger
+			delete(q.triggers, item)
+		}
+		q.mu.Unlock()
+	}
+	return item, shutdown
+}
+
+// Add implements workqueue.Interface.
+func (q *enqueueTimesQueue) Add(item interface{}) {
+	q.record(item, audit.TriggerEvent, 0)
+	q.RateLimitingInterface.Add(item)
+}
+
+// AddAfter implements workqueue.DelayingInterface.
+func (q *enqueueTimesQueue) AddAfter(item interface{}, duration time.Duration) {
+	q.record(item, audit.TriggerRequeueAfter, duration)
+	q.RateLimitingInterface.AddAfter(item, duration)
+}
+
+// AddRateLimited implements workqueue.RateLimitingInterface.  If the rate limiter of the
+// wrapped queue is known, the item is added after the delay it returns, like the
+// wrapped queue would, so that it is known when the item is due.
+func (q *enqueueTimesQueue) AddRateLimited(item interface{}) {
+	if req, ok := item.(reconcile.Request); ok && q.requeues != nil {
+		q.requeues.WithLabelValues(q.name, req.Cluster.String()).Inc()
+	}
+	if q.rateLimiter == nil {
+		q.record(item, audit.TriggerRequeue, 0)
+		q.RateLimitingInterface.AddRateLimited(item)
+		return
+	}
+	delay := q.rateLimiter.When(item)
+	q.record(item, audit.TriggerRequeue, delay)
+	q.RateLimitingInterface.AddAfter(item, delay)
+}
+
+// AddWithPriority implements priorityAdder, falling back to Add if the wrapped
+// queue doesn't support priorities.
+func (q *enqueueTimesQueue) AddWithPriority(item interface{}) {
+	q.record(item, audit.TriggerDelete, 0)
+	if pq, ok := q.RateLimitingInterface.(priorityAdder); ok {
+		pq.AddWithPriority(item)
+		return
+	}
+	q.RateLimitingInterface.Add(item)
+}
+
+// AddWithLowPriority implements lowPriorityAdder, falling back to Add if the wrapped
+// queue doesn't support priorities.
+func (q *enqueueTimesQueue) AddWithLowPriority(item interface{}) {
+	q.record(item, audit.TriggerEvent, 0)
+	if pq, ok := q.RateLimitingInterface.(lowPriorityAdder); ok {
+		pq.AddWithLowPriority(item)
+		return
+	}
+	q.RateLimitingInterface.Add(item)
+}
+
+// clusterPending returns the number of recorded requests of the logical cluster.
+func (q *enqueueTimesQueue) clusterPending(cluster logicalcluster.Name) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.clusters[cluster]
+}
+
+// Forget implements workqueue.RateLimitingInterface.
+func (q *enqueueTimesQueue) Forget(item interface{}) {
+	drained := false
+	req, isRequest := item.(reconcile.Request)
+	q.mu.Lock()
+	if _, ok := q.firstAdded[item]; ok {
+		delete(q.firstAdded, item)
+		delete(q.readyAt, item)
+		q.updateQueued(item, -1)
+		if isRequest {
+			q.clusters[req.Cluster]--
+			if q.clusters[req.Cluster] <= 0 {
+				delete(q.clusters, req.Cluster)
+				drained = true
+			}
+		}
+	}
+	onClusterDrained := q.onClusterDrained
+	q.mu.Unlock()
+	q.RateLimitingInterface.Forget(item)
+	if drained && onClusterDrained != nil {
+		onClusterDrained(req.Cluster)
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/internal/log"
+	"sigs.k8s.io/controller-runtime/pkg/leaderelection"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// memoryQueueHandover is a leaderelection.QueueHandover keeping the requests in memory.
+type memoryQueueHandover struct {
+	mu   sync.Mutex
+	reqs map[string][]leaderelection.PendingRequest
+}
+
+func (h *memoryQueueHandover) Save(_ context.Context, controller string, reqs []leaderelection.PendingRequest) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.reqs[controller] = reqs
+	return nil
+}
+
+func (h *memoryQueueHandover) Take(_ context.Context, controller string) ([]leaderelection.PendingRequest, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	reqs := h.reqs[controller]
+	delete(h.reqs, controller)
+	return reqs, nil
+}
+
+func (h *memoryQueueHandover) saved(controller string) []leaderelection.PendingRequest {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.reqs[controller]
+}
+
+var _ = Describe("QueueHandover", func() {
+	failing := reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "failing"}}}
+	handedOver := reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "handed-over"}}}
+
+	It("should reconcile the requests handed over, and hand over the pending ones with their backoff", func() {
+		handover := &memoryQueueHandover{reqs: map[string][]leaderelection.PendingRequest{
+			"test": {{Request: handedOver}},
+		}}
+		rateLimiter := workqueue.NewItemExponentialFailureRateLimiter(time.Hour, time.Hour)
+		reconciled := make(chan reconcile.Request, 10)
+		ctrl := &Controller{
+			Name:                    "test",
+			MaxConcurrentReconciles: 1,
+			Do: reconcile.Func(func(_ context.Context, req reconcile.Request) (reconcile.Result, error) {
+				reconciled <- req
+				if req == failing {
+					return reconcile.Result{}, errors.New("failed")
+				}
+				return reconcile.Result{}, nil
+			}),
+			MakeQueue:     func() workqueue.RateLimitingInterface { return workqueue.NewRateLimitingQueue(rateLimiter) },
+			RateLimiter:   rateLimiter,
+			QueueHandover: handover,
+			Log:           log.RuntimeLog.WithName("controller").WithName("test"),
+		}
+		Expect(ctrl.InjectFunc(func(interface{}) error { return nil })).To(Succeed())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(ctrl.Start(ctx)).To(Succeed())
+		}()
+		Eventually(reconciled).Should(Receive(Equal(handedOver)))
+
+		ctrl.mu.Lock()
+		ctrl.Queue.Add(failing)
+		ctrl.mu.Unlock()
+		Eventually(reconciled).Should(Receive(Equal(failing)))
+		Eventually(func() int { return ctrl.Queue.NumRequeues(failing) }).Should(Equal(1))
+
+		cancel()
+		Eventually(done).Should(BeClosed())
+		saved := handover.saved("test")
+		Expect(saved).To(HaveLen(1))
+		Expect(saved[0].Request).To(Equal(failing))
+		Expect(saved[0].ReadyAt).To(BeTemporally("~", time.Now().Add(time.Hour), time.Minute))
+	})
+
+	It("should hand over the items added with a delay at the earliest of their due times", func() {
+		q := newEnqueueTimesQueue(workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()), "test")
+		defer q.ShutDown()
+
+		q.AddAfter(failing, time.Hour)
+		q.AddAfter(failing, 2*time.Hour)
+		q.AddAfter(handedOver, time.Hour)
+		q.Add(handedOver)
+		items, readyAt := q.pending()
+		Expect(items).To(Equal([]interface{}{failing, handedOver}))
+		Expect(readyAt[0]).To(BeTemporally("~", time.Now().Add(time.Hour), time.Minute))
+		Expect(readyAt[1]).To(BeZero())
+
+		item, _ := q.Get()
+		Expect(item).To(Equal(handedOver))
+		q.Forget(item)
+		q.Done(item)
+		items, _ = q.pending()
+		Expect(items).To(Equal([]interface{}{failing}))
+	})
+})
@@ -17,6 +17,8 @@ limitations under the License.
 package metrics
 
 import (
+	"sync/atomic"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
@@ -61,15 +63,118 @@ var (
 		Name: "controller_runtime_active_workers",
 		Help: "Number of currently used workers per controller",
 	}, []string{"controller"})
+
+	// ReconcileTotalByCluster is ReconcileTotal with an additional cluster label
+	// holding the logical cluster of the reconciled request. It is exposed
+	// instead of ReconcileTotal when the cluster label is enabled.
+	ReconcileTotalByCluster = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "controller_runtime_reconcile_total",
+		Help: "Total number of reconciliations per controller and logical cluster",
+	}, []string{"controller", "cluster", "result"})
+
+	// ReconcileErrorsByCluster is ReconcileErrors with an additional cluster label.
+	// It is exposed instead of ReconcileErrors when the cluster label is enabled.
+	ReconcileErrorsByCluster = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "controller_runtime_reconcile_errors_total",
+		Help: "Total number of reconciliation errors per controller and logical cluster",
+	}, []string{"controller", "cluster"})
+
+	// ReconcileTimeByCluster is ReconcileTime with an additional cluster label.
+	// It is exposed instead of ReconcileTime when the cluster label is enabled.
+	ReconcileTimeByCluster = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "controller_runtime_reconcile_time_seconds",
+		Help: "Length of time per reconciliation per controller and logical cluster",
+		Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.15, 0.2, 0.25, 0.3, 0.35, 0.4, 0.45, 0.5, 0.6, 0.7, 0.8, 0.9, 1.0,
+			1.25, 1.5, 1.75, 2.0, 2.5, 3.0, 3.5, 4.0, 4.5, 5, 6, 7, 8, 9, 10, 15, 20, 25, 30, 40, 50, 60},
+	}, []string{"controller", "cluster"})
+
+	// QueuedRequestsByCluster is a prometheus metric which holds the number of
+	// requests per controller and logical cluster which were added to the workqueue
+	// and have not been reconciled successfully yet. It is only exposed when the
+	// cluster label is enabled.
+	QueuedRequestsByCluster = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "controller_runtime_queued_requests",
+		Help: "Number of queued requests not yet reconciled successfully per controller and logical cluster",
+	}, []string{"controller", "cluster"})
+
+	// RateLimitedRequeuesByCluster is a prometheus counter metrics which holds the total
+	// number of requests requeued with a backoff per controller and logical cluster.  It is
+	// only exposed when the cluster label is enabled.
+	RateLimitedRequeuesByCluster = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "controller_runtime_rate_limited_requeues_total",
+		Help: "Total number of requests requeued with a backoff per controller and logical cluster",
+	}, []string{"controller", "cluster"})
+
+	// FairQueueClusters is a prometheus metric which holds the number of logical
+	// clusters with requests waiting in the queue of the controllers whose queue
+	// serves the clusters in turn.
+	FairQueueClusters = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "controller_runtime_fair_queue_clusters",
+		Help: "Number of logical clusters with requests waiting in the fair queue per controller",
+	}, []string{"controller"})
+
+	// DirectQueueDropped is a prometheus counter metrics which holds the total number of
+	// requests dropped by the queue of the controllers notified directly, because it was full.
+	DirectQueueDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "controller_runtime_direct_queue_dropped_total",
+		Help: "Total number of requests dropped by the direct queue per controller because it was full",
+	}, []string{"controller"})
+
+	// ReconcileDeferred is a prometheus counter metrics which holds the total number of
+	// steps deferred to a follow-up reconcile with reconcile.Defer per controller and reason.
+	ReconcileDeferred = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "controller_runtime_reconcile_deferred_total",
+		Help: "Total number of reconcile steps deferred to a follow-up reconcile per controller and reason",
+	}, []string{"controller", "reason"})
 )
 
+var clusterLabelEnabled int32
+
+// EnableClusterLabel exposes the reconcile metrics labelled with the logical cluster
+// of the requests instead of the ones without the cluster label, as well as the
+// number of queued requests per logical cluster.  The metrics without the cluster
+// label keep being updated.  It should be called before any controller is started,
+// and can't be undone.
+func EnableClusterLabel() {
+	atomic.StoreInt32(&clusterLabelEnabled, 1)
+}
+
+// ClusterLabelEnabled returns whether EnableClusterLabel was called.
+func ClusterLabelEnabled() bool {
+	return atomic.LoadInt32(&clusterLabelEnabled) == 1
+}
+
+// reconcileCollector collects the reconcile metrics either with or without the
+// cluster label.  It is an unchecked collector, as the same metrics are exposed
+// with different label names depending on whether the cluster label is enabled.
+type reconcileCollector struct{}
+
+// Describe implements prometheus.Collector.
+func (reconcileCollector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector.
+func (reconcileCollector) Collect(ch chan<- prometheus.Metric) {
+	if ClusterLabelEnabled() {
+		ReconcileTotalByCluster.Collect(ch)
+		ReconcileErrorsByCluster.Collect(ch)
+		ReconcileTimeByCluster.Collect(ch)
+		QueuedRequestsByCluster.Collect(ch)
+		RateLimitedRequeuesByCluster.Collect(ch)
+		return
+	}
+	ReconcileTotal.Collect(ch)
+	ReconcileErrors.Collect(ch)
+	ReconcileTime.Collect(ch)
+}
+
 func init() {
 	metrics.Registry.MustRegister(
-		ReconcileTotal,
-		ReconcileErrors,
-		ReconcileTime,
+		reconcileCollector{},
 		WorkerCount,
 		ActiveWorkers,
+		FairQueueClusters,
+		DirectQueueDropped,
+		ReconcileDeferred,
 		// expose process metrics like CPU, Memory, file descriptor usage etc.
 		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
 		// expose Go runtime metrics like GC stats, memory stats etc.
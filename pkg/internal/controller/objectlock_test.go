@@ -0,0 +1,96 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/internal/log"
+	"sigs.k8s.io/controller-runtime/pkg/objectlock"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("ObjectLocks", func() {
+	It("should never reconcile the same object in two controllers concurrently", func() {
+		locks := objectlock.New()
+		req := reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "foo"}}}
+
+		var inFlight, overlaps, unlocked, reconciles int32
+		newController := func(name string) *Controller {
+			ctrl := &Controller{
+				Name:                    name,
+				MaxConcurrentReconciles: 1,
+				Do: reconcile.Func(func(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+					defer atomic.AddInt32(&reconciles, 1)
+					if _, ok := objectlock.OwnerFrom(ctx); !ok {
+						atomic.AddInt32(&unlocked, 1)
+					}
+					if atomic.AddInt32(&inFlight, 1) > 1 {
+						atomic.AddInt32(&overlaps, 1)
+					}
+					time.Sleep(50 * time.Millisecond)
+					atomic.AddInt32(&inFlight, -1)
+					return reconcile.Result{}, nil
+				}),
+				MakeQueue: func() workqueue.RateLimitingInterface {
+					return workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+				},
+				ObjectLocks: locks,
+				GroupKind:   schema.GroupKind{Group: "apps", Kind: "Deployment"},
+				Log:         log.RuntimeLog.WithName("controller").WithName(name),
+			}
+			Expect(ctrl.InjectFunc(func(interface{}) error { return nil })).To(Succeed())
+			return ctrl
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var ctrls []*Controller
+		for _, name := range []string{"first", "second", "third"} {
+			ctrl := newController(name)
+			ctrls = append(ctrls, ctrl)
+			go func() {
+				defer GinkgoRecover()
+				Expect(ctrl.Start(ctx)).To(Succeed())
+			}()
+		}
+		for _, ctrl := range ctrls {
+			ctrl := ctrl
+			Eventually(func() bool {
+				ctrl.mu.Lock()
+				defer ctrl.mu.Unlock()
+				return ctrl.Started
+			}).Should(BeTrue())
+			ctrl.mu.Lock()
+			ctrl.Queue.Add(req)
+			ctrl.mu.Unlock()
+		}
+
+		Eventually(func() int32 { return atomic.LoadInt32(&reconciles) }).Should(BeEquivalentTo(3))
+		Expect(atomic.LoadInt32(&overlaps)).To(BeZero())
+		Expect(atomic.LoadInt32(&unlocked)).To(BeZero())
+	})
+})
@@ -0,0 +1,494 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/internal/controller/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// priorityAdder is implemented by queues which can hand out some items before the others.
+type priorityAdder interface {
+	// AddWithPriority adds an item to the queue ahead of all the items added with Add.
+	AddWithPriority(item interface{})
+}
+
+// lowPriorityAdder is implemented by queues which can hand out some items after the others.
+type lowPriorityAdder interface {
+	// AddWithLowPriority adds an item to the queue behind all the items added with Add.
+	AddWithLowPriority(item interface{})
+}
+
+// priority is the lane of an item of a priorityQueue.
+type priority int
+
+const (
+	lowPriority priority = iota
+	normalPriority
+	highPriority
+)
+
+// NewPriorityRateLimitingQueue constructs a rate limiting queue which serves the items added
+// with AddWithPriority before the items added with Add, e.g. to make sure requests caused by
+// Delete events are not starved by a storm of updates, and the items added with
+// AddWithLowPriority after them, e.g. the requests of the initial list of a new cluster.
+func NewPriorityRateLimitingQueue(rateLimiter workqueue.RateLimiter, name string) workqueue.RateLimitingInterface {
+	q := newPriorityQueue()
+	q.name = name
+	return newPriorityRateLimitingQueue(q, rateLimiter, name)
+}
+
+// NewFairRateLimitingQueue constructs a rate limiting queue which serves the requests of the
+// logical clusters in turn, rather than in the order they were added, so that a noisy cluster
+// doesn't starve the others.  Within a cluster, the items added with AddWithPriority are served
+// before the items added with Add, and the items added with AddWithLowPriority after them.  Pair it with a ratelimiter.NewPerCluster rate limiter for
+// the backoffs of a cluster not to delay the others either.
+func NewFairRateLimitingQueue(rateLimiter workqueue.RateLimiter, name string) workqueue.RateLimitingInterface {
+	q := newPriorityQueue()
+	q.byCluster = true
+	q.name = name
+	return newPriorityRateLimitingQueue(q, rateLimiter, name)
+}
+
+func newPriorityRateLimitingQueue(q *priorityQueue, rateLimiter workqueue.RateLimiter, name string) workqueue.RateLimitingInterface {
+	if name != "" {
+		q.metrics = newQueueMetrics(metrics.WorkqueueMetricsProvider(), name)
+		go q.updateUnfinishedWorkLoop()
+	}
+	return &priorityRateLimitingQueue{
+		DelayingInterface: workqueue.NewDelayingQueueWithCustomQueue(q, name),
+		priority:          q,
+		rateLimiter:       rateLimiter,
+	}
+}
+
+var (
+	_ priorityAdder    = &priorityRateLimitingQueue{}
+	_ lowPriorityAdder = &priorityRateLimitingQueue{}
+)
+
+// priorityRateLimitingQueue is a workqueue.RateLimitingInterface on top of a priorityQueue.
+type priorityRateLimitingQueue struct {
+	workqueue.DelayingInterface
+
+	priority    *priorityQueue
+	rateLimiter workqueue.RateLimiter
+}
+
+// AddWithPriority implements priorityAdder.
+func (q *priorityRateLimitingQueue) AddWithPriority(item interface{}) {
+	q.priority.AddWithPriority(item)
+}
+
+// AddWithLowPriority implements lowPriorityAdder.
+func (q *priorityRateLimitingQueue) AddWithLowPriority(item interface{}) {
+	q.priority.AddWithLowPriority(item)
+}
+
+// AddRateLimited adds the item after the rate limiter says it's ok.
+func (q *priorityRateLimitingQueue) AddRateLimited(item interface{}) {
+	q.DelayingInterface.AddAfter(item, q.rateLimiter.When(item))
+}
+
+// Forget stops the rate limiter from tracking the item.
+func (q *priorityRateLimitingQueue) Forget(item interface{}) {
+	q.rateLimiter.Forget(item)
+}
+
+// NumRequeues returns how many times the item was requeued.
+func (q *priorityRateLimitingQueue) NumRequeues(item interface{}) int {
+	return q.rateLimiter.NumRequeues(item)
+}
+
+var _ workqueue.Interface = &priorityQueue{}
+
+// priorityQueue is a workqueue.Interface with three FIFO lanes: high, normal and low.  It
+// provides the same guarantees as workqueue.Type: an item is never processed by two workers
+// at once, and an item added multiple times before being processed is only processed once.
+// An item already waiting in a lane is moved to a higher lane when it is added again with a
+// higher priority.
+//
+// If byCluster is set, the queue has a set of lanes per logical cluster, and serves the
+// clusters with items waiting in turn.
+type priorityQueue struct {
+	cond *sync.Cond
+
+	// lanes holds the items waiting to be processed, per logical cluster if byCluster
+	// is set, in a single entry otherwise.  Only the clusters with items waiting have
+	// an entry.
+	lanes map[logicalcluster.Name]*lanes
+
+	// turns holds the clusters of lanes in the order they are served.
+	turns []logicalcluster.Name
+
+	// waiting is the number of items in lanes.
+	waiting int
+
+	// dirty holds the items that need to be processed, and their priority.
+	dirty map[interface{}]priority
+
+	// processing holds the items that are currently being processed.
+	processing map[interface{}]struct{}
+
+	byCluster bool
+
+	// name is the name of the queue in the metrics.
+	name string
+
+	// metrics are the standard metrics of the workqueues, nil if the queue has no name.
+	metrics *queueMetrics
+
+	shuttingDown bool
+	drain        bool
+}
+
+// lanes holds the items of a logical cluster waiting to be processed.
+type lanes struct {
+	high   []interface{}
+	normal []interface{}
+	low    []interface{}
+}
+
+// lane returns the lane of the priority.
+func (l *lanes) lane(p priority) *[]interface{} {
+	switch p {
+	case highPriority:
+		return &l.high
+	case lowPriority:
+		return &l.low
+	default:
+		return &l.normal
+	}
+}
+
+// len returns the number of items in the lanes.
+func (l *lanes) len() int {
+	return len(l.high) + len(l.normal) + len(l.low)
+}
+
+func newPriorityQueue() *priorityQueue {
+	return &priorityQueue{
+		cond:       sync.NewCond(&sync.Mutex{}),
+		lanes:      map[logicalcluster.Name]*lanes{},
+		dirty:      map[interface{}]priority{},
+		processing: map[interface{}]struct{}{},
+	}
+}
+
+// Add marks item as needing processing.
+func (q *priorityQueue) Add(item interface{}) {
+	q.add(item, normalPriority)
+}
+
+// AddWithPriority marks item as needing processing ahead of the items added with Add.
+func (q *priorityQueue) AddWithPriority(item interface{}) {
+	q.add(item, highPriority)
+}
+
+// AddWithLowPriority marks item as needing processing after the items added with Add.
+func (q *priorityQueue) AddWithLowPriority(item interface{}) {
+	q.add(item, lowPriority)
+}
+
+func (q *priorityQueue) add(item interface{}, p priority) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	if q.shuttingDown {
+		return
+	}
+	_, processing := q.processing[item]
+	if current, dirty := q.dirty[item]; dirty {
+		if p > current {
+			q.dirty[item] = p
+			if !processing {
+				l := q.lanes[q.clusterOf(item)]
+				*l.lane(current) = remove(*l.lane(current), item)
+				*l.lane(p) = append(*l.lane(p), item)
+			}
+		}
+		return
+	}
+
+	q.dirty[item] = p
+	q.metrics.add(item)
+	if processing {
+		return
+	}
+	q.push(item, p)
+	q.cond.Signal()
+}
+
+// clusterOf returns the key of the lanes of item.
+func (q *priorityQueue) clusterOf(item interface{}) logicalcluster.Name {
+	if !q.byCluster {
+		return logicalcluster.Name{}
+	}
+	if req, ok := item.(reconcile.Request); ok {
+		return req.Cluster
+	}
+	return logicalcluster.Name{}
+}
+
+func (q *priorityQueue) push(item interface{}, p priority) {
+	cluster := q.clusterOf(item)
+	l, ok := q.lanes[cluster]
+	if !ok {
+		l = &lanes{}
+		q.lanes[cluster] = l
+		q.turns = append(q.turns, cluster)
+		q.updateClusters()
+	}
+	*l.lane(p) = append(*l.lane(p), item)
+	q.waiting++
+}
+
+// pop removes the next item to process from the lanes of the cluster whose turn it is,
+// which then waits for the other clusters before its next item is served.
+func (q *priorityQueue) pop() interface{} {
+	cluster := q.turns[0]
+	q.turns = q.turns[1:]
+	l := q.lanes[cluster]
+
+	lane := &l.low
+	if len(l.high) > 0 {
+		lane = &l.high
+	} else if len(l.normal) > 0 {
+		lane = &l.normal
+	}
+	item := (*lane)[0]
+	(*lane)[0] = nil
+	*lane = (*lane)[1:]
+	q.waiting--
+
+	if l.len() > 0 {
+		q.turns = append(q.turns, cluster)
+	} else {
+		delete(q.lanes, cluster)
+		q.updateClusters()
+	}
+	return item
+}
+
+// updateClusters updates the gauge of the clusters with items waiting.
+func (q *priorityQueue) updateClusters() {
+	if q.byCluster {
+		ctrlmetrics.FairQueueClusters.WithLabelValues(q.name).Set(float64(len(q.lanes)))
+	}
+}
+
+// Len returns the number of items waiting to be processed.
+func (q *priorityQueue) Len() int {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	return q.waiting
+}
+
+// Get blocks until it can return an item to be processed, serving the high lane first and the
+// low lane last, of the cluster whose turn it is if byCluster is set.
+// If shutdown is true, the caller should end their goroutine.  You must call Done with
+// item when you have finished processing it.
+func (q *priorityQueue) Get() (item interface{}, shutdown bool) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	for q.waiting == 0 && !q.shuttingDown {
+		q.cond.Wait()
+	}
+	if q.waiting == 0 {
+		// We must be shutting down.
+		return nil, true
+	}
+
+	item = q.pop()
+	q.metrics.get(item)
+	q.processing[item] = struct{}{}
+	delete(q.dirty, item)
+	return item, false
+}
+
+// Done marks item as done processing, and if it has been marked as dirty again
+// while it was being processed, it will be re-added to the queue for re-processing.
+func (q *priorityQueue) Done(item interface{}) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	q.metrics.done(item)
+	delete(q.processing, item)
+	if p, dirty := q.dirty[item]; dirty {
+		q.push(item, p)
+		q.cond.Signal()
+	} else if len(q.processing) == 0 {
+		q.cond.Signal()
+	}
+}
+
+// ShutDown will cause q to ignore all new items added to it and immediately
+// instruct the worker goroutines to exit.
+func (q *priorityQueue) ShutDown() {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	q.drain = false
+	q.shuttingDown = true
+	q.cond.Broadcast()
+}
+
+// ShutDownWithDrain will cause q to ignore all new items added to it, and returns
+// once all the items being processed are marked as done.
+func (q *priorityQueue) ShutDownWithDrain() {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	q.drain = true
+	q.shuttingDown = true
+	q.cond.Broadcast()
+	for len(q.processing) != 0 && q.drain {
+		q.cond.Wait()
+	}
+}
+
+// updateUnfinishedWorkLoop updates the metrics of the items being processed until the queue
+// is shut down.
+func (q *priorityQueue) updateUnfinishedWorkLoop() {
+	t := time.NewTicker(unfinishedWorkUpdatePeriod)
+	defer t.Stop()
+	for range t.C {
+		q.cond.L.Lock()
+		if q.shuttingDown {
+			q.cond.L.Unlock()
+			return
+		}
+		q.metrics.updateUnfinishedWork()
+		q.cond.L.Unlock()
+	}
+}
+
+// ShuttingDown returns whether the queue is shutting down.
+func (q *priorityQueue) ShuttingDown() bool {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	return q.shuttingDown
+}
+
+func remove(items []interface{}, item interface{}) []interface{} {
+	for i := range items {
+		if items[i] == item {
+			return append(items[:i], items[i+1:]...)
+		}
+	}
+	return items
+}
+
+var _ handler.EventHandler = &deletePriorityHandler{}
+
+// deletePriorityHandler wraps an EventHandler so that the requests it enqueues for
+// Delete events, and for the Update events of the objects being deleted, e.g. whose
+// finalizers are to be processed, are added with priority, if the queue supports it.
+type deletePriorityHandler struct {
+	handler.EventHandler
+}
+
+// Update implements handler.EventHandler.
+func (h *deletePriorityHandler) Update(evt event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	if _, ok := q.(priorityAdder); !ok || evt.ObjectNew == nil || evt.ObjectNew.GetDeletionTimestamp() == nil {
+		h.EventHandler.Update(evt, q)
+		return
+	}
+	h.EventHandler.Update(evt, &priorityAddQueue{RateLimitingInterface: q})
+}
+
+// Delete implements handler.EventHandler.
+func (h *deletePriorityHandler) Delete(evt event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	if _, ok := q.(priorityAdder); !ok {
+		h.EventHandler.Delete(evt, q)
+		return
+	}
+	h.EventHandler.Delete(evt, &priorityAddQueue{RateLimitingInterface: q})
+}
+
+// priorityAddQueue turns the Adds of an EventHandler into AddWithPriority.
+type priorityAddQueue struct {
+	workqueue.RateLimitingInterface
+}
+
+// Add implements workqueue.Interface.
+func (q *priorityAddQueue) Add(item interface{}) {
+	q.RateLimitingInterface.(priorityAdder).AddWithPriority(item)
+}
+
+var _ handler.EventHandler = &initialSyncHandler{}
+
+// initialSyncHandler wraps an EventHandler so that the requests it enqueues for the Create
+// events of the objects which existed before their logical cluster was first seen, i.e. the
+// objects of the initial list of a newly added cluster, are added with low priority, if the
+// queue supports it.
+type initialSyncHandler struct {
+	handler.EventHandler
+
+	clusters *clustersSeen
+}
+
+// Create implements handler.EventHandler.
+func (h *initialSyncHandler) Create(evt event.CreateEvent, q workqueue.RateLimitingInterface) {
+	if _, ok := q.(lowPriorityAdder); !ok || evt.Object == nil {
+		h.EventHandler.Create(evt, q)
+		return
+	}
+	// the creation timestamps have a precision of a second.
+	firstSeen := h.clusters.firstSeen(logicalcluster.From(evt.Object)).Truncate(time.Second)
+	if !evt.Object.GetCreationTimestamp().Time.Before(firstSeen) {
+		h.EventHandler.Create(evt, q)
+		return
+	}
+	h.EventHandler.Create(evt, &lowPriorityAddQueue{RateLimitingInterface: q})
+}
+
+// clustersSeen records when the logical clusters were first seen by a controller.
+type clustersSeen struct {
+	mu    sync.Mutex
+	times map[logicalcluster.Name]time.Time
+}
+
+func newClustersSeen() *clustersSeen {
+	return &clustersSeen{times: map[logicalcluster.Name]time.Time{}}
+}
+
+// firstSeen returns when the cluster was first seen, recording it if it is now.
+func (c *clustersSeen) firstSeen(cluster logicalcluster.Name) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, ok := c.times[cluster]
+	if !ok {
+		t = time.Now()
+		c.times[cluster] = t
+	}
+	return t
+}
+
+// lowPriorityAddQueue turns the Adds of an EventHandler into AddWithLowPriority.
+type lowPriorityAddQueue struct {
+	workqueue.RateLimitingInterface
+}
+
+// Add implements workqueue.Interface.
+func (q *lowPriorityAddQueue) Add(item interface{}) {
+	q.RateLimitingInterface.(lowPriorityAdder).AddWithLowPriority(item)
+}
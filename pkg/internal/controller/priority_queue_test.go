@@ -0,0 +1,264 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/internal/controller/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("priorityQueue", func() {
+	It("should serve the items added with priority first", func() {
+		q := newPriorityQueue()
+		defer q.ShutDown()
+		q.Add("a")
+		q.Add("b")
+		q.AddWithPriority("c")
+
+		for _, expected := range []string{"c", "a", "b"} {
+			item, shutdown := q.Get()
+			Expect(shutdown).To(BeFalse())
+			Expect(item).To(Equal(expected))
+			q.Done(item)
+		}
+	})
+
+	It("should move a waiting item to the priority lane", func() {
+		q := newPriorityQueue()
+		defer q.ShutDown()
+		q.Add("a")
+		q.Add("b")
+		q.AddWithPriority("b")
+		Expect(q.Len()).To(Equal(2))
+
+		item, _ := q.Get()
+		Expect(item).To(Equal("b"))
+	})
+
+	It("should requeue an item added while it is processed with its priority", func() {
+		q := newPriorityQueue()
+		defer q.ShutDown()
+		q.Add("a")
+		item, _ := q.Get()
+		q.Add("b")
+		q.AddWithPriority("a")
+		Expect(q.Len()).To(Equal(1))
+
+		q.Done(item)
+		item, _ = q.Get()
+		Expect(item).To(Equal("a"))
+	})
+
+	It("should serve the items added with low priority last", func() {
+		q := newPriorityQueue()
+		defer q.ShutDown()
+		q.AddWithLowPriority("a")
+		q.AddWithLowPriority("b")
+		q.Add("c")
+		q.AddWithPriority("d")
+		q.Add("b")
+		Expect(q.Len()).To(Equal(4))
+
+		for _, expected := range []string{"d", "c", "b", "a"} {
+			item, shutdown := q.Get()
+			Expect(shutdown).To(BeFalse())
+			Expect(item).To(Equal(expected))
+			q.Done(item)
+		}
+	})
+
+	It("should not move a waiting item to a lower lane", func() {
+		q := newPriorityQueue()
+		defer q.ShutDown()
+		q.Add("a")
+		q.AddWithLowPriority("b")
+		q.AddWithLowPriority("a")
+		Expect(q.Len()).To(Equal(2))
+
+		item, _ := q.Get()
+		Expect(item).To(Equal("a"))
+	})
+
+	It("should return shutdown once shut down and empty", func() {
+		q := newPriorityQueue()
+		q.ShutDown()
+		q.Add("a")
+		_, shutdown := q.Get()
+		Expect(shutdown).To(BeTrue())
+	})
+})
+
+var _ = Describe("fair priorityQueue", func() {
+	requestIn := func(cluster, name string) reconcile.Request {
+		return reconcile.Request{ObjectKey: client.ObjectKey{
+			NamespacedName: types.NamespacedName{Name: name},
+			Cluster:        logicalcluster.New(cluster),
+		}}
+	}
+
+	It("should serve the logical clusters in turn", func() {
+		q := NewFairRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "fair-test")
+		defer q.ShutDown()
+		for i := 0; i < 3; i++ {
+			q.Add(requestIn("root:noisy", fmt.Sprint(i)))
+		}
+		q.Add(requestIn("root:quiet", "0"))
+		q.Add(requestIn("root:other", "0"))
+		q.(priorityAdder).AddWithPriority(requestIn("root:other", "1"))
+		Expect(q.Len()).To(Equal(6))
+		Expect(testutil.ToFloat64(ctrlmetrics.FairQueueClusters.WithLabelValues("fair-test"))).To(Equal(3.0))
+
+		expected := []reconcile.Request{
+			requestIn("root:noisy", "0"),
+			requestIn("root:quiet", "0"),
+			requestIn("root:other", "1"),
+			requestIn("root:noisy", "1"),
+			requestIn("root:other", "0"),
+			requestIn("root:noisy", "2"),
+		}
+		for _, req := range expected {
+			item, shutdown := q.Get()
+			Expect(shutdown).To(BeFalse())
+			Expect(item).To(Equal(req))
+			q.Done(item)
+		}
+		Expect(testutil.ToFloat64(ctrlmetrics.FairQueueClusters.WithLabelValues("fair-test"))).To(BeZero())
+	})
+
+	It("should give a cluster its turn back once an item processed is added again", func() {
+		q := newPriorityQueue()
+		q.byCluster = true
+		defer q.ShutDown()
+		q.Add(requestIn("root:a", "0"))
+		item, _ := q.Get()
+		q.Add(requestIn("root:b", "0"))
+		q.Add(item)
+		q.Done(item)
+
+		item, _ = q.Get()
+		Expect(item).To(Equal(requestIn("root:b", "0")))
+		item, _ = q.Get()
+		Expect(item).To(Equal(requestIn("root:a", "0")))
+	})
+})
+
+var _ = Describe("deletePriorityHandler", func() {
+	It("should enqueue the requests of Delete events with priority", func() {
+		q := NewPriorityRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test")
+		defer q.ShutDown()
+		h := &deletePriorityHandler{EventHandler: &handler.EnqueueRequestForObject{}}
+
+		h.Update(event.UpdateEvent{
+			ObjectOld: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "updated"}},
+			ObjectNew: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "updated"}},
+		}, q)
+		h.Delete(event.DeleteEvent{
+			Object: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "deleted"}},
+		}, q)
+
+		item, _ := q.Get()
+		Expect(item.(reconcile.Request).Name).To(Equal("deleted"))
+	})
+
+	It("should enqueue the requests of the Update events of the objects being deleted with priority", func() {
+		q := NewPriorityRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test")
+		defer q.ShutDown()
+		h := &deletePriorityHandler{EventHandler: &handler.EnqueueRequestForObject{}}
+		deleted := metav1.Now()
+
+		h.Update(event.UpdateEvent{
+			ObjectOld: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "updated"}},
+			ObjectNew: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "updated"}},
+		}, q)
+		h.Update(event.UpdateEvent{
+			ObjectOld: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "finalizing"}},
+			ObjectNew: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "finalizing", DeletionTimestamp: &deleted}},
+		}, q)
+
+		for _, expected := range []string{"finalizing", "updated"} {
+			item, _ := q.Get()
+			Expect(item.(reconcile.Request).Name).To(Equal(expected))
+			q.Done(item)
+		}
+	})
+})
+
+var _ = Describe("NewPriorityRateLimitingQueue", func() {
+	It("should record the standard workqueue metrics under its name", func() {
+		q := NewPriorityRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "priority-metrics")
+		defer q.ShutDown()
+		provider := metrics.WorkqueueMetricsProvider()
+		depth := provider.NewDepthMetric("priority-metrics").(prometheus.Gauge)
+		adds := provider.NewAddsMetric("priority-metrics").(prometheus.Counter)
+
+		q.Add("a")
+		q.(priorityAdder).AddWithPriority("b")
+		q.Add("a")
+		Expect(testutil.ToFloat64(depth)).To(Equal(2.0))
+		Expect(testutil.ToFloat64(adds)).To(Equal(2.0))
+
+		item, _ := q.Get()
+		Expect(item).To(Equal("b"))
+		Expect(testutil.ToFloat64(depth)).To(Equal(1.0))
+		q.Done(item)
+	})
+})
+
+var _ = Describe("initialSyncHandler", func() {
+	It("should enqueue the requests of the objects which existed before their cluster was seen with low priority", func() {
+		q := NewPriorityRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test")
+		defer q.ShutDown()
+		h := &initialSyncHandler{EventHandler: &handler.EnqueueRequestForObject{}, clusters: newClustersSeen()}
+		podIn := func(cluster, name string, created time.Time) *corev1.Pod {
+			return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Namespace:         "default",
+				Name:              name,
+				CreationTimestamp: metav1.NewTime(created),
+				ClusterName:       cluster,
+			}}
+		}
+
+		h.Create(event.CreateEvent{Object: podIn("root:new", "listed", time.Now().Add(-time.Hour))}, q)
+		h.Create(event.CreateEvent{Object: podIn("root:new", "created", time.Now().Add(time.Second))}, q)
+		h.Update(event.UpdateEvent{
+			ObjectOld: podIn("root:existing", "updated", time.Now().Add(-time.Hour)),
+			ObjectNew: podIn("root:existing", "updated", time.Now().Add(-time.Hour)),
+		}, q)
+
+		for _, expected := range []string{"created", "updated", "listed"} {
+			item, _ := q.Get()
+			Expect(item.(reconcile.Request).Name).To(Equal(expected))
+			q.Done(item)
+		}
+	})
+})
@@ -0,0 +1,96 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/internal/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("DumpQueue", func() {
+	It("should dump the pending requests by logical cluster, with their retries and next retry", func() {
+		failing := reconcile.Request{ObjectKey: client.ObjectKey{
+			NamespacedName: types.NamespacedName{Namespace: "ns", Name: "failing"},
+			Cluster:        logicalcluster.New("root:stuck"),
+		}}
+		delayed := reconcile.Request{ObjectKey: client.ObjectKey{
+			NamespacedName: types.NamespacedName{Namespace: "ns", Name: "delayed"},
+			Cluster:        logicalcluster.New("root:ok"),
+		}}
+		rateLimiter := workqueue.NewItemExponentialFailureRateLimiter(time.Hour, time.Hour)
+		reconciled := make(chan reconcile.Request, 10)
+		ctrl := &Controller{
+			Name:                    "dump",
+			MaxConcurrentReconciles: 1,
+			Do: reconcile.Func(func(_ context.Context, req reconcile.Request) (reconcile.Result, error) {
+				reconciled <- req
+				return reconcile.Result{}, errors.New("failed")
+			}),
+			MakeQueue:   func() workqueue.RateLimitingInterface { return workqueue.NewRateLimitingQueue(rateLimiter) },
+			RateLimiter: rateLimiter,
+			Log:         log.RuntimeLog.WithName("controller").WithName("dump"),
+		}
+		Expect(ctrl.InjectFunc(func(interface{}) error { return nil })).To(Succeed())
+		Expect(ctrl.DumpQueue().Clusters).To(BeEmpty())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			defer GinkgoRecover()
+			Expect(ctrl.Start(ctx)).To(Succeed())
+		}()
+		Eventually(func() bool {
+			ctrl.mu.Lock()
+			defer ctrl.mu.Unlock()
+			return ctrl.Started
+		}).Should(BeTrue())
+
+		ctrl.Queue.Add(failing)
+		Eventually(reconciled).Should(Receive(Equal(failing)))
+		Eventually(func() int { return ctrl.Queue.NumRequeues(failing) }).Should(Equal(1))
+		ctrl.Queue.AddAfter(delayed, time.Hour)
+
+		queue := ctrl.DumpQueue()
+		Expect(queue.Name).To(Equal("dump"))
+		Expect(queue.Clusters).To(HaveLen(2))
+		Expect(queue.Clusters[0].Cluster).To(Equal("root:stuck"))
+		Expect(queue.Clusters[0].Retries).To(Equal(1))
+		Expect(queue.Clusters[0].Requests).To(HaveLen(1))
+		stuck := queue.Clusters[0].Requests[0]
+		Expect(stuck.Key).To(Equal(failing.Key()))
+		Expect(stuck.Retries).To(Equal(1))
+		Expect(stuck.FirstEnqueued).To(BeTemporally("~", time.Now(), time.Minute))
+		Expect(stuck.NextRetry).NotTo(BeNil())
+		Expect(*stuck.NextRetry).To(BeTemporally("~", time.Now().Add(time.Hour), time.Minute))
+
+		Expect(queue.Clusters[1].Cluster).To(Equal("root:ok"))
+		Expect(queue.Clusters[1].Retries).To(BeZero())
+		Expect(queue.Clusters[1].Requests[0].Key).To(Equal(delayed.Key()))
+		Expect(queue.Clusters[1].Requests[0].NextRetry).NotTo(BeNil())
+	})
+})
@@ -0,0 +1,109 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+)
+
+// unfinishedWorkUpdatePeriod is the period between two updates of the metrics of the items
+// being processed, as for the queues of the workqueue package.
+const unfinishedWorkUpdatePeriod = 500 * time.Millisecond
+
+// queueMetrics are the standard metrics of the workqueues, for the queues which don't store
+// their items in a workqueue.Type, which records them otherwise.  Like the ones of a
+// workqueue.Type, they must be updated with the lock of the queue held.  A nil queueMetrics
+// records nothing.
+type queueMetrics struct {
+	depth                   workqueue.GaugeMetric
+	adds                    workqueue.CounterMetric
+	latency                 workqueue.HistogramMetric
+	workDuration            workqueue.HistogramMetric
+	unfinishedWorkSeconds   workqueue.SettableGaugeMetric
+	longestRunningProcessor workqueue.SettableGaugeMetric
+
+	addTimes             map[interface{}]time.Time
+	processingStartTimes map[interface{}]time.Time
+}
+
+func newQueueMetrics(provider workqueue.MetricsProvider, name string) *queueMetrics {
+	return &queueMetrics{
+		depth:                   provider.NewDepthMetric(name),
+		adds:                    provider.NewAddsMetric(name),
+		latency:                 provider.NewLatencyMetric(name),
+		workDuration:            provider.NewWorkDurationMetric(name),
+		unfinishedWorkSeconds:   provider.NewUnfinishedWorkSecondsMetric(name),
+		longestRunningProcessor: provider.NewLongestRunningProcessorSecondsMetric(name),
+		addTimes:                map[interface{}]time.Time{},
+		processingStartTimes:    map[interface{}]time.Time{},
+	}
+}
+
+// add records that the item was marked as needing processing.
+func (m *queueMetrics) add(item interface{}) {
+	if m == nil {
+		return
+	}
+	m.adds.Inc()
+	m.depth.Inc()
+	if _, ok := m.addTimes[item]; !ok {
+		m.addTimes[item] = time.Now()
+	}
+}
+
+// get records that the item is being processed.
+func (m *queueMetrics) get(item interface{}) {
+	if m == nil {
+		return
+	}
+	m.depth.Dec()
+	m.processingStartTimes[item] = time.Now()
+	if start, ok := m.addTimes[item]; ok {
+		m.latency.Observe(time.Since(start).Seconds())
+		delete(m.addTimes, item)
+	}
+}
+
+// done records that the item was processed.
+func (m *queueMetrics) done(item interface{}) {
+	if m == nil {
+		return
+	}
+	if start, ok := m.processingStartTimes[item]; ok {
+		m.workDuration.Observe(time.Since(start).Seconds())
+		delete(m.processingStartTimes, item)
+	}
+}
+
+// updateUnfinishedWork updates the metrics of the items being processed.
+func (m *queueMetrics) updateUnfinishedWork() {
+	if m == nil {
+		return
+	}
+	var total, oldest float64
+	for _, start := range m.processingStartTimes {
+		age := time.Since(start).Seconds()
+		total += age
+		if age > oldest {
+			oldest = age
+		}
+	}
+	m.unfinishedWorkSeconds.Set(total)
+	m.longestRunningProcessor.Set(oldest)
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sync"
+
+	"github.com/kcp-dev/logicalcluster"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ cluster.ClusterSetHandler = &Controller{}
+
+// removedClusters are the logical clusters removed from the ClusterSet of the controller, whose
+// requests are dropped rather than reconciled, see Controller.ClusterSet.  A cluster is pruned
+// once its requests are drained from the queue, as its informers are stopped before it is
+// removed and don't enqueue new ones.
+type removedClusters struct {
+	mu      sync.RWMutex
+	removed map[logicalcluster.Name]struct{}
+}
+
+func newRemovedClusters() *removedClusters {
+	return &removedClusters{removed: map[logicalcluster.Name]struct{}{}}
+}
+
+func (r *removedClusters) add(name logicalcluster.Name) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.removed[name] = struct{}{}
+}
+
+func (r *removedClusters) remove(name logicalcluster.Name) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.removed, name)
+}
+
+func (r *removedClusters) has(name logicalcluster.Name) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.removed[name]
+	return ok
+}
+
+// ClusterAdded implements cluster.ClusterSetHandler.  The requests of a logical cluster added
+// back to the set are reconciled again.
+func (c *Controller) ClusterAdded(name logicalcluster.Name, _ cluster.Cluster) {
+	c.removedClusters.remove(name)
+}
+
+// ClusterRemoved implements cluster.ClusterSetHandler.  The requests of the logical cluster
+// still queued are dropped, and the ClusterRemovedRequest of the cluster is queued if
+// ReconcileRemovedClusters is set.
+func (c *Controller) ClusterRemoved(name logicalcluster.Name) {
+	c.removedClusters.add(name)
+	c.Log.Info("Dropping the requests of a removed cluster", "cluster", name.String())
+	if c.ReconcileRemovedClusters {
+		c.Queue.Add(reconcile.ClusterRemovedRequest(name))
+	}
+	// Nothing is left to drop if no request of the cluster is queued nor being reconciled.
+	if c.enqueueTimes.clusterPending(name) == 0 {
+		c.removedClusters.remove(name)
+	}
+}
+
+// fromRemovedCluster returns whether the item is a request of a logical cluster removed from
+// the ClusterSet, other than its ClusterRemovedRequest.
+func (c *Controller) fromRemovedCluster(obj interface{}) bool {
+	req, ok := obj.(reconcile.Request)
+	return ok && c.removedClusters != nil && !req.IsClusterRemoved() && c.removedClusters.has(req.Cluster)
+}
+
+// dropIfRemoved forgets the item if it is a request of a removed logical cluster, and returns
+// whether it did.
+func (c *Controller) dropIfRemoved(obj interface{}) bool {
+	if !c.fromRemovedCluster(obj) {
+		return false
+	}
+	c.Queue.Forget(obj)
+	c.Log.V(1).Info("Dropping the request of a removed cluster", "request", obj)
+	return true
+}
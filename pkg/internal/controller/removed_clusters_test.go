@@ -0,0 +1,149 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/workqueue"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/internal/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("ClusterSet", func() {
+	clusterA, clusterB := logicalcluster.New("root:a"), logicalcluster.New("root:b")
+	var set *cluster.ClusterSet
+	var ctrl *Controller
+	var reqs chan reconcile.Request
+	var unblock chan struct{}
+
+	request := func(cluster logicalcluster.Name, name string) reconcile.Request {
+		return reconcile.Request{ObjectKey: client.ObjectKey{
+			NamespacedName: types.NamespacedName{Namespace: "ns", Name: name},
+			Cluster:        cluster,
+		}}
+	}
+
+	BeforeEach(func() {
+		var err error
+		set, err = cluster.NewClusterSet(&rest.Config{Host: "https://kcp.example.com"}, cluster.ClusterSetOptions{}, func(o *cluster.Options) {
+			o.MapperProvider = func(*rest.Config) (meta.RESTMapper, error) { return meta.NewDefaultRESTMapper(nil), nil }
+		})
+		Expect(err).NotTo(HaveOccurred())
+		for _, name := range []logicalcluster.Name{clusterA, clusterB} {
+			_, err := set.Add(name)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		reqs = make(chan reconcile.Request, 10)
+		unblock = make(chan struct{})
+		ctrl = &Controller{
+			Name:                    "removed-clusters",
+			MaxConcurrentReconciles: 1,
+			// The reconciles of "blocked" wait to be unblocked, and then fail.
+			Do: reconcile.Func(func(_ context.Context, req reconcile.Request) (reconcile.Result, error) {
+				reqs <- req
+				if req.Name == "blocked" {
+					<-unblock
+					return reconcile.Result{}, errors.New("cluster is gone")
+				}
+				return reconcile.Result{}, nil
+			}),
+			MakeQueue: func() workqueue.RateLimitingInterface {
+				return workqueue.NewRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, time.Millisecond))
+			},
+			ClusterSet: set,
+			Log:        log.RuntimeLog.WithName("controller").WithName("removed-clusters"),
+		}
+		Expect(ctrl.InjectFunc(func(interface{}) error { return nil })).To(Succeed())
+	})
+
+	start := func(ctx context.Context) {
+		go func() {
+			defer GinkgoRecover()
+			Expect(ctrl.Start(ctx)).To(Succeed())
+		}()
+		Eventually(func() bool {
+			ctrl.mu.Lock()
+			defer ctrl.mu.Unlock()
+			return ctrl.Started
+		}).Should(BeTrue())
+	}
+
+	It("should drop the queued requests of the removed clusters and not retry their reconciles in flight", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		start(ctx)
+
+		blocked := request(clusterA, "blocked")
+		ctrl.Queue.Add(blocked)
+		Eventually(reqs).Should(Receive(Equal(blocked)))
+		ctrl.Queue.Add(request(clusterA, "queued"))
+		ctrl.Queue.Add(request(clusterB, "queued"))
+
+		Expect(set.Remove(clusterA)).To(BeTrue())
+		close(unblock)
+		Eventually(reqs).Should(Receive(Equal(request(clusterB, "queued"))))
+		Consistently(reqs).ShouldNot(Receive())
+		Expect(ctrl.Queue.NumRequeues(blocked)).To(Equal(0))
+		Eventually(func() bool { return ctrl.removedClusters.has(clusterA) }).Should(BeFalse(), "the cluster should be pruned once its requests are drained")
+
+		By("reconciling the requests of the clusters added back")
+		_, err := set.Add(clusterA)
+		Expect(err).NotTo(HaveOccurred())
+		ctrl.Queue.Add(request(clusterA, "queued"))
+		Eventually(reqs).Should(Receive(Equal(request(clusterA, "queued"))))
+	})
+
+	It("should reconcile the final request of the removed clusters if configured to", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ctrl.ReconcileRemovedClusters = true
+		start(ctx)
+
+		Expect(set.Remove(clusterA)).To(BeTrue())
+		var req reconcile.Request
+		Eventually(reqs).Should(Receive(&req))
+		Expect(req.IsClusterRemoved()).To(BeTrue())
+		Expect(req.Cluster).To(Equal(clusterA))
+		Expect(req).To(Equal(reconcile.ClusterRemovedRequest(clusterA)))
+		Consistently(reqs).ShouldNot(Receive())
+	})
+
+	It("should stop being notified by the set once stopped", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		ctrl.ReconcileRemovedClusters = true
+		start(ctx)
+		cancel()
+		Eventually(ctrl.Queue.ShuttingDown).Should(BeTrue())
+
+		Expect(set.Remove(clusterB)).To(BeTrue())
+		Expect(ctrl.enqueueTimes.clusterPending(clusterB)).To(Equal(0))
+		Expect(ctrl.removedClusters.has(clusterB)).To(BeFalse())
+	})
+})
@@ -0,0 +1,66 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"github.com/kcp-dev/logicalcluster"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+var _ handler.EventHandler = &suspendedHandler{}
+
+// suspendedHandler wraps an EventHandler so that the events of the objects of the suspended
+// logical clusters are dropped, see Controller.Suspensions.
+type suspendedHandler struct {
+	handler.EventHandler
+	suspensions *cluster.Suspensions
+}
+
+// Create implements handler.EventHandler.
+func (h *suspendedHandler) Create(evt event.CreateEvent, q workqueue.RateLimitingInterface) {
+	if evt.Object != nil && h.suspensions.Suspended(logicalcluster.From(evt.Object)) {
+		return
+	}
+	h.EventHandler.Create(evt, q)
+}
+
+// Update implements handler.EventHandler.
+func (h *suspendedHandler) Update(evt event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	if evt.ObjectNew != nil && h.suspensions.Suspended(logicalcluster.From(evt.ObjectNew)) {
+		return
+	}
+	h.EventHandler.Update(evt, q)
+}
+
+// Delete implements handler.EventHandler.
+func (h *suspendedHandler) Delete(evt event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	if evt.Object != nil && h.suspensions.Suspended(logicalcluster.From(evt.Object)) {
+		return
+	}
+	h.EventHandler.Delete(evt, q)
+}
+
+// Generic implements handler.EventHandler.
+func (h *suspendedHandler) Generic(evt event.GenericEvent, q workqueue.RateLimitingInterface) {
+	if evt.Object != nil && h.suspensions.Suspended(logicalcluster.From(evt.Object)) {
+		return
+	}
+	h.EventHandler.Generic(evt, q)
+}
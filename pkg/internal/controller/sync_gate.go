@@ -0,0 +1,69 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kcp-dev/logicalcluster"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// heldRequests holds the requests of the logical clusters whose caches are not synced,
+// see Controller.SyncGate.  The requests of a cluster are added back to the queue at
+// once when its cache is synced, without counting as failures.
+type heldRequests struct {
+	mu   sync.Mutex
+	held map[logicalcluster.Name]map[interface{}]struct{}
+}
+
+func newHeldRequests() *heldRequests {
+	return &heldRequests{held: map[logicalcluster.Name]map[interface{}]struct{}{}}
+}
+
+// hold holds the item until synced is closed, and then adds it back to the queue, unless
+// the context is done first.  It returns whether the cluster had no held item yet.
+func (h *heldRequests) hold(ctx context.Context, queue workqueue.Interface, cluster logicalcluster.Name, synced <-chan struct{}, item interface{}) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	items, ok := h.held[cluster]
+	if !ok {
+		items = map[interface{}]struct{}{}
+		h.held[cluster] = items
+		go h.releaseOnSync(ctx, queue, cluster, synced)
+	}
+	items[item] = struct{}{}
+	return !ok
+}
+
+func (h *heldRequests) releaseOnSync(ctx context.Context, queue workqueue.Interface, cluster logicalcluster.Name, synced <-chan struct{}) {
+	select {
+	case <-synced:
+	case <-ctx.Done():
+	}
+	h.mu.Lock()
+	items := h.held[cluster]
+	delete(h.held, cluster)
+	h.mu.Unlock()
+	if ctx.Err() != nil {
+		return
+	}
+	for item := range items {
+		queue.Add(item)
+	}
+}
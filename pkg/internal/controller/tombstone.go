@@ -0,0 +1,55 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ handler.EventHandler = &tombstoneHandler{}
+
+// tombstoneHandler wraps an EventHandler so that the requests it enqueues for Delete
+// events are tombstones carrying the last known labels of the deleted object.
+type tombstoneHandler struct {
+	handler.EventHandler
+}
+
+// Delete implements handler.EventHandler.
+func (h *tombstoneHandler) Delete(evt event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	var lastLabels map[string]string
+	if evt.Object != nil {
+		lastLabels = evt.Object.GetLabels()
+	}
+	h.EventHandler.Delete(evt, &tombstoneAddQueue{RateLimitingInterface: q, labels: lastLabels})
+}
+
+// tombstoneAddQueue turns the requests added by an EventHandler into tombstones.
+type tombstoneAddQueue struct {
+	workqueue.RateLimitingInterface
+	labels map[string]string
+}
+
+// Add implements workqueue.Interface.
+func (q *tombstoneAddQueue) Add(item interface{}) {
+	if req, ok := item.(reconcile.Request); ok {
+		item = req.Tombstone(q.labels)
+	}
+	q.RateLimitingInterface.Add(item)
+}
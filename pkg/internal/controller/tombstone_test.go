@@ -0,0 +1,69 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("tombstoneHandler", func() {
+	It("should enqueue tombstones for Delete events only", func() {
+		q := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+		defer q.ShutDown()
+		h := &tombstoneHandler{EventHandler: &handler.EnqueueRequestForObject{}}
+
+		h.Create(event.CreateEvent{
+			Object: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "created"}},
+		}, q)
+		h.Delete(event.DeleteEvent{
+			Object: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "deleted", ClusterName: "root:org", Labels: map[string]string{"app": "foo"}}},
+		}, q)
+
+		item, _ := q.Get()
+		Expect(item.(reconcile.Request).IsTombstone()).To(BeFalse())
+		item, _ = q.Get()
+		req := item.(reconcile.Request)
+		Expect(req.Name).To(Equal("deleted"))
+		Expect(req.Cluster.String()).To(Equal("root:org"))
+		Expect(req.IsTombstone()).To(BeTrue())
+		Expect(req.TombstoneLabels()).To(HaveKeyWithValue("app", "foo"))
+	})
+
+	It("should enqueue tombstones with priority when deletes are prioritized", func() {
+		q := NewPriorityRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test")
+		defer q.ShutDown()
+		h := &deletePriorityHandler{EventHandler: &tombstoneHandler{EventHandler: &handler.EnqueueRequestForObject{}}}
+
+		h.Create(event.CreateEvent{
+			Object: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "created"}},
+		}, q)
+		h.Delete(event.DeleteEvent{
+			Object: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "deleted"}},
+		}, q)
+
+		item, _ := q.Get()
+		Expect(item.(reconcile.Request).Name).To(Equal("deleted"))
+		Expect(item.(reconcile.Request).IsTombstone()).To(BeTrue())
+	})
+})
@@ -0,0 +1,99 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/internal/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/workerpool"
+)
+
+var _ = Describe("WorkerPool", func() {
+	It("should bound the reconciles running concurrently across the controllers sharing it", func() {
+		pool := workerpool.New(2)
+
+		var inFlight, maxInFlight, reconciles int32
+		newController := func(name string, weight int) *Controller {
+			ctrl := &Controller{
+				Name:                    name,
+				MaxConcurrentReconciles: 3,
+				Do: reconcile.Func(func(context.Context, reconcile.Request) (reconcile.Result, error) {
+					defer atomic.AddInt32(&reconciles, 1)
+					n := atomic.AddInt32(&inFlight, 1)
+					for {
+						max := atomic.LoadInt32(&maxInFlight)
+						if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+							break
+						}
+					}
+					time.Sleep(20 * time.Millisecond)
+					atomic.AddInt32(&inFlight, -1)
+					return reconcile.Result{}, nil
+				}),
+				MakeQueue: func() workqueue.RateLimitingInterface {
+					return workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+				},
+				WorkerPool:       pool,
+				WorkerPoolWeight: weight,
+				Log:              log.RuntimeLog.WithName("controller").WithName(name),
+			}
+			Expect(ctrl.InjectFunc(func(interface{}) error { return nil })).To(Succeed())
+			return ctrl
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var ctrls []*Controller
+		for i, name := range []string{"critical", "housekeeping"} {
+			ctrl := newController(name, 3-2*i)
+			ctrls = append(ctrls, ctrl)
+			go func() {
+				defer GinkgoRecover()
+				Expect(ctrl.Start(ctx)).To(Succeed())
+			}()
+		}
+		for _, ctrl := range ctrls {
+			ctrl := ctrl
+			Eventually(func() bool {
+				ctrl.mu.Lock()
+				defer ctrl.mu.Unlock()
+				return ctrl.Started
+			}).Should(BeTrue())
+			ctrl.mu.Lock()
+			for i := 0; i < 6; i++ {
+				ctrl.Queue.Add(reconcile.Request{ObjectKey: client.ObjectKey{
+					NamespacedName: types.NamespacedName{Namespace: "ns", Name: fmt.Sprintf("obj-%d", i)},
+				}})
+			}
+			ctrl.mu.Unlock()
+		}
+
+		Eventually(func() int32 { return atomic.LoadInt32(&reconciles) }).Should(BeEquivalentTo(12))
+		Expect(atomic.LoadInt32(&maxInFlight)).To(BeEquivalentTo(2))
+	})
+})
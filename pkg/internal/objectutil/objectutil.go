@@ -21,6 +21,7 @@ import (
 	"fmt"
 
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -46,6 +47,26 @@ func FilterWithLabels(objs []runtime.Object, labelSel labels.Selector) ([]runtim
 	return outItems, nil
 }
 
+// FilterWithFields returns a copy of the items in objs matching fieldSel on
+// their metadata.name and metadata.namespace fields.
+func FilterWithFields(objs []runtime.Object, fieldSel fields.Selector) ([]runtime.Object, error) {
+	outItems := make([]runtime.Object, 0, len(objs))
+	for _, obj := range objs {
+		meta, err := apimeta.Accessor(obj)
+		if err != nil {
+			return nil, err
+		}
+		if fieldSel != nil {
+			flds := fields.Set{"metadata.name": meta.GetName(), "metadata.namespace": meta.GetNamespace()}
+			if !fieldSel.Matches(flds) {
+				continue
+			}
+		}
+		outItems = append(outItems, obj.DeepCopyObject())
+	}
+	return outItems, nil
+}
+
 // IsAPINamespaced returns true if the object is namespace scoped.
 // For unstructured objects the gvk is found from the object itself.
 func IsAPINamespaced(obj runtime.Object, scheme *runtime.Scheme, restmapper apimeta.RESTMapper) (bool, error) {
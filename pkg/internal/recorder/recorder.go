@@ -22,11 +22,15 @@ import (
 	"sync"
 
 	"github.com/go-logr/logr"
+	"github.com/kcp-dev/logicalcluster"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/record"
+
+	"sigs.k8s.io/controller-runtime/pkg/internal/clusterhost"
 )
 
 // EventBroadcasterProducer makes an event broadcaster, returning
@@ -36,6 +40,11 @@ type EventBroadcasterProducer func() (caster record.EventBroadcaster, stopWithPr
 
 // Provider is a recorder.Provider that records events to the k8s API server
 // and to a logr Logger.
+//
+// The events of objects in another kcp logical cluster than the one of the
+// provider are created in the logical cluster of their object, through a
+// broadcaster of that cluster, which is only created when the first of its
+// events is emitted, and is shut down by ReleaseCluster.
 type Provider struct {
 	lock    sync.RWMutex
 	stopped bool
@@ -50,6 +59,25 @@ type Provider struct {
 	broadcasterOnce sync.Once
 	broadcaster     record.EventBroadcaster
 	stopBroadcaster bool
+
+	// config and cluster are the config of the provider and the logical cluster
+	// it targets, they are used to create the sinks of the other logical clusters.
+	config  *rest.Config
+	cluster logicalcluster.Name
+
+	clustersLock    sync.Mutex
+	clusters        map[logicalcluster.Name]*clusterRecording
+	clustersStopped bool
+}
+
+// clusterRecording records the events of a logical cluster.
+type clusterRecording struct {
+	// broadcaster is started with the first event of the cluster.
+	broadcaster record.EventBroadcaster
+	// recorders are the recorders of the broadcaster, by component name.
+	recorders map[string]record.EventRecorder
+	// targeting are the recorders returned by ForCluster for the cluster, by component name.
+	targeting map[string]*lazyRecorder
 }
 
 // NB(directxman12): this manually implements Stop instead of Being a runnable because we need to
@@ -77,6 +105,18 @@ func (p *Provider) Stop(shutdownCtx context.Context) {
 			p.stopped = true
 			p.lock.Unlock()
 		}
+		// The broadcasters of the logical clusters are always owned by the provider.
+		p.lock.Lock()
+		p.clustersLock.Lock()
+		for _, recording := range p.clusters {
+			if recording.broadcaster != nil {
+				recording.broadcaster.Shutdown()
+			}
+		}
+		p.clusters = nil
+		p.clustersStopped = true
+		p.clustersLock.Unlock()
+		p.lock.Unlock()
 		close(doneCh)
 	}()
 
@@ -109,6 +149,94 @@ func (p *Provider) getBroadcaster() record.EventBroadcaster {
 	return p.broadcaster
 }
 
+// clusterRecording returns what records the events of the given logical cluster, or nil once
+// the provider is stopped.  p.clustersLock must be held.
+func (p *Provider) clusterRecording(cluster logicalcluster.Name) *clusterRecording {
+	if p.clusters == nil {
+		return nil
+	}
+	recording, ok := p.clusters[cluster]
+	if !ok {
+		recording = &clusterRecording{
+			recorders: map[string]record.EventRecorder{},
+			targeting: map[string]*lazyRecorder{},
+		}
+		p.clusters[cluster] = recording
+	}
+	return recording
+}
+
+// getClusterRecorder returns the recorder of the given component name recording the events of
+// the given logical cluster, starting the broadcaster of the cluster if needed.  It returns nil
+// once the provider is stopped.
+func (p *Provider) getClusterRecorder(cluster logicalcluster.Name, name string) (record.EventRecorder, error) {
+	p.clustersLock.Lock()
+	defer p.clustersLock.Unlock()
+	recording := p.clusterRecording(cluster)
+	if recording == nil {
+		return nil, nil
+	}
+	if rec, ok := recording.recorders[name]; ok {
+		return rec, nil
+	}
+
+	if recording.broadcaster == nil {
+		config := rest.CopyConfig(p.config)
+		config.Host = clusterhost.Server(config.Host) + cluster.Path()
+		corev1Client, err := corev1client.NewForConfig(config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to init client for cluster %s: %w", cluster, err)
+		}
+
+		broadcaster := record.NewBroadcaster()
+		broadcaster.StartRecordingToSink(&corev1client.EventSinkImpl{Interface: corev1Client.Events("")})
+		broadcaster.StartEventWatcher(
+			func(e *corev1.Event) {
+				p.logger.V(1).Info(e.Type, "cluster", cluster.String(), "object", e.InvolvedObject, "reason", e.Reason, "message", e.Message)
+			})
+		recording.broadcaster = broadcaster
+	}
+	rec := recording.broadcaster.NewRecorder(p.scheme, corev1.EventSource{Component: name})
+	recording.recorders[name] = rec
+	return rec, nil
+}
+
+// forCluster returns the recorder of the given component name creating the events in the given
+// logical cluster, the same one for each call until the cluster is released.
+func (p *Provider) forCluster(cluster logicalcluster.Name, name string) *lazyRecorder {
+	p.clustersLock.Lock()
+	defer p.clustersLock.Unlock()
+	recording := p.clusterRecording(cluster)
+	if recording == nil {
+		return &lazyRecorder{prov: p, name: name, cluster: cluster}
+	}
+	rec, ok := recording.targeting[name]
+	if !ok {
+		rec = &lazyRecorder{prov: p, name: name, cluster: cluster}
+		recording.targeting[name] = rec
+	}
+	return rec
+}
+
+// ReleaseCluster shuts the broadcaster of the logical cluster down, if it was started, and
+// forgets its recorders, e.g. once the cluster is removed from a ClusterSet, so that the
+// provider doesn't hold them for the clusters which are deleted.  The events of the cluster
+// emitted afterwards, if any, start a new broadcaster.
+func (p *Provider) ReleaseCluster(cluster logicalcluster.Name) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.clustersLock.Lock()
+	defer p.clustersLock.Unlock()
+	recording, ok := p.clusters[cluster]
+	if !ok {
+		return
+	}
+	delete(p.clusters, cluster)
+	if recording.broadcaster != nil {
+		recording.broadcaster.Shutdown()
+	}
+}
+
 // NewProvider create a new Provider instance.
 func NewProvider(config *rest.Config, scheme *runtime.Scheme, logger logr.Logger, makeBroadcaster EventBroadcasterProducer) (*Provider, error) {
 	corev1Client, err := corev1client.NewForConfig(config)
@@ -116,7 +244,15 @@ func NewProvider(config *rest.Config, scheme *runtime.Scheme, logger logr.Logger
 		return nil, fmt.Errorf("failed to init client: %w", err)
 	}
 
-	p := &Provider{scheme: scheme, logger: logger, makeBroadcaster: makeBroadcaster, evtClient: corev1Client.Events("")}
+	p := &Provider{
+		scheme:          scheme,
+		logger:          logger,
+		makeBroadcaster: makeBroadcaster,
+		evtClient:       corev1Client.Events(""),
+		config:          rest.CopyConfig(config),
+		cluster:         clusterhost.Cluster(config.Host),
+		clusters:        map[logicalcluster.Name]*clusterRecording{},
+	}
 	return p, nil
 }
 
@@ -135,6 +271,10 @@ type lazyRecorder struct {
 	prov *Provider
 	name string
 
+	// cluster is the logical cluster to create the events in, whatever the cluster
+	// of their objects.  If empty, the cluster of the objects is used.
+	cluster logicalcluster.Name
+
 	recOnce sync.Once
 	rec     record.EventRecorder
 }
@@ -147,30 +287,69 @@ func (l *lazyRecorder) ensureRecording() {
 	})
 }
 
-func (l *lazyRecorder) Event(object runtime.Object, eventtype, reason, message string) {
-	l.ensureRecording()
+// recorderFor returns the concrete recorder for the events of the object, according to the
+// logical cluster they must be created in, and whether it is the recorder of another logical
+// cluster than the one of the provider.  l.prov.lock must be held while the recorder is used,
+// so that its cluster isn't released meanwhile.
+func (l *lazyRecorder) recorderFor(object runtime.Object) (record.EventRecorder, bool) {
+	cluster := l.cluster
+	if cluster.Empty() {
+		if accessor, err := meta.Accessor(object); err == nil {
+			cluster = logicalcluster.From(accessor)
+		}
+	}
+	if cluster.Empty() || cluster == logicalcluster.Wildcard || cluster == l.prov.cluster {
+		l.ensureRecording()
+		return l.rec, false
+	}
+
+	rec, err := l.prov.getClusterRecorder(cluster, l.name)
+	if err != nil {
+		l.prov.logger.Error(err, "Failed to record the events of the logical cluster", "cluster", cluster.String())
+		return nil, true
+	}
+	return rec, true
+}
+
+// canRecord returns whether the recorder can still be used.  l.prov.lock must be held.
+func (l *lazyRecorder) canRecord(rec record.EventRecorder, clusterRec bool) bool {
+	if rec == nil {
+		return false
+	}
+	if clusterRec {
+		return !l.prov.clustersStopped
+	}
+	return !l.prov.stopped
+}
+
+// ForCluster returns a recorder with the same name, which creates the events in the
+// given logical cluster, whatever the cluster of their objects.  The recorder is shared
+// by the recorders of the same name, until the cluster is released.
+func (l *lazyRecorder) ForCluster(cluster logicalcluster.Name) record.EventRecorder {
+	return l.prov.forCluster(cluster, l.name)
+}
 
+func (l *lazyRecorder) Event(object runtime.Object, eventtype, reason, message string) {
 	l.prov.lock.RLock()
-	if !l.prov.stopped {
-		l.rec.Event(object, eventtype, reason, message)
+	rec, clusterRec := l.recorderFor(object)
+	if l.canRecord(rec, clusterRec) {
+		rec.Event(object, eventtype, reason, message)
 	}
 	l.prov.lock.RUnlock()
 }
 func (l *lazyRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
-	l.ensureRecording()
-
 	l.prov.lock.RLock()
-	if !l.prov.stopped {
-		l.rec.Eventf(object, eventtype, reason, messageFmt, args...)
+	rec, clusterRec := l.recorderFor(object)
+	if l.canRecord(rec, clusterRec) {
+		rec.Eventf(object, eventtype, reason, messageFmt, args...)
 	}
 	l.prov.lock.RUnlock()
 }
 func (l *lazyRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
-	l.ensureRecording()
-
 	l.prov.lock.RLock()
-	if !l.prov.stopped {
-		l.rec.AnnotatedEventf(object, annotations, eventtype, reason, messageFmt, args...)
+	rec, clusterRec := l.recorderFor(object)
+	if l.canRecord(rec, clusterRec) {
+		rec.AnnotatedEventf(object, annotations, eventtype, reason, messageFmt, args...)
 	}
 	l.prov.lock.RUnlock()
 }
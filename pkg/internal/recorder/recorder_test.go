@@ -17,12 +17,23 @@ limitations under the License.
 package recorder_test
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
 	"github.com/go-logr/logr"
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/internal/recorder"
+	pubrecorder "sigs.k8s.io/controller-runtime/pkg/recorder"
 )
 
 var _ = Describe("recorder.Provider", func() {
@@ -52,4 +63,75 @@ var _ = Describe("recorder.Provider", func() {
 			Expect(recorder).NotTo(BeNil())
 		})
 	})
+
+	Describe("GetEventRecorder for objects of logical clusters", func() {
+		var (
+			server   *httptest.Server
+			provider *recorder.Provider
+			pathsMu  sync.Mutex
+			paths    []string
+		)
+		eventPaths := func() []string {
+			pathsMu.Lock()
+			defer pathsMu.Unlock()
+			return append([]string(nil), paths...)
+		}
+
+		BeforeEach(func() {
+			paths = nil
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				pathsMu.Lock()
+				paths = append(paths, r.URL.Path)
+				pathsMu.Unlock()
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte(`{"kind":"Event","apiVersion":"v1","metadata":{"name":"event"}}`))
+			}))
+
+			var err error
+			provider, err = recorder.NewProvider(&rest.Config{Host: server.URL + "/clusters/root:org"}, scheme.Scheme, logr.Discard(), makeBroadcaster)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			provider.Stop(context.Background())
+			server.Close()
+		})
+
+		It("should create the events in the logical cluster of the object.", func() {
+			rec := provider.GetEventRecorderFor("test")
+			rec.Event(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns", ClusterName: "root:a"}}, corev1.EventTypeNormal, "Test", "in root:a")
+			rec.Event(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"}}, corev1.EventTypeNormal, "Test", "in root:org")
+
+			Eventually(eventPaths).Should(ConsistOf(
+				"/clusters/root:a/api/v1/namespaces/ns/events",
+				"/clusters/root:org/api/v1/namespaces/ns/events",
+			))
+		})
+
+		It("should create the events in the logical cluster of the context.", func() {
+			rec := provider.GetEventRecorderFor("test")
+			ctx := kcpclient.WithCluster(context.Background(), logicalcluster.New("root:b"))
+			pubrecorder.ForContext(ctx, rec).Event(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"}}, corev1.EventTypeNormal, "Test", "in root:b")
+
+			Eventually(eventPaths).Should(ConsistOf("/clusters/root:b/api/v1/namespaces/ns/events"))
+		})
+
+		It("should return the same recorder for a logical cluster until it is released.", func() {
+			rec := provider.GetEventRecorderFor("test").(pubrecorder.ClusterAwareRecorder)
+			inB := rec.ForCluster(logicalcluster.New("root:b"))
+			Expect(rec.ForCluster(logicalcluster.New("root:b"))).To(BeIdenticalTo(inB))
+			Expect(rec.ForCluster(logicalcluster.New("root:c"))).NotTo(BeIdenticalTo(inB))
+			inB.Event(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"}}, corev1.EventTypeNormal, "Test", "in root:b")
+			Eventually(eventPaths).Should(ConsistOf("/clusters/root:b/api/v1/namespaces/ns/events"))
+
+			provider.ReleaseCluster(logicalcluster.New("root:b"))
+			Expect(rec.ForCluster(logicalcluster.New("root:b"))).NotTo(BeIdenticalTo(inB))
+
+			By("recording the events of a released cluster with a new broadcaster")
+			inB.Event(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"}}, corev1.EventTypeNormal, "Test", "in root:b again")
+			Eventually(eventPaths).Should(HaveLen(2))
+			Expect(eventPaths()[1]).To(Equal("/clusters/root:b/api/v1/namespaces/ns/events"))
+		})
+	})
 })
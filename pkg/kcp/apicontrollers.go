@@ -0,0 +1,312 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kcp
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/kcp-dev/logicalcluster"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	toolscache "k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/internal/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+var apiControllersLog = logf.RuntimeLog.WithName("api-controllers")
+
+var apisv1alpha1APIBinding = schema.GroupVersionKind{Group: "apis.kcp.dev", Version: "v1alpha1", Kind: "APIBinding"}
+
+// BoundAPI is a kind served in a logical cluster.
+type BoundAPI struct {
+	Cluster          logicalcluster.Name
+	GroupVersionKind schema.GroupVersionKind
+}
+
+// APIControllerFactory returns the controller of a kind served in a logical cluster, e.g. a
+// controller built with the builder package against a cache and a client targeting the
+// logical cluster.  The controller is started with a context which is done once the kind
+// isn't served anymore, or once the APIControllers is stopped.
+type APIControllerFactory func(ctx context.Context, api BoundAPI) (manager.Runnable, error)
+
+// APIControllers runs a controller per kind served in each logical cluster, for generic
+// operators which handle kinds they don't know in advance.  It watches the
+// CustomResourceDefinitions of all the logical clusters, and their APIBindings if Mapper is
+// set, and creates a controller with NewController for every kind they serve.  The
+// controller of a kind is stopped once its CRD is deleted or its APIBinding is unbound.
+//
+// An APIControllers is a Runnable which needs leader election, like the controllers it runs,
+// so that it can be added to a manager.
+type APIControllers struct {
+	// Informers are the informers of all the logical clusters, typically the cache of a
+	// manager built with NewClusterAwareManager.  They must be started by their owner.
+	Informers cache.Informers
+
+	// NewController creates the controller of a kind served in a logical cluster.
+	NewController APIControllerFactory
+
+	// Mapper resolves the kinds of the resources bound by APIBindings.  APIBindings are
+	// ignored if it's nil.
+	Mapper *apiutil.MultiClusterRESTMapper
+
+	// Predicate selects the kinds which get a controller, it defaults to all of them.
+	Predicate func(api BoundAPI) bool
+
+	mu      sync.Mutex
+	ctx     context.Context
+	stopped bool
+	// apis are the kinds served in each logical cluster, by the key of the object serving them.
+	apis map[logicalcluster.Name]map[string][]schema.GroupVersionKind
+	// running are the controllers which are running, by kind.
+	running map[BoundAPI]*apiController
+	wg      sync.WaitGroup
+}
+
+// apiController is a running controller of an APIControllers.
+type apiController struct {
+	cancel context.CancelFunc
+}
+
+// Start watches the APIs of the logical clusters and runs their controllers until the
+// context is done.  It then stops all of them and returns once they are stopped.
+func (c *APIControllers) Start(ctx context.Context) error {
+	if c.Informers == nil || c.NewController == nil {
+		return errors.New("must specify Informers and NewController")
+	}
+
+	c.mu.Lock()
+	if c.ctx != nil {
+		c.mu.Unlock()
+		return errors.New("api controllers were started more than once")
+	}
+	c.ctx = ctx
+	c.apis = map[logicalcluster.Name]map[string][]schema.GroupVersionKind{}
+	c.running = map[BoundAPI]*apiController{}
+	c.mu.Unlock()
+
+	if err := c.watch(ctx, apiextensionsv1CRD, crdKinds); err != nil {
+		return err
+	}
+	if c.Mapper != nil {
+		if err := c.watch(ctx, apisv1alpha1APIBinding, c.apiBindingKinds); err != nil {
+			return err
+		}
+	}
+
+	<-ctx.Done()
+	c.mu.Lock()
+	c.stopped = true
+	c.mu.Unlock()
+	c.wg.Wait()
+	return nil
+}
+
+// Running returns the kinds the controllers of which are running, sorted.
+func (c *APIControllers) Running() []BoundAPI {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	apis := make([]BoundAPI, 0, len(c.running))
+	for api := range c.running {
+		apis = append(apis, api)
+	}
+	sort.Slice(apis, func(i, j int) bool {
+		if apis[i].Cluster != apis[j].Cluster {
+			return apis[i].Cluster.String() < apis[j].Cluster.String()
+		}
+		return apis[i].GroupVersionKind.String() < apis[j].GroupVersionKind.String()
+	})
+	return apis
+}
+
+// watch updates the kinds served in the logical clusters with the objects of the given kind.
+func (c *APIControllers) watch(ctx context.Context, gvk schema.GroupVersionKind, kinds func(*unstructured.Unstructured) []schema.GroupVersionKind) error {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	informer, err := c.Informers.GetInformer(ctx, obj)
+	if err != nil {
+		return err
+	}
+
+	update := func(obj interface{}, deleted bool) {
+		if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+			obj, deleted = tombstone.Obj, true
+		}
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			return
+		}
+		cluster := logicalcluster.From(u)
+		if cluster.Empty() {
+			return
+		}
+		var served []schema.GroupVersionKind
+		if !deleted {
+			served = kinds(u)
+		}
+		c.setAPIs(cluster, gvk.Kind+"/"+u.GetName(), served)
+	}
+	informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { update(obj, false) },
+		UpdateFunc: func(_, newObj interface{}) { update(newObj, false) },
+		DeleteFunc: func(obj interface{}) { update(obj, true) },
+	})
+	return nil
+}
+
+// setAPIs records the kinds served by an object of the logical cluster, and starts and stops
+// the controllers of the logical cluster accordingly.
+func (c *APIControllers) setAPIs(cluster logicalcluster.Name, key string, served []schema.GroupVersionKind) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopped {
+		return
+	}
+
+	if len(served) == 0 {
+		delete(c.apis[cluster], key)
+	} else {
+		if c.apis[cluster] == nil {
+			c.apis[cluster] = map[string][]schema.GroupVersionKind{}
+		}
+		c.apis[cluster][key] = served
+	}
+
+	wanted := map[BoundAPI]struct{}{}
+	for _, gvks := range c.apis[cluster] {
+		for _, gvk := range gvks {
+			api := BoundAPI{Cluster: cluster, GroupVersionKind: gvk}
+			if c.Predicate == nil || c.Predicate(api) {
+				wanted[api] = struct{}{}
+			}
+		}
+	}
+	for api, ctrl := range c.running {
+		if _, ok := wanted[api]; !ok && api.Cluster == cluster {
+			apiControllersLog.Info("Stopping controller", "cluster", cluster.String(), "kind", api.GroupVersionKind.String())
+			ctrl.cancel()
+			delete(c.running, api)
+		}
+	}
+	for api := range wanted {
+		if _, ok := c.running[api]; !ok {
+			c.start(api)
+		}
+	}
+}
+
+// start creates and starts the controller of the kind.  c.mu must be held.
+func (c *APIControllers) start(api BoundAPI) {
+	ctx, cancel := context.WithCancel(c.ctx)
+	ctrl, err := c.NewController(ctx, api)
+	if err != nil {
+		cancel()
+		apiControllersLog.Error(err, "Failed to create controller", "cluster", api.Cluster.String(), "kind", api.GroupVersionKind.String())
+		return
+	}
+	apiControllersLog.Info("Starting controller", "cluster", api.Cluster.String(), "kind", api.GroupVersionKind.String())
+	c.running[api] = &apiController{cancel: cancel}
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		if err := ctrl.Start(ctx); err != nil {
+			apiControllersLog.Error(err, "Controller stopped with an error", "cluster", api.Cluster.String(), "kind", api.GroupVersionKind.String())
+		}
+	}()
+}
+
+// crdKinds returns the kind served by an established CRD, in its storage version if it's served,
+// or else in its first served version.
+func crdKinds(crd *unstructured.Unstructured) []schema.GroupVersionKind {
+	if !hasCondition(crd, "Established") {
+		return nil
+	}
+	group, _, _ := unstructured.NestedString(crd.Object, "spec", "group")
+	kind, _, _ := unstructured.NestedString(crd.Object, "spec", "names", "kind")
+	versions, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+	var version string
+	for _, v := range versions {
+		v, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(v, "name")
+		served, _, _ := unstructured.NestedBool(v, "served")
+		storage, _, _ := unstructured.NestedBool(v, "storage")
+		if !served {
+			continue
+		}
+		if version == "" || storage {
+			version = name
+		}
+	}
+	if group == "" || kind == "" || version == "" {
+		return nil
+	}
+	return []schema.GroupVersionKind{{Group: group, Version: version, Kind: kind}}
+}
+
+// apiBindingKinds returns the kinds of the resources bound by a bound APIBinding, resolved with
+// the RESTMapper of its logical cluster.
+func (c *APIControllers) apiBindingKinds(binding *unstructured.Unstructured) []schema.GroupVersionKind {
+	if phase, _, _ := unstructured.NestedString(binding.Object, "status", "phase"); phase != "Bound" {
+		return nil
+	}
+	// the RESTMapper of the logical cluster discovers its kinds again if it doesn't know
+	// the bound resources yet.
+	cluster := logicalcluster.From(binding)
+	mapper, err := c.Mapper.ForCluster(cluster)
+	if err != nil {
+		apiControllersLog.Error(err, "Failed to get the RESTMapper", "cluster", cluster.String())
+		return nil
+	}
+
+	resources, _, _ := unstructured.NestedSlice(binding.Object, "status", "boundResources")
+	var kinds []schema.GroupVersionKind
+	for _, r := range resources {
+		r, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		group, _, _ := unstructured.NestedString(r, "group")
+		resource, _, _ := unstructured.NestedString(r, "resource")
+		gvk, err := mapper.KindFor(schema.GroupVersionResource{Group: group, Resource: resource})
+		if err != nil {
+			apiControllersLog.Error(err, "Failed to resolve the kind of a bound resource", "cluster", cluster.String(), "apiBinding", binding.GetName(), "resource", resource)
+			continue
+		}
+		kinds = append(kinds, gvk)
+	}
+	return kinds
+}
+
+// hasCondition returns whether the object has a condition of the given type with a True status.
+func hasCondition(u *unstructured.Unstructured, conditionType string) bool {
+	conditions, _, _ := unstructured.NestedSlice(u.Object, "status", "conditions")
+	for _, c := range conditions {
+		c, ok := c.(map[string]interface{})
+		if ok && c["type"] == conditionType && c["status"] == "True" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kcp_test
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	toolscache "k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllertest"
+	"sigs.k8s.io/controller-runtime/pkg/kcp"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// signalingInformers signals when handlers are added to its informers.
+type signalingInformers struct {
+	*informertest.FakeInformers
+	added chan struct{}
+}
+
+func (i signalingInformers) GetInformer(ctx context.Context, obj client.Object) (cache.Informer, error) {
+	informer, err := i.FakeInformers.GetInformer(ctx, obj)
+	return signalingInformer{Informer: informer, added: i.added}, err
+}
+
+type signalingInformer struct {
+	cache.Informer
+	added chan struct{}
+}
+
+func (i signalingInformer) AddEventHandler(handler toolscache.ResourceEventHandler) {
+	i.Informer.AddEventHandler(handler)
+	i.added <- struct{}{}
+}
+
+var _ = Describe("kcp.APIControllers", func() {
+	a := logicalcluster.New("root:a")
+	b := logicalcluster.New("root:b")
+	widgets := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}
+	gadgets := schema.GroupVersionKind{Group: "example.com", Version: "v1beta1", Kind: "Gadget"}
+
+	var crds, bindings *controllertest.FakeInformer
+	var controllers *kcp.APIControllers
+	var mu sync.Mutex
+	var stopped []kcp.BoundAPI
+	var cancel context.CancelFunc
+	var done chan struct{}
+
+	newObject := func(gvk schema.GroupVersionKind, cluster logicalcluster.Name, name string, content map[string]interface{}) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{Object: content}
+		u.SetGroupVersionKind(gvk)
+		u.SetName(name)
+		u.SetClusterName(cluster.String())
+		return u
+	}
+	crd := func(cluster logicalcluster.Name, established bool) *unstructured.Unstructured {
+		status := "False"
+		if established {
+			status = "True"
+		}
+		return newObject(schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"}, cluster, "widgets.example.com", map[string]interface{}{
+			"spec": map[string]interface{}{
+				"group": "example.com",
+				"names": map[string]interface{}{"kind": "Widget"},
+				"versions": []interface{}{
+					map[string]interface{}{"name": "v1alpha1", "served": true},
+					map[string]interface{}{"name": "v1", "served": true, "storage": true},
+				},
+			},
+			"status": map[string]interface{}{
+				"conditions": []interface{}{map[string]interface{}{"type": "Established", "status": status}},
+			},
+		})
+	}
+	binding := newObject(schema.GroupVersionKind{Group: "apis.kcp.dev", Version: "v1alpha1", Kind: "APIBinding"}, b, "gadgets", map[string]interface{}{
+		"status": map[string]interface{}{
+			"phase":          "Bound",
+			"boundResources": []interface{}{map[string]interface{}{"group": "example.com", "resource": "gadgets"}},
+		},
+	})
+
+	BeforeEach(func() {
+		stopped = nil
+		informers := &informertest.FakeInformers{Scheme: runtime.NewScheme()}
+		var err error
+		crds, err = informers.FakeInformerFor(crd(a, true))
+		Expect(err).NotTo(HaveOccurred())
+		bindings, err = informers.FakeInformerFor(binding)
+		Expect(err).NotTo(HaveOccurred())
+		added := make(chan struct{})
+		controllers = &kcp.APIControllers{
+			Informers: signalingInformers{FakeInformers: informers, added: added},
+			Mapper: apiutil.NewMultiClusterRESTMapperFor(func(logicalcluster.Name) (meta.RESTMapper, error) {
+				mapper := meta.NewDefaultRESTMapper(nil)
+				mapper.Add(gadgets, meta.RESTScopeNamespace)
+				return mapper, nil
+			}),
+			NewController: func(_ context.Context, api kcp.BoundAPI) (manager.Runnable, error) {
+				return manager.RunnableFunc(func(ctx context.Context) error {
+					<-ctx.Done()
+					mu.Lock()
+					defer mu.Unlock()
+					stopped = append(stopped, api)
+					return nil
+				}), nil
+			},
+		}
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		done = make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(controllers.Start(ctx)).To(Succeed())
+		}()
+		<-added
+		<-added
+	})
+
+	AfterEach(func() {
+		cancel()
+		Eventually(done).Should(BeClosed())
+	})
+
+	stoppedAPIs := func() []kcp.BoundAPI {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]kcp.BoundAPI(nil), stopped...)
+	}
+
+	It("should run a controller per kind served by the established CRDs of each logical cluster", func() {
+		crds.Add(crd(a, false))
+		Expect(controllers.Running()).To(BeEmpty())
+
+		crds.Update(crd(a, false), crd(a, true))
+		Expect(controllers.Running()).To(Equal([]kcp.BoundAPI{{Cluster: a, GroupVersionKind: widgets}}))
+
+		crds.Delete(crd(a, true))
+		Expect(controllers.Running()).To(BeEmpty())
+		Eventually(stoppedAPIs).Should(Equal([]kcp.BoundAPI{{Cluster: a, GroupVersionKind: widgets}}))
+	})
+
+	It("should run a controller per kind bound by the APIBindings of each logical cluster", func() {
+		bindings.Add(binding)
+		Expect(controllers.Running()).To(Equal([]kcp.BoundAPI{{Cluster: b, GroupVersionKind: gadgets}}))
+
+		cancel()
+		Eventually(done).Should(BeClosed())
+		Expect(stoppedAPIs()).To(Equal([]kcp.BoundAPI{{Cluster: b, GroupVersionKind: gadgets}}))
+	})
+})
@@ -0,0 +1,101 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kcp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+// APIExportResource is the resource of the APIExports of kcp.
+var APIExportResource = schema.GroupVersionResource{Group: "apis.kcp.dev", Version: "v1alpha1", Resource: "apiexports"}
+
+// APIExportPollInterval is the interval at which NewAPIExportConfig checks whether kcp
+// published the virtual workspace URLs of an APIExport.
+var APIExportPollInterval = time.Second
+
+// APIExportVirtualWorkspaceURLs returns the URLs of the virtual workspaces of the APIExport
+// with the given name, as published by kcp in its status: one per shard serving it.  The
+// config must target the workspace of the APIExport.  It waits for kcp to publish at least
+// one URL, and for the API server to be reachable, until the context is done.
+func APIExportVirtualWorkspaceURLs(ctx context.Context, config *rest.Config, name string) ([]string, error) {
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	var urls []string
+	err = wait.PollImmediateUntil(APIExportPollInterval, func() (bool, error) {
+		export, err := client.Resource(APIExportResource).Get(ctx, name, metav1.GetOptions{})
+		if _, isStatus := err.(apierrors.APIStatus); err != nil && !isStatus {
+			// e.g. the API server is unreachable, or the context is about to be done.
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to get APIExport %q: %w", name, err)
+		}
+		workspaces, _, err := unstructured.NestedSlice(export.Object, "status", "virtualWorkspaces")
+		if err != nil {
+			return false, fmt.Errorf("invalid virtual workspaces of APIExport %q: %w", name, err)
+		}
+		urls = urls[:0]
+		for _, workspace := range workspaces {
+			workspace, ok := workspace.(map[string]interface{})
+			if !ok {
+				return false, fmt.Errorf("invalid virtual workspace of APIExport %q: %v", name, workspace)
+			}
+			if url, _, _ := unstructured.NestedString(workspace, "url"); url != "" {
+				urls = append(urls, url)
+			}
+		}
+		return len(urls) > 0, nil
+	}, ctx.Done())
+	if err == wait.ErrWaitTimeout {
+		return nil, fmt.Errorf("APIExport %q has no virtual workspace URL: %w", name, ctx.Err())
+	}
+	return urls, err
+}
+
+// NewAPIExportConfig returns a copy of the config targeting the virtual workspace of the APIExport
+// with the given name, which serves the objects of its APIs in all the workspaces binding it.  Use
+// it with NewClusterAwareManager, or with NewClusterAwareCache and NewClusterAwareClient, to run
+// controllers against the workspaces of the consumers of the APIExport.
+//
+// The config must target the workspace of the APIExport, e.g. https://kcp.example.com/clusters/root:org:ws.
+// NewAPIExportConfig waits for kcp to publish the URL of the virtual workspace in the status of the
+// APIExport, until the context is done.  It fails if the APIExport is served by several shards: use
+// APIExportVirtualWorkspaceURLs to build a config per shard then.
+func NewAPIExportConfig(ctx context.Context, config *rest.Config, name string) (*rest.Config, error) {
+	urls, err := APIExportVirtualWorkspaceURLs(ctx, config, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(urls) > 1 {
+		return nil, fmt.Errorf("APIExport %q is served by %d virtual workspaces: %v", name, len(urls), urls)
+	}
+	config = rest.CopyConfig(config)
+	config.Host = urls[0]
+	return config, nil
+}
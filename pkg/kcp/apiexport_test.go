@@ -0,0 +1,99 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kcp_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/controller-runtime/pkg/kcp"
+)
+
+var _ = Describe("APIExport configs", func() {
+	var server *httptest.Server
+	var config *rest.Config
+	// urls are the virtual workspace URLs published in the status of the APIExport once it was
+	// read published times.
+	var urls []string
+	var published int32
+	var reads int32
+
+	BeforeEach(func() {
+		urls = []string{"https://shard-1.example.com/services/apiexport/root:org:ws/widgets"}
+		published, reads = 0, 0
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/clusters/root:org:ws/apis/apis.kcp.dev/v1alpha1/apiexports/widgets" {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`)
+				return
+			}
+			workspaces := ""
+			if atomic.AddInt32(&reads, 1) > atomic.LoadInt32(&published) {
+				for i, url := range urls {
+					if i > 0 {
+						workspaces += ","
+					}
+					workspaces += fmt.Sprintf(`{"url":%q}`, url)
+				}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"kind":"APIExport","apiVersion":"apis.kcp.dev/v1alpha1","metadata":{"name":"widgets"},"status":{"virtualWorkspaces":[%s]}}`, workspaces)
+		}))
+		config = &rest.Config{Host: server.URL + "/clusters/root:org:ws", BearerToken: "token"}
+		kcp.APIExportPollInterval = 10 * time.Millisecond
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should target the virtual workspace of the APIExport, once published", func() {
+		published = 2
+		exportConfig, err := kcp.NewAPIExportConfig(context.Background(), config, "widgets")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exportConfig.Host).To(Equal("https://shard-1.example.com/services/apiexport/root:org:ws/widgets"))
+		Expect(exportConfig.BearerToken).To(Equal("token"))
+		Expect(config.Host).To(Equal(server.URL + "/clusters/root:org:ws"))
+		Expect(atomic.LoadInt32(&reads)).To(BeNumerically("==", 3))
+	})
+
+	It("should return the virtual workspaces of all the shards, and fail to build a single config for them", func() {
+		urls = append(urls, "https://shard-2.example.com/services/apiexport/root:org:ws/widgets")
+		Expect(kcp.APIExportVirtualWorkspaceURLs(context.Background(), config, "widgets")).To(HaveLen(2))
+		_, err := kcp.NewAPIExportConfig(context.Background(), config, "widgets")
+		Expect(err).To(MatchError(ContainSubstring("is served by 2 virtual workspaces")))
+	})
+
+	It("should fail if the APIExport doesn't exist, or isn't published before the context is done", func() {
+		_, err := kcp.NewAPIExportConfig(context.Background(), config, "gadgets")
+		Expect(err).To(MatchError(ContainSubstring(`failed to get APIExport "gadgets"`)))
+
+		published = 1000
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		_, err = kcp.NewAPIExportConfig(ctx, config, "widgets")
+		Expect(err).To(MatchError(ContainSubstring(`APIExport "widgets" has no virtual workspace URL`)))
+	})
+})
@@ -0,0 +1,297 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	logf "sigs.k8s.io/controller-runtime/pkg/internal/log"
+)
+
+var bootstrapLog = logf.RuntimeLog.WithName("bootstrap")
+
+// DefaultBootstrapRetryPeriod is how often the bootstrap of a logical cluster is retried by
+// default until it succeeds.
+const DefaultBootstrapRetryPeriod = 5 * time.Second
+
+// Bootstrap ensures that the objects the controllers require, e.g. the APIBindings binding
+// their APIs or their CRDs, exist and are ready in each logical cluster of a cluster.ClusterSet,
+// and holds the requests of each logical cluster until they do.  It only holds the requests of
+// the logical clusters which aren't bootstrapped yet, the other ones are reconciled meanwhile.
+//
+// A Bootstrap is a cluster.ClusterSetHandler, bootstrapping the clusters as they are added to
+// the set, and a cluster.SyncGate for the SyncGate option of the controllers.  It is a Runnable
+// which doesn't need leader election, so that it can be added to a manager, and a
+// healthz.ClusterStatuser reporting which clusters are bootstrapped, here combined with the
+// Gate of the set:
+//
+//	bootstrap := kcp.NewBootstrap(true, binding)
+//	set.AddHandler(bootstrap)
+//	if err := mgr.Add(bootstrap); err != nil {
+//		...
+//	}
+//	ctrl, err := controller.New("widgets", mgr, controller.Options{
+//		Reconciler: r,
+//		SyncGate:   cluster.SyncGates{gate, bootstrap},
+//	})
+type Bootstrap struct {
+	// Objects are the objects required in each logical cluster.  They are read by key with
+	// the API reader of the cluster, so their type must be known to the scheme of the cluster,
+	// or they must be unstructured objects with their GroupVersionKind set.
+	Objects []client.Object
+
+	// Create creates the required objects which don't exist, rather than waiting for them
+	// to be created, e.g. by an administrator.  An object created concurrently, e.g. by
+	// another replica, isn't an error.
+	Create bool
+
+	// Ready returns whether a required object is ready to be used by the controllers.  It
+	// defaults to BootstrapObjectReady.
+	Ready func(obj client.Object) bool
+
+	// RetryPeriod is how often the bootstrap of a logical cluster is retried until it succeeds,
+	// it defaults to DefaultBootstrapRetryPeriod.
+	RetryPeriod time.Duration
+
+	gate *cluster.Gate
+
+	mu       sync.Mutex
+	ctx      context.Context
+	clusters map[logicalcluster.Name]*bootstrapRun
+}
+
+// bootstrapRun is the bootstrap of a logical cluster.
+type bootstrapRun struct {
+	cluster cluster.Cluster
+
+	// cancel stops the bootstrap, it is nil until the bootstrap is started.
+	cancel context.CancelFunc
+
+	// done is whether the cluster is bootstrapped, and err the error of the last attempt
+	// otherwise.  They are guarded by the mutex of the Bootstrap.
+	done bool
+	err  error
+}
+
+var (
+	_ cluster.ClusterSetHandler = &Bootstrap{}
+	_ cluster.SyncGate          = &Bootstrap{}
+	_ healthz.ClusterStatuser   = &Bootstrap{}
+)
+
+// NewBootstrap returns a Bootstrap requiring the given objects, creating them if create is true.
+func NewBootstrap(create bool, objects ...client.Object) *Bootstrap {
+	return &Bootstrap{
+		Objects:  objects,
+		Create:   create,
+		gate:     cluster.NewGate(),
+		clusters: map[logicalcluster.Name]*bootstrapRun{},
+	}
+}
+
+// ClusterAdded implements cluster.ClusterSetHandler.  It holds the requests of the logical
+// cluster, and bootstraps it once the Bootstrap is started.
+func (b *Bootstrap) ClusterAdded(name logicalcluster.Name, cl cluster.Cluster) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.clusters[name]; ok {
+		return
+	}
+	run := &bootstrapRun{cluster: cl}
+	b.clusters[name] = run
+	b.gate.Hold(name)
+	if b.ctx != nil {
+		b.start(name, run)
+	}
+}
+
+// ClusterRemoved implements cluster.ClusterSetHandler.  It stops bootstrapping the logical
+// cluster, and releases its requests.
+func (b *Bootstrap) ClusterRemoved(name logicalcluster.Name) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	run, ok := b.clusters[name]
+	if !ok {
+		return
+	}
+	delete(b.clusters, name)
+	if run.cancel != nil {
+		run.cancel()
+	}
+	b.gate.Release(name)
+}
+
+// Synced implements cluster.SyncGate.  The requests of a logical cluster are held from the time
+// it is added until it is bootstrapped.
+func (b *Bootstrap) Synced(name logicalcluster.Name) <-chan struct{} {
+	return b.gate.Synced(name)
+}
+
+// Bootstrapped returns whether the logical cluster is bootstrapped, and otherwise the error
+// of the last attempt, if any.
+func (b *Bootstrap) Bootstrapped(name logicalcluster.Name) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	run, ok := b.clusters[name]
+	if !ok {
+		return false, nil
+	}
+	return run.done, run.err
+}
+
+// ClusterStatuses implements healthz.ClusterStatuser.  A logical cluster is reported as synced
+// once it is bootstrapped, with the error of the last attempt otherwise.
+func (b *Bootstrap) ClusterStatuses() []healthz.ClusterStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	statuses := make([]healthz.ClusterStatus, 0, len(b.clusters))
+	for name, run := range b.clusters {
+		status := healthz.ClusterStatus{Cluster: name, Synced: run.done}
+		if run.err != nil {
+			status.Error = run.err.Error()
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Cluster.String() < statuses[j].Cluster.String() })
+	return statuses
+}
+
+// Start implements manager.Runnable.  It bootstraps the logical clusters until the context is
+// done.
+func (b *Bootstrap) Start(ctx context.Context) error {
+	b.mu.Lock()
+	if b.ctx != nil {
+		b.mu.Unlock()
+		return errors.New("the bootstrap was started more than once")
+	}
+	b.ctx = ctx
+	for name, run := range b.clusters {
+		b.start(name, run)
+	}
+	b.mu.Unlock()
+
+	<-ctx.Done()
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+func (b *Bootstrap) NeedLeaderElection() bool {
+	return false
+}
+
+// start bootstraps the logical cluster until it succeeds, the Bootstrap is stopped or the
+// cluster is removed.  The mutex must be held.
+func (b *Bootstrap) start(name logicalcluster.Name, run *bootstrapRun) {
+	ctx, cancel := context.WithCancel(b.ctx)
+	run.cancel = cancel
+	period := b.RetryPeriod
+	if period <= 0 {
+		period = DefaultBootstrapRetryPeriod
+	}
+	log := bootstrapLog.WithValues("cluster", name.String())
+	go func() {
+		_ = wait.PollImmediateUntil(period, func() (bool, error) {
+			err := b.bootstrap(ctx, run.cluster)
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if b.clusters[name] != run {
+				// the cluster was removed, and maybe added again, since.
+				return true, nil
+			}
+			run.err = err
+			if err != nil {
+				log.V(1).Info("Cluster not bootstrapped yet", "reason", err.Error())
+				return false, nil
+			}
+			run.done = true
+			b.gate.Release(name)
+			log.Info("Cluster bootstrapped")
+			return true, nil
+		}, ctx.Done())
+	}()
+}
+
+// bootstrap ensures that the required objects exist and are ready in the cluster.
+func (b *Bootstrap) bootstrap(ctx context.Context, cl cluster.Cluster) error {
+	ready := b.Ready
+	if ready == nil {
+		ready = BootstrapObjectReady
+	}
+	for _, required := range b.Objects {
+		gvk, err := apiutil.GVKForObject(required, cl.GetScheme())
+		if err != nil {
+			return err
+		}
+		key := client.ObjectKeyFromObject(required)
+		obj := required.DeepCopyObject().(client.Object)
+		err = cl.GetAPIReader().Get(ctx, key, obj)
+		if apierrors.IsNotFound(err) && b.Create {
+			obj = required.DeepCopyObject().(client.Object)
+			obj.SetResourceVersion("")
+			err = cl.GetClient().Create(ctx, obj)
+			if apierrors.IsAlreadyExists(err) {
+				err = cl.GetAPIReader().Get(ctx, key, obj)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to ensure %s %s: %w", gvk.Kind, key.NamespacedName, err)
+		}
+		if !ready(obj) {
+			return fmt.Errorf("%s %s is not ready", gvk.Kind, key.NamespacedName)
+		}
+	}
+	return nil
+}
+
+// BootstrapObjectReady is the default Ready function of a Bootstrap.  APIBindings are ready once
+// they are bound, and CustomResourceDefinitions, typed or unstructured, once they are established.
+// The other objects are ready as soon as they exist.
+func BootstrapObjectReady(obj client.Object) bool {
+	switch obj := obj.(type) {
+	case *apiextensionsv1.CustomResourceDefinition:
+		for _, condition := range obj.Status.Conditions {
+			if condition.Type == apiextensionsv1.Established {
+				return condition.Status == apiextensionsv1.ConditionTrue
+			}
+		}
+		return false
+	case *unstructured.Unstructured:
+		switch obj.GroupVersionKind().GroupKind() {
+		case apisv1alpha1APIBinding.GroupKind():
+			phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+			return phase == "Bound"
+		case apiextensionsv1.SchemeGroupVersion.WithKind("CustomResourceDefinition").GroupKind():
+			return hasCondition(obj, string(apiextensionsv1.Established))
+		}
+	}
+	return true
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kcp_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/kcp"
+)
+
+// clientCluster is a cluster.Cluster reading and writing with a client.
+type clientCluster struct {
+	cluster.Cluster
+	client client.Client
+}
+
+func (c clientCluster) GetClient() client.Client    { return c.client }
+func (c clientCluster) GetAPIReader() client.Reader { return c.client }
+func (c clientCluster) GetScheme() *runtime.Scheme  { return c.client.Scheme() }
+
+var _ = Describe("Bootstrap", func() {
+	a, b := logicalcluster.New("root:a"), logicalcluster.New("root:b")
+	required := func() *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "required"}}
+	}
+
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	start := func(bootstrap *kcp.Bootstrap) {
+		ctx := ctx
+		go func() {
+			defer GinkgoRecover()
+			Expect(bootstrap.Start(ctx)).To(Succeed())
+		}()
+	}
+
+	It("should hold the requests of each cluster until its required objects exist", func() {
+		bootstrap := kcp.NewBootstrap(false, required())
+		bootstrap.RetryPeriod = 10 * time.Millisecond
+		inA := fake.NewClientBuilder().WithObjects(required()).Build()
+		inB := fake.NewClientBuilder().Build()
+		bootstrap.ClusterAdded(a, clientCluster{client: inA})
+		bootstrap.ClusterAdded(b, clientCluster{client: inB})
+		Expect(bootstrap.Synced(a)).NotTo(BeNil())
+		start(bootstrap)
+
+		Eventually(bootstrap.Synced(a)).Should(BeClosed())
+		Consistently(bootstrap.Synced(b), 50*time.Millisecond).ShouldNot(BeClosed())
+		done, err := bootstrap.Bootstrapped(b)
+		Expect(done).To(BeFalse())
+		Expect(err).To(MatchError(ContainSubstring("failed to ensure ConfigMap default/required")))
+		Expect(bootstrap.ClusterStatuses()).To(ConsistOf(
+			healthz.ClusterStatus{Cluster: a, Synced: true},
+			healthz.ClusterStatus{Cluster: b, Error: err.Error()},
+		))
+
+		Expect(inB.Create(ctx, required())).To(Succeed())
+		Eventually(bootstrap.Synced(b)).Should(BeClosed())
+		Expect(bootstrap.Synced(b)).To(BeNil())
+	})
+
+	It("should create the missing objects if asked to", func() {
+		bootstrap := kcp.NewBootstrap(true, required())
+		inA := fake.NewClientBuilder().Build()
+		bootstrap.ClusterAdded(a, clientCluster{client: inA})
+		start(bootstrap)
+
+		Eventually(bootstrap.Synced(a)).Should(BeClosed())
+		Expect(inA.Get(ctx, client.ObjectKeyFromObject(required()), &corev1.ConfigMap{})).To(Succeed())
+	})
+
+	It("should release the requests of the clusters removed before they are bootstrapped", func() {
+		bootstrap := kcp.NewBootstrap(false, required())
+		bootstrap.ClusterAdded(a, clientCluster{client: fake.NewClientBuilder().Build()})
+		start(bootstrap)
+		synced := bootstrap.Synced(a)
+		Expect(synced).NotTo(BeNil())
+
+		bootstrap.ClusterRemoved(a)
+		Expect(synced).To(BeClosed())
+		Expect(bootstrap.ClusterStatuses()).To(BeEmpty())
+	})
+
+	It("should combine with the other gates of the controllers", func() {
+		gate := cluster.NewGate()
+		bootstrap := kcp.NewBootstrap(false, required())
+		bootstrap.ClusterAdded(a, clientCluster{client: fake.NewClientBuilder().Build()})
+		gates := cluster.SyncGates{gate, bootstrap}
+		Expect(gates.Synced(a)).To(Equal(bootstrap.Synced(a)))
+		gate.Hold(a)
+		Expect(gates.Synced(a)).To(Equal(gate.Synced(a)))
+		Expect(gates.Synced(b)).To(BeNil())
+	})
+
+	It("should wait for the APIBindings to be bound and the CRDs to be established", func() {
+		binding := &unstructured.Unstructured{}
+		binding.SetAPIVersion("apis.kcp.dev/v1alpha1")
+		binding.SetKind("APIBinding")
+		Expect(kcp.BootstrapObjectReady(binding)).To(BeFalse())
+		Expect(unstructured.SetNestedField(binding.Object, "Bound", "status", "phase")).To(Succeed())
+		Expect(kcp.BootstrapObjectReady(binding)).To(BeTrue())
+
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		Expect(kcp.BootstrapObjectReady(crd)).To(BeFalse())
+		crd.Status.Conditions = []apiextensionsv1.CustomResourceDefinitionCondition{{Type: apiextensionsv1.Established, Status: apiextensionsv1.ConditionTrue}}
+		Expect(kcp.BootstrapObjectReady(crd)).To(BeTrue())
+
+		Expect(kcp.BootstrapObjectReady(required())).To(BeTrue())
+	})
+})
@@ -0,0 +1,208 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kcp
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/internal/log"
+)
+
+var wildcardCacheLog = logf.RuntimeLog.WithName("wildcard-cache")
+
+// ErrWildcardCacheDisabled is returned by the reads, informers and indexes of the cache built
+// by ClusterAwareCacheBuilder with ClusterAwareCacheOptions.Disabled.
+var ErrWildcardCacheDisabled = errors.New("the wildcard cache of all the logical clusters is disabled: " +
+	"read the objects of a logical cluster with the API reader, or with the cache of a cluster.ClusterSet")
+
+// ClusterAwareCacheOptions configure the wildcard cache built by ClusterAwareCacheBuilder.
+type ClusterAwareCacheOptions struct {
+	// Lazy defers building the cache, and discovering the APIs served across the logical
+	// clusters, to its first read, informer or index, rather than when the manager is
+	// created.  The cache is started then if the manager already started it.  A manager
+	// whose controllers never read or watch across the logical clusters never builds it.
+	Lazy bool
+
+	// Disabled never builds the cache: its reads, informers and indexes fail with
+	// ErrWildcardCacheDisabled.  The controllers must then read and watch the objects of
+	// each logical cluster with other caches, e.g. the ones of a cluster.ClusterSet.
+	Disabled bool
+
+	// NewCache builds the cache, it defaults to NewClusterAwareCache.
+	NewCache cache.NewCacheFunc
+}
+
+// ClusterAwareCacheBuilder returns a cache.NewCacheFunc building the wildcard cache of all the
+// logical clusters, e.g. for the NewCache option of NewClusterAwareManager, lazily or not at all
+// depending on the options.  Building the wildcard cache is expensive for large kcp
+// installations, as the APIs of all the logical clusters are discovered for its RESTMapper.
+func ClusterAwareCacheBuilder(o ClusterAwareCacheOptions) cache.NewCacheFunc {
+	if o.NewCache == nil {
+		o.NewCache = NewClusterAwareCache
+	}
+	return func(config *rest.Config, opts cache.Options) (cache.Cache, error) {
+		if !o.Lazy && !o.Disabled {
+			return o.NewCache(config, opts)
+		}
+		return &lazyCache{
+			disabled: o.Disabled,
+			build:    func() (cache.Cache, error) { return o.NewCache(config, opts) },
+		}, nil
+	}
+}
+
+// lazyCache is a cache.Cache building the cache it delegates to on first use.
+type lazyCache struct {
+	disabled bool
+	build    func() (cache.Cache, error)
+
+	mu sync.Mutex
+	// ctx is the context the lazyCache was started with, if it was.
+	ctx   context.Context
+	cache cache.Cache
+}
+
+var _ cache.Cache = &lazyCache{}
+var _ cache.QuorumSyncer = &lazyCache{}
+
+// get returns the cache, building it, and starting it if the lazyCache is started, on first use.
+func (c *lazyCache) get() (cache.Cache, error) {
+	if c.disabled {
+		return nil, ErrWildcardCacheDisabled
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cache != nil {
+		return c.cache, nil
+	}
+	built, err := c.build()
+	if err != nil {
+		return nil, err
+	}
+	c.cache = built
+	if c.ctx != nil {
+		start(c.ctx, built)
+	}
+	return built, nil
+}
+
+// start starts the cache, and waits for it to be started.
+func start(ctx context.Context, c cache.Cache) {
+	wildcardCacheLog.Info("Starting the wildcard cache")
+	go func() {
+		if err := c.Start(ctx); err != nil {
+			wildcardCacheLog.Error(err, "Wildcard cache stopped with an error")
+		}
+	}()
+	// The cache has no informers to sync yet, this returns once it is started.
+	c.WaitForCacheSync(ctx)
+}
+
+// Get implements client.Reader.
+func (c *lazyCache) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	built, err := c.get()
+	if err != nil {
+		return err
+	}
+	return built.Get(ctx, key, obj)
+}
+
+// List implements client.Reader.
+func (c *lazyCache) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	built, err := c.get()
+	if err != nil {
+		return err
+	}
+	return built.List(ctx, list, opts...)
+}
+
+// GetInformer implements cache.Informers.
+func (c *lazyCache) GetInformer(ctx context.Context, obj client.Object) (cache.Informer, error) {
+	built, err := c.get()
+	if err != nil {
+		return nil, err
+	}
+	return built.GetInformer(ctx, obj)
+}
+
+// GetInformerForKind implements cache.Informers.
+func (c *lazyCache) GetInformerForKind(ctx context.Context, gvk schema.GroupVersionKind) (cache.Informer, error) {
+	built, err := c.get()
+	if err != nil {
+		return nil, err
+	}
+	return built.GetInformerForKind(ctx, gvk)
+}
+
+// IndexField implements client.FieldIndexer.
+func (c *lazyCache) IndexField(ctx context.Context, obj client.Object, field string, extractValue client.IndexerFunc) error {
+	built, err := c.get()
+	if err != nil {
+		return err
+	}
+	return built.IndexField(ctx, obj, field, extractValue)
+}
+
+// Start implements cache.Informers.  It starts the cache if it was already built, and blocks
+// until the context is done.
+func (c *lazyCache) Start(ctx context.Context) error {
+	c.mu.Lock()
+	if c.ctx != nil {
+		c.mu.Unlock()
+		return errors.New("the wildcard cache was started more than once")
+	}
+	c.ctx = ctx
+	if c.cache != nil {
+		start(ctx, c.cache)
+	}
+	c.mu.Unlock()
+
+	<-ctx.Done()
+	return nil
+}
+
+// WaitForCacheSync implements cache.Informers.  It returns true at once while the cache isn't
+// built, as there is nothing to sync.
+func (c *lazyCache) WaitForCacheSync(ctx context.Context) bool {
+	c.mu.Lock()
+	built := c.cache
+	c.mu.Unlock()
+	if built == nil {
+		return true
+	}
+	return built.WaitForCacheSync(ctx)
+}
+
+// WaitForQuorumSync implements cache.QuorumSyncer.  It returns at once while the cache isn't
+// built, as there is nothing to sync.
+func (c *lazyCache) WaitForQuorumSync(ctx context.Context) error {
+	c.mu.Lock()
+	built := c.cache
+	c.mu.Unlock()
+	if built == nil {
+		return nil
+	}
+	return cache.WaitForQuorumSync(ctx, built)
+}
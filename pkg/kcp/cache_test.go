@@ -0,0 +1,116 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kcp_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/kcp"
+)
+
+// startedInformers are FakeInformers recording that they were started.
+type startedInformers struct {
+	informertest.FakeInformers
+	started chan struct{}
+}
+
+func (c *startedInformers) Start(ctx context.Context) error {
+	close(c.started)
+	return nil
+}
+
+var _ = Describe("ClusterAwareCacheBuilder", func() {
+	var (
+		built    []*startedInformers
+		newCache cache.NewCacheFunc
+	)
+
+	BeforeEach(func() {
+		built = nil
+		newCache = func(*rest.Config, cache.Options) (cache.Cache, error) {
+			c := &startedInformers{started: make(chan struct{})}
+			built = append(built, c)
+			return c, nil
+		}
+	})
+
+	It("should build the cache at once by default", func() {
+		_, err := kcp.ClusterAwareCacheBuilder(kcp.ClusterAwareCacheOptions{NewCache: newCache})(&rest.Config{}, cache.Options{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(built).To(HaveLen(1))
+	})
+
+	It("should build and start a lazy cache on first use", func() {
+		c, err := kcp.ClusterAwareCacheBuilder(kcp.ClusterAwareCacheOptions{Lazy: true, NewCache: newCache})(&rest.Config{}, cache.Options{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(built).To(BeEmpty())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			defer GinkgoRecover()
+			Expect(c.Start(ctx)).To(Succeed())
+		}()
+		Expect(c.WaitForCacheSync(ctx)).To(BeTrue())
+		Expect(built).To(BeEmpty())
+
+		Expect(c.List(ctx, &corev1.ConfigMapList{})).To(Succeed())
+		Expect(built).To(HaveLen(1))
+		Eventually(built[0].started).Should(BeClosed())
+
+		_, err = c.GetInformer(ctx, &corev1.ConfigMap{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(built).To(HaveLen(1))
+	})
+
+	It("should start a lazy cache built before it is started once it is", func() {
+		c, err := kcp.ClusterAwareCacheBuilder(kcp.ClusterAwareCacheOptions{Lazy: true, NewCache: newCache})(&rest.Config{}, cache.Options{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.IndexField(context.Background(), &corev1.ConfigMap{}, "field", func(client.Object) []string { return nil })).To(Succeed())
+		Expect(built).To(HaveLen(1))
+		Consistently(built[0].started).ShouldNot(BeClosed())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			defer GinkgoRecover()
+			Expect(c.Start(ctx)).To(Succeed())
+		}()
+		Eventually(built[0].started).Should(BeClosed())
+	})
+
+	It("should fail to read from a disabled cache", func() {
+		c, err := kcp.ClusterAwareCacheBuilder(kcp.ClusterAwareCacheOptions{Disabled: true, NewCache: newCache})(&rest.Config{}, cache.Options{})
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx := context.Background()
+		Expect(c.Get(ctx, client.ObjectKey{}, &corev1.ConfigMap{})).To(MatchError(kcp.ErrWildcardCacheDisabled))
+		Expect(c.List(ctx, &corev1.ConfigMapList{})).To(MatchError(kcp.ErrWildcardCacheDisabled))
+		_, err = c.GetInformer(ctx, &corev1.ConfigMap{})
+		Expect(err).To(MatchError(kcp.ErrWildcardCacheDisabled))
+		Expect(c.WaitForCacheSync(ctx)).To(BeTrue())
+		Expect(built).To(BeEmpty())
+	})
+})
@@ -0,0 +1,294 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"sort"
+
+	"github.com/kcp-dev/logicalcluster"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// ConfigHashAnnotation is the pod template annotation holding the hash of the Secrets and
+// ConfigMaps referenced by the pods, see SetConfigHash.
+const ConfigHashAnnotation = "controller-runtime.sigs.k8s.io/config-hash"
+
+const (
+	// SecretKind is the Kind of the ConfigReferences to Secrets.
+	SecretKind = "Secret"
+	// ConfigMapKind is the Kind of the ConfigReferences to ConfigMaps.
+	ConfigMapKind = "ConfigMap"
+)
+
+// ConfigReference identifies a Secret or a ConfigMap referenced by a workload.  Its
+// Cluster may differ from the cluster of the workload.
+type ConfigReference struct {
+	// Kind is either SecretKind or ConfigMapKind.
+	Kind string
+	client.ObjectKey
+}
+
+// PodSpecConfigReferences returns the Secrets and ConfigMaps referenced by the volumes,
+// env and envFrom of the containers of the pod spec, which live in the given cluster
+// and namespace, i.e. the ones of the workload.
+func PodSpecConfigReferences(cluster logicalcluster.Name, namespace string, spec *corev1.PodSpec) []ConfigReference {
+	set := map[ConfigReference]struct{}{}
+	add := func(kind, name string) {
+		set[ConfigReference{Kind: kind, ObjectKey: client.ObjectKey{
+			NamespacedName: types.NamespacedName{Namespace: namespace, Name: name},
+			Cluster:        cluster,
+		}}] = struct{}{}
+	}
+
+	for _, v := range spec.Volumes {
+		switch {
+		case v.Secret != nil:
+			add(SecretKind, v.Secret.SecretName)
+		case v.ConfigMap != nil:
+			add(ConfigMapKind, v.ConfigMap.Name)
+		case v.Projected != nil:
+			for _, s := range v.Projected.Sources {
+				if s.Secret != nil {
+					add(SecretKind, s.Secret.Name)
+				}
+				if s.ConfigMap != nil {
+					add(ConfigMapKind, s.ConfigMap.Name)
+				}
+			}
+		}
+	}
+	for _, containers := range [][]corev1.Container{spec.InitContainers, spec.Containers} {
+		for _, c := range containers {
+			for _, env := range c.Env {
+				if env.ValueFrom == nil {
+					continue
+				}
+				if env.ValueFrom.SecretKeyRef != nil {
+					add(SecretKind, env.ValueFrom.SecretKeyRef.Name)
+				}
+				if env.ValueFrom.ConfigMapKeyRef != nil {
+					add(ConfigMapKind, env.ValueFrom.ConfigMapKeyRef.Name)
+				}
+			}
+			for _, envFrom := range c.EnvFrom {
+				if envFrom.SecretRef != nil {
+					add(SecretKind, envFrom.SecretRef.Name)
+				}
+				if envFrom.ConfigMapRef != nil {
+					add(ConfigMapKind, envFrom.ConfigMapRef.Name)
+				}
+			}
+		}
+	}
+
+	refs := make([]ConfigReference, 0, len(set))
+	for ref := range set {
+		refs = append(refs, ref)
+	}
+	sortConfigReferences(refs)
+	return refs
+}
+
+// ConfigHash returns a hash of the content of the referenced Secrets and ConfigMaps.  Each of
+// them is read from its own logical cluster.  Missing objects are hashed as such, so that
+// creating them changes the hash.
+func ConfigHash(ctx context.Context, c client.Reader, refs []ConfigReference) (string, error) {
+	refs = append([]ConfigReference(nil), refs...)
+	sortConfigReferences(refs)
+
+	h := sha256.New()
+	for _, ref := range refs {
+		fmt.Fprintf(h, "%s|%s|%s|%s\n", ref.Kind, ref.Cluster, ref.Namespace, ref.Name)
+
+		var data map[string][]byte
+		switch ref.Kind {
+		case SecretKind:
+			secret := &corev1.Secret{}
+			if err := c.Get(ctx, ref.ObjectKey, secret); err != nil {
+				if apierrors.IsNotFound(err) {
+					fmt.Fprintln(h, "<absent>")
+					continue
+				}
+				return "", err
+			}
+			data = secret.Data
+		case ConfigMapKind:
+			cm := &corev1.ConfigMap{}
+			if err := c.Get(ctx, ref.ObjectKey, cm); err != nil {
+				if apierrors.IsNotFound(err) {
+					fmt.Fprintln(h, "<absent>")
+					continue
+				}
+				return "", err
+			}
+			data = make(map[string][]byte, len(cm.Data)+len(cm.BinaryData))
+			for k, v := range cm.Data {
+				data[k] = []byte(v)
+			}
+			for k, v := range cm.BinaryData {
+				data[k] = v
+			}
+		default:
+			return "", fmt.Errorf("unsupported config reference kind %q", ref.Kind)
+		}
+		hashData(h, data)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// SetConfigHash stores the ConfigHash of the referenced Secrets and ConfigMaps in the
+// ConfigHashAnnotation of the pod template, so that the workload rolls out its pods
+// whenever their configuration changes.  It returns whether the annotation changed.
+//
+// Use PodSpecConfigReferences to find the references of the template, and add the
+// references to other clusters the workload depends on, if any.
+func SetConfigHash(ctx context.Context, c client.Reader, template *corev1.PodTemplateSpec, refs []ConfigReference) (bool, error) {
+	sum, err := ConfigHash(ctx, c, refs)
+	if err != nil {
+		return false, err
+	}
+	if template.Annotations[ConfigHashAnnotation] == sum {
+		return false, nil
+	}
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations[ConfigHashAnnotation] = sum
+	return true, nil
+}
+
+// ConfigReferencesField is the field the workloads are indexed with by the Secrets and
+// ConfigMaps they reference, see IndexConfigReferences.
+const ConfigReferencesField = "kcp.configReferences"
+
+// IndexConfigReferences indexes the workloads of the type of obj, e.g. &appsv1.Deployment{},
+// by the Secrets and ConfigMaps they reference according to the references function, for
+// EnqueueReferencingWorkloads.  The indexer is usually the cache of the manager, which must
+// cache the workloads of all the logical clusters.
+func IndexConfigReferences(ctx context.Context, indexer client.FieldIndexer, obj client.Object, references func(client.Object) []ConfigReference) error {
+	return indexer.IndexField(ctx, obj, ConfigReferencesField, func(workload client.Object) []string {
+		refs := references(workload)
+		values := make([]string, 0, len(refs))
+		for _, ref := range refs {
+			values = append(values, ref.indexValue())
+		}
+		return values
+	})
+}
+
+// indexValue returns the value the workloads referencing the object are indexed with.  It
+// includes the cluster of the object, so that the objects of the same name in different
+// clusters don't match the same workloads.
+func (r ConfigReference) indexValue() string {
+	return fmt.Sprintf("%s|%s|%s|%s", r.Kind, r.Cluster, r.Namespace, r.Name)
+}
+
+// EnqueueReferencingWorkloads returns an EventHandler for Secrets and ConfigMaps which
+// enqueues a Request for each workload referencing the object of the event.  The workloads
+// are looked up with the given reader by the ConfigReferencesField index, which must be
+// registered with IndexConfigReferences, across all the logical clusters, so that workloads
+// referencing objects in another cluster are enqueued too.
+//
+// list is the list type of the workloads, e.g. &appsv1.DeploymentList{}.
+func EnqueueReferencingWorkloads(c client.Reader, list client.ObjectList) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+		changed, ok := configReferenceTo(obj)
+		if !ok {
+			return nil
+		}
+
+		workloads := list.DeepCopyObject().(client.ObjectList)
+		if err := c.List(WithCluster(context.Background(), logicalcluster.Wildcard), workloads,
+			client.MatchingFields{ConfigReferencesField: changed.indexValue()}); err != nil {
+			enqueueLog.Error(err, "Failed to list the workloads referencing an object", "kind", changed.Kind, "object", changed.ObjectKey)
+			return nil
+		}
+		items, err := meta.ExtractList(workloads)
+		if err != nil {
+			enqueueLog.Error(err, "Failed to extract the workloads referencing an object", "kind", changed.Kind, "object", changed.ObjectKey)
+			return nil
+		}
+
+		reqs := make([]reconcile.Request, 0, len(items))
+		for _, item := range items {
+			if workload, ok := item.(client.Object); ok {
+				reqs = append(reqs, request(workload))
+			}
+		}
+		return reqs
+	})
+}
+
+// configReferenceTo returns the ConfigReference matching the given Secret or ConfigMap.
+func configReferenceTo(obj client.Object) (ConfigReference, bool) {
+	var kind string
+	switch obj.(type) {
+	case *corev1.Secret:
+		kind = SecretKind
+	case *corev1.ConfigMap:
+		kind = ConfigMapKind
+	default:
+		kind = obj.GetObjectKind().GroupVersionKind().Kind
+		if kind != SecretKind && kind != ConfigMapKind {
+			return ConfigReference{}, false
+		}
+	}
+	return ConfigReference{Kind: kind, ObjectKey: client.ObjectKey{
+		NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()},
+		Cluster:        logicalcluster.From(obj),
+	}}, true
+}
+
+func hashData(h hash.Hash, data map[string][]byte) {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%d:", k, len(data[k]))
+		h.Write(data[k])
+		fmt.Fprintln(h)
+	}
+}
+
+func sortConfigReferences(refs []ConfigReference) {
+	sort.Slice(refs, func(i, j int) bool {
+		a, b := refs[i], refs[j]
+		if a.Cluster != b.Cluster {
+			return a.Cluster.String() < b.Cluster.String()
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+}
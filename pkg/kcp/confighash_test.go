@@ -0,0 +1,177 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kcp_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllertest"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/kcp"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("Config hashing", func() {
+	var cl client.Client
+	var deployment *appsv1.Deployment
+	clusterA := logicalcluster.New("root:a")
+	clusterB := logicalcluster.New("root:b")
+
+	BeforeEach(func() {
+		deployment = &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "ns", ClusterName: clusterA.String()},
+			Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Volumes: []corev1.Volume{{
+					Name:         "config",
+					VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "config"}}},
+				}},
+				Containers: []corev1.Container{{
+					Name: "app",
+					EnvFrom: []corev1.EnvFromSource{{
+						SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "creds"}},
+					}},
+				}},
+			}}},
+		}
+		cl = fake.NewClientBuilder().WithObjects(
+			deployment,
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "ns", ClusterName: clusterA.String()}, Data: map[string]string{"a": "1"}},
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "ns", ClusterName: clusterB.String()}, Data: map[string]string{"a": "2"}},
+		).Build()
+	})
+
+	key := func(cluster logicalcluster.Name, name string) client.ObjectKey {
+		return client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "ns", Name: name}, Cluster: cluster}
+	}
+
+	It("should find the Secrets and ConfigMaps referenced by a pod spec", func() {
+		Expect(kcp.PodSpecConfigReferences(clusterA, "ns", &deployment.Spec.Template.Spec)).To(Equal([]kcp.ConfigReference{
+			{Kind: kcp.ConfigMapKind, ObjectKey: key(clusterA, "config")},
+			{Kind: kcp.SecretKind, ObjectKey: key(clusterA, "creds")},
+		}))
+	})
+
+	It("should change the hash when a referenced object of the cluster changes", func() {
+		ctx := context.Background()
+		refs := kcp.PodSpecConfigReferences(clusterA, "ns", &deployment.Spec.Template.Spec)
+		template := deployment.Spec.Template.DeepCopy()
+
+		changed, err := kcp.SetConfigHash(ctx, cl, template, refs)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(BeTrue())
+		initial := template.Annotations[kcp.ConfigHashAnnotation]
+		Expect(initial).NotTo(BeEmpty())
+
+		By("updating the ConfigMap of another cluster")
+		cm := &corev1.ConfigMap{}
+		Expect(cl.Get(ctx, key(clusterB, "config"), cm)).To(Succeed())
+		cm.Data["a"] = "3"
+		Expect(cl.Update(ctx, cm)).To(Succeed())
+		changed, err = kcp.SetConfigHash(ctx, cl, template, refs)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(BeFalse())
+
+		By("creating the missing Secret of the cluster")
+		Expect(cl.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "ns", ClusterName: clusterA.String()},
+			Data:       map[string][]byte{"password": []byte("secret")},
+		})).To(Succeed())
+		changed, err = kcp.SetConfigHash(ctx, cl, template, refs)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(BeTrue())
+		Expect(template.Annotations[kcp.ConfigHashAnnotation]).NotTo(Equal(initial))
+	})
+
+	It("should enqueue the workloads referencing the object of the event, including from other clusters", func() {
+		other := &appsv1.Deployment{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "ns", ClusterName: clusterB.String(), ResourceVersion: "1"},
+		}
+		deployment.TypeMeta = metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"}
+		deployment.ResourceVersion = "1"
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if r.URL.Query().Get("watch") == "true" {
+				w.WriteHeader(http.StatusOK)
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+				return
+			}
+			_ = json.NewEncoder(w).Encode(&appsv1.DeploymentList{
+				TypeMeta: metav1.TypeMeta{APIVersion: "apps/v1", Kind: "DeploymentList"},
+				ListMeta: metav1.ListMeta{ResourceVersion: "1"},
+				Items:    []appsv1.Deployment{*deployment, *other},
+			})
+		}))
+		defer server.Close()
+
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(appsv1.SchemeGroupVersion.WithKind("Deployment"), meta.RESTScopeNamespace)
+		c, err := cache.New(&rest.Config{Host: server.URL}, cache.Options{Mapper: mapper})
+		Expect(err).NotTo(HaveOccurred())
+		references := func(obj client.Object) []kcp.ConfigReference {
+			d := obj.(*appsv1.Deployment)
+			refs := kcp.PodSpecConfigReferences(logicalcluster.From(d), d.Namespace, &d.Spec.Template.Spec)
+			if d.Name != "app" {
+				return refs
+			}
+			return append(refs, kcp.ConfigReference{Kind: kcp.SecretKind, ObjectKey: key(clusterB, "shared")})
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		Expect(kcp.IndexConfigReferences(ctx, c, &appsv1.Deployment{}, references)).To(Succeed())
+		go func() {
+			defer GinkgoRecover()
+			Expect(c.Start(ctx)).To(Succeed())
+		}()
+		Expect(c.WaitForCacheSync(ctx)).To(BeTrue())
+
+		h := kcp.EnqueueReferencingWorkloads(c, &appsv1.DeploymentList{})
+		expected := reconcile.Request{ObjectKey: key(clusterA, "app")}
+		for _, obj := range []client.Object{
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "ns", ClusterName: clusterA.String()}},
+			&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "shared", Namespace: "ns", ClusterName: clusterB.String()}},
+		} {
+			q := &controllertest.Queue{Interface: workqueue.New()}
+			h.Create(event.CreateEvent{Object: obj}, q)
+			Expect(q.Len()).To(Equal(1))
+			item, _ := q.Get()
+			Expect(item).To(Equal(expected))
+		}
+
+		By("ignoring the objects of the same name in other clusters")
+		q := &controllertest.Queue{Interface: workqueue.New()}
+		h.Create(event.CreateEvent{Object: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "ns", ClusterName: clusterB.String()}}}, q)
+		Expect(q.Len()).To(Equal(0))
+	})
+})
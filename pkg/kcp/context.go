@@ -0,0 +1,53 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kcp
+
+import (
+	"context"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// WithCluster returns a context targeting the given logical cluster.  Clients
+// built with a cluster round tripper, caches and event handlers all read the
+// cluster from this context.
+//
+// The cluster is stored under the same unexported key as the one used by
+// github.com/kcp-dev/apimachinery/pkg/client, so both APIs can be mixed.
+func WithCluster(ctx context.Context, cluster logicalcluster.Name) context.Context {
+	return kcpclient.WithCluster(ctx, cluster)
+}
+
+// ClusterFrom returns the logical cluster of the context, and whether it was set.
+func ClusterFrom(ctx context.Context) (logicalcluster.Name, bool) {
+	return kcpclient.ClusterFromContext(ctx)
+}
+
+// WithClusterInContext wraps a Reconciler so that the context passed to it targets
+// the logical cluster of the request.  Controllers already do this for the
+// reconcilers they run; use it when calling a Reconciler directly.
+func WithClusterInContext(r reconcile.Reconciler) reconcile.Reconciler {
+	return reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		if !req.Cluster.Empty() {
+			ctx = WithCluster(ctx, req.Cluster)
+		}
+		return r.Reconcile(ctx, req)
+	})
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kcp
+
+import (
+	"context"
+	"strings"
+
+	"github.com/kcp-dev/logicalcluster"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// VersionFunc returns the version of a kind to read in a logical cluster: the version of
+// gvk if the cluster serves it, or another version of its group kind otherwise.
+type VersionFunc func(ctx context.Context, cluster logicalcluster.Name, gvk schema.GroupVersionKind) (string, error)
+
+// ServedVersions returns a VersionFunc reading the versions served by each logical cluster
+// from its RESTMapper, preferring the version of the requested kind.
+func ServedVersions(mapper *apiutil.MultiClusterRESTMapper) VersionFunc {
+	return func(_ context.Context, cluster logicalcluster.Name, gvk schema.GroupVersionKind) (string, error) {
+		clusterMapper, err := mapper.ForCluster(cluster)
+		if err != nil {
+			return "", err
+		}
+		mapping, err := clusterMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if meta.IsNoMatchError(err) {
+			mapping, err = clusterMapper.RESTMapping(gvk.GroupKind())
+		}
+		if err != nil {
+			return "", err
+		}
+		return mapping.GroupVersionKind.Version, nil
+	}
+}
+
+// NewConvertingReader returns a client.Reader reading the objects of each logical cluster
+// in the version it serves, as returned by versions, and converting them with the registry
+// into the objects passed in, e.g. the hub type the controller works with.
+//
+// The cluster is read from the key of Get, or from the context.  Reads without a cluster,
+// or across all of them with logicalcluster.Wildcard, are passed through unconverted as
+// the clusters may serve different versions.
+func NewConvertingReader(reader client.Reader, scheme *runtime.Scheme, registry *conversion.Registry, versions VersionFunc) client.Reader {
+	return &convertingReader{reader: reader, scheme: scheme, registry: registry, versions: versions}
+}
+
+type convertingReader struct {
+	reader   client.Reader
+	scheme   *runtime.Scheme
+	registry *conversion.Registry
+	versions VersionFunc
+}
+
+// Get implements client.Reader.
+func (r *convertingReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	cluster := key.Cluster
+	if cluster.Empty() {
+		cluster, _ = ClusterFrom(ctx)
+	}
+	gvk, err := apiutil.GVKForObject(obj, r.scheme)
+	if err != nil {
+		return err
+	}
+	served, err := r.servedKind(ctx, cluster, gvk)
+	if err != nil {
+		return err
+	}
+	if served == gvk {
+		return r.reader.Get(ctx, key, obj)
+	}
+
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(served)
+	if err := r.reader.Get(ctx, key, u); err != nil {
+		return err
+	}
+	return r.registry.Convert(u, obj)
+}
+
+// List implements client.Reader.
+func (r *convertingReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	ctx = client.ListContext(ctx, opts...)
+	cluster, _ := ClusterFrom(ctx)
+	gvk, err := apiutil.GVKForObject(list, r.scheme)
+	if err != nil {
+		return err
+	}
+	gvk.Kind = strings.TrimSuffix(gvk.Kind, "List")
+	served, err := r.servedKind(ctx, cluster, gvk)
+	if err != nil {
+		return err
+	}
+	if served == gvk {
+		return r.reader.List(ctx, list, opts...)
+	}
+
+	ul := &unstructured.UnstructuredList{}
+	ul.SetGroupVersionKind(served.GroupVersion().WithKind(served.Kind + "List"))
+	if err := r.reader.List(ctx, ul, opts...); err != nil {
+		return err
+	}
+	_, isUnstructured := list.(*unstructured.UnstructuredList)
+	items := make([]runtime.Object, 0, len(ul.Items))
+	for i := range ul.Items {
+		var item runtime.Object
+		if isUnstructured {
+			u := &unstructured.Unstructured{}
+			u.SetGroupVersionKind(gvk)
+			item = u
+		} else if item, err = r.scheme.New(gvk); err != nil {
+			return err
+		}
+		if err := r.registry.Convert(&ul.Items[i], item); err != nil {
+			return err
+		}
+		items = append(items, item)
+	}
+	if err := meta.SetList(list, items); err != nil {
+		return err
+	}
+	list.SetResourceVersion(ul.GetResourceVersion())
+	list.SetContinue(ul.GetContinue())
+	list.SetRemainingItemCount(ul.GetRemainingItemCount())
+	return nil
+}
+
+// servedKind returns the kind to read in the cluster for gvk.
+func (r *convertingReader) servedKind(ctx context.Context, cluster logicalcluster.Name, gvk schema.GroupVersionKind) (schema.GroupVersionKind, error) {
+	if cluster.Empty() || cluster == logicalcluster.Wildcard {
+		return gvk, nil
+	}
+	version, err := r.versions(ctx, cluster, gvk)
+	if err != nil || version == "" {
+		return gvk, err
+	}
+	return gvk.GroupKind().WithVersion(version), nil
+}
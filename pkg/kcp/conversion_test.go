@@ -0,0 +1,165 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kcp_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+	"sigs.k8s.io/controller-runtime/pkg/kcp"
+	jobsv1 "sigs.k8s.io/controller-runtime/pkg/webhook/conversion/testdata/api/v1"
+	jobsv2 "sigs.k8s.io/controller-runtime/pkg/webhook/conversion/testdata/api/v2"
+)
+
+// versionedReader serves unstructured objects of a single version per logical cluster.
+type versionedReader struct {
+	objects map[logicalcluster.Name]*unstructured.Unstructured
+}
+
+func (r *versionedReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	stored, ok := r.objects[key.Cluster]
+	if !ok {
+		return fmt.Errorf("no object in %s", key.Cluster)
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok || u.GroupVersionKind() != stored.GroupVersionKind() {
+		return fmt.Errorf("%s is not served in %s", obj.GetObjectKind().GroupVersionKind(), key.Cluster)
+	}
+	stored.DeepCopyInto(u)
+	return nil
+}
+
+func (r *versionedReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	cluster, _ := kcp.ClusterFrom(ctx)
+	stored, ok := r.objects[cluster]
+	if !ok {
+		return fmt.Errorf("no object in %s", cluster)
+	}
+	ul, ok := list.(*unstructured.UnstructuredList)
+	if !ok || ul.GroupVersionKind() != stored.GroupVersionKind().GroupVersion().WithKind(stored.GetKind()+"List") {
+		return fmt.Errorf("%s is not served in %s", list.GetObjectKind().GroupVersionKind(), cluster)
+	}
+	ul.Items = []unstructured.Unstructured{*stored.DeepCopy()}
+	return nil
+}
+
+var _ = Describe("NewConvertingReader", func() {
+	var (
+		scheme   *runtime.Scheme
+		registry *conversion.Registry
+		reader   client.Reader
+		key      = func(cluster string) client.ObjectKey {
+			return client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "default", Name: "job"}, Cluster: logicalcluster.New(cluster)}
+		}
+		v1alpha1 = schema.GroupVersionKind{Group: jobsv2.GroupVersion.Group, Version: "v1alpha1", Kind: "ExternalJob"}
+	)
+
+	job := func(gvk schema.GroupVersionKind, field, value string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(gvk)
+		u.SetNamespace("default")
+		u.SetName("job")
+		Expect(unstructured.SetNestedField(u.Object, value, "spec", field)).To(Succeed())
+		return u
+	}
+
+	BeforeEach(func() {
+		scheme = runtime.NewScheme()
+		Expect(jobsv1.AddToScheme(scheme)).To(Succeed())
+		Expect(jobsv2.AddToScheme(scheme)).To(Succeed())
+		registry = conversion.NewRegistry(scheme)
+		registry.RegisterFieldMappings(v1alpha1, jobsv2.GroupVersion.WithKind("ExternalJob"),
+			conversion.FieldMapping{From: []string{"spec", "when"}, To: []string{"spec", "scheduleAt"}})
+
+		versions := map[string]string{"root:v1": "v1", "root:v2": "v2", "root:v1alpha1": "v1alpha1"}
+		served := &versionedReader{objects: map[logicalcluster.Name]*unstructured.Unstructured{
+			logicalcluster.New("root:v1"):       job(jobsv1.GroupVersion.WithKind("ExternalJob"), "runAt", "Monday"),
+			logicalcluster.New("root:v2"):       job(jobsv2.GroupVersion.WithKind("ExternalJob"), "scheduleAt", "Tuesday"),
+			logicalcluster.New("root:v1alpha1"): job(v1alpha1, "when", "Wednesday"),
+		}}
+		reader = kcp.NewConvertingReader(served, scheme, registry,
+			func(_ context.Context, cluster logicalcluster.Name, _ schema.GroupVersionKind) (string, error) {
+				return versions[cluster.String()], nil
+			})
+	})
+
+	It("should convert the objects of each cluster into the hub type", func() {
+		// root:v2 serves the hub version, which the converting reader reads as is, and
+		// the stub reader only serves unstructured objects.
+		for cluster, scheduleAt := range map[string]string{"root:v1": "Monday", "root:v1alpha1": "Wednesday"} {
+			hub := &jobsv2.ExternalJob{}
+			Expect(reader.Get(context.Background(), key(cluster), hub)).To(Succeed())
+			Expect(hub.Spec.ScheduleAt).To(Equal(scheduleAt))
+			Expect(hub.GetName()).To(Equal("job"))
+		}
+	})
+
+	It("should convert the objects into the requested unstructured version", func() {
+		hub := &unstructured.Unstructured{}
+		hub.SetGroupVersionKind(jobsv2.GroupVersion.WithKind("ExternalJob"))
+		Expect(reader.Get(context.Background(), key("root:v2"), hub)).To(Succeed())
+		Expect(hub.Object).To(HaveKeyWithValue("spec", HaveKeyWithValue("scheduleAt", "Tuesday")))
+
+		Expect(reader.Get(context.Background(), key("root:v1"), hub)).To(Succeed())
+		Expect(hub.GroupVersionKind()).To(Equal(jobsv2.GroupVersion.WithKind("ExternalJob")))
+		Expect(hub.Object).To(HaveKeyWithValue("spec", HaveKeyWithValue("scheduleAt", "Monday")))
+	})
+
+	It("should convert the items of the lists", func() {
+		list := &jobsv2.ExternalJobList{}
+		ctx := kcp.WithCluster(context.Background(), logicalcluster.New("root:v1alpha1"))
+		Expect(reader.List(ctx, list)).To(Succeed())
+		Expect(list.Items).To(HaveLen(1))
+		Expect(list.Items[0].Spec.ScheduleAt).To(Equal("Wednesday"))
+	})
+
+	It("should use the functions registered at runtime", func() {
+		registry.Register(jobsv1.GroupVersion.WithKind("ExternalJob"), jobsv2.GroupVersion.WithKind("ExternalJob"), func(src, dst runtime.Object) error {
+			dst.(*jobsv2.ExternalJob).Spec.ScheduleAt = "overridden"
+			return nil
+		})
+		hub := &jobsv2.ExternalJob{}
+		Expect(reader.Get(context.Background(), key("root:v1"), hub)).To(Succeed())
+		Expect(hub.Spec.ScheduleAt).To(Equal("overridden"))
+	})
+
+	It("should fail when no conversion is registered", func() {
+		registry.Unregister(v1alpha1, jobsv2.GroupVersion.WithKind("ExternalJob"))
+		err := reader.Get(context.Background(), key("root:v1alpha1"), &jobsv2.ExternalJob{})
+		Expect(err).To(MatchError(ContainSubstring("no conversion registered")))
+	})
+
+	It("should convert from the hub with ConvertFrom", func() {
+		hub := &jobsv2.ExternalJob{ObjectMeta: metav1.ObjectMeta{Name: "job"}, Spec: jobsv2.ExternalJobSpec{ScheduleAt: "Friday"}}
+		spoke := &unstructured.Unstructured{}
+		spoke.SetGroupVersionKind(jobsv1.GroupVersion.WithKind("ExternalJob"))
+		Expect(registry.Convert(hub, spoke)).To(Succeed())
+		Expect(spoke.Object).To(HaveKeyWithValue("spec", HaveKeyWithValue("runAt", "Friday")))
+		Expect(spoke.GetName()).To(Equal("job"))
+	})
+})
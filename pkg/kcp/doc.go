@@ -0,0 +1,38 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package kcp runs controllers against the logical clusters of a kcp server.
+
+NewClusterAwareManager builds a manager watching all the logical clusters the config has
+access to in one call, with the options of any other manager:
+
+	mgr, err := kcp.NewClusterAwareManager(ctrl.GetConfigOrDie(), ctrl.Options{})
+
+Its cache watches the objects of all the clusters through the wildcard endpoint and keys them
+by cluster, and its client and API reader send each request to the cluster of the object, of
+the key, or of the context.  The requests enqueued by EnqueueRequestForObject carry the
+cluster of their object, which the controllers put in the context passed to the reconcilers,
+see WithCluster and ClusterFrom; the admission webhooks of the manager read the cluster of
+the admission requests from their path or object the same way.
+
+The other helpers of the package build on the same pieces: SharedWildcardCache serves the
+caches of the clusters of a ClusterSet from a single wildcard watch per kind, Bootstrap
+creates objects in the clusters as they join, APIControllers runs controllers for the APIs
+bound in the clusters, and NewConvertingReader reads the objects in the versions served by
+each cluster.
+*/
+package kcp
@@ -75,7 +75,7 @@ func (e *EnqueueRequestForObject) Generic(evt event.GenericEvent, q workqueue.Ra
 }
 
 func request(obj client.Object) reconcile.Request {
-	return reconcile.Request{client.ObjectKey{
+	return reconcile.Request{ObjectKey: client.ObjectKey{
 		NamespacedName: types.NamespacedName{
 			Name:      obj.GetName(),
 			Namespace: obj.GetNamespace(),
@@ -0,0 +1,36 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kcp
+
+import (
+	"github.com/kcp-dev/logicalcluster"
+
+	"sigs.k8s.io/controller-runtime/pkg/internal/clusterhost"
+)
+
+// ClusterFromHost returns the logical cluster the host of a config targets, i.e. <name> for
+// a host of the form https://<server>/clusters/<name>, or the empty name if it targets none.
+func ClusterFromHost(host string) logicalcluster.Name {
+	return clusterhost.Cluster(host)
+}
+
+// ServerHost returns the host of the kcp server of the host of a config, without the path of
+// the logical cluster it targets, if any, so that the configs of other logical clusters can
+// be derived from it.
+func ServerHost(host string) string {
+	return clusterhost.Server(host)
+}
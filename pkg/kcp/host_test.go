@@ -0,0 +1,36 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kcp_test
+
+import (
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-runtime/pkg/kcp"
+)
+
+var _ = Describe("Config hosts", func() {
+	It("should parse the logical cluster and the server of a host", func() {
+		Expect(kcp.ClusterFromHost("https://kcp.example.com/clusters/root:org")).To(Equal(logicalcluster.New("root:org")))
+		Expect(kcp.ClusterFromHost("https://kcp.example.com/clusters/*/")).To(Equal(logicalcluster.Wildcard))
+		Expect(kcp.ClusterFromHost("https://kcp.example.com")).To(Equal(logicalcluster.Name{}))
+
+		Expect(kcp.ServerHost("https://kcp.example.com/clusters/root:org")).To(Equal("https://kcp.example.com"))
+		Expect(kcp.ServerHost("https://kcp.example.com/")).To(Equal("https://kcp.example.com"))
+	})
+})
@@ -0,0 +1,107 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kcp
+
+import (
+	"net/http"
+
+	kcpcache "github.com/kcp-dev/apimachinery/pkg/cache"
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// NewClusterAwareManager returns a manager whose controllers run against all the logical
+// clusters the config has access to, rather than a single one.  Its cache watches the
+// objects of all the clusters at once, and its client sends each request to the cluster
+// of the object or of the context.  Leader election, health checks and metrics are those
+// of the single manager.
+//
+// Controllers should enqueue requests with EnqueueRequestForObject, so that the requests
+// carry the cluster of their object: the cluster is then put in the context passed to
+// the reconciler, which targets it when using the client of the manager.
+//
+// NewCache, NewClient and NewAPIReader default to NewClusterAwareCache, NewClusterAwareClient
+// and NewClusterAwareAPIReader.  Set NewCache to ClusterAwareCacheBuilder to build the wildcard
+// cache lazily, or not at all.
+func NewClusterAwareManager(config *rest.Config, options manager.Options) (manager.Manager, error) {
+	if options.NewCache == nil {
+		options.NewCache = NewClusterAwareCache
+	}
+	if options.NewClient == nil {
+		options.NewClient = NewClusterAwareClient
+	}
+	if options.NewAPIReader == nil {
+		options.NewAPIReader = NewClusterAwareAPIReader
+	}
+	return manager.New(config, options)
+}
+
+// NewClusterAwareCache is a cache.NewCacheFunc building a cache which watches the objects
+// of all the logical clusters through the wildcard endpoint of the config, and keys them
+// by cluster.  The lists with a context targeting a single logical cluster only return the
+// objects of that cluster, see SharedWildcardCache to partition the cache per cluster.
+func NewClusterAwareCache(config *rest.Config, opts cache.Options) (cache.Cache, error) {
+	config = rest.CopyConfig(config)
+	config.Host += "/clusters/*"
+	opts.KeyFunction = kcpcache.ClusterAwareKeyFunc
+	return cache.New(config, opts)
+}
+
+// NewClusterAwareClient is a cluster.NewClientFunc building a client which sends each
+// request to the logical cluster of its object, or of its context.
+func NewClusterAwareClient(cache cache.Cache, config *rest.Config, options client.Options, uncachedObjects ...client.Object) (client.Client, error) {
+	var err error
+	if options.HTTPClient, err = clusterAwareHTTPClient(config, options.HTTPClient); err != nil {
+		return nil, err
+	}
+	return cluster.DefaultNewClient(cache, config, options, uncachedObjects...)
+}
+
+// NewClusterAwareAPIReader is a cluster.NewAPIReaderFunc building a reader which reads live
+// from the API server in the logical cluster of the key, or of the context, e.g. to read the
+// latest version of an object when the staleness of the cache matters.  Lists list the
+// objects of the cluster of the context.
+func NewClusterAwareAPIReader(config *rest.Config, options client.Options) (client.Reader, error) {
+	var err error
+	if options.HTTPClient, err = clusterAwareHTTPClient(config, options.HTTPClient); err != nil {
+		return nil, err
+	}
+	return cluster.DefaultNewAPIReader(config, options)
+}
+
+// clusterAwareHTTPClient returns a copy of the HTTP client, or of a client for the config,
+// sending each request to the logical cluster of its context.
+func clusterAwareHTTPClient(config *rest.Config, httpClient *http.Client) (*http.Client, error) {
+	if httpClient == nil {
+		var err error
+		if httpClient, err = rest.HTTPClientFor(config); err != nil {
+			return nil, err
+		}
+	}
+	transport := httpClient.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	clusterClient := *httpClient
+	clusterClient.Transport = kcpclient.NewClusterRoundTripper(transport)
+	return &clusterClient, nil
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kcp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/kcp"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+var _ = Describe("NewClusterAwareClient", func() {
+	var server *httptest.Server
+	var paths chan string
+
+	BeforeEach(func() {
+		paths = make(chan string, 10)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			paths <- r.URL.Path
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm","namespace":"ns"}}`))
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should send the requests to the logical cluster of the object or of the context", func() {
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+		cl, err := kcp.NewClusterAwareClient(nil, &rest.Config{Host: server.URL}, client.Options{Scheme: scheme.Scheme, Mapper: mapper})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cl.Create(context.Background(), &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "ns", ClusterName: "root:a"},
+		})).To(Succeed())
+		Expect(<-paths).To(Equal("/clusters/root:a/api/v1/namespaces/ns/configmaps"))
+
+		ctx := kcp.WithCluster(context.Background(), logicalcluster.New("root:b"))
+		Expect(cl.Delete(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "ns"}})).To(Succeed())
+		Expect(<-paths).To(Equal("/clusters/root:b/api/v1/namespaces/ns/configmaps/cm"))
+	})
+})
+
+var _ = Describe("NewClusterAwareClient with ImpersonateByCluster", func() {
+	It("should impersonate the user of the logical cluster of each request", func() {
+		users := make(chan string, 10)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			users <- r.URL.Path + " " + r.Header.Get("Impersonate-User")
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm","namespace":"ns"}}`))
+		}))
+		defer server.Close()
+
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+		cl, err := kcp.NewClusterAwareClient(nil, &rest.Config{Host: server.URL}, client.Options{
+			Scheme: scheme.Scheme,
+			Mapper: mapper,
+			ImpersonateByCluster: func(cluster logicalcluster.Name) (rest.ImpersonationConfig, bool) {
+				return rest.ImpersonationConfig{UserName: "tenant-of-" + cluster.String()}, cluster != logicalcluster.New("root:b")
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cl.Create(context.Background(), &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "ns", ClusterName: "root:a"},
+		})).To(Succeed())
+		Expect(<-users).To(Equal("/clusters/root:a/api/v1/namespaces/ns/configmaps tenant-of-root:a"))
+
+		ctx := kcp.WithCluster(context.Background(), logicalcluster.New("root:b"))
+		Expect(cl.Delete(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "ns"}})).To(Succeed())
+		Expect(<-users).To(Equal("/clusters/root:b/api/v1/namespaces/ns/configmaps/cm "))
+	})
+})
+
+var _ = Describe("NewClusterAwareAPIReader", func() {
+	var server *httptest.Server
+	var paths chan string
+
+	BeforeEach(func() {
+		paths = make(chan string, 10)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			paths <- r.URL.Path
+			w.Header().Set("Content-Type", "application/json")
+			if r.URL.Path == "/clusters/root:b/api/v1/namespaces/ns/configmaps" {
+				_, _ = w.Write([]byte(`{"apiVersion":"v1","kind":"ConfigMapList","items":[]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm","namespace":"ns"}}`))
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should read live from the logical cluster of the key or of the context", func() {
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+		reader, err := kcp.NewClusterAwareAPIReader(&rest.Config{Host: server.URL}, client.Options{Scheme: scheme.Scheme, Mapper: mapper})
+		Expect(err).NotTo(HaveOccurred())
+
+		key := client.ObjectKey{NamespacedName: types.NamespacedName{Name: "cm", Namespace: "ns"}, Cluster: logicalcluster.New("root:a")}
+		Expect(reader.Get(context.Background(), key, &corev1.ConfigMap{})).To(Succeed())
+		Expect(<-paths).To(Equal("/clusters/root:a/api/v1/namespaces/ns/configmaps/cm"))
+
+		ctx := kcp.WithCluster(context.Background(), logicalcluster.New("root:b"))
+		Expect(reader.List(ctx, &corev1.ConfigMapList{}, client.InNamespace("ns"))).To(Succeed())
+		Expect(<-paths).To(Equal("/clusters/root:b/api/v1/namespaces/ns/configmaps"))
+	})
+})
+
+var _ = Describe("NewClusterAwareManager", func() {
+	var server *httptest.Server
+	var paths chan string
+
+	BeforeEach(func() {
+		paths = make(chan string, 10)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			paths <- r.URL.Path
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm","namespace":"ns"}}`))
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should build a manager whose client and API reader target the logical cluster of the object or of the context", func() {
+		mgr, err := kcp.NewClusterAwareManager(&rest.Config{Host: server.URL}, manager.Options{
+			Scheme:             scheme.Scheme,
+			MetricsBindAddress: "0",
+			MapperProvider: func(*rest.Config) (meta.RESTMapper, error) {
+				mapper := meta.NewDefaultRESTMapper(nil)
+				mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+				return mapper, nil
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(mgr.GetClient().Create(context.Background(), &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "ns", ClusterName: "root:a"},
+		})).To(Succeed())
+		Expect(<-paths).To(Equal("/clusters/root:a/api/v1/namespaces/ns/configmaps"))
+
+		ctx := kcp.WithCluster(context.Background(), logicalcluster.New("root:b"))
+		key := client.ObjectKey{NamespacedName: types.NamespacedName{Name: "cm", Namespace: "ns"}}
+		Expect(mgr.GetAPIReader().Get(ctx, key, &corev1.ConfigMap{})).To(Succeed())
+		Expect(<-paths).To(Equal("/clusters/root:b/api/v1/namespaces/ns/configmaps/cm"))
+	})
+})
@@ -0,0 +1,61 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kcp
+
+import (
+	"context"
+
+	"github.com/kcp-dev/logicalcluster"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	toolscache "k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+var apiextensionsv1CRD = schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"}
+
+// InvalidateOnCRDChanges watches the metadata of the CustomResourceDefinitions of all the
+// logical clusters with the informers, and invalidates the RESTMapper of the logical cluster
+// of a CRD whenever it is added, updated or deleted, so that the kinds of the logical cluster
+// are discovered again.
+func InvalidateOnCRDChanges(ctx context.Context, informers cache.Informers, mapper *apiutil.MultiClusterRESTMapper) error {
+	crd := &metav1.PartialObjectMetadata{}
+	crd.SetGroupVersionKind(apiextensionsv1CRD)
+	informer, err := informers.GetInformer(ctx, crd)
+	if err != nil {
+		return err
+	}
+	invalidate := func(obj interface{}) {
+		if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		if o, ok := obj.(client.Object); ok {
+			if cluster := logicalcluster.From(o); !cluster.Empty() {
+				mapper.Invalidate(cluster)
+			}
+		}
+	}
+	informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc:    invalidate,
+		UpdateFunc: func(_, newObj interface{}) { invalidate(newObj) },
+		DeleteFunc: invalidate,
+	})
+	return nil
+}
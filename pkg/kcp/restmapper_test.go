@@ -0,0 +1,58 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kcp_test
+
+import (
+	"context"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/kcp"
+)
+
+var _ = Describe("kcp.InvalidateOnCRDChanges", func() {
+	It("should invalidate the RESTMapper of the logical cluster of a changed CRD", func() {
+		a := logicalcluster.New("root:a")
+		b := logicalcluster.New("root:b")
+		mapper := apiutil.NewMultiClusterRESTMapperFor(func(logicalcluster.Name) (meta.RESTMapper, error) {
+			return meta.NewDefaultRESTMapper(nil), nil
+		})
+		for _, cluster := range []logicalcluster.Name{a, b} {
+			_, err := mapper.ForCluster(cluster)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		crdGVK := schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"}
+		scheme := runtime.NewScheme()
+		scheme.AddKnownTypeWithName(crdGVK, &metav1.PartialObjectMetadata{})
+		informers := &informertest.FakeInformers{Scheme: scheme}
+		Expect(kcp.InvalidateOnCRDChanges(context.Background(), informers, mapper)).To(Succeed())
+
+		informer, err := informers.FakeInformerFor(&metav1.PartialObjectMetadata{})
+		Expect(err).NotTo(HaveOccurred())
+		informer.Add(&metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: "foos.example.com", ClusterName: a.String()}})
+		Expect(mapper.Clusters()).To(Equal([]logicalcluster.Name{b}))
+	})
+})
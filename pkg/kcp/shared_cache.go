@@ -0,0 +1,245 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kcp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	toolscache "k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+)
+
+// SharedWildcardCache is a wildcard cache, watching the objects of all the logical clusters
+// with a single watch per kind, which serves as the cache of the Cluster of each logical
+// cluster of a cluster.ClusterSet, rather than each Cluster watching its logical cluster.
+// This reduces the number of watches from one per kind and logical cluster to one per kind,
+// for sets of hundreds of workspaces, at the cost of caching the objects of the logical
+// clusters which aren't part of the set.
+//
+// The cache of each Cluster is a view of the wildcard cache partitioned per logical cluster:
+// its reads only return the objects of the cluster, using indexes of the wildcard cache, and
+// the handlers of its informers are only notified of the events of the objects of the cluster,
+// until the Cluster is stopped.  Set the ClusterOptions of the set to the ClusterOptions of
+// the SharedWildcardCache to use it, e.g.:
+//
+//	shared, err := kcp.NewSharedWildcardCache(cfg, cache.Options{Scheme: scheme})
+//	...
+//	if err := mgr.Add(shared); err != nil {
+//		...
+//	}
+//	set, err := cluster.NewClusterSet(cfg, cluster.ClusterSetOptions{ClusterOptions: shared.ClusterOptions})
+//
+// or equivalently, set the CacheFactory of the set to the SharedWildcardCache.
+//
+// The SharedWildcardCache must be started, e.g. by adding it to a manager, for the caches of
+// the clusters to sync.  The cache.Options the clusters are built with, and the CacheByCluster
+// of the set, don't apply to the views: the options of the wildcard cache apply to all of them.
+type SharedWildcardCache struct {
+	cache.Cache
+
+	mu      sync.Mutex
+	indexed map[fieldIndex]struct{}
+}
+
+// fieldIndex identifies a field index added to a SharedWildcardCache.
+type fieldIndex struct {
+	objType string
+	gvk     schema.GroupVersionKind
+	field   string
+}
+
+// NewSharedWildcardCache returns a SharedWildcardCache watching the objects of all the logical
+// clusters through the wildcard endpoint of the config, which must target the root of the kcp
+// server, with a cache built by NewClusterAwareCache.
+func NewSharedWildcardCache(config *rest.Config, opts cache.Options) (*SharedWildcardCache, error) {
+	wildcard, err := NewClusterAwareCache(config, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &SharedWildcardCache{Cache: wildcard, indexed: map[fieldIndex]struct{}{}}, nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, the cache is started whether
+// or not the manager is the leader.
+func (s *SharedWildcardCache) NeedLeaderElection() bool {
+	return false
+}
+
+// IndexField implements client.FieldIndexer.  The index is shared by all the logical clusters,
+// so that it is only added once, however many clusters add it: the extractors given by the
+// clusters for the same type and field must be the same.
+func (s *SharedWildcardCache) IndexField(ctx context.Context, obj client.Object, field string, extractValue client.IndexerFunc) error {
+	key := fieldIndex{objType: fmt.Sprintf("%T", obj), gvk: obj.GetObjectKind().GroupVersionKind(), field: field}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.indexed[key]; ok {
+		return nil
+	}
+	if err := s.Cache.IndexField(ctx, obj, field, extractValue); err != nil {
+		return err
+	}
+	s.indexed[key] = struct{}{}
+	return nil
+}
+
+// ForCluster returns the view of the cache partitioned to the given logical cluster.
+func (s *SharedWildcardCache) ForCluster(name logicalcluster.Name) cache.Cache {
+	return &clusterView{shared: s, cluster: name, done: make(chan struct{})}
+}
+
+// ClusterInformers returns the informers of the cache restricted to the given logical cluster,
+// to share with code using client-go listers, see cache.ClusterInformers.
+func (s *SharedWildcardCache) ClusterInformers(name logicalcluster.Name) *cache.ClusterInformers {
+	return cache.NewClusterInformers(s.Cache, name)
+}
+
+// NewCacheFor returns a cache.NewCacheFunc returning the view of the cache partitioned to the
+// given logical cluster, see ForCluster.  The config and options are ignored.
+func (s *SharedWildcardCache) NewCacheFor(name logicalcluster.Name) cache.NewCacheFunc {
+	return func(*rest.Config, cache.Options) (cache.Cache, error) {
+		return s.ForCluster(name), nil
+	}
+}
+
+var _ cache.CacheFactory = &SharedWildcardCache{}
+
+// NewClusterCache implements cache.CacheFactory, it returns the view of the cache partitioned
+// to the given logical cluster, e.g. for the CacheFactory of a cluster.ClusterSet.  The config
+// and options are ignored.
+func (s *SharedWildcardCache) NewClusterCache(name logicalcluster.Name, _ *rest.Config, _ cache.Options) (cache.Cache, error) {
+	return s.ForCluster(name), nil
+}
+
+// ClusterOptions returns the options making the Cluster of the given logical cluster use the
+// view of the cache partitioned to it, for the ClusterOptions of a cluster.ClusterSet.
+func (s *SharedWildcardCache) ClusterOptions(name logicalcluster.Name) []cluster.Option {
+	return []cluster.Option{func(o *cluster.Options) { o.NewCache = s.NewCacheFor(name) }}
+}
+
+// clusterView is the cache.Cache of a logical cluster backed by a SharedWildcardCache.
+type clusterView struct {
+	shared  *SharedWildcardCache
+	cluster logicalcluster.Name
+
+	// done is closed once the view is stopped, so that the handlers added to the shared
+	// informers through it aren't notified anymore.
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+var _ cache.Cache = &clusterView{}
+
+// Get implements client.Reader.
+func (v *clusterView) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	key.Cluster = v.cluster
+	return v.shared.Get(ctx, key, obj)
+}
+
+// List implements client.Reader.
+func (v *clusterView) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if cluster := (&client.ListOptions{}).ApplyOptions(opts).Cluster; !cluster.Empty() && cluster != v.cluster {
+		return fmt.Errorf("logical cluster %s of the options does not match the logical cluster %s of the cache", cluster, v.cluster)
+	}
+	return v.shared.List(kcpclient.WithCluster(ctx, v.cluster), list, opts...)
+}
+
+// GetInformer implements cache.Informers.
+func (v *clusterView) GetInformer(ctx context.Context, obj client.Object) (cache.Informer, error) {
+	informer, err := v.shared.GetInformer(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	return &clusterInformer{Informer: informer, view: v}, nil
+}
+
+// GetInformerForKind implements cache.Informers.
+func (v *clusterView) GetInformerForKind(ctx context.Context, gvk schema.GroupVersionKind) (cache.Informer, error) {
+	informer, err := v.shared.GetInformerForKind(ctx, gvk)
+	if err != nil {
+		return nil, err
+	}
+	return &clusterInformer{Informer: informer, view: v}, nil
+}
+
+// IndexField implements client.FieldIndexer.
+func (v *clusterView) IndexField(ctx context.Context, obj client.Object, field string, extractValue client.IndexerFunc) error {
+	return v.shared.IndexField(ctx, obj, field, extractValue)
+}
+
+// Start implements cache.Informers.  It doesn't start the shared cache, it blocks until the
+// context is done and then stops notifying the handlers added through the view.
+func (v *clusterView) Start(ctx context.Context) error {
+	<-ctx.Done()
+	v.stopOnce.Do(func() { close(v.done) })
+	return nil
+}
+
+// WaitForCacheSync implements cache.Informers.  It waits for the shared cache to be started
+// and synced.
+func (v *clusterView) WaitForCacheSync(ctx context.Context) bool {
+	return v.shared.WaitForCacheSync(ctx)
+}
+
+// contains returns whether the object, or the tombstone of a deleted object, is in the
+// logical cluster of the view, and the view isn't stopped.
+func (v *clusterView) contains(obj interface{}) bool {
+	select {
+	case <-v.done:
+		return false
+	default:
+	}
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return false
+	}
+	return logicalcluster.From(accessor) == v.cluster
+}
+
+// clusterInformer is a shared informer notifying the handlers added to it of the events of
+// the objects of the logical cluster of its view only.
+type clusterInformer struct {
+	cache.Informer
+	view *clusterView
+}
+
+// AddEventHandler implements cache.Informer.
+func (i *clusterInformer) AddEventHandler(handler toolscache.ResourceEventHandler) {
+	i.Informer.AddEventHandler(i.filter(handler))
+}
+
+// AddEventHandlerWithResyncPeriod implements cache.Informer.
+func (i *clusterInformer) AddEventHandlerWithResyncPeriod(handler toolscache.ResourceEventHandler, resyncPeriod time.Duration) {
+	i.Informer.AddEventHandlerWithResyncPeriod(i.filter(handler), resyncPeriod)
+}
+
+func (i *clusterInformer) filter(handler toolscache.ResourceEventHandler) toolscache.ResourceEventHandler {
+	return toolscache.FilteringResourceEventHandler{FilterFunc: i.view.contains, Handler: handler}
+}
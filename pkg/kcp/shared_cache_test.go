@@ -0,0 +1,177 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kcp_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/rest"
+	toolscache "k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/kcp"
+)
+
+var _ = Describe("SharedWildcardCache", func() {
+	a, b := logicalcluster.New("root:a"), logicalcluster.New("root:b")
+	configMap := func(cluster logicalcluster.Name, name string) corev1.ConfigMap {
+		return corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns", ClusterName: cluster.String(), ResourceVersion: "1"},
+		}
+	}
+
+	var (
+		paths  chan string
+		server *httptest.Server
+		shared *kcp.SharedWildcardCache
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+
+	BeforeEach(func() {
+		paths = make(chan string, 10)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if r.URL.Query().Get("watch") == "true" {
+				created := configMap(b, "created")
+				created.ResourceVersion = "2"
+				_ = json.NewEncoder(w).Encode(&metav1.WatchEvent{Type: "ADDED", Object: runtime.RawExtension{Object: &created}})
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+				return
+			}
+			paths <- r.URL.Path
+			_ = json.NewEncoder(w).Encode(&corev1.ConfigMapList{
+				TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMapList"},
+				ListMeta: metav1.ListMeta{ResourceVersion: "1"},
+				Items:    []corev1.ConfigMap{configMap(a, "one"), configMap(b, "two")},
+			})
+		}))
+
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+		var err error
+		shared, err = kcp.NewSharedWildcardCache(&rest.Config{Host: server.URL}, cache.Options{Mapper: mapper})
+		Expect(err).NotTo(HaveOccurred())
+		ctx, cancel = context.WithCancel(context.Background())
+	})
+
+	AfterEach(func() {
+		cancel()
+		server.Close()
+	})
+
+	start := func() {
+		shared, ctx := shared, ctx
+		go func() {
+			defer GinkgoRecover()
+			Expect(shared.Start(ctx)).To(Succeed())
+		}()
+	}
+
+	It("should partition the objects of a single wildcard watch per logical cluster", func() {
+		inA, inB := shared.ForCluster(a), shared.ForCluster(b)
+		_, err := inA.GetInformer(ctx, &corev1.ConfigMap{})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = inB.GetInformer(ctx, &corev1.ConfigMap{})
+		Expect(err).NotTo(HaveOccurred())
+		start()
+		Expect(inA.WaitForCacheSync(ctx)).To(BeTrue())
+		Expect(inB.WaitForCacheSync(ctx)).To(BeTrue())
+		Expect(<-paths).To(Equal("/clusters/*/api/v1/configmaps"))
+		Consistently(paths).ShouldNot(Receive())
+
+		list := &corev1.ConfigMapList{}
+		Expect(inA.List(ctx, list, client.InNamespace("ns"))).To(Succeed())
+		Expect(list.Items).To(HaveLen(1))
+		Expect(list.Items[0].Name).To(Equal("one"))
+		Expect(inA.List(ctx, list, client.InCluster(b))).To(MatchError(ContainSubstring("does not match the logical cluster root:a of the cache")))
+		Eventually(func() int {
+			Expect(inB.List(ctx, list)).To(Succeed())
+			return len(list.Items)
+		}).Should(Equal(2))
+
+		key := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "one"}}
+		Expect(inA.Get(ctx, key, &corev1.ConfigMap{})).To(Succeed())
+		Expect(apierrors.IsNotFound(inB.Get(ctx, key, &corev1.ConfigMap{}))).To(BeTrue())
+	})
+
+	It("should only notify the handlers of the events of the logical cluster until the view is stopped", func() {
+		inA, inB, inStopped := shared.ForCluster(a), shared.ForCluster(b), shared.ForCluster(b)
+		stopped, stop := context.WithCancel(ctx)
+		stop()
+		Expect(inStopped.Start(stopped)).To(Succeed())
+
+		added := func(view cache.Cache) chan string {
+			names := make(chan string, 10)
+			informer, err := view.GetInformer(ctx, &corev1.ConfigMap{})
+			Expect(err).NotTo(HaveOccurred())
+			informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{AddFunc: func(obj interface{}) {
+				names <- obj.(*corev1.ConfigMap).Name
+			}})
+			return names
+		}
+		addedInA, addedInB, addedInStopped := added(inA), added(inB), added(inStopped)
+		start()
+
+		Eventually(addedInA).Should(Receive(Equal("one")))
+		Eventually(addedInB).Should(Receive(Equal("two")))
+		Eventually(addedInB).Should(Receive(Equal("created")))
+		Consistently(addedInA).ShouldNot(Receive())
+		Expect(addedInStopped).NotTo(Receive())
+	})
+
+	It("should share the informers of the wildcard watch with client-go listers per logical cluster", func() {
+		informer, err := shared.ClusterInformers(b).InformerFor(ctx, &corev1.ConfigMap{})
+		Expect(err).NotTo(HaveOccurred())
+		start()
+		Expect(toolscache.WaitForCacheSync(ctx.Done(), informer.HasSynced)).To(BeTrue())
+
+		lister := corev1listers.NewConfigMapLister(informer.GetIndexer())
+		Eventually(func() ([]*corev1.ConfigMap, error) {
+			return lister.ConfigMaps("ns").List(labels.Everything())
+		}).Should(HaveLen(2))
+		_, err = lister.ConfigMaps("ns").Get("two")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = lister.ConfigMaps("ns").Get("one")
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		Expect(<-paths).To(Equal("/clusters/*/api/v1/configmaps"))
+		Consistently(paths).ShouldNot(Receive())
+	})
+
+	It("should add the field indexes shared by the logical clusters once", func() {
+		extract := func(obj client.Object) []string { return []string{obj.GetName()} }
+		Expect(shared.ForCluster(a).IndexField(ctx, &corev1.ConfigMap{}, "name", extract)).To(Succeed())
+		Expect(shared.ForCluster(b).IndexField(ctx, &corev1.ConfigMap{}, "name", extract)).To(Succeed())
+	})
+})
@@ -20,5 +20,9 @@ This is used to ensure that multiple copies of a controller manager can be run w
 only one active set of controllers, for active-passive HA.
 
 It uses built-in Kubernetes leader election APIs.
+
+A ShardedElector elects a leader per shard of logical clusters instead, so that several
+copies of a controller manager can be active at once, each reconciling its share of the
+logical clusters.
 */
 package leaderelection
@@ -0,0 +1,198 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leaderelection
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/internal/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var handoverLog = logf.RuntimeLog.WithName("queue-handover")
+
+// QueueHandover hands the requests pending in the queues of the controllers over to the next
+// leader, so that the pending work, and the backoff of the failing requests, aren't lost when
+// the leader steps down, e.g. during a rolling update.  The controllers save their pending
+// requests once their workers are stopped, and take the saved ones back when they start.  It
+// is meant for the managers which release their lease on shutdown, see the
+// LeaderElectionReleaseOnCancel option of the manager.
+type QueueHandover interface {
+	// Save stores the pending requests of the named controller, replacing the ones stored before.
+	Save(ctx context.Context, controller string, reqs []PendingRequest) error
+
+	// Take returns the pending requests stored for the named controller, and removes them.
+	Take(ctx context.Context, controller string) ([]PendingRequest, error)
+}
+
+// PendingRequest is a request pending in the queue of a controller.
+type PendingRequest struct {
+	reconcile.Request
+
+	// ReadyAt is when the request is due, e.g. once its backoff expires, or zero if it
+	// is due right away.
+	ReadyAt time.Time
+}
+
+// pendingEntry is the stored form of a PendingRequest.
+type pendingEntry struct {
+	Key     string       `json:"key"`
+	ReadyAt *metav1.Time `json:"readyAt,omitempty"`
+}
+
+// NewConfigMapQueueHandover returns a QueueHandover storing the pending requests of each
+// controller under a key of the given ConfigMap, which is created if needed.  The names of
+// the controllers must be valid ConfigMap keys.
+func NewConfigMapQueueHandover(config *rest.Config, namespace, name string) (QueueHandover, error) {
+	client, err := corev1client.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return newConfigMapQueueHandover(client, namespace, name)
+}
+
+func newConfigMapQueueHandover(configMaps corev1client.ConfigMapsGetter, namespace, name string) (*configMapQueueHandover, error) {
+	if namespace == "" {
+		var err error
+		namespace, err = getInClusterNamespace()
+		if err != nil {
+			return nil, fmt.Errorf("unable to find the namespace of the queue handover ConfigMap: %w", err)
+		}
+	}
+	if name == "" {
+		return nil, fmt.Errorf("must specify the name of the queue handover ConfigMap")
+	}
+	return &configMapQueueHandover{configMaps: configMaps.ConfigMaps(namespace), name: name}, nil
+}
+
+// configMapQueueHandover is a QueueHandover storing the requests in a ConfigMap.
+type configMapQueueHandover struct {
+	configMaps corev1client.ConfigMapInterface
+	name       string
+}
+
+// Save implements QueueHandover.
+func (h *configMapQueueHandover) Save(ctx context.Context, controller string, reqs []PendingRequest) error {
+	if errs := validation.IsConfigMapKey(controller); len(errs) > 0 {
+		return fmt.Errorf("cannot hand over the queue of controller %q: %s", controller, strings.Join(errs, ", "))
+	}
+	entries := make([]pendingEntry, 0, len(reqs))
+	for _, req := range reqs {
+		entry := pendingEntry{Key: req.Key()}
+		if !req.ReadyAt.IsZero() {
+			entry.ReadyAt = &metav1.Time{Time: req.ReadyAt}
+		}
+		entries = append(entries, entry)
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := h.configMaps.Get(ctx, h.name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			if len(reqs) == 0 {
+				return nil
+			}
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: h.name},
+				Data:       map[string]string{controller: string(data)},
+			}
+			_, err = h.configMaps.Create(ctx, cm, metav1.CreateOptions{})
+			if apierrors.IsAlreadyExists(err) {
+				// Retry as a conflict, to update the ConfigMap created concurrently.
+				return apierrors.NewConflict(corev1.Resource("configmaps"), h.name, err)
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if len(reqs) == 0 {
+			if _, ok := cm.Data[controller]; !ok {
+				return nil
+			}
+			delete(cm.Data, controller)
+		} else {
+			if cm.Data == nil {
+				cm.Data = map[string]string{}
+			}
+			cm.Data[controller] = string(data)
+		}
+		_, err = h.configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// Take implements QueueHandover.
+func (h *configMapQueueHandover) Take(ctx context.Context, controller string) ([]PendingRequest, error) {
+	var data string
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := h.configMaps.Get(ctx, h.name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var ok bool
+		if data, ok = cm.Data[controller]; !ok {
+			return nil
+		}
+		delete(cm.Data, controller)
+		_, err = h.configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil || data == "" {
+		return nil, err
+	}
+
+	// The requests are already removed from the ConfigMap, so the ones which don't decode are
+	// dropped rather than failing, which would lose the others too.
+	var entries []pendingEntry
+	if err := json.Unmarshal([]byte(data), &entries); err != nil {
+		handoverLog.Error(err, "Dropping the queue handed over which doesn't decode", "controller", controller)
+		return nil, nil
+	}
+	reqs := make([]PendingRequest, 0, len(entries))
+	for _, entry := range entries {
+		req, err := reconcile.ParseKey(entry.Key)
+		if err != nil {
+			handoverLog.Error(err, "Dropping the handed over request which doesn't decode", "controller", controller)
+			continue
+		}
+		pending := PendingRequest{Request: req}
+		if entry.ReadyAt != nil {
+			pending.ReadyAt = entry.ReadyAt.Time
+		}
+		reqs = append(reqs, pending)
+	}
+	return reqs, nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leaderelection
+
+import (
+	"context"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("ConfigMapQueueHandover", func() {
+	ctx := context.Background()
+	var clientset *fake.Clientset
+	var handover *configMapQueueHandover
+
+	BeforeEach(func() {
+		clientset = fake.NewSimpleClientset()
+		var err error
+		handover, err = newConfigMapQueueHandover(clientset.CoreV1(), "default", "test-queues")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should hand the pending requests of each controller over once", func() {
+		readyAt := time.Now().Add(time.Hour).Truncate(time.Second)
+		reqs := []PendingRequest{
+			{Request: reconcile.Request{ObjectKey: client.ObjectKey{
+				NamespacedName: types.NamespacedName{Namespace: "ns", Name: "foo"},
+				Cluster:        logicalcluster.New("root:org:ws"),
+			}}.WithExtra("reason", "drift"), ReadyAt: readyAt},
+			{Request: reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: types.NamespacedName{Name: "bar"}}}},
+		}
+		Expect(handover.Save(ctx, "foo-controller", reqs)).To(Succeed())
+		Expect(handover.Save(ctx, "bar-controller", reqs[1:])).To(Succeed())
+
+		taken, err := handover.Take(ctx, "foo-controller")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(taken).To(HaveLen(2))
+		Expect(taken[0].Request).To(Equal(reqs[0].Request))
+		Expect(taken[0].ReadyAt.Equal(readyAt)).To(BeTrue())
+		Expect(taken[1]).To(Equal(reqs[1]))
+
+		taken, err = handover.Take(ctx, "foo-controller")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(taken).To(BeEmpty())
+
+		cm, err := clientset.CoreV1().ConfigMaps("default").Get(ctx, "test-queues", metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cm.Data).To(HaveLen(1))
+		Expect(cm.Data).To(HaveKey("bar-controller"))
+
+		By("dropping the requests of a controller which has none pending anymore")
+		Expect(handover.Save(ctx, "bar-controller", nil)).To(Succeed())
+		taken, err = handover.Take(ctx, "bar-controller")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(taken).To(BeEmpty())
+	})
+
+	It("should hand over the requests of removed logical clusters", func() {
+		reqs := []PendingRequest{
+			{Request: reconcile.ClusterRemovedRequest(logicalcluster.New("root:org:ws"))},
+			{Request: reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: types.NamespacedName{Name: "foo"}}}},
+		}
+		Expect(handover.Save(ctx, "foo-controller", reqs)).To(Succeed())
+
+		taken, err := handover.Take(ctx, "foo-controller")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(taken).To(Equal(reqs))
+		Expect(taken[0].IsClusterRemoved()).To(BeTrue())
+	})
+
+	It("should skip the handed over requests which don't decode", func() {
+		_, err := clientset.CoreV1().ConfigMaps("default").Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-queues"},
+			Data:       map[string]string{"foo-controller": `[{"key":"bar/"},{"key":"ns/foo"}]`},
+		}, metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		taken, err := handover.Take(ctx, "foo-controller")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(taken).To(Equal([]PendingRequest{{Request: reconcile.Request{ObjectKey: client.ObjectKey{
+			NamespacedName: types.NamespacedName{Namespace: "ns", Name: "foo"},
+		}}}}))
+	})
+
+	It("should refuse the controllers whose name isn't a valid ConfigMap key", func() {
+		err := handover.Save(ctx, "foo/controller", nil)
+		Expect(err).To(MatchError(ContainSubstring(`cannot hand over the queue of controller "foo/controller"`)))
+	})
+})
@@ -49,6 +49,11 @@ type Options struct {
 	// LeaderElectionID determines the name of the resource that leader election
 	// will use for holding the leader lock.
 	LeaderElectionID string
+
+	// LeaderElectionIdentitySuffix is appended to the identity of the candidate,
+	// <hostname>_<uuid>, e.g. to tell apart several managers of the same pod holding
+	// locks, such as the managers of the shards of a multi-cluster controller.
+	LeaderElectionIdentitySuffix string
 }
 
 // NewResourceLock creates a new resource lock for use in a leader election loop.
@@ -79,11 +84,13 @@ func NewResourceLock(config *rest.Config, recorderProvider recorder.Provider, op
 	}
 
 	// Leader id, needs to be unique
-	id, err := os.Hostname()
+	id, err := newIdentity()
 	if err != nil {
 		return nil, err
 	}
-	id = id + "_" + string(uuid.NewUUID())
+	if options.LeaderElectionIdentitySuffix != "" {
+		id += "_" + options.LeaderElectionIdentitySuffix
+	}
 
 	// Construct clients for leader election
 	rest.AddUserAgent(config, "leader-election")
@@ -97,7 +104,7 @@ func NewResourceLock(config *rest.Config, recorderProvider recorder.Provider, op
 		return nil, err
 	}
 
-	return resourcelock.New(options.LeaderElectionResourceLock,
+	lock, err := resourcelock.New(options.LeaderElectionResourceLock,
 		options.LeaderElectionNamespace,
 		options.LeaderElectionID,
 		corev1Client,
@@ -106,6 +113,19 @@ func NewResourceLock(config *rest.Config, recorderProvider recorder.Provider, op
 			Identity:      id,
 			EventRecorder: recorderProvider.GetEventRecorderFor(id),
 		})
+	if err != nil {
+		return nil, err
+	}
+	return WithRenewalMetrics(lock), nil
+}
+
+// newIdentity returns a unique identity for a leader election candidate.
+func newIdentity() (string, error) {
+	id, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+	return id + "_" + string(uuid.NewUUID()), nil
 }
 
 func getInClusterNamespace() (string, error) {
@@ -0,0 +1,86 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leaderelection
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// lockRenewals counts the writes of the leader election locks, i.e. their acquisitions
+	// and renewals.
+	lockRenewals = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "controller_runtime_leader_election_renewals_total",
+		Help: "Total number of acquisitions and renewals of the leader election lock per lock and result",
+	}, []string{"lock", "result"})
+
+	// lockLastRenewal is the time of the last successful write of the leader election locks.
+	lockLastRenewal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "controller_runtime_leader_election_last_renewal_timestamp_seconds",
+		Help: "Time of the last successful acquisition or renewal of the leader election lock per lock",
+	}, []string{"lock"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(lockRenewals, lockLastRenewal)
+}
+
+// WithRenewalMetrics returns a lock recording its acquisitions and renewals, and their
+// failures, in the controller_runtime_leader_election_renewals_total and
+// controller_runtime_leader_election_last_renewal_timestamp_seconds metrics.  The locks
+// returned by NewResourceLock already record them.
+func WithRenewalMetrics(lock resourcelock.Interface) resourcelock.Interface {
+	if _, ok := lock.(*metricsLock); ok {
+		return lock
+	}
+	return &metricsLock{Interface: lock}
+}
+
+// metricsLock records the writes of the lock it wraps.
+type metricsLock struct {
+	resourcelock.Interface
+}
+
+// Create implements resourcelock.Interface.
+func (l *metricsLock) Create(ctx context.Context, ler resourcelock.LeaderElectionRecord) error {
+	err := l.Interface.Create(ctx, ler)
+	l.record(err)
+	return err
+}
+
+// Update implements resourcelock.Interface.
+func (l *metricsLock) Update(ctx context.Context, ler resourcelock.LeaderElectionRecord) error {
+	err := l.Interface.Update(ctx, ler)
+	l.record(err)
+	return err
+}
+
+func (l *metricsLock) record(err error) {
+	name := l.Describe()
+	if err != nil {
+		lockRenewals.WithLabelValues(name, "failure").Inc()
+		return
+	}
+	lockRenewals.WithLabelValues(name, "success").Inc()
+	lockLastRenewal.WithLabelValues(name).Set(float64(time.Now().UnixNano()) / float64(time.Second))
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leaderelection
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// fakeLock is a resourcelock.Interface whose writes fail with err.
+type fakeLock struct {
+	resourcelock.Interface
+	err error
+}
+
+func (l *fakeLock) Create(context.Context, resourcelock.LeaderElectionRecord) error { return l.err }
+func (l *fakeLock) Update(context.Context, resourcelock.LeaderElectionRecord) error { return l.err }
+func (l *fakeLock) Describe() string                                                { return "default/metrics-test" }
+
+var _ = Describe("WithRenewalMetrics", func() {
+	It("should record the acquisitions and renewals of the lock, and their failures", func() {
+		inner := &fakeLock{}
+		lock := WithRenewalMetrics(inner)
+		Expect(WithRenewalMetrics(lock)).To(BeIdenticalTo(lock))
+
+		ctx := context.Background()
+		Expect(lock.Create(ctx, resourcelock.LeaderElectionRecord{})).To(Succeed())
+		Expect(lock.Update(ctx, resourcelock.LeaderElectionRecord{})).To(Succeed())
+		inner.err = errors.New("conflict")
+		Expect(lock.Update(ctx, resourcelock.LeaderElectionRecord{})).To(MatchError("conflict"))
+
+		Expect(testutil.ToFloat64(lockRenewals.WithLabelValues("default/metrics-test", "success"))).To(Equal(2.0))
+		Expect(testutil.ToFloat64(lockRenewals.WithLabelValues("default/metrics-test", "failure"))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(lockLastRenewal.WithLabelValues("default/metrics-test"))).To(BeNumerically(">", 0))
+	})
+})
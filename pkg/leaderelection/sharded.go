@@ -0,0 +1,446 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leaderelection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coordinationv1client "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	"k8s.io/client-go/rest"
+	k8sleaderelection "k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/utils/pointer"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/internal/log"
+)
+
+var shardLog = logf.RuntimeLog.WithName("sharded-leader-election")
+
+// ShardedIDLabel is the label of the Leases announcing the members of a sharded leader
+// election, whose value is the ID of the election.
+const ShardedIDLabel = "leaderelection.controller-runtime.sigs.k8s.io/sharded-id"
+
+const (
+	defaultShardLeaseDuration = 15 * time.Second
+	defaultShardRenewDeadline = 10 * time.Second
+	defaultShardRetryPeriod   = 2 * time.Second
+)
+
+// ShardedOptions configures a sharded leader election.
+type ShardedOptions struct {
+	// Shards is the number of shards the logical clusters are split into.  Each shard has
+	// its own leader.  It must be the same for all the members of the election.
+	Shards int
+
+	// Namespace is the namespace of the Leases of the election.  It defaults to the
+	// namespace of the process when it runs in a cluster.
+	Namespace string
+
+	// ID is the prefix of the names of the Leases of the election.
+	ID string
+
+	// Identity is the identity of this member of the election.  It defaults to the hostname
+	// with a random suffix.
+	Identity string
+
+	// LeaseDuration, RenewDeadline and RetryPeriod configure the election of each shard,
+	// like the options of the same name of the manager.  They default to 15, 10 and 2
+	// seconds.  The shards are rebalanced every RetryPeriod.
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+}
+
+// ShardHandler is notified when a ShardedElector starts or stops leading a shard.  The
+// notifications are delivered in order, with the elector locked, so handlers must not call
+// the methods of the elector.
+type ShardHandler interface {
+	// ShardAcquired is called once the elector leads the shard.
+	ShardAcquired(shard int)
+
+	// ShardReleased is called once the elector stopped leading the shard.
+	ShardReleased(shard int)
+}
+
+// ShardHandlerFuncs is an adaptor to let you easily specify as many or as few of the
+// notification functions as you want while still implementing ShardHandler.
+type ShardHandlerFuncs struct {
+	AcquiredFunc func(shard int)
+	ReleasedFunc func(shard int)
+}
+
+// ShardAcquired calls AcquiredFunc if it's not nil.
+func (f ShardHandlerFuncs) ShardAcquired(shard int) {
+	if f.AcquiredFunc != nil {
+		f.AcquiredFunc(shard)
+	}
+}
+
+// ShardReleased calls ReleasedFunc if it's not nil.
+func (f ShardHandlerFuncs) ShardReleased(shard int) {
+	if f.ReleasedFunc != nil {
+		f.ReleasedFunc(shard)
+	}
+}
+
+// ShardedElector elects a leader per shard of logical clusters, so that several replicas
+// of a manager split the logical clusters between them instead of a single leader
+// reconciling all of them.
+//
+// Each member announces itself with a Lease, and campaigns for shards until it leads its
+// fair share of them, i.e. the number of shards divided by the number of members, rounded
+// up.  When members join, the ones leading more than their fair share release the extra
+// shards; when members leave, their shards are released, or expire, and are acquired by
+// the others.
+//
+// A ShardedElector is a Runnable which doesn't need leader election: the manager must not
+// use leader election itself, and the controllers only watch the clusters of the shards
+// this member leads, e.g. with cluster.NewShardedHandler.
+type ShardedElector struct {
+	opts   ShardedOptions
+	leases coordinationv1client.LeasesGetter
+
+	mu        sync.Mutex
+	started   bool
+	campaigns map[int]*shardCampaign
+	handlers  []ShardHandler
+}
+
+// shardCampaign is the election of a shard this member campaigns for.
+type shardCampaign struct {
+	// cancel stops campaigning, and releases the shard if it is led; done is closed once
+	// the campaign is over.
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	leading bool
+}
+
+// NewShardedElector returns a ShardedElector for the given options, whose Leases are in
+// the cluster of the given config.
+func NewShardedElector(config *rest.Config, opts ShardedOptions) (*ShardedElector, error) {
+	config = rest.CopyConfig(config)
+	rest.AddUserAgent(config, "leader-election")
+	client, err := coordinationv1client.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return newShardedElector(client, opts)
+}
+
+func newShardedElector(leases coordinationv1client.LeasesGetter, opts ShardedOptions) (*ShardedElector, error) {
+	if opts.Shards < 1 {
+		return nil, errors.New("Shards must be at least 1")
+	}
+	if opts.ID == "" {
+		return nil, errors.New("ID must be configured")
+	}
+	if opts.Namespace == "" {
+		var err error
+		opts.Namespace, err = getInClusterNamespace()
+		if err != nil {
+			return nil, fmt.Errorf("unable to find leader election namespace: %w", err)
+		}
+	}
+	if opts.Identity == "" {
+		var err error
+		if opts.Identity, err = newIdentity(); err != nil {
+			return nil, err
+		}
+	}
+	if opts.LeaseDuration == 0 {
+		opts.LeaseDuration = defaultShardLeaseDuration
+	}
+	if opts.RenewDeadline == 0 {
+		opts.RenewDeadline = defaultShardRenewDeadline
+	}
+	if opts.RetryPeriod == 0 {
+		opts.RetryPeriod = defaultShardRetryPeriod
+	}
+	return &ShardedElector{
+		opts:      opts,
+		leases:    leases,
+		campaigns: map[int]*shardCampaign{},
+	}, nil
+}
+
+// ShardFor returns the shard of the logical cluster.
+func (e *ShardedElector) ShardFor(cluster logicalcluster.Name) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(cluster.String()))
+	return int(h.Sum32() % uint32(e.opts.Shards))
+}
+
+// IsLeaderFor returns whether this member leads the shard of the logical cluster.
+func (e *ShardedElector) IsLeaderFor(cluster logicalcluster.Name) bool {
+	return e.IsLeading(e.ShardFor(cluster))
+}
+
+// IsLeading returns whether this member leads the shard.
+func (e *ShardedElector) IsLeading(shard int) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	c, ok := e.campaigns[shard]
+	return ok && c.leading
+}
+
+// Shards returns the shards this member leads, sorted.
+func (e *ShardedElector) Shards() []int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leadingShards()
+}
+
+// AddHandler registers a handler for the shards this member starts or stops leading.  It is
+// immediately notified of the shards this member already leads.
+func (e *ShardedElector) AddHandler(h ShardHandler) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.handlers = append(e.handlers, h)
+	for _, shard := range e.leadingShards() {
+		h.ShardAcquired(shard)
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+func (e *ShardedElector) NeedLeaderElection() bool {
+	return false
+}
+
+// Start announces this member, and campaigns for its fair share of the shards until the
+// context is done.  It then releases the shards it leads and its membership, and returns
+// once they are released.
+func (e *ShardedElector) Start(ctx context.Context) error {
+	e.mu.Lock()
+	if e.started {
+		e.mu.Unlock()
+		return errors.New("sharded elector was started more than once")
+	}
+	e.started = true
+	e.mu.Unlock()
+
+	wait.UntilWithContext(ctx, e.rebalance, e.opts.RetryPeriod)
+
+	e.mu.Lock()
+	campaigns := make([]*shardCampaign, 0, len(e.campaigns))
+	for _, c := range e.campaigns {
+		c.cancel()
+		campaigns = append(campaigns, c)
+	}
+	e.mu.Unlock()
+	for _, c := range campaigns {
+		<-c.done
+	}
+
+	releaseCtx, cancel := context.WithTimeout(context.Background(), e.opts.RenewDeadline)
+	defer cancel()
+	if err := e.leases.Leases(e.opts.Namespace).Delete(releaseCtx, e.memberLeaseName(), metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to release the membership of %s: %w", e.opts.Identity, err)
+	}
+	return nil
+}
+
+// rebalance renews the membership of this member, and campaigns for or releases shards so
+// that it leads its fair share of them.
+func (e *ShardedElector) rebalance(ctx context.Context) {
+	if err := e.renewMembership(ctx); err != nil {
+		shardLog.Error(err, "Failed to renew the membership", "identity", e.opts.Identity)
+		return
+	}
+	members, err := e.countMembers(ctx)
+	if err != nil {
+		shardLog.Error(err, "Failed to list the members", "identity", e.opts.Identity)
+		return
+	}
+	fairShare := (e.opts.Shards + members - 1) / members
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	leading := e.leadingShards()
+	switch {
+	case len(leading) > fairShare:
+		// Release the extra shards, so that the new members can acquire them.
+		for _, shard := range leading[fairShare:] {
+			shardLog.Info("Releasing shard to rebalance", "shard", shard, "members", members)
+			e.campaigns[shard].cancel()
+		}
+		fallthrough
+	case len(leading) == fairShare:
+		// Stop competing with the other members for the shards they lead.
+		for _, c := range e.campaigns {
+			if !c.leading {
+				c.cancel()
+			}
+		}
+	default:
+		for shard := 0; shard < e.opts.Shards; shard++ {
+			if _, ok := e.campaigns[shard]; !ok {
+				if err := e.campaign(ctx, shard); err != nil {
+					shardLog.Error(err, "Failed to campaign", "shard", shard)
+				}
+			}
+		}
+	}
+}
+
+// campaign starts the election of a shard.  e.mu must be held.
+func (e *ShardedElector) campaign(ctx context.Context, shard int) error {
+	ctx, cancel := context.WithCancel(ctx)
+	c := &shardCampaign{cancel: cancel, done: make(chan struct{})}
+	elector, err := k8sleaderelection.NewLeaderElector(k8sleaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Namespace: e.opts.Namespace, Name: e.shardLeaseName(shard)},
+			Client:     e.leases,
+			LockConfig: resourcelock.ResourceLockConfig{Identity: e.opts.Identity},
+		},
+		LeaseDuration:   e.opts.LeaseDuration,
+		RenewDeadline:   e.opts.RenewDeadline,
+		RetryPeriod:     e.opts.RetryPeriod,
+		ReleaseOnCancel: true,
+		Callbacks: k8sleaderelection.LeaderCallbacks{
+			OnStartedLeading: func(leadCtx context.Context) {
+				e.mu.Lock()
+				defer e.mu.Unlock()
+				// The callback runs asynchronously, after the leadership may be lost already.
+				if leadCtx.Err() != nil {
+					return
+				}
+				c.leading = true
+				shardLog.Info("Acquired shard", "shard", shard, "identity", e.opts.Identity)
+				for _, h := range e.handlers {
+					h.ShardAcquired(shard)
+				}
+			},
+			OnStoppedLeading: func() {
+				e.mu.Lock()
+				defer e.mu.Unlock()
+				if !c.leading {
+					return
+				}
+				c.leading = false
+				shardLog.Info("Released shard", "shard", shard, "identity", e.opts.Identity)
+				for _, h := range e.handlers {
+					h.ShardReleased(shard)
+				}
+			},
+		},
+		Name: e.shardLeaseName(shard),
+	})
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	e.campaigns[shard] = c
+	go func() {
+		defer close(c.done)
+		elector.Run(ctx)
+
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		if e.campaigns[shard] == c {
+			delete(e.campaigns, shard)
+		}
+	}()
+	return nil
+}
+
+// renewMembership creates or renews the Lease announcing this member.
+func (e *ShardedElector) renewMembership(ctx context.Context) error {
+	leases := e.leases.Leases(e.opts.Namespace)
+	now := metav1.NewMicroTime(time.Now())
+	lease, err := leases.Get(ctx, e.memberLeaseName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: e.opts.Namespace,
+				Name:      e.memberLeaseName(),
+				Labels:    map[string]string{ShardedIDLabel: e.opts.ID},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       pointer.String(e.opts.Identity),
+				LeaseDurationSeconds: pointer.Int32(int32(e.opts.LeaseDuration / time.Second)),
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		_, err = leases.Create(ctx, lease, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	lease.Spec.RenewTime = &now
+	_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+	return err
+}
+
+// countMembers returns the number of members whose membership has not expired, including
+// this member.
+func (e *ShardedElector) countMembers(ctx context.Context) (int, error) {
+	list, err := e.leases.Leases(e.opts.Namespace).List(ctx, metav1.ListOptions{LabelSelector: ShardedIDLabel + "=" + e.opts.ID})
+	if err != nil {
+		return 0, err
+	}
+	members := 1
+	for _, lease := range list.Items {
+		if lease.Name == e.memberLeaseName() || lease.Spec.RenewTime == nil {
+			continue
+		}
+		duration := e.opts.LeaseDuration
+		if lease.Spec.LeaseDurationSeconds != nil {
+			duration = time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second
+		}
+		if time.Since(lease.Spec.RenewTime.Time) < duration {
+			members++
+		}
+	}
+	return members, nil
+}
+
+// leadingShards returns the shards this member leads, sorted.  e.mu must be held.
+func (e *ShardedElector) leadingShards() []int {
+	shards := []int{}
+	for shard, c := range e.campaigns {
+		if c.leading {
+			shards = append(shards, shard)
+		}
+	}
+	sort.Ints(shards)
+	return shards
+}
+
+func (e *ShardedElector) shardLeaseName(shard int) string {
+	return fmt.Sprintf("%s-shard-%d", e.opts.ID, shard)
+}
+
+func (e *ShardedElector) memberLeaseName() string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(e.opts.Identity))
+	return fmt.Sprintf("%s-member-%08x", e.opts.ID, h.Sum32())
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leaderelection
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("ShardedElector", func() {
+	var clientset *fake.Clientset
+
+	BeforeEach(func() {
+		clientset = fake.NewSimpleClientset()
+	})
+
+	newElector := func(identity string) *ShardedElector {
+		e, err := newShardedElector(clientset.CoordinationV1(), ShardedOptions{
+			Shards:        4,
+			Namespace:     "default",
+			ID:            "test",
+			Identity:      identity,
+			LeaseDuration: 2 * time.Second,
+			RenewDeadline: time.Second,
+			RetryPeriod:   200 * time.Millisecond,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return e
+	}
+
+	start := func(e *ShardedElector) (context.CancelFunc, chan struct{}) {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(e.Start(ctx)).To(Succeed())
+		}()
+		return cancel, done
+	}
+
+	It("should reject invalid options", func() {
+		_, err := newShardedElector(clientset.CoordinationV1(), ShardedOptions{ID: "test", Namespace: "default"})
+		Expect(err).To(HaveOccurred())
+		_, err = newShardedElector(clientset.CoordinationV1(), ShardedOptions{Shards: 2, Namespace: "default"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should assign every logical cluster to a stable shard", func() {
+		e := newElector("a")
+		cluster := logicalcluster.New("root:org:ws")
+		Expect(e.ShardFor(cluster)).To(BeNumerically("<", 4))
+		Expect(e.ShardFor(cluster)).To(Equal(newElector("b").ShardFor(cluster)))
+	})
+
+	It("should split the shards between the members, and rebalance them when members come and go", func() {
+		a, b := newElector("a"), newElector("b")
+
+		var mu sync.Mutex
+		acquired := map[int]int{}
+		a.AddHandler(ShardHandlerFuncs{
+			AcquiredFunc: func(shard int) { mu.Lock(); acquired[shard]++; mu.Unlock() },
+			ReleasedFunc: func(shard int) { mu.Lock(); acquired[shard]--; mu.Unlock() },
+		})
+
+		By("leading all the shards alone")
+		cancelA, doneA := start(a)
+		defer func() { cancelA(); <-doneA }()
+		Eventually(a.Shards, 10*time.Second).Should(Equal([]int{0, 1, 2, 3}))
+		Expect(a.IsLeaderFor(logicalcluster.New("root:org:ws"))).To(BeTrue())
+
+		By("releasing half of the shards to a new member")
+		cancelB, doneB := start(b)
+		Eventually(a.Shards, 10*time.Second).Should(HaveLen(2))
+		Eventually(b.Shards, 10*time.Second).Should(HaveLen(2))
+		Consistently(func() []int { return append(a.Shards(), b.Shards()...) }, time.Second).Should(ConsistOf(0, 1, 2, 3))
+
+		mu.Lock()
+		for _, shard := range a.Shards() {
+			Expect(acquired[shard]).To(Equal(1))
+		}
+		for _, shard := range b.Shards() {
+			Expect(acquired[shard]).To(Equal(0))
+		}
+		mu.Unlock()
+
+		By("acquiring the shards of a member which left")
+		cancelB()
+		Eventually(doneB).Should(BeClosed())
+		Expect(b.Shards()).To(BeEmpty())
+		Eventually(a.Shards, 10*time.Second).Should(Equal([]int{0, 1, 2, 3}))
+	})
+})
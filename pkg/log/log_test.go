@@ -19,8 +19,11 @@ package log
 import (
 	"context"
 	"errors"
+	"fmt"
+	"runtime"
 
 	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -327,4 +330,48 @@ var _ = Describe("logging", func() {
 		})
 	})
 
+	Describe("swappable logger", func() {
+		It("should route derived loggers to the swapped sink", func() {
+			By("logging to a derived logger before swapping")
+			before := &fakeLoggerRoot{}
+			swappable := NewSwappableLogSink(&fakeLogger{root: before})
+			l := logr.New(swappable).WithName("controller").WithValues("tag", "value")
+			l.Info("before swap")
+
+			By("swapping the sink and logging to the same logger")
+			after := &fakeLoggerRoot{}
+			swappable.Swap(&fakeLogger{root: after})
+			l.Info("after swap")
+
+			Expect(before.messages).To(ConsistOf(
+				logInfo{name: []string{"controller"}, tags: []interface{}{"tag", "value"}, msg: "before swap"},
+			))
+			Expect(after.messages).To(ConsistOf(
+				logInfo{name: []string{"controller"}, tags: []interface{}{"tag", "value"}, msg: "after swap"},
+			))
+		})
+
+		It("should attribute the messages to their callers, before and after swapping", func() {
+			var lines []string
+			newLogger := func() logr.Logger {
+				return funcr.New(func(_, args string) { lines = append(lines, args) }, funcr.Options{LogCaller: funcr.All})
+			}
+			swappable := NewSwappableLogSink(newLogger().GetSink())
+			l := logr.New(swappable).WithName("controller")
+			helper := func(msg string) { l.WithCallDepth(1).Info(msg) }
+
+			_, _, line, _ := runtime.Caller(0)
+			l.Info("before swap")
+			helper("helper before swap")
+			swappable.Swap(newLogger().GetSink())
+			l.Info("after swap")
+			helper("helper after swap")
+
+			Expect(lines).To(HaveLen(4))
+			for i, offset := range []int{1, 2, 4, 5} {
+				Expect(lines[i]).To(ContainSubstring(fmt.Sprintf(`"caller"={"file":"log_test.go","line":%d}`, line+offset)))
+			}
+		})
+	})
+
 })
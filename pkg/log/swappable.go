@@ -0,0 +1,156 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package log
+
+import (
+	"sync/atomic"
+
+	"github.com/go-logr/logr"
+)
+
+// swappableRoot holds the LogSink shared by a SwappableLogSink and all of
+// the sinks derived from it.
+type swappableRoot struct {
+	// state holds the current *swappableState.
+	state atomic.Value
+}
+
+// swappableState is a sink of a swappableRoot, along with its generation.
+type swappableState struct {
+	sink       logr.LogSink
+	generation uint64
+}
+
+// swappableOp is a WithName, WithValues or WithCallDepth call to replay on top of the root sink.
+type swappableOp struct {
+	name      *string
+	values    []interface{}
+	callDepth int
+}
+
+// SwappableLogSink is a logsink whose underlying logr.LogSink can be replaced
+// at any time, e.g. to raise the verbosity of a running process.  Sinks derived
+// from it through WithName, WithValues and WithCallDepth keep their names, values
+// and call depth and follow the replacement as well.
+//
+// The underlying sinks are those of loggers, e.g. from logr.Logger.GetSink, which
+// are already initialized: they are not initialized again, and only skip the
+// frame of the SwappableLogSink on top of their own.
+type SwappableLogSink struct {
+	root *swappableRoot
+	ops  []swappableOp
+
+	// current holds the *swappableState of the root sink with the operations applied.
+	current atomic.Value
+}
+
+var _ logr.CallDepthLogSink = &SwappableLogSink{}
+
+// NewSwappableLogSink constructs a new SwappableLogSink which uses the given
+// sink until Swap is called.
+func NewSwappableLogSink(initial logr.LogSink) *SwappableLogSink {
+	root := &swappableRoot{}
+	root.state.Store(&swappableState{sink: initial, generation: 1})
+	return &SwappableLogSink{root: root}
+}
+
+// Swap replaces the underlying sink of this logger and of all the loggers
+// derived from it.
+func (l *SwappableLogSink) Swap(sink logr.LogSink) {
+	for {
+		old := l.root.state.Load().(*swappableState)
+		if l.root.state.CompareAndSwap(old, &swappableState{sink: sink, generation: old.generation + 1}) {
+			return
+		}
+	}
+}
+
+// sink returns the sink with all the operations applied, rebuilding it if the
+// root sink was swapped in the meantime.
+func (l *SwappableLogSink) sink() logr.LogSink {
+	root := l.root.state.Load().(*swappableState)
+	if current, ok := l.current.Load().(*swappableState); ok && current.generation == root.generation {
+		return current.sink
+	}
+
+	// the frame of the SwappableLogSink comes on top of those of the sink.
+	sink := withCallDepth(root.sink, 1)
+	for _, op := range l.ops {
+		switch {
+		case op.name != nil:
+			sink = sink.WithName(*op.name)
+		case op.values != nil:
+			sink = sink.WithValues(op.values...)
+		default:
+			sink = withCallDepth(sink, op.callDepth)
+		}
+	}
+	l.current.Store(&swappableState{sink: sink, generation: root.generation})
+	return sink
+}
+
+// withCallDepth returns the sink skipping depth more frames, if it supports it.
+func withCallDepth(sink logr.LogSink, depth int) logr.LogSink {
+	if withCallDepth, ok := sink.(logr.CallDepthLogSink); ok {
+		return withCallDepth.WithCallDepth(depth)
+	}
+	return sink
+}
+
+// derive returns a new SwappableLogSink sharing the root of this one, with the
+// given operation appended.
+func (l *SwappableLogSink) derive(op swappableOp) *SwappableLogSink {
+	ops := make([]swappableOp, 0, len(l.ops)+1)
+	ops = append(ops, l.ops...)
+	ops = append(ops, op)
+	return &SwappableLogSink{root: l.root, ops: ops}
+}
+
+// Init implements logr.LogSink.  The underlying sinks are already initialized.
+func (l *SwappableLogSink) Init(info logr.RuntimeInfo) {
+}
+
+// Enabled tests whether this Logger is enabled.
+func (l *SwappableLogSink) Enabled(level int) bool {
+	return l.sink().Enabled(level)
+}
+
+// Info logs a non-error message with the given key/value pairs as context.
+func (l *SwappableLogSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	l.sink().Info(level, msg, keysAndValues...)
+}
+
+// Error logs an error, with the given message and key/value pairs as context.
+func (l *SwappableLogSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	l.sink().Error(err, msg, keysAndValues...)
+}
+
+// WithName provides a new Logger with the name appended.
+func (l *SwappableLogSink) WithName(name string) logr.LogSink {
+	return l.derive(swappableOp{name: &name})
+}
+
+// WithValues provides a new Logger with the tags appended.
+func (l *SwappableLogSink) WithValues(tags ...interface{}) logr.LogSink {
+	return l.derive(swappableOp{values: tags})
+}
+
+// WithCallDepth implements logr.CallDepthLogSink, by skipping the frames in the
+// underlying sinks which support it.
+func (l *SwappableLogSink) WithCallDepth(depth int) logr.LogSink {
+	return l.derive(swappableOp{callDepth: depth})
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manager
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-logr/logr"
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+)
+
+// clusterRunnables runs the Runnables added with AddForCluster while their logical cluster is in
+// the ClusterSet of the manager.  It is a Runnable of the manager itself, so that the Runnables of
+// the clusters only run while the manager does, and while it leads if they need leader election:
+// the manager has one clusterRunnables for the Runnables which need leader election, and one for
+// the others.
+type clusterRunnables struct {
+	needLeaderElection bool
+	logger             logr.Logger
+
+	mu sync.Mutex
+	// ctx is the context of the manager, set once started.
+	ctx context.Context
+	// runnables are the Runnables of each logical cluster.
+	runnables map[logicalcluster.Name][]Runnable
+	// present are the logical clusters of the ClusterSet.
+	present map[logicalcluster.Name]bool
+	// running are the clusters whose Runnables are running.
+	running map[logicalcluster.Name]*clusterRun
+	// wg waits for all the Runnables to return.
+	wg sync.WaitGroup
+}
+
+// clusterRun is the run of the Runnables of a logical cluster, from the time the cluster is added
+// until it is removed.
+type clusterRun struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	// started is the number of Runnables of the cluster started.
+	started int
+}
+
+var (
+	_ LeaderElectionRunnable    = &clusterRunnables{}
+	_ cluster.ClusterSetHandler = &clusterRunnables{}
+)
+
+func newClusterRunnables(needLeaderElection bool, logger logr.Logger) *clusterRunnables {
+	return &clusterRunnables{
+		needLeaderElection: needLeaderElection,
+		logger:             logger,
+		runnables:          map[logicalcluster.Name][]Runnable{},
+		present:            map[logicalcluster.Name]bool{},
+		running:            map[logicalcluster.Name]*clusterRun{},
+	}
+}
+
+// Add adds a Runnable to the logical cluster, and starts it right away if the cluster is running.
+func (c *clusterRunnables) Add(name logicalcluster.Name, r Runnable) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.runnables[name] = append(c.runnables[name], r)
+	if c.ctx != nil && c.present[name] {
+		c.startCluster(name)
+	}
+}
+
+// Start starts the Runnables of the logical clusters of the ClusterSet, and stops them all when the
+// context is done.  The errors of the Runnables of the clusters are logged rather than stopping the
+// manager, like the failures of the clusters of a ClusterSet.
+func (c *clusterRunnables) Start(ctx context.Context) error {
+	c.mu.Lock()
+	c.ctx = ctx
+	for name := range c.present {
+		c.startCluster(name)
+	}
+	c.mu.Unlock()
+
+	<-ctx.Done()
+	c.wg.Wait()
+	return nil
+}
+
+// NeedLeaderElection implements LeaderElectionRunnable.
+func (c *clusterRunnables) NeedLeaderElection() bool {
+	return c.needLeaderElection
+}
+
+// ClusterAdded implements cluster.ClusterSetHandler.
+func (c *clusterRunnables) ClusterAdded(name logicalcluster.Name, _ cluster.Cluster) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.present[name] = true
+	if c.ctx != nil {
+		c.startCluster(name)
+	}
+}
+
+// ClusterRemoved implements cluster.ClusterSetHandler.  It cancels the context of the Runnables of
+// the cluster, without waiting for them to return.
+func (c *clusterRunnables) ClusterRemoved(name logicalcluster.Name) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.present, name)
+	if run, ok := c.running[name]; ok {
+		run.cancel()
+		delete(c.running, name)
+	}
+}
+
+// startCluster starts the Runnables of the logical cluster which are not running yet, with a context
+// targeting the cluster which is cancelled once the cluster is removed.  c.mu must be held.
+func (c *clusterRunnables) startCluster(name logicalcluster.Name) {
+	run, ok := c.running[name]
+	if !ok {
+		ctx, cancel := context.WithCancel(kcpclient.WithCluster(c.ctx, name))
+		run = &clusterRun{ctx: ctx, cancel: cancel}
+		c.running[name] = run
+	}
+	for _, r := range c.runnables[name][run.started:] {
+		c.wg.Add(1)
+		go func(r Runnable) {
+			defer c.wg.Done()
+			if err := r.Start(run.ctx); err != nil {
+				c.logger.Error(err, "Runnable of logical cluster failed", "cluster", name.String())
+			}
+		}(r)
+	}
+	run.started = len(c.runnables[name])
+}
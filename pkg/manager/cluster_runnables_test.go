@@ -0,0 +1,86 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manager
+
+import (
+	"context"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var _ = Describe("clusterRunnables", func() {
+	a := logicalcluster.New("root:a")
+	b := logicalcluster.New("root:b")
+
+	// clusterRunnable records the cluster of its context while it runs.
+	clusterRunnable := func(running chan<- logicalcluster.Name, stopped chan<- logicalcluster.Name) Runnable {
+		return RunnableFunc(func(ctx context.Context) error {
+			cluster, _ := kcpclient.ClusterFromContext(ctx)
+			running <- cluster
+			<-ctx.Done()
+			stopped <- cluster
+			return nil
+		})
+	}
+
+	It("should run the Runnables of the clusters while they are in the set and the manager runs", func() {
+		running := make(chan logicalcluster.Name, 10)
+		stopped := make(chan logicalcluster.Name, 10)
+		c := newClusterRunnables(true, logf.Log)
+		c.Add(a, clusterRunnable(running, stopped))
+		c.ClusterAdded(a, nil)
+		Consistently(running).ShouldNot(Receive())
+
+		By("starting the Runnables of the clusters added before the manager started")
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(c.Start(ctx)).To(Succeed())
+		}()
+		Eventually(running).Should(Receive(Equal(a)))
+
+		By("starting the Runnables added to a running cluster")
+		c.Add(a, clusterRunnable(running, stopped))
+		Eventually(running).Should(Receive(Equal(a)))
+
+		By("starting the Runnables once their cluster is added")
+		c.Add(b, clusterRunnable(running, stopped))
+		Consistently(running).ShouldNot(Receive())
+		c.ClusterAdded(b, nil)
+		Eventually(running).Should(Receive(Equal(b)))
+
+		By("stopping the Runnables of a removed cluster, and starting them again when it is added back")
+		c.ClusterRemoved(a)
+		Eventually(stopped).Should(Receive(Equal(a)))
+		Eventually(stopped).Should(Receive(Equal(a)))
+		Consistently(stopped).ShouldNot(Receive())
+		c.ClusterAdded(a, nil)
+		Eventually(running).Should(Receive(Equal(a)))
+		Eventually(running).Should(Receive(Equal(a)))
+
+		By("stopping all the Runnables when the manager stops")
+		cancel()
+		Eventually(done).Should(BeClosed())
+		Expect(stopped).To(HaveLen(3))
+	})
+})
@@ -0,0 +1,132 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manager
+
+import (
+	"context"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// defaultGCTuningPeriod is how often GCTuning.Tune is called by default.
+const defaultGCTuningPeriod = 30 * time.Second
+
+// GCTuning configures how the manager tunes the garbage collector while it runs, to reduce the
+// time spent collecting garbage by controllers whose caches hold millions of objects: by default
+// the garbage collector runs every time the heap doubles, which is very often when most of the
+// heap is the long-lived objects of the caches.
+type GCTuning struct {
+	// MemoryBallast is the size, in bytes, of a ballast allocated when the manager starts and
+	// kept until it stops.  The ballast raises the heap size at which the garbage collector runs,
+	// without using resident memory as its pages are never written to.
+	MemoryBallast int64
+
+	// GCPercent is the garbage collection target percentage, see debug.SetGCPercent, to set
+	// when the manager starts.  The previous one is restored when it stops.  0 leaves the
+	// percentage unchanged.
+	GCPercent int
+
+	// Tune is called periodically with the memory statistics of the process, most of which is
+	// the caches of controllers, and returns the garbage collection target percentage to set,
+	// or 0 to leave it unchanged.
+	Tune func(stats GCStats) int
+
+	// Period is how often Tune is called.  Defaults to 30 seconds.
+	Period time.Duration
+}
+
+// GCStats are the memory statistics passed to GCTuning.Tune.
+type GCStats struct {
+	// HeapInuse is the number of bytes of the heap which are in use, including the ballast.
+	HeapInuse uint64
+
+	// HeapObjects is the number of allocated heap objects.
+	HeapObjects uint64
+
+	// NumGC is the number of garbage collections since the process started.
+	NumGC uint32
+
+	// GCPercent is the current garbage collection target percentage.
+	GCPercent int
+}
+
+// gcTuner is the Runnable applying a GCTuning while the manager runs.
+type gcTuner struct {
+	tuning GCTuning
+	logger logr.Logger
+}
+
+// NeedLeaderElection implements LeaderElectionRunnable, the memory of the caches must be tuned
+// whether the manager is the leader or not.
+func (t *gcTuner) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements Runnable.
+func (t *gcTuner) Start(ctx context.Context) error {
+	var ballast []byte
+	if t.tuning.MemoryBallast > 0 {
+		ballast = make([]byte, t.tuning.MemoryBallast)
+	}
+
+	// debug.SetGCPercent returns the previous percentage, so setting it is the only way to
+	// read it.
+	previous := debug.SetGCPercent(-1)
+	current := previous
+	if t.tuning.GCPercent != 0 {
+		current = t.tuning.GCPercent
+	}
+	debug.SetGCPercent(current)
+	defer debug.SetGCPercent(previous)
+
+	if t.tuning.Tune != nil {
+		period := t.tuning.Period
+		if period <= 0 {
+			period = defaultGCTuningPeriod
+		}
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				runtime.KeepAlive(ballast)
+				return nil
+			case <-ticker.C:
+			}
+			var stats runtime.MemStats
+			runtime.ReadMemStats(&stats)
+			percent := t.tuning.Tune(GCStats{
+				HeapInuse:   stats.HeapInuse,
+				HeapObjects: stats.HeapObjects,
+				NumGC:       stats.NumGC,
+				GCPercent:   current,
+			})
+			if percent != 0 && percent != current {
+				t.logger.V(1).Info("Tuning the garbage collector", "gcPercent", percent, "heapInuse", stats.HeapInuse)
+				debug.SetGCPercent(percent)
+				current = percent
+			}
+		}
+	}
+
+	<-ctx.Done()
+	runtime.KeepAlive(ballast)
+	return nil
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manager
+
+import (
+	"context"
+	"runtime/debug"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("gcTuner", func() {
+	It("should tune the garbage collector while running, and restore it once stopped", func() {
+		previous := debug.SetGCPercent(100)
+		defer debug.SetGCPercent(previous)
+
+		percents := make(chan int, 10)
+		t := &gcTuner{logger: logr.Discard(), tuning: GCTuning{
+			MemoryBallast: 1 << 20,
+			GCPercent:     200,
+			Period:        10 * time.Millisecond,
+			Tune: func(stats GCStats) int {
+				Expect(stats.HeapInuse).To(BeNumerically(">=", 1<<20))
+				select {
+				case percents <- stats.GCPercent:
+				default:
+				}
+				return 300
+			},
+		}}
+		Expect(t.NeedLeaderElection()).To(BeFalse())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(t.Start(ctx)).To(Succeed())
+		}()
+		Eventually(percents).Should(Receive(Equal(200)))
+		Eventually(percents).Should(Receive(Equal(300)))
+
+		cancel()
+		Eventually(done).Should(BeClosed())
+		Expect(debug.SetGCPercent(100)).To(Equal(100))
+	})
+})
@@ -22,12 +22,15 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/kcp-dev/logicalcluster"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/trace"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	kerrors "k8s.io/apimachinery/pkg/util/errors"
@@ -44,9 +47,12 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/internal/httpserver"
 	intrec "sigs.k8s.io/controller-runtime/pkg/internal/recorder"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/objectlock"
 	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/workerpool"
 )
 
 const (
@@ -58,6 +64,7 @@ const (
 
 	defaultReadinessEndpoint = "/readyz"
 	defaultLivenessEndpoint  = "/healthz"
+	defaultClustersEndpoint  = "/clusters"
 	defaultMetricsEndpoint   = "/metrics"
 )
 
@@ -106,6 +113,11 @@ type controllerManager struct {
 	// Healthz probe handler
 	healthzHandler *healthz.Handler
 
+	// clusterStatuser reports the state of the caches of the logical clusters, served at
+	// clustersEndpointName if set.
+	clusterStatuser      healthz.ClusterStatuser
+	clustersEndpointName string
+
 	// controllerOptions are the global controller options.
 	controllerOptions v1alpha1.ControllerConfigurationSpec
 
@@ -113,6 +125,38 @@ type controllerManager struct {
 	// If none is set, it defaults to log.Log global logger.
 	logger logr.Logger
 
+	// logSink is the sink backing logger, it allows replacing the logger at runtime.
+	logSink *log.SwappableLogSink
+
+	// globalReader reads across all the logical clusters of the cache, it is nil
+	// unless enabled in the options.
+	globalReader client.Reader
+
+	// objectLocks serialize the reconciles of the same object across controllers, they are nil
+	// unless enabled in the options.
+	objectLocks *objectlock.Locks
+
+	// workerPool is the pool of reconcile workers shared by the controllers, it is nil unless
+	// enabled in the options.
+	workerPool *workerpool.Pool
+
+	// clusterSuspensions suspend the logical clusters for the controllers, it is nil unless
+	// enabled in the options.
+	clusterSuspensions *cluster.Suspensions
+
+	// tracerProvider traces the reconciles of the controllers, it is nil unless set in the options.
+	tracerProvider trace.TracerProvider
+
+	// manifestPath is the file the manifest of the manager is written to when it starts, if set.
+	manifestPath string
+
+	// clusterSet is the set of the logical clusters of the manager, if any.
+	clusterSet *cluster.ClusterSet
+
+	// indexes are the field indexes added with GetFieldIndexer, for the manifest.
+	indexes   []IndexManifest
+	indexesMu sync.Mutex
+
 	// leaderElectionStopped is an internal channel used to signal the stopping procedure that the
 	// LeaderElection.Run(...) function has returned and the shutdown can proceed.
 	leaderElectionStopped chan struct{}
@@ -141,6 +185,17 @@ type controllerManager struct {
 	// webhookServer if unset, and Add() it to controllerManager.
 	webhookServerOnce sync.Once
 
+	// leaderClusterRunnables and clusterRunnables run the Runnables added with AddForCluster which
+	// need leader election, and the ones which don't.  They are nil if the manager has no ClusterSet.
+	leaderClusterRunnables *clusterRunnables
+	clusterRunnables       *clusterRunnables
+
+	// webhookServers are the named webhook servers, see Options.WebhookServers.  They are
+	// added to the manager by GetNamedWebhookServer, which records them in addedWebhookServers.
+	webhookServers      map[string]*webhook.Server
+	addedWebhookServers map[string]bool
+	webhookServersMu    sync.Mutex
+
 	// leaseDuration is the duration that non-leader candidates will
 	// wait to force acquire leadership.
 	leaseDuration time.Duration
@@ -150,11 +205,22 @@ type controllerManager struct {
 	// retryPeriod is the duration the LeaderElector clients should wait
 	// between tries of actions.
 	retryPeriod time.Duration
+	// leaderElectionWarmup is what to wait for after winning the leader election, if anything.
+	leaderElectionWarmup *LeaderElectionWarmup
+
+	// gcTuning is how the garbage collector is tuned while the manager runs, if at all.
+	gcTuning *GCTuning
+
+	// caches are the caches of the cluster and of the other Runnables added to the manager.
+	caches []cache.Cache
 
 	// gracefulShutdownTimeout is the duration given to runnable to stop
 	// before the manager actually returns on stop.
 	gracefulShutdownTimeout time.Duration
 
+	// onLeadershipLost is the callback of the user called when the leader election lease is lost.
+	onLeadershipLost func()
+
 	// onStoppedLeading is callled when the leader election lease is lost.
 	// It can be overridden for tests.
 	onStoppedLeading func()
@@ -189,7 +255,29 @@ func (cm *controllerManager) add(r Runnable) error {
 	if err := cm.SetFields(r); err != nil {
 		return err
 	}
-	return cm.runnables.Add(r)
+	if err := cm.runnables.Add(r); err != nil {
+		return err
+	}
+	if c, ok := r.(hasCache); ok {
+		cm.caches = append(cm.caches, c.GetCache())
+	}
+	return nil
+}
+
+// AddForCluster sets dependencies on r, and adds it to the Runnables of the logical cluster.
+func (cm *controllerManager) AddForCluster(name logicalcluster.Name, r Runnable) error {
+	if cm.clusterRunnables == nil {
+		return errors.New("unable to add a runnable for a logical cluster: the manager has no ClusterSet")
+	}
+	if err := cm.SetFields(r); err != nil {
+		return err
+	}
+	if ler, ok := r.(LeaderElectionRunnable); ok && !ler.NeedLeaderElection() {
+		cm.clusterRunnables.Add(name, r)
+	} else {
+		cm.leaderClusterRunnables.Add(name, r)
+	}
+	return nil
 }
 
 // Deprecated: use the equivalent Options field to set a field. This method will be removed in v0.10.
@@ -279,7 +367,15 @@ func (cm *controllerManager) GetScheme() *runtime.Scheme {
 }
 
 func (cm *controllerManager) GetFieldIndexer() client.FieldIndexer {
-	return cm.cluster.GetFieldIndexer()
+	return &manifestFieldIndexer{
+		FieldIndexer: cm.cluster.GetFieldIndexer(),
+		scheme:       cm.cluster.GetScheme(),
+		record: func(index IndexManifest) {
+			cm.indexesMu.Lock()
+			defer cm.indexesMu.Unlock()
+			cm.indexes = append(cm.indexes, index)
+		},
+	}
 }
 
 func (cm *controllerManager) GetCache() cache.Cache {
@@ -298,6 +394,51 @@ func (cm *controllerManager) GetAPIReader() client.Reader {
 	return cm.cluster.GetAPIReader()
 }
 
+func (cm *controllerManager) GetGlobalReader() client.Reader {
+	return cm.globalReader
+}
+
+func (cm *controllerManager) GetObjectLocks() *objectlock.Locks {
+	return cm.objectLocks
+}
+
+func (cm *controllerManager) GetWorkerPool() *workerpool.Pool {
+	return cm.workerPool
+}
+
+func (cm *controllerManager) GetClusterSuspensions() *cluster.Suspensions {
+	return cm.clusterSuspensions
+}
+
+func (cm *controllerManager) GetTracerProvider() trace.TracerProvider {
+	return cm.tracerProvider
+}
+
+func (cm *controllerManager) GetManifest() Manifest {
+	cm.Lock()
+	defer cm.Unlock()
+	return cm.manifest()
+}
+
+// manifest describes the manager, cm must be locked.
+func (cm *controllerManager) manifest() Manifest {
+	m := Manifest{
+		HealthChecks: checkNames(cm.healthzHandler),
+		ReadyChecks:  checkNames(cm.readyzHandler),
+	}
+	m.AddRunnables(cm.runnables.List()...)
+	if cm.clusterSet != nil {
+		for _, name := range cm.clusterSet.Names() {
+			m.Clusters = append(m.Clusters, name.String())
+		}
+		sort.Strings(m.Clusters)
+	}
+	cm.indexesMu.Lock()
+	m.Indexes = append([]IndexManifest(nil), cm.indexes...)
+	cm.indexesMu.Unlock()
+	return m
+}
+
 func (cm *controllerManager) GetWebhookServer() *webhook.Server {
 	cm.webhookServerOnce.Do(func() {
 		if cm.webhookServer == nil {
@@ -314,10 +455,30 @@ func (cm *controllerManager) GetWebhookServer() *webhook.Server {
 	return cm.webhookServer
 }
 
+func (cm *controllerManager) GetNamedWebhookServer(name string) *webhook.Server {
+	cm.webhookServersMu.Lock()
+	defer cm.webhookServersMu.Unlock()
+	server, ok := cm.webhookServers[name]
+	if !ok || server == nil {
+		return nil
+	}
+	if !cm.addedWebhookServers[name] {
+		if err := cm.Add(server); err != nil {
+			panic(fmt.Sprintf("unable to add webhook server %q to the controller manager: %s", name, err))
+		}
+		cm.addedWebhookServers[name] = true
+	}
+	return server
+}
+
 func (cm *controllerManager) GetLogger() logr.Logger {
 	return cm.logger
 }
 
+func (cm *controllerManager) SetLogger(logger logr.Logger) {
+	cm.logSink.Swap(logger.GetSink())
+}
+
 func (cm *controllerManager) GetControllerOptions() v1alpha1.ControllerConfigurationSpec {
 	return cm.controllerOptions
 }
@@ -351,6 +512,9 @@ func (cm *controllerManager) serveHealthProbes() {
 		// Append '/' suffix to handle subpaths
 		mux.Handle(cm.livenessEndpointName+"/", http.StripPrefix(cm.livenessEndpointName, cm.healthzHandler))
 	}
+	if cm.clusterStatuser != nil {
+		mux.Handle(cm.clustersEndpointName, healthz.ClustersHandler{Statuser: cm.clusterStatuser})
+	}
 
 	go cm.httpServe("health probe", cm.logger, server, cm.healthProbeListener)
 }
@@ -438,6 +602,26 @@ func (cm *controllerManager) Start(ctx context.Context) (err error) {
 		return fmt.Errorf("failed to add cluster to runnables: %w", err)
 	}
 
+	// Tune the garbage collector while the manager runs.
+	if cm.gcTuning != nil {
+		if err := cm.add(&gcTuner{tuning: *cm.gcTuning, logger: cm.logger.WithName("gc-tuning")}); err != nil {
+			return fmt.Errorf("failed to add the garbage collector tuning to runnables: %w", err)
+		}
+	}
+
+	// Order the runnables, before any of them is started.
+	if err := cm.runnables.ResolveDependencies(); err != nil {
+		return fmt.Errorf("failed to resolve the dependencies of the runnables: %w", err)
+	}
+
+	// Describe the manager, before any runnable is started.
+	if cm.manifestPath != "" {
+		manifest := cm.manifest()
+		if err := manifest.WriteFile(cm.manifestPath); err != nil {
+			return fmt.Errorf("failed to write the manifest of the manager: %w", err)
+		}
+	}
+
 	// Metrics should be served whether the controller is leader or not.
 	// (If we don't serve metrics for non-leaders, prometheus will still scrape
 	// the pod but will get a connection refused).
@@ -611,7 +795,14 @@ func (cm *controllerManager) startLeaderElection(ctx context.Context) (err error
 		RenewDeadline: cm.renewDeadline,
 		RetryPeriod:   cm.retryPeriod,
 		Callbacks: leaderelection.LeaderCallbacks{
-			OnStartedLeading: func(_ context.Context) {
+			OnStartedLeading: func(ctx context.Context) {
+				if err := cm.warmUp(ctx); err != nil {
+					// Losing the leadership is reported by OnStoppedLeading.
+					if ctx.Err() == nil {
+						cm.errChan <- err
+					}
+					return
+				}
 				if err := cm.startLeaderElectionRunnables(); err != nil {
 					cm.errChan <- err
 					return
@@ -619,6 +810,9 @@ func (cm *controllerManager) startLeaderElection(ctx context.Context) (err error
 				close(cm.elected)
 			},
 			OnStoppedLeading: func() {
+				if cm.onLeadershipLost != nil {
+					cm.onLeadershipLost()
+				}
 				if cm.onStoppedLeading != nil {
 					cm.onStoppedLeading()
 				}
@@ -646,6 +840,47 @@ func (cm *controllerManager) startLeaderElection(ctx context.Context) (err error
 	return nil
 }
 
+// warmUp waits for the leader election warmup, if one is configured.  It returns the
+// error of the context if the leadership is lost in the meantime.
+func (cm *controllerManager) warmUp(ctx context.Context) error {
+	warmup := cm.leaderElectionWarmup
+	if warmup == nil {
+		return nil
+	}
+	cm.logger.Info("Warming up before starting the leader election runnables", "delay", warmup.Delay, "waitForCacheSync", warmup.WaitForCacheSync)
+
+	if warmup.Delay > 0 {
+		timer := time.NewTimer(warmup.Delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if warmup.WaitForCacheSync {
+		syncCtx := ctx
+		if warmup.CacheSyncTimeout > 0 {
+			var cancel context.CancelFunc
+			syncCtx, cancel = context.WithTimeout(ctx, warmup.CacheSyncTimeout)
+			defer cancel()
+		}
+		cm.Lock()
+		caches := append([]cache.Cache(nil), cm.caches...)
+		cm.Unlock()
+		for _, c := range caches {
+			if err := cache.WaitForQuorumSync(syncCtx, c); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				return fmt.Errorf("unable to sync the caches with the API server after winning the leader election: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
 func (cm *controllerManager) Elected() <-chan struct{} {
 	return cm.elected
 }
@@ -25,6 +25,8 @@ import (
 	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/kcp-dev/logicalcluster"
+	"go.opentelemetry.io/otel/trace"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -38,13 +40,16 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/config"
 	"sigs.k8s.io/controller-runtime/pkg/config/v1alpha1"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/internal/controller/metrics"
 	intrec "sigs.k8s.io/controller-runtime/pkg/internal/recorder"
 	"sigs.k8s.io/controller-runtime/pkg/leaderelection"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/objectlock"
 	"sigs.k8s.io/controller-runtime/pkg/recorder"
 	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/workerpool"
 )
 
 // Manager initializes shared dependencies such as Caches and Clients, and provides them to Runnables.
@@ -58,8 +63,17 @@ type Manager interface {
 	// implements the inject interface - e.g. inject.Client.
 	// Depending on if a Runnable implements LeaderElectionRunnable interface, a Runnable can be run in either
 	// non-leaderelection mode (always running) or leader election mode (managed by leader election if enabled).
+	// A Runnable implementing DependentRunnable is started after, and stopped before, the Runnables it depends on.
 	Add(Runnable) error
 
+	// AddForCluster adds a Runnable scoped to a logical cluster of Options.ClusterSet: it is started, with a
+	// context targeting the cluster, once the cluster is added to the set and the manager is started, and
+	// its context is cancelled when the cluster is removed from the set.  It is started again if the cluster
+	// is added back.  Like with Add, the dependencies of the Runnable are injected, and it is run in leader
+	// election mode unless it implements LeaderElectionRunnable and doesn't need leader election.
+	// AddForCluster fails if the manager has no ClusterSet.
+	AddForCluster(name logicalcluster.Name, r Runnable) error
+
 	// Elected is closed when this manager is elected leader of a group of
 	// managers, either because it won a leader election or because no leader
 	// election was configured.
@@ -89,11 +103,49 @@ type Manager interface {
 	// GetWebhookServer returns a webhook.Server
 	GetWebhookServer() *webhook.Server
 
+	// GetNamedWebhookServer returns the webhook.Server of Options.WebhookServers with the
+	// given name, which is added to the manager on the first call, or nil if there is none.
+	GetNamedWebhookServer(name string) *webhook.Server
+
 	// GetLogger returns this manager's logger.
 	GetLogger() logr.Logger
 
 	// GetControllerOptions returns controller global configuration options.
 	GetControllerOptions() v1alpha1.ControllerConfigurationSpec
+
+	// SetLogger replaces the sink of this manager's logger while the manager is
+	// running, e.g. to raise the verbosity during an incident.  The change applies
+	// to every logger derived from GetLogger, including the ones handed to the
+	// controllers and the cluster.
+	SetLogger(logger logr.Logger)
+
+	// GetGlobalReader returns a read-only client over the cache of the manager
+	// which lists objects across all the logical clusters the cache watches,
+	// e.g. for reporting Runnables.  It returns nil unless EnableGlobalReader is set.
+	GetGlobalReader() client.Reader
+
+	// GetObjectLocks returns the locks serializing the reconciles of the same object across the
+	// controllers of the manager.  It returns nil unless SerializeReconciles is set.
+	GetObjectLocks() *objectlock.Locks
+
+	// GetWorkerPool returns the pool of reconcile workers shared by the controllers of the
+	// manager.  It returns nil unless ReconcileWorkerPoolSize is set.
+	GetWorkerPool() *workerpool.Pool
+
+	// GetManifest returns a machine-readable description of the composition of the manager: its
+	// controllers and their watches, logical clusters, webhooks, indexes and health checks.  Only
+	// the indexes added with GetFieldIndexer are described.
+	GetManifest() Manifest
+
+	// GetClusterSuspensions returns the suspensions of the logical clusters shared by the
+	// controllers of the manager, to suspend the event delivery and the reconciles of a logical
+	// cluster, e.g. during the migration of a workspace, and to resume them with a resync of its
+	// objects.  It returns nil unless EnableClusterSuspensions is set.
+	GetClusterSuspensions() *cluster.Suspensions
+
+	// GetTracerProvider returns the TracerProvider of the controllers and of the client of the
+	// manager.  It returns nil unless TracerProvider is set.
+	GetTracerProvider() trace.TracerProvider
 }
 
 // Options are the arguments for creating a new Manager.
@@ -164,6 +216,11 @@ type Options struct {
 	// will use for holding the leader lock.
 	LeaderElectionID string
 
+	// LeaderElectionIdentitySuffix is appended to the identity of this leader election
+	// candidate, <hostname>_<uuid>, e.g. to tell apart several managers of the same pod
+	// holding locks.
+	LeaderElectionIdentitySuffix string
+
 	// LeaderElectionConfig can be specified to override the default configuration
 	// that is used to build the leader election client.
 	LeaderElectionConfig *rest.Config
@@ -186,6 +243,17 @@ type Options struct {
 	// between tries of actions. Default is 2 seconds.
 	RetryPeriod *time.Duration
 
+	// OnLeadershipLost is called as soon as the manager stops leading, before the Runnables
+	// which need leader election are stopped, so that the controllers can fence the work they
+	// started outside of the cluster, e.g. in external systems, promptly.  The manager still
+	// stops with an error afterwards.
+	OnLeadershipLost func()
+
+	// LeaderElectionWarmup configures a warmup phase between winning the leader
+	// election and starting the Runnables which need leader election, such as
+	// controllers.  It is ignored when leader election is disabled.
+	LeaderElectionWarmup *LeaderElectionWarmup
+
 	// Namespace if specified restricts the manager's cache to watch objects in
 	// the desired namespace Defaults to all namespaces
 	//
@@ -199,6 +267,12 @@ type Options struct {
 	// It can be set to "0" to disable the metrics serving.
 	MetricsBindAddress string
 
+	// MetricsClusterLabel adds a cluster label holding the logical cluster of the
+	// requests to the reconcile metrics of the controllers, and exposes the number of
+	// queued requests per logical cluster.  It is off by default as the cardinality of
+	// the metrics grows with the number of logical clusters.
+	MetricsClusterLabel bool
+
 	// HealthProbeBindAddress is the TCP address that the controller should bind to
 	// for serving health probes
 	HealthProbeBindAddress string
@@ -209,6 +283,21 @@ type Options struct {
 	// Liveness probe endpoint name, defaults to "healthz"
 	LivenessEndpointName string
 
+	// ClusterStatuser, if set, reports the state of the caches of logical clusters, e.g. a
+	// cluster.ClusterSet.  The readiness probe then includes a "clusters" check which fails
+	// until the caches of all the clusters are synced, and the health probe server serves
+	// the state of the caches as JSON at ClustersEndpointName.
+	ClusterStatuser healthz.ClusterStatuser
+
+	// ClusterSet, if set, is the set of the logical clusters of the manager, e.g. maintained by the
+	// discovery of the workspaces binding an APIExport.  The Runnables added with AddForCluster run
+	// while their cluster is in the set.  The set isn't started by the manager: add it too.
+	ClusterSet *cluster.ClusterSet
+
+	// ClustersEndpointName is the endpoint of the state of the caches of the logical clusters,
+	// defaults to "/clusters".  It is only served if ClusterStatuser is set.
+	ClustersEndpointName string
+
 	// Port is the port that the webhook server serves at.
 	// It is used to set webhook.Server.Port if WebhookServer is not set.
 	Port int
@@ -228,6 +317,13 @@ type Options struct {
 	// if this is set, the Manager will use this server instead.
 	WebhookServer *webhook.Server
 
+	// WebhookServers are additional webhook servers by name, e.g. one serving the conversion
+	// webhooks and one serving the admission webhooks, or one per trust domain, each with its
+	// own listener and certificates.  They run with the manager like WebhookServer, and the
+	// webhooks are registered with them by name, see GetNamedWebhookServer and
+	// builder.WebhookBuilder.WithWebhookServer.
+	WebhookServers map[string]*webhook.Server
+
 	// Functions to all for a user to customize the values that will be injected.
 
 	// NewCache is the function that will create the cache to be used
@@ -239,14 +335,75 @@ type Options struct {
 	// use the cache for reads and the client for writes.
 	NewClient cluster.NewClientFunc
 
+	// NewAPIReader is the func that creates the reader returned by GetAPIReader, reading
+	// from the API server rather than the cache.  If not set this will use a client
+	// built with client.New.
+	NewAPIReader cluster.NewAPIReaderFunc
+
 	// ClientDisableCacheFor tells the client that, if any cache is used, to bypass it
 	// for the given objects.
 	ClientDisableCacheFor []client.Object
 
+	// ClientDisableCacheByCluster tells the client to bypass the cache for the given objects
+	// of the given logical clusters only.  See cluster.Options.ClientDisableCacheByCluster.
+	ClientDisableCacheByCluster client.UncachedByCluster
+
 	// DryRunClient specifies whether the client should be configured to enforce
 	// dryRun mode.
 	DryRunClient bool
 
+	// DiagnoseConflicts makes the client diagnose the conflicts of updates and patches,
+	// comparing the resourceVersion of the written object to those of the cache and of
+	// the API server, to tell stale caches from concurrent writers.  The diagnoses are
+	// logged, and returned in client.ConflictErrors.
+	DiagnoseConflicts bool
+
+	// FieldOwner is the field manager set on the writes of the client of the manager, see
+	// cluster.Options.FieldOwner.
+	FieldOwner string
+
+	// EnableGlobalReader enables GetGlobalReader.  It must only be set when the cache
+	// is a wildcard ("*") cache, which requires permissions to list and watch objects
+	// across all the workspaces.
+	EnableGlobalReader bool
+
+	// SerializeReconciles makes the controllers of the manager lock the object of each request
+	// before reconciling it, so that two controllers never reconcile, and write, the same object
+	// concurrently.  The objects are identified by their logical cluster, GroupKind, namespace and
+	// name.  See the objectlock package.
+	SerializeReconciles bool
+
+	// ReconcileWorkerPoolSize, if set, bounds the number of reconciles running concurrently across
+	// all the controllers of the manager: the workers of each controller borrow a slot of a pool of
+	// this size for each reconcile, and the slots are shared between the controllers waiting for
+	// them in proportion to their controller.Options.WorkerPoolWeight.  See the workerpool package.
+	ReconcileWorkerPoolSize int
+
+	// EnableClusterSuspensions enables GetClusterSuspensions, and makes the controllers of the
+	// manager honor them.
+	EnableClusterSuspensions bool
+
+	// ManifestPath, if set, is the file the Manifest of the manager is written to as JSON when it
+	// starts, before any Runnable is started.  See BindManifestFlags.
+	ManifestPath string
+
+	// EnableQueueDebugHandler serves the requests in the queues of the controllers on
+	// QueueDebugPath of the metrics server, grouped by logical cluster with their retries and
+	// when they are due, e.g. to find the workspaces stuck in a retry storm.
+	EnableQueueDebugHandler bool
+
+	// TracerProvider, if set, e.g. to otel.GetTracerProvider(), makes the controllers of the
+	// manager start a span around every reconcile, and the client of the manager a child span
+	// around each of its calls, propagated to the API server in the traceparent header of the
+	// requests, see the tracing package.
+	TracerProvider trace.TracerProvider
+
+	// ImpersonateByCluster, if set, makes the requests of the client and of the API reader of
+	// the manager to each logical cluster impersonate the user it returns for the cluster, so
+	// that a multi-tenant controller acts with the RBAC of the tenant of each workspace.  See
+	// client.Options.ImpersonateByCluster.
+	ImpersonateByCluster client.ClusterImpersonation
+
 	// EventBroadcaster records Events emitted by the manager and sends them to the Kubernetes API
 	// Use this to customize the event correlator and spam filter
 	//
@@ -260,6 +417,10 @@ type Options struct {
 	// The graceful shutdown is skipped for safety reasons in case the leader election lease is lost.
 	GracefulShutdownTimeout *time.Duration
 
+	// GCTuning configures a memory ballast and the tuning of the garbage collector while
+	// the manager runs, for controllers caching very many objects.  It is off by default.
+	GCTuning *GCTuning
+
 	// Controller contains global configuration options for controllers
 	// registered within this manager.
 	// +optional
@@ -305,11 +466,58 @@ type LeaderElectionRunnable interface {
 	NeedLeaderElection() bool
 }
 
+// DependentRunnable knows the Runnables a Runnable depends on, e.g. a controller watching the
+// clusters of a ClusterSet depends on the ClusterSet.  The manager only starts a Runnable once the
+// Runnables it depends on are ready, e.g. once their cache is synced, and stops it before them.
+//
+// The Runnables it depends on must be added to the manager too, and must not be started after
+// it: webhook servers are started first, then caches, then the Runnables which don't need leader
+// election, and finally the ones which do.  The manager fails to start if the dependencies of its
+// Runnables are missing, misordered or cyclic.
+type DependentRunnable interface {
+	Runnable
+
+	// DependsOn returns the Runnables which must be started before this one.
+	DependsOn() []Runnable
+}
+
+// LeaderElectionWarmup configures what the manager waits for after winning the leader
+// election, before starting the Runnables which need leader election.  It gives the writes
+// the previous leader still had in flight time to land and to be observed by the caches,
+// so that controllers don't start reconciling from stale reads and fight over the same
+// objects in many clusters at once.
+type LeaderElectionWarmup struct {
+	// Delay is how long to wait after winning the leader election.
+	Delay time.Duration
+
+	// WaitForCacheSync makes the manager, after the Delay, list the objects of every kind
+	// its caches hold from the API server with quorum reads, and wait until the caches
+	// have observed these lists, see cache.QuorumSyncer.  The writes made before the
+	// leader election was won, e.g. by the previous leader, are then visible in the caches
+	// when the controllers start.  The caches which aren't cache.QuorumSyncers are only
+	// waited for to sync.
+	WaitForCacheSync bool
+
+	// CacheSyncTimeout bounds the time spent waiting for the caches to sync.  When
+	// it is exceeded, the manager fails to start the Runnables which need leader
+	// election.  Defaults to no timeout.
+	CacheSyncTimeout time.Duration
+}
+
 // New returns a new Manager for creating Controllers.
 func New(config *rest.Config, options Options) (Manager, error) {
 	// Set default values for options fields
 	options = setOptionsDefaults(options)
 
+	// Wrap the logger so that its sink can be replaced at runtime with SetLogger.
+	logSink := log.NewSwappableLogSink(options.Logger.GetSink())
+	options.Logger = logr.New(logSink)
+
+	var clusterSuspensions *cluster.Suspensions
+	if options.EnableClusterSuspensions {
+		clusterSuspensions = cluster.NewSuspensions()
+	}
+
 	cluster, err := cluster.New(config, func(clusterOptions *cluster.Options) {
 		clusterOptions.Scheme = options.Scheme
 		clusterOptions.MapperProvider = options.MapperProvider
@@ -318,8 +526,14 @@ func New(config *rest.Config, options Options) (Manager, error) {
 		clusterOptions.Namespace = options.Namespace
 		clusterOptions.NewCache = options.NewCache
 		clusterOptions.NewClient = options.NewClient
+		clusterOptions.NewAPIReader = options.NewAPIReader
 		clusterOptions.ClientDisableCacheFor = options.ClientDisableCacheFor
+		clusterOptions.ClientDisableCacheByCluster = options.ClientDisableCacheByCluster
 		clusterOptions.DryRunClient = options.DryRunClient
+		clusterOptions.DiagnoseConflicts = options.DiagnoseConflicts
+		clusterOptions.FieldOwner = options.FieldOwner
+		clusterOptions.TracerProvider = options.TracerProvider
+		clusterOptions.ImpersonateByCluster = options.ImpersonateByCluster
 		clusterOptions.EventBroadcaster = options.EventBroadcaster //nolint:staticcheck
 	})
 	if err != nil {
@@ -333,6 +547,9 @@ func New(config *rest.Config, options Options) (Manager, error) {
 	if err != nil {
 		return nil, err
 	}
+	if options.ClusterSet != nil {
+		releaseRemovedClusters(options.ClusterSet, recorderProvider)
+	}
 
 	// Create the resource lock to enable leader election)
 	leaderConfig := options.LeaderElectionConfig
@@ -344,11 +561,17 @@ func New(config *rest.Config, options Options) (Manager, error) {
 		LeaderElectionResourceLock: options.LeaderElectionResourceLock,
 		LeaderElectionID:           options.LeaderElectionID,
 		LeaderElectionNamespace:    options.LeaderElectionNamespace,
+
+		LeaderElectionIdentitySuffix: options.LeaderElectionIdentitySuffix,
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	if options.MetricsClusterLabel {
+		ctrlmetrics.EnableClusterLabel()
+	}
+
 	// Create the metrics listener. This will throw an error if the metrics bind
 	// address is invalid or already in use.
 	metricsListener, err := options.newMetricsListener(options.MetricsBindAddress)
@@ -369,6 +592,44 @@ func New(config *rest.Config, options Options) (Manager, error) {
 	errChan := make(chan error)
 	runnables := newRunnables(errChan)
 
+	if options.EnableQueueDebugHandler {
+		metricsExtraHandlers[QueueDebugPath] = &queueDebugHandler{runnables: runnables.List}
+	}
+
+	var globalReader client.Reader
+	if options.EnableGlobalReader {
+		globalReader = &wildcardReader{reader: cluster.GetCache()}
+	}
+
+	var leaderForClusters, forClusters *clusterRunnables
+	if options.ClusterSet != nil {
+		leaderForClusters = newClusterRunnables(true, options.Logger.WithName("cluster-runnables"))
+		forClusters = newClusterRunnables(false, options.Logger.WithName("cluster-runnables"))
+		for _, r := range []*clusterRunnables{leaderForClusters, forClusters} {
+			if err := runnables.Add(r); err != nil {
+				return nil, err
+			}
+			options.ClusterSet.AddHandler(r)
+		}
+	}
+
+	var objectLocks *objectlock.Locks
+	if options.SerializeReconciles {
+		objectLocks = objectlock.New()
+	}
+
+	var workerPool *workerpool.Pool
+	if options.ReconcileWorkerPoolSize > 0 {
+		workerPool = workerpool.New(options.ReconcileWorkerPoolSize)
+	}
+
+	var readyzHandler *healthz.Handler
+	if options.ClusterStatuser != nil {
+		readyzHandler = &healthz.Handler{Checks: map[string]healthz.Checker{
+			"clusters": healthz.ClustersSynced(options.ClusterStatuser),
+		}}
+	}
+
 	return &controllerManager{
 		stopProcedureEngaged:          pointer.Int64(0),
 		cluster:                       cluster,
@@ -380,17 +641,35 @@ func New(config *rest.Config, options Options) (Manager, error) {
 		metricsExtraHandlers:          metricsExtraHandlers,
 		controllerOptions:             options.Controller,
 		logger:                        options.Logger,
+		logSink:                       logSink,
+		globalReader:                  globalReader,
+		objectLocks:                   objectLocks,
+		workerPool:                    workerPool,
+		clusterSuspensions:            clusterSuspensions,
+		manifestPath:                  options.ManifestPath,
+		tracerProvider:                options.TracerProvider,
+		clusterSet:                    options.ClusterSet,
 		elected:                       make(chan struct{}),
 		port:                          options.Port,
 		host:                          options.Host,
 		certDir:                       options.CertDir,
 		webhookServer:                 options.WebhookServer,
+		webhookServers:                options.WebhookServers,
+		leaderClusterRunnables:        leaderForClusters,
+		clusterRunnables:              forClusters,
+		addedWebhookServers:           map[string]bool{},
 		leaseDuration:                 *options.LeaseDuration,
 		renewDeadline:                 *options.RenewDeadline,
 		retryPeriod:                   *options.RetryPeriod,
+		leaderElectionWarmup:          options.LeaderElectionWarmup,
+		onLeadershipLost:              options.OnLeadershipLost,
+		gcTuning:                      options.GCTuning,
 		healthProbeListener:           healthProbeListener,
 		readinessEndpointName:         options.ReadinessEndpointName,
 		livenessEndpointName:          options.LivenessEndpointName,
+		readyzHandler:                 readyzHandler,
+		clusterStatuser:               options.ClusterStatuser,
+		clustersEndpointName:          options.ClustersEndpointName,
 		gracefulShutdownTimeout:       *options.GracefulShutdownTimeout,
 		internalProceduresStop:        make(chan struct{}),
 		leaderElectionStopped:         make(chan struct{}),
@@ -519,6 +798,12 @@ func defaultHealthProbeListener(addr string) (net.Listener, error) {
 	return ln, nil
 }
 
+// releaseRemovedClusters makes the recorder provider release the broadcasters of the logical
+// clusters removed from the set.
+func releaseRemovedClusters(set *cluster.ClusterSet, provider *intrec.Provider) {
+	set.AddHandler(cluster.ClusterSetHandlerFuncs{RemoveFunc: provider.ReleaseCluster})
+}
+
 // setOptionsDefaults set default values for Options fields.
 func setOptionsDefaults(options Options) Options {
 	// Allow newResourceLock to be mocked
@@ -569,6 +854,10 @@ func setOptionsDefaults(options Options) Options {
 		options.LivenessEndpointName = defaultLivenessEndpoint
 	}
 
+	if options.ClustersEndpointName == "" {
+		options.ClustersEndpointName = defaultClustersEndpoint
+	}
+
 	if options.newHealthProbeListener == nil {
 		options.newHealthProbeListener = defaultHealthProbeListener
 	}
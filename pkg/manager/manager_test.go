@@ -23,12 +23,15 @@ import (
 	"io/ioutil"
 	"net"
 	"net/http"
+	"os"
 	"path"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	"github.com/kcp-dev/logicalcluster"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/prometheus/client_golang/prometheus"
@@ -272,6 +275,59 @@ var _ = Describe("manger.Manager", func() {
 			Expect(svr.Port).To(Equal(9440))
 		})
 
+		It("should return the webhook servers of Options.WebhookServers by name", func() {
+			admission := &webhook.Server{Port: 9443, CertDir: "/admission"}
+			conversion := &webhook.Server{Port: 9444, CertDir: "/conversion"}
+			m, err := New(cfg, Options{WebhookServers: map[string]*webhook.Server{
+				"admission":  admission,
+				"conversion": conversion,
+			}})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(m.GetNamedWebhookServer("admission")).To(BeIdenticalTo(admission))
+			Expect(m.GetNamedWebhookServer("conversion")).To(BeIdenticalTo(conversion))
+			Expect(m.GetNamedWebhookServer("conversion")).To(BeIdenticalTo(conversion))
+			Expect(m.GetNamedWebhookServer("other")).To(BeNil())
+			Expect(m.GetWebhookServer()).NotTo(BeIdenticalTo(admission))
+		})
+
+		It("should only add runnables for logical clusters if it has a ClusterSet", func() {
+			m, err := New(cfg, Options{})
+			Expect(err).NotTo(HaveOccurred())
+			runnable := RunnableFunc(func(context.Context) error { return nil })
+			Expect(m.AddForCluster(logicalcluster.New("root:a"), runnable)).To(MatchError(ContainSubstring("the manager has no ClusterSet")))
+
+			set, err := cluster.NewClusterSet(cfg, cluster.ClusterSetOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			m, err = New(cfg, Options{ClusterSet: set})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m.AddForCluster(logicalcluster.New("root:a"), runnable)).To(Succeed())
+		})
+
+		It("should replace the sink of its logger, and of the loggers derived from it, with SetLogger", func() {
+			var before, after []string
+			newLogger := func(lines *[]string) logr.Logger {
+				return funcr.New(func(prefix, args string) { *lines = append(*lines, prefix+" "+args) }, funcr.Options{LogCaller: funcr.All})
+			}
+			m, err := New(cfg, Options{
+				Logger:         newLogger(&before),
+				MapperProvider: func(*rest.Config) (meta.RESTMapper, error) { return meta.NewDefaultRESTMapper(nil), nil },
+			})
+			Expect(err).NotTo(HaveOccurred())
+			l := m.GetLogger().WithName("controller")
+
+			l.Info("before swap")
+			m.SetLogger(newLogger(&after))
+			l.Info("after swap")
+			m.GetLogger().Info("from the manager")
+
+			Expect(before).To(ConsistOf(SatisfyAll(ContainSubstring(`"msg"="before swap"`), ContainSubstring(`"file":"manager_test.go"`))))
+			Expect(after).To(ConsistOf(
+				SatisfyAll(HavePrefix("controller "), ContainSubstring(`"msg"="after swap"`), ContainSubstring(`"file":"manager_test.go"`)),
+				SatisfyAll(ContainSubstring(`"msg"="from the manager"`), ContainSubstring(`"file":"manager_test.go"`)),
+			))
+		})
+
 		Context("with leader election enabled", func() {
 			It("should only cancel the leader election after all runnables are done", func() {
 				m, err := New(cfg, Options{
@@ -346,6 +402,76 @@ var _ = Describe("manger.Manager", func() {
 
 				Expect(cm.gracefulShutdownTimeout.Nanoseconds()).To(Equal(int64(0)))
 			})
+			It("should call OnLeadershipLost when stopping to lead, and suffix the identity", func() {
+				lost := make(chan struct{})
+				m, err := New(cfg, Options{
+					LeaderElection:               true,
+					LeaderElectionNamespace:      "default",
+					LeaderElectionID:             "test-leader-election-id-lost",
+					LeaderElectionIdentitySuffix: "shard-0",
+					OnLeadershipLost:             func() { close(lost) },
+					HealthProbeBindAddress:       "0",
+					MetricsBindAddress:           "0",
+				})
+				Expect(err).To(BeNil())
+				cm := m.(*controllerManager)
+				Expect(cm.resourceLock.Identity()).To(HaveSuffix("_shard-0"))
+
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+				mgrDone := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					Expect(m.Start(ctx)).To(MatchError(ContainSubstring("leader election lost")))
+					close(mgrDone)
+				}()
+				<-cm.elected
+
+				cm.leaderElectionCancel()
+				Eventually(lost).Should(BeClosed())
+				<-mgrDone
+			})
+			It("should warm up before starting the leader election runnables", func() {
+				m, err := New(cfg, Options{
+					LeaderElection:          true,
+					LeaderElectionNamespace: "default",
+					LeaderElectionID:        "test-leader-election-id-warmup",
+					LeaderElectionWarmup: &LeaderElectionWarmup{
+						Delay:            2 * time.Second,
+						WaitForCacheSync: true,
+					},
+					HealthProbeBindAddress: "0",
+					MetricsBindAddress:     "0",
+				})
+				Expect(err).To(BeNil())
+				cm := m.(*controllerManager)
+				cm.onStoppedLeading = func() {}
+
+				started := make(chan time.Time, 1)
+				Expect(m.Add(RunnableFunc(func(ctx context.Context) error {
+					started <- time.Now()
+					<-ctx.Done()
+					return nil
+				}))).To(Succeed())
+
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+				begin := time.Now()
+				mgrDone := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					Expect(m.Start(ctx)).To(Succeed())
+					close(mgrDone)
+				}()
+
+				var startedAt time.Time
+				Eventually(started, 20*time.Second).Should(Receive(&startedAt))
+				Expect(startedAt.Sub(begin)).To(BeNumerically(">=", 2*time.Second))
+				Expect(cm.caches).To(ContainElement(cm.GetCache()))
+
+				cancel()
+				<-mgrDone
+			})
 			It("should default ID to controller-runtime if ID is not set", func() {
 				var rl resourcelock.Interface
 				m1, err := New(cfg, Options{
@@ -1666,6 +1792,67 @@ var _ = Describe("manger.Manager", func() {
 		Expect(err).NotTo(HaveOccurred())
 		Expect(m.GetAPIReader()).NotTo(BeNil())
 	})
+	It("should only provide the global reader when enabled", func() {
+		m, err := New(cfg, Options{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.GetGlobalReader()).To(BeNil())
+
+		m, err = New(cfg, Options{EnableGlobalReader: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.GetGlobalReader()).NotTo(BeNil())
+	})
+	It("should only provide the object locks when reconciles are serialized", func() {
+		m, err := New(cfg, Options{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.GetObjectLocks()).To(BeNil())
+
+		m, err = New(cfg, Options{SerializeReconciles: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.GetObjectLocks()).NotTo(BeNil())
+	})
+	It("should only provide the worker pool when its size is set", func() {
+		m, err := New(cfg, Options{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.GetWorkerPool()).To(BeNil())
+
+		m, err = New(cfg, Options{ReconcileWorkerPoolSize: 4})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.GetWorkerPool()).NotTo(BeNil())
+		Expect(m.GetWorkerPool().Size()).To(Equal(4))
+	})
+
+	It("should write its manifest to ManifestPath when started", func() {
+		dir, err := ioutil.TempDir("", "manifest")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+		manifestPath := path.Join(dir, "manifest.json")
+		m, err := New(cfg, Options{
+			ManifestPath: manifestPath,
+			NewCache: func(_ *rest.Config, _ cache.Options) (cache.Cache, error) {
+				return &informertest.FakeInformers{}, nil
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.GetFieldIndexer().IndexField(context.Background(), &corev1.Pod{}, "spec.nodeName", func(client.Object) []string {
+			return nil
+		})).To(Succeed())
+		Expect(m.AddReadyzCheck("ping", func(*http.Request) error { return nil })).To(Succeed())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		Expect(m.Add(RunnableFunc(func(context.Context) error {
+			cancel()
+			return nil
+		}))).To(Succeed())
+		Expect(m.Start(ctx)).To(Succeed())
+
+		data, err := ioutil.ReadFile(manifestPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(MatchJSON(`{
+			"indexes": [{"groupVersionKind": "/v1, Kind=Pod", "field": "spec.nodeName"}],
+			"readyChecks": ["ping"]
+		}`))
+		Expect(m.GetManifest().Indexes).To(HaveLen(1))
+	})
 })
 
 var _ reconcile.Reconciler = &failRec{}
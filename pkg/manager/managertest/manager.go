@@ -0,0 +1,491 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package managertest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"github.com/kcp-dev/logicalcluster"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/config/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/objectlock"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/workerpool"
+)
+
+var _ manager.Manager = &Manager{}
+
+// Options are the arguments for creating a new Manager.
+type Options struct {
+	// Scheme is the scheme of the manager.  Defaults to the kubernetes/client-go scheme.Scheme.
+	Scheme *runtime.Scheme
+
+	// Client is the client of the manager, and the reader of its cache and API reader.
+	// Defaults to an empty fake client of Scheme, see fake.NewClusterBuilder to seed
+	// the objects of several logical clusters.
+	Client client.Client
+
+	// Logger is the logger of the manager.  Defaults to the log.Log global logger.
+	Logger logr.Logger
+
+	// ControllerOptions are returned by GetControllerOptions.
+	ControllerOptions v1alpha1.ControllerConfigurationSpec
+
+	// EnableGlobalReader makes GetGlobalReader return the client of the manager.
+	EnableGlobalReader bool
+
+	// SerializeReconciles makes GetObjectLocks return the locks of the manager.
+	SerializeReconciles bool
+
+	// ReconcileWorkerPoolSize makes GetWorkerPool return a pool of this size.
+	ReconcileWorkerPoolSize int
+
+	// EnableClusterSuspensions makes GetClusterSuspensions return suspensions.
+	EnableClusterSuspensions bool
+
+	// TracerProvider is returned by GetTracerProvider, so that the controllers trace their
+	// reconciles with it.  The calls of Client aren't traced.
+	TracerProvider trace.TracerProvider
+}
+
+// Manager is an in-memory manager.Manager for unit tests: it talks to no API server, its
+// cache reads the objects of its fake client and its informers are fake informers, see
+// informertest.FakeClusterCache.  Leadership is granted when it starts.
+//
+// Start starts the added Runnables and blocks until the context is cancelled or a Runnable
+// fails, and then until every Runnable returned.
+type Manager struct {
+	// Client is the client of the manager.
+	Client client.Client
+
+	// Cache is the cache of the manager.  Its FakeInformers aren't safe for concurrent use:
+	// get the informers sending events to the controllers before starting the manager.
+	Cache *informertest.FakeClusterCache
+
+	// Recorder records the events of every event recorder of the manager.
+	Recorder *record.FakeRecorder
+
+	scheme      *runtime.Scheme
+	config      *rest.Config
+	logger      logr.Logger
+	options     Options
+	objectLocks *objectlock.Locks
+	workerPool  *workerpool.Pool
+	suspensions *cluster.Suspensions
+	tracer      trace.TracerProvider
+
+	mu                   sync.Mutex
+	runnables            []manager.Runnable
+	clusterRunnables     map[logicalcluster.Name][]manager.Runnable
+	healthzChecks        map[string]healthz.Checker
+	readyzChecks         map[string]healthz.Checker
+	metricsExtraHandlers map[string]http.Handler
+	webhookServer        *webhook.Server
+	webhookServers       map[string]*webhook.Server
+	started              bool
+
+	// ctx is the context of the started Runnables, cancelled when a Runnable fails.
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	err    error
+
+	elected chan struct{}
+	stop    chan struct{}
+}
+
+// New returns a new Manager.
+func New(options Options) *Manager {
+	if options.Scheme == nil {
+		options.Scheme = scheme.Scheme
+	}
+	if options.Client == nil {
+		options.Client = fake.NewClusterBuilder().WithScheme(options.Scheme).Build()
+	}
+	if options.Logger.GetSink() == nil {
+		options.Logger = logf.Log
+	}
+
+	m := &Manager{
+		Client: options.Client,
+		Cache: &informertest.FakeClusterCache{
+			FakeInformers: informertest.FakeInformers{Scheme: options.Scheme},
+			Reader:        options.Client,
+		},
+		Recorder:             record.NewFakeRecorder(100),
+		scheme:               options.Scheme,
+		config:               &rest.Config{},
+		logger:               options.Logger,
+		options:              options,
+		healthzChecks:        map[string]healthz.Checker{},
+		readyzChecks:         map[string]healthz.Checker{},
+		metricsExtraHandlers: map[string]http.Handler{},
+		elected:              make(chan struct{}),
+		stop:                 make(chan struct{}),
+		tracer:               options.TracerProvider,
+	}
+	if options.SerializeReconciles {
+		m.objectLocks = objectlock.New()
+	}
+	if options.ReconcileWorkerPoolSize > 0 {
+		m.workerPool = workerpool.New(options.ReconcileWorkerPoolSize)
+	}
+	if options.EnableClusterSuspensions {
+		m.suspensions = cluster.NewSuspensions()
+	}
+	return m
+}
+
+// SetFields implements cluster.Cluster.
+func (m *Manager) SetFields(i interface{}) error {
+	if _, err := inject.ConfigInto(m.config, i); err != nil {
+		return err
+	}
+	if _, err := inject.ClientInto(m.Client, i); err != nil {
+		return err
+	}
+	if _, err := inject.APIReaderInto(m.Client, i); err != nil {
+		return err
+	}
+	if _, err := inject.SchemeInto(m.scheme, i); err != nil {
+		return err
+	}
+	if _, err := inject.CacheInto(m.Cache, i); err != nil {
+		return err
+	}
+	if _, err := inject.MapperInto(m.GetRESTMapper(), i); err != nil {
+		return err
+	}
+	if _, err := inject.InjectorInto(m.SetFields, i); err != nil {
+		return err
+	}
+	if _, err := inject.StopChannelInto(m.stop, i); err != nil {
+		return err
+	}
+	if _, err := inject.LoggerInto(m.GetLogger(), i); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetConfig implements cluster.Cluster.  The config points to no API server.
+func (m *Manager) GetConfig() *rest.Config {
+	return m.config
+}
+
+// GetScheme implements cluster.Cluster.
+func (m *Manager) GetScheme() *runtime.Scheme {
+	return m.scheme
+}
+
+// GetClient implements cluster.Cluster.
+func (m *Manager) GetClient() client.Client {
+	return m.Client
+}
+
+// GetFieldIndexer implements cluster.Cluster.
+func (m *Manager) GetFieldIndexer() client.FieldIndexer {
+	return m.Cache
+}
+
+// GetCache implements cluster.Cluster.
+func (m *Manager) GetCache() cache.Cache {
+	return m.Cache
+}
+
+// GetEventRecorderFor implements cluster.Cluster.  Every recorder records to Recorder.
+func (m *Manager) GetEventRecorderFor(name string) record.EventRecorder {
+	return m.Recorder
+}
+
+// GetRESTMapper implements cluster.Cluster.
+func (m *Manager) GetRESTMapper() meta.RESTMapper {
+	return m.Client.RESTMapper()
+}
+
+// GetAPIReader implements cluster.Cluster.
+func (m *Manager) GetAPIReader() client.Reader {
+	return m.Client
+}
+
+// Add implements manager.Manager.  The Runnable is started right away if the manager is
+// already started.
+func (m *Manager) Add(r manager.Runnable) error {
+	if err := m.SetFields(r); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runnables = append(m.runnables, r)
+	if m.started {
+		m.startRunnable(r)
+	}
+	return nil
+}
+
+// Runnables returns the Runnables added to the manager.
+func (m *Manager) Runnables() []manager.Runnable {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]manager.Runnable(nil), m.runnables...)
+}
+
+// AddForCluster implements manager.Manager.  It injects the dependencies of the Runnable and records
+// it, but never starts it: the manager has no ClusterSet.  See ClusterRunnables.
+func (m *Manager) AddForCluster(name logicalcluster.Name, r manager.Runnable) error {
+	if err := m.SetFields(r); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.clusterRunnables == nil {
+		m.clusterRunnables = map[logicalcluster.Name][]manager.Runnable{}
+	}
+	m.clusterRunnables[name] = append(m.clusterRunnables[name], r)
+	return nil
+}
+
+// ClusterRunnables returns the Runnables added to the manager for the logical cluster.
+func (m *Manager) ClusterRunnables(name logicalcluster.Name) []manager.Runnable {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]manager.Runnable(nil), m.clusterRunnables[name]...)
+}
+
+// startRunnable starts the Runnable, cancelling the other ones if it fails.  It must be
+// called with the lock held.
+func (m *Manager) startRunnable(r manager.Runnable) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		if err := r.Start(m.ctx); err != nil {
+			m.mu.Lock()
+			if m.err == nil {
+				m.err = err
+			}
+			m.mu.Unlock()
+			m.cancel()
+		}
+	}()
+}
+
+// Elected implements manager.Manager.  It is closed when the manager starts.
+func (m *Manager) Elected() <-chan struct{} {
+	return m.elected
+}
+
+// Start implements manager.Manager.
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	if m.started {
+		m.mu.Unlock()
+		return errors.New("manager already started")
+	}
+	m.started = true
+	m.ctx, m.cancel = context.WithCancel(ctx)
+	defer m.cancel()
+	for _, r := range m.runnables {
+		m.startRunnable(r)
+	}
+	close(m.elected)
+	m.mu.Unlock()
+
+	<-m.ctx.Done()
+	close(m.stop)
+	m.wg.Wait()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.err
+}
+
+// AddMetricsExtraHandler implements manager.Manager.  The handlers aren't served.
+func (m *Manager) AddMetricsExtraHandler(path string, handler http.Handler) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.metricsExtraHandlers[path] = handler
+	return nil
+}
+
+// AddHealthzCheck implements manager.Manager.  The checks aren't served, see HealthzChecks.
+func (m *Manager) AddHealthzCheck(name string, check healthz.Checker) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.healthzChecks[name] = check
+	return nil
+}
+
+// AddReadyzCheck implements manager.Manager.  The checks aren't served, see ReadyzChecks.
+func (m *Manager) AddReadyzCheck(name string, check healthz.Checker) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.readyzChecks[name] = check
+	return nil
+}
+
+// HealthzChecks returns the healthz checks added to the manager, by name.
+func (m *Manager) HealthzChecks() map[string]healthz.Checker {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	checks := make(map[string]healthz.Checker, len(m.healthzChecks))
+	for name, check := range m.healthzChecks {
+		checks[name] = check
+	}
+	return checks
+}
+
+// ReadyzChecks returns the readyz checks added to the manager, by name.
+func (m *Manager) ReadyzChecks() map[string]healthz.Checker {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	checks := make(map[string]healthz.Checker, len(m.readyzChecks))
+	for name, check := range m.readyzChecks {
+		checks[name] = check
+	}
+	return checks
+}
+
+// GetWebhookServer implements manager.Manager.  The server is never started by the manager.
+func (m *Manager) GetWebhookServer() *webhook.Server {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.webhookServer == nil {
+		m.webhookServer = &webhook.Server{}
+	}
+	return m.webhookServer
+}
+
+// GetNamedWebhookServer implements manager.Manager.  It returns an empty server for every name,
+// which is never started by the manager.
+func (m *Manager) GetNamedWebhookServer(name string) *webhook.Server {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.webhookServers == nil {
+		m.webhookServers = map[string]*webhook.Server{}
+	}
+	if m.webhookServers[name] == nil {
+		m.webhookServers[name] = &webhook.Server{}
+	}
+	return m.webhookServers[name]
+}
+
+// GetLogger implements manager.Manager.
+func (m *Manager) GetLogger() logr.Logger {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.logger
+}
+
+// SetLogger implements manager.Manager.  Only the loggers returned by GetLogger afterwards
+// use the new logger.
+func (m *Manager) SetLogger(logger logr.Logger) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logger = logger
+}
+
+// GetControllerOptions implements manager.Manager.
+func (m *Manager) GetControllerOptions() v1alpha1.ControllerConfigurationSpec {
+	return m.options.ControllerOptions
+}
+
+// GetGlobalReader implements manager.Manager.  It returns the client of the manager if
+// EnableGlobalReader is set, or else nil.
+func (m *Manager) GetGlobalReader() client.Reader {
+	if !m.options.EnableGlobalReader {
+		return nil
+	}
+	return m.Client
+}
+
+// GetObjectLocks implements manager.Manager.
+func (m *Manager) GetObjectLocks() *objectlock.Locks {
+	return m.objectLocks
+}
+
+// GetWorkerPool implements manager.Manager.
+func (m *Manager) GetWorkerPool() *workerpool.Pool {
+	return m.workerPool
+}
+
+// GetClusterSuspensions implements manager.Manager.
+func (m *Manager) GetClusterSuspensions() *cluster.Suspensions {
+	return m.suspensions
+}
+
+// GetTracerProvider implements manager.Manager.
+func (m *Manager) GetTracerProvider() trace.TracerProvider {
+	return m.tracer
+}
+
+// GetManifest implements manager.Manager.  It describes the added Runnables, the webhooks
+// registered on the webhook servers and the health checks, but no indexes nor clusters.
+func (m *Manager) GetManifest() manager.Manifest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var manifest manager.Manifest
+	manifest.AddRunnables(m.runnables...)
+	if m.webhookServer != nil {
+		manifest.AddRunnables(m.webhookServer)
+	}
+	names := make([]string, 0, len(m.webhookServers))
+	for name := range m.webhookServers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		manifest.AddRunnables(m.webhookServers[name])
+	}
+	manifest.HealthChecks = sortedNames(m.healthzChecks)
+	manifest.ReadyChecks = sortedNames(m.readyzChecks)
+	return manifest
+}
+
+// sortedNames returns the sorted names of the checks.
+func sortedNames(checks map[string]healthz.Checker) []string {
+	if len(checks) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
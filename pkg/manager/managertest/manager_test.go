@@ -0,0 +1,127 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package managertest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/manager/managertest"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var _ = Describe("Manager", func() {
+	It("should run the controllers against the fake client", func() {
+		cluster := logicalcluster.New("root:org")
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "default"}, Data: map[string]string{"key": "value"}}
+		m := managertest.New(managertest.Options{
+			Client: fake.NewClusterBuilder().WithObjects(cluster, cm).Build(),
+		})
+
+		reconciled := make(chan string, 1)
+		c, err := controller.New("configmaps", m, controller.Options{
+			Reconciler: reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+				read := &corev1.ConfigMap{}
+				if err := m.GetClient().Get(ctx, req.ObjectKey, read); err != nil {
+					return reconcile.Result{}, err
+				}
+				reconciled <- req.Cluster.String() + "/" + read.Data["key"]
+				return reconcile.Result{}, nil
+			}),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		events := make(chan event.GenericEvent, 1)
+		Expect(c.Watch(&source.Channel{Source: events}, &handler.EnqueueRequestForObject{})).To(Succeed())
+		Expect(m.Runnables()).To(ConsistOf(c))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- m.Start(ctx) }()
+		Eventually(m.Elected()).Should(BeClosed())
+
+		obj := cm.DeepCopy()
+		obj.ClusterName = cluster.String()
+		events <- event.GenericEvent{Object: obj}
+		Eventually(reconciled).Should(Receive(Equal("root:org/value")))
+
+		cancel()
+		Eventually(done).Should(Receive(BeNil()))
+	})
+
+	It("should stop the other runnables and return the error of a failing runnable", func() {
+		m := managertest.New(managertest.Options{})
+		stopped := make(chan struct{})
+		Expect(m.Add(manager.RunnableFunc(func(ctx context.Context) error {
+			<-ctx.Done()
+			close(stopped)
+			return nil
+		}))).To(Succeed())
+		Expect(m.Add(manager.RunnableFunc(func(context.Context) error {
+			return errors.New("failed")
+		}))).To(Succeed())
+
+		Expect(m.Start(context.Background())).To(MatchError("failed"))
+		Expect(stopped).To(BeClosed())
+	})
+
+	It("should record the events of the event recorders", func() {
+		m := managertest.New(managertest.Options{})
+		m.GetEventRecorderFor("test").Event(&corev1.ConfigMap{}, corev1.EventTypeNormal, "Reason", "message")
+		Expect(m.Recorder.Events).To(Receive(Equal("Normal Reason message")))
+	})
+
+	It("should describe the controllers, webhooks and health checks in the manifest", func() {
+		m := managertest.New(managertest.Options{})
+		c, err := controller.New("configmaps", m, controller.Options{
+			MaxConcurrentReconciles: 2,
+			Reconciler: reconcile.Func(func(context.Context, reconcile.Request) (reconcile.Result, error) {
+				return reconcile.Result{}, nil
+			}),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Watch(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestForObject{})).To(Succeed())
+		m.GetWebhookServer().Register("/validate", http.NotFoundHandler())
+		Expect(m.AddHealthzCheck("ping", healthz.Ping)).To(Succeed())
+
+		Expect(m.GetManifest()).To(Equal(manager.Manifest{
+			Controllers: []manager.ControllerManifest{{
+				Name:                    "configmaps",
+				MaxConcurrentReconciles: 2,
+				Watches: []manager.WatchManifest{{
+					Source:           "kind source: *v1.ConfigMap",
+					GroupVersionKind: "/v1, Kind=ConfigMap",
+				}},
+			}},
+			Webhooks:     []manager.WebhookManifest{{Path: "/validate", Port: 9443}},
+			HealthChecks: []string{"ping"},
+		}))
+	})
+})
@@ -0,0 +1,165 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// Manifest is a machine-readable description of the composition of a manager: its controllers
+// and what they watch, its logical clusters, webhooks, field indexes and health checks.  It is
+// meant for inventory tooling, e.g. to detect drift between the controllers deployed and the
+// expected configuration.  See Manager.GetManifest and Options.ManifestPath.
+type Manifest struct {
+	// Controllers are the controllers added to the manager, in the order they were added.
+	Controllers []ControllerManifest `json:"controllers,omitempty"`
+
+	// Clusters are the logical clusters of the ClusterSet of the manager, sorted.
+	Clusters []string `json:"clusters,omitempty"`
+
+	// Webhooks are the webhooks served by the webhook servers added to the manager.
+	Webhooks []WebhookManifest `json:"webhooks,omitempty"`
+
+	// Indexes are the field indexes added with the FieldIndexer of the manager, in the
+	// order they were added.
+	Indexes []IndexManifest `json:"indexes,omitempty"`
+
+	// HealthChecks and ReadyChecks are the names of the liveness and readiness checks, sorted.
+	HealthChecks []string `json:"healthChecks,omitempty"`
+	ReadyChecks  []string `json:"readyChecks,omitempty"`
+}
+
+// ControllerManifest describes a controller in a Manifest.
+type ControllerManifest struct {
+	// Name is the name of the controller.
+	Name string `json:"name"`
+
+	// MaxConcurrentReconciles is the number of workers of the controller.
+	MaxConcurrentReconciles int `json:"maxConcurrentReconciles,omitempty"`
+
+	// Watches are the sources the controller watches, in the order they were added.
+	Watches []WatchManifest `json:"watches,omitempty"`
+}
+
+// WatchManifest describes a source watched by a controller in a Manifest.
+type WatchManifest struct {
+	// Source describes the source, e.g. "kind source: *v1.Pod".
+	Source string `json:"source"`
+
+	// GroupVersionKind is the GroupVersionKind of the objects of the source, if known.
+	GroupVersionKind string `json:"groupVersionKind,omitempty"`
+}
+
+// WebhookManifest describes a webhook in a Manifest.
+type WebhookManifest struct {
+	// Path is the path the webhook is served at.
+	Path string `json:"path"`
+
+	// Port is the port of the webhook server serving the webhook.
+	Port int `json:"port,omitempty"`
+}
+
+// IndexManifest describes a field index in a Manifest.
+type IndexManifest struct {
+	// GroupVersionKind is the GroupVersionKind of the indexed objects.
+	GroupVersionKind string `json:"groupVersionKind"`
+
+	// Field is the name of the index.
+	Field string `json:"field"`
+}
+
+// ControllerDescriber is implemented by the Runnables which are controllers, to describe
+// themselves in the Manifest of the manager.
+type ControllerDescriber interface {
+	DescribeController() ControllerManifest
+}
+
+// AddRunnables describes the given Runnables in the manifest: the controllers implementing
+// ControllerDescriber, and the webhooks of the webhook servers.  The other Runnables are skipped.
+func (m *Manifest) AddRunnables(runnables ...Runnable) {
+	for _, r := range runnables {
+		switch r := r.(type) {
+		case ControllerDescriber:
+			m.Controllers = append(m.Controllers, r.DescribeController())
+		case *webhook.Server:
+			for _, path := range r.Paths() {
+				m.Webhooks = append(m.Webhooks, WebhookManifest{Path: path, Port: r.Port})
+			}
+		}
+	}
+}
+
+// WriteFile writes the manifest as indented JSON to the given file.
+func (m *Manifest) WriteFile(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644) //nolint:gosec
+}
+
+// BindManifestFlags binds the flags setting Options.ManifestPath to the given flag set.
+func (o *Options) BindManifestFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.ManifestPath, "manifest-path", o.ManifestPath,
+		"The file the JSON manifest of the manager, describing its controllers, watches, clusters, webhooks, "+
+			"indexes and health checks, is written to when it starts. Not written if empty.")
+}
+
+// checkNames returns the sorted names of the checks of the handler.
+func checkNames(h *healthz.Handler) []string {
+	if h == nil || len(h.Checks) == 0 {
+		return nil
+	}
+	checks := h.Checks
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// manifestFieldIndexer records the indexes added to the manager for its Manifest.
+type manifestFieldIndexer struct {
+	client.FieldIndexer
+	scheme *runtime.Scheme
+	record func(IndexManifest)
+}
+
+// IndexField implements client.FieldIndexer.
+func (i *manifestFieldIndexer) IndexField(ctx context.Context, obj client.Object, field string, extractValue client.IndexerFunc) error {
+	if err := i.FieldIndexer.IndexField(ctx, obj, field, extractValue); err != nil {
+		return err
+	}
+	index := IndexManifest{GroupVersionKind: fmt.Sprintf("%T", obj), Field: field}
+	if gvk, err := apiutil.GVKForObject(obj, i.scheme); err == nil {
+		index.GroupVersionKind = gvk.String()
+	}
+	i.record(index)
+	return nil
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manager
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// QueueDebugPath is the path of the metrics server the queues of the controllers are dumped
+// on, when Options.EnableQueueDebugHandler is set.
+const QueueDebugPath = "/debug/queues"
+
+// QueuedRequest describes a request waiting in the queue of a controller, or being reconciled.
+type QueuedRequest struct {
+	// Key is the key of the request, see reconcile.Request.Key.
+	Key string `json:"key"`
+
+	// Retries is the number of times the request was requeued with a backoff since it was
+	// last reconciled successfully.
+	Retries int `json:"retries,omitempty"`
+
+	// FirstEnqueued is when the request was first enqueued since it was last reconciled
+	// successfully.
+	FirstEnqueued time.Time `json:"firstEnqueued"`
+
+	// NextRetry is when the request is due, if it waits for a backoff or a delay.
+	NextRetry *time.Time `json:"nextRetry,omitempty"`
+}
+
+// ClusterQueue describes the requests of a logical cluster in the queue of a controller.
+type ClusterQueue struct {
+	// Cluster is the logical cluster of the requests, empty for the requests without one.
+	Cluster string `json:"cluster"`
+
+	// Retries is the sum of the retries of the requests.
+	Retries int `json:"retries"`
+
+	// Requests are the requests, in the order they were first enqueued.
+	Requests []QueuedRequest `json:"requests"`
+}
+
+// ControllerQueue describes the requests in the queue of a controller, by logical cluster.
+type ControllerQueue struct {
+	// Name is the name of the controller.
+	Name string `json:"name"`
+
+	// Clusters are the logical clusters with requests in the queue, the ones with the most
+	// retries first.
+	Clusters []ClusterQueue `json:"clusters"`
+}
+
+// QueueDumper is implemented by the Runnables which are controllers, to dump their queue
+// on QueueDebugPath.
+type QueueDumper interface {
+	DumpQueue() ControllerQueue
+}
+
+// SortClusterQueues sorts queues by decreasing retries, then by logical cluster.
+func SortClusterQueues(queues []ClusterQueue) {
+	sort.Slice(queues, func(i, j int) bool {
+		if queues[i].Retries != queues[j].Retries {
+			return queues[i].Retries > queues[j].Retries
+		}
+		return queues[i].Cluster < queues[j].Cluster
+	})
+}
+
+// queueDebugHandler serves the queues of the controllers among the runnables as JSON.  The
+// cluster and controller query parameters restrict the dump to a logical cluster and to a
+// controller.
+type queueDebugHandler struct {
+	runnables func() []Runnable
+}
+
+// ServeHTTP implements http.Handler.
+func (h *queueDebugHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	controller := req.URL.Query().Get("controller")
+	_, filterCluster := req.URL.Query()["cluster"]
+	cluster := req.URL.Query().Get("cluster")
+
+	queues := []ControllerQueue{}
+	for _, r := range h.runnables() {
+		dumper, ok := r.(QueueDumper)
+		if !ok {
+			continue
+		}
+		queue := dumper.DumpQueue()
+		if controller != "" && queue.Name != controller {
+			continue
+		}
+		if filterCluster {
+			clusters := []ClusterQueue{}
+			for _, c := range queue.Clusters {
+				if c.Cluster == cluster {
+					clusters = append(clusters, c)
+				}
+			}
+			queue.Clusters = clusters
+		}
+		queues = append(queues, queue)
+	}
+	sort.Slice(queues, func(i, j int) bool {
+		return queues[i].Name < queues[j].Name
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(struct {
+		Controllers []ControllerQueue `json:"controllers"`
+	}{queues})
+}
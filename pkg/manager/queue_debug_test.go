@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// queueDumperRunnable is a Runnable dumping a fixed queue.
+type queueDumperRunnable struct {
+	queue ControllerQueue
+}
+
+func (r *queueDumperRunnable) Start(context.Context) error { return nil }
+
+func (r *queueDumperRunnable) DumpQueue() ControllerQueue { return r.queue }
+
+var _ = Describe("queueDebugHandler", func() {
+	var handler http.Handler
+
+	BeforeEach(func() {
+		runnables := []Runnable{
+			&queueDumperRunnable{queue: ControllerQueue{Name: "b", Clusters: []ClusterQueue{
+				{Cluster: "root:stuck", Retries: 12, Requests: []QueuedRequest{{Key: "ns/name", Retries: 12}}},
+				{Cluster: "root:ok", Requests: []QueuedRequest{{Key: "ns/other"}}},
+			}}},
+			RunnableFunc(func(context.Context) error { return nil }),
+			&queueDumperRunnable{queue: ControllerQueue{Name: "a", Clusters: []ClusterQueue{}}},
+		}
+		handler = &queueDebugHandler{runnables: func() []Runnable { return runnables }}
+	})
+
+	dump := func(target string) []ControllerQueue {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal("application/json"))
+		var out struct {
+			Controllers []ControllerQueue `json:"controllers"`
+		}
+		Expect(json.Unmarshal(rec.Body.Bytes(), &out)).To(Succeed())
+		return out.Controllers
+	}
+
+	It("should dump the queues of the controllers, sorted by name", func() {
+		queues := dump(QueueDebugPath)
+		Expect(queues).To(HaveLen(2))
+		Expect(queues[0].Name).To(Equal("a"))
+		Expect(queues[1].Name).To(Equal("b"))
+		Expect(queues[1].Clusters).To(HaveLen(2))
+		Expect(queues[1].Clusters[0].Cluster).To(Equal("root:stuck"))
+	})
+
+	It("should restrict the dump to a controller and a logical cluster", func() {
+		queues := dump(QueueDebugPath + "?controller=b&cluster=root:stuck")
+		Expect(queues).To(HaveLen(1))
+		Expect(queues[0].Clusters).To(Equal([]ClusterQueue{
+			{Cluster: "root:stuck", Retries: 12, Requests: []QueuedRequest{{Key: "ns/name", Retries: 12}}},
+		}))
+	})
+})
+
+var _ = Describe("SortClusterQueues", func() {
+	It("should sort the queues by decreasing retries, then by logical cluster", func() {
+		queues := []ClusterQueue{{Cluster: "c"}, {Cluster: "b", Retries: 1}, {Cluster: "a"}, {Cluster: "d", Retries: 5}}
+		SortClusterQueues(queues)
+		Expect(queues).To(Equal([]ClusterQueue{{Cluster: "d", Retries: 5}, {Cluster: "b", Retries: 1}, {Cluster: "a"}, {Cluster: "c"}}))
+	})
+})
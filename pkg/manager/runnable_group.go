@@ -3,6 +3,8 @@ package manager
 import (
 	"context"
 	"errors"
+	"fmt"
+	"reflect"
 	"sync"
 
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -18,6 +20,106 @@ type readyRunnable struct {
 	Runnable
 	Check       runnableCheck
 	signalReady bool
+
+	// group is the group the runnable belongs to.
+	group *runnableGroup
+
+	// dependencies must be ready before the runnable is started, and
+	// dependents must be stopped before it is stopped.
+	dependencies []*readyRunnable
+	dependents   []*readyRunnable
+
+	// ctx is the context the runnable is started with, cancel stops it.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// ready is closed once the check of the runnable passed, and done
+	// once the runnable returned, or won't be started anymore.
+	ready chan struct{}
+	done  chan struct{}
+
+	mu       sync.Mutex
+	launched bool
+	stopped  bool
+}
+
+func newReadyRunnable(rn Runnable, ready runnableCheck) *readyRunnable {
+	if ready == nil {
+		ready = func(_ context.Context) bool { return true }
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &readyRunnable{
+		Runnable: rn,
+		Check:    ready,
+		ctx:      ctx,
+		cancel:   cancel,
+		ready:    make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// launch marks the runnable as being started, unless it was stopped before.
+func (rn *readyRunnable) launch() bool {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	if rn.stopped {
+		return false
+	}
+	rn.launched = true
+	return true
+}
+
+// waitForDependencies waits until the dependencies of the runnable are ready.
+// It returns false if the runnable was stopped, or a dependency returned
+// without getting ready, in the meantime.
+func (rn *readyRunnable) waitForDependencies() bool {
+	for _, dep := range rn.dependencies {
+		select {
+		case <-dep.ready:
+		case <-dep.done:
+			select {
+			case <-dep.ready:
+			default:
+				return false
+			}
+		case <-rn.ctx.Done():
+			return false
+		}
+	}
+	return rn.ctx.Err() == nil
+}
+
+// stop stops the dependents of the runnable, then the runnable itself, and
+// waits until it returned or the context is done.
+func (rn *readyRunnable) stop(ctx context.Context) {
+	rn.mu.Lock()
+	dependents := append([]*readyRunnable(nil), rn.dependents...)
+	rn.mu.Unlock()
+	for _, dependent := range dependents {
+		dependent.stop(ctx)
+	}
+
+	rn.mu.Lock()
+	if !rn.stopped {
+		rn.stopped = true
+		rn.cancel()
+		if !rn.launched {
+			close(rn.done)
+		}
+	}
+	rn.mu.Unlock()
+
+	select {
+	case <-rn.done:
+	case <-ctx.Done():
+	}
+}
+
+// sameRunnable returns whether both runnables are the same, without
+// panicking on runnables which can't be compared, e.g. RunnableFuncs.
+func sameRunnable(a, b Runnable) bool {
+	ta := reflect.TypeOf(a)
+	return ta == reflect.TypeOf(b) && ta.Comparable() && a == b
 }
 
 // runnableCheck can be passed to Add() to let the runnable group determine that a
@@ -32,16 +134,27 @@ type runnables struct {
 	Caches         *runnableGroup
 	LeaderElection *runnableGroup
 	Others         *runnableGroup
+
+	// mu protects the dependency graph of the runnables, which is resolved
+	// when the manager is started, and for each runnable added afterwards.
+	mu       sync.Mutex
+	all      []*readyRunnable
+	resolved bool
 }
 
 // newRunnables creates a new runnables object.
 func newRunnables(errChan chan error) *runnables {
-	return &runnables{
+	r := &runnables{
 		Webhooks:       newRunnableGroup(errChan),
 		Caches:         newRunnableGroup(errChan),
 		LeaderElection: newRunnableGroup(errChan),
 		Others:         newRunnableGroup(errChan),
 	}
+	// The groups are started in this order.
+	for i, group := range []*runnableGroup{r.Webhooks, r.Caches, r.Others, r.LeaderElection} {
+		group.order = i
+	}
+	return r
 }
 
 // Add adds a runnable to closest group of runnable that they belong to.
@@ -51,23 +164,133 @@ func newRunnables(errChan chan error) *runnables {
 // The runnables added before Start are started when Start is called.
 // The runnables added after Start are started directly.
 func (r *runnables) Add(fn Runnable) error {
+	group, ready := r.groupFor(fn)
+	rn := newReadyRunnable(fn, ready)
+	rn.group = group
+
+	r.mu.Lock()
+	if r.resolved {
+		if err := r.resolve(rn); err != nil {
+			r.mu.Unlock()
+			return err
+		}
+	}
+	r.all = append(r.all, rn)
+	r.mu.Unlock()
+
+	return group.add(rn)
+}
+
+// List returns the runnables added so far, in the order they were added.
+func (r *runnables) List() []Runnable {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := make([]Runnable, 0, len(r.all))
+	for _, rn := range r.all {
+		list = append(list, rn.Runnable)
+	}
+	return list
+}
+
+// groupFor returns the group of the runnable, and how to check it is ready.
+func (r *runnables) groupFor(fn Runnable) (*runnableGroup, runnableCheck) {
 	switch runnable := fn.(type) {
 	case hasCache:
-		return r.Caches.Add(fn, func(ctx context.Context) bool {
+		return r.Caches, func(ctx context.Context) bool {
 			return runnable.GetCache().WaitForCacheSync(ctx)
-		})
+		}
 	case *webhook.Server:
-		return r.Webhooks.Add(fn, nil)
+		return r.Webhooks, nil
 	case LeaderElectionRunnable:
 		if !runnable.NeedLeaderElection() {
-			return r.Others.Add(fn, nil)
+			return r.Others, nil
 		}
-		return r.LeaderElection.Add(fn, nil)
+		return r.LeaderElection, nil
 	default:
-		return r.LeaderElection.Add(fn, nil)
+		return r.LeaderElection, nil
 	}
 }
 
+// ResolveDependencies links the runnables added so far to the runnables
+// they depend on, see DependentRunnable, and fails if a dependency wasn't
+// added, is started after its dependent, or is part of a cycle.  The
+// dependencies of the runnables added afterwards are resolved by Add.
+func (r *runnables) ResolveDependencies() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.resolved {
+		return nil
+	}
+	for _, rn := range r.all {
+		if err := r.resolve(rn); err != nil {
+			return err
+		}
+	}
+
+	// Look for cycles with a depth-first search, the runnables being visited
+	// are grey, the ones visited already are black.
+	const (
+		grey = iota + 1
+		black
+	)
+	colors := map[*readyRunnable]int{}
+	var visit func(rn *readyRunnable) error
+	visit = func(rn *readyRunnable) error {
+		switch colors[rn] {
+		case grey:
+			return fmt.Errorf("runnable %T is part of a dependency cycle", rn.Runnable)
+		case black:
+			return nil
+		}
+		colors[rn] = grey
+		for _, dep := range rn.dependencies {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		colors[rn] = black
+		return nil
+	}
+	for _, rn := range r.all {
+		if err := visit(rn); err != nil {
+			return err
+		}
+	}
+
+	r.resolved = true
+	return nil
+}
+
+// resolve links the runnable to the runnables it depends on.  r.mu must be held.
+func (r *runnables) resolve(rn *readyRunnable) error {
+	dependent, ok := rn.Runnable.(DependentRunnable)
+	if !ok {
+		return nil
+	}
+	for _, fn := range dependent.DependsOn() {
+		var dep *readyRunnable
+		for _, existing := range r.all {
+			if sameRunnable(existing.Runnable, fn) {
+				dep = existing
+				break
+			}
+		}
+		switch {
+		case dep == nil:
+			return fmt.Errorf("runnable %T depends on runnable %T, which wasn't added to the manager", rn.Runnable, fn)
+		case dep == rn:
+			return fmt.Errorf("runnable %T depends on itself", rn.Runnable)
+		case dep.group.order > rn.group.order:
+			return fmt.Errorf("runnable %T depends on runnable %T, which is started after it", rn.Runnable, fn)
+		}
+		rn.dependencies = append(rn.dependencies, dep)
+		dep.mu.Lock()
+		dep.dependents = append(dep.dependents, rn)
+		dep.mu.Unlock()
+	}
+	return nil
+}
+
 // runnableGroup manages a group of runnables that are
 // meant to be running together until StopAndWait is called.
 //
@@ -77,6 +300,12 @@ type runnableGroup struct {
 	ctx    context.Context
 	cancel context.CancelFunc
 
+	// order is the position of the group in the start sequence of the manager.
+	order int
+
+	// runnables are all the runnables added to the group.
+	runnables []*readyRunnable
+
 	start        sync.Mutex
 	startOnce    sync.Once
 	started      bool
@@ -198,24 +427,45 @@ func (r *runnableGroup) reconcile() {
 			r.stop.RUnlock()
 		}
 
+		// Drop the runnables which were stopped before they could be
+		// started, e.g. along with a runnable they depend on.
+		if !runnable.launch() {
+			r.wg.Done()
+			if runnable.signalReady {
+				go func(rn *readyRunnable) { r.startReadyCh <- rn }(runnable)
+			}
+			continue
+		}
+
 		// Start the runnable.
 		go func(rn *readyRunnable) {
+			// If we return, the runnable ended cleanly
+			// or returned an error to the channel.
+			//
+			// We should always decrement the WaitGroup here.
+			defer r.wg.Done()
+			defer close(rn.done)
+
+			// Wait for the runnables it depends on to be ready.  If it
+			// won't be started, don't hold the start of the group.
+			if !rn.waitForDependencies() {
+				if rn.signalReady {
+					r.startReadyCh <- rn
+				}
+				return
+			}
+
 			go func() {
 				if rn.Check(r.ctx) {
+					close(rn.ready)
 					if rn.signalReady {
 						r.startReadyCh <- rn
 					}
 				}
 			}()
 
-			// If we return, the runnable ended cleanly
-			// or returned an error to the channel.
-			//
-			// We should always decrement the WaitGroup here.
-			defer r.wg.Done()
-
 			// Start the runnable.
-			if err := rn.Start(r.ctx); err != nil {
+			if err := rn.Start(rn.ctx); err != nil {
 				r.errChan <- err
 			}
 		}(runnable)
@@ -225,6 +475,10 @@ func (r *runnableGroup) reconcile() {
 // Add should be able to be called before and after Start, but not after StopAndWait.
 // Add should return an error when called during StopAndWait.
 func (r *runnableGroup) Add(rn Runnable, ready runnableCheck) error {
+	return r.add(newReadyRunnable(rn, ready))
+}
+
+func (r *runnableGroup) add(readyRunnable *readyRunnable) error {
 	r.stop.RLock()
 	if r.stopped {
 		r.stop.RUnlock()
@@ -232,14 +486,7 @@ func (r *runnableGroup) Add(rn Runnable, ready runnableCheck) error {
 	}
 	r.stop.RUnlock()
 
-	if ready == nil {
-		ready = func(_ context.Context) bool { return true }
-	}
-
-	readyRunnable := &readyRunnable{
-		Runnable: rn,
-		Check:    ready,
-	}
+	readyRunnable.group = r
 
 	// Handle start.
 	// If the overall runnable group isn't started yet
@@ -247,6 +494,7 @@ func (r *runnableGroup) Add(rn Runnable, ready runnableCheck) error {
 	// queue them up again later.
 	{
 		r.start.Lock()
+		r.runnables = append(r.runnables, readyRunnable)
 
 		// Check if we're already started.
 		if !r.started {
@@ -276,8 +524,15 @@ func (r *runnableGroup) StopAndWait(ctx context.Context) {
 		r.stopped = true
 		r.stop.Unlock()
 
-		// Cancel the internal channel.
+		// Cancel the internal channel, and stop the runnables once
+		// the runnables depending on them are stopped.
 		r.cancel()
+		r.start.Lock()
+		runnables := append([]*readyRunnable(nil), r.runnables...)
+		r.start.Unlock()
+		for _, rn := range runnables {
+			go rn.stop(ctx)
+		}
 
 		done := make(chan struct{})
 		go func() {
@@ -4,12 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 )
@@ -45,6 +47,70 @@ var _ = Describe("runnables", func() {
 		Expect(r.Add(runnable)).To(Succeed())
 		Expect(r.LeaderElection.startQueue).To(HaveLen(1))
 	})
+
+	It("should start runnables once their dependencies are ready, and stop them first", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		events := &eventLog{}
+		synced := make(chan struct{})
+		informers := &dependentRunnable{name: "cache", events: events}
+		caches := &cachedRunnable{dependentRunnable: informers, cache: &blockingSyncCache{synced: synced}}
+		set := &dependentRunnable{name: "set", events: events, deps: []Runnable{caches}}
+		ctrl := &dependentRunnable{name: "controller", events: events, deps: []Runnable{set}, leaderElection: true}
+
+		r := newRunnables(errCh)
+		Expect(r.Add(ctrl)).To(Succeed())
+		Expect(r.Add(set)).To(Succeed())
+		Expect(r.Add(caches)).To(Succeed())
+		Expect(r.ResolveDependencies()).To(Succeed())
+
+		for _, group := range []*runnableGroup{r.LeaderElection, r.Others, r.Caches} {
+			go func(group *runnableGroup) {
+				defer GinkgoRecover()
+				Expect(group.Start(ctx)).To(Succeed())
+			}(group)
+		}
+		Eventually(events.get).Should(Equal([]string{"start cache"}))
+		Consistently(events.get, 100*time.Millisecond).Should(Equal([]string{"start cache"}))
+
+		close(synced)
+		Eventually(events.get).Should(HaveLen(3))
+		Expect(events.get()[1:]).To(ConsistOf("start set", "start controller"))
+
+		r.Caches.StopAndWait(ctx)
+		Expect(events.get()[3:]).To(Equal([]string{"stop controller", "stop set", "stop cache"}))
+	})
+
+	It("should fail to resolve cyclic dependencies", func() {
+		a := &dependentRunnable{name: "a"}
+		b := &dependentRunnable{name: "b", deps: []Runnable{a}}
+		a.deps = []Runnable{b}
+
+		r := newRunnables(errCh)
+		Expect(r.Add(a)).To(Succeed())
+		Expect(r.Add(b)).To(Succeed())
+		Expect(r.ResolveDependencies()).To(MatchError(ContainSubstring("cycle")))
+	})
+
+	It("should fail to resolve dependencies which are missing or started later", func() {
+		ctrl := &dependentRunnable{name: "controller", leaderElection: true}
+		set := &dependentRunnable{name: "set", deps: []Runnable{ctrl}}
+
+		r := newRunnables(errCh)
+		Expect(r.Add(set)).To(Succeed())
+		Expect(r.ResolveDependencies()).To(MatchError(ContainSubstring("wasn't added")))
+
+		r = newRunnables(errCh)
+		Expect(r.Add(ctrl)).To(Succeed())
+		Expect(r.Add(set)).To(Succeed())
+		Expect(r.ResolveDependencies()).To(MatchError(ContainSubstring("started after it")))
+	})
+
+	It("should resolve the dependencies of the runnables added once resolved", func() {
+		r := newRunnables(errCh)
+		Expect(r.ResolveDependencies()).To(Succeed())
+		Expect(r.Add(&dependentRunnable{name: "set", deps: []Runnable{&dependentRunnable{name: "missing"}}})).NotTo(Succeed())
+	})
 })
 
 var _ = Describe("runnableGroup", func() {
@@ -180,3 +246,69 @@ var _ = Describe("runnableGroup", func() {
 		}
 	})
 })
+
+// eventLog records the events of runnables.
+type eventLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *eventLog) add(event string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, event)
+}
+
+func (l *eventLog) get() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.events...)
+}
+
+// dependentRunnable records when it is started and stopped.
+type dependentRunnable struct {
+	name           string
+	deps           []Runnable
+	leaderElection bool
+	events         *eventLog
+}
+
+func (r *dependentRunnable) Start(ctx context.Context) error {
+	r.events.add("start " + r.name)
+	<-ctx.Done()
+	r.events.add("stop " + r.name)
+	return nil
+}
+
+func (r *dependentRunnable) DependsOn() []Runnable {
+	return r.deps
+}
+
+func (r *dependentRunnable) NeedLeaderElection() bool {
+	return r.leaderElection
+}
+
+// cachedRunnable is a dependentRunnable with a cache.
+type cachedRunnable struct {
+	*dependentRunnable
+	cache cache.Cache
+}
+
+func (r *cachedRunnable) GetCache() cache.Cache {
+	return r.cache
+}
+
+// blockingSyncCache is synced once synced is closed.
+type blockingSyncCache struct {
+	informertest.FakeInformers
+	synced chan struct{}
+}
+
+func (c *blockingSyncCache) WaitForCacheSync(ctx context.Context) bool {
+	select {
+	case <-c.synced:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manager
+
+import (
+	"context"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ client.Reader = &wildcardReader{}
+
+// wildcardReader is a client.Reader which lists objects of all the logical
+// clusters, whatever the cluster of the context.  It only exposes the Reader
+// methods of the underlying cache, so that its users can't register informers
+// or indexes on it.
+type wildcardReader struct {
+	reader client.Reader
+}
+
+// Get implements client.Reader.  The key must hold the cluster of the object.
+func (r *wildcardReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	return r.reader.Get(ctx, key, obj)
+}
+
+// List implements client.Reader.
+func (r *wildcardReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return r.reader.List(client.ListContext(kcpclient.WithCluster(ctx, logicalcluster.Wildcard), opts...), list, opts...)
+}
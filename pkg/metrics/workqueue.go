@@ -99,6 +99,13 @@ func init() {
 	workqueue.SetProvider(workqueueMetricsProvider{})
 }
 
+// WorkqueueMetricsProvider returns the provider of the metrics of the workqueues, registered
+// in Registry, for the queues which implement their own metrics, e.g. whose items aren't
+// stored in a workqueue.Type.
+func WorkqueueMetricsProvider() workqueue.MetricsProvider {
+	return workqueueMetricsProvider{}
+}
+
 type workqueueMetricsProvider struct{}
 
 func (workqueueMetricsProvider) NewDepthMetric(name string) workqueue.GaugeMetric {
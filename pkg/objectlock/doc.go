@@ -0,0 +1,36 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package objectlock serializes the reconciles of the same object across the controllers of a
+manager, so that two controllers never write the same object concurrently, e.g. a controller
+reconciling the status of an object and another one adding its finalizers.
+
+The locks are keyed by the logical cluster, the GroupKind, the namespace and the name of the
+objects.  Controllers lock the object of each request before reconciling it when the manager
+has SerializeReconciles set, and pass the Owner of the lock in the context of the request, so
+that the Reconciler can lock other objects it writes too:
+
+	owner, _ := objectlock.OwnerFrom(ctx)
+	if err := owner.Lock(ctx, objectlock.KeyFor(gk, client.ObjectKeyFromObject(secret))); err != nil {
+		return reconcile.Result{}, err
+	}
+
+The locks held by an Owner are released once the request is reconciled.  Lock returns
+ErrDeadlock instead of waiting when the Owners would wait for each other, so that the
+Reconciler returns an error, releasing its locks, and is retried with a backoff.
+*/
+package objectlock
@@ -0,0 +1,181 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectlock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ErrDeadlock is returned by Owner.Lock when waiting for the lock would deadlock, as its
+// holder waits, directly or not, for a lock of the Owner.
+var ErrDeadlock = errors.New("locking the object would deadlock")
+
+// Key identifies a locked object.  It holds the GroupKind of the object rather than its
+// GroupVersionKind, since all the versions of a kind are the same objects.
+type Key struct {
+	GroupKind schema.GroupKind
+	client.ObjectKey
+}
+
+// KeyFor returns the Key of the object of the given kind.
+func KeyFor(gk schema.GroupKind, key client.ObjectKey) Key {
+	return Key{GroupKind: gk, ObjectKey: key}
+}
+
+// Locks are the locks of the objects shared by the controllers of a manager.
+type Locks struct {
+	mu sync.Mutex
+
+	// held holds the locked objects.
+	held map[Key]*hold
+
+	// waiting holds the object each Owner waits for, for deadlock detection.
+	waiting map[*Owner]Key
+}
+
+// hold is a lock held by an Owner.
+type hold struct {
+	owner *Owner
+
+	// released is closed once the lock is released.
+	released chan struct{}
+}
+
+// New returns empty Locks.
+func New() *Locks {
+	return &Locks{
+		held:    map[Key]*hold{},
+		waiting: map[*Owner]Key{},
+	}
+}
+
+// NewOwner returns an Owner of locks, which is named after the controller it locks objects for
+// in the metrics.  An Owner is meant to lock objects for a single reconcile at a time.
+func (l *Locks) NewOwner(name string) *Owner {
+	return &Owner{locks: l, name: name}
+}
+
+// Owner locks objects for a reconcile.  It must not be used concurrently.
+type Owner struct {
+	locks *Locks
+	name  string
+	keys  []Key
+}
+
+// Lock locks the object with the given key for the Owner, waiting until it is released by
+// its holder.  Locking an object the Owner already holds does nothing.  It returns the error
+// of the context if it is done before, and ErrDeadlock if the holder of the lock waits for
+// one of the locks of the Owner.
+func (o *Owner) Lock(ctx context.Context, key Key) error {
+	l := o.locks
+	start := time.Now()
+	contended := false
+	for {
+		l.mu.Lock()
+		h, ok := l.held[key]
+		if !ok {
+			l.held[key] = &hold{owner: o, released: make(chan struct{})}
+			l.mu.Unlock()
+			o.keys = append(o.keys, key)
+			if contended {
+				lockWaitSeconds.WithLabelValues(o.name).Observe(time.Since(start).Seconds())
+			}
+			return nil
+		}
+		if h.owner == o {
+			l.mu.Unlock()
+			return nil
+		}
+		if l.waitsFor(h.owner, o) {
+			l.mu.Unlock()
+			lockDeadlocks.WithLabelValues(o.name).Inc()
+			return ErrDeadlock
+		}
+		if !contended {
+			contended = true
+			lockContentions.WithLabelValues(o.name).Inc()
+		}
+		l.waiting[o] = key
+		l.mu.Unlock()
+
+		select {
+		case <-h.released:
+		case <-ctx.Done():
+		}
+		l.mu.Lock()
+		delete(l.waiting, o)
+		l.mu.Unlock()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// waitsFor returns whether the owner waits, directly or through the holders of the locks it
+// waits for, for a lock held by target.  It must be called with the mutex held.
+func (l *Locks) waitsFor(owner, target *Owner) bool {
+	// Each Owner waits for a single lock at a time, so that the chain can't loop without
+	// reaching the target, other than if the Locks are already deadlocked.
+	for i := 0; i <= len(l.waiting); i++ {
+		if owner == target {
+			return true
+		}
+		key, ok := l.waiting[owner]
+		if !ok {
+			return false
+		}
+		h, ok := l.held[key]
+		if !ok {
+			return false
+		}
+		owner = h.owner
+	}
+	return false
+}
+
+// UnlockAll releases all the locks held by the Owner.
+func (o *Owner) UnlockAll() {
+	l := o.locks
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range o.keys {
+		if h, ok := l.held[key]; ok && h.owner == o {
+			delete(l.held, key)
+			close(h.released)
+		}
+	}
+	o.keys = nil
+}
+
+type ownerKey struct{}
+
+// WithOwner returns a copy of the context carrying the given Owner.
+func WithOwner(ctx context.Context, owner *Owner) context.Context {
+	return context.WithValue(ctx, ownerKey{}, owner)
+}
+
+// OwnerFrom returns the Owner of the context, and whether it was set.
+func OwnerFrom(ctx context.Context) (*Owner, bool) {
+	owner, ok := ctx.Value(ownerKey{}).(*Owner)
+	return owner, ok
+}
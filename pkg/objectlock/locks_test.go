@@ -0,0 +1,120 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectlock
+
+import (
+	"context"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Locks", func() {
+	ctx := context.Background()
+	secrets := schema.GroupKind{Kind: "Secret"}
+	keyIn := func(cluster, name string) Key {
+		return KeyFor(secrets, client.ObjectKey{
+			NamespacedName: types.NamespacedName{Namespace: "ns", Name: name},
+			Cluster:        logicalcluster.New(cluster),
+		})
+	}
+	var locks *Locks
+
+	BeforeEach(func() {
+		locks = New()
+	})
+
+	It("should serialize the owners locking the same object", func() {
+		a, b := locks.NewOwner("a"), locks.NewOwner("b")
+		Expect(a.Lock(ctx, keyIn("root:a", "foo"))).To(Succeed())
+		Expect(a.Lock(ctx, keyIn("root:a", "foo"))).To(Succeed())
+
+		By("not serializing the objects of other clusters or kinds")
+		Expect(b.Lock(ctx, keyIn("root:b", "foo"))).To(Succeed())
+		Expect(b.Lock(ctx, KeyFor(schema.GroupKind{Kind: "ConfigMap"}, keyIn("root:a", "foo").ObjectKey))).To(Succeed())
+
+		contentions := testutil.ToFloat64(lockContentions.WithLabelValues("b"))
+		locked := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			Expect(b.Lock(ctx, keyIn("root:a", "foo"))).To(Succeed())
+			close(locked)
+		}()
+		Eventually(func() float64 { return testutil.ToFloat64(lockContentions.WithLabelValues("b")) }).Should(Equal(contentions + 1))
+		Consistently(locked, 100*time.Millisecond).ShouldNot(BeClosed())
+
+		a.UnlockAll()
+		Eventually(locked).Should(BeClosed())
+		b.UnlockAll()
+		Expect(locks.held).To(BeEmpty())
+	})
+
+	It("should stop waiting once the context is done", func() {
+		a, b := locks.NewOwner("a"), locks.NewOwner("b")
+		Expect(a.Lock(ctx, keyIn("root:a", "foo"))).To(Succeed())
+
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		Expect(b.Lock(ctx, keyIn("root:a", "foo"))).To(MatchError(context.DeadlineExceeded))
+		Expect(locks.waiting).To(BeEmpty())
+	})
+
+	It("should refuse the locks which would deadlock", func() {
+		a, b, c := locks.NewOwner("a"), locks.NewOwner("b"), locks.NewOwner("c")
+		Expect(a.Lock(ctx, keyIn("root:a", "foo"))).To(Succeed())
+		Expect(b.Lock(ctx, keyIn("root:a", "bar"))).To(Succeed())
+		Expect(c.Lock(ctx, keyIn("root:a", "baz"))).To(Succeed())
+
+		bLocked := make(chan error)
+		go func() { bLocked <- b.Lock(ctx, keyIn("root:a", "baz")) }()
+		cLocked := make(chan error)
+		go func() { cLocked <- c.Lock(ctx, keyIn("root:a", "foo")) }()
+		Eventually(func() int {
+			locks.mu.Lock()
+			defer locks.mu.Unlock()
+			return len(locks.waiting)
+		}).Should(Equal(2))
+
+		By("refusing a lock closing the cycle a -> b -> c -> a")
+		deadlocks := testutil.ToFloat64(lockDeadlocks.WithLabelValues("a"))
+		Expect(a.Lock(ctx, keyIn("root:a", "bar"))).To(MatchError(ErrDeadlock))
+		Expect(testutil.ToFloat64(lockDeadlocks.WithLabelValues("a"))).To(Equal(deadlocks + 1))
+
+		a.UnlockAll()
+		Eventually(cLocked).Should(Receive(BeNil()))
+		c.UnlockAll()
+		Eventually(bLocked).Should(Receive(BeNil()))
+		b.UnlockAll()
+	})
+
+	It("should pass the owner in the context", func() {
+		_, ok := OwnerFrom(ctx)
+		Expect(ok).To(BeFalse())
+
+		owner := locks.NewOwner("a")
+		got, ok := OwnerFrom(WithOwner(ctx, owner))
+		Expect(ok).To(BeTrue())
+		Expect(got).To(BeIdenticalTo(owner))
+	})
+})
@@ -0,0 +1,48 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectlock
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// lockContentions counts the locks which were held by another controller when they
+	// were requested.
+	lockContentions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "controller_runtime_object_lock_contentions_total",
+		Help: "Total number of object locks which were held by another reconcile when requested per controller",
+	}, []string{"controller"})
+
+	// lockWaitSeconds is the time spent waiting for the contended locks.
+	lockWaitSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "controller_runtime_object_lock_wait_seconds",
+		Help:    "Length of time waiting for contended object locks per controller",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 16),
+	}, []string{"controller"})
+
+	// lockDeadlocks counts the locks which were refused as they would deadlock.
+	lockDeadlocks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "controller_runtime_object_lock_deadlocks_total",
+		Help: "Total number of object locks refused because they would deadlock per controller",
+	}, []string{"controller"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(lockContentions, lockWaitSeconds, lockDeadlocks)
+}
@@ -18,7 +18,9 @@ package predicate
 
 import (
 	"reflect"
+	"regexp"
 
+	"github.com/kcp-dev/logicalcluster"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -331,3 +333,136 @@ func LabelSelectorPredicate(s metav1.LabelSelector) (Predicate, error) {
 		return selector.Matches(labels.Set(o.GetLabels()))
 	}), nil
 }
+
+// InClusters returns a predicate that only admits the events of the given logical clusters.
+// Without clusters, the events of all the clusters are admitted.
+func InClusters(clusters ...logicalcluster.Name) Predicate {
+	if len(clusters) == 0 {
+		return Funcs{}
+	}
+	set := make(map[logicalcluster.Name]struct{}, len(clusters))
+	for _, cluster := range clusters {
+		set[cluster] = struct{}{}
+	}
+	return clusterPredicate(func(cluster logicalcluster.Name) bool {
+		_, ok := set[cluster]
+		return ok
+	})
+}
+
+// ClusterName returns a predicate that only admits the events of the logical clusters with
+// the given names, e.g. root:org:ws, so that a controller ignores the clusters it doesn't own.
+func ClusterName(names ...string) Predicate {
+	clusters := make([]logicalcluster.Name, 0, len(names))
+	for _, name := range names {
+		clusters = append(clusters, logicalcluster.New(name))
+	}
+	return InClusters(clusters...)
+}
+
+// ClusterNameRegexp returns a predicate that only admits the events of the logical clusters
+// whose name matches the regular expression, e.g. ^root:org:.
+func ClusterNameRegexp(expr string) (Predicate, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return Funcs{}, err
+	}
+	return clusterPredicate(func(cluster logicalcluster.Name) bool {
+		return re.MatchString(cluster.String())
+	}), nil
+}
+
+// clusterPredicate returns a predicate admitting the events whose logical cluster matches.
+// The cluster of an event defaults to the one of its object.
+func clusterPredicate(match func(logicalcluster.Name) bool) Funcs {
+	clusterOf := func(cluster logicalcluster.Name, obj client.Object) logicalcluster.Name {
+		if cluster.Empty() && obj != nil {
+			return logicalcluster.From(obj)
+		}
+		return cluster
+	}
+	return Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return match(clusterOf(e.Cluster, e.Object))
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return match(clusterOf(e.Cluster, e.Object))
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return match(clusterOf(e.Cluster, e.ObjectNew))
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return match(clusterOf(e.Cluster, e.Object))
+		},
+	}
+}
+
+// IgnoreUpdatesBy returns a predicate which drops the update events caused solely by the writes of the
+// given field manager, e.g. the one the client of a manager sets with manager.Options.FieldOwner, so that
+// a controller isn't triggered again by its own writes.  The writes are told apart by the entries of the
+// managedFields of the object which changed: the event is dropped if all of them are the field manager's.
+// The events of objects without managedFields, e.g. because the cache strips them, are always admitted,
+// as are the ones without any changed entry.
+func IgnoreUpdatesBy(fieldManager string) Predicate {
+	return Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			if e.ObjectOld == nil || e.ObjectNew == nil {
+				return true
+			}
+			changed := changedManagers(e.ObjectOld.GetManagedFields(), e.ObjectNew.GetManagedFields())
+			if len(changed) == 0 {
+				return true
+			}
+			for _, manager := range changed {
+				if manager != fieldManager {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// changedManagers returns the managers of the managedFields entries which were added or changed.
+func changedManagers(old, new []metav1.ManagedFieldsEntry) []string {
+	type entryKey struct{ manager, operation, subresource string }
+	previous := make(map[entryKey]metav1.ManagedFieldsEntry, len(old))
+	for _, entry := range old {
+		previous[entryKey{entry.Manager, string(entry.Operation), entry.Subresource}] = entry
+	}
+	var managers []string
+	for _, entry := range new {
+		prev, ok := previous[entryKey{entry.Manager, string(entry.Operation), entry.Subresource}]
+		if ok && reflect.DeepEqual(prev, entry) {
+			continue
+		}
+		managers = append(managers, entry.Manager)
+	}
+	return managers
+}
+
+// MetadataFilter filters the events of a source.Kind by the metadata of their object.  Unlike
+// predicates, filters run in the event handler of the informer, before the events are built,
+// so that the objects of high-churn kinds the controller doesn't care about cost as little
+// as possible.  The object is the one held by the cache: filters must not modify it.
+// Update events are admitted if either the old or the new object is admitted.
+type MetadataFilter func(obj metav1.Object) bool
+
+// LabelSelectorFilter returns a MetadataFilter admitting the objects matching the selector.
+func LabelSelectorFilter(selector labels.Selector) MetadataFilter {
+	return func(obj metav1.Object) bool {
+		return selector.Matches(labels.Set(obj.GetLabels()))
+	}
+}
+
+// NamespaceFilter returns a MetadataFilter admitting the objects of the given namespaces.
+func NamespaceFilter(namespaces ...string) MetadataFilter {
+	admitted := make(map[string]struct{}, len(namespaces))
+	for _, ns := range namespaces {
+		admitted[ns] = struct{}{}
+	}
+	return func(obj metav1.Object) bool {
+		_, ok := admitted[obj.GetNamespace()]
+		return ok
+	}
+}
@@ -17,10 +17,12 @@ limitations under the License.
 package predicate_test
 
 import (
+	"github.com/kcp-dev/logicalcluster"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
@@ -941,4 +943,114 @@ var _ = Describe("Predicate", func() {
 			})
 		})
 	})
+
+	Describe("When checking an InClusters predicate", func() {
+		instance := predicate.InClusters(logicalcluster.New("root:a"), logicalcluster.New("root:b"))
+
+		It("should return false for the objects of other clusters", func() {
+			other := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "baz", ClusterName: "root:c"}}
+			Expect(instance.Create(event.CreateEvent{Object: other})).To(BeFalse())
+			Expect(instance.Delete(event.DeleteEvent{Object: other})).To(BeFalse())
+			Expect(instance.Generic(event.GenericEvent{Object: other})).To(BeFalse())
+			Expect(instance.Update(event.UpdateEvent{ObjectOld: other, ObjectNew: other})).To(BeFalse())
+		})
+
+		It("should return true for the objects of the given clusters", func() {
+			match := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "baz", ClusterName: "root:b"}}
+			Expect(instance.Create(event.CreateEvent{Object: match})).To(BeTrue())
+			Expect(instance.Delete(event.DeleteEvent{Object: match})).To(BeTrue())
+			Expect(instance.Generic(event.GenericEvent{Object: match})).To(BeTrue())
+			Expect(instance.Update(event.UpdateEvent{ObjectOld: match, ObjectNew: match})).To(BeTrue())
+		})
+
+		It("should return true for all the objects without clusters", func() {
+			other := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "baz", ClusterName: "root:c"}}
+			Expect(predicate.InClusters().Create(event.CreateEvent{Object: other})).To(BeTrue())
+		})
+	})
+
+	Describe("When checking a ClusterName predicate", func() {
+		instance := predicate.ClusterName("root:a")
+
+		It("should use the logical cluster of the event", func() {
+			obj := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "baz", ClusterName: "root:b"}}
+			Expect(instance.Create(event.CreateEvent{Object: obj, Cluster: logicalcluster.New("root:a")})).To(BeTrue())
+			Expect(instance.Delete(event.DeleteEvent{Object: obj, Cluster: logicalcluster.New("root:b")})).To(BeFalse())
+		})
+
+		It("should default to the logical cluster of the object", func() {
+			obj := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "baz", ClusterName: "root:a"}}
+			Expect(instance.Generic(event.GenericEvent{Object: obj})).To(BeTrue())
+			Expect(instance.Update(event.UpdateEvent{ObjectOld: obj, ObjectNew: obj})).To(BeTrue())
+		})
+	})
+
+	Describe("When checking a ClusterNameRegexp predicate", func() {
+		It("should admit the events of the matching logical clusters", func() {
+			instance, err := predicate.ClusterNameRegexp("^root:org:")
+			Expect(err).NotTo(HaveOccurred())
+
+			match := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "baz", ClusterName: "root:org:ws"}}
+			other := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "baz", ClusterName: "root:other:ws"}}
+			Expect(instance.Create(event.CreateEvent{Object: match})).To(BeTrue())
+			Expect(instance.Create(event.CreateEvent{Object: other})).To(BeFalse())
+			Expect(instance.Update(event.UpdateEvent{ObjectOld: other, ObjectNew: match})).To(BeTrue())
+			Expect(instance.Delete(event.DeleteEvent{Object: other})).To(BeFalse())
+			Expect(instance.Generic(event.GenericEvent{Object: other, Cluster: logicalcluster.New("root:org:a")})).To(BeTrue())
+		})
+
+		It("should return an error for an invalid regular expression", func() {
+			_, err := predicate.ClusterNameRegexp("(")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Describe("When checking an IgnoreUpdatesBy predicate", func() {
+		instance := predicate.IgnoreUpdatesBy("my-controller")
+		entry := func(manager string, seconds int64) metav1.ManagedFieldsEntry {
+			t := metav1.Unix(seconds, 0)
+			return metav1.ManagedFieldsEntry{Manager: manager, Operation: metav1.ManagedFieldsOperationUpdate, Time: &t}
+		}
+		withManagedFields := func(entries ...metav1.ManagedFieldsEntry) *corev1.Pod {
+			return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "baz", ManagedFields: entries}}
+		}
+
+		It("should drop the updates caused solely by the field manager", func() {
+			old := withManagedFields(entry("kubectl", 1), entry("my-controller", 1))
+			Expect(instance.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: withManagedFields(entry("kubectl", 1), entry("my-controller", 2))})).To(BeFalse())
+			Expect(instance.Update(event.UpdateEvent{ObjectOld: withManagedFields(entry("kubectl", 1)), ObjectNew: old})).To(BeFalse())
+		})
+
+		It("should admit the updates caused by other field managers", func() {
+			old := withManagedFields(entry("kubectl", 1), entry("my-controller", 1))
+			Expect(instance.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: withManagedFields(entry("kubectl", 2), entry("my-controller", 1))})).To(BeTrue())
+			Expect(instance.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: withManagedFields(entry("kubectl", 2), entry("my-controller", 2))})).To(BeTrue())
+		})
+
+		It("should admit the updates without changed managedFields", func() {
+			Expect(instance.Update(event.UpdateEvent{ObjectOld: withManagedFields(), ObjectNew: withManagedFields()})).To(BeTrue())
+			old := withManagedFields(entry("my-controller", 1))
+			Expect(instance.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: old})).To(BeTrue())
+		})
+
+		It("should admit the other events", func() {
+			Expect(instance.Create(event.CreateEvent{Object: pod})).To(BeTrue())
+			Expect(instance.Delete(event.DeleteEvent{Object: pod})).To(BeTrue())
+			Expect(instance.Generic(event.GenericEvent{Object: pod})).To(BeTrue())
+		})
+	})
+
+	Describe("When checking metadata filters", func() {
+		It("should admit the objects matching the label selector", func() {
+			filter := predicate.LabelSelectorFilter(labels.SelectorFromSet(labels.Set{"app": "foo"}))
+			Expect(filter(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "foo"}}})).To(BeTrue())
+			Expect(filter(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "bar"}}})).To(BeFalse())
+			Expect(filter(&corev1.Pod{})).To(BeFalse())
+		})
+
+		It("should admit the objects of the given namespaces", func() {
+			filter := predicate.NamespaceFilter("biz", "buz")
+			Expect(filter(pod)).To(BeTrue())
+			Expect(filter(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "other"}})).To(BeFalse())
+		})
+	})
 })
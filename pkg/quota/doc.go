@@ -0,0 +1,37 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package quota helps multi-tenant reconcilers enforce quotas on the number of objects of a kind
+per logical cluster, or per namespace of a logical cluster.
+
+A Tracker counts the objects from a reader, typically the cache of the manager whose indexes
+make the counts cheap, and records the objects about to be created as reservations in an
+accounting ConfigMap per scope.  The reservations are written with optimistic concurrency, so
+that the reconcilers of several controllers, or of several replicas, reserving concurrently
+never exceed the quota:
+
+	if err := tracker.Reserve(ctx, quota.Scope{Cluster: cluster, Namespace: ns}, key); err != nil {
+		return reconcile.Result{}, err
+	}
+	if err := c.Create(ctx, obj); err != nil {
+		_ = tracker.Release(ctx, quota.Scope{Cluster: cluster, Namespace: ns}, key)
+		return reconcile.Result{}, err
+	}
+
+A reservation is dropped once the object is counted by the reader, or once it expires.
+*/
+package quota
@@ -0,0 +1,287 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultReservationTTL is the default time after which the reservations expire.
+const DefaultReservationTTL = 5 * time.Minute
+
+// Unlimited is the limit of the scopes without a quota.
+const Unlimited = -1
+
+// Scope is what a quota applies to: a logical cluster, or a namespace of a logical cluster.
+type Scope struct {
+	Cluster logicalcluster.Name
+
+	// Namespace is the namespace the quota applies to, or empty if it applies to all the
+	// objects of the cluster.
+	Namespace string
+}
+
+// String returns the scope as <cluster>, or <cluster>/<namespace>.
+func (s Scope) String() string {
+	if s.Namespace == "" {
+		return s.Cluster.String()
+	}
+	return s.Cluster.String() + "/" + s.Namespace
+}
+
+// ErrExceeded is returned when reserving an object in a scope whose quota is used up.
+type ErrExceeded struct {
+	Scope Scope
+	Used  int
+	Limit int
+}
+
+func (e *ErrExceeded) Error() string {
+	return fmt.Sprintf("quota of %s exceeded: %d objects used out of %d", e.Scope, e.Used, e.Limit)
+}
+
+// IsExceeded returns whether the error is an ErrExceeded.
+func IsExceeded(err error) bool {
+	var exceeded *ErrExceeded
+	return errors.As(err, &exceeded)
+}
+
+// Tracker counts the objects of a kind per Scope, and enforces the quotas of the scopes with
+// reservations recorded in an accounting ConfigMap per scope: the ConfigMap named Name in the
+// namespace of the scope, or in AccountingNamespace for the scopes of whole clusters.
+type Tracker struct {
+	// Reader lists the objects counted, typically the cache of the manager.
+	Reader client.Reader
+
+	// Client reads and writes the accounting ConfigMaps.  The writes are conditional on the
+	// resource version of the ConfigMaps, so that the concurrent reservations don't exceed
+	// the quotas.
+	Client client.Client
+
+	// NewList returns an empty list of the objects counted, e.g. a
+	// metav1.PartialObjectMetadataList with its GroupVersionKind set.
+	NewList func() client.ObjectList
+
+	// Limit returns the maximum number of objects of the scope, or Unlimited.
+	Limit func(scope Scope) int
+
+	// Name is the name of the accounting ConfigMaps.
+	Name string
+
+	// AccountingNamespace is the namespace of the accounting ConfigMaps of the scopes of whole
+	// clusters.  Defaults to "default".
+	AccountingNamespace string
+
+	// ReservationTTL is the time after which the reservations of the objects which are not
+	// counted by Reader expire, e.g. because the reconciler reserving them crashed before
+	// creating them or releasing them.  Defaults to DefaultReservationTTL.
+	ReservationTTL time.Duration
+
+	// now is overridden in tests.
+	now func() time.Time
+}
+
+// Count returns the number of objects of the scope counted by Reader.
+func (t *Tracker) Count(ctx context.Context, scope Scope) (int, error) {
+	keys, err := t.list(ctx, scope)
+	if err != nil {
+		return 0, err
+	}
+	return len(keys), nil
+}
+
+// Usage returns the number of objects of the scope, along with the unexpired reservations of
+// the objects not counted by Reader yet, and the limit of the scope.
+func (t *Tracker) Usage(ctx context.Context, scope Scope) (used, limit int, err error) {
+	keys, err := t.list(ctx, scope)
+	if err != nil {
+		return 0, 0, err
+	}
+	cm, err := t.getAccounting(ctx, scope)
+	if err != nil {
+		return 0, 0, err
+	}
+	t.prune(cm, keys)
+	return len(keys) + len(cm.Data), t.Limit(scope), nil
+}
+
+// Reserve reserves the object with the given key in the quota of the scope, or returns an
+// ErrExceeded if the quota is used up.  Reserving an object which is counted or reserved
+// already succeeds without using the quota up further.
+func (t *Tracker) Reserve(ctx context.Context, scope Scope, key types.NamespacedName) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		keys, err := t.list(ctx, scope)
+		if err != nil {
+			return err
+		}
+		cm, err := t.getAccounting(ctx, scope)
+		if err != nil {
+			return err
+		}
+		pruned := t.prune(cm, keys)
+		reservation := reservationKey(key)
+		if _, counted := keys[reservation]; counted {
+			return t.writeAccounting(ctx, cm, pruned)
+		}
+		if _, reserved := cm.Data[reservation]; reserved {
+			return t.writeAccounting(ctx, cm, pruned)
+		}
+
+		used, limit := len(keys)+len(cm.Data), t.Limit(scope)
+		if limit != Unlimited && used >= limit {
+			return &ErrExceeded{Scope: scope, Used: used, Limit: limit}
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[reservation] = t.clock().UTC().Format(time.RFC3339)
+		return t.writeAccounting(ctx, cm, true)
+	})
+}
+
+// Release releases the reservation of the object with the given key, e.g. because creating
+// it failed.
+func (t *Tracker) Release(ctx context.Context, scope Scope, key types.NamespacedName) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := t.getAccounting(ctx, scope)
+		if err != nil {
+			return err
+		}
+		reservation := reservationKey(key)
+		if _, reserved := cm.Data[reservation]; !reserved {
+			return nil
+		}
+		delete(cm.Data, reservation)
+		return t.writeAccounting(ctx, cm, true)
+	})
+}
+
+// list returns the reservation keys of the objects of the scope counted by Reader.
+func (t *Tracker) list(ctx context.Context, scope Scope) (map[string]struct{}, error) {
+	list := t.NewList()
+	var opts []client.ListOption
+	if scope.Namespace != "" {
+		opts = append(opts, client.InNamespace(scope.Namespace))
+	}
+	if err := t.Reader.List(kcpclient.WithCluster(ctx, scope.Cluster), list, opts...); err != nil {
+		return nil, err
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]struct{}, len(items))
+	for _, item := range items {
+		obj, err := meta.Accessor(item)
+		if err != nil {
+			return nil, err
+		}
+		keys[reservationKey(types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()})] = struct{}{}
+	}
+	return keys, nil
+}
+
+// getAccounting returns the accounting ConfigMap of the scope, or a new one if it doesn't exist.
+func (t *Tracker) getAccounting(ctx context.Context, scope Scope) (*corev1.ConfigMap, error) {
+	namespace := scope.Namespace
+	if namespace == "" {
+		namespace = t.AccountingNamespace
+		if namespace == "" {
+			namespace = "default"
+		}
+	}
+	cm := &corev1.ConfigMap{}
+	key := client.ObjectKey{Cluster: scope.Cluster}
+	key.Namespace, key.Name = namespace, t.Name
+	err := t.Client.Get(kcpclient.WithCluster(ctx, scope.Cluster), key, cm)
+	if apierrors.IsNotFound(err) {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   namespace,
+			Name:        t.Name,
+			ClusterName: scope.Cluster.String(),
+		}}, nil
+	}
+	return cm, err
+}
+
+// prune drops the reservations of the accounting ConfigMap of the objects counted, and the
+// expired ones.  It returns whether it dropped any.
+func (t *Tracker) prune(cm *corev1.ConfigMap, keys map[string]struct{}) bool {
+	ttl := t.ReservationTTL
+	if ttl == 0 {
+		ttl = DefaultReservationTTL
+	}
+	pruned := false
+	for reservation, reservedAt := range cm.Data {
+		at, err := time.Parse(time.RFC3339, reservedAt)
+		_, counted := keys[reservation]
+		if counted || err != nil || t.clock().Sub(at) > ttl {
+			delete(cm.Data, reservation)
+			pruned = true
+		}
+	}
+	return pruned
+}
+
+// writeAccounting creates or updates the accounting ConfigMap if it changed.  The creations of
+// a ConfigMap created concurrently fail with a conflict, to be retried.
+func (t *Tracker) writeAccounting(ctx context.Context, cm *corev1.ConfigMap, changed bool) error {
+	if !changed {
+		return nil
+	}
+	ctx = kcpclient.WithCluster(ctx, logicalcluster.From(cm))
+	if cm.ResourceVersion != "" {
+		return t.Client.Update(ctx, cm)
+	}
+	err := t.Client.Create(ctx, cm)
+	if apierrors.IsAlreadyExists(err) {
+		return apierrors.NewConflict(schema.GroupResource{Resource: "configmaps"}, cm.Name, err)
+	}
+	return err
+}
+
+func (t *Tracker) clock() time.Time {
+	if t.now != nil {
+		return t.now()
+	}
+	return time.Now()
+}
+
+// reservationKey returns the key of the reservation of an object in the data of an accounting
+// ConfigMap: <namespace>.<name>, or <name> for cluster-scoped objects.  Namespaces can't contain
+// dots, so the keys don't collide.
+func reservationKey(key types.NamespacedName) string {
+	if key.Namespace == "" {
+		return key.Name
+	}
+	return key.Namespace + "." + key.Name
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Tracker", func() {
+	var (
+		ctx     context.Context
+		c       client.Client
+		tracker *Tracker
+		now     time.Time
+		a, b    logicalcluster.Name
+	)
+
+	secret := func(name string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"}}
+	}
+	key := func(name string) types.NamespacedName {
+		return types.NamespacedName{Namespace: "ns", Name: name}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		a, b = logicalcluster.New("root:a"), logicalcluster.New("root:b")
+		c = fake.NewClusterBuilder().
+			WithObjects(a, secret("existing")).
+			Build()
+		now = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+		tracker = &Tracker{
+			Reader:  c,
+			Client:  c,
+			NewList: func() client.ObjectList { return &corev1.SecretList{} },
+			Limit: func(scope Scope) int {
+				if scope.Cluster == b {
+					return Unlimited
+				}
+				return 3
+			},
+			Name: "secrets-quota",
+			now:  func() time.Time { return now },
+		}
+	})
+
+	It("should reserve objects until the quota of the scope is used up", func() {
+		scope := Scope{Cluster: a, Namespace: "ns"}
+		Expect(tracker.Count(ctx, scope)).To(Equal(1))
+		Expect(tracker.Reserve(ctx, scope, key("one"))).To(Succeed())
+		Expect(tracker.Reserve(ctx, scope, key("two"))).To(Succeed())
+
+		err := tracker.Reserve(ctx, scope, key("three"))
+		Expect(IsExceeded(err)).To(BeTrue())
+		Expect(err).To(MatchError("quota of root:a/ns exceeded: 3 objects used out of 3"))
+
+		By("not using the quota up further for the objects counted or reserved already")
+		Expect(tracker.Reserve(ctx, scope, key("existing"))).To(Succeed())
+		Expect(tracker.Reserve(ctx, scope, key("one"))).To(Succeed())
+		used, limit, err := tracker.Usage(ctx, scope)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(used).To(Equal(3))
+		Expect(limit).To(Equal(3))
+
+		By("recording the reservations in the accounting ConfigMap of the scope")
+		cm := &corev1.ConfigMap{}
+		Expect(c.Get(kcpclient.WithCluster(ctx, a), client.ObjectKey{NamespacedName: key("secrets-quota")}, cm)).To(Succeed())
+		Expect(cm.Data).To(HaveKey("ns.one"))
+		Expect(cm.Data).To(HaveKey("ns.two"))
+	})
+
+	It("should release the reservations, and drop the ones of the objects counted and the expired ones", func() {
+		scope := Scope{Cluster: a, Namespace: "ns"}
+		Expect(tracker.Reserve(ctx, scope, key("one"))).To(Succeed())
+		Expect(tracker.Reserve(ctx, scope, key("two"))).To(Succeed())
+
+		By("releasing a reservation")
+		Expect(tracker.Release(ctx, scope, key("two"))).To(Succeed())
+		Expect(tracker.Reserve(ctx, scope, key("three"))).To(Succeed())
+
+		By("dropping the reservation of an object once it is counted")
+		Expect(c.Create(kcpclient.WithCluster(ctx, a), secret("one"))).To(Succeed())
+		used, _, err := tracker.Usage(ctx, scope)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(used).To(Equal(3))
+
+		By("dropping the expired reservations")
+		now = now.Add(DefaultReservationTTL + time.Second)
+		used, _, err = tracker.Usage(ctx, scope)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(used).To(Equal(2))
+		Expect(tracker.Reserve(ctx, scope, key("four"))).To(Succeed())
+	})
+
+	It("should keep the quotas of the clusters apart, and count the objects of whole clusters", func() {
+		Expect(tracker.Count(ctx, Scope{Cluster: b})).To(Equal(0))
+		for _, name := range []string{"one", "two", "three", "four"} {
+			Expect(tracker.Reserve(ctx, Scope{Cluster: b}, key(name))).To(Succeed())
+		}
+
+		cm := &corev1.ConfigMap{}
+		cmKey := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "default", Name: "secrets-quota"}}
+		Expect(c.Get(kcpclient.WithCluster(ctx, b), cmKey, cm)).To(Succeed())
+		Expect(cm.Data).To(HaveLen(4))
+		Expect(c.Get(kcpclient.WithCluster(ctx, a), cmKey, &corev1.ConfigMap{})).NotTo(Succeed())
+	})
+
+	It("should not exceed the quota when reserving concurrently", func() {
+		scope := Scope{Cluster: a, Namespace: "ns"}
+		var wg sync.WaitGroup
+		errs := make(chan error, 4)
+		for _, name := range []string{"one", "two", "three", "four"} {
+			wg.Add(1)
+			go func(name string) {
+				defer wg.Done()
+				errs <- tracker.Reserve(ctx, scope, key(name))
+			}(name)
+		}
+		wg.Wait()
+		close(errs)
+
+		exceeded := 0
+		for err := range errs {
+			if err != nil {
+				Expect(IsExceeded(err)).To(BeTrue(), err.Error())
+				exceeded++
+			}
+		}
+		Expect(exceeded).To(Equal(2))
+		used, _, err := tracker.Usage(ctx, scope)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(used).To(Equal(3))
+	})
+})
@@ -0,0 +1,115 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ratelimiter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// NewPerCluster returns a RateLimiter delegating to a separate RateLimiter per logical cluster,
+// built by newRateLimiter when the first request of the cluster is rate limited.  This keeps the
+// failures of a noisy cluster from exhausting the overall rate limit of the others, e.g. the
+// token bucket of workqueue.DefaultControllerRateLimiter.  The items other than reconcile.Requests
+// share the RateLimiter of the empty cluster.
+func NewPerCluster(newRateLimiter func() RateLimiter) RateLimiter {
+	return &perCluster{
+		newRateLimiter: newRateLimiter,
+		rateLimiters:   map[logicalcluster.Name]RateLimiter{},
+	}
+}
+
+type perCluster struct {
+	newRateLimiter func() RateLimiter
+
+	mu           sync.Mutex
+	rateLimiters map[logicalcluster.Name]RateLimiter
+}
+
+// rateLimiterFor returns the RateLimiter of the cluster of item, building it if create is set.
+func (r *perCluster) rateLimiterFor(item interface{}, create bool) RateLimiter {
+	var cluster logicalcluster.Name
+	if req, ok := item.(reconcile.Request); ok {
+		cluster = req.Cluster
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rateLimiter, ok := r.rateLimiters[cluster]
+	if !ok && create {
+		rateLimiter = r.newRateLimiter()
+		r.rateLimiters[cluster] = rateLimiter
+	}
+	return rateLimiter
+}
+
+// When implements RateLimiter.
+func (r *perCluster) When(item interface{}) time.Duration {
+	return r.rateLimiterFor(item, true).When(item)
+}
+
+// Forget implements RateLimiter.
+func (r *perCluster) Forget(item interface{}) {
+	if rateLimiter := r.rateLimiterFor(item, false); rateLimiter != nil {
+		rateLimiter.Forget(item)
+	}
+}
+
+// NumRequeues implements RateLimiter.
+func (r *perCluster) NumRequeues(item interface{}) int {
+	if rateLimiter := r.rateLimiterFor(item, false); rateLimiter != nil {
+		return rateLimiter.NumRequeues(item)
+	}
+	return 0
+}
+
+// NewByCluster returns a RateLimiter delegating the requests of the logical clusters in
+// rateLimiters to their RateLimiter, and all the other items to fallback.
+func NewByCluster(rateLimiters map[logicalcluster.Name]RateLimiter, fallback RateLimiter) RateLimiter {
+	return &byCluster{rateLimiters: rateLimiters, fallback: fallback}
+}
+
+type byCluster struct {
+	rateLimiters map[logicalcluster.Name]RateLimiter
+	fallback     RateLimiter
+}
+
+func (r *byCluster) rateLimiterFor(item interface{}) RateLimiter {
+	if req, ok := item.(reconcile.Request); ok {
+		if rateLimiter, ok := r.rateLimiters[req.Cluster]; ok {
+			return rateLimiter
+		}
+	}
+	return r.fallback
+}
+
+// When implements RateLimiter.
+func (r *byCluster) When(item interface{}) time.Duration {
+	return r.rateLimiterFor(item).When(item)
+}
+
+// Forget implements RateLimiter.
+func (r *byCluster) Forget(item interface{}) {
+	r.rateLimiterFor(item).Forget(item)
+}
+
+// NumRequeues implements RateLimiter.
+func (r *byCluster) NumRequeues(item interface{}) int {
+	return r.rateLimiterFor(item).NumRequeues(item)
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ratelimiter
+
+import (
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("NewPerCluster", func() {
+	requestIn := func(cluster, name string) reconcile.Request {
+		return reconcile.Request{ObjectKey: client.ObjectKey{
+			NamespacedName: types.NamespacedName{Name: name},
+			Cluster:        logicalcluster.New(cluster),
+		}}
+	}
+
+	It("should rate limit the requests of each logical cluster separately", func() {
+		built := 0
+		r := NewPerCluster(func() RateLimiter {
+			built++
+			// a bucket of a single token refilled every hour, so that the second
+			// request of a cluster waits.
+			return workqueue.NewMaxOfRateLimiter(
+				workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, time.Second),
+				&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Every(time.Hour), 1)},
+			)
+		})
+
+		Expect(r.When(requestIn("root:noisy", "a"))).To(BeNumerically("<", time.Second))
+		Expect(r.When(requestIn("root:noisy", "b"))).To(BeNumerically(">", time.Minute))
+		Expect(r.When(requestIn("root:quiet", "a"))).To(BeNumerically("<", time.Second))
+		Expect(built).To(Equal(2))
+
+		Expect(r.NumRequeues(requestIn("root:noisy", "a"))).To(Equal(1))
+		Expect(r.NumRequeues(requestIn("root:other", "a"))).To(BeZero())
+		r.Forget(requestIn("root:noisy", "a"))
+		r.Forget(requestIn("root:other", "a"))
+		Expect(r.NumRequeues(requestIn("root:noisy", "a"))).To(BeZero())
+		Expect(built).To(Equal(2))
+	})
+})
+
+var _ = Describe("NewByCluster", func() {
+	requestIn := func(cluster, name string) reconcile.Request {
+		return reconcile.Request{ObjectKey: client.ObjectKey{
+			NamespacedName: types.NamespacedName{Name: name},
+			Cluster:        logicalcluster.New(cluster),
+		}}
+	}
+
+	It("should rate limit the requests of the given logical clusters with their own rate limiter", func() {
+		slow := workqueue.NewItemExponentialFailureRateLimiter(time.Minute, time.Hour)
+		fallback := workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, time.Second)
+		r := NewByCluster(map[logicalcluster.Name]RateLimiter{logicalcluster.New("root:noisy"): slow}, fallback)
+
+		Expect(r.When(requestIn("root:noisy", "a"))).To(Equal(time.Minute))
+		Expect(r.When(requestIn("root:quiet", "a"))).To(Equal(time.Millisecond))
+		Expect(r.When("not a request")).To(Equal(time.Millisecond))
+
+		Expect(slow.NumRequeues(requestIn("root:noisy", "a"))).To(Equal(1))
+		Expect(r.NumRequeues(requestIn("root:quiet", "a"))).To(Equal(1))
+		r.Forget(requestIn("root:noisy", "a"))
+		Expect(slow.NumRequeues(requestIn("root:noisy", "a"))).To(BeZero())
+		Expect(fallback.NumRequeues(requestIn("root:quiet", "a"))).To(Equal(1))
+	})
+})
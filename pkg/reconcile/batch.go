@@ -0,0 +1,40 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import "context"
+
+// BatchReconciler reconciles the Requests of a controller in batches rather than one at a
+// time, e.g. to compute the state of a whole fleet of logical clusters, like placement
+// decisions, in a single pass instead of in separate reconciles racing each other.
+type BatchReconciler interface {
+	// ReconcileBatch performs a full reconciliation for the objects referred to by the
+	// Requests, which may belong to different logical clusters.  The Result and the error
+	// apply to all the Requests: the Controller requeues them all if the error is non-nil
+	// or the Result asks for it.
+	ReconcileBatch(ctx context.Context, reqs []Request) (Result, error)
+}
+
+// BatchFunc is a function that implements the BatchReconciler interface.
+type BatchFunc func(context.Context, []Request) (Result, error)
+
+var _ BatchReconciler = BatchFunc(nil)
+
+// ReconcileBatch implements BatchReconciler.
+func (r BatchFunc) ReconcileBatch(ctx context.Context, reqs []Request) (Result, error) {
+	return r(ctx, reqs)
+}
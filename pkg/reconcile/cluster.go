@@ -0,0 +1,77 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"context"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ClusterAwareReconciler is a Reconciler passed the logical cluster of each Request
+// explicitly, rather than reading it from the Request or from the context.
+type ClusterAwareReconciler interface {
+	// Reconcile performs a full reconciliation for the object of the logical cluster referred
+	// to by the Request.
+	Reconcile(ctx context.Context, cluster logicalcluster.Name, req Request) (Result, error)
+}
+
+// ClusterAwareFunc is a function that implements the ClusterAwareReconciler interface.
+type ClusterAwareFunc func(context.Context, logicalcluster.Name, Request) (Result, error)
+
+var _ ClusterAwareReconciler = ClusterAwareFunc(nil)
+
+// Reconcile implements ClusterAwareReconciler.
+func (r ClusterAwareFunc) Reconcile(ctx context.Context, cluster logicalcluster.Name, req Request) (Result, error) {
+	return r(ctx, cluster, req)
+}
+
+// ClusterAdapter returns a Reconciler running the ClusterAwareReconciler in the logical
+// cluster of each Request, or of the context if the Request has none.  The context passed
+// to the ClusterAwareReconciler targets the cluster, and carries the given client pinned to
+// it, see ClientFrom, so that a reconciler written against a single cluster reads and writes
+// the objects of the cluster of the Request with the client of the context.
+func ClusterAdapter(c client.Client, r ClusterAwareReconciler) Reconciler {
+	return Func(func(ctx context.Context, req Request) (Result, error) {
+		cluster := req.Cluster
+		if cluster.Empty() {
+			cluster, _ = kcpclient.ClusterFromContext(ctx)
+		}
+		if cluster.Empty() {
+			return r.Reconcile(WithClient(ctx, c), cluster, req)
+		}
+		ctx = kcpclient.WithCluster(ctx, cluster)
+		return r.Reconcile(WithClient(ctx, client.WithCluster(c, cluster)), cluster, req)
+	})
+}
+
+type clientKey struct{}
+
+// WithClient returns a copy of the context carrying the given client.
+func WithClient(ctx context.Context, c client.Client) context.Context {
+	return context.WithValue(ctx, clientKey{}, c)
+}
+
+// ClientFrom returns the client of the context, and whether it was set.  The client passed
+// by ClusterAdapter is pinned to the logical cluster of the Request.
+func ClientFrom(ctx context.Context) (client.Client, bool) {
+	c, ok := ctx.Value(clientKey{}).(client.Client)
+	return c, ok
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"context"
+	"sync"
+)
+
+// Config holds the configuration of a controller, e.g. a struct of tunables like per-cluster
+// quotas, which the controller passes to its Reconciler in the context of each request, see
+// ConfigFrom.  It is safe for concurrent use, so that the configuration can be replaced while
+// the controller is running, e.g. when it is reloaded.  Each reconciliation sees the value the
+// Config held when it started, so the Reconciler should not modify it.
+type Config struct {
+	mu    sync.RWMutex
+	value interface{}
+}
+
+// NewConfig returns a Config holding the given value.
+func NewConfig(value interface{}) *Config {
+	return &Config{value: value}
+}
+
+// Get returns the current value of the Config.
+func (c *Config) Get() interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.value
+}
+
+// Set replaces the value of the Config.  The reconciliations started afterwards see the
+// new value.
+func (c *Config) Set(value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.value = value
+}
+
+type configKey struct{}
+
+// WithConfig returns a copy of the context carrying the given configuration value.
+func WithConfig(ctx context.Context, value interface{}) context.Context {
+	return context.WithValue(ctx, configKey{}, value)
+}
+
+// ConfigFrom returns the configuration value of the context, and whether it was set.  The
+// Reconciler asserts its type, e.g.:
+//
+//	cfg, ok := reconcile.ConfigFrom(ctx)
+//	quotas := cfg.(*QuotaConfig)
+func ConfigFrom(ctx context.Context) (interface{}, bool) {
+	value := ctx.Value(configKey{})
+	return value, value != nil
+}
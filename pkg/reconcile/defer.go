@@ -0,0 +1,141 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Deferral is a step of a reconciliation deferred to a follow-up reconcile, see Defer.
+type Deferral struct {
+	// After is how long to wait before the follow-up reconcile.  If it is not greater
+	// than 0, the Request is requeued with rate limiting.
+	After time.Duration
+
+	// Reason describes why the step was deferred, e.g. "WaitingForAPIBinding".  It is
+	// used as a metric label, so it should be one of a small set of constant strings.
+	Reason string
+}
+
+// result returns the Result requeueing the Request for the Deferral.
+func (d Deferral) result() Result {
+	if d.After > 0 {
+		return Result{RequeueAfter: d.After}
+	}
+	return Result{Requeue: true}
+}
+
+// Deferrals records the steps deferred by a reconciliation.  It is safe for concurrent use.
+type Deferrals struct {
+	mu    sync.Mutex
+	steps []Deferral
+}
+
+// Add records a deferred step.
+func (d *Deferrals) Add(after time.Duration, reason string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.steps = append(d.steps, Deferral{After: after, Reason: reason})
+}
+
+// List returns the deferred steps in the order they were recorded.
+func (d *Deferrals) List() []Deferral {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Deferral(nil), d.steps...)
+}
+
+// Result aggregates the deferred steps into a Result requeueing the Request as soon as
+// the earliest of them is due.  It returns an empty Result if no step was deferred.
+func (d *Deferrals) Result() Result {
+	return d.Merge(Result{})
+}
+
+// Merge returns the earliest of the given Result and the Result aggregating the deferred
+// steps.  A Result requeueing with rate limiting is considered earlier than any
+// RequeueAfter.
+func (d *Deferrals) Merge(result Result) Result {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, step := range d.steps {
+		result = earliest(result, step.result())
+	}
+	return result
+}
+
+// earliest returns whichever of the Results requeues the Request first.
+func earliest(a, b Result) Result {
+	switch {
+	case a.IsZero():
+		return b
+	case b.IsZero():
+		return a
+	case a.RequeueAfter <= 0:
+		return a
+	case b.RequeueAfter <= 0:
+		return b
+	case b.RequeueAfter < a.RequeueAfter:
+		return b
+	default:
+		return a
+	}
+}
+
+type deferralsKey struct{}
+
+// WithDeferrals returns a copy of the context recording the steps deferred with Defer
+// in the returned Deferrals.  Controllers call it for each reconciliation, merge the
+// deferred steps into the Result returned by the Reconciler and count them per reason.
+func WithDeferrals(ctx context.Context) (context.Context, *Deferrals) {
+	d := &Deferrals{}
+	return context.WithValue(ctx, deferralsKey{}, d), d
+}
+
+// DeferralsFrom returns the Deferrals of the context, or nil if it records none.
+func DeferralsFrom(ctx context.Context) *Deferrals {
+	d, _ := ctx.Value(deferralsKey{}).(*Deferrals)
+	return d
+}
+
+// Defer records a step of the reconciliation deferred to a follow-up reconcile after
+// the given duration, with the reason it was deferred, and returns the Result aggregating
+// all the steps deferred so far.  The controller merges the deferred steps into the Result
+// returned by the Reconciler, so that a Reconciler going through several phases, e.g. one
+// per logical cluster, can defer some of them and carry on with the others:
+//
+//	for _, binding := range bindings {
+//		if !isReady(binding) {
+//			reconcile.Defer(ctx, 10*time.Second, "WaitingForAPIBinding")
+//			continue
+//		}
+//		...
+//	}
+//	return reconcile.Result{}, nil
+//
+// The Request is requeued when the earliest deferred step is due.  If the context records
+// no Deferrals, e.g. when the Reconciler is called directly, Defer returns the Result of
+// the given step alone.
+func Defer(ctx context.Context, after time.Duration, reason string) Result {
+	d := DeferralsFrom(ctx)
+	if d == nil {
+		return Deferral{After: after, Reason: reason}.result()
+	}
+	d.Add(after, reason)
+	return d.Result()
+}
@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
@@ -33,7 +34,7 @@ func ExampleFunc() {
 		return reconcile.Result{}, nil
 	})
 
-	res, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "test"}})
+	res, err := r.Reconcile(context.Background(), reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "default", Name: "test"}}})
 	if err != nil || res.Requeue || res.RequeueAfter != time.Duration(0) {
 		fmt.Printf("got requeue request: %v, %v\n", err, res)
 	}
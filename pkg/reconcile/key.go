@@ -0,0 +1,180 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/kcp-dev/logicalcluster"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The query parameters of the encoded keys.
+const (
+	keyCluster = "cluster"
+	keyGroup   = "group"
+	keyVersion = "version"
+	keyKind    = "kind"
+	keyShard   = "shard"
+	keyExtra   = "extra"
+)
+
+// Key returns a stable string encoding of the Request.  The Requests with only a namespace and a
+// name are encoded as namespace/name, like the keys of the informers, and the other fields are
+// appended as sorted URL query values, e.g.
+//
+//	default/foo?cluster=root%3Aorg&kind=Foo&shard=1
+func (r Request) Key() string {
+	key := r.Name
+	if r.Namespace != "" {
+		key = r.Namespace + "/" + r.Name
+	}
+
+	values := url.Values{}
+	set := func(k, v string) {
+		if v != "" {
+			values.Set(k, v)
+		}
+	}
+	set(keyCluster, r.Cluster.String())
+	set(keyGroup, r.GroupVersionKind.Group)
+	set(keyVersion, r.GroupVersionKind.Version)
+	set(keyKind, r.GroupVersionKind.Kind)
+	set(keyShard, r.Shard)
+	set(keyExtra, r.Extra)
+	if len(values) == 0 {
+		return key
+	}
+	return key + "?" + values.Encode()
+}
+
+// ParseKey decodes a key returned by Request.Key.  The keys must have a name, except the ones
+// of the Requests of removed logical clusters.
+func ParseKey(key string) (Request, error) {
+	path, query := key, ""
+	if i := strings.IndexByte(key, '?'); i >= 0 {
+		path, query = key[:i], key[i+1:]
+	}
+
+	var name types.NamespacedName
+	switch parts := strings.Split(path, "/"); len(parts) {
+	case 1:
+		name.Name = parts[0]
+	case 2:
+		name.Namespace, name.Name = parts[0], parts[1]
+	default:
+		return Request{}, fmt.Errorf("invalid request key %q: unexpected namespace/name %q", key, path)
+	}
+
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return Request{}, fmt.Errorf("invalid request key %q: %w", key, err)
+	}
+	req := Request{
+		ObjectKey: client.ObjectKey{NamespacedName: name, Cluster: logicalcluster.New(values.Get(keyCluster))},
+		GroupVersionKind: schema.GroupVersionKind{
+			Group:   values.Get(keyGroup),
+			Version: values.Get(keyVersion),
+			Kind:    values.Get(keyKind),
+		},
+		Shard: values.Get(keyShard),
+		Extra: values.Get(keyExtra),
+	}
+	// Only the Requests of removed logical clusters have no name, see ClusterRemovedRequest.
+	if name.Name == "" && (name.Namespace != "" || !req.IsClusterRemoved()) {
+		return Request{}, fmt.Errorf("invalid request key %q: empty name", key)
+	}
+	return req, nil
+}
+
+// WithExtra returns a copy of the Request whose Extra metadata has the given value for the
+// given key.  The values are encoded in a stable order, so that Requests with the same
+// metadata are equal regardless of the order the values were set in.
+func (r Request) WithExtra(key, value string) Request {
+	values, _ := url.ParseQuery(r.Extra)
+	if values == nil {
+		values = url.Values{}
+	}
+	values.Set(key, value)
+	r.Extra = values.Encode()
+	return r
+}
+
+// ExtraValue returns the value of the given key in the Extra metadata of the Request, or ""
+// if it isn't set.
+func (r Request) ExtraValue(key string) string {
+	values, _ := url.ParseQuery(r.Extra)
+	return values.Get(key)
+}
+
+// RequestForObject returns the Request reconciling the given object, in its logical cluster.
+func RequestForObject(obj client.Object) Request {
+	return Request{ObjectKey: client.ObjectKeyFromObject(obj)}
+}
+
+// The Extra metadata keys of tombstone Requests.
+const (
+	extraTombstone = "tombstone"
+	extraLabels    = "labels"
+)
+
+// Tombstone returns a copy of the Request flagged as the deletion of its object, carrying the last
+// known labels of the object.  Controllers enqueue tombstones for Delete events when configured to,
+// so that reconcilers can clean up external state without adding finalizers to the objects.
+func (r Request) Tombstone(lastLabels map[string]string) Request {
+	r = r.WithExtra(extraTombstone, "true")
+	if len(lastLabels) > 0 {
+		r = r.WithExtra(extraLabels, labels.Set(lastLabels).String())
+	}
+	return r
+}
+
+// IsTombstone returns whether the Request was enqueued for the deletion of its object, see Tombstone.
+func (r Request) IsTombstone() bool {
+	return r.ExtraValue(extraTombstone) == "true"
+}
+
+// TombstoneLabels returns the last known labels of the deleted object of a tombstone Request, or
+// nil if it had none.
+func (r Request) TombstoneLabels() labels.Set {
+	lastLabels, err := labels.ConvertSelectorToLabelsMap(r.ExtraValue(extraLabels))
+	if err != nil || len(lastLabels) == 0 {
+		return nil
+	}
+	return lastLabels
+}
+
+// The Extra metadata key of the Requests of removed logical clusters.
+const extraClusterRemoved = "clusterRemoved"
+
+// ClusterRemovedRequest returns the Request reconciled once the logical cluster is removed from
+// the ClusterSet of a controller configured to, so that the reconciler can clean up the external
+// state of the cluster.  It has no namespace nor name.
+func ClusterRemovedRequest(cluster logicalcluster.Name) Request {
+	return Request{ObjectKey: client.ObjectKey{Cluster: cluster}}.WithExtra(extraClusterRemoved, "true")
+}
+
+// IsClusterRemoved returns whether the Request was enqueued for the removal of its logical cluster,
+// see ClusterRemovedRequest.
+func (r Request) IsClusterRemoved() bool {
+	return r.ExtraValue(extraClusterRemoved) == "true"
+}
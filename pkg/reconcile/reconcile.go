@@ -20,6 +20,7 @@ import (
 	"context"
 	"time"
 
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -44,9 +45,23 @@ func (r *Result) IsZero() bool {
 // Request contains the information necessary to reconcile a Kubernetes object.  This includes the
 // information to uniquely identify the object - its Name and Namespace.  It does NOT contain information about
 // any specific Event or the object contents itself.
+//
+// Requests are the keys of the workqueue of a controller, so that Requests with the same fields are
+// deduplicated.  Key returns a stable string encoding of a Request, which ParseKey decodes.
 type Request struct {
 	// NamespacedName is the name and namespace of the object to reconcile.
 	client.ObjectKey
+
+	// GroupVersionKind is the kind of the object to reconcile, for the controllers reconciling
+	// several kinds.  It is empty for the controllers reconciling a single kind.
+	GroupVersionKind schema.GroupVersionKind
+
+	// Shard is the shard the Request is assigned to, for the controllers sharding their work.
+	Shard string
+
+	// Extra is arbitrary metadata distinguishing the Requests for the same object.  It is a string
+	// so that Requests stay comparable; WithExtra and ExtraValue encode it as URL query values.
+	Extra string
 }
 
 /*
@@ -100,3 +115,44 @@ var _ Reconciler = Func(nil)
 
 // Reconcile implements Reconciler.
 func (r Func) Reconcile(ctx context.Context, o Request) (Result, error) { return r(ctx, o) }
+
+// RequestInfo describes the attempts made so far to reconcile a Request.  Controllers pass it
+// to their Reconciler in the context, so that it can change its behavior after repeated failures,
+// e.g. by emitting an event or degrading gracefully.
+type RequestInfo struct {
+	// Retries is the number of times the Request was requeued with rate limiting, after
+	// an error or Result.Requeue, since it was last reconciled successfully.
+	Retries int
+
+	// FirstEnqueued is when the Request was first added to the queue since it was last
+	// reconciled successfully.  It is the zero time if the controller doesn't know.
+	FirstEnqueued time.Time
+
+	// CacheResourceVersion is the highest resourceVersion the cache of the controller had
+	// observed when the Request was enqueued.  The controller waited for all its sources to
+	// observe it before reconciling.  It is empty unless the controller waits for cache
+	// consistency.
+	CacheResourceVersion string
+}
+
+// SinceFirstEnqueued returns the time elapsed since the Request was first enqueued,
+// or 0 if FirstEnqueued is unknown.
+func (i RequestInfo) SinceFirstEnqueued() time.Duration {
+	if i.FirstEnqueued.IsZero() {
+		return 0
+	}
+	return time.Since(i.FirstEnqueued)
+}
+
+type requestInfoKey struct{}
+
+// WithRequestInfo returns a copy of the context carrying the given RequestInfo.
+func WithRequestInfo(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+// RequestInfoFrom returns the RequestInfo of the context, and whether it was set.
+func RequestInfoFrom(ctx context.Context) (RequestInfo, bool) {
+	info, ok := ctx.Value(requestInfoKey{}).(RequestInfo)
+	return info, ok
+}
@@ -21,9 +21,17 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/kcp-dev/logicalcluster"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/kcp"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
@@ -49,11 +57,195 @@ var _ = Describe("reconcile", func() {
 		})
 	})
 
+	Describe("RequestInfo", func() {
+		It("should be returned from the context it was stored in", func() {
+			_, ok := reconcile.RequestInfoFrom(context.Background())
+			Expect(ok).To(BeFalse())
+
+			info := reconcile.RequestInfo{Retries: 3, FirstEnqueued: time.Now().Add(-time.Minute)}
+			actual, ok := reconcile.RequestInfoFrom(reconcile.WithRequestInfo(context.Background(), info))
+			Expect(ok).To(BeTrue())
+			Expect(actual).To(Equal(info))
+			Expect(actual.SinceFirstEnqueued()).To(BeNumerically(">=", time.Minute))
+		})
+
+		It("should return 0 as the time since first enqueued if it is unknown", func() {
+			Expect(reconcile.RequestInfo{}.SinceFirstEnqueued()).To(BeZero())
+		})
+	})
+
+	Describe("Config", func() {
+		type quotas struct{ PerCluster int }
+
+		It("should be returned from the context it was stored in", func() {
+			_, ok := reconcile.ConfigFrom(context.Background())
+			Expect(ok).To(BeFalse())
+
+			cfg := reconcile.NewConfig(&quotas{PerCluster: 3})
+			actual, ok := reconcile.ConfigFrom(reconcile.WithConfig(context.Background(), cfg.Get()))
+			Expect(ok).To(BeTrue())
+			Expect(actual.(*quotas).PerCluster).To(Equal(3))
+		})
+
+		It("should keep the value of the contexts it was stored in when replaced", func() {
+			cfg := reconcile.NewConfig(&quotas{PerCluster: 3})
+			ctx := reconcile.WithConfig(context.Background(), cfg.Get())
+
+			cfg.Set(&quotas{PerCluster: 5})
+			Expect(cfg.Get().(*quotas).PerCluster).To(Equal(5))
+			actual, _ := reconcile.ConfigFrom(ctx)
+			Expect(actual.(*quotas).PerCluster).To(Equal(3))
+		})
+	})
+
+	Describe("Defer", func() {
+		It("should return the Result of the step if the context records no deferrals", func() {
+			Expect(reconcile.Defer(context.Background(), time.Minute, "Waiting")).To(Equal(reconcile.Result{RequeueAfter: time.Minute}))
+			Expect(reconcile.Defer(context.Background(), 0, "Waiting")).To(Equal(reconcile.Result{Requeue: true}))
+		})
+
+		It("should aggregate the deferred steps into the earliest Result", func() {
+			ctx, deferrals := reconcile.WithDeferrals(context.Background())
+			Expect(deferrals.Result()).To(Equal(reconcile.Result{}))
+
+			Expect(reconcile.Defer(ctx, time.Minute, "WaitingForQuota")).To(Equal(reconcile.Result{RequeueAfter: time.Minute}))
+			Expect(reconcile.Defer(ctx, 10*time.Second, "WaitingForAPIBinding")).To(Equal(reconcile.Result{RequeueAfter: 10 * time.Second}))
+			Expect(reconcile.Defer(ctx, 2*time.Minute, "WaitingForQuota")).To(Equal(reconcile.Result{RequeueAfter: 10 * time.Second}))
+			Expect(deferrals.List()).To(Equal([]reconcile.Deferral{
+				{After: time.Minute, Reason: "WaitingForQuota"},
+				{After: 10 * time.Second, Reason: "WaitingForAPIBinding"},
+				{After: 2 * time.Minute, Reason: "WaitingForQuota"},
+			}))
+			Expect(reconcile.DeferralsFrom(ctx)).To(BeIdenticalTo(deferrals))
+		})
+
+		It("should merge the deferred steps with the Result of the Reconciler", func() {
+			_, deferrals := reconcile.WithDeferrals(context.Background())
+			Expect(deferrals.Merge(reconcile.Result{RequeueAfter: time.Second})).To(Equal(reconcile.Result{RequeueAfter: time.Second}))
+
+			deferrals.Add(time.Minute, "WaitingForQuota")
+			Expect(deferrals.Merge(reconcile.Result{})).To(Equal(reconcile.Result{RequeueAfter: time.Minute}))
+			Expect(deferrals.Merge(reconcile.Result{RequeueAfter: time.Second})).To(Equal(reconcile.Result{RequeueAfter: time.Second}))
+			Expect(deferrals.Merge(reconcile.Result{RequeueAfter: time.Hour})).To(Equal(reconcile.Result{RequeueAfter: time.Minute}))
+			Expect(deferrals.Merge(reconcile.Result{Requeue: true})).To(Equal(reconcile.Result{Requeue: true}))
+		})
+	})
+
+	Describe("Request keys", func() {
+		It("should encode the requests with only a namespace and a name like the informers", func() {
+			request := reconcile.Request{ObjectKey: client.ObjectKey{
+				NamespacedName: types.NamespacedName{Name: "foo", Namespace: "bar"},
+			}}
+			Expect(request.Key()).To(Equal("bar/foo"))
+			Expect(reconcile.ParseKey("bar/foo")).To(Equal(request))
+
+			request.Namespace = ""
+			Expect(request.Key()).To(Equal("foo"))
+			Expect(reconcile.ParseKey("foo")).To(Equal(request))
+		})
+
+		It("should round-trip the structured fields of the requests", func() {
+			request := reconcile.Request{
+				ObjectKey: client.ObjectKey{
+					NamespacedName: types.NamespacedName{Name: "foo", Namespace: "bar"},
+					Cluster:        logicalcluster.New("root:org:ws"),
+				},
+				GroupVersionKind: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+				Shard:            "2",
+			}
+			request = request.WithExtra("generation", "3").WithExtra("action", "delete")
+			Expect(request.ExtraValue("action")).To(Equal("delete"))
+			Expect(request.ExtraValue("generation")).To(Equal("3"))
+			Expect(request.ExtraValue("missing")).To(BeEmpty())
+
+			key := request.Key()
+			Expect(key).To(Equal("bar/foo?cluster=root%3Aorg%3Aws&extra=action%3Ddelete%26generation%3D3&group=apps&kind=Deployment&shard=2&version=v1"))
+			Expect(reconcile.ParseKey(key)).To(Equal(request))
+		})
+
+		It("should encode the extra metadata regardless of the order it was set in", func() {
+			request := reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: types.NamespacedName{Name: "foo"}}}
+			Expect(request.WithExtra("a", "1").WithExtra("b", "2")).To(Equal(request.WithExtra("b", "2").WithExtra("a", "1")))
+		})
+
+		It("should flag tombstones with the last known labels of their object", func() {
+			request := reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: types.NamespacedName{Name: "foo"}}}
+			Expect(request.IsTombstone()).To(BeFalse())
+
+			tombstone := request.Tombstone(map[string]string{"app": "foo", "tier": "web"})
+			Expect(tombstone.IsTombstone()).To(BeTrue())
+			Expect(tombstone.TombstoneLabels()).To(Equal(labels.Set{"app": "foo", "tier": "web"}))
+			Expect(tombstone).NotTo(Equal(request))
+			Expect(reconcile.ParseKey(tombstone.Key())).To(Equal(tombstone))
+
+			Expect(request.Tombstone(nil).TombstoneLabels()).To(BeNil())
+		})
+
+		It("should round-trip the requests of removed logical clusters without a name", func() {
+			request := reconcile.ClusterRemovedRequest(logicalcluster.New("root:org:ws"))
+			Expect(request.Key()).To(Equal("?cluster=root%3Aorg%3Aws&extra=clusterRemoved%3Dtrue"))
+			parsed, err := reconcile.ParseKey(request.Key())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(parsed).To(Equal(request))
+			Expect(parsed.IsClusterRemoved()).To(BeTrue())
+		})
+
+		It("should reject invalid keys", func() {
+			for _, key := range []string{"", "a/b/c", "bar/", "foo?%zz", "?cluster=root", "bar/?extra=clusterRemoved%3Dtrue"} {
+				_, err := reconcile.ParseKey(key)
+				Expect(err).To(HaveOccurred(), key)
+			}
+		})
+
+		It("should return the request for an object", func() {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "bar", ClusterName: "root:org"}}
+			Expect(reconcile.RequestForObject(pod).Key()).To(Equal("bar/foo?cluster=root%3Aorg"))
+		})
+	})
+
+	Describe("ClusterAdapter", func() {
+		It("should pass the logical cluster of the request and a client pinned to it", func() {
+			c := fake.NewClientBuilder().Build()
+			cluster := logicalcluster.New("root:a")
+			var actual logicalcluster.Name
+			r := reconcile.ClusterAdapter(c, reconcile.ClusterAwareFunc(func(ctx context.Context, cluster logicalcluster.Name, req reconcile.Request) (reconcile.Result, error) {
+				actual = cluster
+				fromContext, ok := kcp.ClusterFrom(ctx)
+				Expect(ok).To(BeTrue())
+				Expect(fromContext).To(Equal(cluster))
+
+				pinned, ok := reconcile.ClientFrom(ctx)
+				Expect(ok).To(BeTrue())
+				return reconcile.Result{}, pinned.Create(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: req.Name, Namespace: req.Namespace}})
+			}))
+
+			req := reconcile.Request{ObjectKey: client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "cm"}, Cluster: cluster}}
+			Expect(r.Reconcile(context.Background(), req)).To(Equal(reconcile.Result{}))
+			Expect(actual).To(Equal(cluster))
+
+			cm := &corev1.ConfigMap{}
+			Expect(c.Get(context.Background(), req.ObjectKey, cm)).To(Succeed())
+			Expect(logicalcluster.From(cm)).To(Equal(cluster))
+		})
+
+		It("should pass the client unpinned if neither the request nor the context has a logical cluster", func() {
+			c := fake.NewClientBuilder().Build()
+			r := reconcile.ClusterAdapter(c, reconcile.ClusterAwareFunc(func(ctx context.Context, cluster logicalcluster.Name, req reconcile.Request) (reconcile.Result, error) {
+				Expect(cluster.Empty()).To(BeTrue())
+				fromContext, ok := reconcile.ClientFrom(ctx)
+				Expect(ok).To(BeTrue())
+				Expect(fromContext).To(BeIdenticalTo(c))
+				return reconcile.Result{Requeue: true}, nil
+			}))
+			Expect(r.Reconcile(context.Background(), reconcile.Request{})).To(Equal(reconcile.Result{Requeue: true}))
+		})
+	})
+
 	Describe("Func", func() {
 		It("should call the function with the request and return a nil error.", func() {
-			request := reconcile.Request{
+			request := reconcile.Request{ObjectKey: client.ObjectKey{
 				NamespacedName: types.NamespacedName{Name: "foo", Namespace: "bar"},
-			}
+			}}
 			result := reconcile.Result{
 				Requeue: true,
 			}
@@ -70,9 +262,9 @@ var _ = Describe("reconcile", func() {
 		})
 
 		It("should call the function with the request and return an error.", func() {
-			request := reconcile.Request{
+			request := reconcile.Request{ObjectKey: client.ObjectKey{
 				NamespacedName: types.NamespacedName{Name: "foo", Namespace: "bar"},
-			}
+			}}
 			result := reconcile.Result{
 				Requeue: false,
 			}
@@ -21,6 +21,10 @@ limitations under the License.
 package recorder
 
 import (
+	"context"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
 	"k8s.io/client-go/tools/record"
 )
 
@@ -29,3 +33,27 @@ type Provider interface {
 	// NewRecorder returns an EventRecorder with given name.
 	GetEventRecorderFor(name string) record.EventRecorder
 }
+
+// ClusterAwareRecorder is an EventRecorder which creates the events of objects in the kcp
+// logical cluster of the objects.  The recorders of the manager are ClusterAwareRecorders.
+type ClusterAwareRecorder interface {
+	record.EventRecorder
+
+	// ForCluster returns an EventRecorder which creates the events in the given logical
+	// cluster, whatever the cluster of their objects.
+	ForCluster(cluster logicalcluster.Name) record.EventRecorder
+}
+
+// ForContext returns an EventRecorder which creates the events in the logical cluster of
+// the context, e.g. the one of the request being reconciled.  The recorder is returned as
+// is if the context has no logical cluster, or if it is not a ClusterAwareRecorder.
+func ForContext(ctx context.Context, recorder record.EventRecorder) record.EventRecorder {
+	cluster, ok := kcpclient.ClusterFromContext(ctx)
+	if !ok || cluster.Empty() || cluster == logicalcluster.Wildcard {
+		return recorder
+	}
+	if clusterAware, ok := recorder.(ClusterAwareRecorder); ok {
+		return clusterAware.ForCluster(cluster)
+	}
+	return recorder
+}
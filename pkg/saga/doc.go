@@ -0,0 +1,32 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package saga helps reconcilers write across logical clusters with a best-effort consistency.
+
+A Saga is a sequence of steps, each writing to a logical cluster and declaring how to
+compensate its write.  If a step fails, the steps already applied are compensated in the
+reverse order, and the steps which couldn't be compensated are flagged in the Result, which
+reconcilers can surface as a condition of the status of their object:
+
+	s := &saga.Saga{Steps: []saga.Step{
+		saga.CreateStep(c, "claim", claim),
+		saga.CreateStep(c, "binding", binding),
+	}}
+	result, err := s.Run(ctx)
+	meta.SetStatusCondition(&obj.Status.Conditions, result.Condition("Bound"))
+*/
+package saga
@@ -0,0 +1,166 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package saga
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/internal/log"
+)
+
+var log = logf.RuntimeLog.WithName("saga")
+
+const (
+	// ReasonCompleted is the reason of the condition of a Saga whose steps were all applied.
+	ReasonCompleted = "Completed"
+	// ReasonCompensated is the reason of the condition of a Saga which failed, and whose
+	// applied steps were all compensated.
+	ReasonCompensated = "Compensated"
+	// ReasonCompensationFailed is the reason of the condition of a Saga which failed, and
+	// some of whose applied steps couldn't be compensated.
+	ReasonCompensationFailed = "CompensationFailed"
+)
+
+// Step is a write of a Saga, along with its compensation.
+type Step struct {
+	// Name identifies the step in the Result.
+	Name string
+
+	// Cluster is the logical cluster the step writes to.  It is passed to Do and
+	// Compensate in their context, unless empty.
+	Cluster logicalcluster.Name
+
+	// Do applies the step.
+	Do func(ctx context.Context) error
+
+	// Compensate undoes the step once it was applied, when a later step fails.  The
+	// step is flagged in the Result if it is nil.
+	Compensate func(ctx context.Context) error
+}
+
+// Saga is a sequence of steps, applied in order, whose applied steps are compensated in the
+// reverse order when one fails.
+type Saga struct {
+	Steps []Step
+}
+
+// Result describes what a Saga did.
+type Result struct {
+	// Applied are the names of the steps applied, in order, including those compensated.
+	Applied []string
+
+	// Failed is the name of the step which failed, if any.
+	Failed string
+
+	// Compensated are the names of the steps compensated, in the order of the compensations.
+	Compensated []string
+
+	// Flagged are the names of the applied steps which couldn't be compensated, either
+	// because they have no compensation or because it failed.
+	Flagged []string
+
+	// Err is the error of the failed step, along with the errors of the compensations.
+	Err error
+}
+
+// Succeeded returns whether all the steps were applied.
+func (r *Result) Succeeded() bool {
+	return r.Failed == ""
+}
+
+// Condition returns a condition of the given type describing the result, to be set in the
+// status of the object being reconciled, e.g. with meta.SetStatusCondition.
+func (r *Result) Condition(conditionType string) metav1.Condition {
+	switch {
+	case r.Succeeded():
+		return metav1.Condition{
+			Type:    conditionType,
+			Status:  metav1.ConditionTrue,
+			Reason:  ReasonCompleted,
+			Message: fmt.Sprintf("applied %s", strings.Join(r.Applied, ", ")),
+		}
+	case len(r.Flagged) == 0:
+		return metav1.Condition{
+			Type:    conditionType,
+			Status:  metav1.ConditionFalse,
+			Reason:  ReasonCompensated,
+			Message: fmt.Sprintf("step %s failed: %v", r.Failed, r.Err),
+		}
+	default:
+		return metav1.Condition{
+			Type:   conditionType,
+			Status: metav1.ConditionFalse,
+			Reason: ReasonCompensationFailed,
+			Message: fmt.Sprintf("step %s failed, and steps %s could not be compensated: %v",
+				r.Failed, strings.Join(r.Flagged, ", "), r.Err),
+		}
+	}
+}
+
+// Run applies the steps in order.  If one fails, the steps already applied are compensated in
+// the reverse order, and the error of the step is returned along with those of the compensations.
+// The Result is returned whether the Saga succeeded or not.
+func (s *Saga) Run(ctx context.Context) (*Result, error) {
+	result := &Result{}
+	for i, step := range s.Steps {
+		if err := step.Do(stepContext(ctx, step)); err != nil {
+			result.Failed = step.Name
+			errs := []error{fmt.Errorf("step %s failed: %w", step.Name, err)}
+			errs = append(errs, s.compensate(ctx, s.Steps[:i], result)...)
+			result.Err = kerrors.NewAggregate(errs)
+			return result, result.Err
+		}
+		result.Applied = append(result.Applied, step.Name)
+	}
+	return result, nil
+}
+
+// compensate compensates the given applied steps in the reverse order, and returns the errors
+// of the compensations.
+func (s *Saga) compensate(ctx context.Context, applied []Step, result *Result) []error {
+	var errs []error
+	for i := len(applied) - 1; i >= 0; i-- {
+		step := applied[i]
+		if step.Compensate == nil {
+			result.Flagged = append(result.Flagged, step.Name)
+			continue
+		}
+		if err := step.Compensate(stepContext(ctx, step)); err != nil {
+			log.Error(err, "failed to compensate step", "step", step.Name, "cluster", step.Cluster)
+			result.Flagged = append(result.Flagged, step.Name)
+			errs = append(errs, fmt.Errorf("compensation of step %s failed: %w", step.Name, err))
+			continue
+		}
+		result.Compensated = append(result.Compensated, step.Name)
+	}
+	return errs
+}
+
+// stepContext returns the context of the given step, targeting its logical cluster.
+func stepContext(ctx context.Context, step Step) context.Context {
+	if step.Cluster.Empty() {
+		return ctx
+	}
+	return kcpclient.WithCluster(ctx, step.Cluster)
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package saga_test
+
+import (
+	"context"
+	"errors"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/saga"
+)
+
+var _ = Describe("Saga", func() {
+	var (
+		ctx  context.Context
+		c    client.Client
+		a, b logicalcluster.Name
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		a, b = logicalcluster.New("root:a"), logicalcluster.New("root:b")
+		c = fake.NewClusterBuilder().
+			WithObjects(a, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "ns"}, Data: map[string]string{"k": "v1"}}).
+			Build()
+	})
+
+	configMap := func(cluster logicalcluster.Name, name string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns", ClusterName: cluster.String()}}
+	}
+	get := func(cluster logicalcluster.Name, name string) (*corev1.ConfigMap, error) {
+		cm := &corev1.ConfigMap{}
+		key := client.ObjectKey{Cluster: cluster}
+		key.Namespace, key.Name = "ns", name
+		return cm, c.Get(ctx, key, cm)
+	}
+	failing := saga.Step{Name: "failing", Do: func(context.Context) error { return errors.New("boom") }}
+
+	It("should apply all the steps across logical clusters", func() {
+		result, err := (&saga.Saga{Steps: []saga.Step{
+			saga.CreateStep(c, "first", configMap(a, "first")),
+			saga.CreateStep(c, "second", configMap(b, "second")),
+		}}).Run(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Succeeded()).To(BeTrue())
+		Expect(result.Applied).To(Equal([]string{"first", "second"}))
+		Expect(result.Condition("Ready").Status).To(Equal(metav1.ConditionTrue))
+
+		_, err = get(a, "first")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = get(b, "second")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should compensate the applied steps in the reverse order when a step fails", func() {
+		existing, err := get(a, "existing")
+		Expect(err).NotTo(HaveOccurred())
+		existing.Data["k"] = "v2"
+
+		var order []string
+		result, err := (&saga.Saga{Steps: []saga.Step{
+			saga.UpdateStep(c, "update", existing),
+			saga.CreateStep(c, "create", configMap(b, "created")),
+			{
+				Name:    "record",
+				Cluster: b,
+				Do:      func(context.Context) error { return nil },
+				Compensate: func(ctx context.Context) error {
+					cluster, _ := kcpclient.ClusterFromContext(ctx)
+					order = append(order, "record "+cluster.String())
+					return nil
+				},
+			},
+			failing,
+		}}).Run(ctx)
+		Expect(err).To(MatchError(ContainSubstring("boom")))
+		Expect(result.Failed).To(Equal("failing"))
+		Expect(result.Compensated).To(Equal([]string{"record", "create", "update"}))
+		Expect(result.Flagged).To(BeEmpty())
+		Expect(order).To(Equal([]string{"record root:b"}))
+		condition := result.Condition("Ready")
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(saga.ReasonCompensated))
+
+		_, err = get(b, "created")
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		restored, err := get(a, "existing")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(restored.Data).To(HaveKeyWithValue("k", "v1"))
+	})
+
+	It("should create back a deleted object when a later step fails", func() {
+		existing, err := get(a, "existing")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = (&saga.Saga{Steps: []saga.Step{saga.DeleteStep(c, "delete", existing), failing}}).Run(ctx)
+		Expect(err).To(HaveOccurred())
+
+		restored, err := get(a, "existing")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(restored.Data).To(HaveKeyWithValue("k", "v1"))
+	})
+
+	It("should flag the steps which can't be compensated", func() {
+		result, err := (&saga.Saga{Steps: []saga.Step{
+			{Name: "irreversible", Do: func(context.Context) error { return nil }},
+			{
+				Name:       "stuck",
+				Do:         func(context.Context) error { return nil },
+				Compensate: func(context.Context) error { return errors.New("stuck") },
+			},
+			saga.CreateStep(c, "create", configMap(a, "created")),
+			failing,
+		}}).Run(ctx)
+		Expect(err).To(MatchError(ContainSubstring("compensation of step stuck failed")))
+		Expect(result.Compensated).To(Equal([]string{"create"}))
+		Expect(result.Flagged).To(Equal([]string{"stuck", "irreversible"}))
+		condition := result.Condition("Ready")
+		Expect(condition.Reason).To(Equal(saga.ReasonCompensationFailed))
+		Expect(condition.Message).To(ContainSubstring("stuck, irreversible"))
+	})
+})
@@ -0,0 +1,92 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package saga
+
+import (
+	"context"
+
+	"github.com/kcp-dev/logicalcluster"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CreateStep returns a step creating the given object in its logical cluster, compensated by
+// deleting it.
+func CreateStep(c client.Client, name string, obj client.Object) Step {
+	return Step{
+		Name:    name,
+		Cluster: logicalcluster.From(obj),
+		Do: func(ctx context.Context) error {
+			return c.Create(ctx, obj)
+		},
+		Compensate: func(ctx context.Context) error {
+			return client.IgnoreNotFound(c.Delete(ctx, obj, client.PropagationPolicy("Background")))
+		},
+	}
+}
+
+// UpdateStep returns a step updating the given object in its logical cluster, compensated by
+// restoring the object it replaced.  The object must have been read before, so that the step
+// fails with a conflict if it changed since.
+func UpdateStep(c client.Client, name string, obj client.Object) Step {
+	var previous client.Object
+	return Step{
+		Name:    name,
+		Cluster: logicalcluster.From(obj),
+		Do: func(ctx context.Context) error {
+			previous = obj.DeepCopyObject().(client.Object)
+			if err := c.Get(ctx, client.ObjectKeyFromObject(obj), previous); err != nil {
+				return err
+			}
+			return c.Update(ctx, obj)
+		},
+		Compensate: func(ctx context.Context) error {
+			restored := previous.DeepCopyObject().(client.Object)
+			restored.SetResourceVersion(obj.GetResourceVersion())
+			return c.Update(ctx, restored)
+		},
+	}
+}
+
+// DeleteStep returns a step deleting the given object in its logical cluster, compensated by
+// creating it back.  The object must have been read before, so that it is created back as it was,
+// and the step fails with a conflict if it changed since.
+func DeleteStep(c client.Client, name string, obj client.Object) Step {
+	return Step{
+		Name:    name,
+		Cluster: logicalcluster.From(obj),
+		Do: func(ctx context.Context) error {
+			preconditions := client.Preconditions{}
+			if uid := obj.GetUID(); uid != "" {
+				preconditions.UID = &uid
+			}
+			if resourceVersion := obj.GetResourceVersion(); resourceVersion != "" {
+				preconditions.ResourceVersion = &resourceVersion
+			}
+			return c.Delete(ctx, obj, preconditions)
+		},
+		Compensate: func(ctx context.Context) error {
+			restored := obj.DeepCopyObject().(client.Object)
+			restored.SetResourceVersion("")
+			restored.SetUID("")
+			restored.SetCreationTimestamp(metav1.Time{})
+			restored.SetDeletionTimestamp(nil)
+			return c.Create(ctx, restored)
+		},
+	}
+}
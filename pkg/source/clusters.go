@@ -0,0 +1,219 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/kcp-dev/logicalcluster"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source/internal"
+)
+
+// Clusters returns a Source of the changes of the logical clusters of the ClusterSet, e.g. to
+// seed default objects in each new workspace: it emits a Create event when a cluster is added
+// to the set, a Delete event when it is removed, and a Generic event when it fails.  The object
+// of the events is a metav1.PartialObjectMetadata named after the cluster, in the cluster, so
+// that handler.EnqueueRequestForObject enqueues requests for the cluster.  The clusters the set
+// already has are emitted as created when the Source starts.
+//
+// The Reconciler reads the state of the cluster from the ClusterSet, e.g. with Get and Failed,
+// as the Requests of the events of a cluster may be merged in the queue.
+func Clusters(set *cluster.ClusterSet) Source {
+	return &clusters{set: set}
+}
+
+type clusters struct {
+	set *cluster.ClusterSet
+}
+
+var _ Source = &clusters{}
+
+// Start implements Source.  The ClusterSet keeps its handlers, so the events are dropped
+// once the context is done.
+func (cs *clusters) Start(ctx context.Context, handler handler.EventHandler, queue workqueue.RateLimitingInterface,
+	prct ...predicate.Predicate) error {
+	if cs.set == nil {
+		return fmt.Errorf("must specify the ClusterSet of the Clusters source")
+	}
+	cs.set.AddHandler(&clusterEvents{ctx: ctx, handler: handler, queue: queue, predicates: prct})
+	return nil
+}
+
+func (cs *clusters) String() string {
+	return fmt.Sprintf("clusters source: %p", cs.set)
+}
+
+// clusterEvents turns the notifications of a ClusterSet into events.
+type clusterEvents struct {
+	ctx        context.Context
+	handler    handler.EventHandler
+	queue      workqueue.RateLimitingInterface
+	predicates []predicate.Predicate
+}
+
+var _ cluster.ClusterSetHandler = &clusterEvents{}
+var _ cluster.ClusterFailedHandler = &clusterEvents{}
+
+// clusterObject returns the object of the events of the logical cluster.
+func clusterObject(name logicalcluster.Name) client.Object {
+	return &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: name.String(), ClusterName: name.String()}}
+}
+
+// ClusterAdded implements cluster.ClusterSetHandler.
+func (e *clusterEvents) ClusterAdded(name logicalcluster.Name, _ cluster.Cluster) {
+	if e.ctx.Err() != nil {
+		return
+	}
+	evt := event.CreateEvent{Object: clusterObject(name)}
+	for _, p := range e.predicates {
+		if !p.Create(evt) {
+			return
+		}
+	}
+	e.handler.Create(evt, e.queue)
+}
+
+// ClusterRemoved implements cluster.ClusterSetHandler.
+func (e *clusterEvents) ClusterRemoved(name logicalcluster.Name) {
+	if e.ctx.Err() != nil {
+		return
+	}
+	evt := event.DeleteEvent{Object: clusterObject(name)}
+	for _, p := range e.predicates {
+		if !p.Delete(evt) {
+			return
+		}
+	}
+	e.handler.Delete(evt, e.queue)
+}
+
+// ClusterFailed implements cluster.ClusterFailedHandler.
+func (e *clusterEvents) ClusterFailed(name logicalcluster.Name, _ error) {
+	if e.ctx.Err() != nil {
+		return
+	}
+	evt := event.GenericEvent{Object: clusterObject(name)}
+	if admitsGeneric(evt, e.predicates) {
+		e.handler.Generic(evt, e.queue)
+	}
+}
+
+// ClusterSetKind returns a Source watching the objects of the given type in the caches of all
+// the clusters of the ClusterSet, including the clusters added after the Source is started,
+// e.g. lazily, when a workspace is first seen.  When a cluster is added, the handler is
+// registered on the informer of its cache, which passes the objects it already has to the
+// handler as created, so that the controller reconciles them too.  The handler of a cluster
+// is removed when the cluster is removed, and the handlers of all the clusters once the
+// context of the Source is done, see cluster.ClusterSet.AddEventHandler.
+//
+// WaitForSync waits for the caches of the clusters the set has when it is called.
+func ClusterSetKind(set *cluster.ClusterSet, object client.Object) SyncingSource {
+	return &clusterSetKind{set: set, object: object}
+}
+
+type clusterSetKind struct {
+	set    *cluster.ClusterSet
+	object client.Object
+
+	mu           sync.Mutex
+	registration *cluster.EventHandlerRegistration
+}
+
+var _ SyncingSource = &clusterSetKind{}
+var _ ResyncingSource = &clusterSetKind{}
+
+// Start implements Source.
+func (ks *clusterSetKind) Start(ctx context.Context, handler handler.EventHandler, queue workqueue.RateLimitingInterface,
+	prct ...predicate.Predicate) error {
+	if ks.set == nil {
+		return fmt.Errorf("must specify the ClusterSet of the ClusterSetKind source")
+	}
+	if ks.object == nil {
+		return fmt.Errorf("must specify the type of the ClusterSetKind source")
+	}
+	registration := ks.set.AddEventHandler(ctx, ks.object, internal.EventHandler{Queue: queue, EventHandler: handler, Predicates: prct})
+	ks.mu.Lock()
+	ks.registration = registration
+	ks.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		ks.set.RemoveEventHandler(registration)
+	}()
+	return nil
+}
+
+// getRegistration returns the registration of the handler of the Source, once it is started.
+func (ks *clusterSetKind) getRegistration() (*cluster.EventHandlerRegistration, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if ks.registration == nil {
+		return nil, fmt.Errorf("%s was not started", ks)
+	}
+	return ks.registration, nil
+}
+
+// WaitForSync implements SyncingSource, waiting for the caches of the clusters of the set.
+func (ks *clusterSetKind) WaitForSync(ctx context.Context) error {
+	registration, err := ks.getRegistration()
+	if err != nil {
+		return err
+	}
+	return registration.WaitForSync(ctx)
+}
+
+// Resync implements ResyncingSource, replaying the objects of the caches of the clusters of the set.
+func (ks *clusterSetKind) Resync(handler handler.EventHandler, queue workqueue.RateLimitingInterface,
+	prct ...predicate.Predicate) error {
+	registration, err := ks.getRegistration()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for name, informer := range registration.Informers() {
+		storer, ok := informer.(interface{ GetStore() toolscache.Store })
+		if !ok {
+			errs = append(errs, fmt.Errorf("cluster %s: the informer of %s doesn't expose its store", name, ks))
+			continue
+		}
+		for _, obj := range storer.GetStore().List() {
+			o, ok := obj.(client.Object)
+			if !ok {
+				continue
+			}
+			evt := event.GenericEvent{Object: o, Cluster: logicalcluster.From(o)}
+			if admitsGeneric(evt, prct) {
+				handler.Generic(evt, queue)
+			}
+		}
+	}
+	return kerrors.NewAggregate(errs)
+}
+
+func (ks *clusterSetKind) String() string {
+	return fmt.Sprintf("cluster set kind source: %T", ks.object)
+}
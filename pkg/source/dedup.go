@@ -0,0 +1,316 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
+)
+
+// DedupPolicy decides which events of the sources of a Deduplicator are passed to the handlers.
+type DedupPolicy string
+
+const (
+	// DedupMerged passes the events of all the sources, but only the first event of each change
+	// of an object, e.g. the creation of a Pod observed by both the wildcard cache and the cache
+	// of its logical cluster.  Changes are told apart by the resourceVersion of the object.
+	DedupMerged DedupPolicy = "Merged"
+
+	// DedupWildcardOnly drops the events of the scoped sources while a wildcard source is started.
+	DedupWildcardOnly DedupPolicy = "WildcardOnly"
+
+	// DedupScopedOnly drops the events of the wildcard sources for the logical clusters which
+	// have a started scoped source.  The events of the other clusters are passed.
+	DedupScopedOnly DedupPolicy = "ScopedOnly"
+)
+
+// defaultDedupWindow is how long a Deduplicator remembers the changes it passed by default.
+const defaultDedupWindow = 5 * time.Minute
+
+// Deduplicator makes the handlers of a controller receive the change of an object once, when the
+// controller watches it both with a source of the wildcard cache of the manager, spanning all the
+// logical clusters, and with a source of the cache of its logical cluster, e.g. a cluster of a
+// cluster.ClusterSet.  Wrap the sources with Wildcard and Scoped:
+//
+//	dedup := source.NewDeduplicator(source.DedupMerged)
+//	ctrl.Watch(dedup.Wildcard(&source.Kind{Type: &corev1.Pod{}}), handler)
+//	clusterSet.AddHandler(cluster.ClusterSetHandlerFuncs{
+//		AddFunc: func(name logicalcluster.Name, cl cluster.Cluster) {
+//			ctrl.Watch(dedup.Scoped(name, source.NewKindWithCache(&corev1.Pod{}, cl.GetCache())), handler)
+//		},
+//	})
+//
+// The events of objects without a resourceVersion, and the GenericEvents, e.g. of a resync, are
+// never merged.
+//
+// The changes are told apart by object only, so a Deduplicator wraps the sources of a single
+// watch, i.e. of a single handler: the watches of the same objects with other handlers, e.g. an
+// EnqueueRequestForOwner next to an EnqueueRequestForObject, each need their own Deduplicator,
+// or they would lose the events passed to the first one.  Starting a second wildcard source, or
+// a second scoped source of a logical cluster, while the first one is running, fails.
+type Deduplicator struct {
+	// Policy decides which events are passed, it defaults to DedupMerged.
+	Policy DedupPolicy
+
+	// Window is how long the changes passed with DedupMerged are remembered, it defaults to
+	// 5 minutes.  It must be longer than the delay between the sources observing a change.
+	Window time.Duration
+
+	mu        sync.Mutex
+	wildcards int
+	scoped    map[logicalcluster.Name]int
+	seen      map[dedupKey]time.Time
+	lastPrune time.Time
+
+	// now is time.Now, overridden in tests.
+	now func() time.Time
+}
+
+// dedupKey identifies a change of an object.
+type dedupKey struct {
+	eventType       string
+	cluster         logicalcluster.Name
+	namespace, name string
+	resourceVersion string
+}
+
+// NewDeduplicator returns a Deduplicator with the given policy.
+func NewDeduplicator(policy DedupPolicy) *Deduplicator {
+	return &Deduplicator{Policy: policy}
+}
+
+// Wildcard wraps a source of the objects of all the logical clusters.
+func (d *Deduplicator) Wildcard(src Source) Source {
+	return d.wrap(&dedupSource{dedup: d, src: src, wildcard: true})
+}
+
+// Scoped wraps a source of the objects of the given logical cluster.
+func (d *Deduplicator) Scoped(cluster logicalcluster.Name, src Source) Source {
+	return d.wrap(&dedupSource{dedup: d, src: src, cluster: cluster})
+}
+
+func (d *Deduplicator) wrap(ds *dedupSource) Source {
+	if _, ok := ds.src.(ResyncingSource); ok {
+		return &resyncingDedupSource{ds}
+	}
+	return ds
+}
+
+// claim records that a source is started, unless the Deduplicator has a running source of the
+// same logical cluster, or wildcard, already.
+func (d *Deduplicator) claim(ds *dedupSource) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if (ds.wildcard && d.wildcards > 0) || (!ds.wildcard && d.scoped[ds.cluster] > 0) {
+		return fmt.Errorf("cannot start %s: the Deduplicator already has a running source like it, "+
+			"each watch needs its own Deduplicator", ds)
+	}
+	d.count(ds, 1)
+	return nil
+}
+
+// release records that a source is stopped.
+func (d *Deduplicator) release(ds *dedupSource) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.count(ds, -1)
+}
+
+func (d *Deduplicator) count(ds *dedupSource, delta int) {
+	if ds.wildcard {
+		d.wildcards += delta
+		return
+	}
+	if d.scoped == nil {
+		d.scoped = map[logicalcluster.Name]int{}
+	}
+	d.scoped[ds.cluster] += delta
+	if d.scoped[ds.cluster] <= 0 {
+		delete(d.scoped, ds.cluster)
+	}
+}
+
+// admits returns whether the event of the object, observed by the source, is passed.
+func (d *Deduplicator) admits(ds *dedupSource, eventType string, cluster logicalcluster.Name, obj client.Object) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	switch d.Policy {
+	case DedupWildcardOnly:
+		return ds.wildcard || d.wildcards == 0
+	case DedupScopedOnly:
+		return !ds.wildcard || d.scoped[cluster] == 0
+	}
+
+	if eventType == "" || obj == nil || obj.GetResourceVersion() == "" {
+		return true
+	}
+	key := dedupKey{
+		eventType:       eventType,
+		cluster:         cluster,
+		namespace:       obj.GetNamespace(),
+		name:            obj.GetName(),
+		resourceVersion: obj.GetResourceVersion(),
+	}
+	now := d.clock()
+	window := d.Window
+	if window <= 0 {
+		window = defaultDedupWindow
+	}
+	if now.Sub(d.lastPrune) > window {
+		for k, t := range d.seen {
+			if now.Sub(t) > window {
+				delete(d.seen, k)
+			}
+		}
+		d.lastPrune = now
+	}
+	if t, ok := d.seen[key]; ok && now.Sub(t) <= window {
+		return false
+	}
+	if d.seen == nil {
+		d.seen = map[dedupKey]time.Time{}
+	}
+	d.seen[key] = now
+	return true
+}
+
+func (d *Deduplicator) clock() time.Time {
+	if d.now != nil {
+		return d.now()
+	}
+	return time.Now()
+}
+
+// dedupSource is a source of a Deduplicator.
+type dedupSource struct {
+	dedup    *Deduplicator
+	src      Source
+	wildcard bool
+	cluster  logicalcluster.Name
+}
+
+var _ SyncingSource = &dedupSource{}
+var _ inject.Injector = &dedupSource{}
+
+// Start starts the wrapped source, passing it a handler which drops the events the Deduplicator
+// doesn't admit.  It fails if the Deduplicator has a running source like it, see Deduplicator.
+func (ds *dedupSource) Start(ctx context.Context, h handler.EventHandler, queue workqueue.RateLimitingInterface,
+	prct ...predicate.Predicate) error {
+	if err := ds.dedup.claim(ds); err != nil {
+		return err
+	}
+	if err := ds.src.Start(ctx, ds.handler(h), queue, prct...); err != nil {
+		ds.dedup.release(ds)
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		ds.dedup.release(ds)
+	}()
+	return nil
+}
+
+// WaitForSync waits for the wrapped source to sync, if it's a SyncingSource.
+func (ds *dedupSource) WaitForSync(ctx context.Context) error {
+	if syncing, ok := ds.src.(SyncingSource); ok {
+		return syncing.WaitForSync(ctx)
+	}
+	return nil
+}
+
+// InjectFunc injects the dependencies of the wrapped source.
+func (ds *dedupSource) InjectFunc(f inject.Func) error {
+	return f(ds.src)
+}
+
+func (ds *dedupSource) String() string {
+	if ds.wildcard {
+		return fmt.Sprintf("wildcard %s", ds.src)
+	}
+	return fmt.Sprintf("%s scoped to cluster %s", ds.src, ds.cluster)
+}
+
+func (ds *dedupSource) handler(h handler.EventHandler) handler.EventHandler {
+	return &dedupHandler{source: ds, handler: h}
+}
+
+// resyncingDedupSource is a dedupSource wrapping a ResyncingSource.
+type resyncingDedupSource struct {
+	*dedupSource
+}
+
+var _ ResyncingSource = &resyncingDedupSource{}
+
+// Resync resyncs the wrapped source.
+func (ds *resyncingDedupSource) Resync(h handler.EventHandler, queue workqueue.RateLimitingInterface,
+	prct ...predicate.Predicate) error {
+	return ds.src.(ResyncingSource).Resync(ds.handler(h), queue, prct...)
+}
+
+// dedupHandler passes the events a Deduplicator admits to the handler.
+type dedupHandler struct {
+	source  *dedupSource
+	handler handler.EventHandler
+}
+
+var _ handler.EventHandler = &dedupHandler{}
+
+func (h *dedupHandler) admits(eventType string, cluster logicalcluster.Name, obj client.Object) bool {
+	if cluster.Empty() && obj != nil {
+		cluster = logicalcluster.From(obj)
+	}
+	return h.source.dedup.admits(h.source, eventType, cluster, obj)
+}
+
+// Create implements handler.EventHandler.
+func (h *dedupHandler) Create(evt event.CreateEvent, q workqueue.RateLimitingInterface) {
+	if h.admits("create", evt.Cluster, evt.Object) {
+		h.handler.Create(evt, q)
+	}
+}
+
+// Update implements handler.EventHandler.
+func (h *dedupHandler) Update(evt event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	if h.admits("update", evt.Cluster, evt.ObjectNew) {
+		h.handler.Update(evt, q)
+	}
+}
+
+// Delete implements handler.EventHandler.
+func (h *dedupHandler) Delete(evt event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	if h.admits("delete", evt.Cluster, evt.Object) {
+		h.handler.Delete(evt, q)
+	}
+}
+
+// Generic implements handler.EventHandler.
+func (h *dedupHandler) Generic(evt event.GenericEvent, q workqueue.RateLimitingInterface) {
+	if h.admits("", evt.Cluster, evt.Object) {
+		h.handler.Generic(evt, q)
+	}
+}
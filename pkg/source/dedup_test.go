@@ -0,0 +1,202 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var _ = Describe("Deduplicator", func() {
+	var (
+		ctx      context.Context
+		cancel   context.CancelFunc
+		events   []string
+		counting handler.EventHandler
+		pod      *corev1.Pod
+	)
+
+	// start starts the source with the handler recording the events.
+	start := func(src source.Source) {
+		Expect(src.Start(ctx, counting, nil)).To(Succeed())
+	}
+	// capture returns a source keeping the handler it is started with.
+	capture := func(h *handler.EventHandler) source.Source {
+		return source.Func(func(_ context.Context, eh handler.EventHandler, _ workqueue.RateLimitingInterface, _ ...predicate.Predicate) error {
+			*h = eh
+			return nil
+		})
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		events = nil
+		counting = handler.Funcs{
+			CreateFunc: func(evt event.CreateEvent, _ workqueue.RateLimitingInterface) {
+				events = append(events, "create "+evt.Object.GetResourceVersion())
+			},
+			UpdateFunc: func(evt event.UpdateEvent, _ workqueue.RateLimitingInterface) {
+				events = append(events, "update "+evt.ObjectNew.GetResourceVersion())
+			},
+			DeleteFunc: func(evt event.DeleteEvent, _ workqueue.RateLimitingInterface) {
+				events = append(events, "delete "+evt.Object.GetResourceVersion())
+			},
+			GenericFunc: func(evt event.GenericEvent, _ workqueue.RateLimitingInterface) {
+				events = append(events, "generic "+evt.Object.GetResourceVersion())
+			},
+		}
+		pod = &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default", Name: "pod", ResourceVersion: "1", ClusterName: "root:org:ws",
+		}}
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("should pass each change of an object once with the merged policy", func() {
+		dedup := source.NewDeduplicator(source.DedupMerged)
+		var wildcard, scoped handler.EventHandler
+		start(dedup.Wildcard(capture(&wildcard)))
+		start(dedup.Scoped(logicalcluster.New("root:org:ws"), capture(&scoped)))
+
+		wildcard.Create(event.CreateEvent{Object: pod}, nil)
+		scoped.Create(event.CreateEvent{Object: pod}, nil)
+		updated := pod.DeepCopy()
+		updated.ResourceVersion = "2"
+		scoped.Update(event.UpdateEvent{ObjectOld: pod, ObjectNew: updated}, nil)
+		wildcard.Update(event.UpdateEvent{ObjectOld: pod, ObjectNew: updated}, nil)
+		wildcard.Delete(event.DeleteEvent{Object: updated}, nil)
+		scoped.Delete(event.DeleteEvent{Object: updated}, nil)
+
+		By("never merging the generic events")
+		wildcard.Generic(event.GenericEvent{Object: updated}, nil)
+		scoped.Generic(event.GenericEvent{Object: updated}, nil)
+
+		Expect(events).To(Equal([]string{"create 1", "update 2", "delete 2", "generic 2", "generic 2"}))
+	})
+
+	It("should tell apart the objects of different logical clusters", func() {
+		dedup := source.NewDeduplicator(source.DedupMerged)
+		var wildcard handler.EventHandler
+		start(dedup.Wildcard(capture(&wildcard)))
+
+		other := pod.DeepCopy()
+		other.ClusterName = "root:org:other"
+		wildcard.Create(event.CreateEvent{Object: pod}, nil)
+		wildcard.Create(event.CreateEvent{Object: other}, nil)
+		Expect(events).To(Equal([]string{"create 1", "create 1"}))
+	})
+
+	It("should forget the changes passed once the window expires", func() {
+		dedup := source.NewDeduplicator(source.DedupMerged)
+		dedup.Window = 10 * time.Millisecond
+		var wildcard, scoped handler.EventHandler
+		start(dedup.Wildcard(capture(&wildcard)))
+		start(dedup.Scoped(logicalcluster.New("root:org:ws"), capture(&scoped)))
+
+		wildcard.Create(event.CreateEvent{Object: pod}, nil)
+		time.Sleep(20 * time.Millisecond)
+		scoped.Create(event.CreateEvent{Object: pod}, nil)
+		Expect(events).To(Equal([]string{"create 1", "create 1"}))
+	})
+
+	It("should only pass the events of the wildcard source with the wildcard only policy", func() {
+		dedup := source.NewDeduplicator(source.DedupWildcardOnly)
+		var wildcard, scoped handler.EventHandler
+		start(dedup.Scoped(logicalcluster.New("root:org:ws"), capture(&scoped)))
+
+		By("passing the events of the scoped source until the wildcard source is started")
+		scoped.Create(event.CreateEvent{Object: pod}, nil)
+		Expect(events).To(Equal([]string{"create 1"}))
+
+		start(dedup.Wildcard(capture(&wildcard)))
+		scoped.Generic(event.GenericEvent{Object: pod}, nil)
+		wildcard.Generic(event.GenericEvent{Object: pod}, nil)
+		Expect(events).To(Equal([]string{"create 1", "generic 1"}))
+	})
+
+	It("should drop the events of the wildcard source for the clusters with a scoped source with the scoped only policy", func() {
+		dedup := source.NewDeduplicator(source.DedupScopedOnly)
+		var wildcard, scoped handler.EventHandler
+		start(dedup.Wildcard(capture(&wildcard)))
+		scopedCtx, scopedCancel := context.WithCancel(ctx)
+		Expect(dedup.Scoped(logicalcluster.New("root:org:ws"), capture(&scoped)).Start(scopedCtx, counting, nil)).To(Succeed())
+
+		other := pod.DeepCopy()
+		other.ClusterName = "root:org:other"
+		wildcard.Create(event.CreateEvent{Object: pod}, nil)
+		wildcard.Create(event.CreateEvent{Object: other}, nil)
+		scoped.Create(event.CreateEvent{Object: pod}, nil)
+		Expect(events).To(Equal([]string{"create 1", "create 1"}))
+
+		By("passing the events of the wildcard source again once the scoped source is stopped")
+		scopedCancel()
+		Eventually(func() []string {
+			events = nil
+			wildcard.Generic(event.GenericEvent{Object: pod}, nil)
+			return events
+		}).Should(Equal([]string{"generic 1"}))
+	})
+
+	It("should refuse the sources of a second watch, which would lose the events of its handler", func() {
+		dedup := source.NewDeduplicator(source.DedupMerged)
+		var forObject, forOwner handler.EventHandler
+		wildcardCtx, wildcardCancel := context.WithCancel(ctx)
+		Expect(dedup.Wildcard(capture(&forObject)).Start(wildcardCtx, &handler.EnqueueRequestForObject{}, nil)).To(Succeed())
+		start(dedup.Scoped(logicalcluster.New("root:org:ws"), capture(new(handler.EventHandler))))
+
+		err := dedup.Wildcard(capture(&forOwner)).Start(ctx, &handler.EnqueueRequestForOwner{}, nil)
+		Expect(err).To(MatchError(ContainSubstring("each watch needs its own Deduplicator")))
+		Expect(forOwner).To(BeNil())
+		err = dedup.Scoped(logicalcluster.New("root:org:ws"), capture(new(handler.EventHandler))).Start(ctx, counting, nil)
+		Expect(err).To(MatchError(ContainSubstring("each watch needs its own Deduplicator")))
+		start(dedup.Scoped(logicalcluster.New("root:org:other"), capture(new(handler.EventHandler))))
+
+		By("passing the events of the second watch to its handler with a Deduplicator of its own")
+		var wildcard handler.EventHandler
+		start(source.NewDeduplicator(source.DedupMerged).Wildcard(capture(&wildcard)))
+		forObject.Create(event.CreateEvent{Object: pod}, workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()))
+		wildcard.Create(event.CreateEvent{Object: pod}, nil)
+		Expect(events).To(Equal([]string{"create 1"}))
+
+		By("accepting a source again once the running one is stopped")
+		wildcardCancel()
+		Eventually(func() error {
+			return dedup.Wildcard(capture(new(handler.EventHandler))).Start(ctx, counting, nil)
+		}).Should(Succeed())
+	})
+
+	It("should only resync the sources which can", func() {
+		dedup := source.NewDeduplicator(source.DedupMerged)
+		_, ok := dedup.Wildcard(capture(new(handler.EventHandler))).(source.ResyncingSource)
+		Expect(ok).To(BeFalse())
+		_, ok = dedup.Wildcard(&source.Kind{Type: &corev1.Pod{}}).(source.ResyncingSource)
+		Expect(ok).To(BeTrue())
+	})
+})
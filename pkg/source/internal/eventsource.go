@@ -19,6 +19,7 @@ package internal
 import (
 	"fmt"
 
+	"github.com/kcp-dev/logicalcluster"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -38,6 +39,19 @@ type EventHandler struct {
 	EventHandler handler.EventHandler
 	Queue        workqueue.RateLimitingInterface
 	Predicates   []predicate.Predicate
+
+	// Filters are run on the objects before the events are built, see predicate.MetadataFilter.
+	Filters []predicate.MetadataFilter
+}
+
+// admits returns whether all the filters admit the object.
+func (e EventHandler) admits(obj client.Object) bool {
+	for _, f := range e.Filters {
+		if !f(obj) {
+			return false
+		}
+	}
+	return true
 }
 
 // OnAdd creates CreateEvent and calls Create on EventHandler.
@@ -46,7 +60,11 @@ func (e EventHandler) OnAdd(obj interface{}) {
 
 	// Pull Object out of the object
 	if o, ok := obj.(client.Object); ok {
+		if !e.admits(o) {
+			return
+		}
 		c.Object = o
+		c.Cluster = logicalcluster.From(o)
 	} else {
 		log.Error(nil, "OnAdd missing Object",
 			"object", obj, "type", fmt.Sprintf("%T", obj))
@@ -77,7 +95,11 @@ func (e EventHandler) OnUpdate(oldObj, newObj interface{}) {
 
 	// Pull Object out of the object
 	if o, ok := newObj.(client.Object); ok {
+		if !e.admits(u.ObjectOld) && !e.admits(o) {
+			return
+		}
 		u.ObjectNew = o
+		u.Cluster = logicalcluster.From(o)
 	} else {
 		log.Error(nil, "OnUpdate missing ObjectNew",
 			"object", newObj, "type", fmt.Sprintf("%T", newObj))
@@ -120,7 +142,11 @@ func (e EventHandler) OnDelete(obj interface{}) {
 
 	// Pull Object out of the object
 	if o, ok := obj.(client.Object); ok {
+		if !e.admits(o) {
+			return
+		}
 		d.Object = o
+		d.Cluster = logicalcluster.From(o)
 	} else {
 		log.Error(nil, "OnDelete missing Object",
 			"object", obj, "type", fmt.Sprintf("%T", obj))
@@ -100,6 +100,25 @@ var _ = Describe("Internal", func() {
 			instance.OnAdd(pod)
 		})
 
+		It("should stamp the events with the logical cluster of their object", func() {
+			pod.ClusterName = "root:org:ws"
+			newPod.ClusterName = "root:org:ws"
+			var clusters []string
+			funcs.CreateFunc = func(evt event.CreateEvent, q workqueue.RateLimitingInterface) {
+				clusters = append(clusters, evt.Cluster.String())
+			}
+			funcs.UpdateFunc = func(evt event.UpdateEvent, q workqueue.RateLimitingInterface) {
+				clusters = append(clusters, evt.Cluster.String())
+			}
+			funcs.DeleteFunc = func(evt event.DeleteEvent, q workqueue.RateLimitingInterface) {
+				clusters = append(clusters, evt.Cluster.String())
+			}
+			instance.OnAdd(pod)
+			instance.OnUpdate(pod, newPod)
+			instance.OnDelete(cache.DeletedFinalStateUnknown{Key: "root:org:ws|default/pod", Obj: pod})
+			Expect(clusters).To(Equal([]string{"root:org:ws", "root:org:ws", "root:org:ws"}))
+		})
+
 		It("should used Predicates to filter CreateEvents", func() {
 			instance = internal.EventHandler{
 				Queue:        controllertest.Queue{},
@@ -305,6 +324,51 @@ var _ = Describe("Internal", func() {
 			instance.OnUpdate(Foo{}, Foo{})
 			instance.OnDelete(Foo{})
 		})
+
+		It("should drop the events of the objects the Filters don't admit before the Predicates", func() {
+			instance = internal.EventHandler{
+				Queue:        controllertest.Queue{},
+				EventHandler: setfuncs,
+				Predicates: []predicate.Predicate{predicate.Funcs{
+					CreateFunc: func(event.CreateEvent) bool {
+						defer GinkgoRecover()
+						Fail("Did not expect the predicates to be called.")
+						return false
+					},
+				}},
+				Filters: []predicate.MetadataFilter{func(obj metav1.Object) bool {
+					return obj.GetLabels()["foo"] == "bar"
+				}},
+			}
+
+			set = false
+			instance.OnAdd(pod)
+			instance.OnDelete(pod)
+			instance.OnDelete(cache.DeletedFinalStateUnknown{Obj: pod})
+			instance.OnUpdate(pod, pod)
+			Expect(set).To(BeFalse())
+
+			instance.OnDelete(newPod)
+			Expect(set).To(BeTrue())
+		})
+
+		It("should admit the UpdateEvents of which either object is admitted by the Filters", func() {
+			instance = internal.EventHandler{
+				Queue:        controllertest.Queue{},
+				EventHandler: setfuncs,
+				Filters: []predicate.MetadataFilter{func(obj metav1.Object) bool {
+					return obj.GetLabels()["foo"] == "bar"
+				}},
+			}
+
+			set = false
+			instance.OnUpdate(pod, newPod)
+			Expect(set).To(BeTrue())
+
+			set = false
+			instance.OnUpdate(newPod, pod)
+			Expect(set).To(BeTrue())
+		})
 	})
 })
 
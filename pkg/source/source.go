@@ -23,8 +23,10 @@ import (
 	"sync"
 	"time"
 
+	"github.com/kcp-dev/logicalcluster"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/util/wait"
+	toolscache "k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
@@ -49,8 +51,15 @@ const (
 //
 // * Use Kind for events originating in the cluster (e.g. Pod Create, Pod Update, Deployment Update).
 //
+// * Use DirectWatch for events of extremely high-churn types originating in the cluster (e.g. Events, Leases),
+// without caching their objects.
+//
 // * Use Channel for events originating outside the cluster (eh.g. GitHub Webhook callback, Polling external urls).
 //
+// * Use ClusterSetKind for events originating in any of the clusters of a ClusterSet, including those added later.
+//
+// * Use Clusters for the logical clusters added to, removed from, or failing in a ClusterSet.
+//
 // Users may build their own Source implementations.  If their implementations implement any of the inject package
 // interfaces, the dependencies will be injected by the Controller when Watch is called.
 type Source interface {
@@ -66,6 +75,16 @@ type SyncingSource interface {
 	WaitForSync(ctx context.Context) error
 }
 
+// ResyncingSource is a source that can replay the objects it observes, e.g. so that a controller
+// reconciles all of them again when the configuration of its reconciler changes.
+type ResyncingSource interface {
+	Source
+
+	// Resync passes a GenericEvent for every object currently observed by the source to the
+	// handler, if the predicates admit it.  It fails if the source wasn't started.
+	Resync(handler handler.EventHandler, queue workqueue.RateLimitingInterface, prct ...predicate.Predicate) error
+}
+
 // NewKindWithCache creates a Source without InjectCache, so that it is assured that the given cache is used
 // and not overwritten. It can be used to watch objects in a different cluster by passing the cache
 // from that other cluster.
@@ -86,11 +105,54 @@ func (ks *kindWithCache) WaitForSync(ctx context.Context) error {
 	return ks.kind.WaitForSync(ctx)
 }
 
+func (ks *kindWithCache) Resync(handler handler.EventHandler, queue workqueue.RateLimitingInterface,
+	prct ...predicate.Predicate) error {
+	return ks.kind.Resync(handler, queue, prct...)
+}
+
+// ClusterKind creates a Source watching the objects of the given type in a single logical cluster,
+// e.g. the configuration of a management workspace, while the other watches of the controller span
+// all the clusters.  It uses the injected cache, and only passes the events, and the objects replayed
+// by Resync, of the objects of that cluster to the handler.
+func ClusterKind(cluster logicalcluster.Name, object client.Object) SyncingSource {
+	return &clusterKind{Kind: Kind{Type: object}, cluster: cluster}
+}
+
+// clusterKind is a Kind filtering the events of the objects of a logical cluster.
+type clusterKind struct {
+	Kind
+	cluster logicalcluster.Name
+}
+
+var _ ResyncingSource = &clusterKind{}
+
+func (ks *clusterKind) Start(ctx context.Context, handler handler.EventHandler, queue workqueue.RateLimitingInterface,
+	prct ...predicate.Predicate) error {
+	return ks.Kind.Start(ctx, handler, queue, ks.predicates(prct)...)
+}
+
+func (ks *clusterKind) Resync(handler handler.EventHandler, queue workqueue.RateLimitingInterface,
+	prct ...predicate.Predicate) error {
+	return ks.Kind.Resync(handler, queue, ks.predicates(prct)...)
+}
+
+func (ks *clusterKind) predicates(prct []predicate.Predicate) []predicate.Predicate {
+	return append([]predicate.Predicate{predicate.InClusters(ks.cluster)}, prct...)
+}
+
+func (ks *clusterKind) String() string {
+	return fmt.Sprintf("%s in cluster %s", ks.Kind.String(), ks.cluster)
+}
+
 // Kind is used to provide a source of events originating inside the cluster from Watches (e.g. Pod Create).
 type Kind struct {
 	// Type is the type of object to watch.  e.g. &v1.Pod{}
 	Type client.Object
 
+	// Filters drop the events of the objects they don't admit in the event handler of the
+	// informer, before the events are built and the predicates are run.
+	Filters []predicate.MetadataFilter
+
 	// cache used to watch APIs
 	cache cache.Cache
 
@@ -98,9 +160,14 @@ type Kind struct {
 	// contain an error, startup and syncing finished.
 	started     chan error
 	startCancel func()
+
+	// informer is the informer of Type, once it was got from the cache.
+	informerMu sync.Mutex
+	informer   cache.Informer
 }
 
 var _ SyncingSource = &Kind{}
+var _ ResyncingSource = &Kind{}
 
 // Start is internal and should be called only by the Controller to register an EventHandler with the Informer
 // to enqueue reconcile.Requests.
@@ -149,7 +216,10 @@ func (ks *Kind) Start(ctx context.Context, handler handler.EventHandler, queue w
 			return
 		}
 
-		i.AddEventHandler(internal.EventHandler{Queue: queue, EventHandler: handler, Predicates: prct})
+		i.AddEventHandler(internal.EventHandler{Queue: queue, EventHandler: handler, Predicates: prct, Filters: ks.Filters})
+		ks.informerMu.Lock()
+		ks.informer = i
+		ks.informerMu.Unlock()
 		if !ks.cache.WaitForCacheSync(ctx) {
 			// Would be great to return something more informative here
 			ks.started <- errors.New("cache did not sync")
@@ -179,6 +249,70 @@ func (ks *Kind) WaitForSync(ctx context.Context) error {
 	}
 }
 
+// Resync implements ResyncingSource, passing a GenericEvent for every object in the cache of
+// the informer of the Kind to the handler.
+func (ks *Kind) Resync(handler handler.EventHandler, queue workqueue.RateLimitingInterface,
+	prct ...predicate.Predicate) error {
+	ks.informerMu.Lock()
+	i := ks.informer
+	ks.informerMu.Unlock()
+	if i == nil {
+		return fmt.Errorf("%s was not started", ks)
+	}
+	storer, ok := i.(interface{ GetStore() toolscache.Store })
+	if !ok {
+		return fmt.Errorf("the informer of %s doesn't expose its store", ks)
+	}
+
+	store := storer.GetStore()
+	if store == nil {
+		return fmt.Errorf("the informer of %s has no store", ks)
+	}
+	for _, obj := range store.List() {
+		o, ok := obj.(client.Object)
+		if !ok || !ks.admits(o) {
+			continue
+		}
+		evt := event.GenericEvent{Object: o, Cluster: logicalcluster.From(o)}
+		if admitsGeneric(evt, prct) {
+			handler.Generic(evt, queue)
+		}
+	}
+	return nil
+}
+
+// admits returns whether all the filters of the Kind admit the object.
+func (ks *Kind) admits(obj client.Object) bool {
+	for _, f := range ks.Filters {
+		if !f(obj) {
+			return false
+		}
+	}
+	return true
+}
+
+// admitsGeneric returns whether all the predicates admit the GenericEvent.
+func admitsGeneric(evt event.GenericEvent, prct []predicate.Predicate) bool {
+	for _, p := range prct {
+		if !p.Generic(evt) {
+			return false
+		}
+	}
+	return true
+}
+
+// LastSyncResourceVersion returns the resourceVersion the informer of the Kind last observed,
+// or "" if it is not known yet, or if the informer doesn't expose it.
+func (ks *Kind) LastSyncResourceVersion() string {
+	ks.informerMu.Lock()
+	i := ks.informer
+	ks.informerMu.Unlock()
+	if rv, ok := i.(interface{ LastSyncResourceVersion() string }); ok {
+		return rv.LastSyncResourceVersion()
+	}
+	return ""
+}
+
 var _ inject.Cache = &Kind{}
 
 // InjectCache is internal should be called only by the Controller.  InjectCache is used to inject
@@ -266,6 +400,9 @@ func (cs *Channel) Start(
 
 	go func() {
 		for evt := range dst {
+			if evt.Cluster.Empty() && evt.Object != nil {
+				evt.Cluster = logicalcluster.From(evt.Object)
+			}
 			shouldHandle := true
 			for _, p := range prct {
 				if !p.Generic(evt) {
@@ -21,17 +21,26 @@ import (
 	"fmt"
 	"time"
 
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	coordinationv1 "k8s.io/api/coordination/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/util/workqueue"
 )
 
@@ -252,6 +261,195 @@ var _ = Describe("Source", func() {
 		})
 	})
 
+	Describe("ClusterKind", func() {
+		It("should only pass the events and the resynced objects of its logical cluster to the handler", func() {
+			a, b := logicalcluster.New("root:a"), logicalcluster.New("root:b")
+			ic := &informertest.FakeInformers{}
+			q := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test")
+			instance := source.ClusterKind(a, &corev1.ConfigMap{})
+			Expect(inject.CacheInto(ic, instance)).To(BeTrue())
+			Expect(fmt.Sprint(instance)).To(Equal("kind source: *v1.ConfigMap in cluster root:a"))
+
+			var created, resynced []string
+			h := handler.Funcs{
+				CreateFunc: func(evt event.CreateEvent, _ workqueue.RateLimitingInterface) {
+					created = append(created, evt.Object.GetName())
+				},
+				GenericFunc: func(evt event.GenericEvent, _ workqueue.RateLimitingInterface) {
+					resynced = append(resynced, evt.Object.GetName())
+				},
+			}
+			Expect(instance.Start(ctx, h, q)).To(Succeed())
+			Expect(instance.WaitForSync(context.Background())).To(Succeed())
+
+			i, err := ic.FakeInformerFor(&corev1.ConfigMap{})
+			Expect(err).NotTo(HaveOccurred())
+			i.Add(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "ns", ClusterName: a.String()}})
+			i.Add(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "tenant", Namespace: "ns", ClusterName: b.String()}})
+			Expect(created).To(Equal([]string{"config"}))
+
+			Expect(instance.(source.ResyncingSource).Resync(h, q)).To(Succeed())
+			Expect(resynced).To(Equal([]string{"config"}))
+		})
+	})
+
+	Describe("DirectWatch", func() {
+		It("should pass the events of the watched logical cluster to the handler", func() {
+			a, b := logicalcluster.New("root:a"), logicalcluster.New("root:b")
+			existing := &coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "ns"}}
+			c := fake.NewClusterBuilder().WithObjects(a, existing).Build()
+
+			events := make(chan string, 10)
+			q := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test")
+			instance := &source.DirectWatch{Type: &coordinationv1.Lease{}, Cluster: a, Client: c}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			Expect(instance.Start(ctx, handler.Funcs{
+				CreateFunc: func(evt event.CreateEvent, _ workqueue.RateLimitingInterface) {
+					events <- "create " + evt.Cluster.String() + "/" + evt.Object.GetName()
+				},
+				UpdateFunc: func(evt event.UpdateEvent, _ workqueue.RateLimitingInterface) {
+					events <- "update " + evt.Cluster.String() + "/" + evt.ObjectOld.GetName() + "/" + evt.ObjectNew.GetName()
+				},
+				DeleteFunc: func(evt event.DeleteEvent, _ workqueue.RateLimitingInterface) {
+					events <- "delete " + evt.Cluster.String() + "/" + evt.Object.GetName()
+				},
+			}, q, predicate.NewPredicateFuncs(func(obj client.Object) bool { return obj.GetName() != "ignored" }))).To(Succeed())
+			Expect(instance.WaitForSync(ctx)).To(Succeed())
+
+			inA, inB := kcpclient.WithCluster(ctx, a), kcpclient.WithCluster(ctx, b)
+			lease := &coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Name: "lease", Namespace: "ns"}}
+			Expect(c.Create(inA, lease)).To(Succeed())
+			Expect(c.Create(inA, &coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Name: "ignored", Namespace: "ns"}})).To(Succeed())
+			Expect(c.Create(inB, &coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "ns"}})).To(Succeed())
+			holder := "me"
+			lease.Spec.HolderIdentity = &holder
+			Expect(c.Update(inA, lease)).To(Succeed())
+			Expect(c.Delete(inA, lease)).To(Succeed())
+
+			Eventually(events).Should(Receive(Equal("create root:a/lease")))
+			Eventually(events).Should(Receive(Equal("update root:a/lease/lease")))
+			Eventually(events).Should(Receive(Equal("delete root:a/lease")))
+			Consistently(events).ShouldNot(Receive())
+		})
+
+		It("should require a type", func() {
+			instance := &source.DirectWatch{}
+			Expect(instance.Start(context.Background(), handler.Funcs{}, nil)).NotTo(Succeed())
+		})
+	})
+
+	Describe("Clusters", func() {
+		It("should pass the logical clusters added to and removed from the set to the handler", func() {
+			set, err := cluster.NewClusterSet(&rest.Config{Host: "https://kcp.example.com"}, cluster.ClusterSetOptions{}, func(o *cluster.Options) {
+				o.MapperProvider = func(*rest.Config) (meta.RESTMapper, error) { return meta.NewDefaultRESTMapper(nil), nil }
+			})
+			Expect(err).NotTo(HaveOccurred())
+			a, b := logicalcluster.New("root:a"), logicalcluster.New("root:b")
+			_, err = set.Add(a)
+			Expect(err).NotTo(HaveOccurred())
+
+			events := make(chan string, 10)
+			record := func(kind string, obj client.Object) {
+				events <- kind + " " + logicalcluster.From(obj).String() + "/" + obj.GetName()
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			instance := source.Clusters(set)
+			Expect(instance.Start(ctx, handler.Funcs{
+				CreateFunc: func(evt event.CreateEvent, _ workqueue.RateLimitingInterface) { record("create", evt.Object) },
+				DeleteFunc: func(evt event.DeleteEvent, _ workqueue.RateLimitingInterface) { record("delete", evt.Object) },
+			}, nil, predicate.Funcs{
+				CreateFunc: func(evt event.CreateEvent) bool { return logicalcluster.From(evt.Object) != b },
+			})).To(Succeed())
+			Expect(events).To(Receive(Equal("create root:a/root:a")))
+
+			_, err = set.Add(b)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(set.Remove(a)).To(BeTrue())
+			Expect(events).To(Receive(Equal("delete root:a/root:a")))
+			Expect(events).NotTo(Receive())
+
+			cancel()
+			Expect(set.Remove(b)).To(BeTrue())
+			Expect(events).NotTo(Receive())
+		})
+
+		It("should require a ClusterSet", func() {
+			Expect(source.Clusters(nil).Start(context.Background(), handler.Funcs{}, nil)).NotTo(Succeed())
+		})
+	})
+
+	Describe("ClusterSetKind", func() {
+		It("should pass the events of the clusters of the set, including those added later, to the handler", func() {
+			caches := map[string]*informertest.FakeInformers{}
+			set, err := cluster.NewClusterSet(&rest.Config{Host: "https://kcp.example.com"}, cluster.ClusterSetOptions{}, func(o *cluster.Options) {
+				o.MapperProvider = func(*rest.Config) (meta.RESTMapper, error) { return meta.NewDefaultRESTMapper(nil), nil }
+				o.NewCache = func(config *rest.Config, _ cache.Options) (cache.Cache, error) {
+					caches[config.Host] = &informertest.FakeInformers{}
+					return caches[config.Host], nil
+				}
+			})
+			Expect(err).NotTo(HaveOccurred())
+			a, b := logicalcluster.New("root:a"), logicalcluster.New("root:b")
+			_, err = set.Add(a)
+			Expect(err).NotTo(HaveOccurred())
+			add := func(name logicalcluster.Name, obj *corev1.ConfigMap) {
+				i, err := caches["https://kcp.example.com/clusters/"+name.String()].FakeInformerFor(&corev1.ConfigMap{})
+				Expect(err).NotTo(HaveOccurred())
+				i.Add(obj)
+			}
+
+			events := make(chan string, 10)
+			h := handler.Funcs{
+				CreateFunc: func(evt event.CreateEvent, _ workqueue.RateLimitingInterface) {
+					events <- "create " + logicalcluster.From(evt.Object).String() + "/" + evt.Object.GetName()
+				},
+				GenericFunc: func(evt event.GenericEvent, _ workqueue.RateLimitingInterface) {
+					events <- "resync " + logicalcluster.From(evt.Object).String() + "/" + evt.Object.GetName()
+				},
+			}
+			q := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test")
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			instance := source.ClusterSetKind(set, &corev1.ConfigMap{})
+			Expect(instance.Start(ctx, h, q)).To(Succeed())
+			Expect(instance.WaitForSync(ctx)).To(Succeed())
+			add(a, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "ns", ClusterName: a.String()}})
+			Expect(events).To(Receive(Equal("create root:a/config")))
+
+			_, err = set.Add(b)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(instance.WaitForSync(ctx)).To(Succeed())
+			add(b, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "tenant", Namespace: "ns", ClusterName: b.String()}})
+			Expect(events).To(Receive(Equal("create root:b/tenant")))
+
+			Expect(set.Remove(a)).To(BeTrue())
+			add(a, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "removed", Namespace: "ns", ClusterName: a.String()}})
+			Expect(instance.(source.ResyncingSource).Resync(h, q)).To(Succeed())
+			Expect(events).To(Receive(Equal("resync root:b/tenant")))
+			Expect(events).NotTo(Receive())
+
+			By("removing the handlers once the context is done")
+			cancel()
+			Eventually(func() bool {
+				add(b, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "stopped", Namespace: "ns", ClusterName: b.String()}})
+				select {
+				case <-events:
+					return false
+				default:
+					return true
+				}
+			}).Should(BeTrue())
+		})
+
+		It("should require a ClusterSet and a type", func() {
+			Expect(source.ClusterSetKind(nil, &corev1.ConfigMap{}).Start(context.Background(), handler.Funcs{}, nil)).NotTo(Succeed())
+			set, err := cluster.NewClusterSet(&rest.Config{Host: "https://kcp.example.com"}, cluster.ClusterSetOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(source.ClusterSetKind(set, nil).Start(context.Background(), handler.Funcs{}, nil)).NotTo(Succeed())
+		})
+	})
+
 	Describe("Func", func() {
 		It("should be called from Start", func() {
 			run := false
@@ -0,0 +1,292 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/workqueue"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
+	"sigs.k8s.io/controller-runtime/pkg/source/internal"
+)
+
+// watchRestartPeriod is the period with which a DirectWatch restarts a failed watch.
+var watchRestartPeriod = time.Second
+
+// DirectWatch is used to provide a source of events delivered straight from a watch of the
+// API server, without an informer nor a store, for extremely high-churn types, e.g. Events or
+// Leases, whose objects controllers react to without reading them back from the cache.
+//
+// As there is no store, the ObjectOld of update events is the new object, there are no events
+// for the objects existing when the watch starts, and events are missed when an expired watch
+// is restarted.
+type DirectWatch struct {
+	// Type is the type of object to watch.  e.g. &coordinationv1.Lease{}
+	Type client.Object
+
+	// Cluster is the logical cluster to watch, or logicalcluster.Wildcard to watch all of them.
+	// Defaults to the logical cluster of the context the source is started with, if any.
+	Cluster logicalcluster.Name
+
+	// ListOptions select the watched objects, e.g. client.InNamespace.
+	ListOptions []client.ListOption
+
+	// Client watches the objects.  Defaults to a client built from the config of the manager,
+	// which sends the requests to Cluster if it is set.
+	Client client.WithWatch
+
+	config *rest.Config
+	scheme *runtime.Scheme
+	mapper meta.RESTMapper
+
+	// started is closed once the first watch is established, or gets the error which
+	// prevented it.
+	started chan error
+}
+
+var _ SyncingSource = &DirectWatch{}
+
+// Start is internal and should be called only by the Controller to start watching the objects and
+// pass their events to the EventHandler.
+func (ds *DirectWatch) Start(ctx context.Context, handler handler.EventHandler, queue workqueue.RateLimitingInterface,
+	prct ...predicate.Predicate) error {
+	// Type should have been specified by the user.
+	if ds.Type == nil {
+		return fmt.Errorf("must specify DirectWatch.Type")
+	}
+
+	cluster := ds.Cluster
+	if cluster.Empty() {
+		cluster, _ = kcpclient.ClusterFromContext(ctx)
+	}
+	if !cluster.Empty() {
+		ctx = kcpclient.WithCluster(ctx, cluster)
+	}
+
+	if ds.Client == nil {
+		c, err := ds.newClient(!cluster.Empty())
+		if err != nil {
+			return err
+		}
+		ds.Client = c
+	}
+
+	list, err := ds.newList()
+	if err != nil {
+		return err
+	}
+
+	ds.started = make(chan error)
+	go ds.run(ctx, list, internal.EventHandler{Queue: queue, EventHandler: handler, Predicates: prct})
+	return nil
+}
+
+// newClient builds a watching client from the injected dependencies, sending the requests
+// to the logical cluster of their context if routed.
+func (ds *DirectWatch) newClient(routed bool) (client.WithWatch, error) {
+	if ds.config == nil {
+		return nil, fmt.Errorf("must specify DirectWatch.Client or call ConfigInto on DirectWatch before calling Start")
+	}
+	opts := client.Options{Scheme: ds.scheme, Mapper: ds.mapper}
+	if routed {
+		httpClient, err := rest.HTTPClientFor(ds.config)
+		if err != nil {
+			return nil, err
+		}
+		transport := httpClient.Transport
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		httpClient.Transport = kcpclient.NewClusterRoundTripper(transport)
+		opts.HTTPClient = httpClient
+	}
+	return client.NewWithWatch(ds.config, opts)
+}
+
+// newList returns an empty list of the type of the watched objects.
+func (ds *DirectWatch) newList() (client.ObjectList, error) {
+	scheme := ds.scheme
+	if scheme == nil {
+		scheme = ds.Client.Scheme()
+	}
+	gvk, err := apiutil.GVKForObject(ds.Type, scheme)
+	if err != nil {
+		return nil, err
+	}
+	listGVK := gvk.GroupVersion().WithKind(gvk.Kind + "List")
+	switch ds.Type.(type) {
+	case *unstructured.Unstructured:
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(listGVK)
+		return list, nil
+	case *metav1.PartialObjectMetadata:
+		list := &metav1.PartialObjectMetadataList{}
+		list.SetGroupVersionKind(listGVK)
+		return list, nil
+	}
+	obj, err := scheme.New(listGVK)
+	if err != nil {
+		return nil, err
+	}
+	list, ok := obj.(client.ObjectList)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a list", obj)
+	}
+	return list, nil
+}
+
+// run watches the objects until the context is done, restarting the watches which end.
+func (ds *DirectWatch) run(ctx context.Context, list client.ObjectList, h internal.EventHandler) {
+	var resourceVersion string
+	first := true
+	wait.JitterUntilWithContext(ctx, func(ctx context.Context) {
+		if resourceVersion == "" {
+			// start watching from the current state, without an event for every existing object.
+			current := list.DeepCopyObject().(client.ObjectList)
+			if err := ds.Client.List(ctx, current, append(ds.ListOptions, client.Limit(1))...); err != nil {
+				log.Error(err, "failed to get the resource version to watch from", "source", ds)
+				return
+			}
+			resourceVersion = current.GetResourceVersion()
+		}
+
+		opts := append(append([]client.ListOption(nil), ds.ListOptions...),
+			&client.ListOptions{Raw: &metav1.ListOptions{ResourceVersion: resourceVersion, AllowWatchBookmarks: true}})
+		w, err := ds.Client.Watch(ctx, list.DeepCopyObject().(client.ObjectList), opts...)
+		if err != nil {
+			log.Error(err, "failed to watch", "source", ds)
+			if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+				resourceVersion = ""
+			}
+			return
+		}
+		if first {
+			close(ds.started)
+			first = false
+		}
+		resourceVersion = ds.handle(ctx, w, resourceVersion, h)
+	}, watchRestartPeriod, 1.0, true)
+}
+
+// handle passes the events of the watch to the handler until the watch or the context ends,
+// and returns the resource version to restart watching from.
+func (ds *DirectWatch) handle(ctx context.Context, w watch.Interface, resourceVersion string, h internal.EventHandler) string {
+	defer w.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return resourceVersion
+		case evt, ok := <-w.ResultChan():
+			if !ok {
+				return resourceVersion
+			}
+			if evt.Type == watch.Error {
+				err := apierrors.FromObject(evt.Object)
+				if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+					return ""
+				}
+				log.Error(err, "watch failed", "source", ds)
+				return resourceVersion
+			}
+
+			obj, ok := evt.Object.(client.Object)
+			if !ok {
+				log.Error(nil, "watch event missing Object", "source", ds, "type", fmt.Sprintf("%T", evt.Object))
+				continue
+			}
+			if rv := obj.GetResourceVersion(); rv != "" {
+				resourceVersion = rv
+			}
+			ds.setKind(obj)
+			switch evt.Type {
+			case watch.Added:
+				h.OnAdd(obj)
+			case watch.Modified:
+				h.OnUpdate(obj, obj)
+			case watch.Deleted:
+				h.OnDelete(obj)
+			}
+		}
+	}
+}
+
+// setKind restores the kind of the watched metadata-only objects, which is lost when decoding.
+func (ds *DirectWatch) setKind(obj client.Object) {
+	if _, ok := obj.(*metav1.PartialObjectMetadata); ok && obj.GetObjectKind().GroupVersionKind().Empty() {
+		obj.GetObjectKind().SetGroupVersionKind(ds.Type.GetObjectKind().GroupVersionKind())
+	}
+}
+
+func (ds *DirectWatch) String() string {
+	if ds.Type != nil {
+		return fmt.Sprintf("direct watch source: %T", ds.Type)
+	}
+	return "direct watch source: unknown type"
+}
+
+// WaitForSync implements SyncingSource to allow controllers to wait with starting
+// workers until the first watch is established.
+func (ds *DirectWatch) WaitForSync(ctx context.Context) error {
+	select {
+	case err := <-ds.started:
+		return err
+	case <-ctx.Done():
+		return errors.New("timed out waiting for the watch to be established")
+	}
+}
+
+var _ inject.Config = &DirectWatch{}
+var _ inject.Scheme = &DirectWatch{}
+var _ inject.Mapper = &DirectWatch{}
+
+// InjectConfig is internal should be called only by the Controller.  It injects the config
+// the default client is built from.
+func (ds *DirectWatch) InjectConfig(config *rest.Config) error {
+	ds.config = config
+	return nil
+}
+
+// InjectScheme is internal should be called only by the Controller.
+func (ds *DirectWatch) InjectScheme(scheme *runtime.Scheme) error {
+	ds.scheme = scheme
+	return nil
+}
+
+// InjectMapper is internal should be called only by the Controller.
+func (ds *DirectWatch) InjectMapper(mapper meta.RESTMapper) error {
+	ds.mapper = mapper
+	return nil
+}
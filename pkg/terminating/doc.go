@@ -0,0 +1,27 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package terminating backstops the objects stuck terminating, e.g. because the controller
+owning one of their finalizers is gone from the logical cluster they live in.
+
+NewReconciler returns a reconcile.Reconciler which reports the objects that have been
+deleted for longer than a threshold without going away: it records a warning event on them,
+counts them in the controller_runtime_stuck_terminating_objects metric, and calls an optional
+callback, e.g. to remove a finalizer known to be stale.  Add runs it for one kind of objects
+of all the logical clusters of a manager.
+*/
+package terminating
@@ -0,0 +1,32 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terminating
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// stuckObjects is the number of objects stuck terminating.
+var stuckObjects = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "controller_runtime_stuck_terminating_objects",
+	Help: "Number of objects deleted for longer than the threshold without going away per kind",
+}, []string{"kind"})
+
+func init() {
+	metrics.Registry.MustRegister(stuckObjects)
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terminating
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+	"k8s.io/client-go/tools/record"
+
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// ReasonStuckTerminating is the reason of the events recorded on the objects stuck terminating.
+const ReasonStuckTerminating = "StuckTerminating"
+
+// StuckFunc is called with an object stuck terminating for the given duration, in the logical
+// cluster of the object.  It may e.g. remove a finalizer known to be stale.
+type StuckFunc func(ctx context.Context, obj client.Object, stuckFor time.Duration) error
+
+// Options configures the Reconciler returned by NewReconciler.
+type Options struct {
+	// Threshold is how long an object may be terminating before it's reported as stuck.
+	// Defaults to 1 hour.
+	Threshold time.Duration
+
+	// RecheckInterval is how often the objects stuck terminating are reported again.
+	// Defaults to 10 minutes.
+	RecheckInterval time.Duration
+
+	// Recorder records a warning event on the objects stuck terminating.  No event is
+	// recorded if it's nil.
+	Recorder record.EventRecorder
+
+	// OnStuck is called with the objects stuck terminating, each time they are reported.
+	OnStuck StuckFunc
+}
+
+// NewReconciler returns a reconcile.Reconciler reporting the objects built by newObject which are
+// stuck terminating, read with the client c.  kind names the objects in the metrics, e.g.
+// "Widget.example.com".
+//
+// An object is stuck once its deletion timestamp is older than the threshold.  It is reported
+// then, and every RecheckInterval until it goes away.
+func NewReconciler(c client.Client, kind string, newObject func() client.Object, opts Options) reconcile.Reconciler {
+	if opts.Threshold <= 0 {
+		opts.Threshold = time.Hour
+	}
+	if opts.RecheckInterval <= 0 {
+		opts.RecheckInterval = 10 * time.Minute
+	}
+	return &reconciler{client: c, kind: kind, newObject: newObject, opts: opts, now: time.Now, stuck: map[string]struct{}{}}
+}
+
+var _ reconcile.Reconciler = &reconciler{}
+
+type reconciler struct {
+	client    client.Client
+	kind      string
+	newObject func() client.Object
+	opts      Options
+	now       func() time.Time
+
+	mu sync.Mutex
+	// stuck are the keys of the objects stuck terminating.
+	stuck map[string]struct{}
+}
+
+// Reconcile implements reconcile.Reconciler.
+func (r *reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	log := logf.FromContext(ctx)
+
+	obj := r.newObject()
+	if err := r.client.Get(ctx, req.ObjectKey, obj); err != nil {
+		r.setStuck(req, false)
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	if obj.GetDeletionTimestamp().IsZero() {
+		r.setStuck(req, false)
+		return reconcile.Result{}, nil
+	}
+
+	stuckFor := r.now().Sub(obj.GetDeletionTimestamp().Time)
+	if stuckFor < r.opts.Threshold {
+		r.setStuck(req, false)
+		return reconcile.Result{RequeueAfter: r.opts.Threshold - stuckFor}, nil
+	}
+
+	r.setStuck(req, true)
+	log.Info("Object is stuck terminating", "stuckFor", stuckFor, "finalizers", obj.GetFinalizers())
+	if r.opts.Recorder != nil {
+		r.opts.Recorder.Eventf(obj, "Warning", ReasonStuckTerminating, "Object has been terminating for %s, pending finalizers: %s",
+			stuckFor.Round(time.Second), strings.Join(obj.GetFinalizers(), ", "))
+	}
+	if r.opts.OnStuck != nil {
+		cluster := req.Cluster
+		if cluster.Empty() {
+			cluster = logicalcluster.From(obj)
+		}
+		if err := r.opts.OnStuck(ctx, obj, stuckFor); err != nil {
+			return reconcile.Result{}, fmt.Errorf("failed to handle object stuck terminating in logical cluster %s: %w", cluster, err)
+		}
+	}
+	return reconcile.Result{RequeueAfter: r.opts.RecheckInterval}, nil
+}
+
+// setStuck records whether the object of the request is stuck, and updates the metric.
+func (r *reconciler) setStuck(req reconcile.Request, stuck bool) {
+	key := req.Cluster.String() + "|" + req.String()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if stuck {
+		r.stuck[key] = struct{}{}
+	} else {
+		delete(r.stuck, key)
+	}
+	stuckObjects.WithLabelValues(r.kind).Set(float64(len(r.stuck)))
+}
+
+// Add adds to the manager a controller reporting the objects of the kind of obj which are stuck
+// terminating in any logical cluster.  The events are recorded with the recorder of the manager,
+// unless opts.Recorder is set.
+func Add(mgr manager.Manager, obj client.Object, opts Options) error {
+	gvk, err := apiutil.GVKForObject(obj, mgr.GetScheme())
+	if err != nil {
+		return err
+	}
+	kind := gvk.GroupKind().String()
+	name := "stuck-terminating-" + strings.ToLower(strings.ReplaceAll(kind, ".", "-"))
+	if opts.Recorder == nil {
+		opts.Recorder = mgr.GetEventRecorderFor(name)
+	}
+
+	newObject := func() client.Object {
+		return obj.DeepCopyObject().(client.Object)
+	}
+	terminating := predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return !e.Object.GetDeletionTimestamp().IsZero() },
+		UpdateFunc:  func(e event.UpdateEvent) bool { return !e.ObjectNew.GetDeletionTimestamp().IsZero() },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return true },
+		GenericFunc: func(e event.GenericEvent) bool { return !e.Object.GetDeletionTimestamp().IsZero() },
+	}
+	return builder.ControllerManagedBy(mgr).
+		Named(name).
+		For(obj, builder.WithPredicates(terminating)).
+		Complete(NewReconciler(mgr.GetClient(), kind, newObject, opts))
+}
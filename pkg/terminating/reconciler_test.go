@@ -0,0 +1,105 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terminating_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/terminating"
+)
+
+var _ = Describe("terminating.NewReconciler", func() {
+	const finalizer = "example.com/stale"
+	ctx := context.Background()
+	cluster := logicalcluster.New("root:org:ws")
+	req := reconcile.Request{ObjectKey: client.ObjectKey{
+		NamespacedName: types.NamespacedName{Namespace: "ns", Name: "cm"},
+		Cluster:        cluster,
+	}}
+	newObject := func() client.Object { return &corev1.ConfigMap{} }
+
+	var recorder *record.FakeRecorder
+	BeforeEach(func() {
+		recorder = record.NewFakeRecorder(10)
+	})
+
+	newClient := func(deletedAgo time.Duration) client.Client {
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "cm", Finalizers: []string{finalizer}}}
+		if deletedAgo > 0 {
+			cm.DeletionTimestamp = &metav1.Time{Time: time.Now().Add(-deletedAgo)}
+		}
+		return fake.NewClusterBuilder().
+			WithRESTMapper(meta.NewDefaultRESTMapper([]schema.GroupVersion{corev1.SchemeGroupVersion})).
+			WithObjects(cluster, cm).
+			Build()
+	}
+
+	It("should ignore the objects which aren't terminating", func() {
+		r := terminating.NewReconciler(newClient(0), "ConfigMap", newObject, terminating.Options{Recorder: recorder})
+		Expect(r.Reconcile(ctx, req)).To(Equal(reconcile.Result{}))
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("should recheck the objects once they reach the threshold", func() {
+		r := terminating.NewReconciler(newClient(10*time.Minute), "ConfigMap", newObject, terminating.Options{Recorder: recorder})
+		result, err := r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeNumerically("~", 50*time.Minute, time.Minute))
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("should report the objects stuck terminating and call OnStuck", func() {
+		cl := newClient(2 * time.Hour)
+		var stuckFor time.Duration
+		r := terminating.NewReconciler(cl, "ConfigMap.test", newObject, terminating.Options{
+			Recorder:        recorder,
+			RecheckInterval: time.Minute,
+			OnStuck: func(ctx context.Context, obj client.Object, d time.Duration) error {
+				stuckFor = d
+				controllerutil.RemoveFinalizer(obj, finalizer)
+				return cl.Update(ctx, obj)
+			},
+		})
+
+		Expect(r.Reconcile(ctx, req)).To(Equal(reconcile.Result{RequeueAfter: time.Minute}))
+		Expect(stuckFor).To(BeNumerically("~", 2*time.Hour, time.Minute))
+		Expect(recorder.Events).To(Receive(And(ContainSubstring(terminating.ReasonStuckTerminating), ContainSubstring(finalizer))))
+		Expect(testutil.GatherAndCount(metrics.Registry, "controller_runtime_stuck_terminating_objects")).To(BeNumerically(">=", 1))
+
+		By("checking the object went away once its finalizer was removed")
+		Expect(apierrors.IsNotFound(cl.Get(ctx, req.ObjectKey, &corev1.ConfigMap{}))).To(BeTrue())
+		Expect(r.Reconcile(ctx, req)).To(Equal(reconcile.Result{}))
+	})
+})
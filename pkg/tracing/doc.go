@@ -0,0 +1,34 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package tracing traces the reconciles of controllers and the calls of their clients with
+OpenTelemetry, so that the API calls a reconcile makes to the logical clusters it reads and
+writes can be followed end to end.
+
+Set in the options of the manager, an OpenTelemetry TracerProvider makes the controllers start
+a span around each reconcile, with the name of the controller, the logical cluster, kind and
+key of the request and its reconcileID, and the client of the manager start a child span
+around each of its calls.  The requests of the client to the API server carry the span in
+their traceparent header, see WrapTransport, so that the spans of the API server join the
+trace:
+
+	mgr, err := manager.New(cfg, manager.Options{TracerProvider: otel.GetTracerProvider()})
+
+The reconcileID of the reconcile is also passed in its context, see ReconcileIDFrom, so that
+it can be set on the requests the Reconciler makes to other services.
+*/
+package tracing
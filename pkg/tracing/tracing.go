@@ -0,0 +1,92 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/client-go/rest"
+)
+
+// The keys of the attributes of the spans.
+const (
+	// AttributeController is the name of the controller of a reconcile.
+	AttributeController = attribute.Key("controller")
+
+	// AttributeReconcileID tells apart the successive reconciles of a request, as in the logs.
+	AttributeReconcileID = attribute.Key("reconcile.id")
+
+	// AttributeCluster is the logical cluster of the request or of the call.
+	AttributeCluster = attribute.Key("kcp.cluster")
+
+	// AttributeGroupVersionKind is the kind of the reconciled or requested object.
+	AttributeGroupVersionKind = attribute.Key("k8s.gvk")
+
+	// AttributeNamespace and AttributeName are the key of the reconciled or requested object.
+	AttributeNamespace = attribute.Key("k8s.namespace")
+	AttributeName      = attribute.Key("k8s.name")
+)
+
+// propagator injects the span of the context of the requests to the API server in their
+// headers, in the W3C trace context format, i.e. traceparent, along with their baggage.
+var propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// End records the error of an operation, if any, on its span, marking it as failed, and
+// ends the span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// WrapTransport returns a round tripper starting a client span around each request with the
+// Tracer of the provider, and propagating the span of the context of the request, e.g. the
+// span of a reconcile or of a call of a client, to the API server.
+func WrapTransport(provider trace.TracerProvider, rt http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(rt, otelhttp.WithTracerProvider(provider), otelhttp.WithPropagators(propagator))
+}
+
+// WrapConfig returns a copy of a rest config whose transport is wrapped with WrapTransport.
+func WrapConfig(config *rest.Config, provider trace.TracerProvider) *rest.Config {
+	config = rest.CopyConfig(config)
+	config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return WrapTransport(provider, rt)
+	})
+	return config
+}
+
+type reconcileIDKey struct{}
+
+// WithReconcileID returns a context carrying the reconcileID of a reconcile.
+func WithReconcileID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, reconcileIDKey{}, id)
+}
+
+// ReconcileIDFrom returns the reconcileID of the reconcile of the context, or an empty
+// string if there is none.
+func ReconcileIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(reconcileIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/oteltest"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/client-go/rest"
+)
+
+var _ = Describe("End", func() {
+	It("should record the error of the operation on its span", func() {
+		sr := new(oteltest.SpanRecorder)
+		tracer := oteltest.NewTracerProvider(oteltest.WithSpanRecorder(sr)).Tracer("test")
+
+		_, span := tracer.Start(context.Background(), "succeeded")
+		End(span, nil)
+		_, span = tracer.Start(context.Background(), "failed")
+		End(span, errors.New("failed"))
+
+		spans := sr.Completed()
+		Expect(spans).To(HaveLen(2))
+		Expect(spans[0].StatusCode()).To(Equal(codes.Unset))
+		Expect(spans[1].StatusCode()).To(Equal(codes.Error))
+		Expect(spans[1].StatusMessage()).To(Equal("failed"))
+		Expect(spans[1].Events()).To(HaveLen(1))
+	})
+})
+
+var _ = Describe("WrapConfig", func() {
+	It("should propagate the span of the context of the requests in their traceparent header", func() {
+		headers := make(chan http.Header, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			headers <- r.Header
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		sr := new(oteltest.SpanRecorder)
+		provider := oteltest.NewTracerProvider(oteltest.WithSpanRecorder(sr))
+		config := &rest.Config{Host: server.URL}
+		wrapped := WrapConfig(config, provider)
+		Expect(config.WrapTransport).To(BeNil())
+
+		ctx, parent := provider.Tracer("test").Start(context.Background(), "reconcile")
+		client, err := rest.HTTPClientFor(wrapped)
+		Expect(err).NotTo(HaveOccurred())
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api", nil)
+		Expect(err).NotTo(HaveOccurred())
+		resp, err := client.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Body.Close()).To(Succeed())
+		parent.End()
+
+		var header http.Header
+		Eventually(headers).Should(Receive(&header))
+		Expect(header.Get("traceparent")).To(ContainSubstring(parent.SpanContext().TraceID().String()))
+
+		spans := sr.Completed()
+		Expect(spans).To(HaveLen(2))
+		Expect(spans[0].SpanKind()).To(Equal(trace.SpanKindClient))
+		Expect(spans[0].ParentSpanID()).To(Equal(parent.SpanContext().SpanID()))
+		Expect(header.Get("traceparent")).To(ContainSubstring(spans[0].SpanContext().SpanID().String()))
+	})
+})
+
+var _ = Describe("ReconcileIDFrom", func() {
+	It("should return the reconcileID of the context", func() {
+		Expect(ReconcileIDFrom(context.Background())).To(BeEmpty())
+		Expect(ReconcileIDFrom(WithReconcileID(context.Background(), "id"))).To(Equal("id"))
+	})
+})
@@ -0,0 +1,146 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ClusterAnnotation is the annotation holding the logical cluster of the objects sent
+// to the webhooks by kcp.
+const ClusterAnnotation = "kcp.dev/cluster"
+
+// clustersPathPrefix prefixes the paths of the admission requests of a logical cluster,
+// i.e. /clusters/<name>/<webhook path>.
+const clustersPathPrefix = "/clusters/"
+
+// ClusterFromPath returns the logical cluster of a request path of the form
+// /clusters/<name>/<webhook path>, along with the webhook path, and whether the path
+// had this form.
+func ClusterFromPath(path string) (logicalcluster.Name, string, bool) {
+	if !strings.HasPrefix(path, clustersPathPrefix) {
+		return logicalcluster.Name{}, path, false
+	}
+	rest := strings.TrimPrefix(path, clustersPathPrefix)
+	i := strings.Index(rest, "/")
+	if i <= 0 {
+		return logicalcluster.Name{}, path, false
+	}
+	return logicalcluster.New(rest[:i]), rest[i:], true
+}
+
+// requestCluster returns the logical cluster of the admission request: the one of the
+// context, e.g. read from the request path, or else the one of the object of the request.
+func requestCluster(ctx context.Context, req Request) logicalcluster.Name {
+	if cluster, ok := kcpclient.ClusterFromContext(ctx); ok && !cluster.Empty() {
+		return cluster
+	}
+	raw := req.Object.Raw
+	if len(raw) == 0 {
+		// OldObject holds the object being deleted.
+		raw = req.OldObject.Raw
+	}
+	var head struct {
+		Metadata struct {
+			Annotations map[string]string `json:"annotations,omitempty"`
+			ClusterName string            `json:"clusterName,omitempty"`
+		} `json:"metadata,omitempty"`
+	}
+	if len(raw) == 0 || json.Unmarshal(raw, &head) != nil {
+		return logicalcluster.Name{}
+	}
+	if cluster := head.Metadata.Annotations[ClusterAnnotation]; cluster != "" {
+		return logicalcluster.New(cluster)
+	}
+	return logicalcluster.New(head.Metadata.ClusterName)
+}
+
+// ClusterReaderFunc returns a reader of the objects of the given logical cluster, e.g.
+// the cache of the cluster.
+type ClusterReaderFunc func(cluster logicalcluster.Name) (client.Reader, error)
+
+// ClusterReaderInjector is implemented by the handlers, CustomDefaulters and
+// CustomValidators which read the objects related to the admitted ones from the
+// logical cluster of the admission requests.
+type ClusterReaderInjector interface {
+	InjectClusterReader(ClusterReaderFunc) error
+}
+
+// InjectClusterReaderInto will set the cluster reader func on i and return the result
+// if it implements ClusterReaderInjector.  Returns false if i does not implement
+// ClusterReaderInjector.
+func InjectClusterReaderInto(f ClusterReaderFunc, i interface{}) (bool, error) {
+	if s, ok := i.(ClusterReaderInjector); ok {
+		return true, s.InjectClusterReader(f)
+	}
+	return false, nil
+}
+
+// ScopedClusterReader returns a ClusterReaderFunc returning the given reader, e.g. the
+// client of a manager watching all the logical clusters, scoped to the logical cluster.
+func ScopedClusterReader(reader client.Reader) ClusterReaderFunc {
+	return func(cluster logicalcluster.Name) (client.Reader, error) {
+		return &clusterReader{reader: reader, cluster: cluster}, nil
+	}
+}
+
+// clusterReader reads the objects of a logical cluster with a reader of several
+// logical clusters.
+type clusterReader struct {
+	reader  client.Reader
+	cluster logicalcluster.Name
+}
+
+var _ client.Reader = &clusterReader{}
+
+func (r *clusterReader) context(ctx context.Context) context.Context {
+	if r.cluster.Empty() {
+		return ctx
+	}
+	return kcpclient.WithCluster(ctx, r.cluster)
+}
+
+// Get implements client.Reader.
+func (r *clusterReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	if key.Cluster.Empty() {
+		key.Cluster = r.cluster
+	}
+	return r.reader.Get(r.context(ctx), key, obj)
+}
+
+// List implements client.Reader.
+func (r *clusterReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return r.reader.List(r.context(client.ListContext(ctx, opts...)), list, opts...)
+}
+
+// ClusterClient returns the given client, e.g. the client of a manager of all the logical
+// clusters, pinned to the logical cluster of the admission request, so that the handlers
+// read and write the objects of the logical cluster the request came from.  The client is
+// returned as is for the requests without a logical cluster.
+func ClusterClient(c client.Client, req Request) client.Client {
+	if req.ClusterName.Empty() {
+		return c
+	}
+	return client.WithCluster(c, req.ClusterName)
+}
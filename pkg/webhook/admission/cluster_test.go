@@ -0,0 +1,210 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/internal/log"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
+)
+
+var _ = Describe("Admission Webhooks in logical clusters", func() {
+	clusterA := logicalcluster.New("root:org:a")
+
+	podRequest := func(metadata string) Request {
+		return Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			Object:    runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "Pod", "metadata": ` + metadata + `}`)},
+		}}
+	}
+
+	recordingWebhook := func(clusters *[]string) *Webhook {
+		return &Webhook{
+			Handler: HandlerFunc(func(ctx context.Context, req Request) Response {
+				fromCtx, _ := kcpclient.ClusterFromContext(ctx)
+				*clusters = append(*clusters, req.ClusterName.String(), fromCtx.String())
+				return Allowed("")
+			}),
+			log: logf.RuntimeLog.WithName("webhook"),
+		}
+	}
+
+	It("should read the logical cluster from the request path", func() {
+		cluster, path, ok := ClusterFromPath("/clusters/root:org:a/validate-pod")
+		Expect(ok).To(BeTrue())
+		Expect(cluster).To(Equal(clusterA))
+		Expect(path).To(Equal("/validate-pod"))
+
+		for _, path := range []string{"/validate-pod", "/clusters/", "/clusters/root:org:a"} {
+			_, actual, ok := ClusterFromPath(path)
+			Expect(ok).To(BeFalse())
+			Expect(actual).To(Equal(path))
+		}
+	})
+
+	It("should set the logical cluster of the object on the request and in the context", func() {
+		var clusters []string
+		webhook := recordingWebhook(&clusters)
+
+		webhook.Handle(context.Background(), podRequest(`{"name": "foo", "annotations": {"kcp.dev/cluster": "root:org:a"}}`))
+		webhook.Handle(context.Background(), podRequest(`{"name": "foo", "clusterName": "root:org:b"}`))
+		webhook.Handle(context.Background(), podRequest(`{"name": "foo"}`))
+		Expect(clusters).To(Equal([]string{"root:org:a", "root:org:a", "root:org:b", "root:org:b", "", ""}))
+	})
+
+	It("should prefer the logical cluster of the request path", func() {
+		var clusters []string
+		webhook := recordingWebhook(&clusters)
+
+		body := `{"kind": "AdmissionReview", "apiVersion": "admission.k8s.io/v1", "request": {"operation": "CREATE", "object": {"apiVersion": "v1", "kind": "Pod", "metadata": {"clusterName": "root:org:b"}}}}`
+		req, err := http.NewRequest(http.MethodPost, "/clusters/root:org:a/validate-pod", bytes.NewBufferString(body))
+		Expect(err).NotTo(HaveOccurred())
+		req.Header.Set("Content-Type", "application/json")
+		webhook.ServeHTTP(httptest.NewRecorder(), req)
+		Expect(clusters).To(Equal([]string{"root:org:a", "root:org:a"}))
+	})
+
+	It("should inject a reader of the logical cluster of the requests into the custom validators", func() {
+		c := fake.NewClientBuilder().WithObjects(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "allowed", ClusterName: clusterA.String()},
+		}).Build()
+		webhook := WithCustomValidator(&corev1.Pod{}, &clusterReadingValidator{})
+		webhook.log = logf.RuntimeLog.WithName("webhook")
+		setFields := func(target interface{}) error {
+			if _, err := inject.SchemeInto(scheme.Scheme, target); err != nil {
+				return err
+			}
+			_, err := inject.ClientInto(c, target)
+			return err
+		}
+		Expect(setFields(webhook)).To(Succeed())
+		Expect(inject.InjectorInto(setFields, webhook)).To(BeTrue())
+
+		resp := webhook.Handle(context.Background(), podRequest(`{"name": "foo", "namespace": "default", "clusterName": "root:org:a"}`))
+		Expect(resp.Allowed).To(BeTrue())
+		resp = webhook.Handle(context.Background(), podRequest(`{"name": "foo", "namespace": "default", "clusterName": "root:org:b"}`))
+		Expect(resp.Allowed).To(BeFalse())
+		Expect(resp.Result.Message).To(ContainSubstring(`configmaps "allowed" not found`))
+	})
+
+	It("should set the logical cluster of the request on the decoded objects without one", func() {
+		decoder, err := NewDecoder(scheme.Scheme)
+		Expect(err).NotTo(HaveOccurred())
+
+		req := podRequest(`{"name": "foo"}`)
+		req.ClusterName = clusterA
+		req.OldObject = runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "foo", "clusterName": "root:org:b"}}`)}
+		pod := &corev1.Pod{}
+		Expect(decoder.Decode(req, pod)).To(Succeed())
+		Expect(pod.ClusterName).To(Equal("root:org:a"))
+		oldPod := &corev1.Pod{}
+		Expect(decoder.DecodeOldObject(req, oldPod)).To(Succeed())
+		Expect(oldPod.ClusterName).To(Equal("root:org:b"))
+
+		pod = &corev1.Pod{}
+		Expect(decoder.DecodeRaw(req.Object, pod)).To(Succeed())
+		Expect(pod.ClusterName).To(BeEmpty())
+	})
+
+	It("should not patch the logical cluster set by the decoder", func() {
+		webhook := WithCustomDefaulter(&corev1.Pod{}, &labelingDefaulter{})
+		webhook.log = logf.RuntimeLog.WithName("webhook")
+		Expect(inject.SchemeInto(scheme.Scheme, webhook)).To(BeTrue())
+
+		resp := webhook.Handle(kcpclient.WithCluster(context.Background(), clusterA), podRequest(`{"name": "foo"}`))
+		Expect(resp.Allowed).To(BeTrue())
+		var paths []string
+		for _, patch := range resp.Patches {
+			paths = append(paths, patch.Path)
+		}
+		Expect(paths).To(ContainElement("/metadata/labels"))
+		Expect(paths).NotTo(ContainElement("/metadata/clusterName"))
+	})
+
+	It("should pin a client to the logical cluster of the request", func() {
+		c := fake.NewClusterBuilder().Build()
+		req := podRequest(`{"name": "foo"}`)
+		req.ClusterName = clusterA
+
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "created"}}
+		Expect(ClusterClient(c, req).Create(context.Background(), cm)).To(Succeed())
+		key := client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: "default", Name: "created"}, Cluster: clusterA}
+		Expect(c.Get(context.Background(), key, &corev1.ConfigMap{})).To(Succeed())
+
+		Expect(ClusterClient(c, podRequest(`{"name": "foo"}`))).To(BeIdenticalTo(c))
+	})
+})
+
+// labelingDefaulter labels the objects it defaults.
+type labelingDefaulter struct{}
+
+func (*labelingDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	obj.(*corev1.Pod).Labels = map[string]string{"defaulted": "true"}
+	return nil
+}
+
+// clusterReadingValidator only allows the Pods of the logical clusters with a ConfigMap
+// named allowed in their namespace.
+type clusterReadingValidator struct {
+	clusterReader ClusterReaderFunc
+}
+
+var _ ClusterReaderInjector = &clusterReadingValidator{}
+
+func (v *clusterReadingValidator) InjectClusterReader(f ClusterReaderFunc) error {
+	v.clusterReader = f
+	return nil
+}
+
+func (v *clusterReadingValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	cluster, _ := kcpclient.ClusterFromContext(ctx)
+	reader, err := v.clusterReader(cluster)
+	if err != nil {
+		return err
+	}
+	pod := obj.(*corev1.Pod)
+	if err := reader.Get(ctx, client.ObjectKey{NamespacedName: types.NamespacedName{Namespace: pod.Namespace, Name: "allowed"}}, &corev1.ConfigMap{}); err != nil {
+		return fmt.Errorf("pods are not allowed in logical cluster %s: %w", cluster, err)
+	}
+	return nil
+}
+
+func (v *clusterReadingValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	return nil
+}
+
+func (v *clusterReadingValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
@@ -19,32 +19,67 @@ package admission
 import (
 	"fmt"
 
+	"github.com/kcp-dev/logicalcluster"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/apimachinery/pkg/util/json"
+
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 )
 
 // Decoder knows how to decode the contents of an admission
 // request into a concrete object.
 type Decoder struct {
+	scheme *runtime.Scheme
 	codecs serializer.CodecFactory
+	strict *apiutil.StrictDecoding
 }
 
 // NewDecoder creates a Decoder given the runtime.Scheme.
 func NewDecoder(scheme *runtime.Scheme) (*Decoder, error) {
-	return &Decoder{codecs: serializer.NewCodecFactory(scheme)}, nil
+	return &Decoder{scheme: scheme, codecs: serializer.NewCodecFactory(scheme)}, nil
+}
+
+// NewStrictDecoder creates a Decoder given the runtime.Scheme, which rejects the objects of
+// the kinds and logical clusters selected by strict that have unknown or duplicate fields.
+func NewStrictDecoder(scheme *runtime.Scheme, strict *apiutil.StrictDecoding) (*Decoder, error) {
+	return &Decoder{scheme: scheme, codecs: serializer.NewCodecFactory(scheme), strict: strict}, nil
 }
 
 // Decode decodes the inlined object in the AdmissionRequest into the passed-in runtime.Object.
-// If you want decode the OldObject in the AdmissionRequest, use DecodeRaw.
+// If you want decode the OldObject in the AdmissionRequest, use DecodeOldObject.
 // It errors out if req.Object.Raw is empty i.e. containing 0 raw bytes.
+// The object gets the logical cluster of the request unless it has one, so that the
+// clients write it back to the logical cluster it came from.  PatchResponseFromRaw
+// leaves it out of the patches of the mutating handlers.
 func (d *Decoder) Decode(req Request, into runtime.Object) error {
-	// we error out if rawObj is an empty object.
-	if len(req.Object.Raw) == 0 {
-		return fmt.Errorf("there is no content to decode")
+	return d.decodeRequest(req, req.Object, into)
+}
+
+// DecodeOldObject decodes the OldObject of the AdmissionRequest, e.g. the object being
+// updated or deleted, into the passed-in runtime.Object, setting its logical cluster like
+// Decode.  It errors out if req.OldObject.Raw is empty i.e. containing 0 raw bytes.
+func (d *Decoder) DecodeOldObject(req Request, into runtime.Object) error {
+	return d.decodeRequest(req, req.OldObject, into)
+}
+
+// decodeRequest decodes an object of the request, setting the logical cluster of the request
+// on it unless it has one.
+func (d *Decoder) decodeRequest(req Request, rawObj runtime.RawExtension, into runtime.Object) error {
+	if err := d.DecodeRaw(rawObj, into); err != nil {
+		return err
 	}
-	return d.DecodeRaw(req.Object, into)
+	if req.ClusterName.Empty() {
+		return nil
+	}
+	accessor, err := meta.Accessor(into)
+	if err != nil || !logicalcluster.From(accessor).Empty() {
+		return nil
+	}
+	accessor.SetClusterName(req.ClusterName.String())
+	return nil
 }
 
 // DecodeRaw decodes a RawExtension object into the passed-in runtime.Object.
@@ -64,9 +99,15 @@ func (d *Decoder) DecodeRaw(rawObj runtime.RawExtension, into runtime.Object) er
 	}
 	if unstructuredInto, isUnstructured := into.(*unstructured.Unstructured); isUnstructured {
 		// unmarshal into unstructured's underlying object to avoid calling the decoder
-		return json.Unmarshal(rawObj.Raw, &unstructuredInto.Object)
+		if err := json.Unmarshal(rawObj.Raw, &unstructuredInto.Object); err != nil {
+			return err
+		}
+		return d.strict.Check(d.scheme, rawObj.Raw, nil)
 	}
 
 	deserializer := d.codecs.UniversalDeserializer()
-	return runtime.DecodeInto(deserializer, rawObj.Raw, into)
+	if err := runtime.DecodeInto(deserializer, rawObj.Raw, into); err != nil {
+		return err
+	}
+	return d.strict.Check(d.scheme, rawObj.Raw, nil)
 }
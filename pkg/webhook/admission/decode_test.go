@@ -17,6 +17,7 @@ limitations under the License.
 package admission
 
 import (
+	"github.com/kcp-dev/logicalcluster"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
@@ -25,7 +26,10 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/kubernetes/scheme"
+
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 )
 
 var _ = Describe("Admission Webhook Decoder", func() {
@@ -154,4 +158,52 @@ var _ = Describe("Admission Webhook Decoder", func() {
 			"namespace": "default",
 		}))
 	})
+
+	Context("with strict decoding", func() {
+		podGVK := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+		drifted := runtime.RawExtension{Raw: []byte(`{
+    "apiVersion": "v1",
+    "kind": "Pod",
+    "metadata": {
+        "name": "foo",
+        "namespace": "default",
+        "clusterName": "root:org:dev"
+    },
+    "spec": {
+        "containers": [
+            {
+                "image": "bar:v2",
+                "name": "bar",
+                "unknownField": true
+            }
+        ]
+    }
+}`)}
+
+		It("should reject unknown fields of the selected kinds", func() {
+			strictDecoder, err := NewStrictDecoder(scheme.Scheme, &apiutil.StrictDecoding{Kinds: []schema.GroupVersionKind{podGVK}})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = strictDecoder.DecodeRaw(drifted, &corev1.Pod{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unknownField"))
+			Expect(strictDecoder.DecodeRaw(drifted, &unstructured.Unstructured{})).NotTo(Succeed())
+
+			By("accepting the objects without unknown fields")
+			Expect(strictDecoder.Decode(req, &corev1.Pod{})).To(Succeed())
+		})
+
+		It("should honor the logical cluster overrides", func() {
+			strictDecoder, err := NewStrictDecoder(scheme.Scheme, &apiutil.StrictDecoding{
+				Kinds:    []schema.GroupVersionKind{podGVK},
+				Clusters: map[logicalcluster.Name][]schema.GroupVersionKind{logicalcluster.New("root:org:dev"): nil},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(strictDecoder.DecodeRaw(drifted, &corev1.Pod{})).To(Succeed())
+		})
+
+		It("should accept unknown fields by default", func() {
+			Expect(decoder.DecodeRaw(drifted, &corev1.Pod{})).To(Succeed())
+		})
+	})
 })
@@ -46,12 +46,20 @@ type defaulterForType struct {
 }
 
 var _ DecoderInjector = &defaulterForType{}
+var _ ClusterReaderInjector = &defaulterForType{}
 
 func (h *defaulterForType) InjectDecoder(d *Decoder) error {
 	h.decoder = d
 	return nil
 }
 
+// InjectClusterReader injects the cluster reader func into the CustomDefaulter, if it implements
+// ClusterReaderInjector.
+func (h *defaulterForType) InjectClusterReader(f ClusterReaderFunc) error {
+	_, err := InjectClusterReaderInto(f, h.defaulter)
+	return err
+}
+
 // Handle handles admission requests.
 func (h *defaulterForType) Handle(ctx context.Context, req Request) Response {
 	if h.defaulter == nil {
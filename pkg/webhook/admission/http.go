@@ -24,6 +24,7 @@ import (
 	"io/ioutil"
 	"net/http"
 
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
 	v1 "k8s.io/api/admission/v1"
 	"k8s.io/api/admission/v1beta1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -46,6 +47,11 @@ func (wh *Webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	var body []byte
 	var err error
 	ctx := r.Context()
+	if r.URL != nil {
+		if cluster, _, ok := ClusterFromPath(r.URL.Path); ok {
+			ctx = kcpclient.WithCluster(ctx, cluster)
+		}
+	}
 	if wh.WithContextFunc != nil {
 		ctx = wh.WithContextFunc(ctx, r)
 	}
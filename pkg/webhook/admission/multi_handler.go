@@ -0,0 +1,231 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	admissionv1 "k8s.io/api/admission/v1"
+
+	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/internal/metrics"
+)
+
+// Combination is how a MultiHandler combines the verdicts of its handlers.
+type Combination int
+
+const (
+	// FirstDenyWins calls the handlers in order, and returns the first response denying the
+	// request, if any.  The patches of the handlers are dropped.
+	FirstDenyWins Combination = iota
+
+	// AllMustAllow calls every handler, and denies the request if any of them denies it, with
+	// the reasons of all the denials.  The first errored response is returned as is.  The
+	// patches of the handlers are dropped.
+	AllMustAllow
+
+	// MergePatches calls the handlers in order, each with the object patched by the handlers
+	// before it, and returns a single JSON patch of the changes of all of them.  The first
+	// response denying the request is returned as is.
+	MergePatches
+)
+
+// NamedHandler is a handler of a MultiHandler, labelled with its name in the metrics.
+type NamedHandler struct {
+	Name    string
+	Handler Handler
+}
+
+// MultiHandler combines several handlers of one webhook into a single handler, with the given
+// combination of their verdicts, e.g. to stack the policies served by a path without writing a
+// dispatcher.  The latency and the verdicts of each handler are recorded in the
+// controller_runtime_webhook_handler_latency_seconds and controller_runtime_webhook_handler_verdicts_total
+// metrics, labelled with the name of the webhook and of the handler.  The warnings of all the
+// called handlers are returned.
+func MultiHandler(name string, combination Combination, handlers ...NamedHandler) Handler {
+	return &multiHandler{name: name, combination: combination, handlers: handlers}
+}
+
+type multiHandler struct {
+	name        string
+	combination Combination
+	handlers    []NamedHandler
+}
+
+// Handle implements Handler.
+func (m *multiHandler) Handle(ctx context.Context, req Request) Response {
+	switch m.combination {
+	case FirstDenyWins:
+		return m.firstDenyWins(ctx, req)
+	case AllMustAllow:
+		return m.allMustAllow(ctx, req)
+	case MergePatches:
+		return m.mergePatches(ctx, req)
+	default:
+		return Errored(http.StatusInternalServerError, fmt.Errorf("unknown combination %d of the handlers of %s", m.combination, m.name))
+	}
+}
+
+func (m *multiHandler) firstDenyWins(ctx context.Context, req Request) Response {
+	var warnings []string
+	for _, h := range m.handlers {
+		resp := m.handle(ctx, h, req)
+		warnings = append(warnings, resp.Warnings...)
+		if !resp.Allowed {
+			resp.Warnings = warnings
+			return resp
+		}
+	}
+	return Allowed("").WithWarnings(warnings...)
+}
+
+func (m *multiHandler) allMustAllow(ctx context.Context, req Request) Response {
+	var warnings, reasons []string
+	var errored *Response
+	for _, h := range m.handlers {
+		resp := m.handle(ctx, h, req)
+		warnings = append(warnings, resp.Warnings...)
+		switch {
+		case resp.Allowed:
+		case isErrored(resp):
+			if errored == nil {
+				errored = &resp
+			}
+		default:
+			reasons = append(reasons, denialReason(h, resp))
+		}
+	}
+	if errored != nil {
+		errored.Warnings = warnings
+		return *errored
+	}
+	if len(reasons) > 0 {
+		return Denied(strings.Join(reasons, "; ")).WithWarnings(warnings...)
+	}
+	return Allowed("").WithWarnings(warnings...)
+}
+
+func (m *multiHandler) mergePatches(ctx context.Context, req Request) Response {
+	original := req.Object.Raw
+	current := original
+	var warnings []string
+	for _, h := range m.handlers {
+		req.Object.Raw = current
+		resp := m.handle(ctx, h, req)
+		warnings = append(warnings, resp.Warnings...)
+		if !resp.Allowed {
+			resp.Warnings = warnings
+			return resp
+		}
+
+		patch, err := patchOf(resp)
+		if err != nil {
+			return Errored(http.StatusInternalServerError, fmt.Errorf("handler %s of %s: %w", h.Name, m.name, err))
+		}
+		if patch == nil {
+			continue
+		}
+		if current, err = patch.Apply(current); err != nil {
+			return Errored(http.StatusInternalServerError, fmt.Errorf("error applying the patch of handler %s of %s: %w", h.Name, m.name, err))
+		}
+	}
+	return PatchResponseFromRaw(original, current).WithWarnings(warnings...)
+}
+
+// handle calls the handler, recording its latency and verdict.
+func (m *multiHandler) handle(ctx context.Context, h NamedHandler, req Request) Response {
+	start := time.Now()
+	resp := h.Handler.Handle(ctx, req)
+	metrics.HandlerLatency.WithLabelValues(m.name, h.Name).Observe(time.Since(start).Seconds())
+
+	verdict := "allowed"
+	switch {
+	case resp.Allowed:
+	case isErrored(resp):
+		verdict = "errored"
+	default:
+		verdict = "denied"
+	}
+	metrics.HandlerVerdicts.WithLabelValues(m.name, h.Name, verdict).Inc()
+	return resp
+}
+
+// isErrored returns whether the response reports an error of the handler rather than a denial,
+// see Errored.
+func isErrored(resp Response) bool {
+	return !resp.Allowed && resp.Result != nil && resp.Result.Code != 0 && resp.Result.Code != http.StatusForbidden
+}
+
+// denialReason returns the reason of the denial of the handler.
+func denialReason(h NamedHandler, resp Response) string {
+	reason := h.Name + " denied the request"
+	if resp.Result == nil {
+		return reason
+	}
+	if resp.Result.Message != "" {
+		return reason + ": " + resp.Result.Message
+	}
+	if resp.Result.Reason != "" {
+		return reason + ": " + string(resp.Result.Reason)
+	}
+	return reason
+}
+
+// patchOf returns the JSON patch of the response, or nil if it has none.
+func patchOf(resp Response) (jsonpatch.Patch, error) {
+	if resp.PatchType != nil && *resp.PatchType != admissionv1.PatchTypeJSONPatch {
+		return nil, fmt.Errorf("unexpected patch type returned by the handler: %v, only allow: %v",
+			*resp.PatchType, admissionv1.PatchTypeJSONPatch)
+	}
+	raw := resp.Patch
+	if len(resp.Patches) > 0 {
+		var err error
+		if raw, err = json.Marshal(resp.Patches); err != nil {
+			return nil, fmt.Errorf("error when marshaling the patch: %w", err)
+		}
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	return jsonpatch.DecodePatch(raw)
+}
+
+// InjectFunc injects the field setter into the handlers.
+func (m *multiHandler) InjectFunc(f inject.Func) error {
+	for _, h := range m.handlers {
+		if err := f(h.Handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InjectDecoder injects the decoder into the handlers.
+func (m *multiHandler) InjectDecoder(d *Decoder) error {
+	for _, h := range m.handlers {
+		if _, err := InjectDecoderInto(d, h.Handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
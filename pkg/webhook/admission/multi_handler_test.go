@@ -0,0 +1,143 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	jsonpatch "gomodules.xyz/jsonpatch/v2"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"sigs.k8s.io/controller-runtime/pkg/webhook/internal/metrics"
+)
+
+var _ = Describe("MultiHandler", func() {
+	allow := func(warning string) Handler {
+		return &fakeHandler{fn: func(ctx context.Context, req Request) Response {
+			return Allowed("").WithWarnings(warning)
+		}}
+	}
+	deny := func(reason string) Handler {
+		return &fakeHandler{fn: func(ctx context.Context, req Request) Response {
+			return Denied(reason)
+		}}
+	}
+	errored := &fakeHandler{fn: func(ctx context.Context, req Request) Response {
+		return Errored(http.StatusBadRequest, errors.New("cannot decode"))
+	}}
+
+	Context("with FirstDenyWins", func() {
+		It("should return the first denial and the warnings of the handlers called until then", func() {
+			handler := MultiHandler("first-deny", FirstDenyWins,
+				NamedHandler{Name: "a", Handler: allow("from a")},
+				NamedHandler{Name: "b", Handler: deny("b says no")},
+				NamedHandler{Name: "c", Handler: deny("c says no")},
+			)
+			resp := handler.Handle(context.Background(), Request{})
+			Expect(resp.Allowed).To(BeFalse())
+			Expect(resp.Result.Reason).To(BeEquivalentTo("b says no"))
+			Expect(resp.Warnings).To(Equal([]string{"from a"}))
+		})
+
+		It("should allow the request if every handler allows it", func() {
+			handler := MultiHandler("first-deny", FirstDenyWins,
+				NamedHandler{Name: "a", Handler: allow("from a")},
+				NamedHandler{Name: "b", Handler: allow("from b")},
+			)
+			resp := handler.Handle(context.Background(), Request{})
+			Expect(resp.Allowed).To(BeTrue())
+			Expect(resp.Warnings).To(Equal([]string{"from a", "from b"}))
+		})
+	})
+
+	Context("with AllMustAllow", func() {
+		It("should call every handler and deny the request with the reasons of all the denials", func() {
+			last := &fakeHandler{fn: func(ctx context.Context, req Request) Response { return Allowed("") }}
+			handler := MultiHandler("all-allow", AllMustAllow,
+				NamedHandler{Name: "a", Handler: deny("no a")},
+				NamedHandler{Name: "b", Handler: deny("no b")},
+				NamedHandler{Name: "c", Handler: last},
+			)
+			resp := handler.Handle(context.Background(), Request{})
+			Expect(resp.Allowed).To(BeFalse())
+			Expect(resp.Result.Code).To(BeEquivalentTo(http.StatusForbidden))
+			Expect(resp.Result.Reason).To(BeEquivalentTo("a denied the request: no a; b denied the request: no b"))
+			Expect(last.invoked).To(BeTrue())
+		})
+
+		It("should return the first error over the denials", func() {
+			handler := MultiHandler("all-allow", AllMustAllow,
+				NamedHandler{Name: "a", Handler: deny("no a")},
+				NamedHandler{Name: "b", Handler: errored},
+			)
+			resp := handler.Handle(context.Background(), Request{})
+			Expect(resp.Allowed).To(BeFalse())
+			Expect(resp.Result.Code).To(BeEquivalentTo(http.StatusBadRequest))
+			Expect(resp.Result.Message).To(Equal("cannot decode"))
+		})
+	})
+
+	Context("with MergePatches", func() {
+		It("should call each handler with the object patched by the previous ones and merge the patches", func() {
+			addLabel := &fakeHandler{fn: func(ctx context.Context, req Request) Response {
+				return Patched("", jsonpatch.JsonPatchOperation{Operation: "add", Path: "/metadata/labels", Value: map[string]interface{}{"a": "b"}})
+			}}
+			var seen string
+			addAnnotation := &fakeHandler{fn: func(ctx context.Context, req Request) Response {
+				seen = string(req.Object.Raw)
+				return Patched("", jsonpatch.JsonPatchOperation{Operation: "add", Path: "/metadata/labels/c", Value: "d"})
+			}}
+			handler := MultiHandler("merge", MergePatches,
+				NamedHandler{Name: "labels", Handler: addLabel},
+				NamedHandler{Name: "more-labels", Handler: addAnnotation},
+			)
+			req := Request{}
+			req.Object = runtime.RawExtension{Raw: []byte(`{"metadata":{"name":"obj"}}`)}
+			resp := handler.Handle(context.Background(), req)
+			Expect(resp.Allowed).To(BeTrue())
+			Expect(seen).To(MatchJSON(`{"metadata":{"name":"obj","labels":{"a":"b"}}}`))
+			Expect(resp.Patches).To(ConsistOf(jsonpatch.JsonPatchOperation{Operation: "add", Path: "/metadata/labels", Value: map[string]interface{}{"a": "b", "c": "d"}}))
+		})
+
+		It("should return the first denial", func() {
+			handler := MultiHandler("merge", MergePatches,
+				NamedHandler{Name: "a", Handler: deny("no a")},
+				NamedHandler{Name: "b", Handler: allow("from b")},
+			)
+			resp := handler.Handle(context.Background(), Request{})
+			Expect(resp.Allowed).To(BeFalse())
+			Expect(resp.Result.Reason).To(BeEquivalentTo("no a"))
+		})
+	})
+
+	It("should record the verdicts of each handler", func() {
+		handler := MultiHandler("metrics", AllMustAllow,
+			NamedHandler{Name: "a", Handler: allow("")},
+			NamedHandler{Name: "b", Handler: deny("no")},
+			NamedHandler{Name: "c", Handler: errored},
+		)
+		handler.Handle(context.Background(), Request{})
+		Expect(testutil.ToFloat64(metrics.HandlerVerdicts.WithLabelValues("metrics", "a", "allowed"))).To(BeEquivalentTo(1))
+		Expect(testutil.ToFloat64(metrics.HandlerVerdicts.WithLabelValues("metrics", "b", "denied"))).To(BeEquivalentTo(1))
+		Expect(testutil.ToFloat64(metrics.HandlerVerdicts.WithLabelValues("metrics", "c", "errored"))).To(BeEquivalentTo(1))
+	})
+})
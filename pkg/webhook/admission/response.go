@@ -82,11 +82,14 @@ func ValidationResponse(allowed bool, reason string) Response {
 // PatchResponseFromRaw takes 2 byte arrays and returns a new response with json patch.
 // The original object should be passed in as raw bytes to avoid the roundtripping problem
 // described in https://github.com/kubernetes-sigs/kubebuilder/issues/510.
+// The patch doesn't add the logical cluster of the object, set by the Decoder on the objects
+// without one: it is owned by the server.
 func PatchResponseFromRaw(original, current []byte) Response {
 	patches, err := jsonpatch.CreatePatch(original, current)
 	if err != nil {
 		return Errored(http.StatusInternalServerError, err)
 	}
+	patches = withoutAddedClusterName(patches)
 	return Response{
 		Patches: patches,
 		AdmissionResponse: admissionv1.AdmissionResponse{
@@ -102,6 +105,25 @@ func PatchResponseFromRaw(original, current []byte) Response {
 	}
 }
 
+// withoutAddedClusterName drops the logical cluster from the patches adding it.
+func withoutAddedClusterName(patches []jsonpatch.JsonPatchOperation) []jsonpatch.JsonPatchOperation {
+	kept := patches[:0]
+	for _, patch := range patches {
+		if patch.Operation == "add" {
+			switch patch.Path {
+			case "/metadata/clusterName":
+				continue
+			case "/metadata":
+				if metadata, ok := patch.Value.(map[string]interface{}); ok {
+					delete(metadata, "clusterName")
+				}
+			}
+		}
+		kept = append(kept, patch)
+	}
+	return kept
+}
+
 // validationResponseFromStatus returns a response for admitting a request with provided Status object.
 func validationResponseFromStatus(allowed bool, status metav1.Status) Response {
 	resp := Response{
@@ -215,6 +215,20 @@ var _ = Describe("Admission Webhook Response Helpers", func() {
 			resp := PatchResponseFromRaw([]byte(`{"a": "foo"}`), []byte(`{"a": "bar"}`))
 			Expect(resp).To(Equal(expected))
 		})
+
+		It("should not add the logical cluster of the object", func() {
+			resp := PatchResponseFromRaw([]byte(`{"metadata": {"name": "foo"}}`), []byte(`{"metadata": {"name": "foo", "clusterName": "root:org"}}`))
+			Expect(resp.Patches).To(BeEmpty())
+			Expect(resp.PatchType).To(BeNil())
+
+			resp = PatchResponseFromRaw([]byte(`{}`), []byte(`{"metadata": {"clusterName": "root:org", "labels": {"a": "b"}}}`))
+			Expect(resp.Patches).To(Equal([]jsonpatch.JsonPatchOperation{
+				{Operation: "add", Path: "/metadata", Value: map[string]interface{}{"labels": map[string]interface{}{"a": "b"}}},
+			}))
+
+			resp = PatchResponseFromRaw([]byte(`{"metadata": {"clusterName": "root:org"}}`), []byte(`{"metadata": {"clusterName": "root:other"}}`))
+			Expect(resp.Patches).To(HaveLen(1))
+		})
 	})
 
 	Describe("WithWarnings", func() {
@@ -81,11 +81,11 @@ func (h *validatingHandler) Handle(ctx context.Context, req Request) Response {
 	if req.Operation == v1.Update {
 		oldObj := obj.DeepCopyObject()
 
-		err := h.decoder.DecodeRaw(req.Object, obj)
+		err := h.decoder.Decode(req, obj)
 		if err != nil {
 			return Errored(http.StatusBadRequest, err)
 		}
-		err = h.decoder.DecodeRaw(req.OldObject, oldObj)
+		err = h.decoder.DecodeOldObject(req, oldObj)
 		if err != nil {
 			return Errored(http.StatusBadRequest, err)
 		}
@@ -103,7 +103,7 @@ func (h *validatingHandler) Handle(ctx context.Context, req Request) Response {
 	if req.Operation == v1.Delete {
 		// In reference to PR: https://github.com/kubernetes/kubernetes/pull/76346
 		// OldObject contains the object being deleted
-		err := h.decoder.DecodeRaw(req.OldObject, obj)
+		err := h.decoder.DecodeOldObject(req, obj)
 		if err != nil {
 			return Errored(http.StatusBadRequest, err)
 		}
@@ -48,6 +48,7 @@ type validatorForType struct {
 }
 
 var _ DecoderInjector = &validatorForType{}
+var _ ClusterReaderInjector = &validatorForType{}
 
 // InjectDecoder injects the decoder into a validatingHandler.
 func (h *validatorForType) InjectDecoder(d *Decoder) error {
@@ -55,6 +56,13 @@ func (h *validatorForType) InjectDecoder(d *Decoder) error {
 	return nil
 }
 
+// InjectClusterReader injects the cluster reader func into the CustomValidator, if it implements
+// ClusterReaderInjector.
+func (h *validatorForType) InjectClusterReader(f ClusterReaderFunc) error {
+	_, err := InjectClusterReaderInto(f, h.validator)
+	return err
+}
+
 // Handle handles admission requests.
 func (h *validatorForType) Handle(ctx context.Context, req Request) Response {
 	if h.validator == nil {
@@ -77,10 +85,10 @@ func (h *validatorForType) Handle(ctx context.Context, req Request) Response {
 		err = h.validator.ValidateCreate(ctx, obj)
 	case v1.Update:
 		oldObj := obj.DeepCopyObject()
-		if err := h.decoder.DecodeRaw(req.Object, obj); err != nil {
+		if err := h.decoder.Decode(req, obj); err != nil {
 			return Errored(http.StatusBadRequest, err)
 		}
-		if err := h.decoder.DecodeRaw(req.OldObject, oldObj); err != nil {
+		if err := h.decoder.DecodeOldObject(req, oldObj); err != nil {
 			return Errored(http.StatusBadRequest, err)
 		}
 
@@ -88,7 +96,7 @@ func (h *validatorForType) Handle(ctx context.Context, req Request) Response {
 	case v1.Delete:
 		// In reference to PR: https://github.com/kubernetes/kubernetes/pull/76346
 		// OldObject contains the object being deleted
-		if err := h.decoder.DecodeRaw(req.OldObject, obj); err != nil {
+		if err := h.decoder.DecodeOldObject(req, obj); err != nil {
 			return Errored(http.StatusBadRequest, err)
 		}
 
@@ -22,6 +22,8 @@ import (
 	"net/http"
 
 	"github.com/go-logr/logr"
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
 	jsonpatch "gomodules.xyz/jsonpatch/v2"
 	admissionv1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -29,6 +31,8 @@ import (
 	"k8s.io/apimachinery/pkg/util/json"
 	"k8s.io/client-go/kubernetes/scheme"
 
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	logf "sigs.k8s.io/controller-runtime/pkg/internal/log"
 	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/internal/metrics"
@@ -45,6 +49,12 @@ var (
 // (e.g. Get, Create, etc), and the object itself.
 type Request struct {
 	admissionv1.AdmissionRequest
+
+	// ClusterName is the logical cluster the request came from.  It is read from the
+	// request path, /clusters/<name>/<webhook path>, or else from the kcp.dev/cluster
+	// annotation or the clusterName of the object of the request.  It is also set in the
+	// context passed to the handlers.
+	ClusterName logicalcluster.Name
 }
 
 // Response is the output of an admission handler.
@@ -126,6 +136,21 @@ type Webhook struct {
 	// headers thus allowing you to read them from within the handler
 	WithContextFunc func(context.Context, *http.Request) context.Context
 
+	// StrictDecoding, if set, makes the decoder of the handler reject the objects of the selected
+	// kinds and logical clusters that have unknown fields.  It must be set before the scheme is
+	// injected.
+	StrictDecoding *apiutil.StrictDecoding
+
+	// ClusterReader returns the readers injected into the handlers, CustomDefaulters and
+	// CustomValidators implementing ClusterReaderInjector, to read the objects of the logical
+	// cluster of the admission requests.  Defaults to the client injected into the webhook,
+	// scoped to the logical cluster, e.g. a cluster-aware manager's client reading from its
+	// cache.  It must be set before the webhook is registered.
+	ClusterReader ClusterReaderFunc
+
+	// client is injected by the manager, and read through by the default ClusterReader.
+	client client.Client
+
 	// decoder is constructed on receiving a scheme and passed down to then handler
 	decoder *Decoder
 
@@ -143,6 +168,13 @@ func (wh *Webhook) InjectLogger(l logr.Logger) error {
 // If the webhook is validating type, it delegates the AdmissionRequest to each handler and
 // deny the request if anyone denies.
 func (wh *Webhook) Handle(ctx context.Context, req Request) Response {
+	if req.ClusterName.Empty() {
+		req.ClusterName = requestCluster(ctx, req)
+	}
+	if !req.ClusterName.Empty() {
+		ctx = kcpclient.WithCluster(ctx, req.ClusterName)
+	}
+
 	resp := wh.Handler.Handle(ctx, req)
 	if err := resp.Complete(req); err != nil {
 		wh.log.Error(err, "unable to encode response")
@@ -152,12 +184,30 @@ func (wh *Webhook) Handle(ctx context.Context, req Request) Response {
 	return resp
 }
 
+// InjectClient injects the client read through by the default ClusterReader.
+func (wh *Webhook) InjectClient(c client.Client) error {
+	wh.client = c
+	return nil
+}
+
+// getClusterReader returns the ClusterReader of the webhook, defaulted to the injected
+// client scoped to the logical clusters.  It is nil if neither was set.
+func (wh *Webhook) getClusterReader() ClusterReaderFunc {
+	if wh.ClusterReader != nil {
+		return wh.ClusterReader
+	}
+	if wh.client != nil {
+		return ScopedClusterReader(wh.client)
+	}
+	return nil
+}
+
 // InjectScheme injects a scheme into the webhook, in order to construct a Decoder.
 func (wh *Webhook) InjectScheme(s *runtime.Scheme) error {
 	// TODO(directxman12): we should have a better way to pass this down
 
 	var err error
-	wh.decoder, err = NewDecoder(s)
+	wh.decoder, err = NewStrictDecoder(s, wh.StrictDecoding)
 	if err != nil {
 		return err
 	}
@@ -204,6 +254,12 @@ func (wh *Webhook) InjectFunc(f inject.Func) error {
 			return err
 		}
 
+		if clusterReader := wh.getClusterReader(); clusterReader != nil {
+			if _, err := InjectClusterReaderInto(clusterReader, target); err != nil {
+				return err
+			}
+		}
+
 		return nil
 	}
 
@@ -0,0 +1,189 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// ClusterWebhookConfigurations registers the same webhooks in the webhook configurations of
+// several logical clusters, so that one webhook server serves the admission of many kcp
+// workspaces.
+//
+// The clientConfig of the webhooks of each logical cluster targets the path of the logical
+// cluster on the webhook server, i.e. /clusters/<name>/<path>: the server passes the
+// logical cluster to the webhook registered at <path>, or serves the webhook registered
+// at the full path, see builder.WebhookBuilder.ForClusters.
+type ClusterWebhookConfigurations struct {
+	// Client is used to create, update and delete the webhook configurations.
+	Client client.Client
+
+	// ValidatingWebhookConfigurations are the templates of the ValidatingWebhookConfigurations
+	// to register, with the paths of the webhooks in their clientConfig.
+	ValidatingWebhookConfigurations []admissionregistrationv1.ValidatingWebhookConfiguration
+
+	// MutatingWebhookConfigurations are the templates of the MutatingWebhookConfigurations
+	// to register, with the paths of the webhooks in their clientConfig.
+	MutatingWebhookConfigurations []admissionregistrationv1.MutatingWebhookConfiguration
+}
+
+// Register creates or updates the webhook configurations in each of the logical clusters.
+func (c *ClusterWebhookConfigurations) Register(ctx context.Context, clusters ...logicalcluster.Name) error {
+	if c.Client == nil {
+		return errors.New("must specify Client")
+	}
+	var errs []error
+	for _, cluster := range clusters {
+		if err := c.register(kcpclient.WithCluster(ctx, cluster), cluster); err != nil {
+			errs = append(errs, fmt.Errorf("cluster %q: %w", cluster, err))
+		}
+	}
+	return kerrors.NewAggregate(errs)
+}
+
+func (c *ClusterWebhookConfigurations) register(ctx context.Context, cluster logicalcluster.Name) error {
+	var errs []error
+	for i := range c.ValidatingWebhookConfigurations {
+		template := &c.ValidatingWebhookConfigurations[i]
+		webhooks := make([]admissionregistrationv1.ValidatingWebhook, len(template.Webhooks))
+		for j := range template.Webhooks {
+			template.Webhooks[j].DeepCopyInto(&webhooks[j])
+			if err := clusterClientConfig(&webhooks[j].ClientConfig, cluster); err != nil {
+				return err
+			}
+		}
+		cfg := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+		cfg.Name = template.Name
+		op, err := controllerutil.CreateOrUpdate(ctx, c.Client, cfg, func() error {
+			cfg.Labels = mergeMetadata(cfg.Labels, template.Labels)
+			cfg.Annotations = mergeMetadata(cfg.Annotations, template.Annotations)
+			cfg.Webhooks = webhooks
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if op != controllerutil.OperationResultNone {
+			log.Info("Registered webhooks", "cluster", cluster, "validatingWebhookConfiguration", template.Name, "operation", op)
+		}
+	}
+
+	for i := range c.MutatingWebhookConfigurations {
+		template := &c.MutatingWebhookConfigurations[i]
+		webhooks := make([]admissionregistrationv1.MutatingWebhook, len(template.Webhooks))
+		for j := range template.Webhooks {
+			template.Webhooks[j].DeepCopyInto(&webhooks[j])
+			if err := clusterClientConfig(&webhooks[j].ClientConfig, cluster); err != nil {
+				return err
+			}
+		}
+		cfg := &admissionregistrationv1.MutatingWebhookConfiguration{}
+		cfg.Name = template.Name
+		op, err := controllerutil.CreateOrUpdate(ctx, c.Client, cfg, func() error {
+			cfg.Labels = mergeMetadata(cfg.Labels, template.Labels)
+			cfg.Annotations = mergeMetadata(cfg.Annotations, template.Annotations)
+			cfg.Webhooks = webhooks
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if op != controllerutil.OperationResultNone {
+			log.Info("Registered webhooks", "cluster", cluster, "mutatingWebhookConfiguration", template.Name, "operation", op)
+		}
+	}
+	return kerrors.NewAggregate(errs)
+}
+
+// Unregister deletes the webhook configurations from each of the logical clusters, e.g.
+// once the workspaces stop using the APIs the webhooks admit.
+func (c *ClusterWebhookConfigurations) Unregister(ctx context.Context, clusters ...logicalcluster.Name) error {
+	if c.Client == nil {
+		return errors.New("must specify Client")
+	}
+	var errs []error
+	for _, cluster := range clusters {
+		clusterCtx := kcpclient.WithCluster(ctx, cluster)
+		for _, template := range c.ValidatingWebhookConfigurations {
+			cfg := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+			cfg.Name = template.Name
+			if err := c.Client.Delete(clusterCtx, cfg); err != nil && !apierrors.IsNotFound(err) {
+				errs = append(errs, fmt.Errorf("cluster %q: %w", cluster, err))
+			}
+		}
+		for _, template := range c.MutatingWebhookConfigurations {
+			cfg := &admissionregistrationv1.MutatingWebhookConfiguration{}
+			cfg.Name = template.Name
+			if err := c.Client.Delete(clusterCtx, cfg); err != nil && !apierrors.IsNotFound(err) {
+				errs = append(errs, fmt.Errorf("cluster %q: %w", cluster, err))
+			}
+		}
+	}
+	return kerrors.NewAggregate(errs)
+}
+
+// clusterClientConfig prefixes the path of the clientConfig of a webhook with the path of
+// the logical cluster.
+func clusterClientConfig(config *admissionregistrationv1.WebhookClientConfig, cluster logicalcluster.Name) error {
+	if config.Service != nil {
+		path := ""
+		if config.Service.Path != nil {
+			path = *config.Service.Path
+		}
+		path = cluster.Path() + path
+		config.Service.Path = &path
+	}
+	if config.URL != nil {
+		u, err := url.Parse(*config.URL)
+		if err != nil {
+			return fmt.Errorf("invalid webhook URL %q: %w", *config.URL, err)
+		}
+		u.Path = cluster.Path() + u.Path
+		u.RawPath = ""
+		s := u.String()
+		config.URL = &s
+	}
+	return nil
+}
+
+// mergeMetadata sets the labels or annotations of the template on the existing ones.
+func mergeMetadata(existing, template map[string]string) map[string]string {
+	if len(template) == 0 {
+		return existing
+	}
+	if existing == nil {
+		existing = make(map[string]string, len(template))
+	}
+	for k, v := range template {
+		existing[k] = v
+	}
+	return existing
+}
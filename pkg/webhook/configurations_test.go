@@ -0,0 +1,112 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook_test
+
+import (
+	"context"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+var _ = Describe("ClusterWebhookConfigurations", func() {
+	a := logicalcluster.New("root:a")
+	b := logicalcluster.New("root:b")
+	key := client.ObjectKey{NamespacedName: types.NamespacedName{Name: "greetings"}}
+
+	var c client.Client
+	var configurations *webhook.ClusterWebhookConfigurations
+
+	BeforeEach(func() {
+		c = fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+		configurations = &webhook.ClusterWebhookConfigurations{
+			Client: c,
+			ValidatingWebhookConfigurations: []admissionregistrationv1.ValidatingWebhookConfiguration{{
+				ObjectMeta: metav1.ObjectMeta{Name: "greetings", Labels: map[string]string{"app": "greeter"}},
+				Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+					Name:         "vgreeting.examples.kcp.dev",
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{URL: pointer.String("https://webhooks.example.com:9443/validate-greeting")},
+				}},
+			}},
+			MutatingWebhookConfigurations: []admissionregistrationv1.MutatingWebhookConfiguration{{
+				ObjectMeta: metav1.ObjectMeta{Name: "greetings"},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: "mgreeting.examples.kcp.dev",
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{Service: &admissionregistrationv1.ServiceReference{
+						Namespace: "webhooks", Name: "greeter", Path: pointer.String("/mutate-greeting"),
+					}},
+				}},
+			}},
+		}
+	})
+
+	It("should register the webhooks in each logical cluster with the path of the cluster", func() {
+		Expect(configurations.Register(context.Background(), a, b)).To(Succeed())
+
+		for _, cluster := range []logicalcluster.Name{a, b} {
+			ctx := kcpclient.WithCluster(context.Background(), cluster)
+			validating := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+			Expect(c.Get(ctx, key, validating)).To(Succeed())
+			Expect(validating.Labels).To(HaveKeyWithValue("app", "greeter"))
+			Expect(validating.Webhooks).To(HaveLen(1))
+			Expect(*validating.Webhooks[0].ClientConfig.URL).To(Equal("https://webhooks.example.com:9443/clusters/" + cluster.String() + "/validate-greeting"))
+
+			mutating := &admissionregistrationv1.MutatingWebhookConfiguration{}
+			Expect(c.Get(ctx, key, mutating)).To(Succeed())
+			Expect(mutating.Webhooks).To(HaveLen(1))
+			Expect(*mutating.Webhooks[0].ClientConfig.Service.Path).To(Equal("/clusters/" + cluster.String() + "/mutate-greeting"))
+		}
+		Expect(*configurations.ValidatingWebhookConfigurations[0].Webhooks[0].ClientConfig.URL).To(Equal("https://webhooks.example.com:9443/validate-greeting"), "the templates are not modified")
+	})
+
+	It("should update the webhooks registered before, keeping the other metadata", func() {
+		ctx := kcpclient.WithCluster(context.Background(), a)
+		Expect(configurations.Register(context.Background(), a)).To(Succeed())
+		validating := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+		Expect(c.Get(ctx, key, validating)).To(Succeed())
+		validating.Labels["team"] = "greetings"
+		validating.Webhooks[0].Name = "stale.examples.kcp.dev"
+		Expect(c.Update(ctx, validating)).To(Succeed())
+
+		Expect(configurations.Register(context.Background(), a)).To(Succeed())
+		Expect(c.Get(ctx, key, validating)).To(Succeed())
+		Expect(validating.Labels).To(Equal(map[string]string{"app": "greeter", "team": "greetings"}))
+		Expect(validating.Webhooks[0].Name).To(Equal("vgreeting.examples.kcp.dev"))
+	})
+
+	It("should unregister the webhooks from the logical clusters", func() {
+		Expect(configurations.Register(context.Background(), a, b)).To(Succeed())
+		Expect(configurations.Unregister(context.Background(), a)).To(Succeed())
+		Expect(configurations.Unregister(context.Background(), a)).To(Succeed())
+
+		err := c.Get(kcpclient.WithCluster(context.Background(), a), key, &admissionregistrationv1.ValidatingWebhookConfiguration{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		Expect(c.Get(kcpclient.WithCluster(context.Background(), b), key, &admissionregistrationv1.MutatingWebhookConfiguration{})).To(Succeed())
+	})
+})
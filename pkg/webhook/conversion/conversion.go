@@ -22,17 +22,24 @@ See pkg/conversion for interface definitions required to ensure an API Type is c
 package conversion
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"sync"
 
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
 	apix "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/conversion"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
 var (
@@ -40,9 +47,28 @@ var (
 )
 
 // Webhook implements a CRD conversion webhook HTTP handler.
+//
+// Different kcp workspaces may serve different versions of a CRD.  The conversion requests
+// of a logical cluster, sent to /clusters/<name>/<path> (see admission.ClusterFromPath),
+// are converted with the scheme returned by SchemeForCluster, and the versions missing from
+// the scheme with the functions of the Registry.
 type Webhook struct {
+	// SchemeForCluster, if set, returns the scheme of the types to convert the objects of a
+	// logical cluster with, e.g. for the workspaces whose CRDs serve other versions than
+	// those of the injected scheme.  The injected scheme is used for the requests without a
+	// logical cluster.
+	SchemeForCluster func(cluster logicalcluster.Name) (*runtime.Scheme, error)
+
+	// Registry, if set, converts the objects of the versions missing from the scheme of the
+	// logical cluster, e.g. versions only served by some workspaces, as unstructured objects
+	// with the conversion functions registered at runtime.
+	Registry *conversion.Registry
+
 	scheme  *runtime.Scheme
 	decoder *Decoder
+
+	mu       sync.Mutex
+	decoders map[*runtime.Scheme]*Decoder
 }
 
 // InjectScheme injects a scheme into the webhook, in order to construct a Decoder.
@@ -69,9 +95,16 @@ func (wh *Webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ctx := r.Context()
+	if r.URL != nil {
+		if cluster, _, ok := admission.ClusterFromPath(r.URL.Path); ok {
+			ctx = kcpclient.WithCluster(ctx, cluster)
+		}
+	}
+
 	// TODO(droot): may be move the conversion logic to a separate module to
 	// decouple it from the http layer ?
-	resp, err := wh.handleConvertRequest(convertReview.Request)
+	resp, err := wh.handleConvertRequest(ctx, convertReview.Request)
 	if err != nil {
 		log.Error(err, "failed to convert", "request", convertReview.Request.UID)
 		convertReview.Response = errored(err)
@@ -89,22 +122,40 @@ func (wh *Webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 // handles a version conversion request.
-func (wh *Webhook) handleConvertRequest(req *apix.ConversionRequest) (*apix.ConversionResponse, error) {
+func (wh *Webhook) handleConvertRequest(ctx context.Context, req *apix.ConversionRequest) (*apix.ConversionResponse, error) {
 	if req == nil {
 		return nil, fmt.Errorf("conversion request is nil")
 	}
+	scheme, decoder, err := wh.schemeFor(ctx)
+	if err != nil {
+		return nil, err
+	}
 	var objects []runtime.RawExtension
 
 	for _, obj := range req.Objects {
-		src, gvk, err := wh.decoder.Decode(obj.Raw)
+		u := &unstructured.Unstructured{}
+		if err := u.UnmarshalJSON(obj.Raw); err != nil {
+			return nil, err
+		}
+		dstGVK := schema.FromAPIVersionAndKind(req.DesiredAPIVersion, u.GetKind())
+		if !scheme.Recognizes(u.GroupVersionKind()) || !scheme.Recognizes(dstGVK) {
+			dst, err := wh.convertUnstructured(u, dstGVK)
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, runtime.RawExtension{Object: dst})
+			continue
+		}
+
+		src, gvk, err := decoder.Decode(obj.Raw)
 		if err != nil {
 			return nil, err
 		}
-		dst, err := wh.allocateDstObject(req.DesiredAPIVersion, gvk.Kind)
+		dst, err := allocateDstObject(scheme, req.DesiredAPIVersion, gvk.Kind)
 		if err != nil {
 			return nil, err
 		}
-		err = wh.convertObject(src, dst)
+		err = convertObject(scheme, src, dst)
 		if err != nil {
 			return nil, err
 		}
@@ -119,10 +170,57 @@ func (wh *Webhook) handleConvertRequest(req *apix.ConversionRequest) (*apix.Conv
 	}, nil
 }
 
+// schemeFor returns the scheme and the decoder of the logical cluster of the context.
+func (wh *Webhook) schemeFor(ctx context.Context) (*runtime.Scheme, *Decoder, error) {
+	cluster, ok := kcpclient.ClusterFromContext(ctx)
+	if !ok || cluster.Empty() || wh.SchemeForCluster == nil {
+		if wh.scheme == nil {
+			return nil, nil, errors.New("no scheme injected")
+		}
+		return wh.scheme, wh.decoder, nil
+	}
+	scheme, err := wh.SchemeForCluster(cluster)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get the scheme of cluster %s: %w", cluster, err)
+	}
+
+	wh.mu.Lock()
+	defer wh.mu.Unlock()
+	if decoder, ok := wh.decoders[scheme]; ok {
+		return scheme, decoder, nil
+	}
+	decoder, err := NewDecoder(scheme)
+	if err != nil {
+		return nil, nil, err
+	}
+	if wh.decoders == nil {
+		wh.decoders = map[*runtime.Scheme]*Decoder{}
+	}
+	wh.decoders[scheme] = decoder
+	return scheme, decoder, nil
+}
+
+// convertUnstructured converts an object of a version missing from the scheme with the
+// Registry.
+func (wh *Webhook) convertUnstructured(src *unstructured.Unstructured, dstGVK schema.GroupVersionKind) (runtime.Object, error) {
+	if wh.Registry == nil {
+		return nil, fmt.Errorf("cannot convert %s to %s: no kind registered in the scheme and no Registry", src.GroupVersionKind(), dstGVK)
+	}
+	if src.GroupVersionKind() == dstGVK {
+		return nil, fmt.Errorf("conversion is not allowed between same version %s", dstGVK)
+	}
+	dst := &unstructured.Unstructured{}
+	dst.SetGroupVersionKind(dstGVK)
+	if err := wh.Registry.Convert(src, dst); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
 // convertObject will convert given a src object to dst object.
 // Note(droot): couldn't find a way to reduce the cyclomatic complexity under 10
 // without compromising readability, so disabling gocyclo linter
-func (wh *Webhook) convertObject(src, dst runtime.Object) error {
+func convertObject(scheme *runtime.Scheme, src, dst runtime.Object) error {
 	srcGVK := src.GetObjectKind().GroupVersionKind()
 	dstGVK := dst.GetObjectKind().GroupVersionKind()
 
@@ -143,14 +241,14 @@ func (wh *Webhook) convertObject(src, dst runtime.Object) error {
 	case dstIsHub && srcIsConvertible:
 		return src.(conversion.Convertible).ConvertTo(dst.(conversion.Hub))
 	case srcIsConvertible && dstIsConvertible:
-		return wh.convertViaHub(src.(conversion.Convertible), dst.(conversion.Convertible))
+		return convertViaHub(scheme, src.(conversion.Convertible), dst.(conversion.Convertible))
 	default:
 		return fmt.Errorf("%T is not convertible to %T", src, dst)
 	}
 }
 
-func (wh *Webhook) convertViaHub(src, dst conversion.Convertible) error {
-	hub, err := wh.getHub(src)
+func convertViaHub(scheme *runtime.Scheme, src, dst conversion.Convertible) error {
+	hub, err := getHub(scheme, src)
 	if err != nil {
 		return err
 	}
@@ -173,8 +271,8 @@ func (wh *Webhook) convertViaHub(src, dst conversion.Convertible) error {
 }
 
 // getHub returns an instance of the Hub for passed-in object's group/kind.
-func (wh *Webhook) getHub(obj runtime.Object) (conversion.Hub, error) {
-	gvks, err := objectGVKs(wh.scheme, obj)
+func getHub(scheme *runtime.Scheme, obj runtime.Object) (conversion.Hub, error) {
+	gvks, err := objectGVKs(scheme, obj)
 	if err != nil {
 		return nil, err
 	}
@@ -185,7 +283,7 @@ func (wh *Webhook) getHub(obj runtime.Object) (conversion.Hub, error) {
 	var hub conversion.Hub
 	var hubFoundAlready bool
 	for _, gvk := range gvks {
-		instance, err := wh.scheme.New(gvk)
+		instance, err := scheme.New(gvk)
 		if err != nil {
 			return nil, fmt.Errorf("failed to allocate an instance for gvk %v: %w", gvk, err)
 		}
@@ -201,10 +299,10 @@ func (wh *Webhook) getHub(obj runtime.Object) (conversion.Hub, error) {
 }
 
 // allocateDstObject returns an instance for a given GVK.
-func (wh *Webhook) allocateDstObject(apiVersion, kind string) (runtime.Object, error) {
+func allocateDstObject(scheme *runtime.Scheme, apiVersion, kind string) (runtime.Object, error) {
 	gvk := schema.FromAPIVersionAndKind(apiVersion, kind)
 
-	obj, err := wh.scheme.New(gvk)
+	obj, err := scheme.New(gvk)
 	if err != nil {
 		return obj, err
 	}
@@ -23,6 +23,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 
+	"github.com/kcp-dev/logicalcluster"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
@@ -31,8 +32,10 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	kscheme "k8s.io/client-go/kubernetes/scheme"
 
+	runtimeconversion "sigs.k8s.io/controller-runtime/pkg/conversion"
 	jobsv1 "sigs.k8s.io/controller-runtime/pkg/webhook/conversion/testdata/api/v1"
 	jobsv2 "sigs.k8s.io/controller-runtime/pkg/webhook/conversion/testdata/api/v2"
 	jobsv3 "sigs.k8s.io/controller-runtime/pkg/webhook/conversion/testdata/api/v3"
@@ -300,6 +303,93 @@ var _ = Describe("Conversion Webhook", func() {
 
 })
 
+var _ = Describe("Conversion Webhook for logical clusters", func() {
+	var webhook *Webhook
+	v2 := jobsv2.GroupVersion.WithKind("ExternalJob")
+	v1alpha1 := jobsv2.GroupVersion.Group + "/v1alpha1"
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(jobsv1.AddToScheme(scheme)).To(Succeed())
+		Expect(jobsv2.AddToScheme(scheme)).To(Succeed())
+		// the workspaces of root:b serve v1alpha1 rather than v1, which only the
+		// registry converts
+		schemeB := runtime.NewScheme()
+		Expect(jobsv2.AddToScheme(schemeB)).To(Succeed())
+
+		webhook = &Webhook{
+			SchemeForCluster: func(cluster logicalcluster.Name) (*runtime.Scheme, error) {
+				if cluster == logicalcluster.New("root:b") {
+					return schemeB, nil
+				}
+				return scheme, nil
+			},
+		}
+		Expect(webhook.InjectScheme(scheme)).To(Succeed())
+	})
+
+	doRequest := func(path string, obj runtime.Object, desiredAPIVersion string) *apix.ConversionReview {
+		var payload bytes.Buffer
+		Expect(json.NewEncoder(&payload).Encode(&apix.ConversionReview{
+			Request: &apix.ConversionRequest{
+				DesiredAPIVersion: desiredAPIVersion,
+				Objects:           []runtime.RawExtension{{Object: obj}},
+			},
+		})).To(Succeed())
+
+		respRecorder := httptest.NewRecorder()
+		webhook.ServeHTTP(respRecorder, httptest.NewRequest(http.MethodPost, path, &payload))
+		convReview := &apix.ConversionReview{}
+		Expect(json.NewDecoder(respRecorder.Result().Body).Decode(convReview)).To(Succeed())
+		return convReview
+	}
+
+	v1alpha1Obj := func() *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion(v1alpha1)
+		u.SetKind("ExternalJob")
+		u.SetNamespace("default")
+		u.SetName("obj-1")
+		Expect(unstructured.SetNestedField(u.Object, "every 2 seconds", "spec", "when")).To(Succeed())
+		return u
+	}
+
+	It("should convert the objects of a logical cluster with the scheme of the cluster", func() {
+		v1Obj := &jobsv1.ExternalJob{
+			TypeMeta:   metav1.TypeMeta{Kind: "ExternalJob", APIVersion: jobsv1.GroupVersion.String()},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "obj-1"},
+			Spec:       jobsv1.ExternalJobSpec{RunAt: "every 2 seconds"},
+		}
+
+		convReview := doRequest("/clusters/root:a/convert", v1Obj, jobsv2.GroupVersion.String())
+		Expect(convReview.Response.Result.Status).To(Equal(metav1.StatusSuccess))
+		Expect(convReview.Response.ConvertedObjects).To(HaveLen(1))
+		Expect(string(convReview.Response.ConvertedObjects[0].Raw)).To(ContainSubstring(`"scheduleAt":"every 2 seconds"`))
+
+		convReview = doRequest("/clusters/root:b/convert", v1Obj, jobsv2.GroupVersion.String())
+		Expect(convReview.Response.Result.Status).To(Equal(metav1.StatusFailure))
+		Expect(convReview.Response.Result.Message).To(ContainSubstring("no Registry"))
+	})
+
+	It("should convert the versions missing from the scheme of a logical cluster with the Registry", func() {
+		convReview := doRequest("/clusters/root:b/convert", v1alpha1Obj(), jobsv2.GroupVersion.String())
+		Expect(convReview.Response.Result.Status).To(Equal(metav1.StatusFailure))
+
+		webhook.Registry = runtimeconversion.NewRegistry(runtime.NewScheme())
+		webhook.Registry.RegisterFieldMappings(schema.FromAPIVersionAndKind(v1alpha1, "ExternalJob"), v2,
+			runtimeconversion.FieldMapping{From: []string{"spec", "when"}, To: []string{"spec", "scheduleAt"}})
+
+		convReview = doRequest("/clusters/root:b/convert", v1alpha1Obj(), jobsv2.GroupVersion.String())
+		Expect(convReview.Response.Result.Status).To(Equal(metav1.StatusSuccess))
+		Expect(convReview.Response.ConvertedObjects).To(HaveLen(1))
+		got := &unstructured.Unstructured{}
+		Expect(got.UnmarshalJSON(convReview.Response.ConvertedObjects[0].Raw)).To(Succeed())
+		Expect(got.GroupVersionKind()).To(Equal(v2))
+		Expect(got.GetName()).To(Equal("obj-1"))
+		Expect(got.Object).To(HaveKeyWithValue("spec", HaveKeyWithValue("scheduleAt", "every 2 seconds")))
+	})
+})
+
 var _ = Describe("IsConvertible", func() {
 
 	var scheme *runtime.Scheme
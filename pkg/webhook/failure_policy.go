@@ -0,0 +1,288 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+const (
+	// defaultFailurePolicyInterval is the default period between two health evaluations
+	// of the FailurePolicyController.
+	defaultFailurePolicyInterval = 10 * time.Second
+
+	// defaultFailureThreshold is the default number of consecutive failed health
+	// evaluations after which the FailurePolicyController degrades the webhooks.
+	defaultFailureThreshold = 3
+
+	// OriginalFailurePoliciesAnnotation records, on the webhook configurations degraded by
+	// a FailurePolicyController, the failurePolicy of each of their webhooks before it was
+	// set to Ignore, as a JSON object keyed by webhook name.  An empty policy stands for a
+	// webhook without failurePolicy.
+	OriginalFailurePoliciesAnnotation = "webhook.controller-runtime.sigs.k8s.io/original-failure-policies"
+)
+
+// FailurePolicyController sets the failurePolicy of the webhooks of a set of webhook
+// configurations to Ignore while the webhook server is unhealthy.
+//
+// Once the Checker failed FailureThreshold times in a row, the failurePolicy of each webhook
+// is recorded in the OriginalFailurePoliciesAnnotation of its configuration, and the webhooks
+// are switched to Ignore so that a degraded webhook server doesn't lock every cluster out of
+// admission.  As soon as the Checker recovers, the recorded policies are restored, so that the
+// webhooks which were deliberately set to Ignore stay so.
+//
+// FailurePolicyController implements Runnable and should be added to the manager,
+// which will inject the Client if none was set.
+type FailurePolicyController struct {
+	// Client is used to read and patch the webhook configurations.
+	Client client.Client
+
+	// Checker reports the health of the webhook server, e.g. Server.StartedChecker().
+	Checker healthz.Checker
+
+	// ValidatingWebhookConfigurations are the names of the
+	// ValidatingWebhookConfigurations to manage.
+	ValidatingWebhookConfigurations []string
+
+	// MutatingWebhookConfigurations are the names of the
+	// MutatingWebhookConfigurations to manage.
+	MutatingWebhookConfigurations []string
+
+	// Clusters are the logical clusters in which the webhook configurations live.
+	// Defaults to the cluster of the context passed to Start.
+	Clusters []logicalcluster.Name
+
+	// Interval is the period between two health evaluations.
+	// Defaults to 10 seconds.
+	Interval time.Duration
+
+	// FailureThreshold is the number of consecutive failed health evaluations after which
+	// the webhooks are set to Ignore, e.g. so that the webhook server isn't degraded while
+	// it is starting.  Defaults to 3.
+	FailureThreshold int
+}
+
+// InjectClient injects the client into the FailurePolicyController.
+func (f *FailurePolicyController) InjectClient(c client.Client) error {
+	if f.Client == nil {
+		f.Client = c
+	}
+	return nil
+}
+
+// NeedLeaderElection implements the LeaderElectionRunnable interface, only the leader
+// should patch the webhook configurations.
+func (f *FailurePolicyController) NeedLeaderElection() bool {
+	return true
+}
+
+// Start periodically evaluates the Checker and updates the webhook configurations
+// until the context is closed.
+func (f *FailurePolicyController) Start(ctx context.Context) error {
+	if f.Client == nil {
+		return errors.New("must specify Client")
+	}
+	if f.Checker == nil {
+		return errors.New("must specify Checker")
+	}
+	interval := f.Interval
+	if interval <= 0 {
+		interval = defaultFailurePolicyInterval
+	}
+	threshold := f.FailureThreshold
+	if threshold <= 0 {
+		threshold = defaultFailureThreshold
+	}
+
+	failures := 0
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if f.healthy(ctx) {
+			failures = 0
+		} else if failures < threshold {
+			failures++
+		}
+		healthy := failures < threshold
+		if err := f.Sync(ctx, healthy); err != nil {
+			log.Error(err, "unable to update webhook failure policy", "healthy", healthy)
+		}
+	}, interval)
+	return nil
+}
+
+// healthy evaluates the Checker.
+func (f *FailurePolicyController) healthy(ctx context.Context) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+	if err != nil {
+		return false
+	}
+	if err := f.Checker(req); err != nil {
+		log.V(1).Info("webhook server is unhealthy", "error", err.Error())
+		return false
+	}
+	return true
+}
+
+// Sync degrades the webhooks of the managed configurations to Ignore, recording their
+// failure policies, unless healthy is set, in which case it restores the recorded policies.
+// It does so in every configured cluster.
+func (f *FailurePolicyController) Sync(ctx context.Context, healthy bool) error {
+	if len(f.Clusters) == 0 {
+		return f.syncCluster(ctx, healthy)
+	}
+
+	var errs []error
+	for _, cluster := range f.Clusters {
+		if err := f.syncCluster(kcpclient.WithCluster(ctx, cluster), healthy); err != nil {
+			errs = append(errs, fmt.Errorf("cluster %q: %w", cluster, err))
+		}
+	}
+	return kerrors.NewAggregate(errs)
+}
+
+func (f *FailurePolicyController) syncCluster(ctx context.Context, healthy bool) error {
+	var errs []error
+	for _, name := range f.ValidatingWebhookConfigurations {
+		if err := f.syncConfiguration(ctx, name, &admissionregistrationv1.ValidatingWebhookConfiguration{}, healthy); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, name := range f.MutatingWebhookConfigurations {
+		if err := f.syncConfiguration(ctx, name, &admissionregistrationv1.MutatingWebhookConfiguration{}, healthy); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return kerrors.NewAggregate(errs)
+}
+
+// syncConfiguration degrades or restores the webhooks of the named webhook configuration.
+func (f *FailurePolicyController) syncConfiguration(ctx context.Context, name string, cfg client.Object, healthy bool) error {
+	if err := f.Client.Get(ctx, client.ObjectKey{NamespacedName: types.NamespacedName{Name: name}}, cfg); err != nil {
+		return err
+	}
+	patch := client.MergeFrom(cfg.DeepCopyObject().(client.Object))
+	var changed bool
+	var err error
+	if healthy {
+		changed, err = restoreFailurePolicies(cfg)
+	} else {
+		changed, err = degradeFailurePolicies(cfg)
+	}
+	if err != nil || !changed {
+		return err
+	}
+	if err := f.Client.Patch(ctx, cfg, patch); err != nil {
+		return err
+	}
+	log.Info("Updated webhook failure policy", "kind", fmt.Sprintf("%T", cfg), "name", name, "healthy", healthy)
+	return nil
+}
+
+// failurePolicies returns the failurePolicy fields of the webhooks of the configuration, by
+// webhook name.
+func failurePolicies(cfg client.Object) map[string]**admissionregistrationv1.FailurePolicyType {
+	policies := map[string]**admissionregistrationv1.FailurePolicyType{}
+	switch cfg := cfg.(type) {
+	case *admissionregistrationv1.ValidatingWebhookConfiguration:
+		for i := range cfg.Webhooks {
+			policies[cfg.Webhooks[i].Name] = &cfg.Webhooks[i].FailurePolicy
+		}
+	case *admissionregistrationv1.MutatingWebhookConfiguration:
+		for i := range cfg.Webhooks {
+			policies[cfg.Webhooks[i].Name] = &cfg.Webhooks[i].FailurePolicy
+		}
+	}
+	return policies
+}
+
+// degradeFailurePolicies sets the webhooks of the configuration to Ignore, recording their
+// policies unless they are already recorded, and returns whether it changed the configuration.
+func degradeFailurePolicies(cfg client.Object) (bool, error) {
+	policies := failurePolicies(cfg)
+	changed := false
+	if _, ok := cfg.GetAnnotations()[OriginalFailurePoliciesAnnotation]; !ok {
+		original := make(map[string]admissionregistrationv1.FailurePolicyType, len(policies))
+		for name, policy := range policies {
+			original[name] = ""
+			if *policy != nil {
+				original[name] = **policy
+			}
+		}
+		data, err := json.Marshal(original)
+		if err != nil {
+			return false, err
+		}
+		annotations := cfg.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[OriginalFailurePoliciesAnnotation] = string(data)
+		cfg.SetAnnotations(annotations)
+		changed = true
+	}
+	for _, policy := range policies {
+		if *policy == nil || **policy != admissionregistrationv1.Ignore {
+			ignore := admissionregistrationv1.Ignore
+			*policy = &ignore
+			changed = true
+		}
+	}
+	return changed, nil
+}
+
+// restoreFailurePolicies restores the recorded policies of the webhooks of the configuration,
+// and returns whether it changed the configuration.  The webhooks without a recorded policy,
+// e.g. added while the configuration was degraded, are left as they are.
+func restoreFailurePolicies(cfg client.Object) (bool, error) {
+	data, ok := cfg.GetAnnotations()[OriginalFailurePoliciesAnnotation]
+	if !ok {
+		return false, nil
+	}
+	original := map[string]admissionregistrationv1.FailurePolicyType{}
+	if err := json.Unmarshal([]byte(data), &original); err != nil {
+		return false, fmt.Errorf("invalid %s annotation: %w", OriginalFailurePoliciesAnnotation, err)
+	}
+	for name, policy := range failurePolicies(cfg) {
+		recorded, ok := original[name]
+		if !ok {
+			continue
+		}
+		if recorded == "" {
+			*policy = nil
+			continue
+		}
+		*policy = &recorded
+	}
+	annotations := cfg.GetAnnotations()
+	delete(annotations, OriginalFailurePoliciesAnnotation)
+	cfg.SetAnnotations(annotations)
+	return true, nil
+}
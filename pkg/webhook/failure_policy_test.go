@@ -0,0 +1,180 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+var _ = Describe("FailurePolicyController", func() {
+	fail, ignore := admissionregistrationv1.Fail, admissionregistrationv1.Ignore
+	a, b := logicalcluster.New("root:a"), logicalcluster.New("root:b")
+	var ctx context.Context
+
+	newConfigurations := func() (*admissionregistrationv1.ValidatingWebhookConfiguration, *admissionregistrationv1.MutatingWebhookConfiguration) {
+		return &admissionregistrationv1.ValidatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{Name: "validating"},
+				Webhooks: []admissionregistrationv1.ValidatingWebhook{
+					{Name: "fail.example.com", FailurePolicy: &fail},
+					{Name: "ignore.example.com", FailurePolicy: &ignore},
+					{Name: "default.example.com"},
+				},
+			}, &admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{Name: "mutating"},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{
+					{Name: "fail.example.com", FailurePolicy: &fail},
+				},
+			}
+	}
+	policies := func(c client.Client, cluster logicalcluster.Name) []*admissionregistrationv1.FailurePolicyType {
+		ctx := kcpclient.WithCluster(ctx, cluster)
+		validating := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+		Expect(c.Get(ctx, client.ObjectKey{NamespacedName: types.NamespacedName{Name: "validating"}}, validating)).To(Succeed())
+		mutating := &admissionregistrationv1.MutatingWebhookConfiguration{}
+		Expect(c.Get(ctx, client.ObjectKey{NamespacedName: types.NamespacedName{Name: "mutating"}}, mutating)).To(Succeed())
+		var policies []*admissionregistrationv1.FailurePolicyType
+		for _, w := range validating.Webhooks {
+			policies = append(policies, w.FailurePolicy)
+		}
+		for _, w := range mutating.Webhooks {
+			policies = append(policies, w.FailurePolicy)
+		}
+		return policies
+	}
+	annotated := func(c client.Client, cluster logicalcluster.Name) bool {
+		validating := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+		Expect(c.Get(kcpclient.WithCluster(ctx, cluster), client.ObjectKey{NamespacedName: types.NamespacedName{Name: "validating"}}, validating)).To(Succeed())
+		_, ok := validating.Annotations[webhook.OriginalFailurePoliciesAnnotation]
+		return ok
+	}
+	newClient := func(clusters ...logicalcluster.Name) client.Client {
+		builder := fake.NewClusterBuilder()
+		for _, cluster := range clusters {
+			validating, mutating := newConfigurations()
+			builder.WithObjects(cluster, validating, mutating)
+		}
+		return builder.Build()
+	}
+	newController := func(c client.Client) *webhook.FailurePolicyController {
+		return &webhook.FailurePolicyController{
+			Client:                          c,
+			Checker:                         func(*http.Request) error { return nil },
+			ValidatingWebhookConfigurations: []string{"validating"},
+			MutatingWebhookConfigurations:   []string{"mutating"},
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	It("should leave the webhooks alone while the webhook server is healthy", func() {
+		c := newClient(a)
+		Expect(newController(c).Sync(kcpclient.WithCluster(ctx, a), true)).To(Succeed())
+		Expect(policies(c, a)).To(Equal([]*admissionregistrationv1.FailurePolicyType{&fail, &ignore, nil, &fail}))
+		Expect(annotated(c, a)).To(BeFalse())
+	})
+
+	It("should ignore the failures of the webhooks while the webhook server is unhealthy, and restore their policies", func() {
+		c := newClient(a)
+		f := newController(c)
+		Expect(f.Sync(kcpclient.WithCluster(ctx, a), false)).To(Succeed())
+		Expect(policies(c, a)).To(Equal([]*admissionregistrationv1.FailurePolicyType{&ignore, &ignore, &ignore, &ignore}))
+		Expect(annotated(c, a)).To(BeTrue())
+
+		By("keeping the recorded policies while it stays unhealthy")
+		Expect(f.Sync(kcpclient.WithCluster(ctx, a), false)).To(Succeed())
+
+		Expect(f.Sync(kcpclient.WithCluster(ctx, a), true)).To(Succeed())
+		Expect(policies(c, a)).To(Equal([]*admissionregistrationv1.FailurePolicyType{&fail, &ignore, nil, &fail}))
+		Expect(annotated(c, a)).To(BeFalse())
+	})
+
+	It("should update the webhook configurations of every cluster", func() {
+		c := newClient(a, b)
+		f := newController(c)
+		f.Clusters = []logicalcluster.Name{a, b}
+		Expect(f.Sync(ctx, false)).To(Succeed())
+		Expect(policies(c, a)).To(Equal([]*admissionregistrationv1.FailurePolicyType{&ignore, &ignore, &ignore, &ignore}))
+		Expect(policies(c, b)).To(Equal([]*admissionregistrationv1.FailurePolicyType{&ignore, &ignore, &ignore, &ignore}))
+
+		Expect(f.Sync(ctx, true)).To(Succeed())
+		Expect(policies(c, a)).To(Equal([]*admissionregistrationv1.FailurePolicyType{&fail, &ignore, nil, &fail}))
+		Expect(policies(c, b)).To(Equal([]*admissionregistrationv1.FailurePolicyType{&fail, &ignore, nil, &fail}))
+
+		f.Clusters = append(f.Clusters, logicalcluster.New("root:missing"))
+		Expect(f.Sync(ctx, false)).To(MatchError(ContainSubstring(`cluster "root:missing"`)))
+		Expect(policies(c, a)).To(Equal([]*admissionregistrationv1.FailurePolicyType{&ignore, &ignore, &ignore, &ignore}))
+	})
+
+	It("should only degrade the webhooks after FailureThreshold consecutive failed checks", func() {
+		c := newClient(a)
+		f := newController(c)
+		f.Interval = time.Millisecond
+		f.FailureThreshold = 2
+		results := make(chan error)
+		f.Checker = func(req *http.Request) error {
+			select {
+			case err := <-results:
+				return err
+			case <-req.Context().Done():
+				return req.Context().Err()
+			}
+		}
+		ctx, cancel := context.WithCancel(kcpclient.WithCluster(ctx, a))
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(f.Start(ctx)).To(Succeed())
+		}()
+		defer func() {
+			cancel()
+			<-done
+		}()
+
+		unhealthy := errors.New("unhealthy")
+		// each result is only received once the previous check was acted upon.
+		results <- unhealthy
+		results <- nil
+		results <- unhealthy
+		Expect(annotated(c, a)).To(BeFalse())
+		results <- unhealthy
+		results <- unhealthy
+		Expect(annotated(c, a)).To(BeTrue())
+		Expect(policies(c, a)).To(Equal([]*admissionregistrationv1.FailurePolicyType{&ignore, &ignore, &ignore, &ignore}))
+		results <- nil
+		results <- nil
+		Expect(annotated(c, a)).To(BeFalse())
+		Expect(policies(c, a)).To(Equal([]*admissionregistrationv1.FailurePolicyType{&fail, &ignore, nil, &fail}))
+	})
+})
@@ -57,10 +57,30 @@ var (
 			[]string{"webhook"},
 		)
 	}()
+
+	// HandlerLatency is a prometheus metric which is a histogram of the latency
+	// of the handlers of the admission multi-handlers.
+	HandlerLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "controller_runtime_webhook_handler_latency_seconds",
+			Help: "Histogram of the latency of the handlers of admission multi-handlers",
+		},
+		[]string{"webhook", "handler"},
+	)
+
+	// HandlerVerdicts is a prometheus metric which is a counter of the verdicts
+	// of the handlers of the admission multi-handlers.
+	HandlerVerdicts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "controller_runtime_webhook_handler_verdicts_total",
+			Help: "Total number of verdicts of the handlers of admission multi-handlers, by verdict: allowed, denied or errored.",
+		},
+		[]string{"webhook", "handler", "verdict"},
+	)
 )
 
 func init() {
-	metrics.Registry.MustRegister(RequestLatency, RequestTotal, RequestInFlight)
+	metrics.Registry.MustRegister(RequestLatency, RequestTotal, RequestInFlight, HandlerLatency, HandlerVerdicts)
 }
 
 // InstrumentedHook adds some instrumentation on top of the given webhook.
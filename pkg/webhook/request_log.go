@@ -0,0 +1,192 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// redacted replaces the values removed from the logged objects.
+const redacted = "<redacted>"
+
+// RequestLogging configures the logging of the admission requests served by a Server, so
+// that the admission traffic can be audited.
+//
+// Each request is logged once its response is written, with the path it was served at (which,
+// with kcp, holds the logical cluster of the request), its UID, operation, resource, object,
+// user, and whether it was allowed.
+type RequestLogging struct {
+	// Logger is the logger the requests are logged to.  It defaults to the logger of the
+	// webhooks.
+	Logger logr.Logger
+
+	// SampleEvery makes only one in SampleEvery requests be logged.  The requests which are
+	// denied or fail are always logged.  Defaults to 1, i.e. every request is logged.
+	SampleEvery uint64
+
+	// IncludeObjects makes the object and old object of the requests be logged, once redacted.
+	IncludeObjects bool
+
+	// RedactSecretData replaces the values of the data and stringData of the Secrets by a
+	// placeholder in the logged objects.
+	RedactSecretData bool
+
+	// RedactAnnotations replaces the values of the annotations whose key matches any of
+	// the patterns by a placeholder in the logged objects.
+	RedactAnnotations []*regexp.Regexp
+}
+
+// requestLogger is the middleware logging the requests of a webhook.
+type requestLogger struct {
+	opts    RequestLogging
+	log     logr.Logger
+	handler http.Handler
+	count   uint64
+}
+
+// logRequests wraps the handler of the webhook served at the given path so that its requests
+// are logged according to the options.
+func logRequests(opts RequestLogging, path string, handler http.Handler) http.Handler {
+	l := opts.Logger
+	if l.GetSink() == nil {
+		l = log.WithName("webhooks")
+	}
+	if opts.SampleEvery == 0 {
+		opts.SampleEvery = 1
+	}
+	return &requestLogger{
+		opts:    opts,
+		log:     l.WithValues("webhook", path),
+		handler: handler,
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (l *requestLogger) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	var body []byte
+	if r.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(r.Body); err != nil {
+			l.log.Error(err, "unable to read the body of the request")
+		}
+		r.Body.Close()
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+	l.handler.ServeHTTP(rec, r)
+
+	review := admissionv1.AdmissionReview{}
+	_ = json.Unmarshal(rec.body.Bytes(), &review)
+	res := review.Response
+	failed := rec.status != http.StatusOK || res == nil || !res.Allowed
+	if !failed && atomic.AddUint64(&l.count, 1)%l.opts.SampleEvery != 0 {
+		return
+	}
+
+	values := []interface{}{"path", r.URL.Path, "latency", time.Since(start).String(), "status", rec.status}
+	review = admissionv1.AdmissionReview{}
+	if err := json.Unmarshal(body, &review); err == nil && review.Request != nil {
+		req := review.Request
+		values = append(values,
+			"UID", req.UID,
+			"operation", req.Operation,
+			"kind", req.Kind,
+			"resource", req.Resource,
+			"subResource", req.SubResource,
+			"namespace", req.Namespace,
+			"name", req.Name,
+			"user", req.UserInfo.Username,
+			"dryRun", req.DryRun != nil && *req.DryRun,
+		)
+		if l.opts.IncludeObjects {
+			secret := req.Kind.Group == "" && req.Kind.Kind == "Secret"
+			values = append(values,
+				"object", l.redact(req.Object, secret),
+				"oldObject", l.redact(req.OldObject, secret),
+			)
+		}
+	}
+	if res != nil {
+		values = append(values, "allowed", res.Allowed)
+		if res.Result != nil {
+			values = append(values, "code", res.Result.Code, "reason", res.Result.Reason)
+		}
+	}
+	l.log.Info("admission request", values...)
+}
+
+// redact returns the object with its sensitive fields replaced by a placeholder.
+func (l *requestLogger) redact(raw runtime.RawExtension, secret bool) map[string]interface{} {
+	if len(raw.Raw) == 0 {
+		return nil
+	}
+	obj := map[string]interface{}{}
+	if err := json.Unmarshal(raw.Raw, &obj); err != nil {
+		return map[string]interface{}{"error": "unable to decode the object"}
+	}
+	if secret && l.opts.RedactSecretData {
+		for _, field := range []string{"data", "stringData"} {
+			if values, ok := obj[field].(map[string]interface{}); ok {
+				for key := range values {
+					values[key] = redacted
+				}
+			}
+		}
+	}
+	if metadata, ok := obj["metadata"].(map[string]interface{}); ok && len(l.opts.RedactAnnotations) > 0 {
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			for key := range annotations {
+				for _, pattern := range l.opts.RedactAnnotations {
+					if pattern.MatchString(key) {
+						annotations[key] = redacted
+						break
+					}
+				}
+			}
+		}
+	}
+	return obj
+}
+
+// responseRecorder records the status and the body of a response while writing it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
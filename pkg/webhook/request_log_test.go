@@ -0,0 +1,111 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr/funcr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var _ = Describe("Webhook Server request logging", func() {
+	var (
+		mu     sync.Mutex
+		lines  []string
+		server *webhook.Server
+	)
+
+	BeforeEach(func() {
+		lines = nil
+		server = &webhook.Server{
+			RequestLogging: &webhook.RequestLogging{
+				Logger: funcr.New(func(prefix, args string) {
+					mu.Lock()
+					defer mu.Unlock()
+					lines = append(lines, args)
+				}, funcr.Options{}),
+				IncludeObjects:    true,
+				RedactSecretData:  true,
+				RedactAnnotations: []*regexp.Regexp{regexp.MustCompile(`^secret\.example\.com/`)},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		server.Register("/validate", &admission.Webhook{
+			Handler: admission.HandlerFunc(func(ctx context.Context, req admission.Request) admission.Response {
+				if req.Name == "denied" {
+					return admission.Denied("denied")
+				}
+				return admission.Allowed("")
+			}),
+		})
+		Expect(server.InjectFunc(func(interface{}) error { return nil })).To(Succeed())
+	})
+
+	review := func(kind, name, object string) {
+		body := `{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview","request":{"uid":"123",` +
+			`"kind":{"group":"","version":"v1","kind":"` + kind + `"},"name":"` + name + `","operation":"CREATE",` +
+			`"object":` + object + `}}`
+		req := httptest.NewRequest(http.MethodPost, "/validate", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.WebhookMux.ServeHTTP(w, req)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(ContainSubstring(`"uid":"123"`))
+	}
+
+	It("should log the requests with their sensitive fields redacted", func() {
+		review("Secret", "creds",
+			`{"metadata":{"name":"creds","annotations":{"secret.example.com/token":"abc","other":"visible"}},"data":{"password":"c2VjcmV0"}}`)
+
+		Expect(lines).To(HaveLen(1))
+		Expect(lines[0]).To(ContainSubstring(`"webhook"="/validate"`))
+		Expect(lines[0]).To(ContainSubstring(`"name"="creds"`))
+		Expect(lines[0]).To(ContainSubstring(`"allowed"=true`))
+		Expect(lines[0]).To(ContainSubstring(`"visible"`))
+		Expect(lines[0]).NotTo(ContainSubstring("c2VjcmV0"))
+		Expect(lines[0]).NotTo(ContainSubstring(`"abc"`))
+		Expect(lines[0]).To(ContainSubstring("<redacted>"))
+	})
+
+	Context("with sampling", func() {
+		BeforeEach(func() {
+			server.RequestLogging.SampleEvery = 3
+		})
+
+		It("should only log a sample of the allowed requests, and all the denied ones", func() {
+			for i := 0; i < 6; i++ {
+				review("ConfigMap", "allowed", `{"metadata":{"name":"allowed"}}`)
+			}
+			review("ConfigMap", "denied", `{"metadata":{"name":"denied"}}`)
+
+			Expect(lines).To(HaveLen(3))
+			Expect(lines[2]).To(ContainSubstring(`"allowed"=false`))
+		})
+	})
+})
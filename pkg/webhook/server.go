@@ -26,16 +26,20 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
 
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
 	"k8s.io/apimachinery/pkg/runtime"
 	kscheme "k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/internal/httpserver"
 	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/internal/metrics"
 )
 
@@ -80,6 +84,15 @@ type Server struct {
 	// WebhookMux is the multiplexer that handles different webhooks.
 	WebhookMux *http.ServeMux
 
+	// RequestLogging, if set, makes the admission requests served by the webhooks be logged.
+	// It must be set before the webhooks are registered.
+	RequestLogging *RequestLogging
+
+	// StrictDecoding, if set, makes the admission webhooks that don't set their own reject the
+	// objects of the selected kinds and logical clusters that have unknown fields.  It must be
+	// set before the webhooks are registered.
+	StrictDecoding *apiutil.StrictDecoding
+
 	// webhooks keep track of all registered webhooks for dependency injection,
 	// and to provide better panic messages on duplicate webhook registration.
 	webhooks map[string]http.Handler
@@ -139,8 +152,15 @@ func (s *Server) Register(path string, hook http.Handler) {
 		panic(fmt.Errorf("can't register duplicate path: %v", path))
 	}
 	// TODO(directxman12): call setfields if we've already started the server
+	if wh, ok := hook.(*admission.Webhook); ok && wh.StrictDecoding == nil {
+		wh.StrictDecoding = s.StrictDecoding
+	}
 	s.webhooks[path] = hook
-	s.WebhookMux.Handle(path, metrics.InstrumentedHook(path, hook))
+	handler := http.Handler(hook)
+	if s.RequestLogging != nil {
+		handler = logRequests(*s.RequestLogging, path, handler)
+	}
+	s.WebhookMux.Handle(path, metrics.InstrumentedHook(path, handler))
 
 	regLog := log.WithValues("path", path)
 	regLog.Info("Registering webhook")
@@ -165,6 +185,19 @@ func (s *Server) Register(path string, hook http.Handler) {
 	}
 }
 
+// Paths returns the sorted paths of the registered webhooks.
+func (s *Server) Paths() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	paths := make([]string, 0, len(s.webhooks))
+	for path := range s.webhooks {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
 // StartStandalone runs a webhook server without
 // a controller manager.
 func (s *Server) StartStandalone(ctx context.Context, scheme *runtime.Scheme) error {
@@ -185,6 +218,24 @@ func (s *Server) StartStandalone(ctx context.Context, scheme *runtime.Scheme) er
 	return s.Start(ctx)
 }
 
+// routeClusters serves the admission requests of a logical cluster sent to
+// /clusters/<name>/<webhook path> with the webhook registered at the webhook path,
+// unless a webhook is registered at the full path.  The logical cluster is passed
+// to the webhook in the context of the request.
+func routeClusters(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cluster, path, ok := admission.ClusterFromPath(r.URL.Path)
+		if _, pattern := mux.Handler(r); !ok || pattern == r.URL.Path {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		routed := r.Clone(kcpclient.WithCluster(r.Context(), cluster))
+		routed.URL.Path = path
+		routed.URL.RawPath = ""
+		mux.ServeHTTP(w, routed)
+	})
+}
+
 // tlsVersion converts from human-readable TLS version (for example "1.1")
 // to the values accepted by tls.Config (for example 0x301).
 func tlsVersion(version string) (uint16, error) {
@@ -262,7 +313,7 @@ func (s *Server) Start(ctx context.Context) error {
 
 	log.Info("Serving webhook server", "host", s.Host, "port", s.Port)
 
-	srv := httpserver.New(s.WebhookMux)
+	srv := httpserver.New(routeClusters(s.WebhookMux))
 
 	idleConnsClosed := make(chan struct{})
 	go func() {
@@ -23,6 +23,7 @@ import (
 	"net"
 	"net/http"
 
+	kcpclient "github.com/kcp-dev/apimachinery/pkg/client"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -145,6 +146,29 @@ var _ = Describe("Webhook Server", func() {
 			Eventually(doneCh, "4s").Should(BeClosed())
 		})
 
+		It("should serve the webhooks of a logical cluster under its path", func() {
+			server.Register("/somepath", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				cluster, _ := kcpclient.ClusterFromContext(r.Context())
+				_, _ = w.Write([]byte(r.URL.Path + " in " + cluster.String()))
+			}))
+			doneCh := startServer()
+
+			Eventually(func() ([]byte, error) {
+				resp, err := client.Get(fmt.Sprintf("https://%s/clusters/root:org:ws/somepath", testHostPort))
+				Expect(err).NotTo(HaveOccurred())
+				defer resp.Body.Close()
+				return ioutil.ReadAll(resp.Body)
+			}).Should(Equal([]byte("/somepath in root:org:ws")))
+
+			resp, err := client.Get(fmt.Sprintf("https://%s/clusters/root:org:ws/otherpath", testHostPort))
+			Expect(err).NotTo(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+
+			ctxCancel()
+			Eventually(doneCh, "4s").Should(BeClosed())
+		})
+
 		It("should inject dependencies eventually, given an inject func is eventually provided", func() {
 			handler := &testHandler{}
 			server.Register("/somepath", handler)
@@ -0,0 +1,38 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package workerpool shares a budget of reconcile workers between the controllers of a manager,
+so that the aggregate number of concurrent reconciles is bounded and a low-priority controller
+flooded with events can't starve a critical one.
+
+Each controller still runs its own MaxConcurrentReconciles workers, but a worker borrows a slot
+of the Pool for each reconcile.  When controllers wait for a slot, the next free one goes to the
+controller which used the least worker time relative to its weight, so that the controllers get
+shares of the workers proportional to their weights while they are all busy, and an idle
+controller lends its share to the others:
+
+	mgr, err := manager.New(cfg, manager.Options{ReconcileWorkerPoolSize: 10})
+	...
+	ctrl.NewControllerManagedBy(mgr).For(&corev1.Secret{}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: 10, WorkerPoolWeight: 4}).
+		Complete(critical)
+
+The slots held and the time spent waiting for them are exposed in the
+controller_runtime_worker_pool_active_workers and controller_runtime_worker_pool_wait_seconds
+metrics.
+*/
+package workerpool
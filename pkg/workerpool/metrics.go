@@ -0,0 +1,41 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workerpool
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// activeWorkers is the number of slots of the pool held per controller.
+	activeWorkers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "controller_runtime_worker_pool_active_workers",
+		Help: "Number of slots of the shared worker pool currently held per controller",
+	}, []string{"controller"})
+
+	// waitSeconds is the time spent waiting for a slot of the pool.
+	waitSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "controller_runtime_worker_pool_wait_seconds",
+		Help:    "Length of time waiting for a slot of the shared worker pool per controller",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 16),
+	}, []string{"controller"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(activeWorkers, waitSeconds)
+}
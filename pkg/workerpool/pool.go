@@ -0,0 +1,184 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workerpool
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// minSlice is the least worker time charged for a reconcile, so that the controllers with
+// very short reconciles still pay for the slots they are granted.
+const minSlice = time.Millisecond
+
+// Pool is a budget of reconcile workers shared between controllers.
+type Pool struct {
+	mu     sync.Mutex
+	size   int
+	inUse  int
+	shares map[string]*share
+	// vtime is the pass of the last controller granted a slot.  The controllers which start
+	// waiting after being idle catch up with it, so that they don't hoard the credit
+	// accumulated while idle.
+	vtime float64
+
+	now func() time.Time
+}
+
+// share is the usage of the pool by a controller.
+type share struct {
+	name   string
+	weight int
+	// pass is the worker time used by the controller, in seconds divided by its weight.
+	pass float64
+	// slice is the moving average of the worker time of its reconciles, charged when a slot
+	// is granted and corrected when it is released.
+	slice   time.Duration
+	held    int
+	waiters []*waiter
+}
+
+// waiter is a reconcile waiting for a worker.
+type waiter struct {
+	ready chan struct{}
+	// charged is the worker time charged when the worker was granted.
+	charged time.Duration
+}
+
+// New returns a Pool of size workers.  A size of 0 or less means a single worker.
+func New(size int) *Pool {
+	if size <= 0 {
+		size = 1
+	}
+	return &Pool{
+		size:   size,
+		shares: map[string]*share{},
+		now:    time.Now,
+	}
+}
+
+// Size returns the number of workers of the Pool.
+func (p *Pool) Size() int {
+	return p.size
+}
+
+// SetWeight sets the weight of the controller.  While several controllers wait for workers,
+// each one gets a share of them proportional to its weight.  Controllers default to a weight
+// of 1, a weight of 0 or less means 1.
+func (p *Pool) SetWeight(controller string, weight int) {
+	if weight <= 0 {
+		weight = 1
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.shareFor(controller).weight = weight
+}
+
+// Acquire waits for a worker of the Pool for a reconcile of the controller, or until ctx is
+// done.  The returned function releases the worker once the reconcile is done.
+func (p *Pool) Acquire(ctx context.Context, controller string) (func(), error) {
+	p.mu.Lock()
+	s := p.shareFor(controller)
+	if s.held == 0 && len(s.waiters) == 0 && s.pass < p.vtime {
+		s.pass = p.vtime
+	}
+	w := &waiter{ready: make(chan struct{})}
+	s.waiters = append(s.waiters, w)
+	p.grant()
+	p.mu.Unlock()
+
+	start := p.now()
+	select {
+	case <-w.ready:
+	case <-ctx.Done():
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		for i := range s.waiters {
+			if s.waiters[i] == w {
+				s.waiters = append(s.waiters[:i], s.waiters[i+1:]...)
+				return nil, ctx.Err()
+			}
+		}
+		// The worker was granted in the meantime, hand it over to the next waiter.
+		p.release(s, w.charged, 0)
+		return nil, ctx.Err()
+	}
+	granted := p.now()
+	waitSeconds.WithLabelValues(controller).Observe(granted.Sub(start).Seconds())
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			p.release(s, w.charged, p.now().Sub(granted))
+		})
+	}, nil
+}
+
+// shareFor returns the share of the controller, creating it if needed.
+func (p *Pool) shareFor(controller string) *share {
+	s, ok := p.shares[controller]
+	if !ok {
+		s = &share{name: controller, weight: 1, slice: minSlice}
+		p.shares[controller] = s
+	}
+	return s
+}
+
+// grant hands the free workers over to the waiting controllers with the lowest pass.
+func (p *Pool) grant() {
+	for p.inUse < p.size {
+		var next *share
+		for _, s := range p.shares {
+			if len(s.waiters) == 0 {
+				continue
+			}
+			if next == nil || s.pass < next.pass || (s.pass == next.pass && s.name < next.name) {
+				next = s
+			}
+		}
+		if next == nil {
+			return
+		}
+		w := next.waiters[0]
+		next.waiters[0] = nil
+		next.waiters = next.waiters[1:]
+		p.inUse++
+		next.held++
+		p.vtime = next.pass
+		w.charged = next.slice
+		next.pass += w.charged.Seconds() / float64(next.weight)
+		activeWorkers.WithLabelValues(next.name).Set(float64(next.held))
+		close(w.ready)
+	}
+}
+
+// release frees a worker held by the controller for used time, of which charged was charged
+// when it was granted, and grants it to the next waiting controller.
+func (p *Pool) release(s *share, charged, used time.Duration) {
+	if used < minSlice {
+		used = minSlice
+	}
+	s.pass += (used - charged).Seconds() / float64(s.weight)
+	s.slice += (used - s.slice) / 8
+	s.held--
+	p.inUse--
+	activeWorkers.WithLabelValues(s.name).Set(float64(s.held))
+	p.grant()
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workerpool
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Pool", func() {
+	ctx := context.Background()
+
+	var pool *Pool
+	var mu sync.Mutex
+	var now time.Time
+	BeforeEach(func() {
+		pool = New(1)
+		mu.Lock()
+		now = time.Unix(0, 0)
+		mu.Unlock()
+		pool.now = func() time.Time {
+			mu.Lock()
+			defer mu.Unlock()
+			return now
+		}
+	})
+	advance := func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+	}
+	waiting := func(controller string) func() int {
+		return func() int {
+			pool.mu.Lock()
+			defer pool.mu.Unlock()
+			return len(pool.shareFor(controller).waiters)
+		}
+	}
+
+	type grant struct {
+		controller string
+		release    func()
+	}
+	// queue starts n reconciles of the controller waiting for a worker, and sends them on
+	// granted once they get one.
+	queue := func(granted chan<- grant, controller string, n int) {
+		p := pool
+		for i := 0; i < n; i++ {
+			go func() {
+				defer GinkgoRecover()
+				release, err := p.Acquire(ctx, controller)
+				Expect(err).NotTo(HaveOccurred())
+				granted <- grant{controller: controller, release: release}
+			}()
+		}
+		Eventually(waiting(controller)).Should(BeNumerically(">=", n))
+	}
+	// run runs the n next granted reconciles for d each, and returns their controllers.
+	run := func(granted <-chan grant, n int, d time.Duration) []string {
+		var controllers []string
+		for i := 0; i < n; i++ {
+			var g grant
+			Eventually(granted).Should(Receive(&g))
+			controllers = append(controllers, g.controller)
+			advance(d)
+			g.release()
+		}
+		return controllers
+	}
+	count := func(controllers []string, controller string) int {
+		n := 0
+		for _, c := range controllers {
+			if c == controller {
+				n++
+			}
+		}
+		return n
+	}
+
+	It("should bound the number of concurrent reconciles", func() {
+		pool = New(2)
+		release1, err := pool.Acquire(ctx, "a")
+		Expect(err).NotTo(HaveOccurred())
+		release2, err := pool.Acquire(ctx, "b")
+		Expect(err).NotTo(HaveOccurred())
+
+		granted := make(chan grant, 1)
+		queue(granted, "a", 1)
+		Consistently(granted, 100*time.Millisecond).ShouldNot(Receive())
+
+		release1()
+		release1()
+		Eventually(granted).Should(Receive())
+		release2()
+		Expect(pool.inUse).To(Equal(1))
+	})
+
+	It("should share the workers in proportion to the weights of the waiting controllers", func() {
+		pool.SetWeight("critical", 3)
+		blocker, err := pool.Acquire(ctx, "blocker")
+		Expect(err).NotTo(HaveOccurred())
+
+		granted := make(chan grant, 80)
+		queue(granted, "housekeeping", 40)
+		queue(granted, "critical", 40)
+		blocker()
+
+		controllers := run(granted, 40, 10*time.Millisecond)
+		Expect(count(controllers, "critical")).To(BeNumerically("~", 30, 2))
+		run(granted, 40, 10*time.Millisecond)
+	})
+
+	It("should charge the controllers for the time their reconciles hold the workers", func() {
+		blocker, err := pool.Acquire(ctx, "blocker")
+		Expect(err).NotTo(HaveOccurred())
+
+		granted := make(chan grant, 60)
+		queue(granted, "slow", 30)
+		queue(granted, "fast", 30)
+		blocker()
+
+		var controllers []string
+		for len(controllers) < 30 {
+			var g grant
+			Eventually(granted).Should(Receive(&g))
+			controllers = append(controllers, g.controller)
+			if g.controller == "slow" {
+				advance(40 * time.Millisecond)
+			} else {
+				advance(10 * time.Millisecond)
+			}
+			g.release()
+		}
+		Expect(count(controllers, "fast")).To(BeNumerically(">", 3*count(controllers, "slow")))
+		run(granted, 30, 0)
+	})
+
+	It("should not let an idle controller hoard credit", func() {
+		blocker, err := pool.Acquire(ctx, "blocker")
+		Expect(err).NotTo(HaveOccurred())
+
+		granted := make(chan grant, 20)
+		queue(granted, "busy", 10)
+		blocker()
+		run(granted, 10, 10*time.Millisecond)
+
+		blocker, err = pool.Acquire(ctx, "blocker")
+		Expect(err).NotTo(HaveOccurred())
+		queue(granted, "busy", 5)
+		queue(granted, "idle", 5)
+		blocker()
+		controllers := run(granted, 4, 10*time.Millisecond)
+		Expect(count(controllers, "busy")).To(BeNumerically(">=", 1))
+		run(granted, 6, 10*time.Millisecond)
+	})
+
+	It("should stop waiting when the context is done", func() {
+		release, err := pool.Acquire(ctx, "a")
+		Expect(err).NotTo(HaveOccurred())
+
+		cctx, cancel := context.WithCancel(ctx)
+		errs := make(chan error, 1)
+		go func() {
+			_, err := pool.Acquire(cctx, "b")
+			errs <- err
+		}()
+		Eventually(waiting("b")).Should(Equal(1))
+		cancel()
+		Eventually(errs).Should(Receive(MatchError(context.Canceled)))
+		Expect(waiting("b")()).To(Equal(0))
+
+		release()
+		release, err = pool.Acquire(ctx, "b")
+		Expect(err).NotTo(HaveOccurred())
+		release()
+		Expect(pool.inUse).To(Equal(0))
+	})
+})